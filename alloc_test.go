@@ -0,0 +1,95 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+// TestParseNextIntoAllocs verifies that decoding a stream one frame at the time
+// into the same frame does not allocate memory, once the buffers of the frame
+// have grown to hold the largest frame of the stream.
+func TestParseNextIntoAllocs(t *testing.T) {
+	paths := []string{
+		"testdata/172960.flac",
+		"testdata/189983.flac",
+		"testdata/191885.flac",
+		"testdata/19875.flac",
+		"testdata/212768.flac",
+		"testdata/220014.flac",
+		"testdata/243749.flac",
+		"testdata/256529.flac",
+		"testdata/257344.flac",
+		"testdata/44127.flac",
+		"testdata/59996.flac",
+		"testdata/80574.flac",
+		"testdata/8297-275156-0011.flac",
+		"testdata/love.flac",
+	}
+	golden := []struct {
+		name string
+		opts flac.DecodeOptions
+	}{
+		{name: "default"},
+		{name: "intermediates", opts: flac.DecodeOptions{KeepIntermediates: true}},
+	}
+	for _, path := range paths {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, g := range golden {
+			t.Run(filepath.Base(path)+"/"+g.name, func(t *testing.T) {
+				// Grow the buffers of the frame by decoding the entire stream.
+				f := new(frame.Frame)
+				nframes := parseFramesInto(t, buf, &g.opts, f)
+				if nframes < 2 {
+					t.Skipf("too few frames (%d)", nframes)
+				}
+
+				// Decode the stream again; the first call of AllocsPerRun is a
+				// warm-up run.
+				stream, err := flac.NewWithOptions(bytes.NewReader(buf), &g.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var parseErr error
+				allocs := testing.AllocsPerRun(nframes-1, func() {
+					if err := stream.ParseNextInto(f); err != nil && parseErr == nil {
+						parseErr = err
+					}
+				})
+				if parseErr != nil {
+					t.Fatal(parseErr)
+				}
+				if allocs != 0 {
+					t.Errorf("allocations per frame mismatch; expected 0, got %v", allocs)
+				}
+			})
+		}
+	}
+}
+
+// parseFramesInto decodes the frames of the given FLAC stream into f, and
+// returns the number of frames.
+func parseFramesInto(t *testing.T, buf []byte, opts *flac.DecodeOptions, f *frame.Frame) int {
+	stream, err := flac.NewWithOptions(bytes.NewReader(buf), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nframes := 0
+	for {
+		if err := stream.ParseNextInto(f); err != nil {
+			if err == io.EOF {
+				return nframes
+			}
+			t.Fatal(err)
+		}
+		nframes++
+	}
+}
@@ -1,8 +1,8 @@
 package flac
 
 import (
+	iobits "github.com/mewkiz/flac/bits"
 	"github.com/mewkiz/flac/frame"
-	iobits "github.com/mewkiz/flac/internal/bits"
 )
 
 // analyzeFixed selects the best fixed predictor (order 0-4) for the given
@@ -128,27 +128,29 @@ func costFixed(order int, bps uint, residuals []int32, k uint) int {
 	return 6 + warmUpBits + residBits
 }
 
-// analyzeSubframe decides on the best prediction method (constant, verbatim, or
-// fixed) for a subframe that is currently marked PredVerbatim. It will update
-// the Subframe fields to use the chosen method. The heuristic is simple: it
-// picks the encoding that yields the fewest estimated bits when assuming a
-// single Rice partition.
-func analyzeSubframe(sf *frame.Subframe, bps uint) {
+// analyzeSubframe decides on the best prediction method (constant, verbatim,
+// fixed, or FIR if lpc is non-nil) for a subframe that is currently marked
+// PredVerbatim. It will update the Subframe fields to use the chosen method.
+// The heuristic is simple: it picks the encoding that yields the fewest
+// estimated bits when assuming a single Rice partition. The estimated size in
+// bits of the chosen encoding is returned; or 0 if the subframe was not
+// analyzed.
+func analyzeSubframe(sf *frame.Subframe, bps uint, lpc *lpcConfig) (estBits int) {
 	// Only analyze when the caller has not chosen a prediction method yet.
 	if sf.Pred != frame.PredVerbatim {
-		return
+		return 0
 	}
 
 	samples := sf.Samples
 	n := len(samples)
 	if n == 0 {
-		return
+		return 0
 	}
 
 	// Guard against degenerate inputs. If there are fewer than two samples we
 	// simply keep verbatim encoding.
 	if n < 2 {
-		return
+		return 0
 	}
 
 	// --- Constant predictor cost.
@@ -174,20 +176,43 @@ func analyzeSubframe(sf *frame.Subframe, bps uint) {
 	fixedResiduals := computeFixedResiduals(samples, sf.Order)
 	fixedBits := costFixed(sf.Order, bps, fixedResiduals, sf.RiceSubframe.Partitions[0].Param)
 
+	// --- FIR predictor: best of the apodization functions.
+	firBits := int(^uint(0) >> 1) // max int
+	var fir *lpcCandidate
+	if lpc != nil && !allEqual {
+		if c, ok := analyzeLPC(sf, bps, lpc); ok {
+			fir, firBits = c, c.bits
+		}
+	}
+
 	// Choose the smallest.
 	switch {
 	case constBits < verbatimBits && constBits < fixedBits:
 		// Use constant encoding.
 		sf.Pred = frame.PredConstant
 		// No other metadata needed.
+		return constBits
+	case firBits < verbatimBits && firBits < fixedBits:
+		sf.Pred = frame.PredFIR
+		sf.Order = len(fir.coeffs)
+		sf.CoeffPrec = fir.prec
+		sf.CoeffShift = fir.shift
+		sf.Coeffs = fir.coeffs
+		sf.RiceSubframe = &frame.RiceSubframe{
+			PartOrder:  0,
+			Partitions: []frame.RicePartition{{Param: fir.param}},
+		}
+		return firBits
 	case fixedBits < verbatimBits:
 		// Keep fixed settings filled in by analyzeFixed.
 		sf.Pred = frame.PredFixed
+		return fixedBits
 	default:
 		// Stick with verbatim – restore defaults that analyzeFixed may have
 		// overwritten.
 		sf.Pred = frame.PredVerbatim
 		sf.Order = 0
 		sf.RiceSubframe = nil
+		return verbatimBits
 	}
 }
@@ -0,0 +1,362 @@
+package flac
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/internal/pmath"
+	"github.com/mewkiz/flac/meta"
+)
+
+// NOTE: the floating-point computations of LPC analysis explicitly round the
+// products of multiply-add operations (e.g. float64(x*y) + z), to prevent
+// their fusion on architectures with FMA instructions; so that the output of
+// the encoder is identical across platforms.
+
+// mathFuncs holds the elementary functions of LPC analysis.
+type mathFuncs struct {
+	cos, exp, log2 func(x float64) float64
+}
+
+var (
+	// Elementary functions of the math package.
+	stdMath = &mathFuncs{cos: math.Cos, exp: math.Exp, log2: math.Log2}
+	// Portable elementary functions, with bit-identical results on every
+	// platform and Go version; used by reproducible encoders.
+	portableMath = &mathFuncs{cos: pmath.Cos, exp: pmath.Exp, log2: pmath.Log2}
+)
+
+// lpcConfig specifies the LPC analysis of an encoder.
+type lpcConfig struct {
+	// Maximum prediction order.
+	maxOrder int
+	// Apodization functions, of which the best performing is selected for each
+	// subframe.
+	windows []window
+	// Evaluate every prediction order.
+	exhaustive bool
+	// Evaluate every precision of quantized coefficients.
+	precisionSearch bool
+	// Elementary functions.
+	m *mathFuncs
+	// Use fixed-point LPC analysis; see analyzeLPCFixed.
+	fixed bool
+	// Fixed-point windows of fixed-point LPC analysis, indexed by block size;
+	// computed on demand.
+	fixedWindows struct {
+		sync.Mutex
+		m map[int][][]int32
+	}
+}
+
+// newLPCConfig returns the LPC analysis configuration of the given encoder
+// options; or nil if LPC analysis is disabled.
+func newLPCConfig(info *meta.StreamInfo, opts *EncodeOptions) (*lpcConfig, error) {
+	if opts.MaxLPCOrder == 0 {
+		return nil, nil
+	}
+	maxOrder := frame.MaxLPCOrder
+	if opts.Subset {
+		maxOrder = frame.SubsetMaxLPCOrderFor(info.SampleRate)
+	}
+	if opts.MaxLPCOrder < 0 || opts.MaxLPCOrder > maxOrder {
+		return nil, fmt.Errorf("invalid maximum LPC order %d; expected >= 0 and <= %d", opts.MaxLPCOrder, maxOrder)
+	}
+	m := stdMath
+	if opts.Reproducible || opts.IntegerLPC {
+		m = portableMath
+	}
+	spec := opts.Apodization
+	if spec == "" {
+		spec = DefaultApodization
+	}
+	windows, err := parseApodization(spec, m)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &lpcConfig{
+		maxOrder:        opts.MaxLPCOrder,
+		windows:         windows,
+		exhaustive:      opts.ExhaustiveModelSearch,
+		precisionSearch: opts.PrecisionSearch,
+		m:               m,
+		fixed:           opts.IntegerLPC,
+	}
+	return cfg, nil
+}
+
+// lpcCandidate is a FIR linear predictor of a subframe.
+type lpcCandidate struct {
+	// Quantized predictor coefficients.
+	coeffs []int32
+	// Coefficients' precision in bits.
+	prec uint
+	// Predictor coefficient shift in bits.
+	shift int32
+	// Rice parameter of the residuals, using a single Rice partition.
+	param uint
+	// Number of bits needed to code the subframe.
+	bits int
+}
+
+// analyzeLPC returns the best FIR linear predictor of the given subframe,
+// evaluating the apodization functions of the given configuration; or false if
+// no FIR linear predictor is applicable.
+//
+// The algorithm follows the LPC analysis of libFLAC:
+//  1. For each window, compute the autocorrelation of the windowed samples.
+//  2. Compute the LPC coefficients of orders 1 through the maximum order using
+//     the Levinson-Durbin recursion, and select the order of fewest expected
+//     bits based on the prediction error; or every order if exhaustive model
+//     search is enabled.
+//  3. Quantize the coefficients of the selected orders, using the precision
+//     selected from the sample size and block size; or every precision if
+//     precision search is enabled. Compute the number of bits needed to code
+//     the residuals.
+//  4. Pick the window, order and precision with the overall fewest bits.
+func analyzeLPC(sf *frame.Subframe, bps uint, cfg *lpcConfig) (*lpcCandidate, bool) {
+	if cfg.fixed {
+		return analyzeLPCFixed(sf, bps, cfg)
+	}
+	samples := sf.Samples
+	n := len(samples)
+	maxOrder := min(cfg.maxOrder, n-1)
+	if maxOrder < 1 {
+		return nil, false
+	}
+	prec := lpcPrecision(bps, n)
+	w := make([]float64, n)
+	data := make([]float64, n)
+	autoc := make([]float64, maxOrder+1)
+	var best *lpcCandidate
+	for _, window := range cfg.windows {
+		window(w)
+		for i, sample := range samples {
+			data[i] = float64(sample) * w[i]
+		}
+		autocorrelation(data, autoc)
+		if autoc[0] == 0 {
+			// Silent window.
+			continue
+		}
+		coeffs, errs := levinsonDurbin(autoc, maxOrder)
+		firstOrder, lastOrder := 1, len(coeffs)
+		if !cfg.exhaustive {
+			firstOrder = lpcOrderEstimate(cfg.m, errs, n, bps+prec)
+			lastOrder = firstOrder
+		}
+		firstPrec, lastPrec := prec, prec
+		if cfg.precisionSearch {
+			firstPrec, lastPrec = minCoeffPrec, frame.MaxCoeffPrec
+		}
+		for order := firstOrder; order <= lastOrder; order++ {
+			for p := firstPrec; p <= lastPrec; p++ {
+				c, ok := quantizeLPC(sf, bps, coeffs[order-1], p)
+				if !ok {
+					continue
+				}
+				if best == nil || c.bits < best.bits {
+					best = c
+				}
+			}
+		}
+	}
+	return best, best != nil
+}
+
+// maxLPCShift is the maximum shift in bits of quantized LPC coefficients. The
+// shift is stored as a 5-bit signed integer; negative shifts are not supported
+// by decoders.
+const maxLPCShift = 1<<4 - 1
+
+// minCoeffPrec is the minimum precision in bits of quantized LPC coefficients
+// evaluated by precision search.
+const minCoeffPrec = 5
+
+// lpcPrecision returns the precision in bits of quantized LPC coefficients of
+// subframes with the given sample size and block size, as selected by libFLAC.
+func lpcPrecision(bps uint, blockSize int) uint {
+	switch {
+	case bps < 16:
+		return max(minCoeffPrec, 2+bps/2)
+	case bps == 16:
+		switch {
+		case blockSize <= 192:
+			return 7
+		case blockSize <= 384:
+			return 8
+		case blockSize <= 576:
+			return 9
+		case blockSize <= 1152:
+			return 10
+		case blockSize <= 2304:
+			return 11
+		case blockSize <= 4608:
+			return 12
+		}
+		return 13
+	}
+	switch {
+	case blockSize <= 384:
+		return frame.MaxCoeffPrec - 2
+	case blockSize <= 1152:
+		return frame.MaxCoeffPrec - 1
+	}
+	return frame.MaxCoeffPrec
+}
+
+// autocorrelation stores the autocorrelation of data for lags 0 through
+// len(autoc)-1 in autoc.
+func autocorrelation(data, autoc []float64) {
+	for lag := range autoc {
+		sum := 0.0
+		for i := lag; i < len(data); i++ {
+			sum += float64(data[i] * data[i-lag])
+		}
+		autoc[lag] = sum
+	}
+}
+
+// levinsonDurbin computes the LPC coefficients of orders 1 through maxOrder
+// from the given autocorrelation, using the Levinson-Durbin recursion. The
+// i:th element of coeffs holds the coefficients of order i+1, and the i:th
+// element of errs holds the prediction error of order i+1. Fewer orders are
+// returned if the prediction error reaches zero.
+func levinsonDurbin(autoc []float64, maxOrder int) (coeffs [][]float64, errs []float64) {
+	lpc := make([]float64, maxOrder)
+	err := autoc[0]
+	for i := 0; i < maxOrder; i++ {
+		// Reflection coefficient.
+		r := -autoc[i+1]
+		for j := 0; j < i; j++ {
+			r -= float64(lpc[j] * autoc[i-j])
+		}
+		r /= err
+		// Update LPC coefficients and prediction error.
+		lpc[i] = r
+		j := 0
+		for ; j < i/2; j++ {
+			tmp := lpc[j]
+			lpc[j] += float64(r * lpc[i-1-j])
+			lpc[i-1-j] += float64(r * tmp)
+		}
+		if i%2 == 1 {
+			lpc[j] += float64(lpc[j] * r)
+		}
+		err *= 1 - float64(r*r)
+		// Store the coefficients of order i+1, negated to predict samples as
+		// the sum of the products of coefficients and preceding samples.
+		c := make([]float64, i+1)
+		for j := range c {
+			c[j] = -lpc[j]
+		}
+		coeffs = append(coeffs, c)
+		errs = append(errs, err)
+		if err == 0 {
+			break
+		}
+	}
+	return coeffs, errs
+}
+
+// lpcOrderEstimate returns the prediction order of fewest expected bits, given
+// the prediction errors of orders 1 through len(errs), the block size and the
+// number of bits per predictor coefficient (including the warm-up sample).
+func lpcOrderEstimate(m *mathFuncs, errs []float64, blockSize int, coeffBits uint) int {
+	errScale := 0.5 / float64(blockSize)
+	bestOrder, bestBits := 1, math.Inf(1)
+	for i, err := range errs {
+		order := i + 1
+		// Expected bits per residual sample.
+		var bps float64
+		switch {
+		case err > 0:
+			bps = max(0.5*m.log2(errScale*err), 0)
+		case err < 0:
+			bps = 1e32
+		}
+		bits := float64(bps*float64(blockSize-order)) + float64(float64(order)*float64(coeffBits))
+		if bits < bestBits {
+			bestOrder, bestBits = order, bits
+		}
+	}
+	return bestOrder
+}
+
+// quantizeLPC quantizes the given LPC coefficients to the given precision in
+// bits, and returns the resulting FIR linear predictor of the subframe; or
+// false if the coefficients cannot be quantized, or the residuals exceed
+// 32-bit signed integers.
+func quantizeLPC(sf *frame.Subframe, bps uint, lpc []float64, prec uint) (*lpcCandidate, bool) {
+	// Precision excluding the sign bit.
+	qmax := int32(1)<<(prec-1) - 1
+	qmin := -qmax - 1
+	cmax := 0.0
+	for _, c := range lpc {
+		cmax = max(cmax, math.Abs(c))
+	}
+	if cmax <= 0 || math.IsInf(cmax, 0) || math.IsNaN(cmax) {
+		return nil, false
+	}
+	_, log2cmax := math.Frexp(cmax)
+	log2cmax--
+	shift := min(int(prec)-1-log2cmax-1, maxLPCShift)
+	if shift < 0 {
+		return nil, false
+	}
+	coeffs := make([]int32, len(lpc))
+	// Carry the quantization error to the next coefficient.
+	qerr := 0.0
+	for i, c := range lpc {
+		qerr += float64(c * float64(int(1)<<shift))
+		q := int32(math.Round(qerr))
+		q = min(max(q, qmin), qmax)
+		qerr -= float64(q)
+		coeffs[i] = q
+	}
+	return newLPCCandidate(sf, bps, coeffs, prec, shift)
+}
+
+// newLPCCandidate returns the FIR linear predictor of the subframe with the
+// given quantized coefficients, precision and shift; or false if the residuals
+// exceed 32-bit signed integers.
+func newLPCCandidate(sf *frame.Subframe, bps uint, coeffs []int32, prec uint, shift int) (*lpcCandidate, bool) {
+	residuals, ok := lpcResiduals(sf.Samples, coeffs, shift)
+	if !ok {
+		return nil, false
+	}
+	order := len(coeffs)
+	k := chooseRice(residuals)
+	c := &lpcCandidate{
+		coeffs: coeffs,
+		prec:   prec,
+		shift:  int32(shift),
+		param:  k,
+		// Share the cost model of fixed prediction, adding the bits of the
+		// coefficient precision, shift and coefficients.
+		bits: costFixed(order, bps, residuals, k) + 4 + 5 + order*int(prec),
+	}
+	return c, true
+}
+
+// lpcResiduals returns the residuals of the given samples using the given
+// quantized LPC coefficients and shift; or false if a residual exceeds a
+// 32-bit signed integer.
+func lpcResiduals(samples, coeffs []int32, shift int) ([]int32, bool) {
+	order := len(coeffs)
+	residuals := make([]int32, 0, len(samples)-order)
+	for i := order; i < len(samples); i++ {
+		var sum int64
+		for j, c := range coeffs {
+			sum += int64(c) * int64(samples[i-j-1])
+		}
+		residual := int64(samples[i]) - sum>>shift
+		if residual < math.MinInt32 || residual > math.MaxInt32 {
+			return nil, false
+		}
+		residuals = append(residuals, int32(residual))
+	}
+	return residuals, true
+}
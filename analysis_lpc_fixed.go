@@ -0,0 +1,295 @@
+package flac
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// Fractional bits of the fixed-point values of fixed-point LPC analysis.
+const (
+	// Fractional bits of window values.
+	windowFracBits = 15
+	// Fractional bits of normalized autocorrelation values, LPC coefficients and
+	// normalized prediction errors.
+	lpcFracBits = 30
+	// Fractional bits of logarithms and bit counts of order estimation.
+	log2FracBits = 16
+)
+
+// maxFixedWindowSizes is the maximum number of block sizes of which fixed-point
+// windows are cached; bounding the memory usage of variable block size
+// streams.
+const maxFixedWindowSizes = 8
+
+// windowsFixed returns the fixed-point windows of the given block size, of each
+// apodization function of the configuration. The windows are computed using the
+// portable elementary functions and quantized on first use, and cached for
+// subsequent subframes of the same block size.
+func (cfg *lpcConfig) windowsFixed(n int) [][]int32 {
+	cfg.fixedWindows.Lock()
+	defer cfg.fixedWindows.Unlock()
+	if ws, ok := cfg.fixedWindows.m[n]; ok {
+		return ws
+	}
+	if cfg.fixedWindows.m == nil || len(cfg.fixedWindows.m) >= maxFixedWindowSizes {
+		cfg.fixedWindows.m = make(map[int][][]int32)
+	}
+	w := make([]float64, n)
+	ws := make([][]int32, len(cfg.windows))
+	for i, window := range cfg.windows {
+		window(w)
+		ws[i] = make([]int32, n)
+		for j, x := range w {
+			ws[i][j] = int32(math.Round(x * (1 << windowFracBits)))
+		}
+	}
+	cfg.fixedWindows.m[n] = ws
+	return ws
+}
+
+// analyzeLPCFixed returns the best FIR linear predictor of the given subframe,
+// as done by analyzeLPC; using fixed-point integer arithmetic only, once the
+// windows of the block size have been computed.
+func analyzeLPCFixed(sf *frame.Subframe, bps uint, cfg *lpcConfig) (*lpcCandidate, bool) {
+	samples := sf.Samples
+	n := len(samples)
+	maxOrder := min(cfg.maxOrder, n-1)
+	if maxOrder < 1 {
+		return nil, false
+	}
+	prec := lpcPrecision(bps, n)
+	data := make([]int64, n)
+	autoc := make([]int64, maxOrder+1)
+	var best *lpcCandidate
+	for _, w := range cfg.windowsFixed(n) {
+		for i, sample := range samples {
+			data[i] = int64(sample) * int64(w[i]) >> windowFracBits
+		}
+		scale := autocorrelationFixed(data, autoc)
+		if autoc[0] == 0 {
+			// Silent window.
+			continue
+		}
+		coeffs, errs := levinsonDurbinFixed(autoc, maxOrder)
+		if len(coeffs) == 0 {
+			continue
+		}
+		firstOrder, lastOrder := 1, len(coeffs)
+		if !cfg.exhaustive {
+			// Logarithm of the scale of the normalized prediction errors; i.e. of
+			// the unscaled autocorrelation at lag 0.
+			log2Scale := log2Fixed(uint64(autoc[0])) + int64(2*scale)<<log2FracBits
+			firstOrder = lpcOrderEstimateFixed(errs, log2Scale, n, bps+prec)
+			lastOrder = firstOrder
+		}
+		firstPrec, lastPrec := prec, prec
+		if cfg.precisionSearch {
+			firstPrec, lastPrec = minCoeffPrec, frame.MaxCoeffPrec
+		}
+		for order := firstOrder; order <= lastOrder; order++ {
+			for p := firstPrec; p <= lastPrec; p++ {
+				c, ok := quantizeLPCFixed(sf, bps, coeffs[order-1], p)
+				if !ok {
+					continue
+				}
+				if best == nil || c.bits < best.bits {
+					best = c
+				}
+			}
+		}
+	}
+	return best, best != nil
+}
+
+// autocorrelationFixed stores the autocorrelation of data for lags 0 through
+// len(autoc)-1 in autoc. The data is scaled down by 2^scale before computing
+// the autocorrelation, so that the sums fit in 64-bit signed integers; the
+// scale is returned, and data is overwritten with the scaled data.
+func autocorrelationFixed(data, autoc []int64) (scale int) {
+	var maxAbs uint64
+	for _, x := range data {
+		maxAbs = max(maxAbs, uint64(abs64(x)))
+	}
+	// Each sum holds at most len(data) products of at most 2^(2*sampleBits).
+	sampleBits := bits.Len64(maxAbs)
+	lenBits := bits.Len(uint(len(data)))
+	if excess := 2*sampleBits + lenBits - 62; excess > 0 {
+		scale = (excess + 1) / 2
+		for i := range data {
+			data[i] >>= scale
+		}
+	}
+	for lag := range autoc {
+		var sum int64
+		for i := lag; i < len(data); i++ {
+			sum += data[i] * data[i-lag]
+		}
+		autoc[lag] = sum
+	}
+	return scale
+}
+
+// levinsonDurbinFixed computes the LPC coefficients of orders 1 through
+// maxOrder from the given autocorrelation, using the Levinson-Durbin recursion
+// in fixed-point arithmetic, as done by levinsonDurbin. The coefficients and
+// prediction errors have lpcFracBits fractional bits, and the prediction errors
+// are normalized to the autocorrelation at lag 0. Fewer orders are returned if
+// the prediction error reaches zero, or the recursion becomes numerically
+// unstable.
+func levinsonDurbinFixed(autoc []int64, maxOrder int) (coeffs [][]int64, errs []int64) {
+	const one = 1 << lpcFracBits
+	// Normalize the autocorrelation to the lag 0 value, limiting the lag 0 value
+	// to 31 bits so that the shifted values fit in 64-bit signed integers.
+	shift := max(bits.Len64(uint64(autoc[0]))-31, 0)
+	r0 := autoc[0] >> shift
+	r := make([]int64, len(autoc))
+	for i, x := range autoc {
+		r[i] = (x >> shift << lpcFracBits) / r0
+	}
+	lpc := make([]int64, maxOrder)
+	err := int64(one)
+	for i := 0; i < maxOrder; i++ {
+		// Reflection coefficient.
+		num := -r[i+1]
+		for j := 0; j < i; j++ {
+			num -= mulFixed(lpc[j], r[i-j])
+		}
+		// The magnitude of reflection coefficients is below 1 for positive
+		// definite autocorrelations; stop if rounding errors break the bound.
+		if abs64(num) >= err {
+			break
+		}
+		k := num << lpcFracBits / err
+		// Update LPC coefficients and prediction error.
+		lpc[i] = k
+		j := 0
+		for ; j < i/2; j++ {
+			tmp := lpc[j]
+			lpc[j] += mulFixed(k, lpc[i-1-j])
+			lpc[i-1-j] += mulFixed(k, tmp)
+		}
+		if i%2 == 1 {
+			lpc[j] += mulFixed(lpc[j], k)
+		}
+		err = mulFixed(err, one-mulFixed(k, k))
+		// Store the coefficients of order i+1, negated to predict samples as
+		// the sum of the products of coefficients and preceding samples.
+		c := make([]int64, i+1)
+		for j := range c {
+			c[j] = -lpc[j]
+		}
+		coeffs = append(coeffs, c)
+		errs = append(errs, err)
+		if err <= 0 {
+			break
+		}
+	}
+	return coeffs, errs
+}
+
+// lpcOrderEstimateFixed returns the prediction order of fewest expected bits,
+// as done by lpcOrderEstimate; given the normalized prediction errors of orders
+// 1 through len(errs), the base 2 logarithm of their scale (with log2FracBits
+// fractional bits), the block size and the number of bits per predictor
+// coefficient.
+func lpcOrderEstimateFixed(errs []int64, log2Scale int64, blockSize int, coeffBits uint) int {
+	// Logarithm of the error scale of lpcOrderEstimate; 0.5/blockSize, relative
+	// to the fractional bits of the normalized prediction errors.
+	log2ErrScale := log2Scale - log2Fixed(uint64(blockSize)) - (1+lpcFracBits)<<log2FracBits
+	bestOrder, bestBits := 1, int64(math.MaxInt64)
+	for i, err := range errs {
+		order := i + 1
+		// Expected bits per residual sample.
+		var bps int64
+		if err > 0 {
+			bps = max((log2ErrScale+log2Fixed(uint64(err)))/2, 0)
+		}
+		bits := bps*int64(blockSize-order) + int64(order)*int64(coeffBits)<<log2FracBits
+		if bits < bestBits {
+			bestOrder, bestBits = order, bits
+		}
+	}
+	return bestOrder
+}
+
+// quantizeLPCFixed quantizes the given fixed-point LPC coefficients to the
+// given precision in bits, as done by quantizeLPC.
+func quantizeLPCFixed(sf *frame.Subframe, bps uint, lpc []int64, prec uint) (*lpcCandidate, bool) {
+	// Precision excluding the sign bit.
+	qmax := int64(1)<<(prec-1) - 1
+	qmin := -qmax - 1
+	var cmax int64
+	for _, c := range lpc {
+		cmax = max(cmax, abs64(c))
+	}
+	if cmax <= 0 {
+		return nil, false
+	}
+	log2cmax := bits.Len64(uint64(cmax)) - 1 - lpcFracBits
+	shift := min(int(prec)-1-log2cmax-1, maxLPCShift)
+	if shift < 0 {
+		return nil, false
+	}
+	coeffs := make([]int32, len(lpc))
+	// Carry the quantization error to the next coefficient.
+	const half = 1 << (lpcFracBits - 1)
+	var qerr int64
+	for i, c := range lpc {
+		qerr += c << shift
+		// Round half away from zero, as done by math.Round.
+		var q int64
+		if qerr >= 0 {
+			q = (qerr + half) >> lpcFracBits
+		} else {
+			q = -((-qerr + half) >> lpcFracBits)
+		}
+		q = min(max(q, qmin), qmax)
+		qerr -= q << lpcFracBits
+		coeffs[i] = int32(q)
+	}
+	return newLPCCandidate(sf, bps, coeffs, prec, shift)
+}
+
+// mulFixed returns the product of the given fixed-point values, of lpcFracBits
+// fractional bits; rounded toward zero.
+func mulFixed(x, y int64) int64 {
+	hi, lo := bits.Mul64(uint64(abs64(x)), uint64(abs64(y)))
+	z := int64(hi<<(64-lpcFracBits) | lo>>lpcFracBits)
+	if (x < 0) != (y < 0) {
+		return -z
+	}
+	return z
+}
+
+// log2Fixed returns the base 2 logarithm of x, with log2FracBits fractional
+// bits; rounded down. x must be positive.
+func log2Fixed(x uint64) int64 {
+	e := bits.Len64(x) - 1
+	// Mantissa in [1, 2), with 31 fractional bits.
+	var m uint64
+	if e >= 31 {
+		m = x >> (e - 31)
+	} else {
+		m = x << (31 - e)
+	}
+	y := int64(e) << log2FracBits
+	// Compute the fractional bits by repeated squaring of the mantissa.
+	for i := log2FracBits - 1; i >= 0; i-- {
+		m = m * m >> 31
+		if m >= 1<<32 {
+			m >>= 1
+			y |= 1 << i
+		}
+	}
+	return y
+}
+
+// abs64 returns the absolute value of x.
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
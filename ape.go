@@ -0,0 +1,309 @@
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// An APETag is an APEv2 tag, as appended to FLAC files by some rippers and
+// taggers. APEv2 tags are not part of the FLAC format, and confuse strict
+// decoders; see ReadAPETrailer, APETag.MigrateTo and StripAPETag.
+//
+// ref: https://wiki.hydrogenaud.io/index.php?title=APEv2_specification
+type APETag struct {
+	// Version of the tag; 2000 for APEv2, and 1000 for APEv1.
+	Version uint32
+	// Items of the tag, in order of appearance.
+	Items []APEItem
+}
+
+// An APEItem is an item of an APEv2 tag.
+type APEItem struct {
+	// Key of the item; e.g. "Artist". Keys are compared case-insensitively.
+	Key string
+	// Type of the item value.
+	Type APEItemType
+	// Specifies whether the item is marked read-only.
+	ReadOnly bool
+	// Value of the item. The values of text items are UTF-8 encoded, and
+	// multiple values are separated by NUL characters.
+	Value []byte
+}
+
+// Values returns the values of the text item; or nil if the item is not a text
+// item.
+func (item *APEItem) Values() []string {
+	if item.Type != APEText {
+		return nil
+	}
+	return strings.Split(string(item.Value), "\x00")
+}
+
+// APEItemType specifies the type of the value of an APEv2 item.
+type APEItemType uint8
+
+// APEv2 item types.
+const (
+	// APEText is a UTF-8 encoded text value.
+	APEText APEItemType = 0
+	// APEBinary is a binary value; e.g. cover art.
+	APEBinary APEItemType = 1
+	// APELocator is a UTF-8 encoded locator of external data; e.g. a URL.
+	APELocator APEItemType = 2
+)
+
+// String returns the string representation of the item type.
+func (typ APEItemType) String() string {
+	switch typ {
+	case APEText:
+		return "text"
+	case APEBinary:
+		return "binary"
+	case APELocator:
+		return "locator"
+	}
+	return fmt.Sprintf("APEItemType(%d)", uint8(typ))
+}
+
+// Flags of APEv2 headers, footers and items.
+const (
+	// apeHasHeader is the flag of tags containing a header.
+	apeHasHeader = 1 << 31
+	// apeReadOnly is the flag of read-only items.
+	apeReadOnly = 1 << 0
+)
+
+// ErrInvalidAPETag reports that an APEv2 tag is malformed.
+var ErrInvalidAPETag = errors.New("invalid APEv2 tag")
+
+// Get returns the values of the first text item with the given key, compared
+// case-insensitively; or nil if not present.
+func (tag *APETag) Get(key string) []string {
+	for i := range tag.Items {
+		item := &tag.Items[i]
+		if item.Type == APEText && strings.EqualFold(item.Key, key) {
+			return item.Values()
+		}
+	}
+	return nil
+}
+
+// ParseAPETag parses the given APEv2 tag, which ends with the tag footer and
+// optionally starts with the tag header; e.g. the data of a Trailer of kind
+// TrailerAPE.
+func ParseAPETag(data []byte) (*APETag, error) {
+	tag, err := parseAPETag(data)
+	if err != nil {
+		return nil, fmt.Errorf("flac.ParseAPETag: %w", err)
+	}
+	return tag, nil
+}
+
+// parseAPETag parses the given APEv2 tag. See ParseAPETag for details.
+func parseAPETag(data []byte) (*APETag, error) {
+	if len(data) < apeHeaderSize {
+		return nil, fmt.Errorf("%w; size (%d) below footer size", ErrInvalidAPETag, len(data))
+	}
+	footer := data[len(data)-apeHeaderSize:]
+	if !bytes.HasPrefix(footer, apeSignature) {
+		return nil, fmt.Errorf("%w; missing footer", ErrInvalidAPETag)
+	}
+	le := binary.LittleEndian
+	// The tag size includes the items and the footer, but not the header.
+	tagSize := int64(le.Uint32(footer[12:]))
+	nitems := le.Uint32(footer[16:])
+	flags := le.Uint32(footer[20:])
+	size := tagSize
+	if flags&apeHasHeader != 0 {
+		size += apeHeaderSize
+	}
+	if tagSize < apeHeaderSize || size != int64(len(data)) {
+		return nil, fmt.Errorf("%w; tag size (%d) differs from data size (%d)", ErrInvalidAPETag, size, len(data))
+	}
+	tag := &APETag{Version: le.Uint32(footer[8:])}
+	items := data[len(data)-int(tagSize) : len(data)-apeHeaderSize]
+	for i := uint32(0); i < nitems; i++ {
+		// 4 bytes: value size; 4 bytes: item flags; NUL-terminated key.
+		if len(items) < 8 {
+			return nil, fmt.Errorf("%w; item %d truncated", ErrInvalidAPETag, i)
+		}
+		// The value size is validated before conversion to int, which may
+		// overflow on 32-bit platforms.
+		n := le.Uint32(items)
+		itemFlags := le.Uint32(items[4:])
+		items = items[8:]
+		keySize := bytes.IndexByte(items, 0)
+		if keySize == -1 || uint64(n) > uint64(len(items)-keySize-1) {
+			return nil, fmt.Errorf("%w; item %d truncated", ErrInvalidAPETag, i)
+		}
+		valueSize := int(n)
+		key := string(items[:keySize])
+		for j := 0; j < len(key); j++ {
+			if key[j] < 0x20 || key[j] > 0x7E {
+				return nil, fmt.Errorf("%w; invalid character 0x%02X in key of item %d", ErrInvalidAPETag, key[j], i)
+			}
+		}
+		items = items[keySize+1:]
+		tag.Items = append(tag.Items, APEItem{
+			Key:      key,
+			Type:     APEItemType(itemFlags >> 1 & 0x3),
+			ReadOnly: itemFlags&apeReadOnly != 0,
+			Value:    items[:valueSize:valueSize],
+		})
+		items = items[valueSize:]
+	}
+	return tag, nil
+}
+
+// ReadAPETrailer locates the APEv2 tag appended to the FLAC file of ra, of the
+// given size in bytes, by its footer at the end of the file or preceding an
+// ID3v1 tag; and returns the tag as a Trailer of kind TrailerAPE. It returns nil
+// if no APEv2 tag is present.
+//
+// Unlike Stream.Trailers, which locates tags starting with a tag header while
+// decoding, the audio frames are not decoded; as such, tags without a header
+// (e.g. APEv1 tags) are located as well.
+func ReadAPETrailer(ra io.ReaderAt, size int64) (*Trailer, error) {
+	end := size
+	if size >= id3v1Size {
+		buf := make([]byte, len(id3v1Signature))
+		if _, err := ra.ReadAt(buf, size-id3v1Size); err != nil {
+			return nil, err
+		}
+		if bytes.Equal(buf, id3v1Signature) {
+			end -= id3v1Size
+		}
+	}
+	if end < apeHeaderSize {
+		return nil, nil
+	}
+	footer := make([]byte, apeHeaderSize)
+	if _, err := ra.ReadAt(footer, end-apeHeaderSize); err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(footer, apeSignature) {
+		return nil, nil
+	}
+	tagSize := int64(binary.LittleEndian.Uint32(footer[12:]))
+	flags := binary.LittleEndian.Uint32(footer[20:])
+	if flags&apeIsHeader != 0 || tagSize < apeHeaderSize || tagSize > maxAPETagSize {
+		return nil, fmt.Errorf("flac.ReadAPETrailer: %w; invalid footer", ErrInvalidAPETag)
+	}
+	if flags&apeHasHeader != 0 {
+		tagSize += apeHeaderSize
+	}
+	if tagSize > end {
+		return nil, fmt.Errorf("flac.ReadAPETrailer: %w; tag size (%d) exceeds file size", ErrInvalidAPETag, tagSize)
+	}
+	data := make([]byte, tagSize)
+	if _, err := ra.ReadAt(data, end-tagSize); err != nil {
+		return nil, err
+	}
+	return &Trailer{Kind: TrailerAPE, Offset: end - tagSize, Data: data}, nil
+}
+
+// MigrateTo adds the text items of the APEv2 tag to the given Vorbis comment,
+// and returns the number of tags added. Keys are mapped to Vorbis comment field
+// names as done by meta.CanonicalTagName with meta.DefaultTagAliases; e.g.
+// "Year" to DATE and "Track" to TRACKNUMBER. Track and disc numbers of the form
+// "3/12" are split into a number and a total (e.g. TRACKTOTAL).
+//
+// Fields already present in the Vorbis comment are retained, as the Vorbis
+// comment is the native tag of FLAC files. Binary items (e.g. cover art),
+// locator items, and items whose keys are not valid field names are skipped.
+func (tag *APETag) MigrateTo(comment *meta.VorbisComment) int {
+	// Fields present prior to the migration.
+	present := make(map[string]bool)
+	for _, t := range comment.Tags {
+		present[strings.ToUpper(t[0])] = true
+	}
+	nadded := 0
+	add := func(name, value string) {
+		if present[name] || value == "" {
+			return
+		}
+		comment.Tags = append(comment.Tags, [2]string{name, value})
+		nadded++
+	}
+	for i := range tag.Items {
+		item := &tag.Items[i]
+		if item.Type != APEText || strings.ContainsAny(item.Key, "=~") {
+			continue
+		}
+		name := meta.CanonicalTagName(item.Key, meta.DefaultTagAliases)
+		values := item.Values()
+		var totals []string
+		switch name {
+		case "TRACKNUMBER", "DISCNUMBER":
+			for j, value := range values {
+				if number, total, ok := strings.Cut(value, "/"); ok {
+					values[j] = strings.TrimSpace(number)
+					totals = append(totals, strings.TrimSpace(total))
+				}
+			}
+		}
+		for _, value := range values {
+			add(name, value)
+		}
+		for _, total := range totals {
+			add(strings.TrimSuffix(name, "NUMBER")+"TOTAL", total)
+		}
+	}
+	return nadded
+}
+
+// StripAPETag rewrites the FLAC file at src to dst without its APEv2 tag, as
+// located by ReadAPETrailer, and returns the tag; or returns nil without writing
+// dst if the file has no APEv2 tag. If migrate is set, the text items of the
+// tag are migrated into the Vorbis comment of the file as done by
+// APETag.MigrateTo, adding a VorbisComment metadata block if not present.
+// Prepended ID3v2 data and a trailing ID3v1 tag are retained. dst may equal
+// src, in which case the file is replaced atomically.
+func StripAPETag(dst, src string, migrate bool) (*APETag, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	trailer, err := ReadAPETrailer(bytes.NewReader(data), int64(len(data)))
+	if err != nil || trailer == nil {
+		return nil, err
+	}
+	tag, err := parseAPETag(trailer.Data)
+	if err != nil {
+		return nil, fmt.Errorf("flac.StripAPETag: %w", err)
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	if migrate {
+		stream, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		blocks := withComment(stream.Blocks, func(comment *meta.VorbisComment) {
+			comment.Tags = slices.Clip(comment.Tags)
+			tag.MigrateTo(comment)
+		})
+		hdr, err := MarshalMetadata(stream.Info, blocks...)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data[:id3v2Size(data)])
+		buf.Write(hdr)
+		buf.Write(data[stream.DataStart():trailer.Offset])
+	} else {
+		buf.Write(data[:trailer.Offset])
+	}
+	buf.Write(data[trailer.Offset+int64(len(trailer.Data)):])
+	if err := writeFileAtomic(dst, buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
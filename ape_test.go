@@ -0,0 +1,148 @@
+package flac_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestAPETag(t *testing.T) {
+	orig, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := [][2]string{
+		{"Artist", "Love"},
+		{"Year", "2001"},
+		{"Track", "3/12"},
+		{"Genre", "Rock\x00Pop"},
+	}
+	id3v1 := append([]byte("TAG"), make([]byte, 125)...)
+	for _, hasHeader := range []bool{false, true} {
+		ape := apeTag(hasHeader, items...)
+		data := slices.Concat(orig, ape, id3v1)
+
+		// Locate tag.
+		trailer, err := flac.ReadAPETrailer(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("hasHeader=%v: unable to locate APEv2 tag; %v", hasHeader, err)
+		}
+		if trailer == nil || trailer.Kind != flac.TrailerAPE || trailer.Offset != int64(len(orig)) || !bytes.Equal(trailer.Data, ape) {
+			t.Fatalf("hasHeader=%v: APEv2 tag mismatch; got %+v", hasHeader, trailer)
+		}
+
+		// Parse tag.
+		tag, err := flac.ParseAPETag(trailer.Data)
+		if err != nil {
+			t.Fatalf("hasHeader=%v: unable to parse APEv2 tag; %v", hasHeader, err)
+		}
+		if tag.Version != 2000 || len(tag.Items) != len(items) {
+			t.Errorf("hasHeader=%v: expected %d items of version 2000, got %d items of version %d", hasHeader, len(items), len(tag.Items), tag.Version)
+		}
+		if got, want := tag.Get("GENRE"), []string{"Rock", "Pop"}; !slices.Equal(got, want) {
+			t.Errorf("hasHeader=%v: genre mismatch; expected %q, got %q", hasHeader, want, got)
+		}
+	}
+
+	// Migrate tag; fields present in the Vorbis comment are retained.
+	tag, err := flac.ParseAPETag(apeTag(true, items...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := &meta.VorbisComment{Tags: [][2]string{{"artist", "Arthur Lee"}}}
+	if n := tag.MigrateTo(comment); n != 5 {
+		t.Errorf("number of migrated tags mismatch; expected 5, got %d", n)
+	}
+	want := [][2]string{
+		{"artist", "Arthur Lee"},
+		{"DATE", "2001"},
+		{"TRACKNUMBER", "3"},
+		{"TRACKTOTAL", "12"},
+		{"GENRE", "Rock"},
+		{"GENRE", "Pop"},
+	}
+	if !slices.Equal(comment.Tags, want) {
+		t.Errorf("migrated tags mismatch; expected %q, got %q", want, comment.Tags)
+	}
+
+	// Malformed tag.
+	ape := apeTag(true, items...)
+	if _, err := flac.ParseAPETag(ape[:len(ape)-40]); !errors.Is(err, flac.ErrInvalidAPETag) {
+		t.Errorf("expected ErrInvalidAPETag for malformed tag, got %v", err)
+	}
+	// Value size exceeding the range of int on 32-bit platforms.
+	ape = apeTag(true, items...)
+	binary.LittleEndian.PutUint32(ape[32:], 0xFFFFFFFF)
+	if _, err := flac.ParseAPETag(ape); !errors.Is(err, flac.ErrInvalidAPETag) {
+		t.Errorf("expected ErrInvalidAPETag for oversized item value, got %v", err)
+	}
+}
+
+func TestStripAPETag(t *testing.T) {
+	orig, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id3v1 := append([]byte("TAG"), make([]byte, 125)...)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.flac")
+	if err := os.WriteFile(src, slices.Concat(orig, apeTag(true, [2]string{"Artist", "Love"}), id3v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Strip tag.
+	dst := filepath.Join(dir, "stripped.flac")
+	tag, err := flac.StripAPETag(dst, src, false)
+	if err != nil {
+		t.Fatalf("unable to strip APEv2 tag; %v", err)
+	}
+	if got := tag.Get("Artist"); !slices.Equal(got, []string{"Love"}) {
+		t.Errorf("artist mismatch of stripped tag; got %q", got)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, slices.Concat(orig, id3v1)) {
+		t.Errorf("content mismatch of stripped file")
+	}
+
+	// Files without APEv2 tags are not written.
+	if tag, err := flac.StripAPETag(filepath.Join(dir, "none.flac"), dst, false); tag != nil || err != nil {
+		t.Errorf("expected no APEv2 tag, got %v; %v", tag, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "none.flac")); !os.IsNotExist(err) {
+		t.Errorf("expected file without APEv2 tag not to be written; %v", err)
+	}
+
+	// Migrate and strip tag in place.
+	if _, err := flac.StripAPETag(src, src, true); err != nil {
+		t.Fatalf("unable to migrate APEv2 tag; %v", err)
+	}
+	stream, err := flac.ParseFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	comment := stream.Blocks.VorbisComment()
+	if comment == nil {
+		t.Fatal("expected VorbisComment block of migrated tag")
+	}
+	if got, _ := comment.Get("ARTIST"); got != "Love" {
+		t.Errorf("artist mismatch of migrated tag; got %q", got)
+	}
+	got, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origStream, err := flac.New(bytes.NewReader(orig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got[stream.DataStart():], slices.Concat(orig[origStream.DataStart():], id3v1)) {
+		t.Errorf("audio frames mismatch of migrated file")
+	}
+}
@@ -0,0 +1,331 @@
+package flac
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultApodization is the apodization of LPC analysis used if
+// EncodeOptions.Apodization is not specified.
+const DefaultApodization = "tukey(0.5)"
+
+// A window is an apodization (windowing) function of LPC analysis, which stores
+// the window of len(w) samples in w.
+type window func(w []float64)
+
+// parseApodization parses the given apodization specification, a list of
+// window functions separated by semicolons; using the syntax of the -A option
+// of the reference encoder. The window functions use the given elementary
+// functions.
+//
+// Supported window functions:
+//
+//	bartlett
+//	bartlett_hann
+//	blackman
+//	blackman_harris_4term_92db
+//	connes
+//	flattop
+//	gauss(STDDEV)
+//	hamming
+//	hann
+//	kaiser_bessel
+//	nuttall
+//	rectangle
+//	triangle
+//	tukey(P)
+//	partial_tukey(n[/ov[/P]])
+//	punchout_tukey(n[/ov[/P]])
+//	welch
+//
+// The partial_tukey and punchout_tukey functions expand into n windows each.
+func parseApodization(spec string, m *mathFuncs) ([]window, error) {
+	var windows []window
+	for _, s := range strings.Split(spec, ";") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		name, args := s, ""
+		if i := strings.IndexByte(s, '('); i != -1 {
+			if !strings.HasSuffix(s, ")") {
+				return nil, fmt.Errorf("invalid window function %q; missing closing parenthesis", s)
+			}
+			name, args = s[:i], s[i+1:len(s)-1]
+		}
+		params, err := parseWindowParams(args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters of window function %q; %v", s, err)
+		}
+		// param returns the i:th parameter, or def if not present.
+		param := func(i int, def float64) float64 {
+			if i < len(params) {
+				return params[i]
+			}
+			return def
+		}
+		nparams := 0
+		switch name {
+		case "bartlett":
+			windows = append(windows, bartlettWindow)
+		case "bartlett_hann":
+			windows = append(windows, bartlettHannWindow(m))
+		case "blackman":
+			windows = append(windows, cosineWindow(m, 0.42, -0.5, 0.08))
+		case "blackman_harris_4term_92db":
+			windows = append(windows, cosineWindow(m, 0.35875, -0.48829, 0.14128, -0.01168))
+		case "connes":
+			windows = append(windows, connesWindow)
+		case "flattop":
+			windows = append(windows, cosineWindow(m, 0.21557895, -0.41663158, 0.277263158, -0.083578947, 0.006947368))
+		case "gauss":
+			nparams = 1
+			stddev := param(0, 0.25)
+			if stddev <= 0 || stddev > 0.5 {
+				return nil, fmt.Errorf("invalid standard deviation %v of window function %q; expected > 0 and <= 0.5", stddev, s)
+			}
+			windows = append(windows, gaussWindow(m, stddev))
+		case "hamming":
+			windows = append(windows, cosineWindow(m, 0.54, -0.46))
+		case "hann":
+			windows = append(windows, cosineWindow(m, 0.5, -0.5))
+		case "kaiser_bessel":
+			windows = append(windows, cosineWindow(m, 0.402, -0.498, 0.098, -0.001))
+		case "nuttall":
+			windows = append(windows, cosineWindow(m, 0.3635819, -0.4891775, 0.1365995, -0.0106411))
+		case "rectangle":
+			windows = append(windows, rectangleWindow)
+		case "triangle":
+			windows = append(windows, triangleWindow)
+		case "tukey":
+			nparams = 1
+			p := param(0, 0.5)
+			if p < 0 || p > 1 {
+				return nil, fmt.Errorf("invalid parameter %v of window function %q; expected >= 0 and <= 1", p, s)
+			}
+			windows = append(windows, tukeyWindow(m, p))
+		case "partial_tukey", "punchout_tukey":
+			nparams = 3
+			if len(params) == 0 {
+				return nil, fmt.Errorf("missing number of parts of window function %q", s)
+			}
+			nparts := int(params[0])
+			overlap := min(param(1, 0.1), 0.99)
+			p := param(2, 0.2)
+			if nparts < 1 || float64(nparts) != params[0] || overlap < 0 || p < 0 || p > 1 {
+				return nil, fmt.Errorf("invalid parameters of window function %q", s)
+			}
+			if nparts == 1 {
+				windows = append(windows, tukeyWindow(m, p))
+				break
+			}
+			// Overlap of adjacent parts, in units of parts.
+			overlapUnits := 1/(1-overlap) - 1
+			for i := 0; i < nparts; i++ {
+				start := float64(i) / (float64(nparts) + overlapUnits)
+				end := (float64(i) + 1 + overlapUnits) / (float64(nparts) + overlapUnits)
+				if name == "partial_tukey" {
+					windows = append(windows, partialTukeyWindow(m, p, start, end))
+				} else {
+					windows = append(windows, punchoutTukeyWindow(m, p, start, end))
+				}
+			}
+		case "welch":
+			windows = append(windows, welchWindow)
+		default:
+			return nil, fmt.Errorf("unknown window function %q", name)
+		}
+		if len(params) > nparams {
+			return nil, fmt.Errorf("too many parameters of window function %q; expected at most %d", s, nparams)
+		}
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no window functions in apodization %q", spec)
+	}
+	return windows, nil
+}
+
+// parseWindowParams parses the given parameters of a window function, separated
+// by slashes.
+func parseWindowParams(args string) ([]float64, error) {
+	if args == "" {
+		return nil, nil
+	}
+	var params []float64
+	for _, arg := range strings.Split(args, "/") {
+		x, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, x)
+	}
+	return params, nil
+}
+
+// cosineWindow returns a generalized cosine window with the given coefficients;
+// w[n] = a[0] + a[1]*cos(2πn/(N-1)) + a[2]*cos(4πn/(N-1)) + ...
+func cosineWindow(m *mathFuncs, a ...float64) window {
+	return func(w []float64) {
+		n1 := float64(len(w) - 1)
+		for n := range w {
+			x := 0.0
+			for k, ak := range a {
+				x += float64(ak * m.cos(2*math.Pi*float64(k)*float64(n)/n1))
+			}
+			w[n] = x
+		}
+	}
+}
+
+// raisedCosine returns 0.5 - 0.5*cos(x), the taper of Hann and Tukey windows.
+func raisedCosine(m *mathFuncs, x float64) float64 {
+	return 0.5 - float64(0.5*m.cos(x))
+}
+
+// rectangleWindow stores a rectangular window in w.
+func rectangleWindow(w []float64) {
+	for n := range w {
+		w[n] = 1
+	}
+}
+
+// bartlettWindow stores a Bartlett window in w.
+func bartlettWindow(w []float64) {
+	n1 := float64(len(w) - 1)
+	for n := range w {
+		w[n] = 1 - math.Abs(2*float64(n)/n1-1)
+	}
+}
+
+// bartlettHannWindow returns a Bartlett-Hann window.
+func bartlettHannWindow(m *mathFuncs) window {
+	return func(w []float64) {
+		n1 := float64(len(w) - 1)
+		for n := range w {
+			x := float64(n)/n1 - 0.5
+			w[n] = 0.62 - float64(0.48*math.Abs(x)) - float64(0.38*m.cos(2*math.Pi*float64(n)/n1))
+		}
+	}
+}
+
+// connesWindow stores a Connes window in w.
+func connesWindow(w []float64) {
+	n2 := float64(len(w)-1) / 2
+	for n := range w {
+		k := (float64(n) - n2) / n2
+		k = 1 - float64(k*k)
+		w[n] = k * k
+	}
+}
+
+// gaussWindow returns a Gaussian window with the given standard deviation,
+// relative to half the window length.
+func gaussWindow(m *mathFuncs, stddev float64) window {
+	return func(w []float64) {
+		n2 := float64(len(w)-1) / 2
+		for n := range w {
+			k := (float64(n) - n2) / (stddev * n2)
+			w[n] = m.exp(-0.5 * k * k)
+		}
+	}
+}
+
+// triangleWindow stores a triangular window in w.
+func triangleWindow(w []float64) {
+	l := float64(len(w))
+	for n := range w {
+		w[n] = 1 - math.Abs(float64(2*float64(n))+1-l)/(l+1)
+	}
+}
+
+// welchWindow stores a Welch window in w.
+func welchWindow(w []float64) {
+	n2 := float64(len(w)-1) / 2
+	for n := range w {
+		k := (float64(n) - n2) / n2
+		w[n] = 1 - float64(k*k)
+	}
+}
+
+// tukeyWindow returns a Tukey window, of which the fraction p is tapered; i.e.
+// a rectangular window if p is 0, and a Hann window if p is 1.
+func tukeyWindow(m *mathFuncs, p float64) window {
+	switch {
+	case p <= 0:
+		return rectangleWindow
+	case p >= 1:
+		return cosineWindow(m, 0.5, -0.5)
+	}
+	return func(w []float64) {
+		rectangleWindow(w)
+		np := int(p/2*float64(len(w))) - 1
+		if np <= 0 {
+			return
+		}
+		for n := 0; n <= np; n++ {
+			w[n] = raisedCosine(m, math.Pi*float64(n)/float64(np))
+			w[len(w)-np-1+n] = raisedCosine(m, math.Pi*float64(n+np)/float64(np))
+		}
+	}
+}
+
+// partialTukeyWindow returns a Tukey window covering the part [start, end) of
+// the window length, and zero elsewhere.
+func partialTukeyWindow(m *mathFuncs, p, start, end float64) window {
+	return func(w []float64) {
+		l := len(w)
+		startN, endN := int(start*float64(l)), int(end*float64(l))
+		np := int(p / 2 * float64(endN-startN))
+		n := 0
+		for ; n < startN && n < l; n++ {
+			w[n] = 0
+		}
+		for i := 1; n < startN+np && n < l; n, i = n+1, i+1 {
+			w[n] = raisedCosine(m, math.Pi*float64(i)/float64(np))
+		}
+		for ; n < endN-np && n < l; n++ {
+			w[n] = 1
+		}
+		for i := np; n < endN && n < l; n, i = n+1, i-1 {
+			w[n] = raisedCosine(m, math.Pi*float64(i)/float64(np))
+		}
+		for ; n < l; n++ {
+			w[n] = 0
+		}
+	}
+}
+
+// punchoutTukeyWindow returns a Tukey window with the part [start, end) of the
+// window length punched out; the complement of partialTukeyWindow.
+func punchoutTukeyWindow(m *mathFuncs, p, start, end float64) window {
+	return func(w []float64) {
+		l := len(w)
+		startN, endN := int(start*float64(l)), int(end*float64(l))
+		ns := int(p / 2 * float64(startN))
+		ne := int(p / 2 * float64(l-endN))
+		n := 0
+		for i := 1; n < ns && n < l; n, i = n+1, i+1 {
+			w[n] = raisedCosine(m, math.Pi*float64(i)/float64(ns))
+		}
+		for ; n < startN-ns && n < l; n++ {
+			w[n] = 1
+		}
+		for i := ns; n < startN && n < l; n, i = n+1, i-1 {
+			w[n] = raisedCosine(m, math.Pi*float64(i)/float64(ns))
+		}
+		for ; n < endN && n < l; n++ {
+			w[n] = 0
+		}
+		for i := 1; n < endN+ne && n < l; n, i = n+1, i+1 {
+			w[n] = raisedCosine(m, math.Pi*float64(i)/float64(ne))
+		}
+		for ; n < l-ne && n < l; n++ {
+			w[n] = 1
+		}
+		for i := ne; n < l; n, i = n+1, i-1 {
+			w[n] = raisedCosine(m, math.Pi*float64(i)/float64(ne))
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package flac_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// The benchmarks of this file operate on a fixed corpus of synthetic audio,
+// generated deterministically at first use, so that results are comparable
+// between runs and machines without depending on external files. Run
+//
+//    go test -run NONE -bench . -benchmem
+//
+// to track the performance of the decoder and encoder hot paths.
+
+// Properties of the synthetic benchmark corpus.
+const (
+	benchSampleRate = 44100
+	benchNChannels  = 2
+	benchBPS        = 16
+	benchBlockSize  = 4096
+	// Duration of each corpus entry in seconds.
+	benchDuration = 5
+	benchNSamples = benchSampleRate * benchDuration
+)
+
+// benchSignals specifies the signals of the benchmark corpus; the name of each
+// signal and a function generating its audio samples.
+var benchSignals = []struct {
+	name string
+	gen  func(samples [][]int32)
+}{
+	{name: "silence", gen: func(samples [][]int32) {}},
+	{name: "sine", gen: genSine},
+	{name: "noise", gen: genNoise},
+}
+
+// benchLevels specifies the encoder configurations of the benchmark corpus.
+var benchLevels = []struct {
+	name     string
+	analysis bool
+}{
+	{name: "verbatim", analysis: false},
+	{name: "analysis", analysis: true},
+}
+
+// genSine generates a 440 Hz sine wave in the left channel and a 660 Hz sine
+// wave in the right channel.
+func genSine(samples [][]int32) {
+	const amp = 1<<(benchBPS-1) - 1
+	for i := range samples[0] {
+		t := float64(i) / benchSampleRate
+		samples[0][i] = int32(amp * 0.8 * math.Sin(2*math.Pi*440*t))
+		samples[1][i] = int32(amp * 0.8 * math.Sin(2*math.Pi*660*t))
+	}
+}
+
+// genNoise generates white noise using a linear congruential generator with a
+// fixed seed.
+func genNoise(samples [][]int32) {
+	x := uint32(1)
+	for _, channel := range samples {
+		for i := range channel {
+			x = x*1664525 + 1013904223
+			channel[i] = int32(int16(x >> 16))
+		}
+	}
+}
+
+// benchCorpusEntry is an entry of the benchmark corpus.
+type benchCorpusEntry struct {
+	// Name of the entry, in the form "signal/level".
+	name string
+	// Encoded FLAC stream.
+	data []byte
+}
+
+var (
+	// benchCorpus is the benchmark corpus, generated at first use.
+	benchCorpus     []benchCorpusEntry
+	benchCorpusOnce sync.Once
+	benchCorpusErr  error
+)
+
+// getBenchCorpus returns the benchmark corpus, generating it at first use.
+func getBenchCorpus(b *testing.B) []benchCorpusEntry {
+	benchCorpusOnce.Do(func() {
+		for _, signal := range benchSignals {
+			samples := make([][]int32, benchNChannels)
+			for i := range samples {
+				samples[i] = make([]int32, benchNSamples)
+			}
+			signal.gen(samples)
+			for _, level := range benchLevels {
+				data, err := encodeBench(samples, level.analysis, 0)
+				if err != nil {
+					benchCorpusErr = fmt.Errorf("unable to encode %s/%s; %v", signal.name, level.name, err)
+					return
+				}
+				entry := benchCorpusEntry{
+					name: signal.name + "/" + level.name,
+					data: data,
+				}
+				benchCorpus = append(benchCorpus, entry)
+			}
+		}
+	})
+	if benchCorpusErr != nil {
+		b.Fatal(benchCorpusErr)
+	}
+	return benchCorpus
+}
+
+// encodeBench encodes the given audio samples, one slice per channel, into a
+// FLAC stream, using the given number of encoder worker goroutines.
+func encodeBench(samples [][]int32, analysis bool, workers int) ([]byte, error) {
+	info := &meta.StreamInfo{
+		BlockSizeMin:  benchBlockSize,
+		BlockSizeMax:  benchBlockSize,
+		SampleRate:    benchSampleRate,
+		NChannels:     benchNChannels,
+		BitsPerSample: benchBPS,
+		NSamples:      benchNSamples,
+	}
+	buf := &bytes.Buffer{}
+	enc, err := flac.NewEncoderWithOptions(buf, info, &flac.EncodeOptions{Workers: workers})
+	if err != nil {
+		return nil, err
+	}
+	enc.EnablePredictionAnalysis(analysis)
+	nsamples := len(samples[0])
+	for offset := 0; offset < nsamples; offset += benchBlockSize {
+		blockSize := benchBlockSize
+		if offset+blockSize > nsamples {
+			blockSize = nsamples - offset
+		}
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(blockSize),
+				SampleRate:        benchSampleRate,
+				Channels:          frame.ChannelsLR,
+				BitsPerSample:     benchBPS,
+			},
+			Subframes: make([]*frame.Subframe, len(samples)),
+		}
+		for channel := range samples {
+			subSamples := make([]int32, blockSize)
+			copy(subSamples, samples[channel][offset:])
+			f.Subframes[channel] = &frame.Subframe{
+				SubHeader: frame.SubHeader{
+					Pred: frame.PredVerbatim,
+				},
+				Samples:  subSamples,
+				NSamples: blockSize,
+			}
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pcmSize returns the size in bytes of the decoded audio samples of the
+// benchmark corpus entries, for throughput reporting.
+const pcmSize = benchNSamples * benchNChannels * benchBPS / 8
+
+// BenchmarkDecode measures the decoding throughput of each entry of the
+// benchmark corpus.
+func BenchmarkDecode(b *testing.B) {
+	for _, entry := range getBenchCorpus(b) {
+		b.Run(entry.name, func(b *testing.B) {
+			b.SetBytes(pcmSize)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				stream, err := flac.New(bytes.NewReader(entry.data))
+				if err != nil {
+					b.Fatal(err)
+				}
+				for {
+					if _, err := stream.ParseNext(); err != nil {
+						if err == io.EOF {
+							break
+						}
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDecodeLowMemory measures the decoding throughput and allocations of
+// each entry of the benchmark corpus in low-memory mode, where frame buffers
+// are reused.
+func BenchmarkDecodeLowMemory(b *testing.B) {
+	opts := &flac.DecodeOptions{LowMemory: true}
+	for _, entry := range getBenchCorpus(b) {
+		b.Run(entry.name, func(b *testing.B) {
+			b.SetBytes(pcmSize)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				stream, err := flac.NewWithOptions(bytes.NewReader(entry.data), opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for {
+					if _, err := stream.ParseNext(); err != nil {
+						if err == io.EOF {
+							break
+						}
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDecodeFrame measures the time and allocations per decoded frame,
+// using a reused frame.
+func BenchmarkDecodeFrame(b *testing.B) {
+	for _, entry := range getBenchCorpus(b) {
+		b.Run(entry.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var stream *flac.Stream
+			f := new(frame.Frame)
+			for i := 0; i < b.N; i++ {
+				if stream == nil {
+					b.StopTimer()
+					var err error
+					stream, err = flac.New(bytes.NewReader(entry.data))
+					if err != nil {
+						b.Fatal(err)
+					}
+					b.StartTimer()
+				}
+				if err := stream.ParseNextInto(f); err != nil {
+					if err == io.EOF {
+						stream = nil
+						continue
+					}
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSeek measures the latency of seeking to a sample position, and
+// decoding the frame at that position.
+func BenchmarkSeek(b *testing.B) {
+	for _, entry := range getBenchCorpus(b) {
+		b.Run(entry.name, func(b *testing.B) {
+			stream, err := flac.NewSeek(bytes.NewReader(entry.data))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			x := uint64(1)
+			for i := 0; i < b.N; i++ {
+				x = x*6364136223846793005 + 1442695040888963407
+				sampleNum := (x >> 33) % benchNSamples
+				if _, err := stream.Seek(sampleNum); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := stream.ParseNext(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEncode measures the encoding throughput of each signal of the
+// benchmark corpus, for each encoder configuration.
+func BenchmarkEncode(b *testing.B) {
+	for _, signal := range benchSignals {
+		samples := make([][]int32, benchNChannels)
+		for i := range samples {
+			samples[i] = make([]int32, benchNSamples)
+		}
+		signal.gen(samples)
+		for _, level := range benchLevels {
+			b.Run(signal.name+"/"+level.name, func(b *testing.B) {
+				b.SetBytes(pcmSize)
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := encodeBench(samples, level.analysis, 0); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkEncodeWorkers measures the encoding throughput of the noise signal
+// of the benchmark corpus with prediction analysis, for varying numbers of
+// encoder worker goroutines.
+func BenchmarkEncodeWorkers(b *testing.B) {
+	samples := make([][]int32, benchNChannels)
+	for i := range samples {
+		samples[i] = make([]int32, benchNSamples)
+	}
+	genNoise(samples)
+	for _, workers := range []int{1, 2, 4, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(pcmSize)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := encodeBench(samples, true, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDecodeReference measures the decoding throughput of the reference
+// implementation (the flac command line tool) on each entry of the benchmark
+// corpus, to provide a baseline for BenchmarkDecode. It is skipped if the flac
+// tool is not installed.
+//
+// Note: the measurement includes process startup and file I/O.
+func BenchmarkDecodeReference(b *testing.B) {
+	flacPath, err := exec.LookPath("flac")
+	if err != nil {
+		b.Skip("flac tool not found in PATH")
+	}
+	dir := b.TempDir()
+	for i, entry := range getBenchCorpus(b) {
+		path := filepath.Join(dir, fmt.Sprintf("%d.flac", i))
+		if err := os.WriteFile(path, entry.data, 0o644); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(entry.name, func(b *testing.B) {
+			b.SetBytes(pcmSize)
+			for i := 0; i < b.N; i++ {
+				cmd := exec.Command(flacPath, "--silent", "--decode", "--force-raw-format", "--endian=little", "--sign=signed", "--stdout", path)
+				cmd.Stdout = io.Discard
+				if err := cmd.Run(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Package bits provides bit access operations and binary decoding algorithms,
+// as used by the FLAC decoder to parse metadata blocks and audio frames.
+//
+// The package is intended for codec experimentation; e.g. decoding custom
+// residual coding methods or subframe types on top of the FLAC bitstream. Its
+// API is stable.
+package bits
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/mewkiz/flac/internal/hashutil"
+	"github.com/mewkiz/flac/internal/hashutil/crc16"
+	"github.com/mewkiz/flac/internal/hashutil/crc8"
+)
+
+// A Reader handles bit reading operations. It buffers bits up to the next byte
+// boundary.
+type Reader struct {
+	// Underlying reader.
+	r io.Reader
+	// Temporary read buffer.
+	buf [8]uint8
+	// Between 0 and 7 buffered bits since previous read operations.
+	x uint8
+	// The number of buffered bits in x.
+	n uint
+	// Hash of the bytes read from r; or nil if not hashed.
+	h hash.Hash
+}
+
+// NewReader returns a new Reader that reads bits from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Reset discards any buffered bits and resets the Reader to read from r. The
+// hash of the Reader, if any, is retained but not reset.
+func (br *Reader) Reset(r io.Reader) {
+	*br = Reader{r: r, h: br.h}
+}
+
+// SetHash sets the hash to which all subsequent bytes read from the underlying
+// reader are added; or disables hashing if h is nil. As bits are buffered up to
+// the next byte boundary, a partially read byte is added to the hash as a whole;
+// e.g. to compute the CRC-8 of a FLAC frame header, set a hash returned by
+// NewCRC8 before reading the sync code and call Sum8 after reading the last bit
+// preceding the CRC-8.
+func (br *Reader) SetHash(h hash.Hash) {
+	br.h = h
+}
+
+// Hash returns the hash set by SetHash; or nil if hashing is disabled.
+func (br *Reader) Hash() hash.Hash {
+	return br.h
+}
+
+// Aligned reports whether the Reader is positioned at a byte boundary.
+func (br *Reader) Aligned() bool {
+	return br.n == 0
+}
+
+// Align discards the buffered bits up to the next byte boundary, and returns
+// them; e.g. the zero-padding at the end of a FLAC frame.
+func (br *Reader) Align() uint8 {
+	x := br.x
+	br.x, br.n = 0, 0
+	return x
+}
+
+// ReadAligned discards the buffered bits up to the next byte boundary, as done
+// by Align, and reads exactly len(p) bytes into p.
+func (br *Reader) ReadAligned(p []byte) error {
+	br.Align()
+	if _, err := io.ReadFull(br.r, p); err != nil {
+		return err
+	}
+	if br.h != nil {
+		br.h.Write(p)
+	}
+	return nil
+}
+
+// Read reads and returns the next n bits, at most 64. It buffers bits up to the
+// next byte boundary.
+func (br *Reader) Read(n uint) (x uint64, err error) {
+	if n == 0 {
+		return 0, nil
+	}
+	if n > 64 {
+		return 0, fmt.Errorf("bits.Reader.Read: invalid number of bits; n (%d) exceeds 64", n)
+	}
+
+	// Read buffered bits.
+	if br.n >= n {
+		br.n -= n
+		x = uint64(br.x) >> br.n
+		br.x &= 1<<br.n - 1
+		return x, nil
+	}
+
+	// Refill the bit cache with the bytes holding the remaining bits, as a
+	// single big-endian 64-bit word. To not read past the next byte boundary,
+	// at most 8 bytes are read.
+	need := n - br.n
+	nbytes := (need + 7) / 8
+	buf := br.buf[8-nbytes:]
+	if _, err := io.ReadFull(br.r, buf); err != nil {
+		return 0, err
+	}
+	if br.h != nil {
+		br.h.Write(buf)
+	}
+	cache := binary.BigEndian.Uint64(br.buf[:])
+	if nbytes < 8 {
+		cache &= 1<<(8*nbytes) - 1
+	}
+
+	// Read buffered bits followed by bits from the bit cache, and buffer the
+	// remaining bits of the last byte.
+	rem := 8*nbytes - need
+	x = uint64(br.x)<<need | cache>>rem
+	br.x = uint8(cache & (1<<rem - 1))
+	br.n = rem
+	return x, nil
+}
+
+// Hash8 is the common interface implemented by all 8-bit hash functions.
+type Hash8 = hashutil.Hash8
+
+// Hash16 is the common interface implemented by all 16-bit hash functions.
+type Hash16 = hashutil.Hash16
+
+// NewCRC8 returns a new CRC-8 hash using the ATM polynomial, as used by the
+// headers of FLAC frames.
+func NewCRC8() Hash8 {
+	return crc8.NewATM()
+}
+
+// NewCRC16 returns a new CRC-16 hash using the IBM polynomial, as used by FLAC
+// frames.
+func NewCRC16() Hash16 {
+	return crc16.NewIBM()
+}
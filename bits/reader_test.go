@@ -626,6 +626,63 @@ func TestReadEOF(t *testing.T) {
 	}
 }
 
+func TestReadAligned(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xA5, 0x12, 0x34, 0xFF}))
+	if !r.Aligned() {
+		t.Errorf("expected aligned reader")
+	}
+	if x, err := r.Read(3); err != nil || x != 0x5 {
+		t.Fatalf("expected 0x5, got 0x%X; %v", x, err)
+	}
+	if r.Aligned() {
+		t.Errorf("expected unaligned reader")
+	}
+	if pad := r.Align(); pad != 0x05 {
+		t.Errorf("padding mismatch; expected 0x05, got 0x%02X", pad)
+	}
+	buf := make([]byte, 2)
+	if err := r.ReadAligned(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, []byte{0x12, 0x34}) {
+		t.Errorf("aligned read mismatch; expected 1234, got %X", buf)
+	}
+	if x, err := r.Read(8); err != nil || x != 0xFF {
+		t.Errorf("expected 0xFF, got 0x%X; %v", x, err)
+	}
+	if err := r.ReadAligned(buf); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestHash(t *testing.T) {
+	// Frame header of testdata/love.flac, with its CRC-8.
+	data := []byte{0xFF, 0xF8, 0xC9, 0x18, 0x00, 0xC2}
+	r := NewReader(bytes.NewReader(data))
+	h := NewCRC8()
+	r.SetHash(h)
+	if r.Hash() != h {
+		t.Fatalf("hash mismatch")
+	}
+	for _, n := range []uint{14, 1, 1, 4, 4, 4, 3, 1, 8} {
+		if _, err := r.Read(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := h.Sum8()
+	r.SetHash(nil)
+	got, err := r.Read(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint8(got) != want {
+		t.Errorf("CRC-8 mismatch; expected 0x%02X, got 0x%02X", want, got)
+	}
+	if h.Sum8() != want {
+		t.Errorf("expected bytes read after disabling the hash not to be hashed")
+	}
+}
+
 func BenchmarkReadAlign1(b *testing.B) {
 	benchmarkReads(b, 64, 1)
 }
@@ -638,6 +695,12 @@ func BenchmarkReadAlign64(b *testing.B) {
 	benchmarkReads(b, 64, 64)
 }
 
+// BenchmarkReadSmall benchmarks reads of at most 16 bits; e.g. frame header
+// fields and LPC coefficients.
+func BenchmarkReadSmall(b *testing.B) {
+	benchmarkReads(b, 16, 1)
+}
+
 func benchmarkReads(b *testing.B, chunk, align int) {
 	size := 1 << 12
 	buf, bits, _, last := prepareBenchmark(size, chunk, align)
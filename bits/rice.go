@@ -0,0 +1,82 @@
+package bits
+
+import (
+	"fmt"
+
+	"github.com/icza/bitio"
+)
+
+// ReadRice decodes and returns a Rice coded integer with the given Rice
+// parameter k, as used by the residuals of FLAC subframes; i.e. the unary coded
+// most significant bits and the k least significant bits of the ZigZag encoded
+// integer.
+func (br *Reader) ReadRice(k uint) (int32, error) {
+	high, err := br.ReadUnary()
+	if err != nil {
+		return 0, err
+	}
+	low, err := br.Read(k)
+	if err != nil {
+		return 0, err
+	}
+	return DecodeZigZag(uint32(high<<k | low)), nil
+}
+
+// WriteRice encodes x as a Rice coded integer with the given Rice parameter k;
+// i.e. the unary coded most significant bits and the k least significant bits
+// of the ZigZag encoded integer. See Reader.ReadRice.
+func WriteRice(bw *bitio.Writer, k uint, x int32) error {
+	folded := EncodeZigZag(x)
+	if err := WriteUnary(bw, uint64(folded>>k)); err != nil {
+		return err
+	}
+	return bw.WriteBits(uint64(folded), uint8(k))
+}
+
+// RiceEscape returns the Rice parameter escape code for Rice parameters of the
+// given size in bits; 0xF for 4-bit and 0x1F for 5-bit Rice parameters.
+func RiceEscape(paramSize uint) uint {
+	return 1<<paramSize - 1
+}
+
+// WriteRiceBlock encodes a block of residuals as a Rice partition of a FLAC
+// subframe; i.e. the Rice parameter k as a paramSize-bit integer (4 or 5),
+// followed by the residuals Rice coded as done by WriteRice.
+//
+// If k is the escape code of the parameter size (see RiceEscape), the block is
+// escaped instead; i.e. escapedBPS follows as a 5-bit integer, and the
+// residuals are stored unencoded as signed two's complement integers of
+// escapedBPS bits. escapedBPS is ignored for Rice coded blocks.
+//
+// ref: https://www.xiph.org/flac/format.html#rice_partition
+func WriteRiceBlock(bw *bitio.Writer, paramSize, k, escapedBPS uint, residuals []int32) error {
+	if paramSize != 4 && paramSize != 5 {
+		return fmt.Errorf("bits.WriteRiceBlock: invalid Rice parameter size; expected 4 or 5, got %d", paramSize)
+	}
+	if k > RiceEscape(paramSize) {
+		return fmt.Errorf("bits.WriteRiceBlock: Rice parameter (%d) exceeds %d-bit range", k, paramSize)
+	}
+	if err := bw.WriteBits(uint64(k), uint8(paramSize)); err != nil {
+		return err
+	}
+	if k != RiceEscape(paramSize) {
+		for _, residual := range residuals {
+			if err := WriteRice(bw, k, residual); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if escapedBPS > 31 {
+		return fmt.Errorf("bits.WriteRiceBlock: escaped bits-per-sample (%d) exceeds 5-bit range", escapedBPS)
+	}
+	if err := bw.WriteBits(uint64(escapedBPS), 5); err != nil {
+		return err
+	}
+	for _, residual := range residuals {
+		if err := bw.WriteBits(uint64(residual), uint8(escapedBPS)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
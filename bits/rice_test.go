@@ -0,0 +1,133 @@
+package bits_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/icza/bitio"
+	"github.com/mewkiz/flac/bits"
+)
+
+func TestReadRice(t *testing.T) {
+	for k := uint(0); k < 8; k++ {
+		buf := &bytes.Buffer{}
+		bw := bitio.NewWriter(buf)
+		for want := int32(-100); want <= 100; want++ {
+			folded := uint64(bits.EncodeZigZag(want))
+			if err := bits.WriteUnary(bw, folded>>k); err != nil {
+				t.Fatalf("unable to write unary; %v", err)
+			}
+			if err := bw.WriteBits(folded&(1<<k-1), uint8(k)); err != nil {
+				t.Fatalf("unable to write bits; %v", err)
+			}
+		}
+		if err := bw.Close(); err != nil {
+			t.Fatalf("unable to close (flush) the bit buffer; %v", err)
+		}
+		r := bits.NewReader(buf)
+		for want := int32(-100); want <= 100; want++ {
+			got, err := r.ReadRice(k)
+			if err != nil {
+				t.Fatalf("k=%d: unable to read Rice coded integer; %v", k, err)
+			}
+			if want != got {
+				t.Fatalf("k=%d: mismatch between written and read Rice coded integer; expected: %d, got: %d", k, want, got)
+			}
+		}
+	}
+}
+
+func TestWriteRiceBlock(t *testing.T) {
+	residuals := []int32{0, -1, 1, -2, 2, 7, -8, 3}
+	golden := []struct {
+		paramSize, k, escapedBPS uint
+	}{
+		{paramSize: 4, k: 0},
+		{paramSize: 4, k: 2},
+		{paramSize: 5, k: 17},
+		{paramSize: 4, k: 0xF, escapedBPS: 4},
+		{paramSize: 5, k: 0x1F, escapedBPS: 31},
+	}
+	for _, g := range golden {
+		buf := &bytes.Buffer{}
+		wh := bits.NewCRC16()
+		bw := bits.NewHashWriter(buf, wh)
+		if err := bits.WriteRiceBlock(bw, g.paramSize, g.k, g.escapedBPS, residuals); err != nil {
+			t.Fatalf("paramSize=%d, k=%d: unable to write Rice block; %v", g.paramSize, g.k, err)
+		}
+		if err := bw.Close(); err != nil {
+			t.Fatalf("unable to close (flush) the bit buffer; %v", err)
+		}
+
+		r := bits.NewReader(bytes.NewReader(buf.Bytes()))
+		rh := bits.NewCRC16()
+		r.SetHash(rh)
+		k, err := r.Read(g.paramSize)
+		if err != nil || uint(k) != g.k {
+			t.Fatalf("paramSize=%d: Rice parameter mismatch; expected %d, got %d (%v)", g.paramSize, g.k, k, err)
+		}
+		escaped := g.k == bits.RiceEscape(g.paramSize)
+		if escaped {
+			n, err := r.Read(5)
+			if err != nil || uint(n) != g.escapedBPS {
+				t.Fatalf("escaped bits-per-sample mismatch; expected %d, got %d (%v)", g.escapedBPS, n, err)
+			}
+		}
+		for _, want := range residuals {
+			var got int32
+			if escaped {
+				x, err := r.Read(g.escapedBPS)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = int32(bits.IntN(x, g.escapedBPS))
+			} else {
+				got, err = r.ReadRice(g.k)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if want != got {
+				t.Errorf("paramSize=%d, k=%d: residual mismatch; expected %d, got %d", g.paramSize, g.k, want, got)
+			}
+		}
+		r.Align()
+		if wh.Sum16() != rh.Sum16() {
+			t.Errorf("paramSize=%d, k=%d: CRC-16 mismatch; expected 0x%04X, got 0x%04X", g.paramSize, g.k, wh.Sum16(), rh.Sum16())
+		}
+	}
+
+	// Invalid Rice parameters.
+	bw := bitio.NewWriter(&bytes.Buffer{})
+	if err := bits.WriteRiceBlock(bw, 4, 16, 0, residuals); err == nil {
+		t.Errorf("expected error for out of range Rice parameter")
+	}
+	if err := bits.WriteRiceBlock(bw, 6, 0, 0, residuals); err == nil {
+		t.Errorf("expected error for invalid Rice parameter size")
+	}
+}
+
+func TestWriteRiceBlockAllocs(t *testing.T) {
+	residuals := make([]int32, 4096)
+	for i := range residuals {
+		residuals[i] = int32(i%97 - 48)
+	}
+	golden := []struct {
+		paramSize, k, escapedBPS uint
+	}{
+		{paramSize: 4, k: 5},
+		{paramSize: 5, k: 0x1F, escapedBPS: 8},
+	}
+	for _, g := range golden {
+		bw := bitio.NewWriter(io.Discard)
+		allocs := testing.AllocsPerRun(1, func() {
+			if err := bits.WriteRiceBlock(bw, g.paramSize, g.k, g.escapedBPS, residuals); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("paramSize=%d, k=%d: allocations per encoded Rice partition mismatch; expected 0, got %v", g.paramSize, g.k, allocs)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package bits
+
+import (
+	"hash"
+	"io"
+
+	"github.com/icza/bitio"
+)
+
+// NewHashWriter returns a new bit writer that writes to w, and adds all bytes
+// written to w to the hash h; e.g. a hash returned by NewCRC16, to compute the
+// CRC-16 of a FLAC frame. Bytes are written and hashed in batches as the bit
+// writer flushes its buffer; the hash is complete once the bit writer has been
+// closed, which pads the final byte with zero bits but does not close w.
+func NewHashWriter(w io.Writer, h hash.Hash) *bitio.Writer {
+	return bitio.NewWriter(io.MultiWriter(h, w))
+}
@@ -0,0 +1,115 @@
+package flac
+
+import (
+	"fmt"
+	"io"
+)
+
+// BlockingStrategy specifies the blocking strategy of a FLAC stream, as stored
+// in the header of each audio frame.
+type BlockingStrategy uint8
+
+// Blocking strategies.
+const (
+	// BlockingUnknown specifies that the blocking strategy of the stream is not
+	// yet known; e.g. for streams without audio frames.
+	BlockingUnknown BlockingStrategy = iota
+	// BlockingFixed specifies a fixed-blocksize stream, in which all frames but
+	// the last hold the same number of samples, and frame headers store the
+	// frame number.
+	BlockingFixed
+	// BlockingVariable specifies a variable-blocksize stream, in which the
+	// number of samples may differ between frames, and frame headers store the
+	// sample number of the first sample of the frame.
+	BlockingVariable
+)
+
+// String returns the string representation of the blocking strategy.
+func (strategy BlockingStrategy) String() string {
+	switch strategy {
+	case BlockingUnknown:
+		return "unknown"
+	case BlockingFixed:
+		return "fixed"
+	case BlockingVariable:
+		return "variable"
+	}
+	return fmt.Sprintf("BlockingStrategy(%d)", uint8(strategy))
+}
+
+// blockingStrategy returns the blocking strategy of the given frame header.
+func blockingStrategy(hasFixedBlockSize bool) BlockingStrategy {
+	if hasFixedBlockSize {
+		return BlockingFixed
+	}
+	return BlockingVariable
+}
+
+// BlockingStrategy returns the blocking strategy of the stream. The blocking
+// strategy is that of the frames parsed so far; or, if no frame has been
+// parsed, that of the header of the next frame, which is read without
+// advancing the stream. BlockingUnknown is returned if the stream has no audio
+// frames.
+//
+// The sample numbers of frames, as returned by Stream.Seek and
+// Stream.SamplePosition, account for the blocking strategy of the stream.
+func (stream *Stream) BlockingStrategy() BlockingStrategy {
+	if stream.blocking != BlockingUnknown {
+		return stream.blocking
+	}
+	// The blocking strategy bit is the least significant bit of the 14-bit sync
+	// code, the reserved bit and the blocking strategy bit.
+	var hdr [2]byte
+	switch {
+	case stream.br != nil:
+		buf, err := stream.br.Peek(len(hdr))
+		if err != nil {
+			return BlockingUnknown
+		}
+		copy(hdr[:], buf)
+	default:
+		rs, ok := stream.r.(io.ReadSeeker)
+		if !ok {
+			return BlockingUnknown
+		}
+		pos, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return BlockingUnknown
+		}
+		_, err = io.ReadFull(rs, hdr[:])
+		if _, err := rs.Seek(pos, io.SeekStart); err != nil {
+			return BlockingUnknown
+		}
+		if err != nil {
+			return BlockingUnknown
+		}
+	}
+	if hdr[0] != 0xFF || hdr[1]&0xFE != 0xF8 {
+		// Not a frame header.
+		return BlockingUnknown
+	}
+	return blockingStrategy(hdr[1]&0x01 == 0)
+}
+
+// BlockSizeHistogram parses the FLAC stream of r in its entirety, and returns
+// the number of audio frames of each block size, including the last frame,
+// which may hold fewer samples; e.g. to inspect the block sizes chosen by
+// variable-blocksize encoders.
+func BlockSizeHistogram(r io.Reader) (map[uint16]int, error) {
+	stream, err := New(r)
+	if err != nil {
+		return nil, err
+	}
+	hist := make(map[uint16]int)
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		hist[f.BlockSize]++
+	}
+	return hist, nil
+}
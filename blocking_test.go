@@ -0,0 +1,172 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// encodeBlocks encodes a mono stream with frames of the given block sizes, using
+// the given blocking strategy, and returns the encoded stream. Sample i of the
+// stream holds the value i%30000.
+func encodeBlocks(t *testing.T, blockSizes []uint16, fixed bool) []byte {
+	info := &meta.StreamInfo{
+		BlockSizeMin:  16,
+		BlockSizeMax:  65535,
+		SampleRate:    44100,
+		NChannels:     1,
+		BitsPerSample: 16,
+	}
+	buf := &bytes.Buffer{}
+	enc, err := flac.NewEncoder(buf, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sampleNum int
+	for _, blockSize := range blockSizes {
+		samples := make([]int32, blockSize)
+		for i := range samples {
+			samples[i] = int32((sampleNum + i) % 30000)
+		}
+		sampleNum += int(blockSize)
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: fixed,
+				BlockSize:         blockSize,
+				SampleRate:        44100,
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     16,
+			},
+			Subframes: []*frame.Subframe{{SubHeader: frame.SubHeader{Pred: frame.PredVerbatim}, NSamples: int(blockSize), Samples: samples}},
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBlockingStrategy(t *testing.T) {
+	golden := []struct {
+		name       string
+		blockSizes []uint16
+		fixed      bool
+		want       flac.BlockingStrategy
+	}{
+		{name: "variable", blockSizes: []uint16{1152, 4096, 576, 2000, 4608, 300}, want: flac.BlockingVariable},
+		// Shortened last frame.
+		{name: "fixed", blockSizes: []uint16{4096, 4096, 4096, 1000}, fixed: true, want: flac.BlockingFixed},
+	}
+	for _, g := range golden {
+		buf := encodeBlocks(t, g.blockSizes, g.fixed)
+
+		// Block size histogram.
+		hist, err := flac.BlockSizeHistogram(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		want := make(map[uint16]int)
+		for _, blockSize := range g.blockSizes {
+			want[blockSize]++
+		}
+		if len(hist) != len(want) {
+			t.Errorf("%s: histogram mismatch; expected %v, got %v", g.name, want, hist)
+		}
+		for blockSize, n := range want {
+			if hist[blockSize] != n {
+				t.Errorf("%s: histogram mismatch; expected %v, got %v", g.name, want, hist)
+				break
+			}
+		}
+
+		// Blocking strategy prior to and after parsing frames, and sample
+		// numbers of frames.
+		stream, err := flac.New(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		if got := stream.BlockingStrategy(); got != g.want {
+			t.Errorf("%s: blocking strategy mismatch prior to parsing; expected %v, got %v", g.name, g.want, got)
+		}
+		var sampleNum uint64
+		for {
+			if got := stream.SamplePosition(); got != sampleNum {
+				t.Errorf("%s: sample position mismatch; expected %d, got %d", g.name, sampleNum, got)
+			}
+			f, err := stream.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatalf("%s: %v", g.name, err)
+			}
+			if got := uint64(f.Subframes[0].Samples[0]); got != sampleNum%30000 {
+				t.Errorf("%s: first sample mismatch of frame at sample %d; got %d", g.name, sampleNum, got)
+			}
+			sampleNum += uint64(f.BlockSize)
+		}
+		if got := stream.BlockingStrategy(); got != g.want {
+			t.Errorf("%s: blocking strategy mismatch after parsing; expected %v, got %v", g.name, g.want, got)
+		}
+
+		// Seek to the first sample, samples within frames, frame boundaries and
+		// the last sample.
+		stream, err = flac.NewSeek(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		if got := stream.BlockingStrategy(); got != g.want {
+			t.Errorf("%s: blocking strategy mismatch of seekable stream; expected %v, got %v", g.name, g.want, got)
+		}
+		var starts []uint64
+		var start uint64
+		for _, blockSize := range g.blockSizes {
+			starts = append(starts, start)
+			start += uint64(blockSize)
+		}
+		total := start
+		for _, target := range []uint64{0, 1, 1151, 1152, 5000, 5248, 9000, total - 1} {
+			first, err := stream.Seek(target)
+			if err != nil {
+				t.Errorf("%s: unable to seek to sample %d; %v", g.name, target, err)
+				continue
+			}
+			// Expected first sample number of the frame containing target.
+			var want uint64
+			for _, s := range starts {
+				if s <= target {
+					want = s
+				}
+			}
+			if first != want {
+				t.Errorf("%s: frame start mismatch of sample %d; expected %d, got %d", g.name, target, want, first)
+			}
+			f, err := stream.ParseNext()
+			if err != nil {
+				t.Errorf("%s: unable to parse frame after seek to sample %d; %v", g.name, target, err)
+				continue
+			}
+			if got := f.Subframes[0].Samples[target-first]; got != int32(target%30000) {
+				t.Errorf("%s: sample %d mismatch after seek; expected %d, got %d", g.name, target, target%30000, got)
+			}
+		}
+	}
+}
+
+func TestBlockingStrategyEmpty(t *testing.T) {
+	buf := encodeBlocks(t, nil, true)
+	stream, err := flac.New(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stream.BlockingStrategy(); got != flac.BlockingUnknown {
+		t.Errorf("blocking strategy mismatch of stream without frames; expected %v, got %v", flac.BlockingUnknown, got)
+	}
+}
@@ -1,3 +1,11 @@
+// Package bufseekio implements buffering for io.ReadSeeker objects, retaining
+// the buffered data across seeks within the buffer.
+//
+// A ReadSeeker may be used to buffer the reads of flac.NewSeek, e.g. with a
+// larger buffer for files on network storage:
+//
+//	f, err := os.Open("foo.flac")
+//	stream, err := flac.NewSeek(bufseekio.NewReadSeekerSize(f, 64*1024))
 package bufseekio
 
 import (
@@ -44,8 +52,94 @@ func NewReadSeeker(rd io.ReadSeeker) *ReadSeeker {
 	return NewReadSeekerSize(rd, defaultBufSize)
 }
 
+// NewReaderAtSize returns a new ReadSeeker reading the first n bytes of ra,
+// whose buffer has at least the specified size. Reads of the ReadSeeker do not
+// affect other users of ra.
+func NewReaderAtSize(ra io.ReaderAt, n int64, size int) *ReadSeeker {
+	return NewReadSeekerSize(io.NewSectionReader(ra, 0, n), size)
+}
+
+// Size returns the size of the underlying buffer in bytes.
+func (b *ReadSeeker) Size() int {
+	return len(b.buf)
+}
+
+// Resize changes the size of the underlying buffer to size bytes, or to the
+// number of buffered bytes if larger. The buffered data and the read offset are
+// retained.
+func (b *ReadSeeker) Resize(size int) {
+	if size < minReadBufferSize {
+		size = minReadBufferSize
+	}
+	if n := b.buffered(); size < n {
+		size = n
+	}
+	if size == len(b.buf) {
+		return
+	}
+	buf := make([]byte, size)
+	n := copy(buf, b.buf[b.r:b.w])
+	b.buf = buf
+	b.pos += int64(b.r)
+	b.r, b.w = 0, n
+}
+
 var errNegativeRead = errors.New("bufseekio: reader returned negative count from Read")
 
+var (
+	// ErrBufferFull reports that the number of bytes requested by Peek exceeds
+	// the buffer size.
+	ErrBufferFull = errors.New("bufseekio: buffer full")
+	// ErrNegativeCount reports that a negative number of bytes was requested by
+	// Peek.
+	ErrNegativeCount = errors.New("bufseekio: negative count")
+)
+
+// maxConsecutiveEmptyReads specifies the maximum number of consecutive reads
+// returning no data and no error, before Peek fails with io.ErrNoProgress.
+const maxConsecutiveEmptyReads = 100
+
+// Peek returns the next n bytes without advancing the reader, reading from the
+// underlying io.ReadSeeker as needed. The bytes stop being valid at the next
+// read or seek call. If Peek returns fewer than n bytes, it also returns an
+// error explaining why the read is short; ErrBufferFull if n is larger than the
+// buffer size (see Resize).
+func (b *ReadSeeker) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	var err error
+	if n > len(b.buf) {
+		n = len(b.buf)
+		err = ErrBufferFull
+	}
+	// Slide the buffered data to the start of the buffer if needed to make room.
+	if b.r+n > len(b.buf) {
+		copy(b.buf, b.buf[b.r:b.w])
+		b.pos += int64(b.r)
+		b.w -= b.r
+		b.r = 0
+	}
+	for empty := 0; b.buffered() < n && b.err == nil; {
+		m, err := b.rd.Read(b.buf[b.w:])
+		if m < 0 {
+			panic(errNegativeRead)
+		}
+		b.w += m
+		b.err = err
+		if m > 0 {
+			empty = 0
+		} else if empty++; empty >= maxConsecutiveEmptyReads {
+			b.err = io.ErrNoProgress
+		}
+	}
+	if avail := b.buffered(); avail < n {
+		n = avail
+		err = b.readErr()
+	}
+	return b.buf[b.r : b.r+n], err
+}
+
 func (b *ReadSeeker) reset(buf []byte, r io.ReadSeeker) {
 	*b = ReadSeeker{
 		buf: buf,
@@ -85,7 +179,8 @@ func (b *ReadSeeker) Read(p []byte) (n int, err error) {
 			if n < 0 {
 				panic(errNegativeRead)
 			}
-			b.pos += int64(n)
+			b.pos += int64(b.r + n)
+			b.r, b.w = 0, 0
 			return n, b.readErr()
 		}
 		// One read.
@@ -110,6 +205,30 @@ func (b *ReadSeeker) Read(p []byte) (n int, err error) {
 	return n, nil
 }
 
+// ReadAt reads len(p) bytes into p starting at offset off of the underlying
+// reader, and implements io.ReaderAt. ReadAt does not affect the read offset of
+// Read and Seek. If the underlying reader implements io.ReaderAt, the read is
+// delegated to it; otherwise, the underlying reader is seeked to off, and
+// restored to its prior offset after the read.
+func (b *ReadSeeker) ReadAt(p []byte, off int64) (n int, err error) {
+	if ra, ok := b.rd.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+	// Offset of the underlying reader; i.e. the end of the buffered data.
+	cur := b.pos + int64(b.w)
+	if _, err := b.rd.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err = io.ReadFull(b.rd, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if _, serr := b.rd.Seek(cur, io.SeekStart); serr != nil && err == nil {
+		err = serr
+	}
+	return n, err
+}
+
 // buffered returns the number of bytes that can be read from the current buffer.
 func (b *ReadSeeker) buffered() int { return b.w - b.r }
 
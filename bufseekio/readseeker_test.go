@@ -264,3 +264,143 @@ func (r *seekRecorder) assertSeeked(t *testing.T, expected []seekRecord) {
 func (r *seekRecorder) reset() {
 	r.seeks = nil
 }
+
+func TestReadSeeker_SeekAfterLargeRead(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	rs := NewReadSeekerSize(bytes.NewReader(data), 16)
+	if len(rs.buf) != 16 {
+		t.Fatal("the buffer size was changed and the validity of this test has become unknown")
+	}
+	// Empty the buffer, then read past it directly into p.
+	for _, n := range []int{10, 6, 20} {
+		got := make([]byte, n)
+		if _, err := io.ReadFull(rs, got); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, err := rs.Seek(0, io.SeekCurrent); err != nil || p != 36 {
+		t.Fatalf("want %d got %d, err=%v", 36, p, err)
+	}
+	// Seek to an offset previously held by the buffer.
+	if p, err := rs.Seek(25, io.SeekStart); err != nil || p != 25 {
+		t.Fatalf("want %d got %d, err=%v", 25, p, err)
+	}
+	got := make([]byte, 1)
+	if _, err := io.ReadFull(rs, got); err != nil || got[0] != 25 {
+		t.Fatalf("want byte %d got %d, err=%v", 25, got[0], err)
+	}
+}
+
+func TestReadSeeker_ReadAt(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	r := &seekRecorder{rs: bytes.NewReader(data)}
+	for _, rs := range []*ReadSeeker{NewReadSeekerSize(r, 20), NewReaderAtSize(bytes.NewReader(data), 100, 20)} {
+		got := make([]byte, 5)
+		if n, err := rs.Read(got); err != nil || n != 5 {
+			t.Fatalf("want n read %d got %d, err=%v", 5, n, err)
+		}
+
+		// Test ReadAt outside of buffer.
+		if n, err := rs.ReadAt(got, 50); err != nil || n != 5 || !reflect.DeepEqual(got, []byte{50, 51, 52, 53, 54}) {
+			t.Fatalf("want n read %d got %d, want buffer %v got %v, err=%v", 5, n, []byte{50, 51, 52, 53, 54}, got, err)
+		}
+
+		// Test ReadAt past end.
+		if n, err := rs.ReadAt(got, 98); err != io.EOF || n != 2 || !reflect.DeepEqual(got[:2], []byte{98, 99}) {
+			t.Fatalf("want n read %d got %d, want buffer %v got %v, err=%v", 2, n, []byte{98, 99}, got[:2], err)
+		}
+
+		// Test that the read offset is unaffected, both within and past buf.
+		if n, err := rs.Read(got); err != nil || n != 5 || !reflect.DeepEqual(got, []byte{5, 6, 7, 8, 9}) {
+			t.Fatalf("want n read %d got %d, want buffer %v got %v, err=%v", 5, n, []byte{5, 6, 7, 8, 9}, got, err)
+		}
+		if p, err := rs.Seek(30, io.SeekStart); err != nil || p != 30 {
+			t.Fatalf("want %d got %d, err=%v", 30, p, err)
+		}
+		if n, err := rs.ReadAt(got, 0); err != nil || n != 5 {
+			t.Fatalf("want n read %d got %d, err=%v", 5, n, err)
+		}
+		if n, err := rs.Read(got); err != nil || n != 5 || !reflect.DeepEqual(got, []byte{30, 31, 32, 33, 34}) {
+			t.Fatalf("want n read %d got %d, want buffer %v got %v, err=%v", 5, n, []byte{30, 31, 32, 33, 34}, got, err)
+		}
+	}
+}
+
+func TestReadSeeker_Resize(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	rs := NewReadSeekerSize(bytes.NewReader(data), 40)
+	got := make([]byte, 5)
+	if n, err := rs.Read(got); err != nil || n != 5 {
+		t.Fatalf("want n read %d got %d, err=%v", 5, n, err)
+	}
+
+	// Test shrink below the number of buffered bytes.
+	rs.Resize(20)
+	if rs.Size() != 35 {
+		t.Fatalf("want %d got %d", 35, rs.Size())
+	}
+	if n, err := rs.Read(got); err != nil || n != 5 || !reflect.DeepEqual(got, []byte{5, 6, 7, 8, 9}) {
+		t.Fatalf("want n read %d got %d, want buffer %v got %v, err=%v", 5, n, []byte{5, 6, 7, 8, 9}, got, err)
+	}
+
+	// Test grow.
+	rs.Resize(50)
+	if rs.Size() != 50 {
+		t.Fatalf("want %d got %d", 50, rs.Size())
+	}
+	if p, err := rs.Seek(0, io.SeekCurrent); err != nil || p != 10 {
+		t.Fatalf("want %d got %d, err=%v", 10, p, err)
+	}
+	big := make([]byte, 30)
+	if n, err := io.ReadFull(rs, big); err != nil || n != 30 || big[0] != 10 || big[29] != 39 {
+		t.Fatalf("want n read %d got %d, want range [%d, %d] got [%d, %d], err=%v", 30, n, 10, 39, big[0], big[29], err)
+	}
+}
+
+func TestReadSeeker_Peek(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	rs := NewReadSeekerSize(bytes.NewReader(data), 20)
+	got := make([]byte, 15)
+	if n, err := rs.Read(got); err != nil || n != 15 {
+		t.Fatalf("want n read %d got %d, err=%v", 15, n, err)
+	}
+
+	// Test peek beyond the end of the buffer, sliding the buffered data.
+	if p, err := rs.Peek(10); err != nil || !reflect.DeepEqual(p, []byte{15, 16, 17, 18, 19, 20, 21, 22, 23, 24}) {
+		t.Fatalf("want %v got %v, err=%v", []byte{15, 16, 17, 18, 19, 20, 21, 22, 23, 24}, p, err)
+	}
+	if p, err := rs.Seek(0, io.SeekCurrent); err != nil || p != 15 {
+		t.Fatalf("want %d got %d, err=%v", 15, p, err)
+	}
+
+	// Test peek larger than the buffer.
+	if p, err := rs.Peek(30); err != ErrBufferFull || len(p) != 20 || p[0] != 15 {
+		t.Fatalf("want %d bytes from %d got %v, err=%v", 20, 15, p, err)
+	}
+	if _, err := rs.Peek(-1); err != ErrNegativeCount {
+		t.Fatalf("want %v got %v", ErrNegativeCount, err)
+	}
+	if n, err := rs.Read(got[:5]); err != nil || n != 5 || !reflect.DeepEqual(got[:5], []byte{15, 16, 17, 18, 19}) {
+		t.Fatalf("want n read %d got %d, want buffer %v got %v, err=%v", 5, n, []byte{15, 16, 17, 18, 19}, got[:5], err)
+	}
+
+	// Test peek at the end of the data.
+	if _, err := rs.Seek(95, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := rs.Peek(10); err != io.EOF || !reflect.DeepEqual(p, []byte{95, 96, 97, 98, 99}) {
+		t.Fatalf("want %v got %v, err=%v", []byte{95, 96, 97, 98, 99}, p, err)
+	}
+}
@@ -0,0 +1,281 @@
+package flac
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/internal/hashutil/crc16"
+	"github.com/mewkiz/flac/meta"
+)
+
+// CarveOptions specifies the options of Carve. The zero value specifies the
+// default options.
+type CarveOptions struct {
+	// MinFrames specifies the minimum number of consecutive frames of a carved
+	// stream; shorter sequences are discarded. A 0 value implies 2 frames, which
+	// rules out false positives in practice.
+	MinFrames int
+	// SampleRate and BitsPerSample specify the sample rate and sample size of
+	// frames whose headers leave them unspecified (i.e. "get from StreamInfo");
+	// such frames are skipped if not specified.
+	SampleRate    uint32
+	BitsPerSample uint8
+}
+
+// defaultMinCarveFrames specifies the minimum number of consecutive frames of a
+// carved stream, if unspecified by CarveOptions.
+const defaultMinCarveFrames = 2
+
+// A CarvedStream is a sequence of consecutive audio frames of consistent stream
+// properties, located by Carve.
+type CarvedStream struct {
+	// Byte offset of the first frame, relative to the start of the input.
+	Offset int64
+	// Size in bytes of the frames.
+	Size int64
+	// Number of frames.
+	NFrames int
+	// StreamInfo metadata block synthesized from the frames, including the MD5
+	// checksum of the decoded audio samples.
+	Info *meta.StreamInfo
+	// Input of Carve.
+	ra io.ReaderAt
+	// Frames of the stream, in order.
+	frames []carvedFrame
+}
+
+// A carvedFrame is a frame of a carved stream.
+type carvedFrame struct {
+	// Frame header, as stored in the input.
+	hdr frame.Header
+	// Byte offset of the frame, relative to the start of the input.
+	offset int64
+	// Size in bytes of the frame header and frame.
+	hdrSize, size int
+}
+
+// Carve scans the first size bytes of ra for sequences of consecutive FLAC
+// audio frames, and returns a stream for each sequence; e.g. to recover audio
+// from raw disk images, memory dumps and fragments of damaged files. The input
+// need not contain a FLAC signature or metadata blocks.
+//
+// Frame headers are located using frame.SyncScanner, and frames are decoded and
+// verified against their CRC-16 checksum. Frames belong to the same sequence
+// if they are contiguous, have the same sample rate, sample size, channel
+// count and blocking strategy, and have consecutive frame numbers (or sample
+// numbers). Use CarvedStream.WriteTo to write a playable FLAC stream of each
+// sequence.
+func Carve(ra io.ReaderAt, size int64, opts *CarveOptions) ([]*CarvedStream, error) {
+	if opts == nil {
+		opts = &CarveOptions{}
+	}
+	minFrames := opts.MinFrames
+	if minFrames == 0 {
+		minFrames = defaultMinCarveFrames
+	}
+	hint := &frame.Header{SampleRate: opts.SampleRate, BitsPerSample: opts.BitsPerSample}
+	var streams []*CarvedStream
+	var cur *carveState
+	flush := func() {
+		if cur != nil && len(cur.stream.frames) >= minFrames {
+			streams = append(streams, cur.finish())
+		}
+		cur = nil
+	}
+	s := frame.NewSyncScanner(io.NewSectionReader(ra, 0, size))
+	for {
+		offset, _, err := s.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		f, cf, err := parseFrameAtOffset(ra, offset, size, hint)
+		if err != nil {
+			// False positive, or damaged frame.
+			continue
+		}
+		if cur == nil || !cur.follows(f, offset) {
+			flush()
+			cur = newCarveState(ra, f)
+		}
+		cur.add(f, cf)
+		if err := s.Skip(offset + int64(cf.size)); err != nil {
+			return nil, err
+		}
+	}
+	flush()
+	return streams, nil
+}
+
+// carveState tracks the state of a stream being carved.
+type carveState struct {
+	// Carved stream.
+	stream *CarvedStream
+	// Header of the first frame, with the sample rate and sample size of the
+	// stream.
+	first frame.Header
+	// Frame number (or sample number) expected of the next frame.
+	next uint64
+	// MD5 running hash of the decoded audio samples.
+	md5sum hash.Hash
+}
+
+// newCarveState returns the state of a new stream starting with the given
+// frame.
+func newCarveState(ra io.ReaderAt, f *frame.Frame) *carveState {
+	return &carveState{
+		stream: &CarvedStream{ra: ra},
+		first:  f.Header,
+		next:   f.Num,
+		md5sum: md5.New(),
+	}
+}
+
+// follows reports whether the given frame at the given byte offset continues
+// the stream.
+func (c *carveState) follows(f *frame.Frame, offset int64) bool {
+	last := c.stream.frames[len(c.stream.frames)-1]
+	switch {
+	case offset != last.offset+int64(last.size):
+		return false
+	case f.HasFixedBlockSize != c.first.HasFixedBlockSize:
+		return false
+	case f.SampleRate != c.first.SampleRate, f.BitsPerSample != c.first.BitsPerSample:
+		return false
+	case f.Channels.Count() != c.first.Channels.Count():
+		return false
+	case f.Num != c.next:
+		return false
+	}
+	// Only the last frame of fixed-blocksize streams may hold fewer samples.
+	return !f.HasFixedBlockSize || last.hdr.BlockSize == c.first.BlockSize
+}
+
+// add adds the given frame to the stream.
+func (c *carveState) add(f *frame.Frame, cf carvedFrame) {
+	c.stream.frames = append(c.stream.frames, cf)
+	if f.HasFixedBlockSize {
+		c.next = f.Num + 1
+	} else {
+		c.next = f.Num + uint64(f.BlockSize)
+	}
+	f.Hash(c.md5sum)
+}
+
+// finish synthesizes the StreamInfo metadata block of the stream, and returns
+// the stream.
+func (c *carveState) finish() *CarvedStream {
+	cs := c.stream
+	first, last := cs.frames[0], cs.frames[len(cs.frames)-1]
+	cs.Offset = first.offset
+	cs.Size = last.offset + int64(last.size) - first.offset
+	cs.NFrames = len(cs.frames)
+	info := &meta.StreamInfo{
+		SampleRate:    c.first.SampleRate,
+		NChannels:     uint8(c.first.Channels.Count()),
+		BitsPerSample: c.first.BitsPerSample,
+	}
+	for i, f := range cs.frames {
+		// The minimum block size excludes the last frame, unless it is the sole
+		// frame.
+		if i < len(cs.frames)-1 || len(cs.frames) == 1 {
+			if info.BlockSizeMin == 0 || f.hdr.BlockSize < info.BlockSizeMin {
+				info.BlockSizeMin = f.hdr.BlockSize
+			}
+		}
+		info.BlockSizeMax = max(info.BlockSizeMax, f.hdr.BlockSize)
+		if info.FrameSizeMin == 0 || uint32(f.size) < info.FrameSizeMin {
+			info.FrameSizeMin = uint32(f.size)
+		}
+		info.FrameSizeMax = max(info.FrameSizeMax, uint32(f.size))
+		info.NSamples += uint64(f.hdr.BlockSize)
+	}
+	info.BlockSizeMin = max(info.BlockSizeMin, frame.MinBlockSize)
+	info.BlockSizeMax = max(info.BlockSizeMax, info.BlockSizeMin)
+	copy(info.MD5sum[:], c.md5sum.Sum(nil))
+	cs.Info = info
+	return cs
+}
+
+// WriteTo writes the carved stream to w as a FLAC stream, consisting of the
+// FLAC signature, the synthesized StreamInfo metadata block and the frames of
+// the stream. The frames are renumbered to start at frame number (or sample
+// number) 0; their header and CRC-16 checksum are updated accordingly, and the
+// encoded audio samples are copied as is.
+func (cs *CarvedStream) WriteTo(w io.Writer) (n int64, err error) {
+	ow := &offsetWriter{w: w}
+	if err := encodeHeader(ow, cs.Info, nil); err != nil {
+		return ow.n, err
+	}
+	var num uint64
+	var buf []byte
+	hdrBuf := &bytes.Buffer{}
+	for _, f := range cs.frames {
+		if cap(buf) < f.size {
+			buf = make([]byte, f.size)
+		}
+		data := buf[:f.size]
+		if _, err := cs.ra.ReadAt(data, f.offset); err != nil {
+			return ow.n, err
+		}
+		hdr := f.hdr
+		hdr.Num = num
+		if hdr.HasFixedBlockSize {
+			num++
+		} else {
+			num += uint64(hdr.BlockSize)
+		}
+		hdrBuf.Reset()
+		if err := encodeFrameHeader(hdrBuf, hdr); err != nil {
+			return ow.n, err
+		}
+		body := data[f.hdrSize : f.size-2]
+		h := crc16.NewIBM()
+		h.Write(hdrBuf.Bytes())
+		h.Write(body)
+		if _, err := ow.Write(hdrBuf.Bytes()); err != nil {
+			return ow.n, err
+		}
+		if _, err := ow.Write(body); err != nil {
+			return ow.n, err
+		}
+		if err := binary.Write(ow, binary.BigEndian, h.Sum16()); err != nil {
+			return ow.n, err
+		}
+	}
+	return ow.n, nil
+}
+
+// parseFrameAtOffset parses the frame at the given byte offset of the first
+// size bytes of ra, and returns the frame and its location. The sample rate and
+// sample size of the given hint are used if unspecified by the frame header.
+func parseFrameAtOffset(ra io.ReaderAt, offset, size int64, hint *frame.Header) (*frame.Frame, carvedFrame, error) {
+	cr := &countReader{r: bufio.NewReader(io.NewSectionReader(ra, offset, size-offset))}
+	f, err := frame.New(cr)
+	if err != nil {
+		return nil, carvedFrame{}, err
+	}
+	cf := carvedFrame{hdr: f.Header, offset: offset, hdrSize: int(cr.n)}
+	if f.SampleRate == 0 {
+		f.SampleRate = hint.SampleRate
+	}
+	if f.BitsPerSample == 0 {
+		f.BitsPerSample = hint.BitsPerSample
+	}
+	if f.SampleRate == 0 || f.BitsPerSample == 0 {
+		return nil, carvedFrame{}, errors.New("flac.Carve: sample rate or sample size unspecified by frame header")
+	}
+	if err := f.Parse(); err != nil {
+		return nil, carvedFrame{}, err
+	}
+	cf.size = int(cr.n)
+	return f, cf, nil
+}
@@ -0,0 +1,123 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestCarve(t *testing.T) {
+	// frameData returns the audio frames of the given FLAC file, and the byte
+	// offset of each frame relative to the first.
+	frameData := func(path string) ([]byte, []int64) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := flac.NewSeek(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := stream.DataStart()
+		var offsets []int64
+		for {
+			offsets = append(offsets, stream.Offset()-start)
+			if _, err := stream.ParseNext(); err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+		}
+		return data[start:], offsets
+	}
+	love, loveOffsets := frameData("testdata/love.flac")
+	other, otherOffsets := frameData("testdata/19875.flac")
+
+	// Disk image holding the frames of love.flac, and a fragment of the frames
+	// of 19875.flac starting and ending within a frame; separated by random
+	// data.
+	rnd := rand.New(rand.NewSource(1))
+	garbage := func(n int) []byte {
+		buf := make([]byte, n)
+		rnd.Read(buf)
+		return buf
+	}
+	const (
+		firstFrame = 3
+		lastFrame  = 9
+	)
+	fragment := other[otherOffsets[firstFrame]-10 : otherOffsets[lastFrame+1]+20]
+	var img []byte
+	img = append(img, garbage(5000)...)
+	loveStart := len(img)
+	img = append(img, love...)
+	img = append(img, garbage(3000)...)
+	fragmentStart := len(img) + 10
+	img = append(img, fragment...)
+	img = append(img, garbage(1000)...)
+
+	streams, err := flac.Carve(bytes.NewReader(img), int64(len(img)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		offset  int
+		nframes int
+		frames  []byte
+	}{
+		{offset: loveStart, nframes: len(loveOffsets) - 1, frames: love},
+		{offset: fragmentStart, nframes: lastFrame - firstFrame + 1, frames: other[otherOffsets[firstFrame]:otherOffsets[lastFrame+1]]},
+	}
+	if len(streams) != len(want) {
+		t.Fatalf("number of carved streams mismatch; expected %d, got %d", len(want), len(streams))
+	}
+	dir := t.TempDir()
+	for i, cs := range streams {
+		if cs.Offset != int64(want[i].offset) || cs.NFrames != want[i].nframes || cs.Size != int64(len(want[i].frames)) {
+			t.Errorf("stream %d: expected %d frames (%d bytes) at offset %d, got %d frames (%d bytes) at offset %d", i, want[i].nframes, len(want[i].frames), want[i].offset, cs.NFrames, cs.Size, cs.Offset)
+			continue
+		}
+		// Verify the recovered stream against the original frames.
+		path := filepath.Join(dir, "carved.flac")
+		buf := &bytes.Buffer{}
+		if _, err := cs.WriteTo(buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		check, err := flac.FixMD5(path, true)
+		if err != nil {
+			t.Fatalf("stream %d: %v", i, err)
+		}
+		if check.Mismatch() {
+			t.Errorf("stream %d: MD5 checksum mismatch; expected %x, got %x", i, check.Computed, check.Stored)
+		}
+		got, err := flac.New(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotSamples, err := getSamples(got)
+		if err != nil {
+			t.Fatalf("stream %d: %v", i, err)
+		}
+		orig, err := flac.New(io.MultiReader(bytes.NewReader(buf.Bytes()[:got.DataStart()]), bytes.NewReader(want[i].frames)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantSamples, err := getSamples(orig)
+		if err != nil {
+			t.Fatalf("stream %d: %v", i, err)
+		}
+		if !slices.Equal(gotSamples, wantSamples) {
+			t.Errorf("stream %d: content mismatch", i)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package flac
+
+import (
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// Samples returns an iterator over the audio samples of the given channel of
+// the remaining frames of the stream, yielding the samples of one frame at a
+// time. The samples are the subframe samples of each frame, without
+// interleaving. The subframes of other channels are skipped without being
+// decoded if the channel is coded independently of the other channels, and are
+// decoded and discarded otherwise, e.g. for mid/side stereo frames; see
+// frame.Frame.ParseChannel. As such, the totals of the stream are not reported
+// (see DecodeOptions.OnTotals).
+//
+// The yielded slice is owned by the decoder, and is only valid until the next
+// iteration in low-memory mode. Decoding errors are yielded as a final nil
+// slice with a non-nil error; the iterator stops at the end of the stream
+// without error.
+//
+// Example:
+//
+//	for samples, err := range stream.Samples(0) {
+//		if err != nil {
+//			return err
+//		}
+//		for _, sample := range samples {
+//			...
+//		}
+//	}
+func (stream *Stream) Samples(channel int) iter.Seq2[[]int32, error] {
+	return func(yield func([]int32, error) bool) {
+		if channel < 0 || channel >= int(stream.Info.NChannels) {
+			yield(nil, fmt.Errorf("flac.Stream.Samples: invalid channel %d; expected >= 0 and < %d", channel, stream.Info.NChannels))
+			return
+		}
+		for {
+			// Decode into the frame buffer in low-memory mode.
+			f := stream.buf
+			if f == nil {
+				f = new(frame.Frame)
+			}
+			if err := stream.parseNextInto(f, channel); err != nil {
+				if err != io.EOF {
+					yield(nil, err)
+				}
+				return
+			}
+			if !yield(f.Subframes[channel].Samples, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Channels returns an iterator over the audio samples of the remaining frames
+// of the stream in channel-major order, yielding the samples of one frame at a
+// time as one slice per channel.
+//
+// The yielded slices are owned by the decoder, and are only valid until the
+// next iteration in low-memory mode. Decoding errors are yielded as a final nil
+// slice with a non-nil error; the iterator stops at the end of the stream
+// without error.
+func (stream *Stream) Channels() iter.Seq2[[][]int32, error] {
+	return func(yield func([][]int32, error) bool) {
+		for {
+			f, err := stream.ParseNext()
+			if err != nil {
+				if err != io.EOF {
+					yield(nil, err)
+				}
+				return
+			}
+			channels := make([][]int32, len(f.Subframes))
+			for i, subframe := range f.Subframes {
+				channels[i] = subframe.Samples
+			}
+			if !yield(channels, nil) {
+				return
+			}
+		}
+	}
+}
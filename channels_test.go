@@ -0,0 +1,94 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+// decodeChannels returns the audio samples of the FLAC file at path, one slice
+// per channel.
+func decodeChannels(t *testing.T, path string) [][]int32 {
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	channels := make([][]int32, stream.Info.NChannels)
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		for i, subframe := range f.Subframes {
+			channels[i] = append(channels[i], subframe.Samples...)
+		}
+	}
+	return channels
+}
+
+func TestSamples(t *testing.T) {
+	for _, path := range []string{"testdata/19875.flac", "testdata/59996.flac", "testdata/love.flac"} {
+		want := decodeChannels(t, path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for channel := range want {
+			for _, lowMemory := range []bool{false, true} {
+				stream, err := flac.NewWithOptions(bytes.NewReader(data), &flac.DecodeOptions{LowMemory: lowMemory})
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []int32
+				for samples, err := range stream.Samples(channel) {
+					if err != nil {
+						t.Fatalf("%q: unable to decode samples of channel %d; %v", path, channel, err)
+					}
+					got = append(got, samples...)
+				}
+				if !slices.Equal(got, want[channel]) {
+					t.Errorf("%q: sample mismatch in channel %d (low-memory mode %v)", path, channel, lowMemory)
+				}
+			}
+		}
+	}
+}
+
+func TestChannels(t *testing.T) {
+	const path = "testdata/love.flac"
+	want := decodeChannels(t, path)
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	got := make([][]int32, len(want))
+	for channels, err := range stream.Channels() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, samples := range channels {
+			got[i] = append(got[i], samples...)
+		}
+	}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("sample mismatch in channel %d", i)
+		}
+	}
+
+	// Invalid channel.
+	for _, err := range stream.Samples(2) {
+		if err == nil {
+			t.Error("expected error for invalid channel, got nil")
+		}
+	}
+}
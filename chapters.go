@@ -0,0 +1,339 @@
+package flac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// A Chapter is a named marker of a FLAC stream, such as the start of a track
+// of an album or a chapter of an audiobook.
+type Chapter struct {
+	// Chapter (or track) number, starting at 1.
+	Num int
+	// Chapter title; empty if unknown.
+	Title string
+	// Chapter performer; empty if unknown.
+	Performer string
+	// Sample number of the first sample of the chapter.
+	SampleNum uint64
+}
+
+// Chapters returns the chapters of the stream, from the first of the following
+// sources present in the metadata blocks of the stream:
+//
+//  1. the CueSheet metadata block; titles are taken from a CUESHEET tag if
+//     present, as the CueSheet block stores no titles.
+//  2. a cue sheet stored in the CUESHEET tag of the VorbisComment block.
+//  3. the CHAPTERxxx and CHAPTERxxxNAME tags of the VorbisComment block, as
+//     used by Ogg Vorbis.
+//
+// Chapters are returned in order of sample number; a nil slice is returned if
+// the stream holds no chapters.
+func (stream *Stream) Chapters() ([]Chapter, error) {
+	var cueSheet *meta.CueSheet
+	var tags [][2]string
+	for _, block := range stream.Blocks {
+		switch body := block.Body.(type) {
+		case *meta.CueSheet:
+			if cueSheet == nil {
+				cueSheet = body
+			}
+		case *meta.VorbisComment:
+			tags = append(tags, body.Tags...)
+		}
+	}
+	sampleRate := stream.Info.SampleRate
+	var cueChapters []Chapter
+	if cue, ok := findTag(tags, "CUESHEET"); ok {
+		chapters, err := ParseCueChapters(strings.NewReader(cue), sampleRate)
+		if err != nil {
+			return nil, err
+		}
+		cueChapters = chapters
+	}
+	if cueSheet != nil {
+		chapters := cueSheetChapters(cueSheet)
+		// Titles and performers of the CUESHEET tag.
+		for i := range chapters {
+			for _, c := range cueChapters {
+				if c.Num == chapters[i].Num {
+					chapters[i].Title, chapters[i].Performer = c.Title, c.Performer
+				}
+			}
+		}
+		return chapters, nil
+	}
+	if cueChapters != nil {
+		return cueChapters, nil
+	}
+	return vorbisChapters(tags, sampleRate)
+}
+
+// cueSheetChapters returns the chapters of the given CueSheet metadata block;
+// one per track, starting at index point 1 of the track (or the first index
+// point if absent). The lead-out track is excluded.
+func cueSheetChapters(cueSheet *meta.CueSheet) []Chapter {
+	var chapters []Chapter
+	for _, track := range cueSheet.Tracks {
+		// Lead-out track; 170 for CD-DA and 255 otherwise.
+		if track.Num == 170 || track.Num == 255 || len(track.Indicies) == 0 {
+			continue
+		}
+		index := track.Indicies[0]
+		for _, idx := range track.Indicies {
+			if idx.Num == 1 {
+				index = idx
+				break
+			}
+		}
+		chapters = append(chapters, Chapter{
+			Num:       int(track.Num),
+			SampleNum: track.Offset + index.Offset,
+		})
+	}
+	return chapters
+}
+
+// ParseCueChapters parses the given cue sheet text (e.g. the contents of an
+// external .cue file, or a CUESHEET tag), and returns one chapter per track,
+// starting at index point 1 of the track (or the first index point if absent).
+// Timestamps of the cue sheet, in MM:SS:FF format with 75 frames per second,
+// are converted to sample numbers using the given sample rate.
+//
+// Only the tracks of the first FILE of the cue sheet are returned, as sample
+// numbers of subsequent files are relative to those files.
+func ParseCueChapters(r io.Reader, sampleRate uint32) ([]Chapter, error) {
+	if sampleRate == 0 {
+		return nil, fmt.Errorf("flac.ParseCueChapters: invalid sample rate %d", sampleRate)
+	}
+	var chapters []Chapter
+	// Current track; nil before the first TRACK command.
+	var track *Chapter
+	// Number of the first index point parsed of the current track; or -1 if
+	// none.
+	index := -1
+	nfiles := 0
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		fields := cueFields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			nfiles++
+		case "TRACK":
+			if nfiles > 1 {
+				continue
+			}
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("flac.ParseCueChapters: missing track number on line %d", lineNum)
+			}
+			num, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("flac.ParseCueChapters: invalid track number %q on line %d", fields[1], lineNum)
+			}
+			chapters = append(chapters, Chapter{Num: num})
+			track = &chapters[len(chapters)-1]
+			index = -1
+		case "TITLE":
+			if track != nil && len(fields) >= 2 && nfiles <= 1 {
+				track.Title = fields[1]
+			}
+		case "PERFORMER":
+			if track != nil && len(fields) >= 2 && nfiles <= 1 {
+				track.Performer = fields[1]
+			}
+		case "INDEX":
+			if track == nil || nfiles > 1 {
+				continue
+			}
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("flac.ParseCueChapters: missing index point on line %d", lineNum)
+			}
+			num, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("flac.ParseCueChapters: invalid index number %q on line %d", fields[1], lineNum)
+			}
+			sampleNum, err := parseCueTime(fields[2], sampleRate)
+			if err != nil {
+				return nil, fmt.Errorf("flac.ParseCueChapters: invalid index point %q on line %d; %v", fields[2], lineNum, err)
+			}
+			// Index point 1 marks the start of the track, and takes precedence
+			// over the pregap of index point 0.
+			if index == -1 || num == 1 && index != 1 {
+				track.SampleNum = sampleNum
+				index = num
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].SampleNum < chapters[j].SampleNum
+	})
+	return chapters, nil
+}
+
+// cueFields splits the given line of a cue sheet into fields, separated by
+// white space. Double-quoted fields may contain white space.
+func cueFields(line string) []string {
+	var fields []string
+	line = strings.TrimSpace(line)
+	for len(line) > 0 {
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end == -1 {
+				// Unterminated quote.
+				fields = append(fields, line[1:])
+				break
+			}
+			fields = append(fields, line[1:1+end])
+			line = strings.TrimSpace(line[end+2:])
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end == -1 {
+			fields = append(fields, line)
+			break
+		}
+		fields = append(fields, line[:end])
+		line = strings.TrimSpace(line[end:])
+	}
+	return fields
+}
+
+// parseCueTime parses the given cue sheet timestamp in MM:SS:FF format, with 75
+// frames per second, and returns the corresponding sample number.
+func parseCueTime(s string, sampleRate uint32) (uint64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("expected MM:SS:FF format")
+	}
+	var x [3]uint64
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		x[i] = v
+	}
+	min, sec, frames := x[0], x[1], x[2]
+	if sec >= 60 || frames >= 75 {
+		return 0, fmt.Errorf("seconds or frames out of range")
+	}
+	rate := uint64(sampleRate)
+	return (min*60+sec)*rate + frames*rate/75, nil
+}
+
+// vorbisChapters returns the chapters of the given CHAPTERxxx and
+// CHAPTERxxxNAME tags, with timestamps in HH:MM:SS.sss format.
+//
+// ref: https://wiki.xiph.org/Chapter_Extension
+func vorbisChapters(tags [][2]string, sampleRate uint32) ([]Chapter, error) {
+	byNum := make(map[int]*Chapter)
+	var chapters []*Chapter
+	for _, tag := range tags {
+		name := strings.ToUpper(tag[0])
+		if !strings.HasPrefix(name, "CHAPTER") {
+			continue
+		}
+		rest := name[len("CHAPTER"):]
+		i := 0
+		for i < len(rest) && '0' <= rest[i] && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			continue
+		}
+		num, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			continue
+		}
+		c, ok := byNum[num]
+		if !ok {
+			c = &Chapter{Num: num}
+			byNum[num] = c
+			chapters = append(chapters, c)
+		}
+		switch rest[i:] {
+		case "":
+			sampleNum, err := parseChapterTime(tag[1], sampleRate)
+			if err != nil {
+				return nil, fmt.Errorf("flac.Stream.Chapters: invalid timestamp %q of tag %s; %v", tag[1], tag[0], err)
+			}
+			c.SampleNum = sampleNum
+		case "NAME":
+			c.Title = tag[1]
+		}
+	}
+	if len(chapters) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].SampleNum < chapters[j].SampleNum
+	})
+	result := make([]Chapter, len(chapters))
+	for i, c := range chapters {
+		result[i] = *c
+	}
+	return result, nil
+}
+
+// parseChapterTime parses the given chapter timestamp in HH:MM:SS.sss format,
+// with an arbitrary number of fractional digits, and returns the corresponding
+// sample number.
+func parseChapterTime(s string, sampleRate uint32) (uint64, error) {
+	if sampleRate == 0 {
+		return 0, fmt.Errorf("sample rate not specified")
+	}
+	clock, frac, _ := strings.Cut(s, ".")
+	parts := strings.Split(clock, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("expected HH:MM:SS.sss format")
+	}
+	var secs uint64
+	for _, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		secs = secs*60 + v
+	}
+	rate := uint64(sampleRate)
+	sampleNum := secs * rate
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		v, err := strconv.ParseUint(frac, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		scale := uint64(1)
+		for range frac {
+			scale *= 10
+		}
+		// Round to the nearest sample.
+		sampleNum += (v*rate + scale/2) / scale
+	}
+	return sampleNum, nil
+}
+
+// findTag returns the value of the first tag with the given name, compared
+// case-insensitively.
+func findTag(tags [][2]string, name string) (string, bool) {
+	for _, tag := range tags {
+		if strings.EqualFold(tag[0], name) {
+			return tag[1], true
+		}
+	}
+	return "", false
+}
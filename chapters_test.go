@@ -0,0 +1,116 @@
+package flac_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestParseCueChapters(t *testing.T) {
+	const cue = `REM GENRE Ambient
+PERFORMER "Various"
+TITLE "Album"
+FILE "album.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "Intro"
+    PERFORMER "First Artist"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Second Song"
+    INDEX 00 03:15:50
+    INDEX 01 03:17:00
+  TRACK 03 AUDIO
+    TITLE "Last"
+    INDEX 01 10:00:74
+`
+	got, err := flac.ParseCueChapters(strings.NewReader(cue), 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []flac.Chapter{
+		{Num: 1, Title: "Intro", Performer: "First Artist", SampleNum: 0},
+		{Num: 2, Title: "Second Song", SampleNum: 197 * 44100},
+		{Num: 3, Title: "Last", SampleNum: 600*44100 + 74*44100/75},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("chapters mismatch; expected %+v, got %+v", want, got)
+	}
+
+	if _, err := flac.ParseCueChapters(strings.NewReader("TRACK 01 AUDIO\nINDEX 01 00:61:00\n"), 44100); err == nil {
+		t.Error("expected error for invalid index point")
+	}
+}
+
+func TestChapters(t *testing.T) {
+	golden := []struct {
+		name   string
+		blocks []*meta.Block
+		want   []flac.Chapter
+	}{
+		{
+			name: "vorbis",
+			blocks: []*meta.Block{
+				{Body: &meta.VorbisComment{Tags: [][2]string{
+					{"CHAPTER002", "00:01:30.500"},
+					{"CHAPTER002NAME", "Second"},
+					{"chapter001", "00:00:00.000"},
+					{"chapter001name", "First"},
+					{"ARTIST", "Someone"},
+				}}},
+			},
+			want: []flac.Chapter{
+				{Num: 1, Title: "First", SampleNum: 0},
+				{Num: 2, Title: "Second", SampleNum: 90*48000 + 24000},
+			},
+		},
+		{
+			name: "cuesheet tag",
+			blocks: []*meta.Block{
+				{Body: &meta.VorbisComment{Tags: [][2]string{
+					{"CUESHEET", "FILE \"a.flac\" WAVE\nTRACK 01 AUDIO\nTITLE \"One\"\nINDEX 01 00:02:00\n"},
+					{"CHAPTER001", "00:00:05.000"},
+				}}},
+			},
+			want: []flac.Chapter{
+				{Num: 1, Title: "One", SampleNum: 2 * 48000},
+			},
+		},
+		{
+			name: "cuesheet block",
+			blocks: []*meta.Block{
+				{Body: &meta.CueSheet{Tracks: []meta.CueSheetTrack{
+					{Num: 1, Offset: 0, Indicies: []meta.CueSheetTrackIndex{{Num: 1}}},
+					{Num: 2, Offset: 48000, Indicies: []meta.CueSheetTrackIndex{{Num: 0}, {Num: 1, Offset: 588}}},
+					{Num: 255, Offset: 96000},
+				}}},
+				{Body: &meta.VorbisComment{Tags: [][2]string{
+					{"CUESHEET", "TRACK 02 AUDIO\nTITLE \"Two\"\nINDEX 01 00:01:00\n"},
+				}}},
+			},
+			want: []flac.Chapter{
+				{Num: 1, SampleNum: 0},
+				{Num: 2, Title: "Two", SampleNum: 48588},
+			},
+		},
+		{
+			name: "none",
+		},
+	}
+	for _, g := range golden {
+		stream := &flac.Stream{
+			Info:   &meta.StreamInfo{SampleRate: 48000},
+			Blocks: g.blocks,
+		}
+		got, err := stream.Chapters()
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, g.want) {
+			t.Errorf("%s: chapters mismatch; expected %+v, got %+v", g.name, g.want, got)
+		}
+	}
+}
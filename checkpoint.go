@@ -0,0 +1,219 @@
+package flac
+
+import (
+	"crypto/md5"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mewkiz/pkg/errutil"
+)
+
+// A Checkpoint records the state of an encoder at a point where the output
+// stream was a valid FLAC stream, as committed by Encoder.Checkpoint. It
+// enables an interrupted encoder to be resumed using ResumeEncoder; e.g. to
+// continue a live capture after a crash.
+type Checkpoint struct {
+	// Size in bytes of the output stream at the checkpoint.
+	Offset int64 `json:"offset"`
+	// Byte offset of the last frame of the output stream; or 0 if no frame has
+	// been written.
+	LastFrameOffset int64 `json:"last_frame_offset"`
+	// Total number of samples (per channel) of the output stream.
+	NSamples uint64 `json:"nsamples"`
+	// Frame number of the next frame if block size is fixed, and the first
+	// sample number of the next frame otherwise.
+	Num uint64 `json:"num"`
+	// Minimum block size (in samples) of frames, excluding the last frame, and
+	// maximum block size of frames.
+	BlockSizeMin uint16 `json:"block_size_min"`
+	BlockSizeMax uint16 `json:"block_size_max"`
+	// Block size (in samples) of the last frame.
+	LastBlockSize uint16 `json:"last_block_size"`
+	// Internal state of the running MD5 hash of the unencoded audio samples.
+	MD5 []byte `json:"md5"`
+}
+
+// ReadCheckpoint reads the checkpoint stored in the given sidecar file.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(buf, cp); err != nil {
+		return nil, fmt.Errorf("flac.ReadCheckpoint: invalid checkpoint %q; %w", path, err)
+	}
+	return cp, nil
+}
+
+// WriteFile writes the checkpoint to the given sidecar file. The file is
+// replaced atomically, so that a previous checkpoint remains intact if writing
+// is interrupted.
+func (cp *Checkpoint) WriteFile(path string) error {
+	buf, err := json.Marshal(cp)
+	if err != nil {
+		return errutil.Err(err)
+	}
+	return writeFileAtomic(path, buf)
+}
+
+// writeFileAtomic writes data to the given file, replacing the file atomically
+// through a temporary file in the same directory.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Checkpoint writes pending frames to the output stream and records the state
+// of the encoder. If the underlying io.Writer implements io.WriteSeeker, the
+// StreamInfo metadata block is updated to describe the frames written so far,
+// as done by Close; and if the io.Writer implements Sync (e.g. *os.File), the
+// output stream is committed to stable storage. The checkpoint is written to
+// EncodeOptions.CheckpointPath if specified.
+//
+// Checkpoints are taken periodically if EncodeOptions.CheckpointInterval is
+// specified.
+func (enc *Encoder) Checkpoint() (*Checkpoint, error) {
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	if ws, ok := enc.w.(io.WriteSeeker); ok {
+		if err := enc.updateStreamInfo(ws); err != nil {
+			return nil, err
+		}
+		if _, err := ws.Seek(enc.ow.n, io.SeekStart); err != nil {
+			return nil, errutil.Err(err)
+		}
+	}
+	if s, ok := enc.w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return nil, errutil.Err(err)
+		}
+	}
+	state, err := enc.md5sum.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, errutil.Err(err)
+	}
+	cp := &Checkpoint{
+		Offset:          enc.ow.n,
+		LastFrameOffset: enc.lastFrameOffset,
+		NSamples:        enc.nsamples,
+		Num:             enc.curNum,
+		BlockSizeMin:    enc.blockSizeMin,
+		BlockSizeMax:    enc.blockSizeMax,
+		LastBlockSize:   enc.lastBlockSize,
+		MD5:             state,
+	}
+	enc.checkpointSamples = enc.nsamples
+	if enc.opts.CheckpointPath != "" {
+		if err := cp.WriteFile(enc.opts.CheckpointPath); err != nil {
+			return nil, err
+		}
+	}
+	return cp, nil
+}
+
+// periodicCheckpoint takes a checkpoint if the duration of audio written since
+// the last checkpoint has reached EncodeOptions.CheckpointInterval.
+func (enc *Encoder) periodicCheckpoint() error {
+	if enc.opts.CheckpointInterval <= 0 {
+		return nil
+	}
+	interval := uint64(enc.opts.CheckpointInterval.Seconds() * float64(enc.Info.SampleRate))
+	if enc.nsamples-enc.checkpointSamples < max(interval, 1) {
+		return nil
+	}
+	_, err := enc.Checkpoint()
+	return err
+}
+
+// ResumeEncoder returns a new FLAC encoder which resumes encoding to the output
+// stream of an interrupted encoder, from the given checkpoint of the encoder.
+// The stream header is parsed from the start of rw, and any data following the
+// checkpoint is discarded; rw is truncated at the checkpoint if it implements
+// Truncate (e.g. *os.File). A nil options value specifies the default options.
+// The metadata blocks of the output stream are retained as is; the layout
+// options BlockOrder, Padding and SeekTableInterval are ignored.
+//
+// The resumed encoder may be closed without writing frames, to recover a valid
+// FLAC stream of the audio samples written before the checkpoint.
+func ResumeEncoder(rw io.ReadWriteSeeker, cp *Checkpoint, opts *EncodeOptions) (*Encoder, error) {
+	if opts == nil {
+		opts = &EncodeOptions{}
+	}
+	if opts.MaxFrameSize < 0 || opts.PadFrames && opts.MaxFrameSize == 0 {
+		return nil, errutil.Newf("invalid maximum frame size %d", opts.MaxFrameSize)
+	}
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return nil, errutil.Err(err)
+	}
+	stream, err := Parse(rw)
+	if err != nil {
+		return nil, err
+	}
+	lpc, err := newLPCConfig(stream.Info, opts)
+	if err != nil {
+		return nil, errutil.Err(err)
+	}
+	if cp.Offset < stream.DataStart() || cp.LastFrameOffset > cp.Offset {
+		return nil, fmt.Errorf("flac.ResumeEncoder: checkpoint offset %d outside of the audio frames of the stream, starting at offset %d", cp.Offset, stream.DataStart())
+	}
+	md5sum := md5.New()
+	if err := md5sum.(encoding.BinaryUnmarshaler).UnmarshalBinary(cp.MD5); err != nil {
+		return nil, fmt.Errorf("flac.ResumeEncoder: invalid MD5 state of checkpoint; %w", err)
+	}
+	if t, ok := rw.(interface{ Truncate(size int64) error }); ok {
+		if err := t.Truncate(cp.Offset); err != nil {
+			return nil, errutil.Err(err)
+		}
+	}
+	if _, err := rw.Seek(cp.Offset, io.SeekStart); err != nil {
+		return nil, errutil.Err(err)
+	}
+	enc := &Encoder{
+		Stream: &Stream{
+			Info:   stream.Info,
+			Blocks: stream.Blocks,
+		},
+		w:                 rw,
+		ow:                &offsetWriter{w: rw, n: cp.Offset},
+		blockSizeMin:      cp.BlockSizeMin,
+		blockSizeMax:      cp.BlockSizeMax,
+		lastBlockSize:     cp.LastBlockSize,
+		frameSizeMin:      stream.Info.FrameSizeMin,
+		frameSizeMax:      stream.Info.FrameSizeMax,
+		md5sum:            md5sum,
+		nsamples:          cp.NSamples,
+		curNum:            cp.Num,
+		AnalysisEnabled:   true, // enable prediction analysis by default.
+		opts:              *opts,
+		lpc:               lpc,
+		lastFrameOffset:   cp.LastFrameOffset,
+		checkpointSamples: cp.NSamples,
+		dataStart:         stream.DataStart(),
+		nsamplesWritten:   cp.NSamples,
+	}
+	return enc, nil
+}
@@ -0,0 +1,137 @@
+package flac_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	const (
+		blockSize = 1024
+		nframes   = 10
+		crashAt   = 7
+	)
+	info := &meta.StreamInfo{
+		BlockSizeMin:  blockSize,
+		BlockSizeMax:  blockSize,
+		SampleRate:    44100,
+		NChannels:     2,
+		BitsPerSample: 16,
+	}
+	for _, workers := range []int{0, 4} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "capture.flac")
+		cpPath := filepath.Join(dir, "capture.checkpoint")
+		opts := &flac.EncodeOptions{
+			Workers:            workers,
+			CheckpointInterval: 50 * time.Millisecond,
+			CheckpointPath:     cpPath,
+		}
+
+		// Capture until crash, leaving a partially written frame.
+		w, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := flac.NewEncoderWithOptions(w, info, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for num := 0; num < crashAt; num++ {
+			if err := enc.WriteFrame(makeTestFrame(info, num)); err != nil {
+				t.Fatalf("workers %d: unable to write frame %d; %v", workers, num, err)
+			}
+		}
+		if _, err := w.Write([]byte{0xFF, 0xF8, 0x69, 0x08}); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+
+		// Resume capture from the last checkpoint.
+		cp, err := flac.ReadCheckpoint(cpPath)
+		if err != nil {
+			t.Fatalf("workers %d: unable to read checkpoint; %v", workers, err)
+		}
+		if cp.NSamples == 0 || cp.NSamples%blockSize != 0 || cp.NSamples >= crashAt*blockSize {
+			t.Fatalf("workers %d: unexpected number of samples at checkpoint; got %d", workers, cp.NSamples)
+		}
+		rw, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err = flac.ResumeEncoder(rw, cp, opts)
+		if err != nil {
+			t.Fatalf("workers %d: unable to resume encoder; %v", workers, err)
+		}
+		for num := int(cp.NSamples / blockSize); num < nframes; num++ {
+			if err := enc.WriteFrame(makeTestFrame(info, num)); err != nil {
+				t.Fatalf("workers %d: unable to write frame %d; %v", workers, num, err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Verify resumed stream.
+		stream, err := flac.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for num := 0; ; num++ {
+			f, err := stream.ParseNext()
+			if err != nil {
+				if num != nframes {
+					t.Fatalf("workers %d: frame count mismatch; expected %d, got %d (%v)", workers, nframes, num, err)
+				}
+				break
+			}
+			if f.Num != uint64(num) {
+				t.Errorf("workers %d: frame number mismatch; expected %d, got %d", workers, num, f.Num)
+			}
+		}
+		stream.Close()
+		if got, want := stream.Info.NSamples, uint64(nframes*blockSize); got != want {
+			t.Errorf("workers %d: number of samples mismatch; expected %d, got %d", workers, want, got)
+		}
+		check, err := flac.FixMD5(path, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if check.Mismatch() {
+			t.Errorf("workers %d: MD5 checksum mismatch; expected %x, got %x", workers, check.Computed, check.Stored)
+		}
+	}
+}
+
+// makeTestFrame returns the given frame of a synthetic recording with the given
+// stream properties, of fixed block size.
+func makeTestFrame(info *meta.StreamInfo, num int) *frame.Frame {
+	blockSize := int(info.BlockSizeMax)
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(blockSize),
+			SampleRate:        info.SampleRate,
+			Channels:          frame.Channels(info.NChannels - 1),
+			BitsPerSample:     info.BitsPerSample,
+		},
+	}
+	for channel := 0; channel < int(info.NChannels); channel++ {
+		samples := make([]int32, blockSize)
+		for i := range samples {
+			samples[i] = int32((num*blockSize+i)*(channel+3)%4000 - 2000)
+		}
+		f.Subframes = append(f.Subframes, &frame.Subframe{
+			SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+			Samples:   samples,
+			NSamples:  blockSize,
+		})
+	}
+	return f
+}
@@ -0,0 +1,264 @@
+// The flacfix tool repairs common defects of FLAC files.
+//
+// Usage:
+//
+//	flacfix md5 [OPTION]... FILE...
+//	flacfix carve [OPTION]... FILE...
+//	flacfix verify FILE...
+//	flacfix ape [OPTION]... FILE...
+//
+// Verbs:
+//
+//	md5
+//	   Recompute the MD5 checksum of the decoded audio samples, and patch the
+//	   StreamInfo block if the stored checksum differs or is unset.
+//	carve
+//	   Recover FLAC audio frames from raw data (e.g. disk images), and store
+//	   each sequence of consecutive frames as FILE-OFFSET.flac in the output
+//	   directory.
+//	verify
+//	   Decode the audio frames, and report all damaged regions with their
+//	   byte offsets and timestamps, instead of stopping at the first error.
+//	ape
+//	   Migrate the text items of an appended APEv2 tag into the Vorbis comment,
+//	   and strip the APEv2 tag.
+//
+// Flags:
+//
+//	-bps uint
+//	      bits-per-sample of carved frames which leave it unspecified
+//	-n    dry run; report mismatches, carved streams or APEv2 tags without writing files
+//	-o string
+//	      output directory of carved streams (default ".")
+//	-rate uint
+//	      sample rate of carved frames which leave it unspecified
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/mewkiz/flac"
+)
+
+func usage() {
+	const use = `
+Usage:
+
+	flacfix md5 [OPTION]... FILE...
+	flacfix carve [OPTION]... FILE...
+	flacfix verify FILE...
+	flacfix ape [OPTION]... FILE...
+
+Verbs:
+
+	md5
+	   Recompute the MD5 checksum of the decoded audio samples, and patch the
+	   StreamInfo block if the stored checksum differs or is unset.
+	carve
+	   Recover FLAC audio frames from raw data (e.g. disk images), and store
+	   each sequence of consecutive frames as FILE-OFFSET.flac in the output
+	   directory.
+	verify
+	   Decode the audio frames, and report all damaged regions with their
+	   byte offsets and timestamps, instead of stopping at the first error.
+	ape
+	   Migrate the text items of an appended APEv2 tag into the Vorbis comment,
+	   and strip the APEv2 tag.
+
+Flags:
+`
+	fmt.Fprintln(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("flacfix: ")
+	var (
+		dryRun     bool
+		outputDir  string
+		sampleRate uint
+		bps        uint
+	)
+	flag.BoolVar(&dryRun, "n", false, "dry run; report mismatches, carved streams or APEv2 tags without writing files")
+	flag.StringVar(&outputDir, "o", ".", "output directory of carved streams")
+	flag.UintVar(&sampleRate, "rate", 0, "sample rate of carved frames which leave it unspecified")
+	flag.UintVar(&bps, "bps", 0, "bits-per-sample of carved frames which leave it unspecified")
+	flag.Usage = usage
+	if len(os.Args) < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	verb := os.Args[1]
+	if err := flag.CommandLine.Parse(os.Args[2:]); err != nil {
+		os.Exit(1)
+	}
+	if verb != "md5" && verb != "carve" && verb != "verify" && verb != "ape" {
+		log.Printf("unknown verb %q", verb)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	ok := true
+	for _, path := range flag.Args() {
+		switch verb {
+		case "md5":
+			if !fixMD5(path, dryRun) {
+				ok = false
+			}
+		case "carve":
+			opts := &flac.CarveOptions{SampleRate: uint32(sampleRate), BitsPerSample: uint8(bps)}
+			if err := carve(path, outputDir, opts, dryRun); err != nil {
+				log.Printf("%s: %v", path, err)
+				ok = false
+			}
+		case "verify":
+			if !verify(path) {
+				ok = false
+			}
+		case "ape":
+			if err := stripAPE(path, dryRun); err != nil {
+				log.Printf("%s: %v", path, err)
+				ok = false
+			}
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// fixMD5 recomputes and patches the MD5 checksum of the given FLAC file. It
+// reports whether the file was valid or fixed.
+func fixMD5(path string, dryRun bool) bool {
+	check, err := flac.FixMD5(path, dryRun)
+	if err != nil {
+		log.Printf("%s: %v", path, err)
+		return false
+	}
+	switch {
+	case !check.Mismatch():
+		fmt.Printf("%s: ok\n", path)
+		return true
+	case check.Fixed:
+		fmt.Printf("%s: fixed; stored %032x, computed %032x\n", path, check.Stored, check.Computed)
+		return true
+	default:
+		fmt.Printf("%s: mismatch; stored %032x, computed %032x\n", path, check.Stored, check.Computed)
+		return false
+	}
+}
+
+// verify maps the damaged regions of the given FLAC file. It reports whether
+// the file is intact.
+func verify(path string) bool {
+	m, err := flac.MapDamageFile(path)
+	if err != nil {
+		log.Printf("%s: %v", path, err)
+		return false
+	}
+	if len(m.Regions) == 0 {
+		fmt.Printf("%s: ok; %d frames\n", path, m.Frames)
+		return true
+	}
+	fmt.Printf("%s: %d damaged regions (%v); %d intact frames\n", path, len(m.Regions), m.Duration(), m.Frames)
+	for _, region := range m.Regions {
+		fmt.Printf("%s: %v\n", path, region)
+	}
+	return false
+}
+
+// stripAPE migrates the text items of the APEv2 tag of the given FLAC file into
+// its Vorbis comment, and strips the APEv2 tag.
+func stripAPE(path string, dryRun bool) error {
+	var tag *flac.APETag
+	if dryRun {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		trailer, err := flac.ReadAPETrailer(f, fi.Size())
+		if err != nil || trailer == nil {
+			return err
+		}
+		if tag, err = flac.ParseAPETag(trailer.Data); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if tag, err = flac.StripAPETag(path, path, true); err != nil {
+			return err
+		}
+	}
+	if tag == nil {
+		fmt.Printf("%s: no APEv2 tag\n", path)
+		return nil
+	}
+	for _, item := range tag.Items {
+		if item.Type != flac.APEText {
+			fmt.Printf("%s: %s: %v item (%d bytes) not migrated\n", path, item.Key, item.Type, len(item.Value))
+			continue
+		}
+		fmt.Printf("%s: %s=%q\n", path, item.Key, item.Values())
+	}
+	return nil
+}
+
+// carve recovers the FLAC audio frames of the given file, and stores each
+// recovered stream in the output directory.
+func carve(path, outputDir string, opts *flac.CarveOptions, dryRun bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	streams, err := flac.Carve(f, fi.Size(), opts)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		fmt.Printf("%s: no FLAC frames found\n", path)
+		return nil
+	}
+	for _, cs := range streams {
+		info := cs.Info
+		fmt.Printf("%s: offset %d: %d frames (%d bytes); %d Hz, %d bits-per-sample, %d channels, %d samples\n", path, cs.Offset, cs.NFrames, cs.Size, info.SampleRate, info.BitsPerSample, info.NChannels, info.NSamples)
+		if dryRun {
+			continue
+		}
+		name := fmt.Sprintf("%s-%d.flac", filepath.Base(path), cs.Offset)
+		if err := writeCarved(filepath.Join(outputDir, name), cs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCarved writes the given carved stream to the given FLAC file.
+func writeCarved(path string, cs *flac.CarvedStream) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := cs.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,576 @@
+// The flacgen tool generates a corpus of small FLAC files, for testing FLAC
+// decoders. Each file exercises a specific feature of the format; e.g. a
+// subframe type, a channel assignment, a boundary block size, escaped Rice
+// partitions, wasted bits or a sample size between 4 and 32 bits-per-sample.
+//
+// The audio samples of each file are synthesized deterministically, and each
+// subframe is encoded exactly as specified by the test vector, without
+// prediction analysis. A manifest listing the file name, the MD5 checksum of
+// the decoded audio samples and a description of each file is written to
+// MANIFEST in the output directory.
+//
+// Usage:
+//
+//	flacgen [OPTION]...
+//
+// Flags:
+//
+//	-list
+//	      list test vectors without writing files
+//	-o string
+//	      output directory (default ".")
+//	-run string
+//	      only generate test vectors whose name matches the regular expression
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/bits"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+func usage() {
+	const use = `
+Usage:
+
+	flacgen [OPTION]...
+
+Flags:
+`
+	fmt.Fprintln(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("flacgen: ")
+	var (
+		list      bool
+		outputDir string
+		run       string
+	)
+	flag.BoolVar(&list, "list", false, "list test vectors without writing files")
+	flag.StringVar(&outputDir, "o", ".", "output directory")
+	flag.StringVar(&run, "run", "", "only generate test vectors whose name matches the regular expression")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	re, err := regexp.Compile(run)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var manifest strings.Builder
+	for _, v := range corpus() {
+		if !re.MatchString(v.name) {
+			continue
+		}
+		if list {
+			fmt.Printf("%s.flac\t%s\n", v.name, v.desc)
+			continue
+		}
+		path := filepath.Join(outputDir, v.name+".flac")
+		md5sum, err := v.write(path)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		fmt.Fprintf(&manifest, "%s.flac\t%032x\t%s\n", v.name, md5sum, v.desc)
+	}
+	if list {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "MANIFEST"), []byte(manifest.String()), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// sampleRate specifies the sample rate of the test vectors.
+const sampleRate = 44100
+
+// A vector is a test vector of the corpus.
+type vector struct {
+	// File name, without extension.
+	name string
+	// Description of the features exercised.
+	desc string
+	// Sample size in bits-per-sample, and number of channels.
+	bps       uint8
+	nchannels int
+	// Variable block size; fixed otherwise.
+	variable bool
+	// Audio frames.
+	frames []frameSpec
+}
+
+// A frameSpec specifies an audio frame of a test vector.
+type frameSpec struct {
+	// Block size in samples.
+	blockSize int
+	// Channel assignment; independent channels if zero.
+	channels frame.Channels
+	// Signal of the audio samples.
+	signal signal
+	// Subframes, by channel; the last subframe specifies the remaining
+	// channels.
+	subframes []subframeSpec
+}
+
+// A subframeSpec specifies the encoding of a subframe.
+type subframeSpec struct {
+	// Prediction method, and prediction order of fixed and FIR linear
+	// prediction.
+	pred  frame.Pred
+	order int
+	// Wasted bits-per-sample.
+	wasted uint
+	// Residual coding method, Rice partition order, and the indices of escaped
+	// Rice partitions.
+	method    frame.ResidualCodingMethod
+	partOrder int
+	escaped   []int
+	// Coefficient precision in bits and shift of FIR linear prediction.
+	prec  uint
+	shift int32
+}
+
+// A signal specifies the synthesized audio samples of a frame.
+type signal int
+
+// Signals.
+const (
+	// Sine wave at half of full scale, with a distinct phase per channel.
+	signalSine signal = iota
+	// Pseudo-random noise at a quarter of full scale.
+	signalNoise
+	// Digital silence.
+	signalSilence
+	// Full scale extremes; i.e. the minimum and maximum sample values.
+	signalFullScale
+)
+
+// Subframe specifications.
+var (
+	constant = subframeSpec{pred: frame.PredConstant}
+	verbatim = subframeSpec{pred: frame.PredVerbatim}
+)
+
+// fixed returns the subframe specification of fixed prediction of the given
+// order.
+func fixed(order int) subframeSpec {
+	return subframeSpec{pred: frame.PredFixed, order: order}
+}
+
+// fir returns the subframe specification of FIR linear prediction of the given
+// order, coefficient precision and shift.
+func fir(order int, prec uint, shift int32) subframeSpec {
+	return subframeSpec{pred: frame.PredFIR, order: order, prec: prec, shift: shift}
+}
+
+// withWasted returns the subframe specification with the given wasted
+// bits-per-sample.
+func (s subframeSpec) withWasted(wasted uint) subframeSpec {
+	s.wasted = wasted
+	return s
+}
+
+// withPartitions returns the subframe specification with the given residual
+// coding method, Rice partition order and escaped Rice partitions.
+func (s subframeSpec) withPartitions(method frame.ResidualCodingMethod, partOrder int, escaped ...int) subframeSpec {
+	s.method = method
+	s.partOrder = partOrder
+	s.escaped = escaped
+	return s
+}
+
+// frames returns n frames of the given block size, signal and subframes, using
+// independent channels.
+func frames(n, blockSize int, sig signal, subframes ...subframeSpec) []frameSpec {
+	fs := make([]frameSpec, n)
+	for i := range fs {
+		fs[i] = frameSpec{blockSize: blockSize, signal: sig, subframes: subframes}
+	}
+	return fs
+}
+
+// corpus returns the test vectors of the corpus.
+func corpus() []vector {
+	var vs []vector
+	add := func(name, desc string, bps uint8, nchannels int, fs ...frameSpec) {
+		vs = append(vs, vector{name: name, desc: desc, bps: bps, nchannels: nchannels, frames: fs})
+	}
+
+	// Subframe types.
+	add("subframe-constant", "constant subframes", 16, 1, frames(2, 4096, signalSine, constant)...)
+	add("subframe-constant-silence", "constant subframes of digital silence", 16, 1, frames(2, 4096, signalSilence, constant)...)
+	add("subframe-verbatim", "verbatim subframes", 16, 1, frames(2, 4096, signalSine, verbatim)...)
+	for order := 0; order <= frame.MaxFixedOrder; order++ {
+		add(fmt.Sprintf("subframe-fixed-%d", order), fmt.Sprintf("fixed prediction subframes of order %d", order), 16, 1, frames(2, 4096, signalSine, fixed(order))...)
+	}
+	for _, order := range []int{1, 2, 8, 12, frame.MaxLPCOrder} {
+		add(fmt.Sprintf("subframe-fir-%d", order), fmt.Sprintf("FIR linear prediction subframes of order %d", order), 16, 1, frames(2, 4096, signalSine, fir(order, 15, 13))...)
+	}
+	add("subframe-fir-prec-4", "FIR linear prediction subframes with 4-bit coefficients", 16, 1, frames(2, 4096, signalSine, fir(8, 4, 2))...)
+	add("subframe-fir-shift-0", "FIR linear prediction subframes with a coefficient shift of 0", 16, 1, frames(2, 4096, signalSine, fir(2, 3, 0))...)
+	add("subframe-fir-shift-15", "FIR linear prediction subframes with 15-bit coefficients and a coefficient shift of 15", 16, 1, frames(2, 4096, signalSine, fir(8, frame.MaxCoeffPrec, 15))...)
+	add("subframe-mixed", "frames of each subframe type", 16, 1,
+		frameSpec{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{constant}},
+		frameSpec{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{verbatim}},
+		frameSpec{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{fixed(3)}},
+		frameSpec{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{fir(10, 12, 10)}},
+	)
+
+	// Rice partitions.
+	for _, partOrder := range []int{1, 4, frame.SubsetMaxPartitionOrder} {
+		add(fmt.Sprintf("rice-partition-order-%d", partOrder), fmt.Sprintf("Rice partition order %d", partOrder), 16, 1, frames(2, 4096, signalNoise, fixed(2).withPartitions(frame.ResidualCodingMethodRice1, partOrder))...)
+	}
+	add("rice-partition-order-12", "Rice partitions of 1 residual; the first partition holds no residuals", 16, 1, frames(2, 4096, signalNoise, fixed(1).withPartitions(frame.ResidualCodingMethodRice1, 12))...)
+	add("rice-partition-order-15", "Rice partition order 15", 16, 1, frames(1, 32768, signalNoise, fixed(1).withPartitions(frame.ResidualCodingMethodRice1, frame.MaxPartitionOrder))...)
+	add("rice2", "5-bit Rice parameters exceeding the range of 4-bit Rice parameters", 24, 1, frames(2, 4096, signalNoise, fixed(0).withPartitions(frame.ResidualCodingMethodRice2, 2))...)
+	add("escape-rice1", "escaped partitions of 4-bit Rice parameters", 16, 1, frames(2, 4096, signalNoise, fixed(2).withPartitions(frame.ResidualCodingMethodRice1, 3, 0, 3, 5))...)
+	add("escape-rice2", "escaped partitions of 5-bit Rice parameters", 24, 1, frames(2, 4096, signalNoise, fixed(2).withPartitions(frame.ResidualCodingMethodRice2, 3, 1, 2, 7))...)
+	add("escape-all", "escaped partitions only", 16, 1, frames(2, 4096, signalSine, fir(4, 14, 12).withPartitions(frame.ResidualCodingMethodRice1, 4, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15))...)
+	add("escape-zero", "escaped partitions of 0-bit residuals", 16, 1, frames(2, 4096, signalSilence, fixed(1).withPartitions(frame.ResidualCodingMethodRice1, 2, 0, 1, 2, 3))...)
+
+	// Channel assignments.
+	for nchannels := 1; nchannels <= frame.MaxChannels; nchannels++ {
+		add(fmt.Sprintf("channels-%d", nchannels), fmt.Sprintf("%d independent channels", nchannels), 16, nchannels, frames(2, 4096, signalSine, fixed(2))...)
+	}
+	for _, c := range []struct {
+		name     string
+		channels frame.Channels
+	}{
+		{name: "left-side", channels: frame.ChannelsLeftSide},
+		{name: "side-right", channels: frame.ChannelsSideRight},
+		{name: "mid-side", channels: frame.ChannelsMidSide},
+	} {
+		channels := c.channels
+		fs := []frameSpec{
+			{blockSize: 4096, channels: channels, signal: signalSine, subframes: []subframeSpec{fixed(2)}},
+			{blockSize: 4096, channels: channels, signal: signalSine, subframes: []subframeSpec{verbatim, fir(8, 15, 13)}},
+			{blockSize: 4096, channels: channels, signal: signalFullScale, subframes: []subframeSpec{verbatim}},
+		}
+		add("channels-"+c.name, c.name+" inter-channel decorrelation, including full scale side channels", 16, 2, fs...)
+	}
+	var mixed []frameSpec
+	for _, channels := range []frame.Channels{frame.ChannelsLR, frame.ChannelsLeftSide, frame.ChannelsSideRight, frame.ChannelsMidSide} {
+		mixed = append(mixed, frameSpec{blockSize: 4096, channels: channels, signal: signalSine, subframes: []subframeSpec{fixed(1)}})
+	}
+	add("channels-mixed", "channel assignment changing between frames", 16, 2, mixed...)
+
+	// Block sizes; including the boundaries of the block size codes of the
+	// frame header.
+	for _, blockSize := range []int{frame.MinBlockSize, 192, 255, 256, 257, 576, 4608, 32768, frame.MaxBlockSize} {
+		add(fmt.Sprintf("blocksize-%d", blockSize), fmt.Sprintf("block size of %d samples", blockSize), 16, 1, frames(3, blockSize, signalSine, fixed(2))...)
+	}
+	short := frames(3, 4096, signalSine, fixed(2))
+	short[2] = frameSpec{blockSize: 1, signal: signalSine, subframes: []subframeSpec{verbatim}}
+	add("blocksize-short-last", "last frame of 1 sample", 16, 1, short...)
+	var variable []frameSpec
+	for _, blockSize := range []int{16, 4096, 1000, 17, frame.MaxBlockSize, 192, 5} {
+		variable = append(variable, frameSpec{blockSize: blockSize, signal: signalSine, subframes: []subframeSpec{fixed(1)}})
+	}
+	vs = append(vs, vector{name: "blocksize-variable", desc: "variable block size", bps: 16, nchannels: 1, variable: true, frames: variable})
+
+	// Wasted bits.
+	for _, wasted := range []uint{1, 8, 15} {
+		fs := []frameSpec{
+			{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{verbatim.withWasted(wasted)}},
+			{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{fixed(2).withWasted(wasted)}},
+			{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{fir(8, 15, 13).withWasted(wasted)}},
+		}
+		add(fmt.Sprintf("wasted-%d", wasted), fmt.Sprintf("%d wasted bits-per-sample", wasted), 16, 1, fs...)
+	}
+	add("wasted-per-channel", "wasted bits-per-sample differing between channels", 16, 2, frames(2, 4096, signalSine, fixed(2).withWasted(2), fixed(2).withWasted(5))...)
+	add("wasted-mid-side", "wasted bits-per-sample of mid and side channels", 16, 2,
+		frameSpec{blockSize: 4096, channels: frame.ChannelsMidSide, signal: signalSine, subframes: []subframeSpec{fixed(2).withWasted(3), fixed(2).withWasted(4)}},
+		frameSpec{blockSize: 4096, channels: frame.ChannelsMidSide, signal: signalSine, subframes: []subframeSpec{constant.withWasted(4), verbatim.withWasted(1)}},
+	)
+
+	// Sample sizes; inter-channel decorrelation is limited to sample sizes
+	// below 32 bits-per-sample, as the side channel would require 33 bits.
+	for bps := uint8(frame.MinBitsPerSample); bps <= frame.MaxBitsPerSample; bps++ {
+		// Residuals of large sample sizes require 5-bit Rice parameters.
+		method := frame.ResidualCodingMethodRice1
+		if bps > 16 {
+			method = frame.ResidualCodingMethodRice2
+		}
+		fs := []frameSpec{
+			{blockSize: 4096, signal: signalFullScale, subframes: []subframeSpec{verbatim}},
+			{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{fixed(2).withPartitions(method, 0)}},
+			{blockSize: 4096, signal: signalSine, subframes: []subframeSpec{fir(4, 15, 13).withPartitions(method, 0)}},
+		}
+		if bps < frame.MaxBitsPerSample {
+			fs = append(fs, frameSpec{blockSize: 4096, channels: frame.ChannelsMidSide, signal: signalSine, subframes: []subframeSpec{fixed(1).withPartitions(method, 0)}})
+		}
+		add(fmt.Sprintf("bps-%d", bps), fmt.Sprintf("%d bits-per-sample", bps), bps, 2, fs...)
+	}
+	return vs
+}
+
+// write writes the test vector to the given path, and returns the MD5 checksum
+// of its audio samples.
+func (v vector) write(path string) ([16]byte, error) {
+	info := &meta.StreamInfo{
+		BlockSizeMin:  frame.MinBlockSize,
+		BlockSizeMax:  frame.MaxBlockSize,
+		SampleRate:    sampleRate,
+		NChannels:     uint8(v.nchannels),
+		BitsPerSample: v.bps,
+	}
+	fw, err := os.Create(path)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	enc, err := flac.NewEncoder(fw, info)
+	if err != nil {
+		fw.Close()
+		return [16]byte{}, err
+	}
+	// Encode the subframes as specified.
+	enc.EnablePredictionAnalysis(false)
+	offset := 0
+	for i, spec := range v.frames {
+		f, err := v.newFrame(spec, offset)
+		if err != nil {
+			enc.Close()
+			return [16]byte{}, fmt.Errorf("frame %d; %v", i, err)
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			enc.Close()
+			return [16]byte{}, fmt.Errorf("frame %d; %v", i, err)
+		}
+		offset += spec.blockSize
+	}
+	if err := enc.Close(); err != nil {
+		return [16]byte{}, err
+	}
+	return info.MD5sum, nil
+}
+
+// newFrame returns the audio frame of the given specification, starting at the
+// given sample offset.
+func (v vector) newFrame(spec frameSpec, offset int) (*frame.Frame, error) {
+	channels := spec.channels
+	if channels == 0 {
+		channels = frame.Channels(v.nchannels - 1)
+	}
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: !v.variable,
+			BlockSize:         uint16(spec.blockSize),
+			SampleRate:        sampleRate,
+			Channels:          channels,
+			BitsPerSample:     v.bps,
+		},
+	}
+	for channel := 0; channel < v.nchannels; channel++ {
+		f.Subframes = append(f.Subframes, &frame.Subframe{
+			Samples:  synthesize(spec.signal, v.bps, channel, offset, spec.blockSize),
+			NSamples: spec.blockSize,
+		})
+	}
+	// Prepare the subframes as stored; i.e. after inter-channel decorrelation.
+	f.Decorrelate()
+	for channel, subframe := range f.Subframes {
+		s := spec.subframes[min(channel, len(spec.subframes)-1)]
+		if err := prepareSubframe(subframe, s); err != nil {
+			return nil, fmt.Errorf("channel %d; %v", channel, err)
+		}
+	}
+	f.Correlate()
+	return f, nil
+}
+
+// synthesize returns n audio samples of the given signal, sample size and
+// channel, starting at the given sample offset.
+func synthesize(sig signal, bps uint8, channel, offset, n int) []int32 {
+	maxSample := int64(1)<<(bps-1) - 1
+	minSample := -int64(1) << (bps - 1)
+	samples := make([]int32, n)
+	// Linear congruential generator of the pseudo-random noise, seeded by
+	// channel and sample offset.
+	seed := uint64(channel+1)*0x9E3779B97F4A7C15 + uint64(offset)
+	for i := range samples {
+		var x int64
+		switch sig {
+		case signalSine:
+			t := float64(offset+i) / sampleRate
+			x = int64(math.Round(float64(maxSample) / 2 * math.Sin(2*math.Pi*441*t+float64(channel))))
+		case signalNoise:
+			seed = seed*6364136223846793005 + 1442695040888963407
+			x = int64(int32(seed>>32)) % (maxSample/4 + 1)
+		case signalFullScale:
+			// Cycle through extremes, alternating between channels.
+			extremes := [...]int64{maxSample, minSample, 0, -1, minSample, maxSample, 1}
+			x = extremes[(i+channel)%len(extremes)]
+		}
+		samples[i] = int32(x)
+	}
+	return samples
+}
+
+// prepareSubframe prepares the subframe header and audio samples of the given
+// decorrelated subframe for encoding, as specified.
+func prepareSubframe(subframe *frame.Subframe, s subframeSpec) error {
+	samples := subframe.Samples
+	if s.wasted > 0 {
+		for i, sample := range samples {
+			samples[i] = sample >> s.wasted << s.wasted
+		}
+	}
+	if s.pred == frame.PredConstant {
+		for i := range samples {
+			samples[i] = samples[0]
+		}
+	}
+	subframe.SubHeader = frame.SubHeader{
+		Pred:   s.pred,
+		Order:  s.order,
+		Wasted: s.wasted,
+	}
+	var coeffs []int32
+	switch s.pred {
+	case frame.PredConstant, frame.PredVerbatim:
+		return nil
+	case frame.PredFixed:
+		coeffs = frame.FixedCoeffs[s.order]
+	case frame.PredFIR:
+		coeffs = firCoeffs(s.order, s.prec, s.shift)
+		subframe.CoeffPrec = s.prec
+		subframe.CoeffShift = s.shift
+		subframe.Coeffs = coeffs
+	}
+	// Compute the residuals of the samples with wasted bits-per-sample shifted
+	// out, as encoded.
+	shifted := make([]int32, len(samples))
+	for i, sample := range samples {
+		shifted[i] = sample >> s.wasted
+	}
+	residuals := make([]int32, 0, len(samples))
+	for i := s.order; i < len(shifted); i++ {
+		var prediction int64
+		for j, c := range coeffs {
+			prediction += int64(c) * int64(shifted[i-j-1])
+		}
+		residual := int64(shifted[i]) - prediction>>uint(subframe.CoeffShift)
+		if residual < math.MinInt32 || residual > math.MaxInt32 {
+			return fmt.Errorf("residual %d out of range of 32-bit integer", residual)
+		}
+		residuals = append(residuals, int32(residual))
+	}
+	rice, err := partitionResiduals(residuals, s, len(samples))
+	if err != nil {
+		return err
+	}
+	subframe.ResidualCodingMethod = s.method
+	subframe.RiceSubframe = rice
+	return nil
+}
+
+// firCoeffs returns the quantized coefficients of a FIR linear predictor of the
+// given order, coefficient precision and shift.
+func firCoeffs(order int, prec uint, shift int32) []int32 {
+	maxCoeff := float64(int64(1)<<(prec-1) - 1)
+	minCoeff := -float64(int64(1) << (prec - 1))
+	coeffs := make([]int32, order)
+	for i := range coeffs {
+		// Second order predictor, with decaying higher order terms.
+		var c float64
+		switch {
+		case order == 1:
+			c = 0.9
+		case i == 0:
+			c = 1.8
+		case i == 1:
+			c = -0.85
+		default:
+			c = 0.02 * math.Pow(-1, float64(i)) / float64(i)
+		}
+		coeffs[i] = int32(math.Max(minCoeff, math.Min(maxCoeff, math.Round(c*float64(int64(1)<<shift)))))
+	}
+	return coeffs
+}
+
+// partitionResiduals returns the Rice partitions of the given residuals of a
+// subframe of n samples, as specified.
+func partitionResiduals(residuals []int32, s subframeSpec, n int) (*frame.RiceSubframe, error) {
+	paramSize := uint(4)
+	if s.method == frame.ResidualCodingMethodRice2 {
+		paramSize = 5
+	}
+	escape := bits.RiceEscape(paramSize)
+	nparts := 1 << s.partOrder
+	if n%nparts != 0 || n/nparts < s.order {
+		return nil, fmt.Errorf("invalid Rice partition order %d of %d samples of prediction order %d", s.partOrder, n, s.order)
+	}
+	rice := &frame.RiceSubframe{
+		PartOrder:  s.partOrder,
+		Partitions: make([]frame.RicePartition, nparts),
+	}
+	start := 0
+	for i := range rice.Partitions {
+		size := n / nparts
+		if i == 0 {
+			size -= s.order
+		}
+		part := residuals[start : start+size]
+		start += size
+		partition := &rice.Partitions[i]
+		if slices.Contains(s.escaped, i) {
+			partition.Param = escape
+			partition.EscapedBitsPerSample = escapedBitsPerSample(part)
+			continue
+		}
+		partition.Param = bestRiceParam(part, escape-1)
+	}
+	return rice, nil
+}
+
+// bestRiceParam returns the Rice parameter, at most maxParam, which minimizes
+// the encoded size of the given residuals.
+func bestRiceParam(residuals []int32, maxParam uint) uint {
+	best, bestSize := uint(0), -1
+	for k := uint(0); k <= maxParam; k++ {
+		size := 0
+		for _, residual := range residuals {
+			size += 1 + int(k) + int(bits.EncodeZigZag(residual)>>k)
+		}
+		if bestSize == -1 || size < bestSize {
+			best, bestSize = k, size
+		}
+	}
+	return best
+}
+
+// escapedBitsPerSample returns the minimum sample size in bits required to
+// store the given residuals in two's complement; 0 if all residuals are zero.
+func escapedBitsPerSample(residuals []int32) uint {
+	n := uint(0)
+	for _, residual := range residuals {
+		if residual < 0 {
+			residual = ^residual
+		} else if residual == 0 {
+			continue
+		}
+		m := uint(1)
+		for residual != 0 {
+			residual >>= 1
+			m++
+		}
+		n = max(n, m)
+	}
+	return n
+}
@@ -0,0 +1,135 @@
+package flac
+
+import (
+	"fmt"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// A Profile describes the stream properties supported by a class of decoders,
+// such as the hardware decoders of mobile devices, which may reject streams
+// that are valid FLAC.
+type Profile struct {
+	// Name of the profile.
+	Name string
+	// Maximum sample size in bits-per-sample.
+	MaxBitsPerSample uint8
+	// Maximum number of channels.
+	MaxChannels uint8
+	// Maximum sample rate in Hz.
+	MaxSampleRate uint32
+	// Specifies whether block sizes must conform to the streamable subset.
+	Subset bool
+	// Specifies whether the stream must use a fixed block size.
+	FixedBlockSize bool
+}
+
+// Decoder profiles.
+var (
+	// ProfileAndroid describes the FLAC decoder of the Android platform, which
+	// supports up to 8 channels of at most 24 bits-per-sample and 192 kHz (as of
+	// Android 10); conservative values are used for properties that vary with
+	// the platform version.
+	ProfileAndroid = &Profile{
+		Name:             "android",
+		MaxBitsPerSample: 24,
+		MaxChannels:      8,
+		MaxSampleRate:    192000,
+		Subset:           true,
+	}
+	// ProfileMobile describes the lowest common denominator of mobile and
+	// embedded hardware decoders, e.g. for delivery of FLAC in 3GPP and MP4
+	// containers; stereo 16-bit audio at up to 48 kHz, with a fixed block size
+	// conforming to the streamable subset.
+	ProfileMobile = &Profile{
+		Name:             "mobile",
+		MaxBitsPerSample: 16,
+		MaxChannels:      2,
+		MaxSampleRate:    48000,
+		Subset:           true,
+		FixedBlockSize:   true,
+	}
+)
+
+// CompatFeature specifies a stream property which may be rejected by a decoder.
+type CompatFeature uint8
+
+// Stream properties.
+const (
+	CompatBitsPerSample CompatFeature = iota + 1
+	CompatChannels
+	CompatSampleRate
+	CompatBlockSize
+	CompatVariableBlockSize
+)
+
+// String returns the string representation of the stream property.
+func (feature CompatFeature) String() string {
+	switch feature {
+	case CompatBitsPerSample:
+		return "bits-per-sample"
+	case CompatChannels:
+		return "channels"
+	case CompatSampleRate:
+		return "sample rate"
+	case CompatBlockSize:
+		return "block size"
+	case CompatVariableBlockSize:
+		return "variable block size"
+	}
+	return fmt.Sprintf("CompatFeature(%d)", uint8(feature))
+}
+
+// A CompatIssue is a stream property which is not supported by the decoders of
+// a profile.
+type CompatIssue struct {
+	// Unsupported stream property.
+	Feature CompatFeature
+	// Human-readable description of the issue.
+	Detail string
+}
+
+// String returns the string representation of the issue.
+func (issue CompatIssue) String() string {
+	return fmt.Sprintf("%v: %s", issue.Feature, issue.Detail)
+}
+
+// CheckCompat returns the stream properties described by the given StreamInfo
+// metadata block which are not supported by the decoders of the given profile;
+// or nil if the stream is expected to decode. Transcoding services may use
+// CheckCompat to decide whether to re-encode a stream before serving it.
+//
+// Note: CheckCompat only validates the stream properties; the audio frames are
+// not inspected.
+func CheckCompat(info *meta.StreamInfo, p *Profile) []CompatIssue {
+	var issues []CompatIssue
+	add := func(feature CompatFeature, format string, args ...interface{}) {
+		issues = append(issues, CompatIssue{Feature: feature, Detail: fmt.Sprintf(format, args...)})
+	}
+	if p.MaxBitsPerSample != 0 && info.BitsPerSample > p.MaxBitsPerSample {
+		add(CompatBitsPerSample, "sample size (%d) exceeds %d bits-per-sample", info.BitsPerSample, p.MaxBitsPerSample)
+	}
+	if p.MaxChannels != 0 && info.NChannels > p.MaxChannels {
+		add(CompatChannels, "number of channels (%d) exceeds %d", info.NChannels, p.MaxChannels)
+	}
+	if p.MaxSampleRate != 0 && info.SampleRate > p.MaxSampleRate {
+		add(CompatSampleRate, "sample rate (%d) exceeds %d Hz", info.SampleRate, p.MaxSampleRate)
+	}
+	if p.Subset {
+		if max := frame.SubsetMaxBlockSizeFor(info.SampleRate); int(info.BlockSizeMax) > max {
+			add(CompatBlockSize, "maximum block size (%d) exceeds %d samples at %d Hz", info.BlockSizeMax, max, info.SampleRate)
+		}
+	}
+	if p.FixedBlockSize && info.BlockSizeMin != info.BlockSizeMax {
+		add(CompatVariableBlockSize, "block size varies between %d and %d samples", info.BlockSizeMin, info.BlockSizeMax)
+	}
+	return issues
+}
+
+// CheckCompat returns the stream properties of the stream which are not
+// supported by the decoders of the given profile; or nil if the stream is
+// expected to decode. See CheckCompat for details.
+func (stream *Stream) CheckCompat(p *Profile) []CompatIssue {
+	return CheckCompat(stream.Info, p)
+}
@@ -0,0 +1,50 @@
+package flac_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestCheckCompat(t *testing.T) {
+	golden := []struct {
+		info    *meta.StreamInfo
+		profile *flac.Profile
+		want    []flac.CompatFeature
+	}{
+		{
+			info:    &meta.StreamInfo{BlockSizeMin: 4096, BlockSizeMax: 4096, SampleRate: 44100, NChannels: 2, BitsPerSample: 16},
+			profile: flac.ProfileMobile,
+		},
+		{
+			info:    &meta.StreamInfo{BlockSizeMin: 4096, BlockSizeMax: 4096, SampleRate: 96000, NChannels: 6, BitsPerSample: 24},
+			profile: flac.ProfileAndroid,
+		},
+		{
+			info:    &meta.StreamInfo{BlockSizeMin: 4096, BlockSizeMax: 4096, SampleRate: 96000, NChannels: 6, BitsPerSample: 24},
+			profile: flac.ProfileMobile,
+			want:    []flac.CompatFeature{flac.CompatBitsPerSample, flac.CompatChannels, flac.CompatSampleRate},
+		},
+		{
+			info:    &meta.StreamInfo{BlockSizeMin: 1152, BlockSizeMax: 8192, SampleRate: 44100, NChannels: 2, BitsPerSample: 32},
+			profile: flac.ProfileAndroid,
+			want:    []flac.CompatFeature{flac.CompatBitsPerSample, flac.CompatBlockSize},
+		},
+		{
+			info:    &meta.StreamInfo{BlockSizeMin: 1152, BlockSizeMax: 4608, SampleRate: 44100, NChannels: 2, BitsPerSample: 16},
+			profile: flac.ProfileMobile,
+			want:    []flac.CompatFeature{flac.CompatVariableBlockSize},
+		},
+	}
+	for i, g := range golden {
+		var got []flac.CompatFeature
+		for _, issue := range flac.CheckCompat(g.info, g.profile) {
+			got = append(got, issue.Feature)
+		}
+		if !reflect.DeepEqual(got, g.want) {
+			t.Errorf("i=%d: issue mismatch for profile %q; expected %v, got %v", i, g.profile.Name, g.want, got)
+		}
+	}
+}
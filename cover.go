@@ -0,0 +1,73 @@
+package flac
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// ReplaceFrontCover replaces the first Picture metadata block of the front
+// cover type of the FLAC file at path with pic, or adds a Picture block if the
+// file has no front cover, and reports how the file was rewritten. The picture
+// type of pic is stored as is, and is typically meta.PictureFrontCover.
+//
+// The metadata blocks are rewritten in place if they fit the space of the
+// original metadata blocks, including their padding; as such, the audio frames
+// are never touched. Otherwise, the file is rewritten safely. See EditMetadata
+// for details.
+func ReplaceFrontCover(path string, pic *meta.Picture) (MetadataRewrite, error) {
+	return ReplaceFrontCoverWithOptions(path, pic, nil)
+}
+
+// ReplaceFrontCoverWithOptions replaces the front cover of the FLAC file at
+// path with pic, as done by ReplaceFrontCover, using the specified rewrite
+// options. A nil opts specifies the default options.
+func ReplaceFrontCoverWithOptions(path string, pic *meta.Picture, opts *RewriteOptions) (MetadataRewrite, error) {
+	if opts == nil {
+		opts = &RewriteOptions{}
+	}
+	if err := pic.Validate(); err != nil {
+		return 0, err
+	}
+	f, stream, hdrStart, err := openMetadata(path, os.O_RDWR)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	blocks, err := withFrontCover(stream.Blocks, pic)
+	if err != nil {
+		return 0, err
+	}
+	plan, err := editMetadata(path, f, stream, hdrStart, blocks, opts)
+	if err != nil {
+		return 0, err
+	}
+	return plan.Rewrite, nil
+}
+
+// withFrontCover returns the given metadata blocks with the first front cover
+// replaced by pic, or with pic added following the last non-padding block if
+// the blocks hold no front cover. The metadata blocks are not modified.
+func withFrontCover(blocks []*meta.Block, pic *meta.Picture) ([]*meta.Block, error) {
+	// Length of the metadata block body.
+	length := 4 + 4 + len(pic.MIME) + 4 + len(pic.Desc) + 4 + 4 + 4 + 4 + 4 + len(pic.Data)
+	if length > maxBlockLength {
+		return nil, fmt.Errorf("flac.ReplaceFrontCover: picture of %d bytes exceeds maximum metadata block length", length)
+	}
+	block := &meta.Block{
+		Header: meta.Header{Type: meta.TypePicture, Length: int64(length)},
+		Body:   pic,
+	}
+	i := slices.IndexFunc(blocks, func(block *meta.Block) bool {
+		cover, ok := block.Body.(*meta.Picture)
+		return ok && cover.Type == meta.PictureFrontCover
+	})
+	if i != -1 {
+		blocks = slices.Clone(blocks)
+		blocks[i] = block
+		return blocks, nil
+	}
+	return insertBlock(blocks, block), nil
+}
@@ -0,0 +1,171 @@
+package flac_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestReplaceFrontCover(t *testing.T) {
+	golden := []struct {
+		path string
+		// Size in bytes of the image data of the new front cover.
+		size int
+		want flac.MetadataRewrite
+	}{
+		// Smaller front cover.
+		{path: "meta/testdata/silence.flac", size: 1000, want: flac.RewriteInPlace},
+		// Larger front cover, absorbed by the padding.
+		{path: "meta/testdata/silence.flac", size: 75000, want: flac.RewriteInPlace},
+		// Front cover exceeding the padding.
+		{path: "meta/testdata/silence.flac", size: 100000, want: flac.RewriteFile},
+		// New front cover of a file with prepended ID3v2 data.
+		{path: "testdata/id3.flac", size: 20000, want: flac.RewriteInPlace},
+		// New front cover of a file without padding.
+		{path: "testdata/172960.flac", size: 100, want: flac.RewriteFile},
+	}
+	for _, g := range golden {
+		orig, err := os.ReadFile(g.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src, err := flac.ParseFile(g.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src.Close()
+		path := filepath.Join(t.TempDir(), "cover.flac")
+		if err := os.WriteFile(path, orig, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		pic := &meta.Picture{
+			Type:   meta.PictureFrontCover,
+			MIME:   meta.MIMEJPEG,
+			Width:  500,
+			Height: 500,
+			Data:   bytes.Repeat([]byte{0xAB}, g.size),
+		}
+		got, err := flac.ReplaceFrontCover(path, pic)
+		if err != nil {
+			t.Errorf("%q: unable to replace front cover of %d bytes; %v", g.path, g.size, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%q: rewrite mismatch for front cover of %d bytes; expected %v, got %v", g.path, g.size, g.want, got)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := flac.ParseFile(path)
+		if err != nil {
+			t.Fatalf("%q: unable to parse rewritten file; %v", g.path, err)
+		}
+		stream.Close()
+		cover := meta.FrontCover(stream.Blocks)
+		if cover == nil || !bytes.Equal(cover.Data, pic.Data) {
+			t.Errorf("%q: front cover of %d bytes not found", g.path, g.size)
+		}
+		// The audio frames and prepended ID3v2 data are retained.
+		if !bytes.Equal(data[stream.DataStart():], orig[src.DataStart():]) {
+			t.Errorf("%q: audio frames mismatch", g.path)
+		}
+		if g.want == flac.RewriteInPlace && len(data) != len(orig) {
+			t.Errorf("%q: file size changed by in-place rewrite; expected %d, got %d", g.path, len(orig), len(data))
+		}
+		if bytes.HasPrefix(orig, []byte("ID3")) && !bytes.Equal(data[:10], orig[:10]) {
+			t.Errorf("%q: ID3v2 data mismatch", g.path)
+		}
+	}
+}
+
+func TestReplaceFrontCoverAtomic(t *testing.T) {
+	orig, err := os.ReadFile("meta/testdata/silence.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cover.flac")
+	if err := os.WriteFile(path, orig, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	pic := &meta.Picture{Type: meta.PictureFrontCover, MIME: meta.MIMEPNG, Data: bytes.Repeat([]byte{0xCD}, 1000)}
+
+	// Aborted rewrite.
+	errAbort := errors.New("aborted")
+	opts := &flac.RewriteOptions{
+		Atomic: true,
+		Progress: func(written, total int64) error {
+			return errAbort
+		},
+	}
+	if _, err := flac.ReplaceFrontCoverWithOptions(path, pic, opts); !errors.Is(err, errAbort) {
+		t.Errorf("error mismatch of aborted rewrite; expected %v, got %v", errAbort, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, orig) {
+		t.Error("file modified by aborted rewrite")
+	}
+
+	// Atomic rewrite of metadata blocks which fit the original metadata blocks.
+	var written, total int64
+	opts = &flac.RewriteOptions{
+		Atomic:        true,
+		PreserveOwner: true,
+		PreserveTimes: true,
+		Progress: func(n, m int64) error {
+			written, total = n, m
+			return nil
+		},
+	}
+	got, err := flac.ReplaceFrontCoverWithOptions(path, pic, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != flac.RewriteFile {
+		t.Errorf("rewrite mismatch; expected %v, got %v", flac.RewriteFile, got)
+	}
+	if written != int64(len(orig)) || total != int64(len(orig)) {
+		t.Errorf("progress mismatch; expected %d of %d bytes, got %d of %d bytes", len(orig), len(orig), written, total)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o640 {
+		t.Errorf("permissions mismatch; expected %v, got %v", os.FileMode(0o640), fi.Mode().Perm())
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Errorf("modification time mismatch; expected %v, got %v", mtime, fi.ModTime())
+	}
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+	if cover := meta.FrontCover(stream.Blocks); cover == nil || !bytes.Equal(cover.Data, pic.Data) {
+		t.Error("front cover not found")
+	}
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("number of files mismatch; expected 1, got %d", len(entries))
+	}
+}
@@ -0,0 +1,159 @@
+package flac
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// A DamageMap maps the damaged regions of a FLAC stream, as located by
+// MapDamage.
+type DamageMap struct {
+	// Sample rate of the stream in Hz.
+	SampleRate uint32
+	// Total number of samples per channel of the stream, as stored in the
+	// StreamInfo block; or 0 if unknown.
+	NSamples uint64
+	// Number of intact frames of the stream.
+	Frames int
+	// Damaged regions of the stream, in order of appearance.
+	Regions []DamagedRegion
+}
+
+// A DamagedRegion is a contiguous region of damaged frames of a FLAC stream,
+// which are not decodable.
+type DamagedRegion struct {
+	// Byte offset of the region, relative to the start of the stream.
+	Offset int64
+	// Size of the region in bytes, up to the next intact frame.
+	Size int64
+	// Sample number of the first sample of the region.
+	Sample uint64
+	// Number of samples per channel of the region; or 0 if unknown, as for
+	// regions at the end of streams of unknown length.
+	NSamples uint64
+	// Start time and duration of the region, derived from the sample numbers
+	// and the sample rate of the stream; the duration is 0 if unknown.
+	Start, Duration time.Duration
+	// Parse error of the first damaged frame of the region.
+	Err error
+}
+
+// String returns a human-readable representation of the damaged region.
+func (region DamagedRegion) String() string {
+	return fmt.Sprintf("offset %d (%d bytes): %v-%v (%d samples): %v", region.Offset, region.Size, region.Start, region.Start+region.Duration, region.NSamples, region.Err)
+}
+
+// Duration returns the total duration of the damaged regions.
+func (m *DamageMap) Duration() time.Duration {
+	var total time.Duration
+	for _, region := range m.Regions {
+		total += region.Duration
+	}
+	return total
+}
+
+// MapDamage reads the FLAC stream of r in its entirety, and maps all damaged
+// regions of the audio frames, instead of stopping at the first error as
+// Stream.ParseNext does. The metadata blocks must be intact. After a damaged
+// frame, the decoder resynchronizes at the next frame header which is followed
+// by a frame passing its CRC checksums; consecutive damaged frames are merged
+// into one region.
+//
+// The sample ranges of damaged regions are derived from the frame and sample
+// numbers of the intact frames surrounding them. As such, users may decide
+// whether the damage is audible, e.g. before discarding an archive copy.
+func MapDamage(r io.Reader) (*DamageMap, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := New(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	info := stream.Info
+	hint := &frame.Header{SampleRate: info.SampleRate, BitsPerSample: info.BitsPerSample}
+	m := &DamageMap{SampleRate: info.SampleRate, NSamples: info.NSamples}
+	offset := int(stream.DataStart())
+	// Sample number following the last intact frame.
+	var sample uint64
+	// Current damaged region; or nil if the preceding frame is intact.
+	var region *DamagedRegion
+	for offset < len(data) {
+		if isID3v1(data[offset:]) {
+			// Trailing ID3v1 tag.
+			break
+		}
+		f, n, err := parseFrameAt(data[offset:], hint)
+		if err == nil {
+			start := frameSample(f, info.BlockSizeMax)
+			if region != nil {
+				if start > region.Sample {
+					region.NSamples = start - region.Sample
+				}
+				m.addRegion(region)
+				region = nil
+			}
+			m.Frames++
+			sample = start + uint64(f.BlockSize)
+			offset += n
+			continue
+		}
+
+		// Resynchronize at the next valid frame header.
+		end := findNextFrame(data, offset+1, nil, -1)
+		if region == nil {
+			region = &DamagedRegion{Offset: int64(offset), Sample: sample, Err: err}
+		}
+		region.Size = int64(end) - region.Offset
+		offset = end
+	}
+	if region != nil {
+		if m.NSamples > region.Sample {
+			region.NSamples = m.NSamples - region.Sample
+		}
+		m.addRegion(region)
+	}
+	return m, nil
+}
+
+// MapDamageFile maps the damaged regions of the FLAC file at path. See
+// MapDamage for details.
+func MapDamageFile(path string) (*DamageMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return MapDamage(f)
+}
+
+// addRegion derives the timestamps of the given damaged region, and adds it to
+// the damage map.
+func (m *DamageMap) addRegion(region *DamagedRegion) {
+	if m.SampleRate != 0 {
+		region.Start = samplesDuration(region.Sample, m.SampleRate)
+		region.Duration = samplesDuration(region.Sample+region.NSamples, m.SampleRate) - region.Start
+	}
+	m.Regions = append(m.Regions, *region)
+}
+
+// frameSample returns the sample number of the first sample of the given frame,
+// where blockSize is the block size of fixed-blocksize streams.
+func frameSample(f *frame.Frame, blockSize uint16) uint64 {
+	if f.HasFixedBlockSize {
+		return f.Num * uint64(blockSize)
+	}
+	return f.Num
+}
+
+// samplesDuration returns the duration of the given number of samples at the
+// given sample rate.
+func samplesDuration(nsamples uint64, sampleRate uint32) time.Duration {
+	return time.Duration(float64(nsamples) / float64(sampleRate) * float64(time.Second))
+}
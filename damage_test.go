@@ -0,0 +1,104 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestMapDamage(t *testing.T) {
+	orig, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Byte offsets and sample numbers of the frames.
+	stream, err := flac.New(bytes.NewReader(orig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int64
+	var samples []uint64
+	var sample uint64
+	for {
+		offset := stream.Offset()
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		offsets = append(offsets, offset)
+		samples = append(samples, sample)
+		sample += uint64(f.BlockSize)
+	}
+	nframes := len(offsets)
+	nsamples := stream.Info.NSamples
+	sampleRate := stream.Info.SampleRate
+
+	// Intact stream.
+	m, err := flac.MapDamage(bytes.NewReader(orig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Frames != nframes || len(m.Regions) != 0 {
+		t.Errorf("intact stream: expected %d intact frames and no damage, got %d intact frames and %d damaged regions", nframes, m.Frames, len(m.Regions))
+	}
+
+	golden := []struct {
+		name string
+		// Frame indices of damaged frames.
+		indices []int
+		// Expected damaged regions, as frame index ranges [start, end).
+		want [][2]int
+	}{
+		{name: "one frame", indices: []int{3}, want: [][2]int{{3, 4}}},
+		{name: "consecutive frames", indices: []int{3, 4, 5}, want: [][2]int{{3, 6}}},
+		{name: "separate frames", indices: []int{1, 6}, want: [][2]int{{1, 2}, {6, 7}}},
+		{name: "last frame", indices: []int{nframes - 1}, want: [][2]int{{nframes - 1, nframes}}},
+	}
+	for _, g := range golden {
+		damaged := append([]byte(nil), orig...)
+		for _, index := range g.indices {
+			// Damage the frame header, including its sync code.
+			damaged[offsets[index]] ^= 0xFF
+		}
+		m, err := flac.MapDamage(bytes.NewReader(damaged))
+		if err != nil {
+			t.Fatalf("%s: unable to map damage; %v", g.name, err)
+		}
+		if want := nframes - len(g.indices); m.Frames != want {
+			t.Errorf("%s: intact frame count mismatch; expected %d, got %d", g.name, want, m.Frames)
+		}
+		if len(m.Regions) != len(g.want) {
+			t.Fatalf("%s: damaged region count mismatch; expected %d, got %d", g.name, len(g.want), len(m.Regions))
+		}
+		for i, want := range g.want {
+			region := m.Regions[i]
+			start, end := want[0], want[1]
+			wantOffset, wantSample := offsets[start], samples[start]
+			wantSize, wantNSamples := int64(len(orig))-wantOffset, nsamples-wantSample
+			if end < nframes {
+				wantSize, wantNSamples = offsets[end]-wantOffset, samples[end]-wantSample
+			}
+			if region.Offset != wantOffset || region.Size != wantSize {
+				t.Errorf("%s: region %d: byte range mismatch; expected %d (%d bytes), got %d (%d bytes)", g.name, i, wantOffset, wantSize, region.Offset, region.Size)
+			}
+			if region.Sample != wantSample || region.NSamples != wantNSamples {
+				t.Errorf("%s: region %d: sample range mismatch; expected %d (%d samples), got %d (%d samples)", g.name, i, wantSample, wantNSamples, region.Sample, region.NSamples)
+			}
+			if region.Duration <= 0 || region.Duration > m.Duration() {
+				t.Errorf("%s: region %d: invalid timestamps; start %v, duration %v", g.name, i, region.Start, region.Duration)
+			}
+			if want := uint64(region.Start.Seconds() * float64(sampleRate)); want+1 < region.Sample || want > region.Sample {
+				t.Errorf("%s: region %d: start time mismatch; expected sample %d, got %v", g.name, i, region.Sample, region.Start)
+			}
+			if region.Err == nil {
+				t.Errorf("%s: region %d: expected parse error", g.name, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package flac
+
+import (
+	"fmt"
+	"io"
+)
+
+// DecodeRange decodes exactly nSamples audio samples (per channel) starting at
+// the absolute sample number firstSample, and returns them as one slice per
+// channel. Frames straddling the start or end of the range are trimmed. The
+// samples are stored in dst, reusing the capacity of its slices if dst holds
+// one slice per channel; otherwise new slices are allocated.
+//
+// Streams created with NewSeek seek to the frame containing firstSample. Other
+// streams are decoded forward from the current position, and samples preceding
+// the current position result in ErrNoSeeker. On return, the stream is
+// positioned at the frame following the last decoded frame.
+func (stream *Stream) DecodeRange(firstSample, nSamples uint64, dst [][]int32) ([][]int32, error) {
+	nchannels := int(stream.Info.NChannels)
+	if len(dst) != nchannels {
+		dst = make([][]int32, nchannels)
+	}
+	for i := range dst {
+		dst[i] = dst[i][:0]
+	}
+	if nSamples == 0 {
+		return dst, nil
+	}
+	end := firstSample + nSamples
+	if end < firstSample || stream.Info.NSamples != 0 && end > stream.Info.NSamples {
+		return nil, fmt.Errorf("flac.Stream.DecodeRange: sample range [%d, %d) exceeds the %d samples of the stream", firstSample, firstSample+nSamples, stream.Info.NSamples)
+	}
+	if _, ok := stream.r.(io.ReadSeeker); ok {
+		if _, err := stream.Seek(firstSample); err != nil {
+			return nil, err
+		}
+	} else if pos := stream.SamplePosition(); firstSample < pos {
+		return nil, fmt.Errorf("flac.Stream.DecodeRange: unable to decode sample %d preceding the current position %d; %w", firstSample, pos, ErrNoSeeker)
+	}
+	for uint64(len(dst[0])) < nSamples {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("flac.Stream.DecodeRange: stream ended %d samples short of the sample range [%d, %d); %w", nSamples-uint64(len(dst[0])), firstSample, end, io.ErrUnexpectedEOF)
+			}
+			return nil, err
+		}
+		if len(f.Subframes) != nchannels {
+			return nil, fmt.Errorf("flac.Stream.DecodeRange: channel count mismatch of frame %d; expected %d, got %d", f.Num, nchannels, len(f.Subframes))
+		}
+		start := stream.sampleNumber(f)
+		frameEnd := start + uint64(f.BlockSize)
+		if frameEnd <= firstSample {
+			continue
+		}
+		lo := max(firstSample, start) - start
+		hi := min(end, frameEnd) - start
+		for i, subframe := range f.Subframes {
+			dst[i] = append(dst[i], subframe.Samples[lo:hi]...)
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,93 @@
+package flac_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+// decodeAll returns the audio samples of the FLAC file at path, one slice per
+// channel.
+func decodeAll(t *testing.T, path string) [][]int32 {
+	t.Helper()
+	stream, err := flac.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	samples := make([][]int32, stream.Info.NChannels)
+	for channels, err := range stream.Channels() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range samples {
+			samples[i] = append(samples[i], channels[i]...)
+		}
+	}
+	return samples
+}
+
+func TestDecodeRange(t *testing.T) {
+	for _, path := range []string{"testdata/59996.flac", "testdata/love.flac"} {
+		t.Run(path, func(t *testing.T) {
+			all := decodeAll(t, path)
+			nsamples := uint64(len(all[0]))
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			stream, err := flac.NewSeek(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ranges := [][2]uint64{
+				{0, 10},
+				{4090, 20},        // straddles frame boundary
+				{100, 5000},       // spans frames
+				{nsamples - 7, 7}, // end of stream
+				{1, 0},
+				{0, nsamples},
+			}
+			var dst [][]int32
+			for _, r := range ranges {
+				dst, err = stream.DecodeRange(r[0], r[1], dst)
+				if err != nil {
+					t.Fatalf("range %v: %v", r, err)
+				}
+				for i, samples := range dst {
+					want := all[i][r[0] : r[0]+r[1]]
+					if !reflect.DeepEqual(samples, want) && len(want)+len(samples) > 0 {
+						t.Fatalf("range %v: sample mismatch of channel %d", r, i)
+					}
+				}
+			}
+			if _, err := stream.DecodeRange(nsamples-1, 2, nil); err == nil {
+				t.Fatalf("expected error for range beyond end of stream")
+			}
+		})
+	}
+}
+
+func TestDecodeRangeNoSeeker(t *testing.T) {
+	const path = "testdata/59996.flac"
+	all := decodeAll(t, path)
+	stream, err := flac.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	dst, err := stream.DecodeRange(5000, 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst[1], all[1][5000:5100]) {
+		t.Fatalf("sample mismatch")
+	}
+	if _, err := stream.DecodeRange(10, 10, dst); !errors.Is(err, flac.ErrNoSeeker) {
+		t.Fatalf("error mismatch; expected %v, got %v", flac.ErrNoSeeker, err)
+	}
+}
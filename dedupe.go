@@ -0,0 +1,102 @@
+package flac
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// DedupeOptions specifies the options of FindDuplicates. The zero value
+// specifies the default options.
+type DedupeOptions struct {
+	// Scan specifies the options of the directory scan; nil specifies the
+	// default options.
+	Scan *ScanOptions
+	// ComputeMD5 specifies whether to decode files whose StreamInfo block leaves
+	// the MD5 checksum unset, to compute the checksum of their audio samples as
+	// done by FixMD5 in dry-run mode; otherwise, such files are ignored. Files
+	// are decoded sequentially.
+	ComputeMD5 bool
+}
+
+// A DuplicateSet is a set of FLAC files with identical audio samples, as
+// reported by FindDuplicates. The files may differ in their metadata blocks
+// (e.g. tags and pictures), encoding parameters and compression.
+type DuplicateSet struct {
+	// MD5 checksum of the audio samples of the files.
+	MD5sum [md5.Size]uint8
+	// Paths of the files, sorted.
+	Paths []string
+}
+
+// FindDuplicates scans the directory tree rooted at root using ScanDir, and
+// returns the sets of FLAC files with identical audio samples; i.e. of the same
+// MD5 checksum of the audio samples, total number of samples, sample rate,
+// channel count and sample size. The sets are sorted by the path of their first
+// file.
+//
+// The errors of the scan, and of decoding files to compute their MD5 checksum,
+// are joined as done by ScanDir; the duplicates found among the remaining files
+// are returned along with the error. If ctx is canceled, the scan is stopped
+// and the error of the context is returned.
+func FindDuplicates(ctx context.Context, root string, opts *DedupeOptions) ([]*DuplicateSet, error) {
+	if opts == nil {
+		opts = &DedupeOptions{}
+	}
+	// audioKey identifies the audio samples of a file.
+	type audioKey struct {
+		md5sum        [md5.Size]uint8
+		nsamples      uint64
+		sampleRate    uint32
+		nchannels     uint8
+		bitsPerSample uint8
+	}
+	index := make(map[audioKey][]string)
+	var zero [md5.Size]uint8
+	var errs []error
+	err := ScanDir(ctx, root, opts.Scan, func(result *ScanResult) error {
+		if result.Err != nil {
+			return nil
+		}
+		info := result.Info
+		md5sum := info.MD5sum
+		if md5sum == zero {
+			if !opts.ComputeMD5 {
+				return nil
+			}
+			check, err := FixMD5(result.Path, true)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", result.Path, err))
+				return nil
+			}
+			md5sum = check.Computed
+		}
+		key := audioKey{
+			md5sum:        md5sum,
+			nsamples:      info.NSamples,
+			sampleRate:    info.SampleRate,
+			nchannels:     info.NChannels,
+			bitsPerSample: info.BitsPerSample,
+		}
+		index[key] = append(index[key], result.Path)
+		return nil
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	err = errors.Join(append([]error{err}, errs...)...)
+	var sets []*DuplicateSet
+	for key, paths := range index {
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		sets = append(sets, &DuplicateSet{MD5sum: key.md5sum, Paths: paths})
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Paths[0] < sets[j].Paths[0]
+	})
+	return sets, err
+}
@@ -0,0 +1,78 @@
+package flac_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	love, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := os.ReadFile("testdata/19875.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Zero the MD5 checksum of StreamInfo; 4 bytes signature, 4 bytes metadata
+	// block header and 18 bytes preceding the checksum.
+	unset := append([]byte(nil), love...)
+	copy(unset[4+4+18:4+4+18+16], make([]byte, 16))
+	// ID3v2 header with a synchsafe size of 200 bytes; files with identical
+	// audio samples but different containers are duplicates.
+	id3 := append([]byte("ID3\x04\x00\x00\x00\x00\x01\x48"), make([]byte, 200)...)
+	root := t.TempDir()
+	files := map[string][]byte{
+		"a.flac":       love,
+		"b/copy.flac":  love,
+		"b/id3.flac":   append(id3, love...),
+		"c/other.flac": other,
+		"c/unset.flac": unset,
+		"damaged.flac": love[:100],
+	}
+	for name, buf := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden := []struct {
+		computeMD5 bool
+		want       []string
+	}{
+		// Files with an unset MD5 checksum are ignored.
+		{computeMD5: false, want: []string{"a.flac", "b/copy.flac", "b/id3.flac"}},
+		{computeMD5: true, want: []string{"a.flac", "b/copy.flac", "b/id3.flac", "c/unset.flac"}},
+	}
+	for _, g := range golden {
+		sets, err := flac.FindDuplicates(context.Background(), root, &flac.DedupeOptions{ComputeMD5: g.computeMD5})
+		if err == nil || !strings.Contains(err.Error(), "damaged.flac") {
+			t.Errorf("computeMD5=%v: expected error of damaged file; got %v", g.computeMD5, err)
+		}
+		if len(sets) != 1 {
+			t.Errorf("computeMD5=%v: number of duplicate sets mismatch; expected 1, got %d", g.computeMD5, len(sets))
+			continue
+		}
+		var got []string
+		for _, path := range sets[0].Paths {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if !slices.Equal(got, g.want) {
+			t.Errorf("computeMD5=%v: duplicates mismatch; expected %q, got %q", g.computeMD5, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package flac
+
+import (
+	"crypto/sha256"
+	"hash"
+	"io"
+)
+
+// MetadataDigest returns the digest of the metadata section of the stream,
+// covering any prepended ID3v2 data, the FLAC signature and all metadata
+// blocks; or nil if digests are not enabled. See DecodeOptions.Digest.
+func (stream *Stream) MetadataDigest() []byte {
+	if stream.dr == nil {
+		return nil
+	}
+	return stream.dr.metaSum
+}
+
+// AudioDigest returns the digest of the audio frames consumed so far; or nil if
+// digests are not enabled. The digest covers the entire audio section of the
+// stream once all audio frames have been parsed; i.e. once Stream.ParseNext
+// has returned io.EOF. See DecodeOptions.Digest.
+func (stream *Stream) AudioDigest() []byte {
+	if stream.dr == nil {
+		return nil
+	}
+	stream.dr.flush()
+	return stream.dr.h.Sum(nil)
+}
+
+// digestReader wraps read operations to r, adding the data consumed to a
+// running hash. The metadata section and audio section of the stream are
+// hashed separately.
+type digestReader struct {
+	// Underlying io.Reader.
+	r io.Reader
+	// Running hash of the current section.
+	h hash.Hash
+	// Returns a new hash.
+	newHash func() hash.Hash
+	// Digest of the metadata section; nil until the metadata section has been
+	// consumed.
+	metaSum []byte
+	// Pending bytes consumed by ReadByte, not yet added to the running hash.
+	buf [64]byte
+	// Number of pending bytes in buf.
+	n int
+}
+
+// newDigestReader returns a new digest reader for r, using hashes returned by
+// newHash; or SHA-256 if newHash is nil.
+func newDigestReader(r io.Reader, newHash func() hash.Hash) *digestReader {
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	return &digestReader{r: r, h: newHash(), newHash: newHash}
+}
+
+// Read reads up to len(p) bytes into p, and adds the bytes read to the running
+// hash.
+func (dr *digestReader) Read(p []byte) (n int, err error) {
+	dr.flush()
+	n, err = dr.r.Read(p)
+	dr.h.Write(p[:n])
+	return n, err
+}
+
+// ReadByte reads and returns the next byte, and adds it to the running hash.
+func (dr *digestReader) ReadByte() (byte, error) {
+	var c byte
+	if br, ok := dr.r.(io.ByteReader); ok {
+		var err error
+		if c, err = br.ReadByte(); err != nil {
+			return 0, err
+		}
+	} else {
+		var buf [1]byte
+		if _, err := io.ReadFull(dr.r, buf[:]); err != nil {
+			return 0, err
+		}
+		c = buf[0]
+	}
+	dr.buf[dr.n] = c
+	dr.n++
+	if dr.n == len(dr.buf) {
+		dr.flush()
+	}
+	return c, nil
+}
+
+// flush adds the pending bytes consumed by ReadByte to the running hash.
+func (dr *digestReader) flush() {
+	if dr.n > 0 {
+		dr.h.Write(dr.buf[:dr.n])
+		dr.n = 0
+	}
+}
+
+// next finishes the digest of the metadata section, and starts the digest of
+// the audio section.
+func (dr *digestReader) next() {
+	dr.flush()
+	dr.metaSum = dr.h.Sum(nil)
+	dr.h = dr.newHash()
+}
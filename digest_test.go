@@ -0,0 +1,63 @@
+package flac_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestDigest(t *testing.T) {
+	for _, path := range []string{"testdata/id3.flac", "testdata/love.flac"} {
+		t.Run(path, func(t *testing.T) {
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, lowMemory := range []bool{false, true} {
+				opts := &flac.DecodeOptions{Digest: true, LowMemory: lowMemory}
+				stream, err := flac.NewWithOptions(bytes.NewReader(buf), opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for {
+					if _, err := stream.ParseNext(); err != nil {
+						if err == io.EOF {
+							break
+						}
+						// Trailing ID3v1 data of id3.flac.
+						break
+					}
+				}
+				end := stream.Offset()
+				metaSum := sha256.Sum256(buf[:stream.DataStart()])
+				if got := stream.MetadataDigest(); !bytes.Equal(got, metaSum[:]) {
+					t.Errorf("metadata digest mismatch; expected %x, got %x", metaSum, got)
+				}
+				audioSum := sha256.Sum256(buf[stream.DataStart():end])
+				if got := stream.AudioDigest(); !bytes.Equal(got, audioSum[:]) {
+					t.Errorf("audio digest mismatch; expected %x, got %x", audioSum, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDigestNewHash(t *testing.T) {
+	f, err := os.Open("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stream, err := flac.NewWithOptions(f, &flac.DecodeOptions{Digest: true, NewHash: md5.New})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(stream.MetadataDigest()); got != md5.Size {
+		t.Errorf("digest size mismatch; expected %d, got %d", md5.Size, got)
+	}
+}
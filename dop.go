@@ -0,0 +1,91 @@
+package flac
+
+import (
+	"github.com/mewkiz/flac/frame"
+)
+
+// DoP markers, stored in the most significant byte of each 24-bit sample of DSD
+// over PCM streams. The markers of consecutive samples alternate, and are the
+// same for all channels of a sample.
+//
+// ref: https://dsd-guide.com/sites/default/files/white-papers/DoP_openStandard_1v1.pdf
+const (
+	dopMarker1 = 0x05
+	dopMarker2 = 0xFA
+)
+
+// dopState specifies whether the audio frames decoded so far carry DoP markers.
+type dopState uint8
+
+// DoP states.
+const (
+	// No frame decoded.
+	dopUnknown dopState = iota
+	// All decoded frames carry DoP markers.
+	dopDetected
+	// A decoded frame lacks DoP markers.
+	dopNone
+)
+
+// IsDoP reports whether the decoded audio samples of the given frame carry the
+// marker patterns of DoP (DSD over PCM); i.e. whether each 24-bit sample holds
+// 16 bits of DSD data following a marker byte, which alternates between 0x05
+// and 0xFA for consecutive samples, and is the same for all channels of a
+// sample.
+//
+// Note: The audio samples of the frame must be parsed before calling IsDoP.
+func IsDoP(f *frame.Frame) bool {
+	if f.BitsPerSample != 24 || len(f.Subframes) == 0 || f.Subframes[0].NSamples == 0 {
+		return false
+	}
+	samples := f.Subframes[0].Samples
+	first := dopMarker(samples[0])
+	if first != dopMarker1 && first != dopMarker2 {
+		return false
+	}
+	for _, subframe := range f.Subframes {
+		if len(subframe.Samples) != len(samples) {
+			return false
+		}
+		marker := first
+		for _, sample := range subframe.Samples {
+			if dopMarker(sample) != marker {
+				return false
+			}
+			marker ^= dopMarker1 ^ dopMarker2
+		}
+	}
+	return true
+}
+
+// dopMarker returns the most significant byte of the given 24-bit sample.
+func dopMarker(sample int32) byte {
+	return byte(sample >> 16)
+}
+
+// DoP reports whether the audio frames of the stream carry DoP (DSD over PCM)
+// data, rather than PCM audio samples; in which case players should route the
+// stream to a DoP-capable output unmodified, as decoding it as PCM produces
+// loud noise. The DSD data of the stream has a rate of 16 times the sample rate
+// of StreamInfo; e.g. 2.8224 MHz (DSD64) for 176.4 kHz DoP streams.
+//
+// DoP reports true if all audio frames decoded so far by Stream.ParseNext and
+// Stream.ParseNextInto carry DoP markers (see IsDoP), and false if no frame has
+// been decoded; as such, DoP is typically called after decoding the first frame
+// of the stream.
+func (stream *Stream) DoP() bool {
+	return stream.dop == dopDetected
+}
+
+// checkDoP updates the DoP state of the stream with the decoded audio samples of
+// the given frame.
+func (stream *Stream) checkDoP(f *frame.Frame) {
+	if stream.dop == dopNone {
+		return
+	}
+	if IsDoP(f) {
+		stream.dop = dopDetected
+	} else {
+		stream.dop = dopNone
+	}
+}
@@ -0,0 +1,92 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestDoP(t *testing.T) {
+	stream, err := flac.ParseFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if _, err := stream.ParseNext(); err != nil {
+		t.Fatal(err)
+	}
+	if stream.DoP() {
+		t.Error("DoP detected in PCM stream")
+	}
+
+	info := &meta.StreamInfo{
+		BlockSizeMin:  1024,
+		BlockSizeMax:  1024,
+		SampleRate:    176400,
+		NChannels:     2,
+		BitsPerSample: 24,
+	}
+	golden := []struct {
+		name string
+		// Frame number of the PCM frame; -1 if none.
+		pcm  int
+		want bool
+	}{
+		{name: "DoP", pcm: -1, want: true},
+		{name: "DoP with PCM frame", pcm: 2, want: false},
+	}
+	for _, g := range golden {
+		out := new(bytes.Buffer)
+		enc, err := flac.NewEncoder(out, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for num := 0; num < 4; num++ {
+			f := makeTestFrame(info, num)
+			if num != g.pcm {
+				// Markers alternate between 0x05 and 0xFA, and continue across
+				// frames.
+				for _, subframe := range f.Subframes {
+					for i, sample := range subframe.Samples {
+						marker := int32(0x05)
+						if i%2 == 1 {
+							marker = -6 // 0xFA
+						}
+						subframe.Samples[i] = marker<<16 | sample&0xFFFF
+					}
+				}
+			}
+			if err := enc.WriteFrame(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		stream, err := flac.New(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stream.DoP() {
+			t.Errorf("%s: DoP detected before decoding", g.name)
+		}
+		for {
+			f, err := stream.ParseNext()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := flac.IsDoP(f); got != (f.Num != uint64(g.pcm)) {
+				t.Errorf("%s: DoP mismatch of frame %d; got %v", g.name, f.Num, got)
+			}
+		}
+		if got := stream.DoP(); got != g.want {
+			t.Errorf("%s: DoP mismatch; expected %v, got %v", g.name, g.want, got)
+		}
+	}
+}
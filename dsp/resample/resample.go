@@ -0,0 +1,280 @@
+// Package resample implements sample rate conversion of decoded audio, using
+// linear interpolation or windowed-sinc interpolation, without any cgo
+// dependency.
+//
+// A Resampler converts planar audio samples (one slice per channel) in a
+// streaming fashion; samples are fed in chunks of arbitrary size using Process,
+// and the remaining samples are emitted by Flush once the input is exhausted.
+package resample
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+// Quality specifies the quality preset of a resampler, trading speed for
+// accuracy.
+type Quality uint8
+
+// Quality presets.
+const (
+	// QualityLinear uses linear interpolation between adjacent samples. It is
+	// the fastest preset, but does not suppress aliasing; suitable for
+	// fingerprinting and previews.
+	QualityLinear Quality = iota
+	// QualityLow uses windowed-sinc interpolation with 8 zero-crossings per
+	// side.
+	QualityLow
+	// QualityMedium uses windowed-sinc interpolation with 16 zero-crossings per
+	// side; suitable for playback.
+	QualityMedium
+	// QualityHigh uses windowed-sinc interpolation with 32 zero-crossings per
+	// side.
+	QualityHigh
+)
+
+// String returns the string representation of the quality preset.
+func (q Quality) String() string {
+	switch q {
+	case QualityLinear:
+		return "linear"
+	case QualityLow:
+		return "low"
+	case QualityMedium:
+		return "medium"
+	case QualityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Quality(%d)", uint8(q))
+}
+
+// zeroCrossings returns the number of zero-crossings per side of the sinc
+// kernel of the quality preset; or 0 for linear interpolation.
+func (q Quality) zeroCrossings() int {
+	switch q {
+	case QualityLow:
+		return 8
+	case QualityMedium:
+		return 16
+	case QualityHigh:
+		return 32
+	}
+	return 0
+}
+
+// Number of kernel table entries per zero-crossing.
+const phases = 512
+
+// A Resampler converts planar audio samples from one sample rate to another.
+type Resampler struct {
+	// Source and destination sample rates in Hz.
+	srcRate, dstRate int64
+	// Quality preset.
+	quality Quality
+	// Kernel half-width in input samples.
+	hw int
+	// Kernel scale along the time axis; i.e. the normalized cutoff frequency,
+	// which is less than 1 when downsampling to suppress aliasing.
+	cutoff float64
+	// Windowed-sinc kernel table, with phases entries per zero-crossing; nil for
+	// linear interpolation.
+	kernel []float64
+	// Buffered input samples per channel, starting at input sample base.
+	bufs [][]float64
+	// Input sample number of the first buffered sample.
+	base int64
+	// Total number of input samples (per channel) processed.
+	nin int64
+	// Output sample number of the next output sample.
+	pos int64
+}
+
+// New returns a new resampler from srcRate to dstRate (in Hz), for audio with
+// the given number of channels.
+func New(srcRate, dstRate, nchannels int, quality Quality) (*Resampler, error) {
+	if srcRate <= 0 || dstRate <= 0 {
+		return nil, fmt.Errorf("resample.New: invalid sample rate conversion from %d Hz to %d Hz", srcRate, dstRate)
+	}
+	if nchannels <= 0 {
+		return nil, fmt.Errorf("resample.New: invalid channel count %d", nchannels)
+	}
+	if quality > QualityHigh {
+		return nil, fmt.Errorf("resample.New: invalid quality preset %v", quality)
+	}
+	r := &Resampler{
+		srcRate: int64(srcRate),
+		dstRate: int64(dstRate),
+		quality: quality,
+		bufs:    make([][]float64, nchannels),
+		cutoff:  1,
+	}
+	if quality == QualityLinear {
+		r.hw = 1
+		return r, nil
+	}
+	if dstRate < srcRate {
+		r.cutoff = float64(dstRate) / float64(srcRate)
+	}
+	n := quality.zeroCrossings()
+	r.hw = int(math.Ceil(float64(n) / r.cutoff))
+	// Blackman-windowed sinc, tabulated over [0, n] zero-crossings.
+	r.kernel = make([]float64, n*phases+2)
+	for i := range r.kernel {
+		x := float64(i) / phases
+		if x > float64(n) {
+			break
+		}
+		w := 0.42 + 0.5*math.Cos(math.Pi*x/float64(n)) + 0.08*math.Cos(2*math.Pi*x/float64(n))
+		r.kernel[i] = sinc(x) * w
+	}
+	return r, nil
+}
+
+// sinc returns the normalized sinc function of x.
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Process feeds the resampler with the given planar input samples, one slice
+// per channel of equal length, and returns the output samples which are
+// available. Due to the kernel look-ahead, output samples lag behind the input
+// samples; call Flush to emit the remaining output samples at the end of the
+// input.
+func (r *Resampler) Process(in [][]float64) ([][]float64, error) {
+	if len(in) != len(r.bufs) {
+		return nil, fmt.Errorf("resample.Resampler.Process: channel count mismatch; expected %d, got %d", len(r.bufs), len(in))
+	}
+	for i := range in {
+		if len(in[i]) != len(in[0]) {
+			return nil, fmt.Errorf("resample.Resampler.Process: sample count mismatch in channel %d; expected %d, got %d", i, len(in[0]), len(in[i]))
+		}
+		r.bufs[i] = append(r.bufs[i], in[i]...)
+	}
+	if len(in) > 0 {
+		r.nin += int64(len(in[0]))
+	}
+	return r.generate(false), nil
+}
+
+// Flush returns the remaining output samples, treating the input as ended. The
+// total number of output samples is ceil(n*dstRate/srcRate) for n input
+// samples. The resampler is reset for reuse after Flush.
+func (r *Resampler) Flush() [][]float64 {
+	out := r.generate(true)
+	for i := range r.bufs {
+		r.bufs[i] = r.bufs[i][:0]
+	}
+	r.base, r.nin, r.pos = 0, 0, 0
+	return out
+}
+
+// generate generates the output samples which are available. If final is set,
+// the input is treated as ended, and padded with silence.
+func (r *Resampler) generate(final bool) [][]float64 {
+	out := make([][]float64, len(r.bufs))
+	// Total number of output samples; ceil(nin*dstRate/srcRate).
+	total := (r.nin*r.dstRate + r.srcRate - 1) / r.srcRate
+	for ; r.pos < total; r.pos++ {
+		// Input time of the output sample, as integer and fractional part.
+		num := r.pos * r.srcRate
+		j := num / r.dstRate
+		frac := float64(num%r.dstRate) / float64(r.dstRate)
+		if !final && j+int64(r.hw) >= r.nin {
+			// Look-ahead not yet available.
+			break
+		}
+		for c, buf := range r.bufs {
+			out[c] = append(out[c], r.interpolate(buf, j, frac))
+		}
+	}
+	// Discard input samples no longer required.
+	num := r.pos * r.srcRate
+	keep := num/r.dstRate - int64(r.hw) + 1
+	if keep > r.base {
+		n := int(keep - r.base)
+		if n > len(r.bufs[0]) {
+			n = len(r.bufs[0])
+		}
+		for c, buf := range r.bufs {
+			r.bufs[c] = append(buf[:0], buf[n:]...)
+		}
+		r.base += int64(n)
+	}
+	return out
+}
+
+// interpolate returns the interpolated sample at input time j+frac, from the
+// buffered samples of a channel. Samples outside of the input are treated as
+// silence.
+func (r *Resampler) interpolate(buf []float64, j int64, frac float64) float64 {
+	at := func(i int64) float64 {
+		i -= r.base
+		if i < 0 || i >= int64(len(buf)) {
+			return 0
+		}
+		return buf[i]
+	}
+	if r.kernel == nil {
+		// Linear interpolation.
+		x := at(j)
+		return x + (at(j+1)-x)*frac
+	}
+	var sum float64
+	for k := -r.hw + 1; k <= r.hw; k++ {
+		// Distance from the output time to the input sample, scaled by the
+		// cutoff frequency.
+		d := math.Abs(float64(k)-frac) * r.cutoff
+		t := d * phases
+		i := int(t)
+		if i+1 >= len(r.kernel) {
+			continue
+		}
+		w := r.kernel[i] + (r.kernel[i+1]-r.kernel[i])*(t-float64(i))
+		sum += at(j+int64(k)) * w
+	}
+	return sum * r.cutoff
+}
+
+// Transform returns a sample transform for the frames of a transcode pipeline
+// (see flac.Transcode), which resamples the audio samples of each frame, and
+// updates the sample rate of the frame header. Samples are rounded and clipped
+// to the sample size of the frame.
+//
+// Due to the kernel look-ahead, the frames hold a varying number of samples,
+// and the last output samples of the stream, corresponding to at most the
+// kernel half-width in input samples, are not emitted.
+func (r *Resampler) Transform() flac.Transform {
+	return func(f *frame.Frame) error {
+		in := make([][]float64, len(f.Subframes))
+		for c, subframe := range f.Subframes {
+			in[c] = make([]float64, len(subframe.Samples))
+			for i, sample := range subframe.Samples {
+				in[c][i] = float64(sample)
+			}
+		}
+		out, err := r.Process(in)
+		if err != nil {
+			return err
+		}
+		max := float64(int64(1)<<(f.BitsPerSample-1) - 1)
+		min := -max - 1
+		for c, subframe := range f.Subframes {
+			samples := make([]int32, len(out[c]))
+			for i, x := range out[c] {
+				samples[i] = int32(math.Max(min, math.Min(max, math.Round(x))))
+			}
+			subframe.Samples = samples
+		}
+		// The number of samples per frame varies, so the frames of a
+		// fixed-blocksize stream are encoded as variable-blocksize frames.
+		f.HasFixedBlockSize = false
+		f.SampleRate = uint32(r.dstRate)
+		return nil
+	}
+}
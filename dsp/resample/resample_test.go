@@ -0,0 +1,173 @@
+package resample_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/dsp/resample"
+)
+
+var qualities = []resample.Quality{
+	resample.QualityLinear,
+	resample.QualityLow,
+	resample.QualityMedium,
+	resample.QualityHigh,
+}
+
+// resampleAll resamples the given mono samples in chunks of the given size.
+func resampleAll(t *testing.T, r *resample.Resampler, in []float64, chunkSize int) []float64 {
+	var out []float64
+	for i := 0; i < len(in); i += chunkSize {
+		end := i + chunkSize
+		if end > len(in) {
+			end = len(in)
+		}
+		o, err := r.Process([][]float64{in[i:end]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, o[0]...)
+	}
+	return append(out, r.Flush()[0]...)
+}
+
+func sine(n int, freq, rate float64) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = math.Sin(2 * math.Pi * freq * float64(i) / rate)
+	}
+	return x
+}
+
+func TestResampleLength(t *testing.T) {
+	golden := []struct {
+		src, dst, n int
+	}{
+		{src: 44100, dst: 48000, n: 44100},
+		{src: 48000, dst: 44100, n: 48000},
+		{src: 44100, dst: 11025, n: 12345},
+		{src: 8000, dst: 44100, n: 1000},
+	}
+	for _, g := range golden {
+		for _, q := range qualities {
+			r, err := resample.New(g.src, g.dst, 1, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, chunkSize := range []int{1000, g.n} {
+				out := resampleAll(t, r, make([]float64, g.n), chunkSize)
+				want := (g.n*g.dst + g.src - 1) / g.src
+				if len(out) != want {
+					t.Errorf("%d->%d Hz (%v, chunk size %d): sample count mismatch; expected %d, got %d", g.src, g.dst, q, chunkSize, want, len(out))
+				}
+			}
+		}
+	}
+}
+
+func TestResampleSine(t *testing.T) {
+	const (
+		src  = 44100
+		dst  = 48000
+		freq = 1000
+	)
+	// Maximum error per quality preset, away from the edges.
+	maxErr := map[resample.Quality]float64{
+		resample.QualityLinear: 1e-2,
+		resample.QualityLow:    1e-2,
+		resample.QualityMedium: 1e-3,
+		resample.QualityHigh:   1e-3,
+	}
+	in := sine(src, freq, src)
+	want := sine(dst, freq, dst)
+	for _, q := range qualities {
+		r, err := resample.New(src, dst, 1, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := resampleAll(t, r, in, 4096)
+		var worst float64
+		for i := 1000; i < len(out)-1000; i++ {
+			worst = math.Max(worst, math.Abs(out[i]-want[i]))
+		}
+		if worst > maxErr[q] {
+			t.Errorf("%v: error mismatch; expected <= %g, got %g", q, maxErr[q], worst)
+		}
+	}
+}
+
+func TestResampleAntiAlias(t *testing.T) {
+	// A 20 kHz tone is above the Nyquist frequency at 8 kHz, and should be
+	// suppressed by the windowed-sinc presets.
+	const (
+		src = 48000
+		dst = 8000
+	)
+	in := sine(src, 20000, src)
+	for _, q := range qualities[1:] {
+		r, err := resample.New(src, dst, 1, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := resampleAll(t, r, in, 4096)
+		var sum float64
+		for _, x := range out[500 : len(out)-500] {
+			sum += x * x
+		}
+		if rms := math.Sqrt(sum / float64(len(out)-1000)); rms > 0.01 {
+			t.Errorf("%v: aliasing not suppressed; rms %g", q, rms)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	src, err := flac.ParseFile("../../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	r, err := resample.New(int(src.Info.SampleRate), 48000, int(src.Info.NChannels), resample.QualityMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := *src.Info
+	info.SampleRate = 48000
+	info.BlockSizeMin, info.BlockSizeMax = 16, 65535
+	buf := &bytes.Buffer{}
+	enc, err := flac.NewEncoder(buf, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &flac.TranscodeOptions{Transforms: []flac.Transform{r.Transform()}, Verify: true}
+	if err := flac.Transcode(enc, src, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nsamples uint64
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		if f.SampleRate != 48000 {
+			t.Fatalf("sample rate mismatch; expected 48000, got %d", f.SampleRate)
+		}
+		nsamples += uint64(f.BlockSize)
+	}
+	want := src.Info.NSamples * 48000 / uint64(src.Info.SampleRate)
+	if nsamples > want || want-nsamples > 64 {
+		t.Errorf("sample count mismatch; expected about %d, got %d", want, nsamples)
+	}
+}
@@ -2,7 +2,9 @@ package flac_test
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -10,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
 	"github.com/mewkiz/flac/meta"
 )
 
@@ -334,6 +337,320 @@ func TestEncodeAnalysisFixed(t *testing.T) {
 	}
 }
 
+// reencode re-encodes the FLAC file at path from verbatim subframes, using the
+// given encoder options, and returns the encoded stream.
+func reencode(path string, opts *flac.EncodeOptions) ([]byte, error) {
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	out := new(bytes.Buffer)
+	enc, err := flac.NewEncoderWithOptions(out, stream.Info, opts, stream.Blocks...)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		for _, subframe := range f.Subframes {
+			subframe.SubHeader = frame.SubHeader{Pred: frame.PredVerbatim}
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func TestEncodeAnalysisLPC(t *testing.T) {
+	golden := []struct {
+		name string
+		opts *flac.EncodeOptions
+	}{
+		{name: "default", opts: &flac.EncodeOptions{MaxLPCOrder: 8}},
+		{name: "hann", opts: &flac.EncodeOptions{MaxLPCOrder: 8, Apodization: "hann"}},
+		{name: "flattop", opts: &flac.EncodeOptions{MaxLPCOrder: 8, Apodization: "flattop"}},
+		{name: "-8", opts: &flac.EncodeOptions{MaxLPCOrder: 12, Apodization: "tukey(0.5);partial_tukey(2);punchout_tukey(3)"}},
+		{name: "integer", opts: &flac.EncodeOptions{MaxLPCOrder: 8, IntegerLPC: true}},
+		{name: "integer -8", opts: &flac.EncodeOptions{MaxLPCOrder: 12, Apodization: "tukey(0.5);partial_tukey(2);punchout_tukey(3)", IntegerLPC: true}},
+		{name: "integer exhaustive", opts: &flac.EncodeOptions{MaxLPCOrder: 32, ExhaustiveModelSearch: true, IntegerLPC: true}},
+		{name: "all", opts: &flac.EncodeOptions{MaxLPCOrder: 32, Apodization: "bartlett;bartlett_hann;blackman;blackman_harris_4term_92db;connes;gauss(0.2);hamming;kaiser_bessel;nuttall;rectangle;triangle;welch;partial_tukey(3/0.2/0.3)"}},
+	}
+	for _, path := range []string{"testdata/19875.flac", "testdata/59996.flac", "testdata/love.flac"} {
+		t.Run(path, func(t *testing.T) {
+			if !exists(path) {
+				t.Skipf("path %q does not exist", path)
+			}
+			fixed, err := reencode(path, nil)
+			if err != nil {
+				t.Fatalf("%q: unable to encode FLAC file; %v", path, err)
+			}
+			wantStream, err := flac.Parse(bytes.NewReader(fixed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantSamples, err := getSamples(wantStream)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, g := range golden {
+				got, err := reencode(path, g.opts)
+				if err != nil {
+					t.Fatalf("%q: unable to encode FLAC file using %s apodization; %v", path, g.name, err)
+				}
+				gotStream, err := flac.Parse(bytes.NewReader(got))
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotSamples, err := getSamples(gotStream)
+				if err != nil {
+					t.Fatalf("%q: unable to decode FLAC file encoded using %s apodization; %v", path, g.name, err)
+				}
+				if !slices.Equal(gotSamples, wantSamples) {
+					t.Fatalf("%q: content mismatch using %s apodization", path, g.name)
+				}
+				if len(got) > len(fixed) {
+					t.Errorf("%q: LPC analysis using %s apodization increased size from %d to %d bytes", path, g.name, len(fixed), len(got))
+				}
+				t.Logf("%q: %s apodization: %d bytes (fixed prediction: %d bytes)", path, g.name, len(got), len(fixed))
+			}
+		})
+	}
+}
+
+func TestEncodeModelSearch(t *testing.T) {
+	// encode re-encodes the FLAC file at path from verbatim subframes, using
+	// the given encoder options, and returns the size of the encoded stream.
+	encode := func(path string, opts *flac.EncodeOptions) (int, error) {
+		data, err := reencode(path, opts)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := flac.Parse(bytes.NewReader(data)); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	for _, path := range []string{"testdata/19875.flac", "testdata/love.flac"} {
+		if !exists(path) {
+			continue
+		}
+		base, err := encode(path, &flac.EncodeOptions{MaxLPCOrder: 8})
+		if err != nil {
+			t.Fatalf("%q: unable to encode FLAC file; %v", path, err)
+		}
+		exhaustive, err := encode(path, &flac.EncodeOptions{MaxLPCOrder: 8, ExhaustiveModelSearch: true})
+		if err != nil {
+			t.Fatalf("%q: unable to encode FLAC file using exhaustive model search; %v", path, err)
+		}
+		precision, err := encode(path, &flac.EncodeOptions{MaxLPCOrder: 8, PrecisionSearch: true})
+		if err != nil {
+			t.Fatalf("%q: unable to encode FLAC file using precision search; %v", path, err)
+		}
+		both, err := encode(path, &flac.EncodeOptions{MaxLPCOrder: 8, ExhaustiveModelSearch: true, PrecisionSearch: true})
+		if err != nil {
+			t.Fatalf("%q: unable to encode FLAC file using exhaustive model and precision search; %v", path, err)
+		}
+		t.Logf("%q: %d bytes; -e: %d bytes; -p: %d bytes; -e -p: %d bytes", path, base, exhaustive, precision, both)
+		if exhaustive > base || precision > base {
+			t.Errorf("%q: search increased size; expected <= %d bytes, got %d bytes (-e) and %d bytes (-p)", path, base, exhaustive, precision)
+		}
+		if both > exhaustive || both > precision {
+			t.Errorf("%q: combined search increased size; expected <= %d bytes, got %d bytes", path, min(exhaustive, precision), both)
+		}
+	}
+}
+
+func TestEncodeReproducible(t *testing.T) {
+	const path = "testdata/love.flac"
+	if !exists(path) {
+		t.Skipf("path %q does not exist", path)
+	}
+	opts := &flac.EncodeOptions{
+		MaxLPCOrder:  12,
+		Apodization:  "tukey(0.5);partial_tukey(2);punchout_tukey(3);gauss(0.2)",
+		Reproducible: true,
+		Vendor:       "reproducible",
+	}
+	want, err := reencode(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The output of reproducible encoders is identical across platforms; the
+	// golden digest must only change with the encoder algorithm.
+	const golden = "d4695982619cb618b25db60ff735e7a6524e35b909c2aedd68f7beafc8d70b50"
+	if got := fmt.Sprintf("%x", sha256.Sum256(want)); got != golden {
+		t.Errorf("SHA-256 digest mismatch; expected %s, got %s", golden, got)
+	}
+	opts.Workers = 4
+	got, err := reencode(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("content mismatch using 4 workers")
+	}
+	stream, err := flac.Parse(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range stream.Blocks {
+		if comment, ok := block.Body.(*meta.VorbisComment); ok && comment.Vendor != opts.Vendor {
+			t.Errorf("vendor string mismatch; expected %q, got %q", opts.Vendor, comment.Vendor)
+		}
+	}
+}
+
+func TestEncodeApodizationInvalid(t *testing.T) {
+	info := &meta.StreamInfo{
+		BlockSizeMin:  4096,
+		BlockSizeMax:  4096,
+		SampleRate:    44100,
+		NChannels:     2,
+		BitsPerSample: 16,
+	}
+	golden := []*flac.EncodeOptions{
+		{MaxLPCOrder: 8, Apodization: "cosine"},
+		{MaxLPCOrder: 8, Apodization: "tukey(2)"},
+		{MaxLPCOrder: 8, Apodization: "tukey(0.5"},
+		{MaxLPCOrder: 8, Apodization: "hann(1)"},
+		{MaxLPCOrder: 8, Apodization: "partial_tukey"},
+		{MaxLPCOrder: 8, Apodization: ";"},
+		{MaxLPCOrder: 33},
+		{MaxLPCOrder: 16, Subset: true},
+	}
+	for _, opts := range golden {
+		if _, err := flac.NewEncoderWithOptions(io.Discard, info, opts); err == nil {
+			t.Errorf("expected error for apodization %q and maximum LPC order %d", opts.Apodization, opts.MaxLPCOrder)
+		}
+	}
+}
+
+func TestEncodeWorkers(t *testing.T) {
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			if !exists(path) {
+				t.Skipf("path %q does not exist", path)
+			}
+			want, err := reencode(path, nil)
+			if err != nil {
+				t.Fatalf("%q: unable to encode FLAC file; %v", path, err)
+			}
+			for _, workers := range []int{2, 4} {
+				got, err := reencode(path, &flac.EncodeOptions{Workers: workers})
+				if err != nil {
+					t.Fatalf("%q: unable to encode FLAC file using %d workers; %v", path, workers, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%q: content mismatch using %d workers", path, workers)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeMaxFrameSize(t *testing.T) {
+	golden := []struct {
+		path string
+		// Maximum frame size in bytes.
+		size int
+		pad  bool
+		// Expected error.
+		err error
+	}{
+		{path: "testdata/love.flac", size: 12000, pad: true},
+		{path: "testdata/love.flac", size: 16000, pad: true},
+		{path: "testdata/59996.flac", size: 20000, pad: true},
+		{path: "testdata/59996.flac", size: 20000},
+		{path: "testdata/172960.flac", size: 10000, pad: true},
+		{path: "testdata/love.flac", size: 1000, err: flac.ErrFrameTooLarge},
+	}
+	for _, g := range golden {
+		stream, err := flac.ParseFile(g.path)
+		if err != nil {
+			t.Fatalf("%q: unable to parse FLAC file; %v", g.path, err)
+		}
+		defer stream.Close()
+		out := new(bytes.Buffer)
+		opts := &flac.EncodeOptions{MaxFrameSize: g.size, PadFrames: g.pad}
+		enc, err := flac.NewEncoderWithOptions(out, stream.Info, opts, stream.Blocks...)
+		if err != nil {
+			t.Fatalf("%q: unable to create encoder; %v", g.path, err)
+		}
+		var encErr error
+		for {
+			frame, err := stream.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatalf("%q: unable to parse audio frame; %v", g.path, err)
+			}
+			if encErr = enc.WriteFrame(frame); encErr != nil {
+				break
+			}
+		}
+		if g.err != nil {
+			if !errors.Is(encErr, g.err) {
+				t.Errorf("%q: error mismatch; expected %v, got %v", g.path, g.err, encErr)
+			}
+			continue
+		}
+		if encErr != nil {
+			t.Fatalf("%q: unable to encode audio frame; %v", g.path, encErr)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("%q: unable to close encoder; %v", g.path, err)
+		}
+
+		// Verify frame sizes and decoded audio samples.
+		got, err := flac.New(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatalf("%q: unable to parse encoded FLAC stream; %v", g.path, err)
+		}
+		want, err := flac.ParseFile(g.path)
+		if err != nil {
+			t.Fatalf("%q: unable to parse FLAC file; %v", g.path, err)
+		}
+		defer want.Close()
+		for {
+			start := got.Offset()
+			f, err := got.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatalf("%q: unable to parse encoded audio frame; %v", g.path, err)
+			}
+			size := int(got.Offset() - start)
+			if size > g.size {
+				t.Errorf("%q: size of frame %d exceeds maximum; expected <= %d, got %d", g.path, f.Num, g.size, size)
+			}
+			wantFrame, err := want.ParseNext()
+			if err != nil {
+				t.Fatalf("%q: unable to parse audio frame; %v", g.path, err)
+			}
+			if g.pad && size != g.size && f.BlockSize == wantFrame.BlockSize && wantFrame.BlockSize == want.Info.BlockSizeMax {
+				t.Errorf("%q: size mismatch of padded frame %d; expected %d, got %d", g.path, f.Num, g.size, size)
+			}
+			for i, subframe := range f.Subframes {
+				if !slices.Equal(subframe.Samples, wantFrame.Subframes[i].Samples) {
+					t.Fatalf("%q: sample mismatch in channel %d of frame %d", g.path, i, f.Num)
+				}
+			}
+		}
+	}
+}
+
 // getSamples returns all audio samples in stream.
 func getSamples(stream *flac.Stream) ([]int32, error) {
 	var out []int32
@@ -357,3 +674,93 @@ func exists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+func TestMarshalMetadata(t *testing.T) {
+	for _, path := range []string{"meta/testdata/input-SCPAP.flac", "meta/testdata/input-SCVPAP.flac", "testdata/love.flac"} {
+		t.Run(path, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream, err := flac.Parse(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			hdr, err := flac.MarshalMetadata(stream.Info, stream.Blocks...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := data[:stream.DataStart()]; !bytes.Equal(hdr, want) {
+				t.Fatalf("stream header mismatch; expected %d bytes, got %d bytes", len(want), len(hdr))
+			}
+		})
+	}
+	// Metadata blocks of reserved types are rejected.
+	block := &meta.Block{Header: meta.Header{Type: 0x7E, Length: 4}}
+	if _, err := flac.MarshalMetadata(&meta.StreamInfo{}, block); err == nil {
+		t.Fatalf("expected error for metadata block without body")
+	}
+}
+
+func TestEncodeBitsPerSampleFromStreamInfo(t *testing.T) {
+	// Sample sizes without a frame header code are stored as "get from
+	// STREAMINFO".
+	for _, bps := range []uint8{4, 13, 31} {
+		info := &meta.StreamInfo{
+			BlockSizeMin:  16,
+			BlockSizeMax:  16,
+			SampleRate:    44100,
+			NChannels:     1,
+			BitsPerSample: bps,
+		}
+		out := new(bytes.Buffer)
+		enc, err := flac.NewEncoder(out, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := make([]int32, 16)
+		for i := range samples {
+			samples[i] = int32(i-8) << (bps - 4)
+		}
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         16,
+				SampleRate:        44100,
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     bps,
+			},
+			Subframes: []*frame.Subframe{{SubHeader: frame.SubHeader{Pred: frame.PredVerbatim}, Samples: samples, NSamples: 16}},
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatalf("%d bits-per-sample: unable to encode frame; %v", bps, err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		stream, err := flac.New(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := stream.ParseNext()
+		if err != nil {
+			t.Fatalf("%d bits-per-sample: unable to decode frame; %v", bps, err)
+		}
+		if got.BitsPerSample != bps {
+			t.Errorf("%d bits-per-sample: sample size mismatch; expected %d, got %d", bps, bps, got.BitsPerSample)
+		}
+		if !slices.Equal(got.Subframes[0].Samples, samples) {
+			t.Errorf("%d bits-per-sample: samples mismatch; expected %v, got %v", bps, samples, got.Subframes[0].Samples)
+		}
+
+		// The sample size of the frame must match StreamInfo.
+		info.BitsPerSample = 24
+		enc, err = flac.NewEncoder(new(bytes.Buffer), info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.WriteFrame(f); err == nil {
+			t.Errorf("%d bits-per-sample: expected error for sample size differing from StreamInfo", bps)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package flac
 
 import (
+	"bytes"
 	"crypto/md5"
+	"errors"
+	"fmt"
 	"hash"
 	"io"
+	"time"
 
 	"github.com/icza/bitio"
+	"github.com/mewkiz/flac/frame"
 	"github.com/mewkiz/flac/meta"
 	"github.com/mewkiz/pkg/errutil"
 )
@@ -16,12 +21,21 @@ type Encoder struct {
 	*Stream
 	// Underlying io.Writer or io.WriteCloser to the output stream.
 	w io.Writer
-	// Minimum and maximum block size (in samples) of frames written by encoder.
+	// Output stream, tracking the byte offset of written data.
+	ow *offsetWriter
+	// Minimum block size (in samples) of frames written by encoder, excluding
+	// the last frame, and maximum block size of frames written by encoder.
 	blockSizeMin, blockSizeMax uint16
+	// Block size (in samples) of the last frame written by encoder.
+	lastBlockSize uint16
 	// Minimum and maximum frame size (in bytes) of frames written by encoder.
 	frameSizeMin, frameSizeMax uint32
 	// MD5 running hash of unencoded audio samples.
 	md5sum hash.Hash
+	// MD5 running hash of the audio samples of the frames written to the output
+	// stream, as decoded from the encoded frames; or nil if the encoded frames
+	// are not verified. See TranscodeOptions.Verify.
+	verifySum hash.Hash
 	// Total number of samples (per channel) written by encoder.
 	nsamples uint64
 	// Current frame number if block size is fixed, and the first sample number
@@ -29,14 +43,220 @@ type Encoder struct {
 	curNum uint64
 	// AnalysisEnabled indicates whether analysis is enabled for the encoder.
 	AnalysisEnabled bool
+	// Encoder options.
+	opts EncodeOptions
+	// LPC analysis configuration; or nil if LPC analysis is disabled.
+	lpc *lpcConfig
+	// Frames being encoded concurrently, in stream order; used if the Workers
+	// option is greater than 1.
+	pending []*pendingFrame
+	// Byte offset of the last frame written by encoder.
+	lastFrameOffset int64
+	// Total number of samples (per channel) at the last checkpoint.
+	checkpointSamples uint64
+	// Byte offset of the first frame of the output stream.
+	dataStart int64
+	// Total number of samples (per channel) of frames written to the output
+	// stream; excluding pending frames.
+	nsamplesWritten uint64
+	// Seek table of the output stream, filled in as frames are written; or nil
+	// if unspecified by EncodeOptions.SeekTableInterval.
+	seekTable *meta.SeekTable
+	// Byte offset of the seek table metadata block in the output stream, and
+	// whether it is the last metadata block.
+	seekTableOffset int64
+	seekTableLast   bool
+	// Distance in samples between the target samples of seek points.
+	seekInterval uint64
+	// Number of seek points filled in, and number of target samples passed.
+	nseekPoints  int
+	nseekTargets uint64
 }
 
+// EncodeOptions specifies the options of a FLAC encoder. The zero value
+// specifies the default options.
+type EncodeOptions struct {
+	// Subset restricts the encoder to the streamable subset of the FLAC format,
+	// which is the only format hardware decoders are required to support. The
+	// StreamInfo block and each frame are validated against the subset before
+	// being written, and the sample rate and sample size are stored in each
+	// frame header.
+	//
+	// ref: https://www.xiph.org/flac/format.html#subset
+	Subset bool
+	// MaxLPCOrder specifies the maximum prediction order of FIR linear
+	// prediction, as used by prediction analysis; a 0 value disables FIR linear
+	// prediction, in which case subframes are encoded using fixed prediction.
+	// The maximum order of subset streams is 12 for sample rates of at most
+	// 48 kHz; the reference encoder uses an order of 8 by default, and 12 at
+	// its highest compression level.
+	MaxLPCOrder int
+	// Apodization specifies the apodization (windowing) functions of LPC
+	// analysis, separated by semicolons, using the syntax of the -A option of
+	// the reference encoder; e.g. "tukey(0.5);partial_tukey(2);punchout_tukey(3)"
+	// as used at its highest compression level. Each window function is
+	// evaluated for every subframe, and the one yielding the fewest bits is
+	// selected; more window functions improve compression at the expense of
+	// encoding speed. An empty value specifies DefaultApodization.
+	Apodization string
+	// ExhaustiveModelSearch specifies whether to evaluate every prediction order
+	// up to MaxLPCOrder in LPC analysis, and select the order yielding the
+	// fewest bits; rather than the order estimated from the prediction error,
+	// as done by default. As each order is quantized and its residuals are
+	// computed, encoding is considerably slower; roughly by a factor of
+	// MaxLPCOrder. Corresponds to the -e option of the reference encoder.
+	ExhaustiveModelSearch bool
+	// PrecisionSearch specifies whether to evaluate every precision of the
+	// quantized LPC coefficients, from 5 to 15 bits, and select the precision
+	// yielding the fewest bits; rather than the precision selected from the
+	// sample size and block size, as done by default. Encoding is slower by
+	// roughly a factor of 11, and the gains are typically small. Corresponds to
+	// the -p option of the reference encoder.
+	PrecisionSearch bool
+	// Reproducible specifies whether to guarantee bit-identical output across
+	// runs, platforms and Go versions, for the same input and options; e.g. for
+	// content-addressed archives. LPC analysis, the only floating-point
+	// computation of the encoder, then uses portable implementations of the
+	// elementary functions rather than those of the math package, which are
+	// implemented in architecture-specific assembly on some platforms. The
+	// output of the encoder is otherwise identical across runs (regardless of
+	// Workers), and across platforms of identical math package results.
+	Reproducible bool
+	// IntegerLPC specifies whether to use fixed-point integer arithmetic in LPC
+	// analysis, rather than floating-point arithmetic; e.g. on platforms without
+	// hardware floating-point support (GOARM=5, GOMIPS=softfloat), where
+	// floating-point arithmetic is emulated in software. The window functions
+	// are computed once per block size, using the portable elementary functions
+	// of Reproducible, and quantized to 16 bits; the analysis of subframes is
+	// integer-only. The output is reproducible, and typically slightly larger
+	// than that of floating-point LPC analysis, due to the limited precision of
+	// the fixed-point windows and coefficients.
+	IntegerLPC bool
+	// Vendor, if non-empty, specifies the vendor string of the VorbisComment
+	// metadata block, replacing that of the given metadata blocks; a
+	// VorbisComment block is added if not present. A fixed vendor string keeps
+	// the output independent of the software which produced the metadata
+	// blocks. If empty, the given metadata blocks are retained, and a
+	// VorbisComment block with the vendor string of this package (e.g.
+	// "farcloser/flac 1.0.14") is added if no metadata blocks are given.
+	Vendor string
+	// Workers specifies the number of frames encoded concurrently by worker
+	// goroutines. The encoded frames are written in order, and the output is
+	// identical to that of a single-threaded encoder. A value of 0 or 1
+	// specifies that frames are encoded synchronously by WriteFrame; a value of
+	// runtime.NumCPU() utilizes all CPU cores.
+	//
+	// When Workers is greater than 1, WriteFrame copies the audio samples of the
+	// frame before returning, and encoded frames are written to the output
+	// stream once available; use Flush to wait for pending frames to be
+	// written.
+	Workers int
+	// MaxFrameSize specifies the maximum size in bytes of encoded frames; a 0
+	// value implies no limit. Frames exceeding the limit are re-encoded using
+	// fixed predictors of lower orders, and as a last resort using verbatim
+	// subframes; frames which still exceed the limit are rejected with
+	// ErrFrameTooLarge. A limit of at least the size of a verbatim frame
+	// guarantees that every frame is accepted.
+	MaxFrameSize int
+	// PadFrames specifies whether to pad encoded frames to exactly MaxFrameSize
+	// bytes, for constant bitrate streaming over fixed-bandwidth links. Frames
+	// are padded within the FLAC format, by storing the residuals of one
+	// subframe in escaped Rice partitions of increased sample size; the decoded
+	// audio samples are unaffected. Frames which leave less than a few bytes of
+	// room for padding may be left short of MaxFrameSize.
+	PadFrames bool
+	// CheckpointInterval specifies the duration of audio between periodic
+	// checkpoints of the encoder; a 0 value disables periodic checkpoints. At
+	// each checkpoint, pending frames are written, the StreamInfo block of the
+	// output stream is updated if the io.Writer implements io.WriteSeeker, and
+	// the output stream is committed to stable storage if the io.Writer
+	// implements Sync (e.g. *os.File); the output stream remains a valid FLAC
+	// stream up to the last checkpoint if the encoder is interrupted. See
+	// Encoder.Checkpoint and ResumeEncoder.
+	CheckpointInterval time.Duration
+	// CheckpointPath specifies the path of a sidecar file, to which the state
+	// of each periodic checkpoint is written; see Checkpoint.WriteFile.
+	CheckpointPath string
+	// OnFrame, if non-nil, is invoked with the statistics of each audio frame
+	// once it has been written to the output stream; e.g. to display the live
+	// compression ratio, or to debug size regressions. Frames are reported in
+	// stream order, from the goroutine calling WriteFrame, Flush or Close. The
+	// subframes are encoded twice to record their sizes, so encoding is
+	// slightly slower when OnFrame is set.
+	OnFrame func(stats *FrameStats)
+	// BlockOrder specifies the order of the metadata blocks following the
+	// StreamInfo block, by block type; e.g. []meta.Type{meta.TypeSeekTable,
+	// meta.TypeVorbisComment, meta.TypePicture, meta.TypePadding}. Blocks are
+	// sorted by the position of their type in BlockOrder; blocks of the same
+	// type, and of types not in BlockOrder, retain their relative order,
+	// following the blocks of the types in BlockOrder. A nil value retains the
+	// order of the given metadata blocks, followed by the padding block.
+	BlockOrder []meta.Type
+	// Padding specifies the size in bytes of a padding block appended to the
+	// metadata blocks; e.g. 8192, as reserved by the reference encoder. Tags
+	// may then be edited in place, without rewriting the audio frames, as long
+	// as the metadata blocks fit in the padding (see MarshalMetadata). A 0
+	// value adds no padding block.
+	Padding int
+	// SeekTableInterval specifies the duration of audio between the seek points
+	// of a seek table, inserted before the given metadata blocks; e.g. 10
+	// seconds, as used by the reference encoder. A 0 value adds no seek table.
+	// The number of seek points follows from the total number of samples of the
+	// StreamInfo block, which must be specified. The seek table is written as
+	// placeholder points, filled in by Close if the io.Writer implements
+	// io.WriteSeeker; each seek point references the first frame holding its
+	// target sample.
+	SeekTableInterval time.Duration
+}
+
+// ErrFrameTooLarge reports that an encoded frame exceeds the maximum frame size
+// of the encoder.
+var ErrFrameTooLarge = errors.New("flac: frame too large")
+
 // NewEncoder returns a new FLAC encoder for the given metadata StreamInfo block
 // and optional metadata blocks.
 //
 // By default prediction analysis is enabled. For more information, see
 // Encoder.EnablePredictionAnalysis.
 func NewEncoder(w io.Writer, info *meta.StreamInfo, blocks ...*meta.Block) (*Encoder, error) {
+	return NewEncoderWithOptions(w, info, nil, blocks...)
+}
+
+// NewEncoderWithOptions returns a new FLAC encoder for the given metadata
+// StreamInfo block and optional metadata blocks, using the specified encoder
+// options. A nil opts specifies the default options. The StreamInfo block is
+// validated using meta.StreamInfo.Validate, except for unknown block sizes (0
+// values), which are permitted as the block sizes are computed from the encoded
+// frames; see Encoder.Close.
+func NewEncoderWithOptions(w io.Writer, info *meta.StreamInfo, opts *EncodeOptions, blocks ...*meta.Block) (*Encoder, error) {
+	if opts == nil {
+		opts = &EncodeOptions{}
+	}
+	if opts.MaxFrameSize < 0 || opts.PadFrames && opts.MaxFrameSize == 0 {
+		return nil, errutil.Newf("invalid maximum frame size %d", opts.MaxFrameSize)
+	}
+	// NOTE: the errors of StreamInfo validation are not wrapped, so that
+	// callers may test for meta.ErrInvalidStreamInfo and frame.ErrNotSubset
+	// using errors.Is.
+	if err := validateInfo(info); err != nil {
+		return nil, err
+	}
+	if opts.Subset {
+		if err := checkInfoSubset(info); err != nil {
+			return nil, fmt.Errorf("flac.NewEncoderWithOptions: %w", err)
+		}
+	}
+	lpc, err := newLPCConfig(info, opts)
+	if err != nil {
+		return nil, errutil.Err(err)
+	}
+	if vendor := encoderVendor(blocks, opts); vendor != "" {
+		blocks = withVendor(blocks, vendor)
+	}
+	blocks, table, err := layoutBlocks(info, blocks, opts)
+	if err != nil {
+		return nil, errutil.Err(err)
+	}
 	// Store FLAC signature.
 	enc := &Encoder{
 		Stream: &Stream{
@@ -44,64 +264,55 @@ func NewEncoder(w io.Writer, info *meta.StreamInfo, blocks ...*meta.Block) (*Enc
 			Blocks: blocks,
 		},
 		w:               w,
+		ow:              &offsetWriter{w: w},
 		md5sum:          md5.New(),
 		AnalysisEnabled: true, // enable prediction analysis by default.
+		opts:            *opts,
+		lpc:             lpc,
 	}
 
-	bw := bitio.NewWriter(w)
-	if _, err := bw.Write(flacSignature); err != nil {
-		return nil, errutil.Err(err)
+	if err := enc.initSeekTable(table); err != nil {
+		return nil, err
 	}
-	// Encode metadata blocks.
+
 	// TODO: consider using bufio.NewWriter.
-	if err := encodeStreamInfo(bw, info, len(blocks) == 0); err != nil {
-		return nil, errutil.Err(err)
-	}
-	for i, block := range blocks {
-		if err := encodeBlock(bw, block, i == len(blocks)-1); err != nil {
-			return nil, errutil.Err(err)
-		}
-	}
-	// Flush pending writes of metadata blocks.
-	if _, err := bw.Align(); err != nil {
-		return nil, errutil.Err(err)
+	if err := encodeHeader(enc.ow, info, blocks); err != nil {
+		return nil, err
 	}
+	enc.dataStart = enc.ow.n
 	// Return encoder to be used for encoding audio samples.
 	return enc, nil
 }
 
+// validateInfo validates the given StreamInfo block of an encoder, as done by
+// meta.StreamInfo.Validate, permitting unknown block sizes.
+func validateInfo(info *meta.StreamInfo) error {
+	si := *info
+	if si.BlockSizeMin == 0 {
+		si.BlockSizeMin = frame.MinBlockSize
+	}
+	if si.BlockSizeMax == 0 {
+		si.BlockSizeMax = max(si.BlockSizeMin, frame.MinBlockSize)
+	}
+	return si.Validate()
+}
+
 // Close closes the underlying io.Writer of the encoder and flushes any pending
 // writes. If the io.Writer implements io.Seeker, the encoder will update the
 // StreamInfo metadata block with the MD5 checksum of the unencoded audio
 // samples, the number of samples, and the minimum and maximum frame size and
-// block size.
+// block size; and fill in the seek table, if specified by
+// EncodeOptions.SeekTableInterval.
 func (enc *Encoder) Close() error {
+	// Write pending frames.
+	if err := enc.Flush(); err != nil {
+		return err
+	}
 	// TODO: check if bit writer should be flushed before seeking on enc.w.
 	// Update StreamInfo metadata block.
 	if ws, ok := enc.w.(io.WriteSeeker); ok {
-		if _, err := ws.Seek(int64(len(flacSignature)), io.SeekStart); err != nil {
-			return errutil.Err(err)
-		}
-		// Update minimum and maximum block size (in samples) of FLAC stream.
-		enc.Info.BlockSizeMin = enc.blockSizeMin
-		enc.Info.BlockSizeMax = enc.blockSizeMax
-		// Update minimum and maximum frame size (in bytes) of FLAC stream.
-		enc.Info.FrameSizeMin = enc.frameSizeMin
-		enc.Info.FrameSizeMax = enc.frameSizeMax
-		// Update total number of samples (per channel) of FLAC stream.
-		enc.Info.NSamples = enc.nsamples
-		// Update MD5 checksum of the unencoded audio samples.
-		sum := enc.md5sum.Sum(nil)
-		for i := range sum {
-			enc.Info.MD5sum[i] = sum[i]
-		}
-		bw := bitio.NewWriter(ws)
-		// Write updated StreamInfo metadata block to output stream.
-		if err := encodeStreamInfo(bw, enc.Info, len(enc.Blocks) == 0); err != nil {
-			return errutil.Err(err)
-		}
-		if _, err := bw.Align(); err != nil {
-			return errutil.Err(err)
+		if err := enc.updateStreamInfo(ws); err != nil {
+			return err
 		}
 	}
 	if closer, ok := enc.w.(io.Closer); ok {
@@ -110,6 +321,50 @@ func (enc *Encoder) Close() error {
 	return nil
 }
 
+// updateStreamInfo updates the StreamInfo metadata block of the output stream
+// with the MD5 checksum of the unencoded audio samples, the number of samples,
+// and the minimum and maximum frame size and block size; and writes the seek
+// table of the encoder, if any.
+func (enc *Encoder) updateStreamInfo(ws io.WriteSeeker) error {
+	if _, err := ws.Seek(int64(len(flacSignature)), io.SeekStart); err != nil {
+		return errutil.Err(err)
+	}
+	// Update minimum and maximum block size (in samples) of FLAC stream. The
+	// block size of the last frame is only used if it is the sole frame, and
+	// block sizes are at least 16 samples as required by StreamInfo.
+	blockSizeMin, blockSizeMax := enc.blockSizeMin, enc.blockSizeMax
+	if blockSizeMin == 0 {
+		blockSizeMin = enc.lastBlockSize
+	}
+	if blockSizeMin < frame.MinBlockSize {
+		blockSizeMin = frame.MinBlockSize
+	}
+	if blockSizeMax < blockSizeMin {
+		blockSizeMax = blockSizeMin
+	}
+	enc.Info.BlockSizeMin = blockSizeMin
+	enc.Info.BlockSizeMax = blockSizeMax
+	// Update minimum and maximum frame size (in bytes) of FLAC stream.
+	enc.Info.FrameSizeMin = enc.frameSizeMin
+	enc.Info.FrameSizeMax = enc.frameSizeMax
+	// Update total number of samples (per channel) of FLAC stream.
+	enc.Info.NSamples = enc.nsamples
+	// Update MD5 checksum of the unencoded audio samples.
+	sum := enc.md5sum.Sum(nil)
+	for i := range sum {
+		enc.Info.MD5sum[i] = sum[i]
+	}
+	bw := bitio.NewWriter(ws)
+	// Write updated StreamInfo metadata block to output stream.
+	if err := encodeStreamInfo(bw, enc.Info, len(enc.Blocks) == 0); err != nil {
+		return errutil.Err(err)
+	}
+	if _, err := bw.Align(); err != nil {
+		return errutil.Err(err)
+	}
+	return enc.updateSeekTable(ws)
+}
+
 // EnablePredictionAnalysis specifies whether to enable analysis for the
 // encoder. When analysis is enabled, subframes that are currently marked as
 // PredVerbatim will be analyzed to use the best prediction method
@@ -117,3 +372,26 @@ func (enc *Encoder) Close() error {
 func (enc *Encoder) EnablePredictionAnalysis(enable bool) {
 	enc.AnalysisEnabled = enable
 }
+
+// offsetWriter tracks the byte offset of data written to the underlying
+// io.Writer.
+type offsetWriter struct {
+	// Underlying io.Writer.
+	w io.Writer
+	// Byte offset of the next write.
+	n int64
+	// Copy of the data written since the last frame was verified; or nil if the
+	// encoded frames are not verified (see Encoder.verifyFrames).
+	capture *bytes.Buffer
+}
+
+// Write writes len(p) bytes from p, and advances the byte offset by the number
+// of bytes written.
+func (ow *offsetWriter) Write(p []byte) (n int, err error) {
+	n, err = ow.w.Write(p)
+	ow.n += int64(n)
+	if ow.capture != nil {
+		ow.capture.Write(p[:n])
+	}
+	return n, err
+}
@@ -1,6 +1,7 @@
 package flac
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 	"math"
@@ -29,16 +30,35 @@ func (enc *Encoder) WriteFrame(f *frame.Frame) error {
 			return errutil.Newf("invalid number of samples in channel %d; expected %d, got %d", i, nsamplesPerChannel, len(subframe.Samples))
 		}
 	}
+	if nsamplesPerChannel < 1 || nsamplesPerChannel > frame.MaxBlockSize {
+		return errutil.Newf("invalid block size %d; expected >= 1 and <= %d", nsamplesPerChannel, frame.MaxBlockSize)
+	}
+	if enc.lastBlockSize != 0 && enc.lastBlockSize < frame.MinBlockSize {
+		return errutil.Newf("block size (%d) of preceding frame below %d samples; only the last frame may hold fewer samples", enc.lastBlockSize, frame.MinBlockSize)
+	}
 	if nchannels != f.Channels.Count() {
 		return errutil.Newf("channel count mismatch; expected %d, got %d", nchannels, f.Channels.Count())
 	}
+	if enc.opts.Subset {
+		// Store sample rate and sample size in the frame header, as required by
+		// the streamable subset.
+		if f.SampleRate == 0 {
+			f.SampleRate = enc.Info.SampleRate
+		}
+		if f.BitsPerSample == 0 && hasBitsPerSampleCode(enc.Info.BitsPerSample) {
+			f.BitsPerSample = enc.Info.BitsPerSample
+		}
+		if err := f.CheckSubset(enc.Info.SampleRate); err != nil {
+			return err
+		}
+	}
+	// Sample sizes without a frame header code are stored as "get from
+	// STREAMINFO", and must therefore match StreamInfo.
+	if f.BitsPerSample != 0 && !hasBitsPerSampleCode(f.BitsPerSample) && f.BitsPerSample != enc.Info.BitsPerSample {
+		return errutil.Newf("sample size (%d) without frame header code differs from StreamInfo (%d)", f.BitsPerSample, enc.Info.BitsPerSample)
+	}
 
-	// Create a new CRC-16 hash writer which adds the data from all write
-	// operations to a running hash.
-	h := crc16.NewIBM()
-	hw := io.MultiWriter(h, enc.w)
-
-	// Encode frame header.
+	// Calculate frame number and update stream properties.
 	f.Num = enc.curNum
 	if f.HasFixedBlockSize {
 		enc.curNum++
@@ -47,17 +67,157 @@ func (enc *Encoder) WriteFrame(f *frame.Frame) error {
 	}
 	enc.nsamples += uint64(nsamplesPerChannel)
 	blockSize := uint16(nsamplesPerChannel)
-	if enc.blockSizeMin == 0 || blockSize < enc.blockSizeMin {
-		enc.blockSizeMin = blockSize
+	// The minimum block size of StreamInfo excludes the last frame, which may
+	// hold fewer samples; so the preceding frame is accounted for once it is
+	// known not to be the last.
+	if enc.lastBlockSize != 0 && (enc.blockSizeMin == 0 || enc.lastBlockSize < enc.blockSizeMin) {
+		enc.blockSizeMin = enc.lastBlockSize
 	}
+	enc.lastBlockSize = blockSize
 	if enc.blockSizeMax == 0 || blockSize > enc.blockSizeMax {
 		enc.blockSizeMax = blockSize
 	}
-	// TODO: track number of bytes written to hw, to update values of
-	// frameSizeMin and frameSizeMax.
 	// Add unencoded audio samples to running MD5 hash.
 	f.Hash(enc.md5sum)
-	if err := enc.encodeFrameHeader(hw, f.Header); err != nil {
+	if enc.opts.Workers > 1 {
+		if err := enc.encodeFrameAsync(f, blockSize); err != nil {
+			return err
+		}
+	} else {
+		var stats *FrameStats
+		if enc.opts.OnFrame != nil {
+			stats = &FrameStats{}
+		}
+		enc.lastFrameOffset = enc.ow.n
+		if err := encodeFrameWithOptions(enc.ow, f, enc.AnalysisEnabled, enc.lpc, &enc.opts, stats); err != nil {
+			return err
+		}
+		if err := enc.frameWritten(blockSize, stats); err != nil {
+			return err
+		}
+	}
+	return enc.periodicCheckpoint()
+}
+
+// A pendingFrame is a frame being encoded by a worker goroutine.
+type pendingFrame struct {
+	// Encoded frame.
+	buf bytes.Buffer
+	// Encoding error.
+	err error
+	// Block size (in samples) of the frame.
+	blockSize uint16
+	// Statistics of the encoded frame; or nil if not requested.
+	stats *FrameStats
+	// Closed once the frame has been encoded.
+	done chan struct{}
+}
+
+// encodeFrameAsync encodes a copy of the given audio frame, of the given block
+// size, on a worker goroutine. At most opts.Workers frames are encoded
+// concurrently; once the limit is reached, encodeFrameAsync waits for the
+// oldest pending frame to be encoded, and writes it to the output stream.
+func (enc *Encoder) encodeFrameAsync(f *frame.Frame, blockSize uint16) error {
+	for len(enc.pending) >= enc.opts.Workers {
+		if err := enc.writePending(); err != nil {
+			return err
+		}
+	}
+	// Copy the frame, as the caller may reuse its audio samples after
+	// WriteFrame returns.
+	g := &frame.Frame{
+		Header:    f.Header,
+		Subframes: make([]*frame.Subframe, len(f.Subframes)),
+	}
+	for i, subframe := range f.Subframes {
+		sub := *subframe
+		sub.Samples = append([]int32(nil), subframe.Samples...)
+		g.Subframes[i] = &sub
+	}
+	p := &pendingFrame{blockSize: blockSize, done: make(chan struct{})}
+	if enc.opts.OnFrame != nil {
+		p.stats = &FrameStats{}
+	}
+	enc.pending = append(enc.pending, p)
+	analysis := enc.AnalysisEnabled
+	go func() {
+		p.err = encodeFrameWithOptions(&p.buf, g, analysis, enc.lpc, &enc.opts, p.stats)
+		close(p.done)
+	}()
+	return nil
+}
+
+// writePending waits for the oldest pending frame to be encoded, and writes it
+// to the output stream.
+func (enc *Encoder) writePending() error {
+	p := enc.pending[0]
+	<-p.done
+	enc.pending[0] = nil
+	enc.pending = enc.pending[1:]
+	if p.err != nil {
+		return p.err
+	}
+	enc.lastFrameOffset = enc.ow.n
+	if _, err := enc.ow.Write(p.buf.Bytes()); err != nil {
+		return errutil.Err(err)
+	}
+	return enc.frameWritten(p.blockSize, p.stats)
+}
+
+// Flush waits for the frames being encoded concurrently (see
+// EncodeOptions.Workers) and writes them to the output stream. It is a no-op
+// for encoders without worker goroutines.
+func (enc *Encoder) Flush() error {
+	for len(enc.pending) > 0 {
+		if err := enc.writePending(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalFrame encodes the given audio frame, including its header and CRC-16
+// checksum, and returns the encoded frame. It is the inverse of
+// frame.ParseBytes, and enables sending individual frames through packet based
+// transports and fabricating frames in tests.
+//
+// Unlike Encoder.WriteFrame, the frame header is encoded as is (including the
+// Num field), and the subframes are encoded using the prediction method
+// specified by their subframe headers, without prediction analysis.
+func MarshalFrame(f *frame.Frame) ([]byte, error) {
+	if len(f.Subframes) != f.Channels.Count() {
+		return nil, errutil.Newf("subframe and channel count mismatch; expected %d, got %d", f.Channels.Count(), len(f.Subframes))
+	}
+	buf := &bytes.Buffer{}
+	if err := encodeFrame(buf, f, false, nil, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeFrame encodes the given audio frame, writing to w. If analysis is set,
+// verbatim subframes are analyzed to use the best prediction method; including
+// FIR linear prediction if lpc is non-nil. The frame header and subframe
+// statistics are recorded in stats if non-nil.
+func encodeFrame(w io.Writer, f *frame.Frame, analysis bool, lpc *lpcConfig, stats *FrameStats) error {
+	// Sanity checks.
+	if len(f.Subframes) == 0 {
+		return errutil.Newf("invalid number of subframes; expected > 0, got 0")
+	}
+	nsamplesPerChannel := f.Subframes[0].NSamples
+	for i, subframe := range f.Subframes {
+		if nsamplesPerChannel != len(subframe.Samples) {
+			return errutil.Newf("invalid number of samples in channel %d; expected %d, got %d", i, nsamplesPerChannel, len(subframe.Samples))
+		}
+	}
+
+	// Create a new CRC-16 hash writer which adds the data from all write
+	// operations to a running hash.
+	h := crc16.NewIBM()
+	hw := io.MultiWriter(h, w)
+
+	// Encode frame header.
+	if err := encodeFrameHeader(hw, f.Header); err != nil {
 		return errutil.Err(err)
 	}
 
@@ -65,32 +225,44 @@ func (enc *Encoder) WriteFrame(f *frame.Frame) error {
 	f.Decorrelate()
 	defer f.Correlate() // NOTE: revert decorrelation of audio samples after encoding is done (to make encode non-destructive).
 
+	if stats != nil {
+		stats.Header = f.Header
+		stats.Subframes = make([]SubframeStats, len(f.Subframes))
+	}
+
 	// Encode subframes.
 	bw := bitio.NewWriter(hw)
 	for channel, subframe := range f.Subframes {
-		// The side channel requires an extra bit per sample when using
-		// inter-channel decorrelation.
-		bps := uint(f.BitsPerSample)
-		switch f.Channels {
-		case frame.ChannelsSideRight:
-			// channel 0 is the side channel.
-			if channel == 0 {
-				bps++
-			}
-		case frame.ChannelsLeftSide, frame.ChannelsMidSide:
-			// channel 1 is the side channel.
-			if channel == 1 {
-				bps++
-			}
-		}
+		bps := subframeBitsPerSample(f.Header, channel)
 
 		// optional prediction analysis
 		//
 		// (leave subframe as-is if AnalysisEnabled is false)
-		if enc.AnalysisEnabled {
+		estBits := 0
+		if analysis {
 			switch subframe.Pred {
 			case frame.PredVerbatim:
-				analyzeSubframe(subframe, bps)
+				estBits = analyzeSubframe(subframe, bps, lpc)
+			}
+		}
+
+		if stats != nil {
+			nbits, err := encodedSize(func(bw *bitio.Writer) error {
+				return encodeSubframe(bw, f.Header, subframe, bps)
+			})
+			if err != nil {
+				return errutil.Err(err)
+			}
+			order := 0
+			if subframe.Pred == frame.PredFixed || subframe.Pred == frame.PredFIR {
+				order = subframe.Order
+			}
+			stats.Subframes[channel] = SubframeStats{
+				Pred:          subframe.Pred,
+				Order:         order,
+				Wasted:        subframe.Wasted,
+				Bits:          nbits,
+				EstimatedBits: estBits,
 			}
 		}
 
@@ -109,17 +281,38 @@ func (enc *Encoder) WriteFrame(f *frame.Frame) error {
 	// everything before the crc, back to and including the frame header sync
 	// code.
 	crc := h.Sum16()
-	if err := binary.Write(enc.w, binary.BigEndian, crc); err != nil {
+	if err := binary.Write(w, binary.BigEndian, crc); err != nil {
 		return errutil.Err(err)
 	}
 
 	return nil
 }
 
+// subframeBitsPerSample returns the sample size of the subframe of the given
+// channel.
+func subframeBitsPerSample(hdr frame.Header, channel int) uint {
+	// The side channel requires an extra bit per sample when using
+	// inter-channel decorrelation.
+	bps := uint(hdr.BitsPerSample)
+	switch hdr.Channels {
+	case frame.ChannelsSideRight:
+		// channel 0 is the side channel.
+		if channel == 0 {
+			bps++
+		}
+	case frame.ChannelsLeftSide, frame.ChannelsMidSide:
+		// channel 1 is the side channel.
+		if channel == 1 {
+			bps++
+		}
+	}
+	return bps
+}
+
 // --- [ Frame header ] --------------------------------------------------------
 
 // encodeFrameHeader encodes the given frame header, writing to w.
-func (enc *Encoder) encodeFrameHeader(w io.Writer, hdr frame.Header) error {
+func encodeFrameHeader(w io.Writer, hdr frame.Header) error {
 	// Create a new CRC-8 hash writer which adds the data from all write
 	// operations to a running hash.
 	h := crc8.NewATM()
@@ -395,6 +588,16 @@ func encodeFrameHeaderChannels(bw *bitio.Writer, channels frame.Channels) error
 
 // ~~~ [ Bits-per-sample ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
+// hasBitsPerSampleCode reports whether the given sample size may be stored in
+// the frame header, without referring to the StreamInfo metadata block.
+func hasBitsPerSampleCode(bps uint8) bool {
+	switch bps {
+	case 8, 12, 16, 20, 24, 32:
+		return true
+	}
+	return false
+}
+
 // encodeFrameHeaderBitsPerSample encodes the bits-per-sample of the frame
 // header, writing to bw.
 func encodeFrameHeaderBitsPerSample(bw *bitio.Writer, bps uint8) error {
@@ -431,7 +634,12 @@ func encodeFrameHeaderBitsPerSample(bw *bitio.Writer, bps uint8) error {
 		// 111 : 32 bits per sample (RFC 9639)
 		bits = 0x7
 	default:
-		return errutil.Newf("support for sample size %v not yet implemented", bps)
+		if bps < frame.MinBitsPerSample || bps > frame.MaxBitsPerSample {
+			return errutil.Newf("invalid sample size %d; expected >= %d and <= %d", bps, frame.MinBitsPerSample, frame.MaxBitsPerSample)
+		}
+		// 000 : get from STREAMINFO metadata block; sample sizes without a
+		// frame header code.
+		bits = 0x0
 	}
 	if err := bw.WriteBits(bits, 3); err != nil {
 		return errutil.Err(err)
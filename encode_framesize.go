@@ -0,0 +1,279 @@
+package flac
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/icza/bitio"
+	iobits "github.com/mewkiz/flac/bits"
+	"github.com/mewkiz/flac/frame"
+)
+
+// encodeFrameWithOptions encodes the given audio frame, writing to w, within
+// the maximum frame size and padding specified by opts. If analysis is set,
+// verbatim subframes are analyzed to use the best prediction method; including
+// FIR linear prediction if lpc is non-nil. The frame header and subframe
+// statistics of the encoded frame are recorded in stats if non-nil.
+func encodeFrameWithOptions(w io.Writer, f *frame.Frame, analysis bool, lpc *lpcConfig, opts *EncodeOptions, stats *FrameStats) error {
+	if opts.MaxFrameSize == 0 {
+		return encodeFrame(w, f, analysis, lpc, stats)
+	}
+	buf := &bytes.Buffer{}
+	if err := encodeFrame(buf, f, analysis, lpc, stats); err != nil {
+		return err
+	}
+	if buf.Len() > opts.MaxFrameSize {
+		// Fall back to fixed predictors of lower orders (as selected by
+		// prediction analysis), and to verbatim subframes.
+		for _, analysis := range []bool{true, false} {
+			for _, subframe := range f.Subframes {
+				subframe.SubHeader = frame.SubHeader{Pred: frame.PredVerbatim}
+			}
+			buf.Reset()
+			if err := encodeFrame(buf, f, analysis, nil, stats); err != nil {
+				return err
+			}
+			if buf.Len() <= opts.MaxFrameSize {
+				break
+			}
+		}
+		if buf.Len() > opts.MaxFrameSize {
+			return fmt.Errorf("flac.Encoder.WriteFrame: %w; size of frame %d (%d bytes) exceeds %d bytes", ErrFrameTooLarge, f.Num, buf.Len(), opts.MaxFrameSize)
+		}
+	}
+	if opts.PadFrames && buf.Len() < opts.MaxFrameSize {
+		maxPartOrder := frame.MaxPartitionOrder
+		if opts.Subset {
+			maxPartOrder = frame.SubsetMaxPartitionOrder
+		}
+		padded, err := padFrame(f, opts.MaxFrameSize, maxPartOrder)
+		if err != nil {
+			return err
+		}
+		if padded {
+			buf.Reset()
+			if err := encodeFrame(buf, f, false, nil, stats); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// padFrame updates the subframe headers of the given audio frame, so that the
+// encoded frame is padded to size bytes. It reports whether the frame was
+// padded.
+//
+// The residuals of one subframe are re-partitioned into partitions of the
+// smallest size permitted by maxPartOrder, keeping the prediction method of the
+// subframe. Each partition is either Rice encoded, or escaped with a sample size
+// of at most 31 bits; the sample size of escaped partitions is increased until
+// the frame reaches the requested size.
+func padFrame(f *frame.Frame, size, maxPartOrder int) (bool, error) {
+	// Size of frame header in bits.
+	hdrBits, err := encodedSize(func(bw *bitio.Writer) error {
+		return encodeFrameHeader(bw, f.Header)
+	})
+	if err != nil {
+		return false, err
+	}
+	// Subframes are encoded using decorrelated audio samples.
+	f.Decorrelate()
+	defer f.Correlate()
+	subframeBits := make([]int, len(f.Subframes))
+	total := 0
+	for channel, subframe := range f.Subframes {
+		n, err := encodedSize(func(bw *bitio.Writer) error {
+			return encodeSubframe(bw, f.Header, subframe, subframeBitsPerSample(f.Header, channel))
+		})
+		if err != nil {
+			return false, err
+		}
+		subframeBits[channel] = n
+		total += n
+	}
+	avail := 8*size - hdrBits - 8*2 // 2 bytes: CRC-16
+	for channel := len(f.Subframes) - 1; channel >= 0; channel-- {
+		subframe := f.Subframes[channel]
+		subHdr := subframe.SubHeader
+		// Wasted bits-per-sample are not used by padded subframes; the
+		// residuals of the unshifted audio samples are encoded instead.
+		subHdr.Wasted = 0
+		subHdr.ResidualCodingMethod = frame.ResidualCodingMethodRice1
+		var coeffs []int32
+		var shift int32
+		switch subHdr.Pred {
+		case frame.PredConstant, frame.PredVerbatim:
+			subHdr.Pred = frame.PredFixed
+			subHdr.Order = 0
+			coeffs = frame.FixedCoeffs[0]
+		case frame.PredFixed:
+			coeffs = frame.FixedCoeffs[subHdr.Order]
+		case frame.PredFIR:
+			coeffs, shift = subHdr.Coeffs, subHdr.CoeffShift
+		default:
+			continue
+		}
+		// 8 bits: subframe header; warm-up samples; 2 bits: residual coding
+		// method; 4 bits: partition order.
+		prefix := 8 + subHdr.Order*int(subframeBitsPerSample(f.Header, channel)) + 2 + 4
+		if subHdr.Pred == frame.PredFIR {
+			// 4 bits: coefficient precision; 5 bits: coefficient shift.
+			prefix += 4 + 5 + subHdr.Order*int(subHdr.CoeffPrec)
+		}
+		residuals, err := getLPCResiduals(&frame.Subframe{SubHeader: subHdr, Samples: subframe.Samples, NSamples: len(subframe.Samples)}, coeffs, shift)
+		if err != nil {
+			return false, err
+		}
+		// The padded subframe is followed by zero-padding to byte alignment, so
+		// any size within 7 bits of the available space is sufficient.
+		max := avail - (total - subframeBits[channel]) - prefix
+		min := max - 7
+		riceSubframe := padResiduals(residuals, subHdr.Order, min, max, maxPartOrder)
+		if riceSubframe == nil {
+			continue
+		}
+		subHdr.RiceSubframe = riceSubframe
+		subframe.SubHeader = subHdr
+		return true, nil
+	}
+	return false, nil
+}
+
+// Maximum Rice parameter and escape code of Rice partitions with a 4-bit Rice
+// parameter, and the maximum sample size of escaped partitions.
+const (
+	maxRiceParam       = 14
+	riceEscape         = 0xF
+	maxEscapedBitsSize = 31
+)
+
+// padResiduals returns Rice partitions of the given residuals of a subframe
+// with the given prediction order, such that the encoded size in bits of the
+// partitions (including Rice parameters) is within [min, max]; or nil if no
+// such partitioning was found.
+func padResiduals(residuals []int32, order, min, max, maxPartOrder int) *frame.RiceSubframe {
+	n := len(residuals) + order
+	// Prefer high partition orders, as the size of escaped partitions may be
+	// adjusted in steps of the partition size.
+	for partOrder := maxPartOrder; partOrder >= 0; partOrder-- {
+		nparts := 1 << partOrder
+		if n%nparts != 0 || n/nparts <= order {
+			continue
+		}
+		partitions := make([]frame.RicePartition, nparts)
+		// Number of residuals, minimum escaped sample size, and the number of
+		// additional bits required to escape each partition.
+		partSize := make([]int, nparts)
+		escBits := make([]int, nparts)
+		escCost := make([]int, nparts)
+		nbits := 0
+		start := 0
+		for i := range partitions {
+			partSize[i] = n / nparts
+			if i == 0 {
+				partSize[i] -= order
+			}
+			part := residuals[start : start+partSize[i]]
+			start += partSize[i]
+			k, riceBits := bestRiceParam(part)
+			escBits[i] = escapedBitsPerSample(part)
+			// 4 bits: Rice parameter; 5 bits: escaped sample size.
+			esc := 4 + 5 + escBits[i]*partSize[i]
+			riceBits += 4
+			if escBits[i] <= maxEscapedBitsSize && esc <= riceBits {
+				partitions[i] = frame.RicePartition{Param: riceEscape, EscapedBitsPerSample: uint(escBits[i])}
+				nbits += esc
+				continue
+			}
+			partitions[i] = frame.RicePartition{Param: k}
+			escCost[i] = esc - riceBits
+			nbits += riceBits
+		}
+		if nbits > max {
+			continue
+		}
+		// Escape partitions, increasing their sample size until the requested
+		// size is reached.
+		for i := range partitions {
+			if nbits >= min {
+				break
+			}
+			if escBits[i] > maxEscapedBitsSize || escCost[i] > max-nbits {
+				continue
+			}
+			extra := maxEscapedBitsSize - escBits[i]
+			if partSize[i] > 0 && (max-nbits-escCost[i])/partSize[i] < extra {
+				extra = (max - nbits - escCost[i]) / partSize[i]
+			}
+			partitions[i] = frame.RicePartition{Param: riceEscape, EscapedBitsPerSample: uint(escBits[i] + extra)}
+			nbits += escCost[i] + extra*partSize[i]
+		}
+		if nbits >= min {
+			return &frame.RiceSubframe{PartOrder: partOrder, Partitions: partitions}
+		}
+	}
+	return nil
+}
+
+// bestRiceParam returns the Rice parameter which minimizes the encoded size of
+// the given residuals, and the encoded size in bits.
+func bestRiceParam(residuals []int32) (k uint, nbits int) {
+	nbits = -1
+	for param := uint(0); param <= maxRiceParam; param++ {
+		n := 0
+		for _, residual := range residuals {
+			n += 1 + int(param) + int(iobits.EncodeZigZag(residual)>>param)
+		}
+		if nbits == -1 || n < nbits {
+			k, nbits = param, n
+		}
+	}
+	return k, nbits
+}
+
+// escapedBitsPerSample returns the minimum sample size in bits required to
+// store the given residuals in two's complement.
+func escapedBitsPerSample(residuals []int32) int {
+	n := 1
+	for _, residual := range residuals {
+		if residual < 0 {
+			residual = ^residual
+		}
+		if m := bits.Len32(uint32(residual)) + 1; m > n {
+			n = m
+		}
+	}
+	return n
+}
+
+// encodedSize returns the size in bits of the data written by the given encode
+// function.
+func encodedSize(encode func(bw *bitio.Writer) error) (int, error) {
+	cw := &countWriter{}
+	bw := bitio.NewWriter(cw)
+	if err := encode(bw); err != nil {
+		return 0, err
+	}
+	skipped, err := bw.Align()
+	if err != nil {
+		return 0, err
+	}
+	return 8*int(cw.n) - int(skipped), nil
+}
+
+// countWriter is an io.Writer which counts the number of bytes written, and
+// discards them.
+type countWriter struct {
+	// Number of bytes written.
+	n int64
+}
+
+// Write counts and discards the bytes of p.
+func (cw *countWriter) Write(p []byte) (n int, err error) {
+	cw.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,146 @@
+package flac
+
+import (
+	"bytes"
+	"io"
+	"slices"
+
+	"github.com/icza/bitio"
+	"github.com/mewkiz/flac/meta"
+	"github.com/mewkiz/pkg/errutil"
+)
+
+// maxBlockLength specifies the maximum length in bytes of a metadata block
+// body, as stored in 24 bits of the metadata block header.
+const maxBlockLength = 1<<24 - 1
+
+// seekPointSize specifies the size in bytes of an encoded seek point.
+const seekPointSize = 8 + 8 + 2
+
+// layoutBlocks returns the metadata blocks of an output stream, as laid out by
+// the given encoder options; i.e. with a placeholder seek table inserted if
+// opts.SeekTableInterval is specified, a padding block appended if
+// opts.Padding is specified, and sorted by opts.BlockOrder. The seek table is
+// nil if unspecified. The given metadata blocks are not modified.
+func layoutBlocks(info *meta.StreamInfo, blocks []*meta.Block, opts *EncodeOptions) ([]*meta.Block, *meta.SeekTable, error) {
+	if opts.Padding < 0 || opts.Padding > maxBlockLength {
+		return nil, nil, errutil.Newf("invalid padding size %d", opts.Padding)
+	}
+	if opts.SeekTableInterval < 0 {
+		return nil, nil, errutil.Newf("invalid seek table interval %v", opts.SeekTableInterval)
+	}
+	var table *meta.SeekTable
+	if opts.SeekTableInterval > 0 {
+		if info.NSamples == 0 {
+			return nil, nil, errutil.Newf("seek table requires the total number of samples of StreamInfo")
+		}
+		if meta.BlockList(blocks).SeekTable() != nil {
+			return nil, nil, errutil.Newf("seek table of metadata blocks conflicts with seek table interval")
+		}
+		interval := seekTableInterval(info, opts)
+		npoints := (info.NSamples + interval - 1) / interval
+		if npoints*seekPointSize > maxBlockLength {
+			return nil, nil, errutil.Newf("seek table of %d seek points exceeds maximum metadata block length", npoints)
+		}
+		table = &meta.SeekTable{Points: make([]meta.SeekPoint, npoints)}
+		for i := range table.Points {
+			table.Points[i].SampleNum = meta.PlaceholderPoint
+		}
+		block := &meta.Block{
+			Header: meta.Header{Type: meta.TypeSeekTable, Length: int64(npoints * seekPointSize)},
+			Body:   table,
+		}
+		blocks = append([]*meta.Block{block}, blocks...)
+	}
+	if opts.Padding > 0 {
+		block := &meta.Block{
+			Header: meta.Header{Type: meta.TypePadding, Length: int64(opts.Padding)},
+		}
+		blocks = append(slices.Clip(blocks), block)
+	}
+	if len(opts.BlockOrder) > 0 {
+		rank := func(block *meta.Block) int {
+			if i := slices.Index(opts.BlockOrder, block.Type); i != -1 {
+				return i
+			}
+			return len(opts.BlockOrder)
+		}
+		blocks = slices.Clone(blocks)
+		slices.SortStableFunc(blocks, func(a, b *meta.Block) int {
+			return rank(a) - rank(b)
+		})
+	}
+	return blocks, table, nil
+}
+
+// seekTableInterval returns the distance in samples between the target samples
+// of seek points, as specified by opts.SeekTableInterval.
+func seekTableInterval(info *meta.StreamInfo, opts *EncodeOptions) uint64 {
+	return max(uint64(opts.SeekTableInterval.Seconds()*float64(info.SampleRate)), 1)
+}
+
+// initSeekTable records the seek table of the encoder, and its byte offset in
+// the output stream. It is a no-op if table is nil.
+func (enc *Encoder) initSeekTable(table *meta.SeekTable) error {
+	if table == nil {
+		return nil
+	}
+	i := slices.IndexFunc(enc.Blocks, func(block *meta.Block) bool {
+		return block.Body == table
+	})
+	// Byte offset of the seek table, following the metadata blocks preceding
+	// it.
+	buf := &bytes.Buffer{}
+	if err := encodeHeader(buf, enc.Info, enc.Blocks[:i]); err != nil {
+		return err
+	}
+	enc.seekTable = table
+	enc.seekTableOffset = int64(buf.Len())
+	enc.seekTableLast = i == len(enc.Blocks)-1
+	enc.seekInterval = seekTableInterval(enc.Info, &enc.opts)
+	return nil
+}
+
+// addSeekPoint fills in the next seek point of the seek table of the encoder,
+// if its target sample is held by the frame last written to the output stream,
+// of the given block size. Target samples are spaced by the seek table
+// interval; a frame holding several target samples is referenced by a single
+// seek point, as the sample numbers of seek points must be unique. The
+// remaining seek points are left as placeholders. It is a no-op if the encoder
+// has no seek table.
+func (enc *Encoder) addSeekPoint(blockSize uint16) {
+	if enc.seekTable == nil {
+		return
+	}
+	points := enc.seekTable.Points
+	end := enc.nsamplesWritten + uint64(blockSize)
+	if enc.nseekTargets*enc.seekInterval >= end || enc.nseekPoints >= len(points) {
+		return
+	}
+	points[enc.nseekPoints] = meta.SeekPoint{
+		SampleNum: enc.nsamplesWritten,
+		Offset:    uint64(enc.lastFrameOffset - enc.dataStart),
+		NSamples:  blockSize,
+	}
+	enc.nseekPoints++
+	enc.nseekTargets = (end + enc.seekInterval - 1) / enc.seekInterval
+}
+
+// updateSeekTable writes the seek table of the encoder to the output stream.
+// It is a no-op if the encoder has no seek table.
+func (enc *Encoder) updateSeekTable(ws io.WriteSeeker) error {
+	if enc.seekTable == nil {
+		return nil
+	}
+	if _, err := ws.Seek(enc.seekTableOffset, io.SeekStart); err != nil {
+		return errutil.Err(err)
+	}
+	bw := bitio.NewWriter(ws)
+	if err := encodeSeekTable(bw, enc.seekTable, enc.seekTableLast); err != nil {
+		return errutil.Err(err)
+	}
+	if _, err := bw.Align(); err != nil {
+		return errutil.Err(err)
+	}
+	return nil
+}
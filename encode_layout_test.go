@@ -0,0 +1,147 @@
+package flac_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestEncodeLayout(t *testing.T) {
+	const (
+		blockSize = 1024
+		nframes   = 10
+	)
+	info := &meta.StreamInfo{
+		BlockSizeMin:  blockSize,
+		BlockSizeMax:  blockSize,
+		SampleRate:    1000,
+		NChannels:     2,
+		BitsPerSample: 16,
+		NSamples:      nframes * blockSize,
+	}
+	comment := &meta.Block{
+		Header: meta.Header{Type: meta.TypeVorbisComment, Length: 4 + 4 + 4 + 4 + int64(len("TITLE=layout"))},
+		Body:   &meta.VorbisComment{Vendor: "test", Tags: [][2]string{{"TITLE", "layout"}}},
+	}
+	golden := []struct {
+		interval time.Duration
+		// Sample numbers of the filled in seek points, followed by the given
+		// number of placeholder points.
+		want         []uint64
+		placeholders int
+	}{
+		// Seek points of distinct frames.
+		{interval: 1500 * time.Millisecond, want: []uint64{0, 1024, 2048, 4096, 5120, 7168, 8192}},
+		// Several target samples per frame.
+		{interval: 500 * time.Millisecond, want: []uint64{0, 1024, 2048, 3072, 4096, 5120, 6144, 7168, 8192, 9216}, placeholders: 11},
+	}
+	for _, g := range golden {
+		for _, workers := range []int{0, 4} {
+			path := filepath.Join(t.TempDir(), "layout.flac")
+			w, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := &flac.EncodeOptions{
+				Workers:           workers,
+				BlockOrder:        []meta.Type{meta.TypeVorbisComment, meta.TypeSeekTable},
+				Padding:           8192,
+				SeekTableInterval: g.interval,
+			}
+			enc, err := flac.NewEncoderWithOptions(w, info, opts, comment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for num := 0; num < nframes; num++ {
+				if err := enc.WriteFrame(makeTestFrame(info, num)); err != nil {
+					t.Fatalf("interval %v, workers %d: unable to write frame %d; %v", g.interval, workers, num, err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			stream, err := flac.ParseFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var types []meta.Type
+			for _, block := range stream.Blocks {
+				types = append(types, block.Type)
+			}
+			if want := []meta.Type{meta.TypeVorbisComment, meta.TypeSeekTable, meta.TypePadding}; !slices.Equal(types, want) {
+				t.Errorf("interval %v, workers %d: block order mismatch; expected %v, got %v", g.interval, workers, want, types)
+			}
+			if got := stream.Blocks[2].Length; got != 8192 {
+				t.Errorf("interval %v, workers %d: padding size mismatch; expected 8192, got %d", g.interval, workers, got)
+			}
+			var got []uint64
+			placeholders := 0
+			for _, point := range meta.BlockList(stream.Blocks).SeekTable().Points {
+				if point.SampleNum == meta.PlaceholderPoint {
+					placeholders++
+					continue
+				}
+				got = append(got, point.SampleNum)
+			}
+			if !slices.Equal(got, g.want) || placeholders != g.placeholders {
+				t.Errorf("interval %v, workers %d: seek points mismatch; expected %v and %d placeholders, got %v and %d placeholders", g.interval, workers, g.want, g.placeholders, got, placeholders)
+			}
+			stream.Close()
+
+			// The seek points reference the frames of their sample numbers.
+			r, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream, err = flac.NewSeek(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			broken, err := stream.CheckSeekTable()
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(broken) > 0 {
+				t.Errorf("interval %v, workers %d: broken seek points %v", g.interval, workers, broken)
+			}
+		}
+	}
+}
+
+func TestEncodeLayoutInvalid(t *testing.T) {
+	info := &meta.StreamInfo{
+		BlockSizeMin:  1024,
+		BlockSizeMax:  1024,
+		SampleRate:    44100,
+		NChannels:     2,
+		BitsPerSample: 16,
+	}
+	table := &meta.Block{
+		Header: meta.Header{Type: meta.TypeSeekTable, Length: 18},
+		Body:   &meta.SeekTable{Points: []meta.SeekPoint{{SampleNum: meta.PlaceholderPoint}}},
+	}
+	golden := []struct {
+		name   string
+		info   meta.StreamInfo
+		opts   flac.EncodeOptions
+		blocks []*meta.Block
+	}{
+		{name: "negative padding", info: *info, opts: flac.EncodeOptions{Padding: -1}},
+		{name: "padding too large", info: *info, opts: flac.EncodeOptions{Padding: 1 << 24}},
+		{name: "unknown number of samples", info: *info, opts: flac.EncodeOptions{SeekTableInterval: 10 * time.Second}},
+		{name: "duplicate seek table", info: meta.StreamInfo{BlockSizeMin: 1024, BlockSizeMax: 1024, SampleRate: 44100, NChannels: 2, BitsPerSample: 16, NSamples: 44100}, opts: flac.EncodeOptions{SeekTableInterval: 10 * time.Second}, blocks: []*meta.Block{table}},
+	}
+	for _, g := range golden {
+		if _, err := flac.NewEncoderWithOptions(new(bytes.Buffer), &g.info, &g.opts, g.blocks...); err == nil {
+			t.Errorf("%s: expected error", g.name)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package flac
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"slices"
 
 	"github.com/icza/bitio"
 	"github.com/mewkiz/flac/internal/ioutilx"
@@ -11,6 +13,52 @@ import (
 	"github.com/mewkiz/pkg/errutil"
 )
 
+// --- [ Stream header ] -------------------------------------------------------
+
+// MarshalMetadata returns the header of a FLAC stream; i.e. the FLAC signature
+// followed by the given StreamInfo block and metadata blocks. The audio frames
+// of a FLAC stream are independent of its header, as such the metadata of a
+// FLAC stream may be rewritten without re-encoding its audio frames, by
+// replacing the bytes preceding its first frame header (see
+// Stream.DataStart).
+//
+// Metadata blocks of reserved types, whose body is not parsed, are rejected.
+func MarshalMetadata(info *meta.StreamInfo, blocks ...*meta.Block) ([]byte, error) {
+	for _, block := range blocks {
+		if block.Type != meta.TypePadding && block.Length != 0 && block.Body == nil {
+			return nil, fmt.Errorf("flac.MarshalMetadata: unable to encode metadata block of type %v without body", block.Type)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := encodeHeader(buf, info, blocks); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeHeader encodes the FLAC signature followed by the given StreamInfo
+// block and metadata blocks, writing to w.
+func encodeHeader(w io.Writer, info *meta.StreamInfo, blocks []*meta.Block) error {
+	bw := bitio.NewWriter(w)
+	if _, err := bw.Write(flacSignature); err != nil {
+		return errutil.Err(err)
+	}
+	// Encode metadata blocks.
+	if err := encodeStreamInfo(bw, info, len(blocks) == 0); err != nil {
+		return errutil.Err(err)
+	}
+	for i, block := range blocks {
+		if err := encodeBlock(bw, block, i == len(blocks)-1); err != nil {
+			return errutil.Err(err)
+		}
+	}
+	// Flush pending writes of metadata blocks.
+	if _, err := bw.Align(); err != nil {
+		return errutil.Err(err)
+	}
+	return nil
+}
+
 // --- [ Metadata block ] ------------------------------------------------------
 
 // encodeBlock encodes the metadata block, writing to bw.
@@ -417,3 +465,53 @@ func encodePicture(bw *bitio.Writer, pic *meta.Picture, last bool) error {
 	}
 	return nil
 }
+
+// --- [ VorbisComment helpers ] -----------------------------------------------
+
+// withTags returns the given metadata blocks with the given tags added to the
+// VorbisComment block, which is added if not present. The metadata blocks are
+// not modified.
+func withTags(blocks []*meta.Block, tags [][2]string) []*meta.Block {
+	return withComment(blocks, func(comment *meta.VorbisComment) {
+		comment.Tags = append(slices.Clip(comment.Tags), tags...)
+	})
+}
+
+// withVendor returns the given metadata blocks with the vendor string of the
+// VorbisComment block replaced, which is added if not present. The metadata
+// blocks are not modified.
+func withVendor(blocks []*meta.Block, vendor string) []*meta.Block {
+	return withComment(blocks, func(comment *meta.VorbisComment) {
+		comment.Vendor = vendor
+	})
+}
+
+// withComment returns the given metadata blocks with a copy of the
+// VorbisComment block updated by the given function, which is added if not
+// present. The metadata blocks are not modified.
+func withComment(blocks []*meta.Block, update func(comment *meta.VorbisComment)) []*meta.Block {
+	comment := &meta.VorbisComment{}
+	i := slices.IndexFunc(blocks, func(block *meta.Block) bool {
+		_, ok := block.Body.(*meta.VorbisComment)
+		return ok
+	})
+	if i != -1 {
+		*comment = *blocks[i].Body.(*meta.VorbisComment)
+	}
+	update(comment)
+	// Length of the metadata block body.
+	length := 4 + len(comment.Vendor) + 4
+	for _, tag := range comment.Tags {
+		length += 4 + len(tag[0]) + 1 + len(tag[1])
+	}
+	block := &meta.Block{
+		Header: meta.Header{Type: meta.TypeVorbisComment, Length: int64(length)},
+		Body:   comment,
+	}
+	if i == -1 {
+		return append(slices.Clip(blocks), block)
+	}
+	blocks = slices.Clone(blocks)
+	blocks[i] = block
+	return blocks
+}
@@ -0,0 +1,81 @@
+package flac
+
+import (
+	"github.com/mewkiz/flac/frame"
+)
+
+// FrameStats holds the statistics of an audio frame written by an encoder, as
+// reported by EncodeOptions.OnFrame.
+type FrameStats struct {
+	// Frame header of the encoded frame.
+	Header frame.Header
+	// Byte offset of the frame in the output stream.
+	Offset int64
+	// Size in bytes of the encoded frame, including its header and CRC-16
+	// checksum.
+	Size int
+	// Statistics of each subframe.
+	Subframes []SubframeStats
+	// Total number of samples (per channel) of the frames written so far,
+	// including this frame.
+	TotalSamples uint64
+	// Total size in bytes of the frames written so far, including this frame;
+	// excluding the metadata blocks.
+	TotalSize int64
+	// Compression ratio of the frames written so far; the total size of the
+	// frames relative to the size of their unencoded audio samples, using the
+	// smallest number of whole bytes per sample, as reported by the reference
+	// encoder.
+	Ratio float64
+}
+
+// SubframeStats holds the statistics of an encoded subframe.
+type SubframeStats struct {
+	// Prediction method of the subframe.
+	Pred frame.Pred
+	// Prediction order of fixed and FIR subframes.
+	Order int
+	// Wasted bits-per-sample of the subframe.
+	Wasted uint
+	// Size in bits of the encoded subframe.
+	Bits int
+	// Size in bits of the subframe as estimated by prediction analysis, which
+	// assumes a single Rice partition; or 0 if the subframe was not analyzed
+	// (e.g. analysis was disabled, or the prediction method of the subframe was
+	// specified by the caller).
+	EstimatedBits int
+}
+
+// frameWritten records the frame last written to the output stream, of the
+// given block size, updates the minimum and maximum frame size of the stream,
+// verifies the frame if enabled, and reports its statistics to
+// EncodeOptions.OnFrame if stats is non-nil.
+func (enc *Encoder) frameWritten(blockSize uint16, stats *FrameStats) error {
+	enc.addSeekPoint(blockSize)
+	enc.nsamplesWritten += uint64(blockSize)
+	size := uint32(enc.ow.n - enc.lastFrameOffset)
+	if enc.frameSizeMin == 0 || size < enc.frameSizeMin {
+		enc.frameSizeMin = size
+	}
+	if size > enc.frameSizeMax {
+		enc.frameSizeMax = size
+	}
+	if enc.verifySum != nil {
+		if err := enc.verifyFrame(); err != nil {
+			return err
+		}
+	}
+	if stats == nil {
+		return nil
+	}
+	stats.Offset = enc.lastFrameOffset
+	stats.Size = int(size)
+	stats.TotalSamples = enc.nsamplesWritten
+	stats.TotalSize = enc.ow.n - enc.dataStart
+	bytesPerSample := (uint64(enc.Info.BitsPerSample) + 7) / 8
+	if pcmSize := stats.TotalSamples * uint64(enc.Info.NChannels) * bytesPerSample; pcmSize > 0 {
+		stats.Ratio = float64(stats.TotalSize) / float64(pcmSize)
+	}
+	enc.opts.OnFrame(stats)
+	return nil
+}
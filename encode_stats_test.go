@@ -0,0 +1,179 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestEncodeOnFrame(t *testing.T) {
+	const path = "testdata/love.flac"
+	if !exists(path) {
+		t.Skipf("path %q does not exist", path)
+	}
+	var want []flac.FrameStats
+	for _, workers := range []int{0, 4} {
+		stream, err := flac.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []flac.FrameStats
+		opts := &flac.EncodeOptions{
+			Workers:     workers,
+			MaxLPCOrder: 8,
+			OnFrame: func(stats *flac.FrameStats) {
+				got = append(got, *stats)
+			},
+		}
+		out := new(bytes.Buffer)
+		enc, err := flac.NewEncoderWithOptions(out, stream.Info, opts, stream.Blocks...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			f, err := stream.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+			for _, subframe := range f.Subframes {
+				subframe.SubHeader = frame.SubHeader{Pred: frame.PredVerbatim}
+			}
+			if err := enc.WriteFrame(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		stream.Close()
+
+		// Verify frame statistics against the encoded stream.
+		dec, err := flac.Parse(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset := dec.DataStart()
+		var nsamples uint64
+		for i, stats := range got {
+			f, err := dec.ParseNext()
+			if err != nil {
+				t.Fatalf("workers %d: unable to parse frame %d; %v", workers, i, err)
+			}
+			if stats.Header.Num != f.Num || stats.Header.BlockSize != f.BlockSize {
+				t.Errorf("workers %d: frame %d: header mismatch; expected num %d and block size %d, got %d and %d", workers, i, f.Num, f.BlockSize, stats.Header.Num, stats.Header.BlockSize)
+			}
+			if stats.Offset != offset {
+				t.Errorf("workers %d: frame %d: offset mismatch; expected %d, got %d", workers, i, offset, stats.Offset)
+			}
+			offset = dec.Offset()
+			if size := int(offset - stats.Offset); stats.Size != size {
+				t.Errorf("workers %d: frame %d: size mismatch; expected %d, got %d", workers, i, size, stats.Size)
+			}
+			subframeBits := 0
+			for j, sub := range stats.Subframes {
+				if sub.Pred != f.Subframes[j].Pred || sub.Order != f.Subframes[j].Order {
+					t.Errorf("workers %d: frame %d: subframe %d: prediction mismatch; expected %v of order %d, got %v of order %d", workers, i, j, f.Subframes[j].Pred, f.Subframes[j].Order, sub.Pred, sub.Order)
+				}
+				if sub.EstimatedBits == 0 {
+					t.Errorf("workers %d: frame %d: subframe %d: missing estimated size", workers, i, j)
+				}
+				subframeBits += sub.Bits
+			}
+			// The frame holds a header of at least 6 bytes, zero-padding of at
+			// most 7 bits, and a 2 byte CRC-16 checksum.
+			if extra := 8*stats.Size - subframeBits; extra < 8*(6+2) || extra > 8*(16+2)+7 {
+				t.Errorf("workers %d: frame %d: subframe sizes (%d bits) inconsistent with frame size (%d bytes)", workers, i, subframeBits, stats.Size)
+			}
+			nsamples += uint64(stats.Header.BlockSize)
+			if stats.TotalSamples != nsamples {
+				t.Errorf("workers %d: frame %d: total number of samples mismatch; expected %d, got %d", workers, i, nsamples, stats.TotalSamples)
+			}
+		}
+		if _, err := dec.ParseNext(); err != io.EOF {
+			t.Errorf("workers %d: frame count mismatch; got %d reported frames", workers, len(got))
+		}
+		last := got[len(got)-1]
+		if size := int64(out.Len()) - dec.DataStart(); last.TotalSize != size {
+			t.Errorf("workers %d: total size mismatch; expected %d, got %d", workers, size, last.TotalSize)
+		}
+		pcmSize := float64(dec.Info.NSamples * uint64(dec.Info.NChannels) * uint64(dec.Info.BitsPerSample/8))
+		if ratio := float64(last.TotalSize) / pcmSize; last.Ratio != ratio {
+			t.Errorf("workers %d: compression ratio mismatch; expected %v, got %v", workers, ratio, last.Ratio)
+		}
+		if want == nil {
+			want = got
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("workers %d: frame statistics differ from those of single-threaded encoder", workers)
+		}
+	}
+}
+
+func TestEncodeFrameSize(t *testing.T) {
+	const path = "testdata/love.flac"
+	if !exists(path) {
+		t.Skipf("path %q does not exist", path)
+	}
+	for _, workers := range []int{0, 4} {
+		stream, err := flac.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var min, max int
+		opts := &flac.EncodeOptions{
+			Workers: workers,
+			OnFrame: func(stats *flac.FrameStats) {
+				if min == 0 || stats.Size < min {
+					min = stats.Size
+				}
+				if stats.Size > max {
+					max = stats.Size
+				}
+			},
+		}
+		// The StreamInfo block is updated on Close if the output stream is
+		// seekable.
+		out := filepath.Join(t.TempDir(), "out.flac")
+		fw, err := os.Create(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := flac.NewEncoderWithOptions(fw, stream.Info, opts, stream.Blocks...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			f, err := stream.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+			if err := enc.WriteFrame(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		stream.Close()
+
+		dec, err := flac.ParseFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(dec.Info.FrameSizeMin) != min || int(dec.Info.FrameSizeMax) != max {
+			t.Errorf("workers %d: frame size mismatch; expected [%d, %d], got [%d, %d]", workers, min, max, dec.Info.FrameSizeMin, dec.Info.FrameSizeMax)
+		}
+		dec.Close()
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 
 	"github.com/icza/bitio"
+	iobits "github.com/mewkiz/flac/bits"
 	"github.com/mewkiz/flac/frame"
-	iobits "github.com/mewkiz/flac/internal/bits"
 	"github.com/mewkiz/pkg/errutil"
 )
 
@@ -266,12 +266,6 @@ func encodeRicePart(bw *bitio.Writer, subframe *frame.Subframe, paramSize uint,
 	curResidualIndex := 0
 	for i := range riceSubframe.Partitions {
 		partition := &riceSubframe.Partitions[i]
-		// (4 or 5) bits: Rice parameter.
-		param := partition.Param
-		if err := bw.WriteBits(uint64(param), uint8(paramSize)); err != nil {
-			return errutil.Err(err)
-		}
-
 		// Determine the number of Rice encoded samples in the partition.
 		var nsamples int
 		if partOrder == 0 {
@@ -282,66 +276,28 @@ func encodeRicePart(bw *bitio.Writer, subframe *frame.Subframe, paramSize uint,
 			nsamples = subframe.NSamples/nparts - subframe.Order
 		}
 
-		if paramSize == 4 && param == 0xF || paramSize == 5 && param == 0x1F {
-			// 1111 or 11111: Escape code, meaning the partition is in unencoded
-			// binary form using n bits per sample; n follows as a 5-bit number.
-			if err := bw.WriteBits(uint64(partition.EscapedBitsPerSample), 5); err != nil {
-				return errutil.Err(err)
-			}
-			for j := 0; j < nsamples; j++ {
-				// ref: https://datatracker.ietf.org/doc/draft-ietf-cellar-flac/
-				//
-				// From section 9.2.7.1.  Escaped partition:
-				//
-				// The residual samples themselves are stored signed two's
-				// complement.  For example, when a partition is escaped and each
-				// residual sample is stored with 3 bits, the number -1 is
-				// represented as 0b111.
-				residual := residuals[curResidualIndex]
-				curResidualIndex++
-				if err := bw.WriteBits(uint64(residual), uint8(partition.EscapedBitsPerSample)); err != nil {
-					return errutil.Err(err)
-				}
-			}
-			continue
-		}
-
-		// Encode the Rice residuals of the partition.
-		for j := 0; j < nsamples; j++ {
-			residual := residuals[curResidualIndex]
-			curResidualIndex++
-			if err := encodeRiceResidual(bw, param, residual); err != nil {
-				return errutil.Err(err)
-			}
+		// (4 or 5) bits: Rice parameter, followed by the Rice encoded residuals
+		// of the partition; or by the escaped residuals if the Rice parameter is
+		// the escape code (1111 or 11111).
+		//
+		// ref: https://datatracker.ietf.org/doc/draft-ietf-cellar-flac/
+		//
+		// From section 9.2.7.1.  Escaped partition:
+		//
+		// The residual samples themselves are stored signed two's
+		// complement.  For example, when a partition is escaped and each
+		// residual sample is stored with 3 bits, the number -1 is
+		// represented as 0b111.
+		part := residuals[curResidualIndex : curResidualIndex+nsamples]
+		curResidualIndex += nsamples
+		if err := iobits.WriteRiceBlock(bw, paramSize, partition.Param, partition.EscapedBitsPerSample, part); err != nil {
+			return errutil.Err(err)
 		}
 	}
 
 	return nil
 }
 
-// encodeRiceResidual encodes a Rice residual (error signal).
-func encodeRiceResidual(bw *bitio.Writer, k uint, residual int32) error {
-	// ZigZag encode.
-	folded := iobits.EncodeZigZag(residual)
-
-	// unfold into low- and high.
-	lowMask := ^uint32(0) >> (32 - k) // lower k bits.
-	highMask := ^uint32(0) << k       // upper bits.
-	high := (folded & highMask) >> k
-	low := folded & lowMask
-
-	// Write unary encoded most significant bits.
-	if err := iobits.WriteUnary(bw, uint64(high)); err != nil {
-		return errutil.Err(err)
-	}
-
-	// Write binary encoded least significant bits.
-	if err := bw.WriteBits(uint64(low), uint8(k)); err != nil {
-		return errutil.Err(err)
-	}
-	return nil
-}
-
 // getLPCResiduals returns the residuals (signal errors of the prediction)
 // between the given audio samples and the LPC predicted audio samples, using
 // the coefficients of a given polynomial, and a couple (order of polynomial;
@@ -0,0 +1,356 @@
+package flac
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// An EncoderAnalysis summarizes the characteristics of a FLAC stream which
+// reveal the encoder (and encoder settings) used to produce it, together with
+// a guess of the producing encoder. It is intended for quality control of
+// archives, e.g. to detect streams re-encoded by a different encoder than
+// claimed.
+type EncoderAnalysis struct {
+	// Vendor string of the VorbisComment metadata block; empty if not present.
+	Vendor string
+	// Number of audio frames.
+	Frames int
+	// Number of frames per block size, excluding the last frame.
+	BlockSizes map[uint16]int
+	// Specifies whether the frames use a variable block size.
+	VariableBlockSize bool
+	// Number of frames per channel assignment, indexed by frame.Channels.
+	ChannelAssignments [frame.ChannelsMidSide + 1]int
+	// Number of subframes per prediction method, indexed by frame.Pred.
+	Preds [frame.PredFIR + 1]int
+	// Number of FIR subframes per prediction order.
+	LPCOrders map[int]int
+	// Maximum prediction order of fixed subframes.
+	MaxFixedOrder int
+	// Maximum prediction order of FIR subframes.
+	MaxLPCOrder int
+	// Maximum coefficient precision in bits of FIR subframes.
+	MaxCoeffPrec uint
+	// Maximum Rice partition order.
+	MaxPartitionOrder int
+	// Number of Rice partitions, and the sum of their Rice parameters; excluding
+	// escaped partitions.
+	RicePartitions int
+	RiceParamSum   uint64
+	// Number of escaped Rice partitions.
+	EscapedPartitions int
+	// Number of subframes using Rice coding with 5-bit Rice parameters.
+	Rice2Subframes int
+	// Number of subframes with wasted bits-per-sample.
+	WastedSubframes int
+
+	// Guess of the producing encoder.
+	Guess EncoderGuess
+}
+
+// MeanRiceParam returns the mean Rice parameter of the Rice partitions which
+// are not escaped; or 0 if none.
+func (a *EncoderAnalysis) MeanRiceParam() float64 {
+	if a.RicePartitions == 0 {
+		return 0
+	}
+	return float64(a.RiceParamSum) / float64(a.RicePartitions)
+}
+
+// BlockSize returns the most common block size of the stream, excluding the
+// last frame; or 0 if unknown.
+func (a *EncoderAnalysis) BlockSize() uint16 {
+	var blockSize uint16
+	n := 0
+	for size, count := range a.BlockSizes {
+		if count > n || count == n && size > blockSize {
+			blockSize, n = size, count
+		}
+	}
+	return blockSize
+}
+
+// An EncoderGuess is a guess of the encoder which produced a FLAC stream.
+type EncoderGuess struct {
+	// Name of the encoder (e.g. "libFLAC", "FFmpeg", "farcloser/flac"); empty if
+	// unknown.
+	Name string
+	// Version of the encoder; empty if unknown.
+	Version string
+	// Candidate compression levels of the encoder, consistent with the stream
+	// characteristics; nil if unknown.
+	Levels []int
+	// Evidence supporting the guess, in human-readable form.
+	Reasons []string
+}
+
+// String returns a human-readable representation of the guess.
+func (g EncoderGuess) String() string {
+	if g.Name == "" {
+		return "unknown encoder"
+	}
+	s := g.Name
+	if g.Version != "" {
+		s += " " + g.Version
+	}
+	if len(g.Levels) > 0 {
+		levels := make([]string, len(g.Levels))
+		for i, level := range g.Levels {
+			levels[i] = fmt.Sprintf("-%d", level)
+		}
+		s += " (" + strings.Join(levels, " or ") + ")"
+	}
+	return s
+}
+
+// AnalyzeEncoder parses the FLAC stream of r in its entirety, and returns the
+// encoder characteristics of the stream, together with a guess of the
+// producing encoder.
+//
+// The guess is heuristic; the vendor string is easily rewritten by tag editors,
+// and the compression level is inferred from the block size, the stereo
+// decorrelation, and the maximum prediction and partition orders of the
+// stream. Apodization functions are not observable in the stream, as such the
+// higher compression levels of libFLAC may not be distinguished.
+func AnalyzeEncoder(r io.Reader) (*EncoderAnalysis, error) {
+	stream, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	a := &EncoderAnalysis{
+		BlockSizes: make(map[uint16]int),
+		LPCOrders:  make(map[int]int),
+	}
+	for _, block := range stream.Blocks {
+		if comment, ok := block.Body.(*meta.VorbisComment); ok {
+			a.Vendor = comment.Vendor
+		}
+	}
+	// Block size of the preceding frame; the last frame is excluded from the
+	// block size statistics, as it may hold fewer samples.
+	var prevBlockSize uint16
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if a.Frames > 0 {
+			a.BlockSizes[prevBlockSize]++
+		}
+		prevBlockSize = f.BlockSize
+		a.Frames++
+		if !f.HasFixedBlockSize {
+			a.VariableBlockSize = true
+		}
+		if int(f.Channels) < len(a.ChannelAssignments) {
+			a.ChannelAssignments[f.Channels]++
+		}
+		for _, subframe := range f.Subframes {
+			a.addSubframe(subframe)
+		}
+	}
+	if a.Frames == 1 {
+		a.BlockSizes[prevBlockSize]++
+	}
+	a.Guess = guessEncoder(a, stream.Info)
+	return a, nil
+}
+
+// addSubframe adds the characteristics of the given subframe to the analysis.
+func (a *EncoderAnalysis) addSubframe(subframe *frame.Subframe) {
+	if int(subframe.Pred) < len(a.Preds) {
+		a.Preds[subframe.Pred]++
+	}
+	if subframe.Wasted > 0 {
+		a.WastedSubframes++
+	}
+	switch subframe.Pred {
+	case frame.PredFixed:
+		if subframe.Order > a.MaxFixedOrder {
+			a.MaxFixedOrder = subframe.Order
+		}
+	case frame.PredFIR:
+		a.LPCOrders[subframe.Order]++
+		if subframe.Order > a.MaxLPCOrder {
+			a.MaxLPCOrder = subframe.Order
+		}
+		if subframe.CoeffPrec > a.MaxCoeffPrec {
+			a.MaxCoeffPrec = subframe.CoeffPrec
+		}
+	default:
+		return
+	}
+	if subframe.ResidualCodingMethod == frame.ResidualCodingMethodRice2 {
+		a.Rice2Subframes++
+	}
+	rice := subframe.RiceSubframe
+	if rice == nil {
+		return
+	}
+	if rice.PartOrder > a.MaxPartitionOrder {
+		a.MaxPartitionOrder = rice.PartOrder
+	}
+	escape := uint(0xF)
+	if subframe.ResidualCodingMethod == frame.ResidualCodingMethodRice2 {
+		escape = 0x1F
+	}
+	for _, partition := range rice.Partitions {
+		if partition.Param == escape {
+			a.EscapedPartitions++
+			continue
+		}
+		a.RicePartitions++
+		a.RiceParamSum += uint64(partition.Param)
+	}
+}
+
+// libFLACVendor matches the vendor string of libFLAC; e.g. "reference libFLAC
+// 1.3.2 20170101".
+var libFLACVendor = regexp.MustCompile(`^reference libFLAC ([0-9][0-9a-z.\-]*)`)
+
+// ffmpegVendor matches the vendor string of FFmpeg; e.g. "Lavf58.29.100".
+var ffmpegVendor = regexp.MustCompile(`^Lav[fc]([0-9.]+)`)
+
+// libFLACLevel specifies the settings of a libFLAC compression level which are
+// observable in the stream.
+type libFLACLevel struct {
+	// Block size in samples.
+	blockSize uint16
+	// Maximum FIR prediction order; 0 if only fixed prediction is used.
+	maxLPCOrder int
+	// Maximum Rice partition order.
+	maxPartitionOrder int
+	// Specifies whether stereo decorrelation is used.
+	midSide bool
+}
+
+// libFLACLevels specifies the observable settings of libFLAC compression levels
+// 0 through 8.
+//
+// ref: https://xiph.org/flac/documentation_tools_flac.html
+var libFLACLevels = [...]libFLACLevel{
+	0: {blockSize: 1152, maxLPCOrder: 0, maxPartitionOrder: 3},
+	1: {blockSize: 1152, maxLPCOrder: 0, maxPartitionOrder: 3, midSide: true},
+	2: {blockSize: 1152, maxLPCOrder: 0, maxPartitionOrder: 3, midSide: true},
+	3: {blockSize: 4096, maxLPCOrder: 6, maxPartitionOrder: 4},
+	4: {blockSize: 4096, maxLPCOrder: 8, maxPartitionOrder: 4, midSide: true},
+	5: {blockSize: 4096, maxLPCOrder: 8, maxPartitionOrder: 5, midSide: true},
+	6: {blockSize: 4096, maxLPCOrder: 8, maxPartitionOrder: 6, midSide: true},
+	7: {blockSize: 4096, maxLPCOrder: 12, maxPartitionOrder: 6, midSide: true},
+	8: {blockSize: 4096, maxLPCOrder: 12, maxPartitionOrder: 6, midSide: true},
+}
+
+// guessEncoder returns a guess of the encoder which produced a stream with the
+// given characteristics.
+func guessEncoder(a *EncoderAnalysis, info *meta.StreamInfo) EncoderGuess {
+	var g EncoderGuess
+	switch {
+	case libFLACVendor.MatchString(a.Vendor):
+		g.Name = "libFLAC"
+		g.Version = libFLACVendor.FindStringSubmatch(a.Vendor)[1]
+		g.Reasons = append(g.Reasons, fmt.Sprintf("vendor string %q", a.Vendor))
+	case ownVendor.MatchString(a.Vendor):
+		g.Name = vendorName
+		g.Version = ownVendor.FindStringSubmatch(a.Vendor)[1]
+		g.Reasons = append(g.Reasons, fmt.Sprintf("vendor string %q", a.Vendor))
+	case ffmpegVendor.MatchString(a.Vendor):
+		g.Name = "FFmpeg"
+		g.Version = ffmpegVendor.FindStringSubmatch(a.Vendor)[1]
+		g.Reasons = append(g.Reasons, fmt.Sprintf("vendor string %q", a.Vendor))
+	case a.Vendor != "":
+		g.Name = a.Vendor
+		g.Reasons = append(g.Reasons, fmt.Sprintf("vendor string %q", a.Vendor))
+	}
+	blockSize := a.BlockSize()
+	// The encoder of this package stores audio samples verbatim, or using fixed
+	// prediction with a single Rice partition if analysis is enabled, and writes
+	// its own vendor string to the VorbisComment blocks it creates (see
+	// EncodeOptions.Vendor).
+	verbatimOnly := a.Preds[frame.PredVerbatim] > 0 && a.Preds[frame.PredFixed] == 0 && a.Preds[frame.PredFIR] == 0
+	fixedOnly := a.Preds[frame.PredFixed] > 0 && a.Preds[frame.PredFIR] == 0 && a.MaxPartitionOrder == 0 && a.Rice2Subframes == 0
+	switch {
+	case verbatimOnly || fixedOnly && g.Name == "":
+		if g.Name != vendorName {
+			if g.Name != "" {
+				g.Reasons = append(g.Reasons, "no prediction, inconsistent with vendor string; possibly re-encoded")
+			}
+			g.Name, g.Version = vendorName, ""
+		}
+		g.Reasons = append(g.Reasons, "verbatim or single-partition fixed subframes only")
+		return g
+	case blockSize == 4608 && g.Name != "libFLAC":
+		// FFmpeg selects 4608-sample blocks at 44.1 and 48 kHz.
+		if g.Name == "" {
+			g.Name = "FFmpeg"
+		}
+		g.Reasons = append(g.Reasons, "block size of 4608 samples")
+		return g
+	case a.VariableBlockSize:
+		g.Reasons = append(g.Reasons, "variable block size; not produced by libFLAC")
+		if g.Name == "libFLAC" {
+			g.Name, g.Version = "", ""
+		}
+		return g
+	}
+	if g.Name != "" && g.Name != "libFLAC" {
+		return g
+	}
+
+	// Match the compression levels of libFLAC.
+	midSide := a.ChannelAssignments[frame.ChannelsLeftSide]+a.ChannelAssignments[frame.ChannelsSideRight]+a.ChannelAssignments[frame.ChannelsMidSide] > 0
+	stereo := info.NChannels == 2
+	for level, l := range libFLACLevels {
+		if blockSize != l.blockSize {
+			continue
+		}
+		if a.MaxLPCOrder > l.maxLPCOrder || a.MaxPartitionOrder > l.maxPartitionOrder {
+			continue
+		}
+		if stereo && midSide && !l.midSide {
+			continue
+		}
+		g.Levels = append(g.Levels, level)
+	}
+	// Prefer the levels whose maximum orders are reached, as libFLAC uses the
+	// maximum orders for most streams.
+	g.Levels = preferLevels(g.Levels, func(l libFLACLevel) bool {
+		return l.maxPartitionOrder == a.MaxPartitionOrder
+	})
+	g.Levels = preferLevels(g.Levels, func(l libFLACLevel) bool {
+		return l.maxLPCOrder == a.MaxLPCOrder
+	})
+	if len(g.Levels) == 0 {
+		if g.Name == "libFLAC" {
+			g.Reasons = append(g.Reasons, fmt.Sprintf("block size (%d) and prediction settings match no standard compression level; custom settings or re-encoded", blockSize))
+		}
+		return g
+	}
+	if g.Name == "" {
+		g.Name = "libFLAC"
+	}
+	g.Reasons = append(g.Reasons, fmt.Sprintf("block size %d, maximum LPC order %d, maximum partition order %d", blockSize, a.MaxLPCOrder, a.MaxPartitionOrder))
+	return g
+}
+
+// preferLevels returns the given libFLAC compression levels which satisfy the
+// given condition; or all levels if none does.
+func preferLevels(levels []int, cond func(l libFLACLevel) bool) []int {
+	var preferred []int
+	for _, level := range levels {
+		if cond(libFLACLevels[level]) {
+			preferred = append(preferred, level)
+		}
+	}
+	if len(preferred) == 0 {
+		return levels
+	}
+	return preferred
+}
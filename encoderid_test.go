@@ -0,0 +1,96 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestAnalyzeEncoder(t *testing.T) {
+	golden := []struct {
+		path    string
+		name    string
+		version string
+		levels  []int
+	}{
+		{path: "testdata/love.flac", name: "libFLAC", version: "1.3.1", levels: []int{5}},
+		{path: "testdata/172960.flac", name: "libFLAC", version: "1.2.1", levels: []int{7, 8}},
+		{path: "testdata/220014.flac", name: "libFLAC", version: "1.0.25", levels: []int{0, 1, 2}},
+	}
+	for _, g := range golden {
+		f, err := os.Open(g.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := flac.AnalyzeEncoder(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%q: unable to analyze stream; %v", g.path, err)
+			continue
+		}
+		if a.Guess.Name != g.name || a.Guess.Version != g.version || !reflect.DeepEqual(a.Guess.Levels, g.levels) {
+			t.Errorf("%q: guess mismatch; expected %s %s %v, got %v", g.path, g.name, g.version, g.levels, a.Guess)
+		}
+	}
+}
+
+func TestAnalyzeEncoderReencoded(t *testing.T) {
+	// Re-encode a libFLAC stream using verbatim subframes, retaining its vendor
+	// string.
+	const path = "testdata/love.flac"
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	out := new(bytes.Buffer)
+	enc, err := flac.NewEncoder(out, stream.Info, stream.Blocks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.AnalysisEnabled = false
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		for _, subframe := range f.Subframes {
+			subframe.Pred = frame.PredVerbatim
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := flac.AnalyzeEncoder(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Vendor != "reference libFLAC 1.3.1 20141125" {
+		t.Errorf("%q: vendor mismatch; got %q", path, a.Vendor)
+	}
+	if a.Guess.Name != "farcloser/flac" {
+		t.Errorf("%q: guess mismatch of re-encoded stream; expected farcloser/flac, got %v", path, a.Guess)
+	}
+	found := false
+	for _, reason := range a.Guess.Reasons {
+		if strings.Contains(reason, "possibly re-encoded") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("%q: re-encoding not reported; reasons %q", path, a.Guess.Reasons)
+	}
+}
@@ -0,0 +1,113 @@
+package flac
+
+import (
+	"sync/atomic"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// defaultEventBuffer specifies the capacity of the event channel of
+// Stream.Events, if unspecified by DecodeOptions.EventBuffer.
+const defaultEventBuffer = 256
+
+// Events returns a channel delivering the events of the decoder, as received
+// by DecodeOptions.Logger; i.e. parsed metadata blocks (EventBlock), decoded
+// frames (EventFrame), warnings (EventWarning), resynchronizations by
+// Stream.Resync (EventResync), changes of the stream parameters between frames
+// (EventParametersChanged), and the end of the stream (EventEndOfStream). As
+// such, supervision code of long-running decode sessions may observe the
+// health of the decoder from another goroutine, without polling.
+//
+// The channel is created by the first call to Events, and receives the events
+// from then on; to receive the events of the metadata blocks, set
+// DecodeOptions.EventBuffer to create the channel before the metadata blocks
+// are parsed. Events must not be called concurrently with the decoding of the
+// stream.
+//
+// Events are delivered without blocking the decoder. Events are dropped if the
+// channel is full, as reported by Stream.DroppedEvents. The frames of events
+// hold copies of the frame headers only, without audio samples. The channel is
+// closed by Stream.Close.
+func (stream *Stream) Events() <-chan Event {
+	if stream.events == nil {
+		stream.events = newEventQueue(defaultEventBuffer)
+	}
+	return stream.events.c
+}
+
+// DroppedEvents returns the number of events dropped as the channel of
+// Stream.Events was full. It is safe to call DroppedEvents concurrently with
+// the decoding of the stream, once the channel has been created.
+func (stream *Stream) DroppedEvents() uint64 {
+	if stream.events == nil {
+		return 0
+	}
+	return stream.events.dropped.Load()
+}
+
+// eventQueue delivers the events of the decoder to the channel of
+// Stream.Events.
+type eventQueue struct {
+	// Event channel.
+	c chan Event
+	// Number of events dropped as the channel was full.
+	dropped atomic.Uint64
+	// Set once the channel has been closed.
+	closed bool
+}
+
+// newEventQueue returns a new event queue, delivering events to a channel of
+// the given capacity.
+func newEventQueue(size int) *eventQueue {
+	return &eventQueue{c: make(chan Event, size)}
+}
+
+// send delivers the given event without blocking; the event is dropped if the
+// channel is full.
+func (q *eventQueue) send(event Event) {
+	if q.closed {
+		return
+	}
+	// The frame of the event may be reused by the decoder once the event is
+	// delivered; e.g. in low-memory mode.
+	if event.Frame != nil {
+		event.Frame = &frame.Frame{Header: event.Frame.Header}
+	}
+	select {
+	case q.c <- event:
+	default:
+		q.dropped.Add(1)
+	}
+}
+
+// close closes the event channel.
+func (q *eventQueue) close() {
+	if !q.closed {
+		q.closed = true
+		close(q.c)
+	}
+}
+
+// checkParams logs a parameters changed event if the sample rate, sample size
+// or number of channels of the given frame differ from the preceding frame.
+// The sample size of the frame must be resolved already (see Stream.advance).
+func (stream *Stream) checkParams(offset int64, f *frame.Frame) {
+	prev := stream.prevHeader
+	stream.prevHeader = f.Header
+	if stream.blocking == BlockingUnknown {
+		// First frame of the stream.
+		return
+	}
+	if stream.sampleRate(prev) != stream.sampleRate(f.Header) || prev.BitsPerSample != f.BitsPerSample || prev.Channels.Count() != f.Channels.Count() {
+		stream.log(Event{Kind: EventParametersChanged, Offset: offset, Frame: f, Prev: prev})
+	}
+}
+
+// sampleRate returns the sample rate of the given frame header; as specified by
+// StreamInfo if unspecified by the frame header.
+func (stream *Stream) sampleRate(hdr frame.Header) uint32 {
+	if hdr.SampleRate == 0 {
+		return stream.Info.SampleRate
+	}
+	return hdr.SampleRate
+}
@@ -0,0 +1,150 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestEvents(t *testing.T) {
+	data, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.NewWithOptions(bytes.NewReader(data), &flac.DecodeOptions{EventBuffer: 1 << 16, LowMemory: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := stream.Events()
+	nframes := 0
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		nframes++
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The channel is closed by Close.
+	var kinds []flac.EventKind
+	var frames []flac.Event
+	for event := range events {
+		kinds = append(kinds, event.Kind)
+		if event.Kind == flac.EventFrame {
+			frames = append(frames, event)
+		}
+	}
+	if len(kinds) == 0 || kinds[0] != flac.EventBlock {
+		t.Fatalf("first event mismatch; expected %v, got %v", flac.EventBlock, kinds)
+	}
+	if got := kinds[len(kinds)-1]; got != flac.EventEndOfStream {
+		t.Errorf("last event mismatch; expected %v, got %v", flac.EventEndOfStream, got)
+	}
+	if len(frames) != nframes {
+		t.Fatalf("frame event count mismatch; expected %d, got %d", nframes, len(frames))
+	}
+	// Frames of events hold copies of the frame headers, as the frame is
+	// reused in low-memory mode.
+	for i, event := range frames {
+		if want := uint64(i); event.Frame.Num != want {
+			t.Fatalf("frame number of event %d mismatch; expected %d, got %d", i, want, event.Frame.Num)
+		}
+	}
+	if n := stream.DroppedEvents(); n != 0 {
+		t.Errorf("number of dropped events mismatch; expected 0, got %d", n)
+	}
+
+	// The channel created by Events receives no events of the metadata blocks.
+	stream, err = flac.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events = stream.Events()
+	if _, err := stream.ParseNext(); err != nil {
+		t.Fatal(err)
+	}
+	if event := <-events; event.Kind != flac.EventFrame {
+		t.Errorf("first event mismatch; expected %v, got %v", flac.EventFrame, event.Kind)
+	}
+
+	// Events are dropped once the channel is full.
+	stream, err = flac.NewWithOptions(bytes.NewReader(data), &flac.DecodeOptions{EventBuffer: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.ParseNext(); err != nil {
+		t.Fatal(err)
+	}
+	if n := stream.DroppedEvents(); n == 0 {
+		t.Error("expected dropped events")
+	}
+}
+
+func TestEventsParametersChanged(t *testing.T) {
+	info := &meta.StreamInfo{
+		BlockSizeMin:  16,
+		BlockSizeMax:  16,
+		SampleRate:    44100,
+		NChannels:     1,
+		BitsPerSample: 16,
+	}
+	out := new(bytes.Buffer)
+	enc, err := flac.NewEncoder(out, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Frames of 44.1 kHz, 48 kHz and 48 kHz.
+	for _, sampleRate := range []uint32{44100, 48000, 48000} {
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         16,
+				SampleRate:        sampleRate,
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     16,
+			},
+			Subframes: []*frame.Subframe{{SubHeader: frame.SubHeader{Pred: frame.PredVerbatim}, Samples: make([]int32, 16), NSamples: 16}},
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.New(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := stream.Events()
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+	}
+	stream.Close()
+	var changes []flac.Event
+	for event := range events {
+		if event.Kind == flac.EventParametersChanged {
+			changes = append(changes, event)
+		}
+	}
+	if len(changes) != 1 {
+		t.Fatalf("parameters changed event count mismatch; expected 1, got %d", len(changes))
+	}
+	if got := changes[0]; got.Prev.SampleRate != 44100 || got.Frame.SampleRate != 48000 || got.Frame.Num != 1 {
+		t.Errorf("parameters changed event mismatch; got %v", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Package fingerprint decodes windows of FLAC audio in the layout expected by
+// audio fingerprinting libraries, such as Chromaprint for AcoustID; i.e. mono
+// signed 16-bit PCM at a requested sample rate.
+//
+// A typical AcoustID integration decodes the first 120 seconds at 11025 Hz:
+//
+//	pcm, err := fingerprint.PCMFile("song.flac", 120*time.Second, 11025)
+package fingerprint
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/dsp/resample"
+)
+
+// PCM decodes the first duration of the given FLAC stream as mono signed 16-bit
+// PCM at the given sample rate. Channels are downmixed by averaging, samples are
+// scaled to 16 bits-per-sample, and the audio is resampled using linear
+// interpolation (see package dsp/resample) if sampleRate differs from the
+// sample rate of the stream.
+//
+// The returned slice holds exactly duration*sampleRate samples, or fewer if the
+// stream is shorter than duration.
+func PCM(stream *flac.Stream, duration time.Duration, sampleRate int) ([]int16, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("fingerprint.PCM: invalid sample rate %d", sampleRate)
+	}
+	if duration < 0 {
+		return nil, fmt.Errorf("fingerprint.PCM: invalid duration %v", duration)
+	}
+	srcRate := int(stream.Info.SampleRate)
+	if srcRate <= 0 {
+		return nil, errors.New("fingerprint.PCM: sample rate of stream not specified")
+	}
+	bps := int(stream.Info.BitsPerSample)
+
+	// Decode and downmix the samples required to interpolate the window; one
+	// extra sample for interpolation at the end of the window.
+	nout := int(duration.Seconds() * float64(sampleRate))
+	nsrc := int(math.Ceil(float64(nout)*float64(srcRate)/float64(sampleRate))) + 1
+	mono := make([]float64, 0, nsrc)
+	// Scale factor from the sample size of the stream to 16 bits-per-sample,
+	// including the division of the channel average.
+	scale := math.Pow(2, float64(16-bps))
+	for len(mono) < nsrc {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(f.Subframes) == 0 {
+			continue
+		}
+		mul := scale / float64(len(f.Subframes))
+		for i := 0; i < f.Subframes[0].NSamples && len(mono) < nsrc; i++ {
+			var sum int64
+			for _, subframe := range f.Subframes {
+				sum += int64(subframe.Samples[i])
+			}
+			mono = append(mono, float64(sum)*mul)
+		}
+	}
+
+	// Resample to the requested sample rate.
+	if srcRate == sampleRate {
+		if len(mono) > nout {
+			mono = mono[:nout]
+		}
+		return toInt16(mono), nil
+	}
+	r, err := resample.New(srcRate, sampleRate, 1, resample.QualityLinear)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.Process([][]float64{mono})
+	if err != nil {
+		return nil, err
+	}
+	resampled := append(out[0], r.Flush()[0]...)
+	if len(resampled) > nout {
+		resampled = resampled[:nout]
+	}
+	return toInt16(resampled), nil
+}
+
+// PCMFile decodes the first duration of the given FLAC file as mono signed
+// 16-bit PCM at the given sample rate. See PCM for details.
+func PCMFile(path string, duration time.Duration, sampleRate int) ([]int16, error) {
+	stream, err := flac.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	return PCM(stream, duration, sampleRate)
+}
+
+// toInt16 converts the given samples to signed 16-bit samples, with rounding
+// and clipping.
+func toInt16(samples []float64) []int16 {
+	out := make([]int16, len(samples))
+	for i, x := range samples {
+		x = math.Round(x)
+		switch {
+		case x > math.MaxInt16:
+			x = math.MaxInt16
+		case x < math.MinInt16:
+			x = math.MinInt16
+		}
+		out[i] = int16(x)
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package fingerprint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/fingerprint"
+)
+
+func TestPCMLength(t *testing.T) {
+	golden := []struct {
+		path       string
+		duration   time.Duration
+		sampleRate int
+		want       int
+	}{
+		// 44.1 kHz stereo 16-bit.
+		{path: "../testdata/220014.flac", duration: time.Second, sampleRate: 11025, want: 11025},
+		{path: "../testdata/220014.flac", duration: 2 * time.Second, sampleRate: 44100, want: 88200},
+		// 48 kHz mono 16-bit.
+		{path: "../testdata/19875.flac", duration: 500 * time.Millisecond, sampleRate: 11025, want: 5512},
+		// 44.1 kHz stereo 24-bit, shorter than the requested duration (8192
+		// samples).
+		{path: "../testdata/59996.flac", duration: 10 * time.Second, sampleRate: 44100, want: 8192},
+		{path: "../testdata/59996.flac", duration: 10 * time.Second, sampleRate: 11025, want: 2048},
+	}
+	for _, g := range golden {
+		pcm, err := fingerprint.PCMFile(g.path, g.duration, g.sampleRate)
+		if err != nil {
+			t.Errorf("%s: %v", g.path, err)
+			continue
+		}
+		if len(pcm) != g.want {
+			t.Errorf("%s: sample count mismatch at %d Hz; expected %d, got %d", g.path, g.sampleRate, g.want, len(pcm))
+		}
+	}
+}
+
+func TestPCMDownmix(t *testing.T) {
+	const path = "../testdata/220014.flac"
+	pcm, err := fingerprint.PCMFile(path, time.Second, 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var want []int16
+	for len(want) < len(pcm) {
+		f, err := stream.ParseNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < f.Subframes[0].NSamples && len(want) < len(pcm); i++ {
+			sum := float64(f.Subframes[0].Samples[i]) + float64(f.Subframes[1].Samples[i])
+			x := sum / 2
+			// Round half away from zero.
+			if x < 0 {
+				want = append(want, int16(x-0.5))
+			} else {
+				want = append(want, int16(x+0.5))
+			}
+		}
+	}
+	for i := range pcm {
+		if pcm[i] != want[i] {
+			t.Fatalf("sample %d mismatch; expected %d, got %d", i, want[i], pcm[i])
+		}
+	}
+}
@@ -29,13 +29,20 @@ package flac
 import (
 	"bufio"
 	"bytes"
+	"crypto/md5"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"math"
 	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/mewkiz/flac/bufseekio"
 	"github.com/mewkiz/flac/frame"
-	"github.com/mewkiz/flac/internal/bufseekio"
 	"github.com/mewkiz/flac/meta"
 )
 
@@ -48,20 +55,86 @@ type Stream struct {
 	// audio stream.
 	Info *meta.StreamInfo
 	// Zero or more metadata blocks.
-	Blocks []*meta.Block
+	Blocks meta.BlockList
 
 	// seekTable contains one or more pre-calculated audio frame seek points of
 	// the stream; nil if uninitialized.
 	seekTable *meta.SeekTable
 	// seekTableSize determines how many seek points the seekTable should have if
-	// the flac file does not include one in the metadata.
+	// the flac file does not include one in the metadata; or a seek point per
+	// frame if negative. See Stream.SetSeekTableSize.
 	seekTableSize int
+	// refineSeekTable specifies whether to add seek points of the frames located
+	// by Stream.Seek to the seekTable.
+	refineSeekTable bool
+	// seekCache caches a seek point per frame of the regions of the stream
+	// visited by Stream.Seek; nil if disabled. See Stream.SetSeekCacheSize.
+	seekCache *seekCache
 	// dataStart is the offset of the first frame header since SeekPoint.Offset
 	// is relative to this position.
 	dataStart int64
 
+	// Decoder options.
+	opts DecodeOptions
+	// Frame buffer reused by ParseNext in low-memory mode; nil if unused.
+	buf *frame.Frame
+	// Read buffer and byte counter of the underlying io.Reader of a
+	// non-seekable stream; nil for seekable streams.
+	br *bufio.Reader
+	cr *countReader
+	// Digest reader wrapping br, used to compute digests of the consumed bytes;
+	// nil if unused. The digest reader does not read ahead, so the byte offset
+	// of the stream is unaffected.
+	dr *digestReader
+	// Totals of the frames decoded so far, computed for DecodeOptions.OnTotals;
+	// nil if unused.
+	totals *totalsState
+	// Sample number of the first sample of the next frame.
+	samplePos uint64
+	// Block size of the preceding frame if below the minimum block size of
+	// StreamInfo, and 0 otherwise; only the last frame may hold fewer samples.
+	// Tracked if a logger is present.
+	shortBlockSize uint16
+	// Block size of the preceding frame if below 16 samples, and 0 otherwise.
+	// Tracked in strict mode.
+	shortFrame uint16
+	// Tags trailing the audio frames, located at the end of the stream.
+	trailers []Trailer
+	// Statistics of the compressed frame sizes; nil if not tracked.
+	frameSizes *FrameSizeStats
+	// Blocking strategy of the frames parsed so far; BlockingUnknown if no
+	// frame has been parsed.
+	blocking BlockingStrategy
+	// DoP markers of the frames decoded so far; see Stream.DoP.
+	dop dopState
+	// Largest block size of the frames of a fixed-blocksize stream parsed so
+	// far; i.e. the block size of all frames but the last.
+	fixedBlockSize uint16
+	// Rate limit of decoding; nil if disabled.
+	pacer *pacer
+	// Watchdog limits of decoding; nil if unlimited.
+	watchdog *watchdog
+	// Hashes of the decoded audio samples, including the MD5 checksum of the
+	// stream totals; nil if none.
+	hasher *sampleHasher
+	// MD5 hash of the decoded audio samples, verified against StreamInfo at
+	// the end of the stream; nil if not verified. See DecodeOptions.VerifyMD5.
+	md5sum hash.Hash
+	// Frame of the samples retained by ReadInt16, and the sample number within
+	// the frame of the first retained sample; nil if none are retained.
+	int16Frame *frame.Frame
+	int16Pos   int
+
+	// Event channel of Stream.Events; nil if not created.
+	events *eventQueue
+	// Frame header of the preceding frame; tracked if events are logged.
+	prevHeader frame.Header
+
 	// Underlying io.Reader, or io.ReadCloser.
 	r io.Reader
+	// Closed by Stream.Close, if non-nil; e.g. the memory-mapped file of
+	// OpenMmap.
+	closer io.Closer
 }
 
 // New creates a new Stream for accessing the audio samples of r. It reads and
@@ -71,40 +144,74 @@ type Stream struct {
 // Call Stream.Next to parse the frame header of the next audio frame, and call
 // Stream.ParseNext to parse the entire next frame including audio samples.
 func New(r io.Reader) (stream *Stream, err error) {
-	// Verify FLAC signature and parse the StreamInfo metadata block.
-	br := bufio.NewReader(r)
-	stream = &Stream{r: br}
-	block, err := stream.parseStreamInfo()
-	if err != nil {
-		return nil, err
-	}
+	return NewStream(r)
+}
 
-	// Skip the remaining metadata blocks.
-	for !block.IsLast {
-		block, err = meta.New(br)
-		if err != nil && err != meta.ErrReservedType {
-			return stream, err
-		}
-		if err = block.Skip(); err != nil {
-			return stream, err
-		}
-	}
+// NewSeek returns a Stream that has seeking enabled. The buffering strategy is
+// selected based on the type of the incoming io.ReadSeeker; in-memory readers
+// (*bytes.Reader and *strings.Reader, and *io.SectionReader of a memory-mapped
+// MappedFile) are read directly, a *bufseekio.ReadSeeker is used as is, and
+// other readers (such as *os.File) are buffered using a read buffer sized to
+// hold the frames of the stream, based on the StreamInfo metadata block. Use
+// NewSeekSize to specify the buffer size explicitly.
+func NewSeek(rs io.ReadSeeker) (stream *Stream, err error) {
+	return NewStream(rs, WithSeeking())
+}
 
-	return stream, nil
+// NewSeekReaderAt returns a Stream that has seeking enabled, reading the first
+// size bytes of ra. Reads do not affect other users of ra; as such, several
+// streams may read from the same file concurrently. See NewSeek for the
+// buffering strategy.
+func NewSeekReaderAt(ra io.ReaderAt, size int64) (stream *Stream, err error) {
+	return NewSeek(io.NewSectionReader(ra, 0, size))
 }
 
-// NewSeek returns a Stream that has seeking enabled. The incoming io.ReadSeeker
-// will not be buffered, which might result in performance issues. Using an
-// in-memory buffer like *bytes.Reader should work well.
-func NewSeek(rs io.ReadSeeker) (stream *Stream, err error) {
-	br := bufseekio.NewReadSeeker(rs)
-	stream = &Stream{r: br, seekTableSize: defaultSeekTableSize}
+// NewSeekSize returns a Stream that has seeking enabled, buffering the reads of
+// the incoming io.ReadSeeker using a buffer of at least bufSize bytes. Larger
+// buffers reduce the number of reads of high-latency readers, such as files on
+// network storage.
+func NewSeekSize(rs io.ReadSeeker, bufSize int) (stream *Stream, err error) {
+	if bufSize <= 0 {
+		return nil, fmt.Errorf("flac.NewSeekSize: invalid buffer size %d", bufSize)
+	}
+	return newSeek(rs, bufSize, &DecodeOptions{}, false)
+}
+
+// newSeek returns a Stream that has seeking enabled, buffering the reads of rs
+// using a buffer of at least bufSize bytes; or using the buffering strategy of
+// NewSeek if bufSize is 0. The decoder options are limited to those supported
+// by seekable streams; see checkSeekOptions. The metadata blocks are retained
+// in Stream.Blocks if keepBlocks is set.
+func newSeek(rs io.ReadSeeker, bufSize int, opts *DecodeOptions, keepBlocks bool) (stream *Stream, err error) {
+	var br *bufseekio.ReadSeeker
+	var r io.ReadSeeker
+	switch {
+	case bufSize > 0:
+		br = bufseekio.NewReadSeekerSize(rs, bufSize)
+		r = br
+	case isInMemory(rs):
+		// Reads of in-memory readers are as cheap as reads of a buffer.
+		r = rs
+	default:
+		br = bufseekio.NewReadSeeker(rs)
+		r = br
+	}
+	stream = &Stream{r: r, seekTableSize: defaultSeekTableSize, opts: *opts}
+	stream.watchdog = newWatchdog(opts)
 
 	// Verify FLAC signature and parse the StreamInfo metadata block.
 	block, err := stream.parseStreamInfo()
 	if err != nil {
 		return stream, err
 	}
+	if opts.VerifyMD5 && stream.Info.MD5sum != [md5.Size]uint8{} {
+		stream.md5sum = md5.New()
+		stream.hasher = &sampleHasher{hashes: []hash.Hash{stream.md5sum}}
+	}
+	if bufSize == 0 && br != nil && br != rs {
+		// Read ahead at least one frame of typical size per read.
+		br.Resize(readaheadSize(stream.Info))
+	}
 
 	for !block.IsLast {
 		block, err = meta.Parse(stream.r)
@@ -120,13 +227,59 @@ func NewSeek(rs io.ReadSeeker) (stream *Stream, err error) {
 		if block.Header.Type == meta.TypeSeekTable {
 			stream.seekTable = block.Body.(*meta.SeekTable)
 		}
+		if keepBlocks {
+			stream.Blocks = append(stream.Blocks, block)
+		}
 	}
 
 	// Record file offset of the first frame header.
-	stream.dataStart, err = br.Seek(0, io.SeekCurrent)
+	stream.dataStart, err = r.Seek(0, io.SeekCurrent)
 	return stream, err
 }
 
+// isInMemory reports whether the given reader is backed by memory.
+func isInMemory(r io.Reader) bool {
+	switch r := r.(type) {
+	case *bytes.Reader, *strings.Reader:
+		return true
+	case *io.SectionReader:
+		// Sections of memory-mapped files; e.g. of NewSeekReaderAt.
+		outer, _, _ := r.Outer()
+		m, ok := outer.(*MappedFile)
+		return ok && m.Mapped()
+	}
+	return false
+}
+
+// maxReadaheadSize specifies the maximum size of the read buffer selected by
+// NewSeek.
+const maxReadaheadSize = 1 << 20
+
+// readaheadSize returns the size of the read buffer of a seekable stream with
+// the given properties, sized to hold two frames of typical size; i.e. two
+// frames of the maximum frame size if known, and otherwise two frames of half
+// the uncompressed size of the maximum block size.
+func readaheadSize(info *meta.StreamInfo) int {
+	size := 2 * typicalFrameSize(info)
+	if size < defaultBufSize {
+		return defaultBufSize
+	}
+	if size > maxReadaheadSize {
+		return maxReadaheadSize
+	}
+	return size
+}
+
+// typicalFrameSize returns the typical size in bytes of the frames of a stream
+// with the given properties; i.e. the maximum frame size if known, and
+// otherwise half the uncompressed size of the maximum block size.
+func typicalFrameSize(info *meta.StreamInfo) int {
+	if info.FrameSizeMax != 0 {
+		return int(info.FrameSizeMax)
+	}
+	return int(info.BlockSizeMax) * int(info.NChannels) * int(info.BitsPerSample) / 8 / 2
+}
+
 var (
 	// flacSignature marks the beginning of a FLAC stream.
 	flacSignature = []byte("fLaC")
@@ -146,6 +299,8 @@ var (
 
 const (
 	defaultSeekTableSize = 100
+	// defaultBufSize specifies the size of the read buffer of seekable streams.
+	defaultBufSize = 4096
 )
 
 // parseStreamInfo verifies the signature which marks the beginning of a FLAC
@@ -185,28 +340,32 @@ func (stream *Stream) parseStreamInfo() (block *meta.Block, err error) {
 	if !ok {
 		return block, fmt.Errorf("flac.parseStreamInfo: incorrect type of first metadata block; expected *meta.StreamInfo, got %T", block.Body)
 	}
+	if stream.opts.Strict {
+		if err := si.Validate(); err != nil {
+			return block, err
+		}
+	}
 	stream.Info = si
 	return block, nil
 }
 
 // skipID3v2 skips ID3v2 data prepended to flac files.
 func (stream *Stream) skipID3v2() error {
-	r := bufio.NewReader(stream.r)
+	// NOTE: read directly from stream.r, as wrapping it in a buffered reader
+	// would consume data past the ID3v2 tag, and affect the byte offset of
+	// seekable streams.
+	r := stream.r
 
-	// Discard unnecessary data from the ID3v2 header.
-	if _, err := r.Discard(2); err != nil {
+	// Read the ID3v2 header, and discard the version and flags.
+	var hdr [6]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
 		return err
 	}
 
-	// Read the size from the ID3v2 header.
-	var sizeBuf [4]byte
-	if _, err := r.Read(sizeBuf[:]); err != nil {
-		return err
-	}
 	// The size is encoded as a synchsafe integer.
-	size := int(sizeBuf[0])<<21 | int(sizeBuf[1])<<14 | int(sizeBuf[2])<<7 | int(sizeBuf[3])
+	size := int64(hdr[2])<<21 | int64(hdr[3])<<14 | int64(hdr[4])<<7 | int64(hdr[5])
 
-	_, err := r.Discard(size)
+	_, err := io.CopyN(io.Discard, r, size)
 	return err
 }
 
@@ -216,55 +375,30 @@ func (stream *Stream) skipID3v2() error {
 // Call Stream.Next to parse the frame header of the next audio frame, and call
 // Stream.ParseNext to parse the entire next frame including audio samples.
 func Parse(r io.Reader) (stream *Stream, err error) {
-	// Verify FLAC signature and parse the StreamInfo metadata block.
-	br := bufio.NewReader(r)
-	stream = &Stream{r: br}
-	block, err := stream.parseStreamInfo()
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse the remaining metadata blocks.
-	for !block.IsLast {
-		block, err = meta.Parse(br)
-		if err != nil {
-			if err != meta.ErrReservedType {
-				return stream, err
-			}
-			// Skip the body of unknown (reserved) metadata blocks, as stated by
-			// the specification.
-			//
-			// ref: https://www.xiph.org/flac/format.html#format_overview
-			if err = block.Skip(); err != nil {
-				return stream, err
-			}
-		}
-		stream.Blocks = append(stream.Blocks, block)
-	}
-
-	return stream, nil
+	return NewStream(r, WithAllMetadata())
 }
 
-// Open creates a new Stream for accessing the audio samples of path. It reads
-// and parses the FLAC signature and the StreamInfo metadata block, but skips
-// all other metadata blocks.
+// Open creates a new Stream for accessing the audio samples of path, using the
+// specified options. By default, it reads and parses the FLAC signature and the
+// StreamInfo metadata block, but skips all other metadata blocks; see
+// NewStream.
 //
 // Call Stream.Next to parse the frame header of the next audio frame, and call
 // Stream.ParseNext to parse the entire next frame including audio samples.
 //
 // Note: The Close method of the stream must be called when finished using it.
-func Open(path string) (stream *Stream, err error) {
+func Open(path string, opts ...Option) (stream *Stream, err error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
-
-	stream, err = New(f)
+	stream, err = newStream(f, newStreamConfig(opts), "flac.Open")
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
-
-	return stream, err
+	stream.closer = f
+	return stream, nil
 }
 
 // ParseFile creates a new Stream for accessing the metadata blocks and audio
@@ -276,20 +410,361 @@ func Open(path string) (stream *Stream, err error) {
 //
 // Note: The Close method of the stream must be called when finished using it.
 func ParseFile(path string) (stream *Stream, err error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
+	return Open(path, WithAllMetadata())
+}
+
+// DecodeOptions specifies the options of a FLAC decoder. The zero value
+// specifies the default options.
+type DecodeOptions struct {
+	// LowMemory enables the low-memory decoding mode, intended for embedded and
+	// WebAssembly use. In low-memory mode, the bodies of all metadata blocks but
+	// StreamInfo are skipped without being retained, a 4 KiB read buffer is
+	// used unless ReadBufferSize is set, and the audio samples of each frame
+	// are decoded into a frame buffer which is reused by subsequent calls to
+	// Stream.ParseNext. As such, a frame returned by ParseNext is only valid
+	// until the next call to ParseNext.
+	//
+	// Frames with a block size exceeding the maximum block size of StreamInfo
+	// are rejected in low-memory mode, to ensure that the memory used for audio
+	// samples never exceeds the budget derived from StreamInfo.
+	LowMemory bool
+	// MaxSampleMemory specifies the maximum number of bytes used to hold the
+	// decoded audio samples of a frame in low-memory mode; a 0 value implies no
+	// limit. Streams whose StreamInfo block permits frames exceeding the limit
+	// are rejected with ErrMemoryBudget.
+	MaxSampleMemory int
+	// Logger receives diagnostic events of the decoder, such as parsed metadata
+	// blocks and frames, and warnings for non-fatal violations of the FLAC
+	// format; nil specifies no logging.
+	Logger Logger
+	// Digest enables the computation of digests over the exact bytes consumed
+	// from the stream, split into a metadata digest (covering any prepended
+	// ID3v2 data, the FLAC signature and all metadata blocks) and an audio
+	// digest (covering the audio frames); see Stream.MetadataDigest and
+	// Stream.AudioDigest. As such, archives may detect whether a tag edit
+	// touched the audio data of a file.
+	Digest bool
+	// NewHash returns a new hash used to compute digests; nil specifies
+	// SHA-256.
+	NewHash func() hash.Hash
+	// KeepIntermediates retains the intermediates of subframe decoding, for
+	// analysis of predictor performance and comparison of encoder output. If
+	// set, the residuals of fixed and FIR linear prediction decoding are
+	// retained in frame.Subframe.Residuals by Stream.ParseNext and
+	// Stream.ParseNextInto; see frame.Frame.KeepResiduals. The prediction
+	// parameters (order, coefficients, shift and Rice partitions) are always
+	// retained in frame.SubHeader, as required to re-encode decoded frames.
+	KeepIntermediates bool
+	// OnTotals receives the total number of samples and the MD5 checksum of
+	// the decoded audio samples once Stream.ParseNext or Stream.ParseNextInto
+	// returns io.EOF, for streams whose StreamInfo block leaves either unset
+	// (e.g. streams encoded to a pipe); nil specifies no totals. The totals may
+	// be stored in a sidecar file (see StreamTotals.WriteFile) and patched into
+	// the source file later using PatchTotals, without a second decode. Totals
+	// are not reported once Stream.Next has been called, as frames parsed by
+	// the caller are not accounted for.
+	OnTotals func(totals *StreamTotals)
+	// RepairTags enables the repair of Vorbis comments which are not valid
+	// UTF-8, as written by tag editors of legacy systems; such strings are
+	// transcoded from Windows-1252 (a superset of Latin-1) to UTF-8, and a
+	// warning is logged for each repaired string. See
+	// meta.VorbisComment.RepairEncoding. Vorbis comments are not parsed in
+	// low-memory mode, and are thus not repaired.
+	RepairTags bool
+	// Strict enables strict validation of the StreamInfo metadata block,
+	// rejecting fields outside of the ranges permitted by the FLAC format (see
+	// meta.StreamInfo.Validate); e.g. a sample size below 4 bits-per-sample, or
+	// a minimum block size exceeding the maximum block size. Otherwise, such
+	// streams are decoded on a best-effort basis.
+	//
+	// Strict mode also enables the validation of the frame header rules which
+	// depend on the surrounding stream; frames of fewer than 16 samples other
+	// than the last frame, and frame numbers of fixed-blocksize streams
+	// exceeding 31 bits are rejected with a *frame.HeaderError. Reserved and
+	// forbidden bit patterns of frame headers are always rejected, as such
+	// frames cannot be decoded.
+	Strict bool
+	// Spec specifies the revision of the specification of the FLAC format
+	// targeted by strict validation; the zero value targets RFC 9639. Frame
+	// headers using the 32 bits-per-sample bit pattern, which is reserved by
+	// the classic specification, are rejected in strict mode if Spec is
+	// frame.SpecXiph. See frame.Spec for the differences between revisions.
+	Spec frame.Spec
+	// TrackFrameSizes enables the tracking of running statistics of the
+	// compressed sizes of the audio frames parsed by Stream.ParseNext and
+	// Stream.ParseNextInto, such as the largest frame size and the peak
+	// bitrate; see Stream.FrameSizeStats. As such, streaming clients may adapt
+	// their prebuffering to the actual bitrate variance of the stream, also for
+	// streams whose StreamInfo block leaves the frame sizes unset.
+	TrackFrameSizes bool
+	// OnFrameSize receives the compressed size in bytes of each audio frame
+	// parsed by Stream.ParseNext and Stream.ParseNextInto, along with the
+	// running frame size statistics of the stream, which are updated in place
+	// by subsequent frames; nil specifies no callback. Frame sizes are tracked
+	// if set, as by TrackFrameSizes.
+	OnFrameSize func(size int, stats *FrameSizeStats)
+	// BitrateWindow specifies the duration of the sliding window over which the
+	// bitrate of the most recently parsed audio frames is measured; see
+	// FrameSizeStats.WindowBitrate. A 0 value implies the default of 5 seconds.
+	// Frame sizes are tracked if set, as by TrackFrameSizes.
+	BitrateWindow time.Duration
+	// ReadBufferSize specifies the size in bytes of the read buffer of the
+	// underlying io.Reader; a 0 value implies the default size of 4 KiB. Each
+	// read of the underlying io.Reader requests up to the size of the read
+	// buffer; as such, larger buffers reduce the number of reads of
+	// high-latency sources, such as the response body of an HTTP request (e.g.
+	// 256 KiB). To buffer the reads of seekable streams, see NewSeekSize.
+	//
+	// Note: the decoder reads through the io.Reader interface, and as such does
+	// not benefit from zero-copy I/O such as sendfile or io_uring; a large read
+	// buffer is the means to amortize the latency of each read. To serve FLAC
+	// files without decoding the audio frames, see package flachttp.
+	ReadBufferSize int
+	// Pace rate-limits the decoding of audio frames by Stream.ParseNext and
+	// Stream.ParseNextInto (and thus the iterators of the stream) to the given
+	// multiple of real time, based on the sample rate of StreamInfo; a 0 value
+	// implies no rate limit. As such, streaming servers which only need
+	// real-time delivery may decode at e.g. 1.5 times real time, rather than
+	// bursting CPU and bandwidth by decoding as fast as possible.
+	//
+	// The first frame is returned without delay, and each subsequent frame once
+	// the preceding frames have been played at the given pace. Time spent by the
+	// caller beyond the pace is not made up for by a burst of frames.
+	Pace float64
+	// MaxSampleRatio limits the ratio of the number of samples decoded (across
+	// all channels) to the number of bytes consumed by the audio frames so far;
+	// a 0 value implies no limit. Decoding is aborted with a *WatchdogError once
+	// the ratio is exceeded, protecting servers which decode untrusted uploads
+	// from decompression bombs; i.e. small streams of large frames which encode
+	// vast numbers of samples in a few bytes each. The ratio is only limited
+	// once more than 2^20 samples have been decoded.
+	//
+	// Typical music has a ratio below 2 (e.g. about 0.7 for 16-bit stereo at
+	// 1000 kbit/s), while digital silence may reach several hundred; up to
+	// about 30000 for frames of 65535 samples and 8 channels.
+	MaxSampleRatio float64
+	// MaxFrameTime limits the time spent by Stream.ParseNext and
+	// Stream.ParseNextInto decoding a single frame; a 0 value implies no limit.
+	// Decoding is aborted with a *WatchdogError once a frame took longer to
+	// decode, e.g. due to a slow underlying io.Reader or a pathological frame.
+	// The decode time is measured once the frame has been decoded; as such, the
+	// decoding of a frame is not interrupted.
+	MaxFrameTime time.Duration
+	// SampleHashes receive the decoded audio samples of the frames decoded by
+	// Stream.ParseNext and Stream.ParseNextInto, in the canonical byte
+	// representation of the MD5 checksum of StreamInfo; i.e. interleaved
+	// little-endian two's complement integers of the smallest number of bytes
+	// holding the bits-per-sample of the stream. As such, integrity databases
+	// may compute e.g. SHA-256 checksums of the audio samples in the same pass
+	// as the MD5 checksum (by including an MD5 hash, to verify against
+	// StreamInfo.MD5sum).
+	//
+	// The hashes cover the entire stream only if all frames are decoded by
+	// ParseNext or ParseNextInto; frames parsed by Stream.Next, skipped by
+	// Stream.SkipFrames or decoded in part by Stream.Samples are not hashed.
+	SampleHashes []hash.Hash
+	// VerifyMD5 enables the verification of the MD5 checksum of the decoded
+	// audio samples against the MD5 checksum of StreamInfo, if known; once the
+	// end of the stream is reached, Stream.ParseNext and Stream.ParseNextInto
+	// return an error wrapping ErrChecksumMismatch instead of io.EOF on
+	// mismatch. The checksum only covers streams decoded in their entirety
+	// from the start; as such, the verification is disabled by seeking, and
+	// by frames parsed by Stream.Next, skipped by Stream.SkipFrames or decoded
+	// in part by Stream.Samples.
+	VerifyMD5 bool
+	// EventBuffer specifies the capacity of the event channel of
+	// Stream.Events; a 0 value implies a capacity of 256 once Events is first
+	// called. A non-zero value creates the channel before the metadata blocks
+	// are parsed, so that their events are delivered.
+	EventBuffer int
+}
+
+// ErrMemoryBudget reports that decoding a stream in low-memory mode would
+// exceed the memory budget.
+var ErrMemoryBudget = errors.New("flac: memory budget exceeded")
+
+// lowMemoryBufSize specifies the size of the read buffer used in low-memory
+// mode.
+const lowMemoryBufSize = 4096
+
+// NewWithOptions creates a new Stream for accessing the metadata blocks and
+// audio samples of r, using the specified decoder options. A nil opts specifies
+// the default options. It reads and parses the FLAC signature and all metadata
+// blocks, as Parse; in low-memory mode, the bodies of all metadata blocks but
+// StreamInfo are skipped, as New.
+//
+// Call Stream.Next to parse the frame header of the next audio frame, and call
+// Stream.ParseNext to parse the entire next frame including audio samples.
+func NewWithOptions(r io.Reader, opts *DecodeOptions) (stream *Stream, err error) {
+	if opts == nil {
+		opts = &DecodeOptions{}
 	}
-	stream, err = Parse(f)
+	return newWithOptions(r, opts, false)
+}
+
+// newWithOptions creates a new Stream for accessing the metadata blocks and
+// audio samples of r, using the specified decoder options, as done by
+// NewWithOptions. The bodies of all metadata blocks but StreamInfo are skipped
+// if skipMetadata is set, as in low-memory mode.
+func newWithOptions(r io.Reader, opts *DecodeOptions, skipMetadata bool) (stream *Stream, err error) {
+	if opts.EventBuffer < 0 {
+		return nil, fmt.Errorf("flac.NewWithOptions: invalid event buffer size %d", opts.EventBuffer)
+	}
+	if opts.ReadBufferSize < 0 {
+		return nil, fmt.Errorf("flac.NewWithOptions: invalid read buffer size %d", opts.ReadBufferSize)
+	}
+	if !(opts.Pace >= 0) || math.IsInf(opts.Pace, 0) {
+		return nil, fmt.Errorf("flac.NewWithOptions: invalid pace %v", opts.Pace)
+	}
+	if !(opts.MaxSampleRatio >= 0) {
+		return nil, fmt.Errorf("flac.NewWithOptions: invalid maximum sample ratio %v", opts.MaxSampleRatio)
+	}
+	if opts.MaxFrameTime < 0 {
+		return nil, fmt.Errorf("flac.NewWithOptions: invalid maximum frame decode time %v", opts.MaxFrameTime)
+	}
+	if opts.BitrateWindow < 0 {
+		return nil, fmt.Errorf("flac.NewWithOptions: invalid bitrate window %v", opts.BitrateWindow)
+	}
+
+	// Verify FLAC signature and parse the StreamInfo metadata block.
+	cr := &countReader{r: r}
+	var br *bufio.Reader
+	switch {
+	case opts.ReadBufferSize > 0:
+		br = bufio.NewReaderSize(cr, opts.ReadBufferSize)
+	case opts.LowMemory:
+		br = bufio.NewReaderSize(cr, lowMemoryBufSize)
+	default:
+		br = bufio.NewReader(cr)
+	}
+	stream = &Stream{r: br, br: br, cr: cr, opts: *opts}
+	if opts.EventBuffer > 0 {
+		stream.events = newEventQueue(opts.EventBuffer)
+	}
+	if opts.Digest {
+		stream.dr = newDigestReader(br, opts.NewHash)
+		stream.r = stream.dr
+	}
+	block, err := stream.parseStreamInfo()
 	if err != nil {
 		return nil, err
 	}
+	stream.logBlock(block)
+	info := stream.Info
+	sampleMem := int(info.BlockSizeMax) * int(info.NChannels) * 4 // int32 samples
+	if opts.LowMemory && opts.MaxSampleMemory > 0 && sampleMem > opts.MaxSampleMemory {
+		return nil, fmt.Errorf("flac.NewWithOptions: %w; frames of %d samples in %d channels require %d bytes, limit is %d bytes", ErrMemoryBudget, info.BlockSizeMax, info.NChannels, sampleMem, opts.MaxSampleMemory)
+	}
+	if opts.TrackFrameSizes || opts.OnFrameSize != nil || opts.BitrateWindow != 0 {
+		stream.frameSizes = newFrameSizeStats(info.SampleRate, opts.BitrateWindow)
+	}
+	stream.pacer = newPacer(info.SampleRate, opts.Pace)
+	stream.watchdog = newWatchdog(opts)
 
-	return stream, err
+	// Parse the remaining metadata blocks; or skip them in low-memory mode.
+	skip := opts.LowMemory || skipMetadata
+	for !block.IsLast {
+		if skip {
+			block, err = meta.New(stream.r)
+		} else {
+			block, err = meta.Parse(stream.r)
+		}
+		if err != nil {
+			if err != meta.ErrReservedType {
+				return stream, err
+			}
+			if err = block.Skip(); err != nil {
+				return stream, err
+			}
+			stream.log(Event{Kind: EventWarning, Offset: stream.Offset() - 4 - block.Length, Block: block, Err: fmt.Errorf("skipped metadata block of reserved type %d", uint8(block.Type))})
+		} else if skip {
+			if err = block.Skip(); err != nil {
+				return stream, err
+			}
+		}
+		stream.logBlock(block)
+		if opts.RepairTags {
+			stream.repairTags(block)
+		}
+		if !skip {
+			stream.Blocks = append(stream.Blocks, block)
+		}
+	}
+
+	// Record offset of the first frame header.
+	stream.dataStart = stream.Offset()
+	hashes := slices.Clip(opts.SampleHashes)
+	if opts.OnTotals != nil && (info.NSamples == 0 || info.MD5sum == [md5.Size]uint8{}) {
+		stream.totals = &totalsState{md5sum: md5.New()}
+		hashes = append(hashes, stream.totals.md5sum)
+	}
+	if opts.VerifyMD5 && info.MD5sum != [md5.Size]uint8{} {
+		stream.md5sum = md5.New()
+		hashes = append(hashes, stream.md5sum)
+	}
+	if len(hashes) > 0 {
+		stream.hasher = &sampleHasher{hashes: hashes}
+	}
+	if stream.dr != nil {
+		// Start audio digest.
+		stream.dr.next()
+	}
+
+	if opts.LowMemory {
+		// Allocate frame buffer, holding the audio samples of one frame per
+		// channel.
+		stream.buf = &frame.Frame{
+			Subframes: make([]*frame.Subframe, info.NChannels),
+		}
+		for i := range stream.buf.Subframes {
+			stream.buf.Subframes[i] = &frame.Subframe{
+				Samples: make([]int32, 0, info.BlockSizeMax),
+			}
+		}
+	}
+	return stream, nil
+}
+
+// logBlock logs a block event for the given metadata block, which has been
+// parsed or skipped.
+func (stream *Stream) logBlock(block *meta.Block) {
+	if !stream.logging() {
+		return
+	}
+	// The 4 byte metadata block header precedes the block body.
+	offset := stream.Offset() - 4 - block.Length
+	stream.log(Event{Kind: EventBlock, Offset: offset, Block: block})
+}
+
+// repairTags transcodes the strings of the given VorbisComment metadata block
+// which are not valid UTF-8 from Windows-1252 to UTF-8, logging a warning for
+// each repaired string. See DecodeOptions.RepairTags.
+func (stream *Stream) repairTags(block *meta.Block) {
+	comment, ok := block.Body.(*meta.VorbisComment)
+	if !ok {
+		return
+	}
+	for _, repair := range comment.RepairEncoding() {
+		if !stream.logging() {
+			continue
+		}
+		offset := stream.Offset() - 4 - block.Length
+		name := repair.Name
+		if repair.Index == -1 {
+			name = "vendor string"
+		}
+		stream.log(Event{Kind: EventWarning, Offset: offset, Block: block, Err: fmt.Errorf("transcoded %s from Windows-1252 to UTF-8; %q -> %q", name, repair.Old, repair.New)})
+	}
 }
 
 // Close closes the stream gracefully if the underlying io.Reader also implements the io.Closer interface.
 func (stream *Stream) Close() error {
+	if stream.events != nil {
+		stream.events.close()
+	}
+	if stream.closer != nil {
+		return stream.closer.Close()
+	}
 	if closer, ok := stream.r.(io.Closer); ok {
 		return closer.Close()
 	}
@@ -298,23 +773,254 @@ func (stream *Stream) Close() error {
 }
 
 // Next parses the frame header of the next audio frame. It returns io.EOF to
-// signal a graceful end of FLAC stream.
+// signal a graceful end of FLAC stream. See Stream.Trailers for the
+// handling of data following the audio frames.
 //
 // Call Frame.Parse to parse the audio samples of its subframes.
 func (stream *Stream) Next() (f *frame.Frame, err error) {
-	return frame.New(stream.r)
+	offset := stream.frameOffset()
+	// Stream totals are only computed from frames decoded by the stream.
+	stream.totals = nil
+	stream.md5sum = nil
+	if err := stream.checkEnd(); err != nil {
+		return nil, err
+	}
+	f, err = frame.New(stream.r)
+	if err != nil {
+		return f, stream.truncated(err, stream.samplePos)
+	}
+	if stream.opts.Strict {
+		if err := stream.checkStrict(f); err != nil {
+			return f, err
+		}
+	}
+	stream.advance(offset, f)
+	return f, nil
 }
 
 // ParseNext parses the entire next frame including audio samples. It returns
-// io.EOF to signal a graceful end of FLAC stream.
+// io.EOF to signal a graceful end of FLAC stream. See Stream.Trailers for the
+// handling of data following the audio frames.
+//
+// In low-memory mode, the returned frame is only valid until the next call to
+// ParseNext; see DecodeOptions.LowMemory.
 func (stream *Stream) ParseNext() (f *frame.Frame, err error) {
-	return frame.Parse(stream.r)
+	if stream.buf != nil {
+		if err := stream.ParseNextInto(stream.buf); err != nil {
+			return nil, err
+		}
+		return stream.buf, nil
+	}
+	start := stream.watchStart()
+	offset := stream.frameOffset()
+	if err := stream.checkEnd(); err != nil {
+		stream.reportTotals(err)
+		return nil, stream.verifyMD5(err)
+	}
+	f, err = frame.New(stream.r)
+	if err != nil {
+		stream.reportTotals(err)
+		return f, stream.verifyMD5(stream.truncated(err, stream.samplePos))
+	}
+	if stream.opts.Strict {
+		if err := stream.checkStrict(f); err != nil {
+			return f, err
+		}
+	}
+	stream.advance(offset, f)
+	f.KeepResiduals = stream.opts.KeepIntermediates
+	if err := f.Parse(); err != nil {
+		return f, stream.truncated(err, stream.sampleNumber(f))
+	}
+	if err := stream.watch(f, start); err != nil {
+		return f, err
+	}
+	if err := stream.hashSamples(f); err != nil {
+		return f, err
+	}
+	stream.checkDoP(f)
+	stream.addTotals(f)
+	stream.addFrameSize(offset, f)
+	stream.log(Event{Kind: EventFrame, Offset: offset, Frame: f})
+	if stream.pacer != nil {
+		stream.pacer.wait(f)
+	}
+	return f, nil
+}
+
+// ParseNextInto parses the entire next frame including audio samples into the
+// provided frame, reusing its subframes and audio sample buffers. It returns
+// io.EOF to signal a graceful end of FLAC stream. See Stream.Trailers for the
+// handling of data following the audio frames.
+//
+// Decoding a stream one frame at the time using the same frame does not
+// allocate memory once the buffers of the frame have grown to hold the largest
+// frame of the stream.
+func (stream *Stream) ParseNextInto(f *frame.Frame) error {
+	return stream.parseNextInto(f, -1)
+}
+
+// parseNextInto parses the next frame into the provided frame, as done by
+// ParseNextInto. The audio samples of all channels are decoded if channel is
+// -1, and of the given channel only otherwise (see frame.Frame.ParseChannel).
+func (stream *Stream) parseNextInto(f *frame.Frame, channel int) error {
+	start := stream.watchStart()
+	offset := stream.frameOffset()
+	if err := stream.checkEnd(); err != nil {
+		stream.reportTotals(err)
+		return stream.verifyMD5(err)
+	}
+	if err := frame.NewInto(stream.r, f); err != nil {
+		stream.reportTotals(err)
+		return stream.verifyMD5(stream.truncated(err, stream.samplePos))
+	}
+	if stream.opts.LowMemory && f.BlockSize > stream.Info.BlockSizeMax {
+		return fmt.Errorf("flac.Stream.ParseNextInto: %w; block size (%d) exceeds maximum block size of StreamInfo (%d)", ErrMemoryBudget, f.BlockSize, stream.Info.BlockSizeMax)
+	}
+	if stream.opts.Strict {
+		if err := stream.checkStrict(f); err != nil {
+			return err
+		}
+	}
+	stream.advance(offset, f)
+	f.KeepResiduals = stream.opts.KeepIntermediates
+	var err error
+	if channel == -1 {
+		err = f.Parse()
+	} else {
+		// Stream totals are only computed from fully decoded frames.
+		stream.totals = nil
+		stream.md5sum = nil
+		err = f.ParseChannel(channel)
+	}
+	if err != nil {
+		return stream.truncated(err, stream.sampleNumber(f))
+	}
+	if err := stream.watch(f, start); err != nil {
+		return err
+	}
+	if channel == -1 {
+		if err := stream.hashSamples(f); err != nil {
+			return err
+		}
+		stream.checkDoP(f)
+	}
+	stream.addTotals(f)
+	stream.addFrameSize(offset, f)
+	stream.log(Event{Kind: EventFrame, Offset: offset, Frame: f})
+	if stream.pacer != nil {
+		stream.pacer.wait(f)
+	}
+	return nil
+}
+
+// frameOffset returns the current byte offset of the stream if events are
+// logged or frame sizes are tracked, and -1 otherwise.
+func (stream *Stream) frameOffset() int64 {
+	if !stream.logging() && stream.frameSizes == nil {
+		return -1
+	}
+	return stream.Offset()
+}
+
+// advance updates the sample position of the stream to the end of the given
+// frame, which has had its header parsed at the given byte offset. The frame
+// header is validated against StreamInfo, and against the preceding frame, if
+// events are logged.
+func (stream *Stream) advance(offset int64, f *frame.Frame) {
+	logging := stream.logging()
+	if logging {
+		stream.checkFrameHeader(offset, f)
+	}
+	// Frame headers of streams whose sample size has no frame header code
+	// refer to StreamInfo for the sample size.
+	if f.BitsPerSample == 0 {
+		f.BitsPerSample = stream.Info.BitsPerSample
+	}
+	if logging {
+		stream.checkParams(offset, f)
+	}
+	stream.blocking = blockingStrategy(f.HasFixedBlockSize)
+	if f.HasFixedBlockSize && f.BlockSize > stream.fixedBlockSize {
+		stream.fixedBlockSize = f.BlockSize
+	}
+	stream.samplePos = stream.sampleNumber(f) + uint64(f.BlockSize)
+}
+
+// sampleNumber returns the first sample number contained within the given
+// frame of the stream.
+func (stream *Stream) sampleNumber(f *frame.Frame) uint64 {
+	if f.HasFixedBlockSize {
+		// NOTE: the last frame of a fixed-blocksize stream may hold fewer
+		// samples, so use the block size of the stream to locate its first
+		// sample; as specified by StreamInfo, or otherwise as observed in the
+		// preceding frames (e.g. for streams whose StreamInfo block was not
+		// updated by the encoder).
+		if stream.Info.BlockSizeMin == stream.Info.BlockSizeMax {
+			return f.Num * uint64(stream.Info.BlockSizeMax)
+		}
+		if stream.fixedBlockSize > f.BlockSize {
+			return f.Num * uint64(stream.fixedBlockSize)
+		}
+	}
+	return f.SampleNumber()
+}
+
+// Offset returns the current byte offset of the stream, relative to the start
+// of the underlying io.Reader; i.e. the number of bytes consumed by the
+// decoder, excluding data read ahead into internal buffers. Between calls to
+// Stream.ParseNext, this is the offset of the next frame header.
+func (stream *Stream) Offset() int64 {
+	if stream.cr != nil {
+		return stream.cr.n - int64(stream.br.Buffered())
+	}
+	if rs, ok := stream.r.(io.Seeker); ok {
+		// The buffered read seeker of NewSeek reports the current position
+		// without seeking the underlying io.ReadSeeker, and does not fail.
+		off, _ := rs.Seek(0, io.SeekCurrent)
+		return off
+	}
+	return 0
+}
+
+// SamplePosition returns the sample number of the first sample of the next
+// frame; i.e. the number of samples (per channel) preceding the next frame of
+// the stream.
+func (stream *Stream) SamplePosition() uint64 {
+	return stream.samplePos
+}
+
+// DataStart returns the byte offset of the first frame header of the stream,
+// relative to the start of the underlying io.Reader; i.e. the size of the FLAC
+// signature, metadata blocks and any prepended ID3v2 data.
+func (stream *Stream) DataStart() int64 {
+	return stream.dataStart
+}
+
+// countReader counts the number of bytes read from the underlying io.Reader.
+type countReader struct {
+	// Underlying io.Reader.
+	r io.Reader
+	// Number of bytes read.
+	n int64
+}
+
+// Read reads up to len(p) bytes into p, and counts the number of bytes read.
+func (cr *countReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
 }
 
 // Seek seeks to the frame containing the given absolute sample number. The
 // return value specifies the first sample number of the frame containing
 // sampleNum.
 func (stream *Stream) Seek(sampleNum uint64) (uint64, error) {
+	if err := stream.checkReposition(); err != nil {
+		return 0, fmt.Errorf("flac.Stream.Seek: %w", err)
+	}
+	// The MD5 checksum only covers streams decoded from the start.
+	stream.md5sum = nil
 	if stream.seekTable == nil && stream.seekTableSize > 0 {
 		if err := stream.makeSeekTable(); err != nil {
 			return 0, err
@@ -327,56 +1033,103 @@ func (stream *Stream) Seek(sampleNum uint64) (uint64, error) {
 	if isBiggerThanStream || sampleNum < 0 {
 		return 0, fmt.Errorf("unable to seek to sample number %d", sampleNum)
 	}
-	point, err := stream.searchFromStart(sampleNum)
-	if err != nil {
-		return 0, err
+	var point meta.SeekPoint
+	if stream.seekTable != nil {
+		var err error
+		point, err = stream.searchFromStart(sampleNum)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Seek points of the frames parsed are recorded in the cached region of the
+	// seek point, which may locate the frame already.
+	start := point
+	var region *seekRegion
+	if stream.seekCache != nil {
+		region = stream.seekCache.region(point.SampleNum)
+		if cached, ok := region.find(sampleNum); ok {
+			_, err := rs.Seek(stream.dataStart+int64(cached.Offset), io.SeekStart)
+			stream.resetSeek(cached.SampleNum)
+			return cached.SampleNum, err
+		}
+		if n := len(region.points); n > 0 {
+			// Resume parsing at the last recorded frame of the region.
+			start = region.points[n-1]
+		}
 	}
 
-	if _, err := rs.Seek(stream.dataStart+int64(point.Offset), io.SeekStart); err != nil {
+	if _, err := rs.Seek(stream.dataStart+int64(start.Offset), io.SeekStart); err != nil {
 		return 0, err
 	}
+	stream.samplePos = start.SampleNum
+	stream.shortFrame = 0
 	for {
 		// Record seek offset to start of frame.
 		offset, err := rs.Seek(0, io.SeekCurrent)
 		if err != nil {
 			return 0, err
 		}
-		frame, err := stream.ParseNext()
+		frame, err := stream.scanFrame()
 		if err != nil {
 			return 0, err
 		}
-		if frame.SampleNumber()+uint64(frame.BlockSize) > sampleNum {
+		first := stream.sampleNumber(frame)
+		if region != nil {
+			region.add(meta.SeekPoint{
+				SampleNum: first,
+				Offset:    uint64(offset - stream.dataStart),
+				NSamples:  frame.BlockSize,
+			})
+		}
+		if first+uint64(frame.BlockSize) > sampleNum {
 			// Restore seek offset to the start of the frame containing the
 			// specified sample number.
 			_, err := rs.Seek(offset, io.SeekStart)
-			return frame.SampleNumber(), err
+			stream.resetSeek(first)
+			if stream.refineSeekTable && first != point.SampleNum {
+				stream.addSeekPoint(meta.SeekPoint{
+					SampleNum: first,
+					Offset:    uint64(offset - stream.dataStart),
+					NSamples:  frame.BlockSize,
+				})
+			}
+			return first, err
 		}
 	}
 }
 
-// TODO(_): Utilize binary search in searchFromStart.
+// resetSeek resets the decoder state after seeking to the start of the frame
+// of the given first sample number.
+func (stream *Stream) resetSeek(first uint64) {
+	stream.samplePos = first
+	stream.int16Frame = nil
+	stream.shortBlockSize = 0
+	stream.shortFrame = 0
+	if stream.watchdog != nil {
+		stream.watchdog.reset(stream.Offset() - stream.dataStart)
+	}
+}
 
-// searchFromStart searches for the given sample number from the start of the
-// seek table and returns the last seek point containing the sample number. If
-// no seek point contains the sample number, the last seek point preceding the
-// sample number is returned. If the sample number is lower than the first seek
-// point, the first seek point is returned.
+// searchFromStart searches the seek table for the given sample number and
+// returns the last seek point at or preceding the sample number. If the sample
+// number is lower than the first seek point, the first seek point is returned.
 func (stream *Stream) searchFromStart(sampleNum uint64) (meta.SeekPoint, error) {
-	if len(stream.seekTable.Points) == 0 {
+	points := stream.seekTable.Points
+	if len(points) == 0 {
 		return meta.SeekPoint{}, ErrNoSeektable
 	}
-	prev := stream.seekTable.Points[0]
-	for _, p := range stream.seekTable.Points {
-		if p.SampleNum+uint64(p.NSamples) >= sampleNum {
-			return prev, nil
-		}
-		prev = p
-	}
-	return prev, nil
+	// Seek points are sorted by sample number, with placeholder points last.
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].SampleNum > sampleNum
+	})
+	return points[max(i-1, 0)], nil
 }
 
-// makeSeekTable creates a seek table with seek points to each frame of the FLAC
-// stream.
+// makeSeekTable creates a seek table of the FLAC stream, with the number of
+// seek points specified by seekTableSize; seek points are located at evenly
+// spaced byte offsets, or at each frame if the stream holds no more frames
+// than seek points.
 func (stream *Stream) makeSeekTable() (err error) {
 	rs, ok := stream.r.(io.ReadSeeker)
 	if !ok {
@@ -387,27 +1140,49 @@ func (stream *Stream) makeSeekTable() (err error) {
 	if err != nil {
 		return err
 	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
 
-	_, err = rs.Seek(stream.dataStart, io.SeekStart)
+	var points []meta.SeekPoint
+	dataSize := end - stream.dataStart
+	if frameSize := int64(max(typicalFrameSize(stream.Info), 1)); stream.seekTableSize < 0 || dataSize/frameSize <= int64(stream.seekTableSize) {
+		points, err = stream.frameSeekPoints(rs)
+	} else {
+		points, err = stream.sparseSeekPoints(rs, dataSize)
+	}
 	if err != nil {
 		return err
 	}
 
-	var i int
+	stream.seekTable = &meta.SeekTable{Points: points}
+
+	_, err = rs.Seek(pos, io.SeekStart)
+	return err
+}
+
+// frameSeekPoints returns a seek point of each frame of the FLAC stream.
+func (stream *Stream) frameSeekPoints(rs io.ReadSeeker) ([]meta.SeekPoint, error) {
+	if _, err := rs.Seek(stream.dataStart, io.SeekStart); err != nil {
+		return nil, err
+	}
+	stream.samplePos = 0
+
 	var sampleNum uint64
 	var points []meta.SeekPoint
 	for {
 		// Record seek offset to start of frame.
 		off, err := rs.Seek(0, io.SeekCurrent)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		f, err := stream.ParseNext()
+		f, err := stream.scanFrame()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return err
+			return nil, err
 		}
 		points = append(points, meta.SeekPoint{
 			SampleNum: sampleNum,
@@ -416,11 +1191,6 @@ func (stream *Stream) makeSeekTable() (err error) {
 		})
 
 		sampleNum += uint64(f.BlockSize)
-		i++
 	}
-
-	stream.seekTable = &meta.SeekTable{Points: points}
-
-	_, err = rs.Seek(pos, io.SeekStart)
-	return err
+	return points, nil
 }
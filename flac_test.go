@@ -1,12 +1,17 @@
 package flac_test
 
 import (
+	"bytes"
+	"crypto/md5"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"testing"
 
 	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
 )
 
 func TestSkipID3v2(t *testing.T) {
@@ -50,7 +55,7 @@ func TestSeek(t *testing.T) {
 		{seek: 100, expected: 0},
 		{seek: 8192, expected: 8192},
 		{seek: 8191, expected: 4096},
-		//{seek: 40960 + 2723 - 1, expected: 40960}, // last sample // TODO: re-enable when it works. See https://github.com/mewkiz/flac/pull/73
+		{seek: 40960 + 2723 - 1, expected: 40960}, // last sample
 		{seek: 40960 + 2723, expected: 0, err: "unable to seek to sample number 43683"}, // one after last sample
 	}
 
@@ -81,6 +86,63 @@ func TestSeek(t *testing.T) {
 	}
 }
 
+func TestNewSeekSize(t *testing.T) {
+	const path = "testdata/172960.flac"
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := flac.NewSeek(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readerAt, err := flac.NewSeekReaderAt(f, fi.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := flac.NewSeekSize(io.NewSectionReader(f, 0, fi.Size()), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inMemory, err := flac.NewSeek(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inMemory.DataStart() != want.DataStart() {
+		t.Errorf("%q: data start mismatch; expected %d, got %d", path, want.DataStart(), inMemory.DataStart())
+	}
+	for _, sampleNum := range []uint64{9000, 0, 40960} {
+		var hashes []uint32
+		for _, stream := range []*flac.Stream{want, readerAt, small, inMemory} {
+			if _, err := stream.Seek(sampleNum); err != nil {
+				t.Fatal(err)
+			}
+			frame, err := stream.ParseNext()
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := crc32.NewIEEE()
+			frame.Hash(h)
+			hashes = append(hashes, h.Sum32())
+		}
+		for i, h := range hashes[1:] {
+			if h != hashes[0] {
+				t.Errorf("%q: frame mismatch of stream %d at sample %d; expected %08X, got %08X", path, i+1, sampleNum, hashes[0], h)
+			}
+		}
+	}
+}
+
 func TestDecode(t *testing.T) {
 	paths := []string{
 		"meta/testdata/input-SCPAP.flac",
@@ -200,3 +262,249 @@ func TestDecode(t *testing.T) {
 		}
 	}
 }
+
+func TestStreamOffset(t *testing.T) {
+	paths := []string{
+		"testdata/19875.flac",
+		"testdata/id3.flac",
+		"testdata/love.flac",
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream, err := flac.New(bytes.NewReader(buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			seekStream, err := flac.NewSeek(bytes.NewReader(buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stream.DataStart() != seekStream.DataStart() {
+				t.Fatalf("data start mismatch; New reports %d, NewSeek reports %d", stream.DataStart(), seekStream.DataStart())
+			}
+			if stream.Offset() != stream.DataStart() {
+				t.Fatalf("offset mismatch before first frame; expected %d, got %d", stream.DataStart(), stream.Offset())
+			}
+			for {
+				want := seekStream.Offset()
+				if got := stream.Offset(); got != want {
+					t.Fatalf("offset mismatch; expected %d, got %d", want, got)
+				}
+				_, err := stream.ParseNext()
+				_, seekErr := seekStream.ParseNext()
+				if err != nil || seekErr != nil {
+					break
+				}
+				if stream.SamplePosition() != seekStream.SamplePosition() {
+					t.Fatalf("sample position mismatch; expected %d, got %d", seekStream.SamplePosition(), stream.SamplePosition())
+				}
+			}
+			if path != "testdata/id3.flac" {
+				if got, want := stream.Offset(), int64(len(buf)); got != want {
+					t.Errorf("offset mismatch at end of stream; expected %d, got %d", want, got)
+				}
+				if got, want := stream.SamplePosition(), stream.Info.NSamples; got != want {
+					t.Errorf("sample position mismatch at end of stream; expected %d, got %d", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var events []flac.Event
+	logger := flac.LoggerFunc(func(event flac.Event) {
+		events = append(events, event)
+	})
+	f, err := os.Open("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stream, err := flac.NewWithOptions(f, &flac.DecodeOptions{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nframes := 0
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		nframes++
+	}
+
+	var blocks, frames, ends []flac.Event
+	for _, event := range events {
+		switch event.Kind {
+		case flac.EventBlock:
+			blocks = append(blocks, event)
+		case flac.EventFrame:
+			frames = append(frames, event)
+		case flac.EventEndOfStream:
+			ends = append(ends, event)
+		default:
+			t.Errorf("unexpected event; %v", event)
+		}
+	}
+	// StreamInfo is not included in stream.Blocks.
+	if got, want := len(blocks), 1+len(stream.Blocks); got != want {
+		t.Fatalf("block event count mismatch; expected %d, got %d", want, got)
+	}
+	// The first metadata block follows the FLAC signature.
+	if got, want := blocks[0].Offset, int64(4); got != want {
+		t.Errorf("StreamInfo offset mismatch; expected %d, got %d", want, got)
+	}
+	if got, want := len(frames), nframes; got != want {
+		t.Fatalf("frame event count mismatch; expected %d, got %d", want, got)
+	}
+	if got, want := frames[0].Offset, stream.DataStart(); got != want {
+		t.Errorf("first frame offset mismatch; expected %d, got %d", want, got)
+	}
+	if len(ends) != 1 || ends[0].Err != nil {
+		t.Errorf("end of stream events mismatch; expected one graceful end, got %v", ends)
+	}
+}
+
+func TestDiscontinuity(t *testing.T) {
+	data, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := append(frameOffsets(t, data), int64(len(data)))
+	frameData := func(i int) []byte {
+		return data[offsets[i]:offsets[i+1]]
+	}
+	// Splice the stream; skip frame 3 and duplicate frame 6.
+	spliced := bytes.Clone(data[:offsets[0]])
+	for i := 0; i < len(offsets)-1; i++ {
+		switch i {
+		case 3:
+			continue
+		case 6:
+			spliced = append(spliced, frameData(i)...)
+		}
+		spliced = append(spliced, frameData(i)...)
+	}
+
+	var got []*flac.DiscontinuityError
+	logger := flac.LoggerFunc(func(event flac.Event) {
+		var e *flac.DiscontinuityError
+		if event.Kind == flac.EventWarning && errors.As(event.Err, &e) {
+			got = append(got, e)
+		}
+	})
+	stream, err := flac.NewWithOptions(bytes.NewReader(spliced), &flac.DecodeOptions{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+	}
+	const blockSize = 4096
+	want := []*flac.DiscontinuityError{
+		{Expected: 3 * blockSize, Got: 4 * blockSize},
+		{Expected: 7 * blockSize, Got: 6 * blockSize},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("discontinuity count mismatch; expected %d, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if *got[i] != *want[i] {
+			t.Errorf("discontinuity %d mismatch; expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestRepairTags(t *testing.T) {
+	info := &meta.StreamInfo{BlockSizeMin: 4096, BlockSizeMax: 4096, SampleRate: 44100, NChannels: 2, BitsPerSample: 16}
+	comment := &meta.VorbisComment{Vendor: "vendor", Tags: [][2]string{{"TITLE", "caf\xe9"}, {"ARTIST", "artist"}}}
+	buf := &bytes.Buffer{}
+	enc, err := flac.NewEncoder(buf, info, &meta.Block{Header: meta.Header{Type: meta.TypeVorbisComment, Length: 1}, Body: comment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var warnings []flac.Event
+	logger := flac.LoggerFunc(func(event flac.Event) {
+		if event.Kind == flac.EventWarning {
+			warnings = append(warnings, event)
+		}
+	})
+	stream, err := flac.NewWithOptions(bytes.NewReader(buf.Bytes()), &flac.DecodeOptions{RepairTags: true, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := stream.Blocks.VorbisComment()
+	if got == nil || got.Tags[0][1] != "café" || got.Tags[1][1] != "artist" {
+		t.Fatalf("repaired tags mismatch; got %v", got)
+	}
+	if len(warnings) != 1 || warnings[0].Block == nil {
+		t.Errorf("repair warnings mismatch; expected 1 warning, got %v", warnings)
+	}
+}
+
+func TestReadBufferSize(t *testing.T) {
+	data, err := os.ReadFile("testdata/172960.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 256 * 1024} {
+		r := &readSizes{r: bytes.NewReader(data)}
+		stream, err := flac.NewWithOptions(r, &flac.DecodeOptions{ReadBufferSize: size})
+		if err != nil {
+			t.Fatal(err)
+		}
+		md5sum := md5.New()
+		for {
+			f, err := stream.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+			f.Hash(md5sum)
+		}
+		if got := md5sum.Sum(nil); !bytes.Equal(got, stream.Info.MD5sum[:]) {
+			t.Errorf("size=%d: MD5 checksum mismatch; expected %x, got %x", size, stream.Info.MD5sum, got)
+		}
+		want := size
+		if want == 0 {
+			want = 4096
+		}
+		if r.max != want {
+			t.Errorf("size=%d: read size mismatch; expected %d, got %d", size, want, r.max)
+		}
+	}
+
+	if _, err := flac.NewWithOptions(bytes.NewReader(data), &flac.DecodeOptions{ReadBufferSize: -1}); err == nil {
+		t.Error("expected error for negative read buffer size")
+	}
+}
+
+// readSizes records the largest read of the underlying io.Reader.
+type readSizes struct {
+	r io.Reader
+	// Largest number of bytes requested by a read.
+	max int
+}
+
+// Read reads from the underlying io.Reader, and records the size of the read.
+func (r *readSizes) Read(p []byte) (int, error) {
+	r.max = max(r.max, len(p))
+	return r.r.Read(p)
+}
@@ -0,0 +1,275 @@
+// Package flachttp serves FLAC files over HTTP, with support for Range
+// requests and the stripping or replacement of metadata blocks on the fly; e.g.
+// to remove embedded pictures from streamed tracks to save bandwidth.
+//
+// The metadata of a served file is rewritten without re-encoding its audio
+// frames, which are served unchanged from the original file. The served file
+// is a virtual concatenation of the rewritten stream header and the audio
+// frames of the original file, as such the Content-Length of responses and
+// the byte ranges of Range requests refer to the rewritten file.
+//
+//	h := flachttp.NewHandler(os.DirFS("/srv/music"), &flachttp.Options{StripPictures: true})
+//	http.Handle("/music/", http.StripPrefix("/music/", h))
+package flachttp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+// Options specifies the metadata rewriting of served FLAC files. The zero value
+// serves FLAC files with their metadata blocks intact.
+type Options struct {
+	// StripPictures specifies whether to remove Picture metadata blocks.
+	StripPictures bool
+	// StripPadding specifies whether to remove Padding metadata blocks.
+	StripPadding bool
+	// Rewrite, if non-nil, returns the metadata blocks of the served file,
+	// excluding the StreamInfo block, given the request and the metadata blocks
+	// of the original file which remain after stripping; e.g. to replace the
+	// Vorbis comments of the served file. The given metadata blocks may be
+	// shared between requests, and must not be modified.
+	Rewrite func(r *http.Request, blocks []*meta.Block) ([]*meta.Block, error)
+	// ErrorLog, if non-nil, receives the errors of files which may not be
+	// served, such as invalid FLAC files, which are reported to clients
+	// without details. If nil, errors are logged using the log package's
+	// standard logger.
+	ErrorLog *log.Logger
+}
+
+// A Handler serves the FLAC files of a file system, using the given metadata
+// rewriting options. The files of the file system must implement io.ReaderAt,
+// as do the files of os.DirFS.
+//
+// The metadata blocks of served files are parsed once, and cached until the
+// size or modification time of the file changes.
+type Handler struct {
+	// File system of served files.
+	fsys fs.FS
+	// Metadata rewriting options.
+	opts Options
+	// Parsed stream headers of served files, by file name; protected by mu.
+	mu     sync.Mutex
+	cached map[string]*cachedHeader
+}
+
+// cachedHeader is a parsed stream header of a served file.
+type cachedHeader struct {
+	// Size and modification time of the file when parsed.
+	size    int64
+	modTime time.Time
+	// Parsed stream header.
+	hdr *header
+}
+
+// NewHandler returns a new handler which serves the FLAC files of fsys at the
+// URL path of each request, using the given options; a nil value specifies the
+// default options.
+func NewHandler(fsys fs.FS, opts *Options) *Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return &Handler{fsys: fsys, opts: *opts, cached: make(map[string]*cachedHeader)}
+}
+
+// ServeHTTP serves the FLAC file at the URL path of the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		h.logf("flachttp: unable to serve %q; file does not support random access", name)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	src := io.NewSectionReader(ra, 0, fi.Size())
+	hdr, err := h.header(name, fi, src)
+	if err != nil {
+		h.logf("flachttp: invalid FLAC file %q; %v", name, err)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	content, err := newContent(r, src, hdr, &h.opts)
+	if err != nil {
+		h.logf("flachttp: unable to rewrite metadata of %q; %v", name, err)
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/flac")
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), content)
+}
+
+// header returns the parsed stream header of the given served file, parsing the
+// file if not cached or if modified since cached.
+func (h *Handler) header(name string, fi fs.FileInfo, src *io.SectionReader) (*header, error) {
+	h.mu.Lock()
+	c, ok := h.cached[name]
+	h.mu.Unlock()
+	if ok && c.size == fi.Size() && c.modTime.Equal(fi.ModTime()) {
+		return c.hdr, nil
+	}
+	hdr, err := parseHeader(src, &h.opts)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	h.cached[name] = &cachedHeader{size: fi.Size(), modTime: fi.ModTime(), hdr: hdr}
+	h.mu.Unlock()
+	return hdr, nil
+}
+
+// logf logs the given error message to the error log of the handler.
+func (h *Handler) logf(format string, args ...interface{}) {
+	if h.opts.ErrorLog != nil {
+		h.opts.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// NewContent returns the content of the given FLAC file with its metadata
+// rewritten using the given options; a nil value specifies the default
+// options. The request is passed to the Rewrite option, and may be nil.
+//
+// The returned content may be served using http.ServeContent, which handles
+// Range requests and computes the Content-Length of the rewritten file.
+func NewContent(r *http.Request, src *io.SectionReader, opts *Options) (io.ReadSeeker, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	hdr, err := parseHeader(src, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newContent(r, src, hdr, opts)
+}
+
+// header is the parsed stream header of a FLAC file.
+type header struct {
+	// StreamInfo metadata block.
+	info *meta.StreamInfo
+	// Metadata blocks which remain after stripping, excluding StreamInfo.
+	blocks []*meta.Block
+	// Byte offset of the first audio frame.
+	dataStart int64
+}
+
+// parseHeader parses the stream header of the given FLAC file, and strips its
+// metadata blocks using the given options.
+func parseHeader(src *io.SectionReader, opts *Options) (*header, error) {
+	stream, err := flac.Parse(bufio.NewReader(io.NewSectionReader(src, 0, src.Size())))
+	if err != nil {
+		return nil, err
+	}
+	hdr := &header{info: stream.Info, dataStart: stream.DataStart()}
+	for _, block := range stream.Blocks {
+		switch {
+		case opts.StripPictures && block.Type == meta.TypePicture:
+		case opts.StripPadding && block.Type == meta.TypePadding:
+		case block.Type != meta.TypePadding && block.Body == nil:
+			// Metadata blocks of reserved types are not retained.
+		default:
+			hdr.blocks = append(hdr.blocks, block)
+		}
+	}
+	return hdr, nil
+}
+
+// newContent returns the content of the given FLAC file, with the parsed stream
+// header hdr, with its metadata rewritten using the given options.
+func newContent(r *http.Request, src *io.SectionReader, hdr *header, opts *Options) (io.ReadSeeker, error) {
+	blocks := hdr.blocks
+	if opts.Rewrite != nil {
+		var err error
+		if blocks, err = opts.Rewrite(r, slices.Clone(blocks)); err != nil {
+			return nil, err
+		}
+	}
+	data, err := flac.MarshalMetadata(hdr.info, blocks...)
+	if err != nil {
+		return nil, err
+	}
+	c := &content{
+		hdr:   data,
+		audio: io.NewSectionReader(src, hdr.dataStart, src.Size()-hdr.dataStart),
+	}
+	return c, nil
+}
+
+// content is the content of a FLAC file with rewritten metadata; the
+// concatenation of a stream header and the audio frames of the original file.
+type content struct {
+	// Rewritten stream header.
+	hdr []byte
+	// Audio frames of the original file.
+	audio *io.SectionReader
+	// Current read offset.
+	off int64
+}
+
+// size returns the size in bytes of the content.
+func (c *content) size() int64 {
+	return int64(len(c.hdr)) + c.audio.Size()
+}
+
+// Read reads up to len(p) bytes of the content into p.
+func (c *content) Read(p []byte) (n int, err error) {
+	if c.off >= c.size() {
+		return 0, io.EOF
+	}
+	if c.off < int64(len(c.hdr)) {
+		n = copy(p, c.hdr[c.off:])
+		c.off += int64(n)
+		return n, nil
+	}
+	n, err = c.audio.ReadAt(p, c.off-int64(len(c.hdr)))
+	c.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek sets the offset of the next Read, as specified by io.Seeker.
+func (c *content) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.off
+	case io.SeekEnd:
+		offset += c.size()
+	default:
+		return 0, errors.New("flachttp.content.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("flachttp.content.Seek: negative position")
+	}
+	c.off = offset
+	return offset, nil
+}
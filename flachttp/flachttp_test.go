@@ -0,0 +1,198 @@
+package flachttp_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/flachttp"
+	"github.com/mewkiz/flac/meta"
+)
+
+// writeTestFile writes a copy of love.flac with an embedded picture to dir,
+// and returns its contents.
+func writeTestFile(t *testing.T, dir string) []byte {
+	t.Helper()
+	orig, err := os.ReadFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.Parse(bytes.NewReader(orig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pic := &meta.Picture{Type: 3, MIME: "image/png", Data: bytes.Repeat([]byte{0xAB}, 1000)}
+	blocks := append([]*meta.Block{{Header: meta.Header{Type: meta.TypePicture, Length: 1}, Body: pic}}, stream.Blocks...)
+	hdr, err := flac.MarshalMetadata(stream.Info, blocks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(hdr, orig[stream.DataStart():]...)
+	if err := os.WriteFile(filepath.Join(dir, "love.flac"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// get performs a GET request of the given path, with the given Range header
+// if not empty.
+func get(t *testing.T, h http.Handler, path, rng string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+func TestHandler(t *testing.T) {
+	dir := t.TempDir()
+	data := writeTestFile(t, dir)
+	h := flachttp.NewHandler(os.DirFS(dir), nil)
+	resp := get(t, h, "/love.flac", "")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("response mismatch; status %d, %d bytes", resp.StatusCode, len(body))
+	}
+	if got := resp.Header.Get("Content-Type"); got != "audio/flac" {
+		t.Fatalf("Content-Type mismatch; expected audio/flac, got %q", got)
+	}
+	if resp := get(t, h, "/missing.flac", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status mismatch; expected %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestHandlerStripPictures(t *testing.T) {
+	dir := t.TempDir()
+	data := writeTestFile(t, dir)
+	h := flachttp.NewHandler(os.DirFS(dir), &flachttp.Options{StripPictures: true})
+	resp := get(t, h, "/love.flac", "")
+	body, _ := io.ReadAll(resp.Body)
+	if got, want := resp.Header.Get("Content-Length"), strconv.Itoa(len(body)); got != want {
+		t.Fatalf("Content-Length mismatch; expected %s, got %s", want, got)
+	}
+	stream, err := flac.Parse(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range stream.Blocks {
+		if block.Type == meta.TypePicture {
+			t.Fatalf("unexpected Picture metadata block")
+		}
+	}
+	orig, err := flac.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) >= len(data) || !bytes.Equal(body[stream.DataStart():], data[orig.DataStart():]) {
+		t.Fatalf("audio frames of stripped file differ from the original")
+	}
+
+	// Range request within the rewritten file.
+	resp = get(t, h, "/love.flac", "bytes=10-99")
+	part, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(part, body[10:100]) {
+		t.Fatalf("partial content mismatch; status %d", resp.StatusCode)
+	}
+	if got, want := resp.Header.Get("Content-Range"), fmt.Sprintf("bytes 10-99/%d", len(body)); got != want {
+		t.Fatalf("Content-Range mismatch; expected %q, got %q", want, got)
+	}
+	// Range request of the audio frames.
+	resp = get(t, h, "/love.flac", fmt.Sprintf("bytes=%d-", len(body)-50))
+	part, _ = io.ReadAll(resp.Body)
+	if !bytes.Equal(part, data[len(data)-50:]) {
+		t.Fatalf("partial content of audio frames mismatch")
+	}
+}
+
+func TestHandlerRewrite(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir)
+	rewrite := func(r *http.Request, blocks []*meta.Block) ([]*meta.Block, error) {
+		comment := &meta.VorbisComment{Vendor: "flachttp", Tags: [][2]string{{"TITLE", r.URL.Query().Get("title")}}}
+		var out []*meta.Block
+		for _, block := range blocks {
+			if block.Type == meta.TypeVorbisComment {
+				block = &meta.Block{Header: meta.Header{Type: meta.TypeVorbisComment, Length: 1}, Body: comment}
+			}
+			out = append(out, block)
+		}
+		return out, nil
+	}
+	h := flachttp.NewHandler(os.DirFS(dir), &flachttp.Options{StripPadding: true, Rewrite: rewrite})
+	resp := get(t, h, "/love.flac?title=Love", "")
+	body, _ := io.ReadAll(resp.Body)
+	stream, err := flac.Parse(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags [][2]string
+	for _, block := range stream.Blocks {
+		switch body := block.Body.(type) {
+		case *meta.VorbisComment:
+			tags = body.Tags
+		}
+		if block.Type == meta.TypePadding {
+			t.Fatalf("unexpected Padding metadata block")
+		}
+	}
+	if len(tags) != 1 || tags[0] != [2]string{"TITLE", "Love"} {
+		t.Fatalf("Vorbis comments mismatch; got %q", tags)
+	}
+}
+
+func TestHandlerModified(t *testing.T) {
+	dir := t.TempDir()
+	data := writeTestFile(t, dir)
+	h := flachttp.NewHandler(os.DirFS(dir), nil)
+	resp := get(t, h, "/love.flac", "")
+	body, _ := io.ReadAll(resp.Body)
+	if !bytes.Equal(body, data) {
+		t.Fatalf("response mismatch; expected %d bytes, got %d bytes", len(data), len(body))
+	}
+	// The cached metadata is invalidated once the file is modified.
+	orig, err := os.ReadFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "love.flac"), orig, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resp = get(t, h, "/love.flac", "")
+	body, _ = io.ReadAll(resp.Body)
+	if !bytes.Equal(body, orig) {
+		t.Fatalf("response mismatch of modified file; expected %d bytes, got %d bytes", len(orig), len(body))
+	}
+}
+
+func TestHandlerInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "invalid.flac"), []byte("not a FLAC file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	h := flachttp.NewHandler(os.DirFS(dir), &flachttp.Options{ErrorLog: log.New(buf, "", 0)})
+	resp := get(t, h, "/invalid.flac", "")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("status mismatch; expected %d, got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+	// The details of the error are logged, but not reported to the client.
+	if got, want := string(body), http.StatusText(http.StatusUnprocessableEntity)+"\n"; got != want {
+		t.Errorf("response body mismatch; expected %q, got %q", want, got)
+	}
+	if !strings.Contains(buf.String(), "invalid.flac") {
+		t.Errorf("error of invalid file not logged; got %q", buf.String())
+	}
+}
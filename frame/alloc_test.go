@@ -0,0 +1,71 @@
+package frame_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+// TestParseIntoAllocs verifies that decoding the Rice coded residuals of a
+// frame into the same frame does not allocate memory, once the buffers of the
+// frame have grown to hold the frame.
+func TestParseIntoAllocs(t *testing.T) {
+	paths := []string{
+		"../testdata/love.flac",
+		"../testdata/172960.flac",
+		"../testdata/19875.flac",
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := flac.New(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Locate the first frame holding Rice coded residuals.
+		rd := bytes.NewReader(data[stream.DataStart():])
+		f := new(frame.Frame)
+		var frameData []byte
+		for frameData == nil {
+			start := len(data) - rd.Len()
+			if err := frame.ParseInto(rd, f); err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatalf("%q: unable to parse frame; %v", path, err)
+			}
+			for _, subframe := range f.Subframes {
+				if subframe.RiceSubframe != nil && len(subframe.RiceSubframe.Partitions) > 0 {
+					frameData = data[start : len(data)-rd.Len()]
+					break
+				}
+			}
+		}
+		if frameData == nil {
+			t.Errorf("%q: no frame with Rice coded residuals", path)
+			continue
+		}
+
+		// Decode the frame repeatedly; the first call of AllocsPerRun is a
+		// warm-up run.
+		var parseErr error
+		allocs := testing.AllocsPerRun(10, func() {
+			rd.Reset(frameData)
+			if err := frame.ParseInto(rd, f); err != nil && parseErr == nil {
+				parseErr = err
+			}
+		})
+		if parseErr != nil {
+			t.Fatalf("%q: unable to parse frame; %v", path, parseErr)
+		}
+		if allocs != 0 {
+			t.Errorf("%q: allocations per frame mismatch; expected 0, got %v", path, allocs)
+		}
+	}
+}
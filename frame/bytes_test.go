@@ -0,0 +1,69 @@
+package frame_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestParseBytes(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.New(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hint := &frame.Header{SampleRate: stream.Info.SampleRate, BitsPerSample: stream.Info.BitsPerSample}
+	for {
+		want, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		// Round-trip the frame through MarshalFrame and ParseBytes.
+		data, err := flac.MarshalFrame(want)
+		if err != nil {
+			t.Fatalf("frame %d: %v", want.Num, err)
+		}
+		got, err := frame.ParseBytes(data, hint)
+		if err != nil {
+			t.Fatalf("frame %d: %v", want.Num, err)
+		}
+		if got.Header != want.Header {
+			t.Errorf("frame %d: header mismatch; expected %#v, got %#v", want.Num, want.Header, got.Header)
+		}
+		for i := range want.Subframes {
+			if !equalSamples(got.Subframes[i].Samples, want.Subframes[i].Samples) {
+				t.Errorf("frame %d: sample mismatch in channel %d", want.Num, i)
+			}
+		}
+		// Trailing data is rejected.
+		if _, err := frame.ParseBytes(append(data, 0), hint); err == nil {
+			t.Errorf("frame %d: expected error for trailing data", want.Num)
+		}
+		// Truncated data is rejected.
+		if _, err := frame.ParseBytes(data[:len(data)-1], hint); err != io.ErrUnexpectedEOF {
+			t.Errorf("frame %d: expected unexpected EOF for truncated data, got %v", want.Num, err)
+		}
+	}
+}
+
+func equalSamples(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
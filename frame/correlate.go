@@ -0,0 +1,87 @@
+package frame
+
+// Inter-channel decorrelation of stereo audio samples, as used by the channel
+// assignments ChannelsLeftSide, ChannelsSideRight and ChannelsMidSide. An
+// encoder decorrelates the left and right channels as follows:
+//
+//	mid = (left + right)>>1
+//	side = left - right
+//
+// The functions operate in place on the samples of two channels, which must be
+// of equal length; e.g. the samples of two subframes, as decoded by Frame.Parse
+// prior to inter-channel correlation. The side channel requires an extra bit
+// per sample; i.e. side samples of 32-bit audio do not fit in an int32.
+//
+// ref: https://www.xiph.org/flac/format.html#interchannel
+
+// CorrelateLeftSide reverts left/side decorrelation, replacing the side samples
+// with the right samples.
+func CorrelateLeftSide(left, side []int32) {
+	side = side[:len(left)]
+	for i, l := range left {
+		// right = left - side
+		side[i] = l - side[i]
+	}
+}
+
+// DecorrelateLeftSide performs left/side decorrelation, replacing the right
+// samples with the side samples.
+func DecorrelateLeftSide(left, right []int32) {
+	right = right[:len(left)]
+	for i, l := range left {
+		// side = left - right
+		right[i] = l - right[i]
+	}
+}
+
+// CorrelateSideRight reverts side/right decorrelation, replacing the side
+// samples with the left samples.
+func CorrelateSideRight(side, right []int32) {
+	right = right[:len(side)]
+	for i, s := range side {
+		// left = right + side
+		side[i] = right[i] + s
+	}
+}
+
+// DecorrelateSideRight performs side/right decorrelation, replacing the left
+// samples with the side samples.
+func DecorrelateSideRight(left, right []int32) {
+	right = right[:len(left)]
+	for i, l := range left {
+		// side = left - right
+		left[i] = l - right[i]
+	}
+}
+
+// CorrelateMidSide reverts mid/side decorrelation, replacing the mid samples
+// with the left samples and the side samples with the right samples.
+func CorrelateMidSide(mid, side []int32) {
+	side = side[:len(mid)]
+	for i, m := range mid {
+		// left = (2*mid + side)/2
+		// right = (2*mid - side)/2
+		s := side[i]
+		m *= 2
+		// Notice that the integer division in mid = (left + right)/2 discards
+		// the least significant bit. It can be reconstructed however, since a
+		// sum A+B and a difference A-B has the same least significant bit.
+		//
+		// ref: Data Compression: The Complete Reference (ch. 7, Decorrelation)
+		m |= s & 1
+		mid[i] = (m + s) / 2
+		side[i] = (m - s) / 2
+	}
+}
+
+// DecorrelateMidSide performs mid/side decorrelation, replacing the left
+// samples with the mid samples and the right samples with the side samples.
+func DecorrelateMidSide(left, right []int32) {
+	right = right[:len(left)]
+	for i, l := range left {
+		r := right[i]
+		// NOTE: using `(left + right) >> 1`, not the same as `(left + right) / 2`.
+		left[i] = int32((int64(l) + int64(r)) >> 1)
+		right[i] = l - r
+	}
+}
@@ -0,0 +1,52 @@
+package frame_test
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestCorrelate(t *testing.T) {
+	golden := []struct {
+		name        string
+		decorrelate func(left, right []int32)
+		correlate   func(a, b []int32)
+		// Decorrelated samples of left = {3, -7, 0} and right = {-2, -7, 1}.
+		a, b []int32
+	}{
+		{name: "left/side", decorrelate: frame.DecorrelateLeftSide, correlate: frame.CorrelateLeftSide, a: []int32{3, -7, 0}, b: []int32{5, 0, -1}},
+		{name: "side/right", decorrelate: frame.DecorrelateSideRight, correlate: frame.CorrelateSideRight, a: []int32{5, 0, -1}, b: []int32{-2, -7, 1}},
+		{name: "mid/side", decorrelate: frame.DecorrelateMidSide, correlate: frame.CorrelateMidSide, a: []int32{0, -7, 0}, b: []int32{5, 0, -1}},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for _, g := range golden {
+		left, right := []int32{3, -7, 0}, []int32{-2, -7, 1}
+		g.decorrelate(left, right)
+		if !slices.Equal(left, g.a) || !slices.Equal(right, g.b) {
+			t.Errorf("%s: decorrelated samples mismatch; expected %v and %v, got %v and %v", g.name, g.a, g.b, left, right)
+		}
+		g.correlate(left, right)
+		if !slices.Equal(left, []int32{3, -7, 0}) || !slices.Equal(right, []int32{-2, -7, 1}) {
+			t.Errorf("%s: correlated samples mismatch; got %v and %v", g.name, left, right)
+		}
+
+		// Round trip of random samples of 31 bits-per-sample, the largest sample
+		// size whose side channel fits in 32 bits; including the extremes.
+		const bps = 31
+		left = []int32{math.MinInt32 >> 1, math.MaxInt32 >> 1, math.MinInt32 >> 1, math.MaxInt32 >> 1}
+		right = []int32{math.MaxInt32 >> 1, math.MinInt32 >> 1, math.MinInt32 >> 1, math.MaxInt32 >> 1}
+		for i := 0; i < 1000; i++ {
+			left = append(left, int32(rnd.Int63n(1<<bps)-1<<(bps-1)))
+			right = append(right, int32(rnd.Int63n(1<<bps)-1<<(bps-1)))
+		}
+		wantLeft, wantRight := slices.Clone(left), slices.Clone(right)
+		g.decorrelate(left, right)
+		g.correlate(left, right)
+		if !slices.Equal(left, wantLeft) || !slices.Equal(right, wantRight) {
+			t.Errorf("%s: round trip of random samples mismatch", g.name)
+		}
+	}
+}
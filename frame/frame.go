@@ -27,6 +27,7 @@
 package frame
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -34,7 +35,7 @@ import (
 	"io"
 	"log"
 
-	"github.com/mewkiz/flac/internal/bits"
+	"github.com/mewkiz/flac/bits"
 	"github.com/mewkiz/flac/internal/hashutil"
 	"github.com/mewkiz/flac/internal/hashutil/crc16"
 	"github.com/mewkiz/flac/internal/hashutil/crc8"
@@ -51,12 +52,24 @@ type Frame struct {
 	Header
 	// One subframe per channel, containing encoded audio samples.
 	Subframes []*Subframe
+	// KeepResiduals specifies whether Parse retains the residuals of fixed and
+	// FIR linear prediction decoding in Subframe.Residuals, e.g. for analysis of
+	// predictor performance. Set KeepResiduals after parsing the frame header
+	// using New, and before calling Parse. KeepResiduals is retained by
+	// subsequent calls to NewInto and ParseInto with the same frame.
+	KeepResiduals bool
 	// CRC-16 hash sum, calculated by read operations on hr.
 	crc hashutil.Hash16
-	// A bit reader, wrapping read operations to hr.
+	// CRC-8 hash sum of the frame header, calculated by read operations on hhr.
+	headerCRC hashutil.Hash8
+	// A bit reader, wrapping read operations to hhr.
 	br *bits.Reader
 	// A CRC-16 hash reader, wrapping read operations to r.
-	hr io.Reader
+	hr hashReader
+	// A CRC-8 hash reader, wrapping read operations to hr.
+	hhr hashReader
+	// Temporary read buffer.
+	buf [2]byte
 	// Underlying io.Reader.
 	r io.Reader
 }
@@ -67,15 +80,29 @@ type Frame struct {
 //
 // Call Frame.Parse to parse the audio samples of its subframes.
 func New(r io.Reader) (frame *Frame, err error) {
+	frame = new(Frame)
+	err = NewInto(r, frame)
+	return frame, err
+}
+
+// NewInto is like New, but reads and parses the audio frame header into the
+// provided frame. The subframes and audio sample buffers of the frame are
+// reused by subsequent calls to Frame.Parse, thus avoiding allocations when
+// decoding a stream one frame at the time.
+func NewInto(r io.Reader, frame *Frame) error {
 	// Create a new CRC-16 hash reader which adds the data from all read
 	// operations to a running hash.
-	crc := crc16.NewIBM()
-	hr := io.TeeReader(r, crc)
+	if frame.crc == nil {
+		frame.crc = crc16.NewIBM()
+	} else {
+		frame.crc.Reset()
+	}
+	frame.hr = hashReader{r: r, h: frame.crc}
+	frame.r = r
 
 	// Parse frame header.
-	frame = &Frame{crc: crc, hr: hr, r: r}
-	err = frame.parseHeader()
-	return frame, err
+	frame.Header = Header{}
+	return frame.parseHeader()
 }
 
 // Parse reads and parses the header, and the audio samples from each subframe
@@ -96,62 +123,173 @@ func Parse(r io.Reader) (frame *Frame, err error) {
 	return frame, err
 }
 
+// ParseInto is like Parse, but reads and parses the frame into the provided
+// frame, reusing its subframes and audio sample buffers. As such, the audio
+// samples of a frame are only valid until the next call to ParseInto or
+// NewInto with the same frame.
+func ParseInto(r io.Reader, frame *Frame) error {
+	// Parse frame header.
+	if err := NewInto(r, frame); err != nil {
+		return err
+	}
+
+	// Parse subframes.
+	return frame.Parse()
+}
+
+// ParseBytes parses a single complete audio frame, including its header and
+// audio samples, from data. It enables decoding frames received through packet
+// based transports without access to a FLAC stream.
+//
+// The optional hdrHint specifies the sample rate and sample size of the stream,
+// which are used if the frame header leaves them unspecified (i.e. "get from
+// StreamInfo"); a nil hdrHint specifies no hint. The sample rate and sample size
+// of a StreamInfo metadata block may be passed as
+//
+//	&frame.Header{SampleRate: info.SampleRate, BitsPerSample: info.BitsPerSample}
+//
+// It is an error for data to hold any trailing bytes after the frame.
+func ParseBytes(data []byte, hdrHint *Header) (frame *Frame, err error) {
+	r := bytes.NewReader(data)
+	frame, err = New(r)
+	if err != nil {
+		return frame, unexpected(err)
+	}
+	if hdrHint != nil {
+		if frame.SampleRate == 0 {
+			frame.SampleRate = hdrHint.SampleRate
+		}
+		if frame.BitsPerSample == 0 {
+			frame.BitsPerSample = hdrHint.BitsPerSample
+		}
+	}
+	if frame.BitsPerSample == 0 {
+		return frame, errors.New("frame.ParseBytes: sample size not stored in frame header and no hint specified")
+	}
+	if err := frame.Parse(); err != nil {
+		return frame, err
+	}
+	if r.Len() != 0 {
+		return frame, fmt.Errorf("frame.ParseBytes: %d trailing bytes after frame", r.Len())
+	}
+	return frame, nil
+}
+
 // Parse reads and parses the audio samples from each subframe of the frame. If
 // the samples are inter-channel decorrelated between the subframes, it
 // correlates them.
 //
 // ref: https://www.xiph.org/flac/format.html#interchannel
 func (frame *Frame) Parse() error {
+	return frame.parse(-1, "frame.Frame.Parse")
+}
+
+// ParseChannel is like Parse, but only decodes the audio samples of the given
+// channel if its subframe is coded independently of the other channels; the
+// subframes of the other channels are skipped, as done by Frame.Skip, and hold
+// no audio samples. As such, mono analyses of stereo streams decode half of
+// the subframes.
+//
+// Channels are coded independently, unless inter-channel decorrelated; of the
+// decorrelated channel assignments, the left channel of left/side frames and
+// the right channel of side/right frames are coded independently. Otherwise,
+// e.g. for mid/side frames, all subframes are decoded as done by Parse.
+func (frame *Frame) ParseChannel(channel int) error {
+	if channel < 0 || channel >= frame.Channels.Count() {
+		return fmt.Errorf("frame.Frame.ParseChannel: invalid channel %d; expected >= 0 and < %d", channel, frame.Channels.Count())
+	}
+	if !frame.Channels.independent(channel) {
+		channel = -1
+	}
+	return frame.parse(channel, "frame.Frame.ParseChannel")
+}
+
+// parse reads and parses the subframes of the frame, decoding the audio
+// samples of the given channel, or of all channels if -1, and skipping the
+// subframes of the other channels. The given prefix is used for error
+// messages.
+func (frame *Frame) parse(only int, prefix string) error {
 	// Parse subframes.
-	frame.Subframes = make([]*Subframe, frame.Channels.Count())
-	var err error
+	nchannels := frame.Channels.Count()
+	if cap(frame.Subframes) < nchannels {
+		frame.Subframes = make([]*Subframe, nchannels)
+	}
+	frame.Subframes = frame.Subframes[:nchannels]
 	for channel := range frame.Subframes {
-		// The side channel requires an extra bit per sample when using
-		// inter-channel decorrelation.
-		bps := uint(frame.BitsPerSample)
-		switch frame.Channels {
-		case ChannelsSideRight:
-			// channel 0 is the side channel.
-			if channel == 0 {
-				bps++
-			}
-		case ChannelsLeftSide, ChannelsMidSide:
-			// channel 1 is the side channel.
-			if channel == 1 {
-				bps++
+		bps := frame.subframeBitsPerSample(channel)
+
+		// Parse subframe, reusing the subframe and its sample buffer if present.
+		subframe := frame.Subframes[channel]
+		if subframe == nil {
+			subframe = new(Subframe)
+			frame.Subframes[channel] = subframe
+		}
+		if only != -1 && channel != only {
+			if err := frame.skipSubframe(bps, subframe); err != nil {
+				return err
 			}
+			continue
 		}
-
-		// Parse subframe.
-		frame.Subframes[channel], err = frame.parseSubframe(frame.br, bps)
-		if err != nil {
+		if err := frame.parseSubframe(frame.br, bps, subframe); err != nil {
 			return err
 		}
 	}
 
 	// Inter-channel correlation of subframe samples.
-	frame.Correlate()
+	if only == -1 {
+		frame.Correlate()
+	}
+
+	return frame.checkCRC16(prefix)
+}
+
+// subframeBitsPerSample returns the bits-per-sample of the subframe of the
+// given channel.
+func (frame *Frame) subframeBitsPerSample(channel int) uint {
+	// The side channel requires an extra bit per sample when using
+	// inter-channel decorrelation.
+	bps := uint(frame.BitsPerSample)
+	switch frame.Channels {
+	case ChannelsSideRight:
+		// channel 0 is the side channel.
+		if channel == 0 {
+			bps++
+		}
+	case ChannelsLeftSide, ChannelsMidSide:
+		// channel 1 is the side channel.
+		if channel == 1 {
+			bps++
+		}
+	}
+	return bps
+}
 
+// checkCRC16 reads the CRC-16 checksum of the frame, and verifies it against
+// the bytes read since the start of the frame. The given prefix is used for
+// error messages.
+func (frame *Frame) checkCRC16(prefix string) error {
 	// 2 bytes: CRC-16 checksum.
-	var want uint16
-	if err = binary.Read(frame.r, binary.BigEndian, &want); err != nil {
+	if _, err := io.ReadFull(frame.r, frame.buf[:2]); err != nil {
 		return unexpected(err)
 	}
+	want := binary.BigEndian.Uint16(frame.buf[:2])
 	got := frame.crc.Sum16()
 	if got != want {
-		return fmt.Errorf("frame.Frame.Parse: CRC-16 checksum mismatch; expected 0x%04X, got 0x%04X", want, got)
+		return fmt.Errorf("%s: CRC-16 checksum mismatch; expected 0x%04X, got 0x%04X", prefix, want, got)
 	}
-
 	return nil
 }
 
-// Hash adds the decoded audio samples of the frame to a running MD5 hash. It
-// can be used in conjunction with StreamInfo.MD5sum to verify the integrity of
-// the decoded audio samples.
+// Hash adds the decoded audio samples of the frame to a running hash, in the
+// canonical byte representation of the MD5 checksum of StreamInfo; i.e.
+// interleaved little-endian two's complement integers of the smallest number of
+// bytes holding the bits-per-sample. Using an MD5 hash, it can be used in
+// conjunction with StreamInfo.MD5sum to verify the integrity of the decoded
+// audio samples; other hashes (e.g. SHA-256) checksum the same bytes.
 //
 // Note: The audio samples of the frame must be decoded before calling Hash.
 func (frame *Frame) Hash(md5sum hash.Hash) {
-	// Write decoded samples to a running MD5 hash.
+	// Write decoded samples to a running hash.
 	bps := frame.BitsPerSample
 	var buf [4]byte
 	if len(frame.Subframes) == 0 {
@@ -188,6 +326,59 @@ func (frame *Frame) Hash(md5sum hash.Hash) {
 	}
 }
 
+// InterleaveInt16 writes the decoded audio samples of the frame, starting at
+// the given sample number within the frame, to dst as interleaved 16-bit
+// samples; e.g. for playback of CD audio. It returns the number of samples per
+// channel written, which is limited by the remaining samples of the frame and
+// the capacity of dst; only complete sample frames (one sample per channel) are
+// written.
+//
+// The samples are narrowed and interleaved in a single pass, without an
+// interleaved 32-bit intermediate. It is an error for the frame to hold samples
+// of more than 16 bits-per-sample.
+//
+// Note: The audio samples of the frame must be decoded before calling
+// InterleaveInt16.
+func (frame *Frame) InterleaveInt16(dst []int16, pos int) (int, error) {
+	if frame.BitsPerSample > 16 {
+		return 0, fmt.Errorf("frame.Frame.InterleaveInt16: unable to represent %d-bit samples as 16-bit samples", frame.BitsPerSample)
+	}
+	nchannels := len(frame.Subframes)
+	if nchannels == 0 {
+		return 0, nil
+	}
+	nsamples := len(frame.Subframes[0].Samples) - pos
+	if nsamples <= 0 {
+		return 0, nil
+	}
+	nsamples = min(nsamples, len(dst)/nchannels)
+	switch nchannels {
+	case 1:
+		src := frame.Subframes[0].Samples[pos : pos+nsamples]
+		dst := dst[:len(src)]
+		for i, sample := range src {
+			dst[i] = int16(sample)
+		}
+	case 2:
+		left := frame.Subframes[0].Samples[pos : pos+nsamples]
+		right := frame.Subframes[1].Samples[pos : pos+nsamples]
+		right = right[:len(left)]
+		dst := dst[:2*len(left)]
+		for i, sample := range left {
+			dst[2*i] = int16(sample)
+			dst[2*i+1] = int16(right[i])
+		}
+	default:
+		for channel, subframe := range frame.Subframes {
+			src := subframe.Samples[pos : pos+nsamples]
+			for i, sample := range src {
+				dst[i*nchannels+channel] = int16(sample)
+			}
+		}
+	}
+	return nsamples, nil
+}
+
 // A Header contains the basic properties of an audio frame, such as its sample
 // rate and channel count. To facilitate random access decoding each frame
 // header starts with a sync-code. This allows the decoder to synchronize and
@@ -225,12 +416,21 @@ var (
 func (frame *Frame) parseHeader() error {
 	// Create a new CRC-8 hash reader which adds the data from all read
 	// operations to a running hash.
-	h := crc8.NewATM()
-	hr := io.TeeReader(frame.hr, h)
+	if frame.headerCRC == nil {
+		frame.headerCRC = crc8.NewATM()
+	} else {
+		frame.headerCRC.Reset()
+	}
+	frame.hhr = hashReader{r: &frame.hr, h: frame.headerCRC}
+	hr := &frame.hhr
 
 	// Create bit reader.
-	br := bits.NewReader(hr)
-	frame.br = br
+	if frame.br == nil {
+		frame.br = bits.NewReader(hr)
+	} else {
+		frame.br.Reset(hr)
+	}
+	br := frame.br
 
 	// 14 bits: sync-code (11111111111110)
 	x, err := br.Read(14)
@@ -249,7 +449,7 @@ func (frame *Frame) parseHeader() error {
 		return unexpected(err)
 	}
 	if x != 0 {
-		return errors.New("frame.Frame.parseHeader: non-zero reserved value")
+		return &HeaderError{Rule: RuleReservedBit, Value: x}
 	}
 
 	// 1 bit: HasFixedBlockSize.
@@ -291,7 +491,7 @@ func (frame *Frame) parseHeader() error {
 		return unexpected(err)
 	}
 	if x != 0 {
-		return errors.New("frame.Frame.parseHeader: non-zero reserved value")
+		return &HeaderError{Rule: RuleReservedBit, Value: x}
 	}
 
 	// if (fixed block size)
@@ -314,11 +514,11 @@ func (frame *Frame) parseHeader() error {
 	}
 
 	// 1 byte: CRC-8 checksum.
-	var want uint8
-	if err = binary.Read(frame.hr, binary.BigEndian, &want); err != nil {
+	want, err := frame.hr.ReadByte()
+	if err != nil {
 		return unexpected(err)
 	}
-	got := h.Sum8()
+	got := frame.headerCRC.Sum8()
 	if want != got {
 		return fmt.Errorf("frame.Frame.parseHeader: CRC-8 checksum mismatch; expected 0x%02X, got 0x%02X", want, got)
 	}
@@ -366,7 +566,7 @@ func (frame *Frame) parseBitsPerSample(br *bits.Reader) error {
 		frame.BitsPerSample = 32
 	default:
 		// 011: reserved.
-		return fmt.Errorf("frame.Frame.parseHeader: reserved sample size bit pattern (%03b)", x)
+		return &HeaderError{Rule: RuleReservedBitsPerSample, Value: x}
 	}
 	return nil
 }
@@ -396,7 +596,7 @@ func (frame *Frame) parseChannels(br *bits.Reader) error {
 		return unexpected(err)
 	}
 	if x >= 0xB {
-		return fmt.Errorf("frame.Frame.parseHeader: reserved channels bit pattern (%04b)", x)
+		return &HeaderError{Rule: RuleReservedChannels, Value: x}
 	}
 	frame.Channels = Channels(x)
 	return nil
@@ -415,7 +615,7 @@ func (frame *Frame) parseBlockSize(br *bits.Reader, blockSize uint64) error {
 	switch {
 	case n == 0x0:
 		// 0000: reserved.
-		return errors.New("frame.Frame.parseHeader: reserved block size bit pattern (0000)")
+		return &HeaderError{Rule: RuleReservedBlockSize, Value: n}
 	case n == 0x1:
 		// 0001: 192 samples.
 		frame.BlockSize = 192
@@ -435,6 +635,10 @@ func (frame *Frame) parseBlockSize(br *bits.Reader, blockSize uint64) error {
 		if err != nil {
 			return unexpected(err)
 		}
+		if x+1 > MaxBlockSize {
+			// A block size of 65536 samples is forbidden.
+			return &HeaderError{Rule: RuleBlockSize, Value: x + 1}
+		}
 		frame.BlockSize = uint16(x + 1)
 	default:
 		//    1000-1111: 256 * 2^(n-8) samples.
@@ -525,7 +729,7 @@ func (frame *Frame) parseSampleRate(br *bits.Reader, sampleRate uint64) error {
 		frame.SampleRate = uint32(x * 10)
 	default:
 		// 1111: invalid.
-		return errors.New("frame.Frame.parseHeader: invalid sample rate bit pattern (1111)")
+		return &HeaderError{Rule: RuleForbiddenSampleRate, Value: sampleRate}
 	}
 	return nil
 }
@@ -584,8 +788,23 @@ func (channels Channels) Count() int {
 	return nChannels[channels]
 }
 
+// independent reports whether the given channel is coded independently of the
+// other channels by the channel assignment; i.e. without inter-channel
+// decorrelation.
+func (channels Channels) independent(channel int) bool {
+	switch channels {
+	case ChannelsLeftSide:
+		return channel == 0
+	case ChannelsSideRight:
+		return channel == 1
+	case ChannelsMidSide:
+		return false
+	}
+	return true
+}
+
 // Correlate reverts any inter-channel decorrelation between the samples of the
-// subframes.
+// subframes. See CorrelateLeftSide, CorrelateSideRight and CorrelateMidSide.
 //
 // An encoder decorrelates audio samples as follows:
 //
@@ -595,44 +814,19 @@ func (frame *Frame) Correlate() {
 	switch frame.Channels {
 	case ChannelsLeftSide:
 		// 2 channels: left, side; using inter-channel decorrelation.
-		left := frame.Subframes[0].Samples
-		side := frame.Subframes[1].Samples
-		for i := range side {
-			// right = left - side
-			side[i] = left[i] - side[i]
-		}
+		CorrelateLeftSide(frame.Subframes[0].Samples, frame.Subframes[1].Samples)
 	case ChannelsSideRight:
 		// 2 channels: side, right; using inter-channel decorrelation.
-		side := frame.Subframes[0].Samples
-		right := frame.Subframes[1].Samples
-		for i := range side {
-			// left = right + side
-			side[i] = right[i] + side[i]
-		}
+		CorrelateSideRight(frame.Subframes[0].Samples, frame.Subframes[1].Samples)
 	case ChannelsMidSide:
 		// 2 channels: mid, side; using inter-channel decorrelation.
-		mid := frame.Subframes[0].Samples
-		side := frame.Subframes[1].Samples
-		for i := range side {
-			// left = (2*mid + side)/2
-			// right = (2*mid - side)/2
-			m := mid[i]
-			s := side[i]
-			m *= 2
-			// Notice that the integer division in mid = (left + right)/2 discards
-			// the least significant bit. It can be reconstructed however, since a
-			// sum A+B and a difference A-B has the same least significant bit.
-			//
-			// ref: Data Compression: The Complete Reference (ch. 7, Decorrelation)
-			m |= s & 1
-			mid[i] = (m + s) / 2
-			side[i] = (m - s) / 2
-		}
+		CorrelateMidSide(frame.Subframes[0].Samples, frame.Subframes[1].Samples)
 	}
 }
 
 // Decorrelate performs inter-channel decorrelation between the samples of the
-// subframes.
+// subframes. See DecorrelateLeftSide, DecorrelateSideRight and
+// DecorrelateMidSide.
 //
 // An encoder decorrelates audio samples as follows:
 //
@@ -642,47 +836,23 @@ func (frame *Frame) Decorrelate() {
 	switch frame.Channels {
 	case ChannelsLeftSide:
 		// 2 channels: left, side; using inter-channel decorrelation.
-		left := frame.Subframes[0].Samples  // already left; no change after inter-channel decorrelation.
-		right := frame.Subframes[1].Samples // set to side after inter-channel decorrelation.
-		for i := range left {
-			l := left[i]
-			r := right[i]
-			// inter-channel decorrelation:
-			//	side = left - right
-			side := l - r
-			right[i] = side
-		}
+		DecorrelateLeftSide(frame.Subframes[0].Samples, frame.Subframes[1].Samples)
 	case ChannelsSideRight:
 		// 2 channels: side, right; using inter-channel decorrelation.
-		left := frame.Subframes[0].Samples  // set to side after inter-channel decorrelation.
-		right := frame.Subframes[1].Samples // already right; no change after inter-channel decorrelation.
-		for i := range left {
-			l := left[i]
-			r := right[i]
-			// inter-channel decorrelation:
-			//	side = left - right
-			side := l - r
-			left[i] = side
-		}
+		DecorrelateSideRight(frame.Subframes[0].Samples, frame.Subframes[1].Samples)
 	case ChannelsMidSide:
 		// 2 channels: mid, side; using inter-channel decorrelation.
-		left := frame.Subframes[0].Samples  // set to mid after inter-channel decorrelation.
-		right := frame.Subframes[1].Samples // set to side after inter-channel decorrelation.
-		for i := range left {
-			// inter-channel decorrelation:
-			//	mid = (left + right)/2
-			//	side = left - right
-			l := left[i]
-			r := right[i]
-			mid := int32((int64(l) + int64(r)) >> 1) // NOTE: using `(left + right) >> 1`, not the same as `(left + right) / 2`.
-			side := l - r
-			left[i] = mid
-			right[i] = side
-		}
+		DecorrelateMidSide(frame.Subframes[0].Samples, frame.Subframes[1].Samples)
 	}
 }
 
 // SampleNumber returns the first sample number contained within the frame.
+//
+// The frame header of a fixed-blocksize stream stores the frame number, from
+// which the sample number is derived using the block size of the frame. As the
+// last frame of a fixed-blocksize stream may hold fewer samples, its sample
+// number is only accurate if derived using the block size of the preceding
+// frames, as is done by flac.Stream.
 func (frame *Frame) SampleNumber() uint64 {
 	if frame.HasFixedBlockSize {
 		return frame.Num * uint64(frame.BlockSize)
@@ -690,6 +860,36 @@ func (frame *Frame) SampleNumber() uint64 {
 	return frame.Num
 }
 
+// hashReader wraps read operations to r, adding the data from all read
+// operations to the running hash h.
+type hashReader struct {
+	// Underlying io.Reader.
+	r io.Reader
+	// Running hash.
+	h hash.Hash
+	// Temporary read buffer.
+	buf [1]byte
+}
+
+// Read reads up to len(p) bytes into p, and adds the bytes read to the running
+// hash.
+func (hr *hashReader) Read(p []byte) (n int, err error) {
+	n, err = hr.r.Read(p)
+	if n > 0 {
+		hr.h.Write(p[:n])
+	}
+	return n, err
+}
+
+// ReadByte reads and returns the next byte, and adds it to the running hash.
+func (hr *hashReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(hr.r, hr.buf[:]); err != nil {
+		return 0, err
+	}
+	hr.h.Write(hr.buf[:])
+	return hr.buf[0], nil
+}
+
 // unexpected returns io.ErrUnexpectedEOF if err is io.EOF, and returns err
 // otherwise.
 func unexpected(err error) error {
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
 )
 
 var golden = []struct {
@@ -133,6 +134,65 @@ func TestFrameHash(t *testing.T) {
 	}
 }
 
+func TestKeepResiduals(t *testing.T) {
+	paths := []string{
+		"../testdata/love.flac",
+		"../testdata/172960.flac",
+	}
+	for _, path := range paths {
+		stream, err := flac.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for frameNum := 0; ; frameNum++ {
+			f, err := stream.Next()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+			f.KeepResiduals = true
+			if err := f.Parse(); err != nil {
+				t.Fatal(err)
+			}
+			// Verify the residuals against the prediction of the decorrelated
+			// audio samples.
+			f.Decorrelate()
+			for i, subframe := range f.Subframes {
+				var coeffs []int32
+				var shift int32
+				switch subframe.Pred {
+				case frame.PredFixed:
+					coeffs = frame.FixedCoeffs[subframe.Order]
+				case frame.PredFIR:
+					coeffs, shift = subframe.Coeffs, subframe.CoeffShift
+				default:
+					if len(subframe.Residuals) != 0 {
+						t.Errorf("%q: frame %d, subframe %d: unexpected residuals of %v subframe", path, frameNum, i, subframe.Pred)
+					}
+					continue
+				}
+				if len(subframe.Residuals) != subframe.NSamples-subframe.Order {
+					t.Fatalf("%q: frame %d, subframe %d: residual count mismatch; expected %d, got %d", path, frameNum, i, subframe.NSamples-subframe.Order, len(subframe.Residuals))
+				}
+				samples := subframe.Samples
+				for j := subframe.Order; j < subframe.NSamples; j++ {
+					var pred int64
+					for k, c := range coeffs {
+						pred += int64(c) * int64(samples[j-k-1]>>subframe.Wasted)
+					}
+					want := samples[j]>>subframe.Wasted - int32(pred>>uint(shift))
+					if got := subframe.Residuals[j-subframe.Order]; got != want {
+						t.Fatalf("%q: frame %d, subframe %d: residual %d mismatch; expected %d, got %d", path, frameNum, i, j, want, got)
+					}
+				}
+			}
+		}
+		stream.Close()
+	}
+}
+
 func BenchmarkFrameParse(b *testing.B) {
 	// The file 151185.flac is a 119.5 MB public domain FLAC file used to
 	// benchmark the flac library. Because of its size, it has not been included
@@ -0,0 +1,74 @@
+package frame
+
+import "fmt"
+
+// A HeaderRule identifies a rule of the FLAC format for frame headers.
+type HeaderRule uint8
+
+// Rules of the FLAC format for frame headers.
+//
+// The reserved and forbidden bit patterns, the reserved bits and the range of
+// the block size are validated when parsing a frame header. The block size of
+// all but the last frame, and the range of frame numbers, depend on the
+// surrounding stream; they are validated in the strict mode of the decoder (see
+// flac.DecodeOptions.Strict).
+//
+// ref: https://www.rfc-editor.org/rfc/rfc9639.html#name-frame-header
+const (
+	// RuleReservedBit requires the reserved bits of the frame header to be zero.
+	RuleReservedBit HeaderRule = iota + 1
+	// RuleReservedBlockSize forbids the reserved block size bit pattern (0000).
+	RuleReservedBlockSize
+	// RuleForbiddenSampleRate forbids the sample rate bit pattern (1111), which
+	// prevents emulation of the sync-code.
+	RuleForbiddenSampleRate
+	// RuleReservedBitsPerSample forbids the reserved sample size bit pattern
+	// (011), and the 32 bits-per-sample bit pattern (111) of the classic
+	// specification (see Header.CheckSpec).
+	RuleReservedBitsPerSample
+	// RuleReservedChannels forbids the reserved channels bit patterns (1011
+	// through 1111).
+	RuleReservedChannels
+	// RuleBlockSize requires block sizes between MinBlockSize and MaxBlockSize
+	// samples; only the last frame of a stream may hold fewer samples.
+	RuleBlockSize
+	// RuleFrameNumber requires frame numbers of fixed-blocksize streams to fit
+	// in 31 bits; i.e. to be encoded in at most 6 bytes, rather than the 7 bytes
+	// permitted for sample numbers of variable-blocksize streams.
+	RuleFrameNumber
+)
+
+// A HeaderError reports a frame header which violates a rule of the FLAC
+// format.
+type HeaderError struct {
+	// Violated rule.
+	Rule HeaderRule
+	// Offending value; i.e. the bit pattern of the field for reserved and
+	// forbidden bit patterns, the block size for RuleBlockSize, and the frame
+	// number for RuleFrameNumber.
+	Value uint64
+}
+
+// Error returns the error message of the invalid frame header.
+func (e *HeaderError) Error() string {
+	var msg string
+	switch e.Rule {
+	case RuleReservedBit:
+		msg = "non-zero reserved value"
+	case RuleReservedBlockSize:
+		msg = "reserved block size bit pattern (0000)"
+	case RuleForbiddenSampleRate:
+		msg = "invalid sample rate bit pattern (1111)"
+	case RuleReservedBitsPerSample:
+		msg = fmt.Sprintf("reserved sample size bit pattern (%03b)", e.Value)
+	case RuleReservedChannels:
+		msg = fmt.Sprintf("reserved channels bit pattern (%04b)", e.Value)
+	case RuleBlockSize:
+		msg = fmt.Sprintf("block size (%d) outside of range [%d, %d]; only the last frame may hold fewer samples", e.Value, MinBlockSize, MaxBlockSize)
+	case RuleFrameNumber:
+		msg = fmt.Sprintf("frame number (%d) exceeds 31 bits", e.Value)
+	default:
+		msg = fmt.Sprintf("violation of rule %d", e.Rule)
+	}
+	return "frame: invalid frame header; " + msg
+}
@@ -0,0 +1,53 @@
+package frame_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mewkiz/flac/bits"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestHeaderError(t *testing.T) {
+	// Frame header of the first frame of love.flac; fixed-blocksize, 4096
+	// samples (1100), 44.1 kHz (1001), 2 channels (0001), 16 bits-per-sample
+	// (100), frame number 0. The CRC-8 is recomputed for each damaged header.
+	hdr := []byte{0xFF, 0xF8, 0xC9, 0x18, 0x00}
+	golden := []struct {
+		name  string
+		hdr   []byte
+		rule  frame.HeaderRule
+		value uint64
+	}{
+		{name: "reserved bit after sync-code", hdr: []byte{0xFF, 0xFA, 0xC9, 0x18, 0x00}, rule: frame.RuleReservedBit, value: 1},
+		{name: "reserved bit after sample size", hdr: []byte{0xFF, 0xF8, 0xC9, 0x19, 0x00}, rule: frame.RuleReservedBit, value: 1},
+		{name: "reserved block size", hdr: []byte{0xFF, 0xF8, 0x09, 0x18, 0x00}, rule: frame.RuleReservedBlockSize, value: 0x0},
+		{name: "forbidden sample rate", hdr: []byte{0xFF, 0xF8, 0xCF, 0x18, 0x00}, rule: frame.RuleForbiddenSampleRate, value: 0xF},
+		{name: "reserved sample size", hdr: []byte{0xFF, 0xF8, 0xC9, 0x16, 0x00}, rule: frame.RuleReservedBitsPerSample, value: 0x3},
+		{name: "reserved channels", hdr: []byte{0xFF, 0xF8, 0xC9, 0xB8, 0x00}, rule: frame.RuleReservedChannels, value: 0xB},
+		// Uncommon 16-bit block size of 65536 samples (0111).
+		{name: "block size 65536", hdr: []byte{0xFF, 0xF8, 0x79, 0x18, 0x00, 0xFF, 0xFF}, rule: frame.RuleBlockSize, value: 65536},
+	}
+	if _, err := frame.New(bytes.NewReader(appendCRC8(hdr))); err != nil {
+		t.Fatalf("unable to parse valid frame header; %v", err)
+	}
+	for _, g := range golden {
+		_, err := frame.New(bytes.NewReader(appendCRC8(g.hdr)))
+		var e *frame.HeaderError
+		if !errors.As(err, &e) {
+			t.Errorf("%s: expected *frame.HeaderError, got %v", g.name, err)
+			continue
+		}
+		if e.Rule != g.rule || e.Value != g.value {
+			t.Errorf("%s: rule mismatch; expected rule %d of value %d, got rule %d of value %d", g.name, g.rule, g.value, e.Rule, e.Value)
+		}
+	}
+}
+
+// appendCRC8 returns the given frame header followed by its CRC-8 checksum.
+func appendCRC8(hdr []byte) []byte {
+	h := bits.NewCRC8()
+	h.Write(hdr)
+	return append(bytes.Clone(hdr), h.Sum8())
+}
@@ -0,0 +1,133 @@
+package frame
+
+import (
+	mathbits "math/bits"
+)
+
+// maxLPCOrder specifies the maximum order of linear predictors.
+const maxLPCOrder = 32
+
+// restoreLPC restores the linear prediction coded audio samples in place; i.e.
+// adds the prediction of the preceding samples, using the given coefficients
+// and shift, to each residual of samples[len(coeffs):]. The first len(coeffs)
+// samples are warm-up samples. The order of the predictor, len(coeffs), is at
+// most 32.
+//
+// The prediction is computed using 32-bit arithmetic if it cannot overflow for
+// samples of the given bits-per-sample, as determined by the magnitude of the
+// coefficients; and using 64-bit arithmetic otherwise. Both yield identical
+// results for samples within range of bps.
+func restoreLPC(samples, coeffs []int32, shift, bps uint) {
+	// |prediction| <= 2^(bps-1) * sum(|c|) < 2^(bps-1+len(sum(|c|))).
+	var sum uint64
+	for _, c := range coeffs {
+		if c < 0 {
+			sum += uint64(-int64(c))
+		} else {
+			sum += uint64(c)
+		}
+	}
+	if bps+uint(mathbits.Len64(sum)) <= 32 {
+		restoreLPC32(samples, coeffs, shift)
+		return
+	}
+	restoreLPC64(samples, coeffs, shift)
+}
+
+// restoreLPC32 restores the linear prediction coded audio samples in place,
+// using 32-bit arithmetic. See restoreLPC.
+func restoreLPC32(samples, coeffs []int32, shift uint) {
+	order := len(coeffs)
+	if len(samples) <= order {
+		return
+	}
+	// Low orders (e.g. fixed predictors) carry the preceding samples in
+	// registers.
+	switch order {
+	case 0:
+		return
+	case 1:
+		c0 := coeffs[0]
+		s1 := samples[0]
+		for i := 1; i < len(samples); i++ {
+			s1 = samples[i] + (c0*s1)>>shift
+			samples[i] = s1
+		}
+		return
+	case 2:
+		c0, c1 := coeffs[0], coeffs[1]
+		s2, s1 := samples[0], samples[1]
+		for i := 2; i < len(samples); i++ {
+			s := samples[i] + (c0*s1+c1*s2)>>shift
+			samples[i] = s
+			s2, s1 = s1, s
+		}
+		return
+	case 3:
+		c0, c1, c2 := coeffs[0], coeffs[1], coeffs[2]
+		s3, s2, s1 := samples[0], samples[1], samples[2]
+		for i := 3; i < len(samples); i++ {
+			s := samples[i] + (c0*s1+c1*s2+c2*s3)>>shift
+			samples[i] = s
+			s3, s2, s1 = s2, s1, s
+		}
+		return
+	case 4:
+		c0, c1, c2, c3 := coeffs[0], coeffs[1], coeffs[2], coeffs[3]
+		s4, s3, s2, s1 := samples[0], samples[1], samples[2], samples[3]
+		for i := 4; i < len(samples); i++ {
+			s := samples[i] + (c0*s1+c1*s2+c2*s3+c3*s4)>>shift
+			samples[i] = s
+			s4, s3, s2, s1 = s3, s2, s1, s
+		}
+		return
+	}
+
+	// Higher orders compute the prediction as the dot product of the reversed
+	// coefficients and the window of preceding samples, in blocks of 4.
+	var buf [maxLPCOrder]int32
+	rev := buf[:order]
+	for j, c := range coeffs {
+		rev[order-1-j] = c
+	}
+	for i := order; i < len(samples); i++ {
+		window := samples[i-order : i]
+		window = window[:len(rev)]
+		var pred int32
+		j := 0
+		for ; j+4 <= len(rev); j += 4 {
+			pred += rev[j]*window[j] + rev[j+1]*window[j+1] + rev[j+2]*window[j+2] + rev[j+3]*window[j+3]
+		}
+		for ; j < len(rev); j++ {
+			pred += rev[j] * window[j]
+		}
+		samples[i] += pred >> shift
+	}
+}
+
+// restoreLPC64 restores the linear prediction coded audio samples in place,
+// using 64-bit arithmetic. See restoreLPC.
+func restoreLPC64(samples, coeffs []int32, shift uint) {
+	order := len(coeffs)
+	if len(samples) <= order {
+		return
+	}
+	var buf [maxLPCOrder]int64
+	rev := buf[:order]
+	for j, c := range coeffs {
+		rev[order-1-j] = int64(c)
+	}
+	for i := order; i < len(samples); i++ {
+		window := samples[i-order : i]
+		window = window[:len(rev)]
+		var pred int64
+		j := 0
+		for ; j+4 <= len(rev); j += 4 {
+			pred += rev[j]*int64(window[j]) + rev[j+1]*int64(window[j+1]) + rev[j+2]*int64(window[j+2]) + rev[j+3]*int64(window[j+3])
+		}
+		for ; j < len(rev); j++ {
+			pred += rev[j] * int64(window[j])
+		}
+		samples[i] += int32(pred >> shift)
+	}
+}
@@ -0,0 +1,107 @@
+package frame_test
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestDecodeFIR(t *testing.T) {
+	const nsamples = 1024
+	rnd := rand.New(rand.NewSource(1))
+	golden := []struct {
+		bps  uint8
+		prec uint
+	}{
+		// Predictions computed using 32-bit arithmetic.
+		{bps: 16, prec: 12},
+		// Predictions computed using 64-bit arithmetic.
+		{bps: 24, prec: 15},
+	}
+	for _, g := range golden {
+		info := &meta.StreamInfo{
+			BlockSizeMin:  nsamples,
+			BlockSizeMax:  nsamples,
+			SampleRate:    44100,
+			NChannels:     1,
+			BitsPerSample: g.bps,
+		}
+		buf := &bytes.Buffer{}
+		enc, err := flac.NewEncoder(buf, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want [][]int32
+		for order := 1; order <= 32; order++ {
+			// Second order predictor of a sine wave, with small coefficients of
+			// the remaining orders.
+			shift := int32(g.prec - 2)
+			coeffs := make([]int32, order)
+			coeffs[0] = 1 << shift
+			if order > 1 {
+				coeffs[0], coeffs[1] = 2<<shift-1, -1<<shift
+			}
+			for j := 2; j < order; j++ {
+				coeffs[j] = int32(rnd.Intn(7) - 3)
+			}
+			amp := 0.9 * float64(int32(1)<<(g.bps-1))
+			samples := make([]int32, nsamples)
+			for i := range samples {
+				samples[i] = int32(amp*math.Sin(float64(i)*0.01)) + int32(rnd.Intn(16))
+			}
+			want = append(want, slices.Clone(samples))
+			subframe := &frame.Subframe{
+				SubHeader: frame.SubHeader{
+					Pred:                 frame.PredFIR,
+					Order:                order,
+					CoeffPrec:            g.prec,
+					CoeffShift:           shift,
+					Coeffs:               coeffs,
+					ResidualCodingMethod: frame.ResidualCodingMethodRice2,
+					RiceSubframe: &frame.RiceSubframe{
+						Partitions: []frame.RicePartition{{Param: 0x1F, EscapedBitsPerSample: 31}},
+					},
+				},
+				Samples:  samples,
+				NSamples: nsamples,
+			}
+			f := &frame.Frame{
+				Header: frame.Header{
+					HasFixedBlockSize: true,
+					BlockSize:         nsamples,
+					SampleRate:        info.SampleRate,
+					Channels:          frame.ChannelsMono,
+					BitsPerSample:     g.bps,
+					Num:               uint64(order - 1),
+				},
+				Subframes: []*frame.Subframe{subframe},
+			}
+			if err := enc.WriteFrame(f); err != nil {
+				t.Fatalf("bps=%d, order=%d: unable to encode frame; %v", g.bps, order, err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		stream, err := flac.New(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for order := 1; order <= 32; order++ {
+			f, err := stream.ParseNext()
+			if err != nil {
+				t.Fatalf("bps=%d, order=%d: unable to decode frame; %v", g.bps, order, err)
+			}
+			if got := f.Subframes[0].Samples; !slices.Equal(got, want[order-1]) {
+				t.Errorf("bps=%d, order=%d: decoded samples mismatch", g.bps, order)
+			}
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package frame
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// A PCMFormat specifies the sample format of interleaved PCM audio samples, as
+// written by Frame.AppendPCM.
+type PCMFormat struct {
+	// Size in bytes of each sample; between 1 and 4.
+	BytesPerSample int
+	// Number of valid bits per sample, which are stored in the least
+	// significant bits of each sample; a 0 value implies 8*BytesPerSample.
+	// Samples are converted from the bits-per-sample of the frame by scaling;
+	// e.g. 16-bit samples are shifted left by 8 bits to be stored as 24-bit
+	// samples, and 24-bit samples are shifted right by 8 bits (discarding the
+	// least significant bits) to be stored as 16-bit samples.
+	//
+	// To store samples left-justified within their container, as done by WAVE
+	// files, leave BitsPerSample unset.
+	BitsPerSample int
+	// Specifies whether samples are stored in big-endian byte order, rather
+	// than little-endian byte order.
+	BigEndian bool
+	// Specifies whether samples are stored as unsigned integers, offset by
+	// 2^(BitsPerSample-1), rather than as signed two's complement integers;
+	// e.g. the 8-bit samples of WAVE files.
+	Unsigned bool
+}
+
+// validate validates the sample format, and returns the number of valid bits
+// per sample.
+func (format *PCMFormat) validate() (int, error) {
+	if format.BytesPerSample < 1 || format.BytesPerSample > 4 {
+		return 0, fmt.Errorf("invalid sample size; expected between 1 and 4 bytes, got %d", format.BytesPerSample)
+	}
+	bps := format.BitsPerSample
+	if bps == 0 {
+		bps = 8 * format.BytesPerSample
+	}
+	if bps < 1 || bps > 8*format.BytesPerSample {
+		return 0, fmt.Errorf("invalid bits-per-sample %d of %d-byte samples", bps, format.BytesPerSample)
+	}
+	return bps, nil
+}
+
+// AppendPCM appends the decoded audio samples of the frame to buf as
+// interleaved PCM samples of the given sample format, and returns the extended
+// buffer. Bit-depth conversion, sign conversion and byte ordering are applied
+// in the same pass as interleaving.
+//
+// Note: The audio samples of the frame must be decoded before calling
+// AppendPCM.
+func (frame *Frame) AppendPCM(buf []byte, format PCMFormat) ([]byte, error) {
+	bps, err := format.validate()
+	if err != nil {
+		return buf, fmt.Errorf("frame.Frame.AppendPCM: %v", err)
+	}
+	if frame.BitsPerSample == 0 || frame.BitsPerSample > 32 {
+		return buf, fmt.Errorf("frame.Frame.AppendPCM: invalid bits-per-sample %d of frame", frame.BitsPerSample)
+	}
+	nchannels := len(frame.Subframes)
+	if nchannels == 0 {
+		return buf, nil
+	}
+	nsamples := len(frame.Subframes[0].Samples)
+	for _, subframe := range frame.Subframes[1:] {
+		if len(subframe.Samples) != nsamples {
+			return buf, fmt.Errorf("frame.Frame.AppendPCM: subframe sample count mismatch; expected %d, got %d", nsamples, len(subframe.Samples))
+		}
+	}
+	size := nsamples * nchannels * format.BytesPerSample
+	buf = slices.Grow(buf, size)
+	out := buf[len(buf) : len(buf)+size]
+
+	// Conversion of samples: scale to the target bit depth, and offset
+	// unsigned samples.
+	enc := pcmEncoder{nbytes: format.BytesPerSample, bigEndian: format.BigEndian}
+	if src := int(frame.BitsPerSample); bps >= src {
+		enc.shl = uint(bps - src)
+	} else {
+		enc.shr = uint(src - bps)
+	}
+	if format.Unsigned {
+		enc.offset = 1 << (bps - 1)
+	}
+
+	switch nchannels {
+	case 1:
+		enc.mono(out, frame.Subframes[0].Samples)
+	case 2:
+		enc.stereo(out, frame.Subframes[0].Samples, frame.Subframes[1].Samples)
+	default:
+		enc.interleave(out, frame.Subframes)
+	}
+	return buf[:len(buf)+size], nil
+}
+
+// pcmEncoder encodes audio samples as PCM samples.
+type pcmEncoder struct {
+	// Size in bytes of each sample.
+	nbytes int
+	// Byte order of samples.
+	bigEndian bool
+	// Right and left shift of samples, for bit-depth conversion.
+	shr, shl uint
+	// Offset of unsigned samples.
+	offset uint32
+}
+
+// conv converts the given sample to the target bit depth and signedness.
+func (enc *pcmEncoder) conv(sample int32) uint32 {
+	return uint32(sample>>enc.shr<<enc.shl) + enc.offset
+}
+
+// put stores the given converted sample in the first bytes of out.
+func (enc *pcmEncoder) put(out []byte, x uint32) {
+	if enc.bigEndian {
+		switch enc.nbytes {
+		case 1:
+			out[0] = byte(x)
+		case 2:
+			binary.BigEndian.PutUint16(out, uint16(x))
+		case 3:
+			_ = out[2]
+			out[0], out[1], out[2] = byte(x>>16), byte(x>>8), byte(x)
+		case 4:
+			binary.BigEndian.PutUint32(out, x)
+		}
+		return
+	}
+	switch enc.nbytes {
+	case 1:
+		out[0] = byte(x)
+	case 2:
+		binary.LittleEndian.PutUint16(out, uint16(x))
+	case 3:
+		_ = out[2]
+		out[0], out[1], out[2] = byte(x), byte(x>>8), byte(x>>16)
+	case 4:
+		binary.LittleEndian.PutUint32(out, x)
+	}
+}
+
+// mono encodes the samples of a single channel to out.
+func (enc *pcmEncoder) mono(out []byte, samples []int32) {
+	if enc.nbytes == 2 && !enc.bigEndian {
+		// Fast path for 16-bit little-endian samples.
+		out = out[:2*len(samples)]
+		for i, sample := range samples {
+			binary.LittleEndian.PutUint16(out[2*i:], uint16(enc.conv(sample)))
+		}
+		return
+	}
+	enc.channel(out, samples, enc.nbytes)
+}
+
+// stereo encodes and interleaves the samples of two channels to out.
+func (enc *pcmEncoder) stereo(out []byte, left, right []int32) {
+	right = right[:len(left)]
+	if enc.nbytes == 2 && !enc.bigEndian {
+		// Fast path for 16-bit little-endian samples.
+		out = out[:4*len(left)]
+		for i, sample := range left {
+			x := enc.conv(sample)&0xFFFF | enc.conv(right[i])<<16
+			binary.LittleEndian.PutUint32(out[4*i:], x)
+		}
+		return
+	}
+	stride := 2 * enc.nbytes
+	for i, sample := range left {
+		off := i * stride
+		enc.put(out[off:], enc.conv(sample))
+		enc.put(out[off+enc.nbytes:], enc.conv(right[i]))
+	}
+}
+
+// channel encodes the samples of a single channel to out, storing consecutive
+// samples stride bytes apart.
+func (enc *pcmEncoder) channel(out []byte, samples []int32, stride int) {
+	for i, sample := range samples {
+		enc.put(out[i*stride:], enc.conv(sample))
+	}
+}
+
+// interleave encodes and interleaves the samples of the given subframes to
+// out. The output is written sequentially, one sample frame at a time.
+func (enc *pcmEncoder) interleave(out []byte, subframes []*Subframe) {
+	var buf [8][]int32
+	channels := buf[:0]
+	for _, subframe := range subframes {
+		channels = append(channels, subframe.Samples)
+	}
+	off := 0
+	for i := range channels[0] {
+		for _, samples := range channels {
+			enc.put(out[off:], enc.conv(samples[i]))
+			off += enc.nbytes
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package frame_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// newPCMFrame returns a frame of random audio samples of the given number of
+// channels and bits-per-sample.
+func newPCMFrame(nchannels, nsamples int, bps uint8) *frame.Frame {
+	rnd := rand.New(rand.NewSource(1))
+	f := &frame.Frame{Header: frame.Header{BitsPerSample: bps}}
+	for i := 0; i < nchannels; i++ {
+		samples := make([]int32, nsamples)
+		for j := range samples {
+			samples[j] = int32(rnd.Int63n(1<<bps) - 1<<(bps-1))
+		}
+		f.Subframes = append(f.Subframes, &frame.Subframe{Samples: samples, NSamples: nsamples})
+	}
+	return f
+}
+
+// appendPCM appends the interleaved samples of the frame to buf, converting
+// each sample independently using nested loops.
+func appendPCM(buf []byte, f *frame.Frame, format frame.PCMFormat) []byte {
+	bps := format.BitsPerSample
+	if bps == 0 {
+		bps = 8 * format.BytesPerSample
+	}
+	for i := range f.Subframes[0].Samples {
+		for _, subframe := range f.Subframes {
+			x := int64(subframe.Samples[i])
+			if src := int(f.BitsPerSample); bps >= src {
+				x <<= bps - src
+			} else {
+				x >>= src - bps
+			}
+			if format.Unsigned {
+				x += 1 << (bps - 1)
+			}
+			for j := 0; j < format.BytesPerSample; j++ {
+				shift := 8 * j
+				if format.BigEndian {
+					shift = 8 * (format.BytesPerSample - 1 - j)
+				}
+				buf = append(buf, byte(x>>shift))
+			}
+		}
+	}
+	return buf
+}
+
+func TestAppendPCM(t *testing.T) {
+	formats := []frame.PCMFormat{
+		{BytesPerSample: 1, Unsigned: true},
+		{BytesPerSample: 2},
+		{BytesPerSample: 2, BigEndian: true},
+		{BytesPerSample: 3},
+		{BytesPerSample: 3, BigEndian: true},
+		{BytesPerSample: 4},
+		{BytesPerSample: 4, BitsPerSample: 24},
+		{BytesPerSample: 4, BitsPerSample: 24, BigEndian: true, Unsigned: true},
+		{BytesPerSample: 2, BitsPerSample: 12, Unsigned: true},
+	}
+	for _, nchannels := range []int{1, 2, 3, 6} {
+		for _, bps := range []uint8{8, 16, 24} {
+			f := newPCMFrame(nchannels, 100, bps)
+			for _, format := range formats {
+				prefix := []byte("prefix")
+				want := appendPCM(bytes.Clone(prefix), f, format)
+				got, err := f.AppendPCM(bytes.Clone(prefix), format)
+				if err != nil {
+					t.Fatalf("nchannels=%d, bps=%d, format=%+v: unable to append PCM samples; %v", nchannels, bps, format, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("nchannels=%d, bps=%d, format=%+v: PCM samples mismatch", nchannels, bps, format)
+				}
+			}
+		}
+	}
+
+	// Invalid formats.
+	f := newPCMFrame(2, 10, 16)
+	for _, format := range []frame.PCMFormat{{}, {BytesPerSample: 5}, {BytesPerSample: 2, BitsPerSample: 17}} {
+		if _, err := f.AppendPCM(nil, format); err == nil {
+			t.Errorf("format=%+v: expected error for invalid format", format)
+		}
+	}
+}
+
+func BenchmarkAppendPCM(b *testing.B) {
+	formats := []struct {
+		name   string
+		format frame.PCMFormat
+	}{
+		{name: "s16le", format: frame.PCMFormat{BytesPerSample: 2}},
+		{name: "s24le", format: frame.PCMFormat{BytesPerSample: 3}},
+		{name: "s32be", format: frame.PCMFormat{BytesPerSample: 4, BigEndian: true}},
+	}
+	for _, nchannels := range []int{1, 2, 6} {
+		f := newPCMFrame(nchannels, 4096, 16)
+		for _, format := range formats {
+			name := fmt.Sprintf("%dch/%s", nchannels, format.name)
+			size := int64(len(f.Subframes) * 4096 * format.format.BytesPerSample)
+			var buf []byte
+			b.Run(name, func(b *testing.B) {
+				b.SetBytes(size)
+				for i := 0; i < b.N; i++ {
+					buf, _ = f.AppendPCM(buf[:0], format.format)
+				}
+			})
+			b.Run(name+"/naive", func(b *testing.B) {
+				b.SetBytes(size)
+				for i := 0; i < b.N; i++ {
+					buf = appendPCM(buf[:0], f, format.format)
+				}
+			})
+		}
+	}
+}
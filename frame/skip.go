@@ -0,0 +1,148 @@
+package frame
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mewkiz/flac/bits"
+)
+
+// Skip reads and discards the subframes of the frame, without decoding their
+// audio samples, and verifies the CRC-16 checksum of the frame. As such, it may
+// be used in place of Frame.Parse to move past frames whose audio samples are
+// not needed; e.g. for fast-forward previews or sparse analysis sampling.
+//
+// The sizes of constant and verbatim subframes, and of the warm-up samples,
+// coefficients and escaped residual partitions of predicted subframes, follow
+// from the block size and the sample size of the frame, and are skipped by bit
+// accounting. Rice coded residuals are of variable size, and are read without
+// being decoded into audio samples. The subframes of the frame are left
+// unchanged.
+func (frame *Frame) Skip() error {
+	var subframe Subframe
+	for channel := 0; channel < frame.Channels.Count(); channel++ {
+		if err := frame.skipSubframe(frame.subframeBitsPerSample(channel), &subframe); err != nil {
+			return err
+		}
+	}
+	return frame.checkCRC16("frame.Frame.Skip")
+}
+
+// skipSubframe reads and parses the header of a subframe of the given
+// bits-per-sample into subframe, and discards the audio samples of the
+// subframe. The subframe holds no audio samples, and retains its buffers for
+// reuse.
+func (frame *Frame) skipSubframe(bps uint, subframe *Subframe) error {
+	subframe.reset()
+	if err := subframe.parseHeader(frame.br); err != nil {
+		return err
+	}
+	if subframe.Wasted >= bps {
+		return fmt.Errorf("frame.Frame.Skip: wasted bits-per-sample (%d) exceeds bits-per-sample (%d) of subframe", subframe.Wasted, bps)
+	}
+	return skipSubframeBody(frame.br, subframe, bps-subframe.Wasted, int(frame.BlockSize))
+}
+
+// skipSubframeBody reads and discards the body of a subframe, of the given
+// bits-per-sample and number of samples, following its parsed header.
+func skipSubframeBody(br *bits.Reader, subframe *Subframe, bps uint, nsamples int) error {
+	switch subframe.Pred {
+	case PredConstant:
+		return skipBits(br, uint64(bps))
+	case PredVerbatim:
+		return skipBits(br, uint64(nsamples)*uint64(bps))
+	}
+	// Unencoded warm-up samples.
+	if err := skipBits(br, uint64(subframe.Order)*uint64(bps)); err != nil {
+		return err
+	}
+	if subframe.Pred == PredFIR {
+		// 4 bits: (coefficients' precision in bits) - 1.
+		x, err := br.Read(4)
+		if err != nil {
+			return unexpected(err)
+		}
+		if x == 0xF {
+			return errors.New("frame.Frame.Skip: invalid coefficient precision bit pattern (1111)")
+		}
+		prec := uint64(x) + 1
+		// 5 bits: predictor coefficient shift, followed by the coefficients.
+		if err := skipBits(br, 5+uint64(subframe.Order)*prec); err != nil {
+			return err
+		}
+	}
+	return skipResiduals(br, subframe.Order, nsamples)
+}
+
+// skipResiduals reads and discards the encoded residuals of a predicted
+// subframe of the given prediction order and number of samples.
+func skipResiduals(br *bits.Reader, order, nsamples int) error {
+	// 2 bits: Residual coding method.
+	x, err := br.Read(2)
+	if err != nil {
+		return unexpected(err)
+	}
+	var paramSize uint
+	switch ResidualCodingMethod(x) {
+	case ResidualCodingMethodRice1:
+		paramSize = 4
+	case ResidualCodingMethodRice2:
+		paramSize = 5
+	default:
+		return fmt.Errorf("frame.Frame.Skip: reserved residual coding method bit pattern (%02b)", x)
+	}
+	// 4 bits: Partition order.
+	x, err = br.Read(4)
+	if err != nil {
+		return unexpected(err)
+	}
+	nparts := 1 << x
+	// 1111 or 11111: Escape code of unencoded partitions.
+	escape := uint(1)<<paramSize - 1
+	for i := 0; i < nparts; i++ {
+		// Number of residuals of the partition; the first partition excludes the
+		// warm-up samples.
+		n := nsamples / nparts
+		if i == 0 {
+			n = max(n-order, 0)
+		}
+		// (4 or 5) bits: Rice parameter.
+		x, err := br.Read(paramSize)
+		if err != nil {
+			return unexpected(err)
+		}
+		param := uint(x)
+		if param == escape {
+			// 5 bits: Number of bits per residual of the escaped partition.
+			x, err := br.Read(5)
+			if err != nil {
+				return unexpected(err)
+			}
+			if err := skipBits(br, uint64(n)*x); err != nil {
+				return err
+			}
+			continue
+		}
+		for j := 0; j < n; j++ {
+			if _, err := br.ReadUnary(); err != nil {
+				return unexpected(err)
+			}
+			if _, err := br.Read(param); err != nil {
+				return unexpected(err)
+			}
+		}
+	}
+	return nil
+}
+
+// skipBits reads and discards the next n bits.
+func skipBits(br *bits.Reader, n uint64) error {
+	for n > 0 {
+		m := min(n, 64)
+		if _, err := br.Read(uint(m)); err != nil {
+			return unexpected(err)
+		}
+		n -= m
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package frame_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+// testdataPaths lists the FLAC files of the test data.
+var testdataPaths = []string{
+	"../testdata/172960.flac",
+	"../testdata/189983.flac",
+	"../testdata/191885.flac",
+	"../testdata/19875.flac",
+	"../testdata/212768.flac",
+	"../testdata/220014.flac",
+	"../testdata/243749.flac",
+	"../testdata/256529.flac",
+	"../testdata/257344.flac",
+	"../testdata/44127.flac",
+	"../testdata/59996.flac",
+	"../testdata/80574.flac",
+	"../testdata/8297-275156-0011.flac",
+	"../testdata/love.flac",
+}
+
+func TestSkip(t *testing.T) {
+	for _, path := range testdataPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := flac.New(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := flac.New(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Skipping a frame consumes the same bytes as parsing the frame.
+		for i := 0; ; i++ {
+			_, err := want.ParseNext()
+			if err == io.EOF {
+				if _, err := got.Next(); err != io.EOF {
+					t.Errorf("%s: expected io.EOF after %d frames, got %v", path, i, err)
+				}
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := got.Next()
+			if err != nil {
+				t.Fatalf("%s: unable to parse header of frame %d; %v", path, i, err)
+			}
+			if err := f.Skip(); err != nil {
+				t.Fatalf("%s: unable to skip frame %d; %v", path, i, err)
+			}
+			if got.Offset() != want.Offset() {
+				t.Fatalf("%s: offset after frame %d mismatch; expected %d, got %d", path, i, want.Offset(), got.Offset())
+			}
+		}
+	}
+
+	// Skipping a corrupted frame fails the CRC-16 checksum.
+	data, err := os.ReadFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the last sample of the first frame, preceding its CRC-16.
+	if err := f.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	data[stream.Offset()-3] ^= 0x01
+	stream, err = flac.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, err = stream.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Skip(); err == nil {
+		t.Error("expected error for corrupted frame")
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	// Number of frames decoded in part.
+	var npartial int
+	for _, path := range testdataPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := flac.New(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := flac.New(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; ; i++ {
+			wantFrame, err := want.ParseNext()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := got.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Decode the channels of consecutive frames in turn.
+			channel := i % len(wantFrame.Subframes)
+			if err := f.ParseChannel(channel); err != nil {
+				t.Fatalf("%s: unable to parse channel %d of frame %d; %v", path, channel, i, err)
+			}
+			if !slices.Equal(f.Subframes[channel].Samples, wantFrame.Subframes[channel].Samples) {
+				t.Errorf("%s: samples of channel %d of frame %d mismatch", path, channel, i)
+			}
+			for _, subframe := range f.Subframes {
+				if len(subframe.Samples) == 0 {
+					npartial++
+					break
+				}
+			}
+			if got.Offset() != want.Offset() {
+				t.Fatalf("%s: offset after frame %d mismatch; expected %d, got %d", path, i, want.Offset(), got.Offset())
+			}
+		}
+	}
+	if npartial == 0 {
+		t.Error("no frames decoded in part")
+	}
+
+	f := &frame.Frame{Header: frame.Header{Channels: frame.ChannelsLR}}
+	if err := f.ParseChannel(2); err == nil {
+		t.Error("expected error for invalid channel")
+	}
+}
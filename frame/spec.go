@@ -0,0 +1,188 @@
+package frame
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// Limits of the FLAC format. The limits of the stream properties are those of
+// package meta.
+//
+// ref: https://www.xiph.org/flac/format.html
+const (
+	// MinBlockSize is the minimum block size (in samples) of a FLAC stream. The
+	// last frame of a stream may hold fewer samples.
+	MinBlockSize = meta.MinBlockSize
+	// MaxBlockSize is the maximum block size (in samples) of a FLAC stream.
+	MaxBlockSize = meta.MaxBlockSize
+	// MaxChannels is the maximum number of channels of a FLAC stream.
+	MaxChannels = meta.MaxChannels
+	// MinBitsPerSample is the minimum sample size in bits-per-sample.
+	MinBitsPerSample = meta.MinBitsPerSample
+	// MaxBitsPerSample is the maximum sample size in bits-per-sample.
+	MaxBitsPerSample = meta.MaxBitsPerSample
+	// MaxSampleRate is the maximum sample rate in Hz.
+	MaxSampleRate = meta.MaxSampleRate
+	// MaxFixedOrder is the maximum prediction order of fixed prediction.
+	MaxFixedOrder = 4
+	// MaxLPCOrder is the maximum prediction order of FIR linear prediction.
+	MaxLPCOrder = 32
+	// MaxCoeffPrec is the maximum precision in bits of FIR linear prediction
+	// coefficients.
+	MaxCoeffPrec = 15
+	// MaxPartitionOrder is the maximum Rice partition order.
+	MaxPartitionOrder = 15
+)
+
+// Limits of the streamable subset of the FLAC format. Hardware decoders are
+// only required to support streams conforming to the subset.
+//
+// ref: https://www.xiph.org/flac/format.html#subset
+const (
+	// SubsetMaxBlockSize is the maximum block size (in samples) of a subset
+	// stream.
+	SubsetMaxBlockSize = 16384
+	// SubsetMaxBlockSize48kHz is the maximum block size (in samples) of a subset
+	// stream with a sample rate of at most 48 kHz.
+	SubsetMaxBlockSize48kHz = 4608
+	// SubsetMaxLPCOrder48kHz is the maximum FIR linear prediction order of a
+	// subset stream with a sample rate of at most 48 kHz.
+	SubsetMaxLPCOrder48kHz = 12
+	// SubsetMaxPartitionOrder is the maximum Rice partition order of a subset
+	// stream.
+	SubsetMaxPartitionOrder = 8
+	// SubsetMaxBitsPerSample is the maximum sample size in bits-per-sample of a
+	// subset stream.
+	SubsetMaxBitsPerSample = 24
+)
+
+// SubsetMaxBlockSizeFor returns the maximum block size (in samples) of a subset
+// stream with the given sample rate.
+func SubsetMaxBlockSizeFor(sampleRate uint32) int {
+	if sampleRate <= 48000 {
+		return SubsetMaxBlockSize48kHz
+	}
+	return SubsetMaxBlockSize
+}
+
+// SubsetMaxLPCOrderFor returns the maximum FIR linear prediction order of a
+// subset stream with the given sample rate.
+func SubsetMaxLPCOrderFor(sampleRate uint32) int {
+	if sampleRate <= 48000 {
+		return SubsetMaxLPCOrder48kHz
+	}
+	return MaxLPCOrder
+}
+
+// ErrNotSubset reports that a frame or stream violates the constraints of the
+// streamable subset. Errors returned by CheckSubset wrap ErrNotSubset, and may
+// be tested using errors.Is.
+var ErrNotSubset = errors.New("not subset compliant")
+
+// CheckSubset reports whether the frame conforms to the streamable subset of
+// the FLAC format. The sample rate of the stream is used to determine the
+// block size and prediction order limits, as the frame header may leave the
+// sample rate unspecified; in which case the frame is not subset compliant.
+//
+// Note: The audio samples of the frame must be parsed before calling
+// CheckSubset, as the subframe headers are inspected.
+func (frame *Frame) CheckSubset(sampleRate uint32) error {
+	// The sample rate and sample size must be stored in the frame header, to
+	// enable decoding without access to StreamInfo.
+	if frame.SampleRate == 0 {
+		return fmt.Errorf("frame.Frame.CheckSubset: %w; sample rate not stored in frame header", ErrNotSubset)
+	}
+	if frame.BitsPerSample == 0 {
+		return fmt.Errorf("frame.Frame.CheckSubset: %w; sample size not stored in frame header", ErrNotSubset)
+	}
+	switch frame.BitsPerSample {
+	case 8, 12, 16, 20, 24, 32:
+	default:
+		return fmt.Errorf("frame.Frame.CheckSubset: %w; sample size (%d) has no frame header code", ErrNotSubset, frame.BitsPerSample)
+	}
+	if frame.BitsPerSample > SubsetMaxBitsPerSample {
+		return fmt.Errorf("frame.Frame.CheckSubset: %w; sample size (%d) exceeds %d bits-per-sample", ErrNotSubset, frame.BitsPerSample, SubsetMaxBitsPerSample)
+	}
+	if sampleRate == 0 {
+		sampleRate = frame.SampleRate
+	}
+	if max := SubsetMaxBlockSizeFor(sampleRate); int(frame.BlockSize) > max {
+		return fmt.Errorf("frame.Frame.CheckSubset: %w; block size (%d) exceeds %d samples at %d Hz", ErrNotSubset, frame.BlockSize, max, sampleRate)
+	}
+	maxOrder := SubsetMaxLPCOrderFor(sampleRate)
+	for i, subframe := range frame.Subframes {
+		if subframe.Pred == PredFIR && subframe.Order > maxOrder {
+			return fmt.Errorf("frame.Frame.CheckSubset: %w; prediction order (%d) of subframe %d exceeds %d at %d Hz", ErrNotSubset, subframe.Order, i, maxOrder, sampleRate)
+		}
+		if subframe.RiceSubframe != nil && subframe.RiceSubframe.PartOrder > SubsetMaxPartitionOrder {
+			return fmt.Errorf("frame.Frame.CheckSubset: %w; partition order (%d) of subframe %d exceeds %d", ErrNotSubset, subframe.RiceSubframe.PartOrder, i, SubsetMaxPartitionOrder)
+		}
+	}
+	return nil
+}
+
+// IsSubsetCompliant reports whether the frame conforms to the streamable subset
+// of the FLAC format. See CheckSubset for details.
+func (frame *Frame) IsSubsetCompliant(sampleRate uint32) bool {
+	return frame.CheckSubset(sampleRate) == nil
+}
+
+// A Spec identifies a revision of the specification of the FLAC format, as
+// targeted by validation.
+//
+// RFC 9639 clarifies the classic specification of xiph.org; most notably, it
+// assigns the previously reserved sample size bit pattern (111) of frame
+// headers to 32 bits-per-sample. The rules which are common to both revisions
+// are always validated by the frame parser; e.g. the forbidden sample rate bit
+// pattern (1111), the block size limit of 65535 samples (a 16-bit block size of
+// 65536 samples is forbidden by RFC 9639, and unsupported otherwise), negative
+// shifts of FIR linear prediction, and the CRC-8 and CRC-16 checksums of frames.
+//
+// ref: https://www.rfc-editor.org/rfc/rfc9639.html
+type Spec uint8
+
+// Revisions of the specification of the FLAC format.
+const (
+	// SpecRFC9639 targets RFC 9639, the default.
+	SpecRFC9639 Spec = iota
+	// SpecXiph targets the classic specification of xiph.org, as implemented
+	// by decoders predating RFC 9639.
+	//
+	// ref: https://www.xiph.org/flac/format.html
+	SpecXiph
+)
+
+// String returns the name of the specification.
+func (spec Spec) String() string {
+	switch spec {
+	case SpecRFC9639:
+		return "RFC 9639"
+	case SpecXiph:
+		return "xiph"
+	}
+	return fmt.Sprintf("Spec(%d)", uint8(spec))
+}
+
+// CheckSpec reports whether the frame header conforms to the given revision of
+// the specification, in addition to the rules validated when parsing the frame
+// header; i.e. the 32 bits-per-sample bit pattern (111) is reserved by the
+// classic specification. CheckSpec returns a *HeaderError if the frame header
+// violates a rule of the specification.
+//
+// Note: CheckSpec must be called before the sample size of frame headers which
+// refer to StreamInfo is filled in, as 32 bits-per-sample may only be stored in
+// StreamInfo by the classic specification.
+func (hdr *Header) CheckSpec(spec Spec) error {
+	switch spec {
+	case SpecRFC9639:
+	case SpecXiph:
+		if hdr.BitsPerSample == 32 {
+			return &HeaderError{Rule: RuleReservedBitsPerSample, Value: 0x7}
+		}
+	default:
+		return fmt.Errorf("frame.Header.CheckSpec: unknown specification %v", spec)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package frame_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestFrameCheckSubset(t *testing.T) {
+	// Only use the local test cases, as the IETF test cases are not subset
+	// compliant in their entirety.
+	for _, g := range golden[:13] {
+		t.Run(g.path, func(t *testing.T) {
+			stream, err := flac.Open(g.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+			for {
+				f, err := stream.ParseNext()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					t.Fatal(err)
+				}
+				if err := f.CheckSubset(stream.Info.SampleRate); err != nil {
+					t.Errorf("frame %d: unexpected subset violation; %v", f.Num, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFrameCheckSubsetViolation(t *testing.T) {
+	golden := []struct {
+		hdr        frame.Header
+		sampleRate uint32
+		order      int
+		partOrder  int
+	}{
+		// Sample rate not stored in frame header.
+		{hdr: frame.Header{BlockSize: 4096, BitsPerSample: 16}, sampleRate: 44100},
+		// Sample size not stored in frame header.
+		{hdr: frame.Header{BlockSize: 4096, SampleRate: 44100}, sampleRate: 44100},
+		// Sample size exceeds 24 bits-per-sample.
+		{hdr: frame.Header{BlockSize: 4096, SampleRate: 44100, BitsPerSample: 32}, sampleRate: 44100},
+		// Block size exceeds 4608 samples at 48 kHz.
+		{hdr: frame.Header{BlockSize: 8192, SampleRate: 48000, BitsPerSample: 16}, sampleRate: 48000},
+		// Block size exceeds 16384 samples at 96 kHz.
+		{hdr: frame.Header{BlockSize: 32768, SampleRate: 96000, BitsPerSample: 16}, sampleRate: 96000},
+		// Prediction order exceeds 12 at 44.1 kHz.
+		{hdr: frame.Header{BlockSize: 4096, SampleRate: 44100, BitsPerSample: 16}, sampleRate: 44100, order: 13},
+		// Partition order exceeds 8.
+		{hdr: frame.Header{BlockSize: 4096, SampleRate: 44100, BitsPerSample: 16}, sampleRate: 44100, order: 8, partOrder: 9},
+	}
+	for i, g := range golden {
+		subframe := &frame.Subframe{
+			SubHeader: frame.SubHeader{
+				Pred:         frame.PredFIR,
+				Order:        g.order,
+				RiceSubframe: &frame.RiceSubframe{PartOrder: g.partOrder},
+			},
+		}
+		f := &frame.Frame{Header: g.hdr, Subframes: []*frame.Subframe{subframe}}
+		err := f.CheckSubset(g.sampleRate)
+		if !errors.Is(err, frame.ErrNotSubset) {
+			t.Errorf("i=%d: expected subset violation, got %v", i, err)
+		}
+		if f.IsSubsetCompliant(g.sampleRate) {
+			t.Errorf("i=%d: expected frame not to be subset compliant", i)
+		}
+	}
+}
+
+func TestHeaderCheckSpec(t *testing.T) {
+	golden := []struct {
+		bps  uint8
+		spec frame.Spec
+		rule frame.HeaderRule
+	}{
+		{bps: 24, spec: frame.SpecRFC9639},
+		{bps: 24, spec: frame.SpecXiph},
+		{bps: 32, spec: frame.SpecRFC9639},
+		// Reserved sample size bit pattern (111) of the classic specification.
+		{bps: 32, spec: frame.SpecXiph, rule: frame.RuleReservedBitsPerSample},
+		// Sample size referring to StreamInfo.
+		{bps: 0, spec: frame.SpecXiph},
+	}
+	for _, g := range golden {
+		hdr := &frame.Header{BitsPerSample: g.bps}
+		err := hdr.CheckSpec(g.spec)
+		var e *frame.HeaderError
+		switch {
+		case g.rule == 0 && err != nil:
+			t.Errorf("%d bits-per-sample (%v): unexpected error; %v", g.bps, g.spec, err)
+		case g.rule != 0 && !errors.As(err, &e):
+			t.Errorf("%d bits-per-sample (%v): expected *frame.HeaderError, got %v", g.bps, g.spec, err)
+		case g.rule != 0 && e.Rule != g.rule:
+			t.Errorf("%d bits-per-sample (%v): rule mismatch; expected %d, got %d", g.bps, g.spec, g.rule, e.Rule)
+		}
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/mewkiz/flac/internal/bits"
+	"github.com/mewkiz/flac/bits"
 )
 
 // A Subframe contains the encoded audio samples from one channel of an audio
@@ -23,22 +23,44 @@ type Subframe struct {
 	Samples []int32
 	// Number of audio samples in the subframe.
 	NSamples int
+	// Residuals (signal errors of the prediction) of fixed and FIR linear
+	// prediction decoding, excluding the warm-up samples; i.e. NSamples-Order
+	// residuals. Residuals is only populated by Frame.Parse if
+	// Frame.KeepResiduals is set, and is nil otherwise; it is empty for
+	// constant and verbatim subframes. The residuals are relative to the audio
+	// samples with wasted bits-per-sample shifted out, and before inter-channel
+	// correlation.
+	Residuals []int32
+
+	// Rice-coding subframe fields retained for reuse by subsequent parse
+	// operations; nil if unused.
+	riceBuf *RiceSubframe
+	// Residual buffer retained for reuse by subsequent parse operations.
+	residualBuf []int32
 }
 
 // parseSubframe reads and parses the header, and the audio samples of a
-// subframe.
-func (frame *Frame) parseSubframe(br *bits.Reader, bps uint) (subframe *Subframe, err error) {
+// subframe. The buffers of the subframe are reused if present.
+func (frame *Frame) parseSubframe(br *bits.Reader, bps uint, subframe *Subframe) (err error) {
 	// Parse subframe header.
-	subframe = new(Subframe)
+	subframe.reset()
+	if frame.KeepResiduals {
+		subframe.Residuals = subframe.residualBuf[:0]
+		if subframe.Residuals == nil {
+			subframe.Residuals = make([]int32, 0, frame.BlockSize)
+		}
+	}
 	if err = subframe.parseHeader(br); err != nil {
-		return subframe, err
+		return err
 	}
 	// Adjust bps of subframe for wasted bits-per-sample.
 	bps -= subframe.Wasted
 
 	// Decode subframe audio samples.
 	subframe.NSamples = int(frame.BlockSize)
-	subframe.Samples = make([]int32, 0, subframe.NSamples)
+	if cap(subframe.Samples) < subframe.NSamples {
+		subframe.Samples = make([]int32, 0, subframe.NSamples)
+	}
 	switch subframe.Pred {
 	case PredConstant:
 		err = subframe.decodeConstant(br, bps)
@@ -54,7 +76,27 @@ func (frame *Frame) parseSubframe(br *bits.Reader, bps uint) (subframe *Subframe
 	for i, sample := range subframe.Samples {
 		subframe.Samples[i] = sample << subframe.Wasted
 	}
-	return subframe, err
+	return err
+}
+
+// reset resets the subframe, retaining its buffers for reuse.
+func (subframe *Subframe) reset() {
+	riceBuf := subframe.riceBuf
+	if subframe.RiceSubframe != nil {
+		riceBuf = subframe.RiceSubframe
+	}
+	residualBuf := subframe.residualBuf
+	if subframe.Residuals != nil {
+		residualBuf = subframe.Residuals
+	}
+	*subframe = Subframe{
+		SubHeader: SubHeader{
+			Coeffs: subframe.Coeffs[:0],
+		},
+		Samples:     subframe.Samples[:0],
+		riceBuf:     riceBuf,
+		residualBuf: residualBuf,
+	}
 }
 
 // A SubHeader specifies the prediction method and order of a subframe.
@@ -306,7 +348,7 @@ func (subframe *Subframe) decodeFixed(br *bits.Reader, bps uint) error {
 	// predefined coefficients of a given order. Correct signal errors using the
 	// decoded residuals.
 	const shift = 0
-	return subframe.decodeLPC(FixedCoeffs[subframe.Order], shift)
+	return subframe.decodeLPC(FixedCoeffs[subframe.Order], shift, bps)
 }
 
 // decodeFIR decodes the linear prediction coded samples of the subframe, using
@@ -345,7 +387,11 @@ func (subframe *Subframe) decodeFIR(br *bits.Reader, bps uint) error {
 	subframe.CoeffShift = shift
 
 	// Parse coefficients.
-	coeffs := make([]int32, subframe.Order)
+	coeffs := subframe.Coeffs[:0]
+	if cap(coeffs) < subframe.Order {
+		coeffs = make([]int32, 0, subframe.Order)
+	}
+	coeffs = coeffs[:subframe.Order]
 	for i := range coeffs {
 		// (prec) bits: Predictor coefficient.
 		x, err = br.Read(prec)
@@ -364,7 +410,7 @@ func (subframe *Subframe) decodeFIR(br *bits.Reader, bps uint) error {
 	// Predict the audio samples of the subframe using a polynomial with
 	// predefined coefficients of a given order. Correct signal errors using the
 	// decoded residuals.
-	return subframe.decodeLPC(coeffs, shift)
+	return subframe.decodeLPC(coeffs, shift, bps)
 }
 
 // ResidualCodingMethod specifies a residual coding method.
@@ -417,9 +463,11 @@ func (subframe *Subframe) decodeRicePart(br *bits.Reader, paramSize uint) error
 		return unexpected(err)
 	}
 	partOrder := int(x)
-	riceSubframe := &RiceSubframe{
-		PartOrder: partOrder,
+	riceSubframe := subframe.riceBuf
+	if riceSubframe == nil {
+		riceSubframe = new(RiceSubframe)
 	}
+	riceSubframe.PartOrder = partOrder
 	subframe.RiceSubframe = riceSubframe
 
 	// Parse Rice partitions; in total 2^partOrder partitions.
@@ -427,7 +475,14 @@ func (subframe *Subframe) decodeRicePart(br *bits.Reader, paramSize uint) error
 	// ref: https://www.xiph.org/flac/format.html#rice_partition
 	// ref: https://www.xiph.org/flac/format.html#rice2_partition
 	nparts := 1 << partOrder
-	partitions := make([]RicePartition, nparts)
+	partitions := riceSubframe.Partitions[:0]
+	if cap(partitions) < nparts {
+		partitions = make([]RicePartition, 0, nparts)
+	}
+	partitions = partitions[:nparts]
+	for i := range partitions {
+		partitions[i] = RicePartition{}
+	}
 	riceSubframe.Partitions = partitions
 	for i := 0; i < nparts; i++ {
 		partition := &partitions[i]
@@ -492,28 +547,20 @@ func (subframe *Subframe) decodeRicePart(br *bits.Reader, paramSize uint) error
 // decodeRiceResidual decodes and returns a Rice encoded residual (error
 // signal).
 func (subframe *Subframe) decodeRiceResidual(br *bits.Reader, k uint) (int32, error) {
-	// Read unary encoded most significant bits.
-	high, err := br.ReadUnary()
+	// Read unary encoded most significant bits and binary encoded least
+	// significant bits, and ZigZag decode.
+	residual, err := br.ReadRice(k)
 	if err != nil {
 		return 0, unexpected(err)
 	}
-
-	// Read binary encoded least significant bits.
-	low, err := br.Read(k)
-	if err != nil {
-		return 0, unexpected(err)
-	}
-	folded := uint32(high<<k | low)
-
-	// ZigZag decode.
-	residual := bits.DecodeZigZag(folded)
 	return residual, nil
 }
 
-// decodeLPC decodes linear prediction coded audio samples, using the
-// coefficients of a given polynomial, a couple of unencoded warm-up samples,
-// and the signal errors of the prediction as specified by the residuals.
-func (subframe *Subframe) decodeLPC(coeffs []int32, shift int32) error {
+// decodeLPC decodes linear prediction coded audio samples of the given
+// bits-per-sample, using the coefficients of a given polynomial, a couple of
+// unencoded warm-up samples, and the signal errors of the prediction as
+// specified by the residuals.
+func (subframe *Subframe) decodeLPC(coeffs []int32, shift int32, bps uint) error {
 	if len(coeffs) != subframe.Order {
 		return fmt.Errorf("frame.Subframe.decodeLPC: prediction order (%d) differs from number of coefficients (%d)", subframe.Order, len(coeffs))
 	}
@@ -523,12 +570,10 @@ func (subframe *Subframe) decodeLPC(coeffs []int32, shift int32) error {
 	if subframe.NSamples != len(subframe.Samples) {
 		return fmt.Errorf("frame.Subframe.decodeLPC: subframe sample count mismatch; expected %d, got %d", subframe.NSamples, len(subframe.Samples))
 	}
-	for i := subframe.Order; i < subframe.NSamples; i++ {
-		var sample int64
-		for j, c := range coeffs {
-			sample += int64(c) * int64(subframe.Samples[i-j-1])
-		}
-		subframe.Samples[i] += int32(sample >> uint(shift))
+	if subframe.Residuals != nil {
+		// Retain residuals, which are replaced by the decoded audio samples.
+		subframe.Residuals = append(subframe.Residuals, subframe.Samples[subframe.Order:]...)
 	}
+	restoreLPC(subframe.Samples, coeffs, uint(shift), bps)
 	return nil
 }
@@ -0,0 +1,130 @@
+package frame
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// MaxHeaderSize is the maximum size in bytes of an audio frame header: 4 bytes
+// of fixed size fields, a 7 byte UTF-8 coded sample number, 2 bytes of block
+// size and 2 bytes of sample rate stored after the fixed size fields, and the
+// 1 byte CRC-8 checksum.
+const MaxHeaderSize = 16
+
+// FindHeader returns the byte offset of the first valid audio frame header in
+// data at or after start, and the parsed header; or -1 if no valid frame header
+// is found. A frame header is valid if it starts with the sync code, holds no
+// invalid or reserved field values, and its CRC-8 checksum matches; as
+// validated by New. Frame headers truncated by the end of data are not found.
+//
+// A valid frame header may occur by chance within the audio samples of a frame;
+// the sync code and CRC-8 checksum of random data match with a probability of
+// 1 in 2^23 per byte offset. Parse the complete frame to verify its CRC-16
+// checksum, or verify the frame number of consecutive frames, to rule out false
+// positives.
+func FindHeader(data []byte, start int) (offset int, hdr Header) {
+	frame := new(Frame)
+	for i := max(start, 0); i+1 < len(data); i++ {
+		// Sync code: 11111111111110 followed by a reserved 0 bit.
+		if data[i] != 0xFF || data[i+1]&0xFE != 0xF8 {
+			continue
+		}
+		if err := NewInto(bytes.NewReader(data[i:]), frame); err == nil {
+			return i, frame.Header
+		}
+	}
+	return -1, Header{}
+}
+
+// syncScanBufSize specifies the size of the read buffer of a SyncScanner.
+const syncScanBufSize = 64 * 1024
+
+// A SyncScanner scans a byte stream for valid audio frame headers, from an
+// arbitrary byte position; e.g. to index, repair or carve FLAC frames from
+// damaged files, disk images and fragments of container formats. Frame headers
+// are validated as done by FindHeader.
+type SyncScanner struct {
+	// Underlying io.Reader.
+	r io.Reader
+	// Read buffer, holding the data following the byte offset off.
+	buf []byte
+	// Byte offset of buf[0], relative to the start of r.
+	off int64
+	// Scan position within buf.
+	pos int
+	// Read error of r.
+	err error
+}
+
+// NewSyncScanner returns a new scanner for the frame headers of r. Byte offsets
+// are relative to the current position of r.
+func NewSyncScanner(r io.Reader) *SyncScanner {
+	return &SyncScanner{
+		r:   r,
+		buf: make([]byte, 0, syncScanBufSize),
+	}
+}
+
+// Next returns the byte offset and the parsed header of the next valid frame
+// header. It returns io.EOF once the end of the stream is reached.
+//
+// The scan resumes at the byte following the start of the returned header; as
+// such, false positives within the audio samples of frames are reported (see
+// FindHeader). Use Skip to resume the scan after the end of a frame, once the
+// frame has been verified.
+func (s *SyncScanner) Next() (offset int64, hdr Header, err error) {
+	for {
+		i, hdr := FindHeader(s.buf, s.pos)
+		// A frame header candidate may be truncated by the end of the buffer,
+		// so headers near the end of the buffer are only reported once the
+		// preceding candidates are known to be invalid.
+		tail := len(s.buf) - (MaxHeaderSize - 1)
+		if i != -1 && (i < tail || s.err != nil) {
+			s.pos = i + 1
+			return s.off + int64(i), hdr, nil
+		}
+		if s.err != nil {
+			s.pos = len(s.buf)
+			return 0, Header{}, s.err
+		}
+		s.pos = max(s.pos, tail)
+		s.fill()
+	}
+}
+
+// Skip discards the data preceding the given byte offset, to resume the scan
+// at offset; e.g. to skip the audio samples of a verified frame.
+func (s *SyncScanner) Skip(offset int64) error {
+	cur := s.off + int64(s.pos)
+	if offset < cur {
+		return fmt.Errorf("frame.SyncScanner.Skip: offset %d precedes scan position %d", offset, cur)
+	}
+	end := s.off + int64(len(s.buf))
+	if offset <= end {
+		s.pos = int(offset - s.off)
+		return nil
+	}
+	s.off, s.pos, s.buf = offset, 0, s.buf[:0]
+	if s.err != nil {
+		return nil
+	}
+	if _, err := io.CopyN(io.Discard, s.r, offset-end); err != nil {
+		s.err = err
+		if err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+// fill discards the scanned data of the read buffer, and reads more data from
+// the underlying io.Reader.
+func (s *SyncScanner) fill() {
+	n := copy(s.buf[:cap(s.buf)], s.buf[s.pos:])
+	s.off += int64(s.pos)
+	s.pos = 0
+	m, err := s.r.Read(s.buf[n:cap(s.buf)])
+	s.buf = s.buf[:n+m]
+	s.err = err
+}
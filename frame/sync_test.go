@@ -0,0 +1,80 @@
+package frame_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestSyncScanner(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Locate the frames of the stream.
+	stream, err := flac.NewSeek(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int64
+	var hdrs []frame.Header
+	for {
+		offset := stream.Offset()
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		offsets = append(offsets, offset)
+		hdrs = append(hdrs, f.Header)
+	}
+	offsets = append(offsets, int64(len(buf)))
+
+	// Damage the header of the third frame.
+	const damaged = 2
+	data := bytes.Clone(buf)
+	data[offsets[damaged]+2] ^= 0x10
+
+	// Scan the frames from a fragment starting within the first frame, reading
+	// a byte at the time to exercise truncated frame headers at the end of the
+	// read buffer.
+	start := offsets[0] + 1
+	s := frame.NewSyncScanner(iotest.OneByteReader(bytes.NewReader(data[start:])))
+	for i := 1; i < len(hdrs); i++ {
+		offset, hdr, err := s.Next()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if i == damaged {
+			i++
+		}
+		if want := offsets[i] - start; offset != want {
+			t.Fatalf("frame %d: offset mismatch; expected %d, got %d", i, want, offset)
+		}
+		if hdr != hdrs[i] {
+			t.Fatalf("frame %d: header mismatch; expected %v, got %v", i, hdrs[i], hdr)
+		}
+		// Skip the audio samples of the frame.
+		if err := s.Skip(offsets[i+1] - start); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := s.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after last frame, got %v", err)
+	}
+
+	// Locate the frames of the damaged stream in memory.
+	if offset, _ := frame.FindHeader(data, int(offsets[damaged-1])+1); offset != int(offsets[damaged+1]) {
+		t.Errorf("offset mismatch of frame following damaged frame; expected %d, got %d", offsets[damaged+1], offset)
+	}
+	if offset, _ := frame.FindHeader(data[:offsets[0]+5], 0); offset != -1 {
+		t.Errorf("expected no frame header in truncated data, got offset %d", offset)
+	}
+}
@@ -0,0 +1,198 @@
+package flac
+
+import (
+	"math"
+	"time"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+const (
+	// frameSizeBucket specifies the width in bytes of the buckets of
+	// FrameSizeStats.Histogram.
+	frameSizeBucket = 256
+	// defaultBitrateWindow specifies the default duration of the sliding window
+	// of FrameSizeStats.WindowBitrate.
+	defaultBitrateWindow = 5 * time.Second
+)
+
+// FrameSizeStats holds running statistics of the compressed sizes of the audio
+// frames of a stream, as tracked by the decoder; e.g. for streaming clients to
+// adapt their prebuffering to the bitrate variance of the stream. See
+// DecodeOptions.OnFrameSize and Stream.FrameSizeStats.
+type FrameSizeStats struct {
+	// Number of frames.
+	Frames int
+	// Total size in bytes of the frames, including their headers and CRC-16
+	// checksums.
+	TotalSize int64
+	// Total number of samples per channel of the frames.
+	TotalSamples uint64
+	// Smallest and largest size in bytes of the frames. Unlike the minimum and
+	// maximum frame sizes of StreamInfo, which encoders may leave unset (0),
+	// the sizes are observed.
+	MinSize, MaxSize int
+	// Largest bitrate of the frames in bits per second; i.e. the bitrate
+	// required to receive each frame within its duration.
+	PeakBitrate int64
+	// Histogram of the frame sizes; Histogram[i] holds the number of frames of
+	// size in the range [256*i, 256*(i+1)) bytes.
+	Histogram []int
+	// Sum of the squared frame sizes, to compute the standard deviation.
+	sumSquares float64
+	// Sample rate of the stream in Hz.
+	sampleRate uint32
+	// Most recent frames within the bitrate window, oldest first.
+	window []windowFrame
+	// Total size in bytes and number of samples per channel of the frames of
+	// the bitrate window.
+	windowSize    int64
+	windowSamples uint64
+	// Duration of the bitrate window in samples per channel.
+	windowLen uint64
+}
+
+// windowFrame is a frame of the bitrate window of FrameSizeStats.
+type windowFrame struct {
+	// Size in bytes of the frame.
+	size int
+	// Number of samples per channel of the frame.
+	samples uint64
+}
+
+// newFrameSizeStats returns new frame size statistics of a stream of the given
+// sample rate, measuring the bitrate over a sliding window of the given
+// duration; a 0 value implies the default duration.
+func newFrameSizeStats(sampleRate uint32, window time.Duration) *FrameSizeStats {
+	if window == 0 {
+		window = defaultBitrateWindow
+	}
+	return &FrameSizeStats{
+		sampleRate: sampleRate,
+		windowLen:  uint64(window.Seconds() * float64(sampleRate)),
+	}
+}
+
+// Mean returns the mean frame size in bytes; or 0 if no frame has been parsed.
+func (stats *FrameSizeStats) Mean() float64 {
+	if stats.Frames == 0 {
+		return 0
+	}
+	return float64(stats.TotalSize) / float64(stats.Frames)
+}
+
+// StdDev returns the standard deviation of the frame sizes in bytes.
+func (stats *FrameSizeStats) StdDev() float64 {
+	if stats.Frames == 0 {
+		return 0
+	}
+	mean := stats.Mean()
+	return math.Sqrt(max(stats.sumSquares/float64(stats.Frames)-mean*mean, 0))
+}
+
+// Bitrate returns the average bitrate of the frames in bits per second; or 0
+// if the sample rate is unknown.
+func (stats *FrameSizeStats) Bitrate() int64 {
+	if stats.TotalSamples == 0 || stats.sampleRate == 0 {
+		return 0
+	}
+	return int64(float64(stats.TotalSize*8) * float64(stats.sampleRate) / float64(stats.TotalSamples))
+}
+
+// WindowBitrate returns the bitrate in bits per second of the most recently
+// parsed frames, spanning the duration of DecodeOptions.BitrateWindow; or 0 if
+// the sample rate is unknown. Unlike Bitrate, which averages all frames parsed
+// so far, the measured bitrate follows changes in the complexity of the audio;
+// e.g. for adaptive streaming clients to switch between FLAC and lossy
+// renditions as the bitrate exceeds the available bandwidth.
+func (stats *FrameSizeStats) WindowBitrate() int64 {
+	if stats.windowSamples == 0 || stats.sampleRate == 0 {
+		return 0
+	}
+	return int64(float64(stats.windowSize*8) * float64(stats.sampleRate) / float64(stats.windowSamples))
+}
+
+// WindowDuration returns the duration of the frames over which WindowBitrate
+// is measured; shorter than DecodeOptions.BitrateWindow until frames spanning
+// the window have been parsed, and longer by up to the duration of a frame
+// otherwise.
+func (stats *FrameSizeStats) WindowDuration() time.Duration {
+	if stats.sampleRate == 0 {
+		return 0
+	}
+	return time.Duration(float64(stats.windowSamples) / float64(stats.sampleRate) * float64(time.Second))
+}
+
+// Percentile returns an upper bound of the frame size in bytes below which the
+// given percentage of the frames fall, as derived from the histogram; e.g.
+// Percentile(95) to size a prebuffer holding all but the largest 5% of frames.
+// It returns MaxSize for percentages of 100 or more.
+func (stats *FrameSizeStats) Percentile(p float64) int {
+	if p >= 100 {
+		return stats.MaxSize
+	}
+	n := int(math.Ceil(p / 100 * float64(stats.Frames)))
+	count := 0
+	for i, nframes := range stats.Histogram {
+		count += nframes
+		if count >= n && count > 0 {
+			return min((i+1)*frameSizeBucket-1, stats.MaxSize)
+		}
+	}
+	return stats.MaxSize
+}
+
+// add adds the given frame of the given size in bytes to the statistics.
+func (stats *FrameSizeStats) add(f *frame.Frame, size int) {
+	if stats.Frames == 0 || size < stats.MinSize {
+		stats.MinSize = size
+	}
+	stats.MaxSize = max(stats.MaxSize, size)
+	stats.Frames++
+	stats.TotalSize += int64(size)
+	stats.TotalSamples += uint64(f.BlockSize)
+	stats.sumSquares += float64(size) * float64(size)
+	if f.BlockSize != 0 && stats.sampleRate != 0 {
+		bitrate := int64(size) * 8 * int64(stats.sampleRate) / int64(f.BlockSize)
+		stats.PeakBitrate = max(stats.PeakBitrate, bitrate)
+	}
+	bucket := size / frameSizeBucket
+	for len(stats.Histogram) <= bucket {
+		stats.Histogram = append(stats.Histogram, 0)
+	}
+	stats.Histogram[bucket]++
+
+	// Slide the bitrate window, retaining the most recent frames which span at
+	// least the duration of the window.
+	stats.window = append(stats.window, windowFrame{size: size, samples: uint64(f.BlockSize)})
+	stats.windowSize += int64(size)
+	stats.windowSamples += uint64(f.BlockSize)
+	for len(stats.window) > 1 && stats.windowSamples-stats.window[0].samples >= stats.windowLen {
+		stats.windowSize -= int64(stats.window[0].size)
+		stats.windowSamples -= stats.window[0].samples
+		stats.window = stats.window[1:]
+	}
+}
+
+// FrameSizeStats returns the running statistics of the compressed sizes of the
+// audio frames parsed so far by Stream.ParseNext and Stream.ParseNextInto; or
+// nil if frame sizes are not tracked. See DecodeOptions.TrackFrameSizes. The
+// statistics are updated in place as frames are parsed.
+func (stream *Stream) FrameSizeStats() *FrameSizeStats {
+	return stream.frameSizes
+}
+
+// addFrameSize adds the given frame, which started at the given byte offset and
+// has been parsed, to the frame size statistics of the stream; and reports the
+// statistics to DecodeOptions.OnFrameSize. It is a no-op if frame sizes are not
+// tracked.
+func (stream *Stream) addFrameSize(offset int64, f *frame.Frame) {
+	if stream.frameSizes == nil {
+		return
+	}
+	size := int(stream.Offset() - offset)
+	stream.frameSizes.add(f, size)
+	if stream.opts.OnFrameSize != nil {
+		stream.opts.OnFrameSize(size, stream.frameSizes)
+	}
+}
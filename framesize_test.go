@@ -0,0 +1,153 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestFrameSizeStats(t *testing.T) {
+	data, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := append(frameOffsets(t, data), int64(len(data)))
+	var want []int
+	for i := 1; i < len(offsets); i++ {
+		want = append(want, int(offsets[i]-offsets[i-1]))
+	}
+
+	for _, lowMemory := range []bool{false, true} {
+		var sizes []int
+		opts := &flac.DecodeOptions{
+			LowMemory: lowMemory,
+			OnFrameSize: func(size int, stats *flac.FrameSizeStats) {
+				sizes = append(sizes, size)
+			},
+		}
+		stream, err := flac.NewWithOptions(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			if _, err := stream.ParseNext(); err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+		}
+		if !slices.Equal(sizes, want) {
+			t.Errorf("lowMemory=%v: frame sizes mismatch; expected %v, got %v", lowMemory, want, sizes)
+		}
+		stats := stream.FrameSizeStats()
+		if stats.Frames != len(want) || stats.MinSize != slices.Min(want) || stats.MaxSize != slices.Max(want) {
+			t.Errorf("lowMemory=%v: expected %d frames of %d to %d bytes, got %d frames of %d to %d bytes", lowMemory, len(want), slices.Min(want), slices.Max(want), stats.Frames, stats.MinSize, stats.MaxSize)
+		}
+		if wantSize := offsets[len(offsets)-1] - offsets[0]; stats.TotalSize != wantSize {
+			t.Errorf("lowMemory=%v: total size mismatch; expected %d, got %d", lowMemory, wantSize, stats.TotalSize)
+		}
+		if stats.TotalSamples != stream.Info.NSamples {
+			t.Errorf("lowMemory=%v: total samples mismatch; expected %d, got %d", lowMemory, stream.Info.NSamples, stats.TotalSamples)
+		}
+		nframes := 0
+		for _, n := range stats.Histogram {
+			nframes += n
+		}
+		if nframes != stats.Frames {
+			t.Errorf("lowMemory=%v: histogram count mismatch; expected %d, got %d", lowMemory, stats.Frames, nframes)
+		}
+		if got := stats.Percentile(100); got != stats.MaxSize {
+			t.Errorf("lowMemory=%v: 100th percentile mismatch; expected %d, got %d", lowMemory, stats.MaxSize, got)
+		}
+		if p50 := stats.Percentile(50); p50 < stats.MinSize || p50 > stats.MaxSize {
+			t.Errorf("lowMemory=%v: 50th percentile %d out of range [%d, %d]", lowMemory, p50, stats.MinSize, stats.MaxSize)
+		}
+		if bitrate := stats.Bitrate(); bitrate <= 0 || stats.PeakBitrate < bitrate {
+			t.Errorf("lowMemory=%v: invalid bitrates; average %d, peak %d", lowMemory, bitrate, stats.PeakBitrate)
+		}
+		if mean := stats.Mean(); mean < float64(stats.MinSize) || mean > float64(stats.MaxSize) || stats.StdDev() <= 0 {
+			t.Errorf("lowMemory=%v: invalid mean %v or standard deviation %v", lowMemory, mean, stats.StdDev())
+		}
+	}
+
+	// Frame sizes are not tracked by default.
+	stream, err := flac.NewWithOptions(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := stream.FrameSizeStats(); stats != nil {
+		t.Errorf("expected untracked frame sizes, got %+v", stats)
+	}
+}
+
+func TestFrameSizeStatsWindow(t *testing.T) {
+	data, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const window = 250 * time.Millisecond
+	var (
+		sizes   []int
+		samples []uint64
+		total   uint64
+		// Number of frames of the window of the last frame.
+		nwindow int
+	)
+	opts := &flac.DecodeOptions{
+		BitrateWindow: window,
+		OnFrameSize: func(size int, stats *flac.FrameSizeStats) {
+			sizes = append(sizes, size)
+			samples = append(samples, stats.TotalSamples-total)
+			total = stats.TotalSamples
+			// The window holds the most recent frames spanning the window.
+			windowLen := uint64(window.Seconds() * float64(44100))
+			var (
+				n       uint64
+				nbytes  int64
+				nframes int
+			)
+			for i := len(sizes) - 1; i >= 0 && n < windowLen; i-- {
+				n += samples[i]
+				nbytes += int64(sizes[i])
+				nframes++
+			}
+			nwindow = nframes
+			if want := int64(float64(nbytes*8) * 44100 / float64(n)); stats.WindowBitrate() != want {
+				t.Errorf("frame %d: window bitrate mismatch; expected %d, got %d", len(sizes)-1, want, stats.WindowBitrate())
+			}
+			if n >= windowLen && stats.WindowDuration() < window {
+				t.Errorf("frame %d: window duration %v shorter than %v", len(sizes)-1, stats.WindowDuration(), window)
+			}
+		},
+	}
+	stream, err := flac.NewWithOptions(bytes.NewReader(data), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.Info.SampleRate != 44100 {
+		t.Fatalf("sample rate mismatch; expected 44100, got %d", stream.Info.SampleRate)
+	}
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+	}
+	// The window slides past the first frames.
+	if nwindow == 0 || nwindow >= len(sizes) {
+		t.Errorf("window of %d frames does not slide over %d frames", nwindow, len(sizes))
+	}
+
+	// Invalid window.
+	if _, err := flac.NewWithOptions(bytes.NewReader(data), &flac.DecodeOptions{BitrateWindow: -time.Second}); err == nil {
+		t.Error("expected error for negative bitrate window")
+	}
+}
@@ -0,0 +1,155 @@
+package flac
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// Gain returns a sample transform which scales the audio samples by the given
+// gain in decibels. Samples are clipped to the range of the sample size of the
+// frame.
+func Gain(db float64) Transform {
+	return GainWithOptions(&GainOptions{Gain: db})
+}
+
+// GainOptions specifies the options of a gain transform.
+type GainOptions struct {
+	// Gain in decibels.
+	Gain float64
+	// Peak sample amplitude of the source audio relative to full scale, e.g. as
+	// specified by the REPLAYGAIN_TRACK_PEAK tag; or 0 if unknown. If non-zero,
+	// the gain is reduced to prevent the scaled peak from exceeding full scale.
+	// Samples exceeding the range of the output sample size are clipped
+	// regardless.
+	Peak float64
+	// Sample size of the output audio samples; or 0 to retain the sample size of
+	// each frame. The frame header is updated accordingly.
+	BitsPerSample uint8
+	// Dither enables triangular probability density function (TPDF) dither of
+	// the scaled audio samples prior to quantization to the output sample size.
+	// The pseudo-random sequence of the dither noise is deterministic.
+	Dither bool
+}
+
+// GainWithOptions returns a sample transform which scales the audio samples by
+// the gain of the given options, and quantizes the scaled samples to the output
+// sample size. Combined with the gain options derived from the ReplayGain tags
+// of a stream (see Stream.ReplayGain), the transform enables normalization as
+// part of a transcode pipeline:
+//
+//	opts, err := src.ReplayGain(flac.ReplayGainAlbum, 0)
+//	if err != nil {
+//		return err
+//	}
+//	opts.Dither = true
+//	transforms := []flac.Transform{flac.GainWithOptions(opts)}
+func GainWithOptions(opts *GainOptions) Transform {
+	scale := math.Pow(10, opts.Gain/20)
+	if opts.Peak > 0 && opts.Peak*scale > 1 {
+		scale = 1 / opts.Peak
+	}
+	bps := opts.BitsPerSample
+	rnd := rand.New(rand.NewSource(1))
+	return func(f *frame.Frame) error {
+		outBps := f.BitsPerSample
+		if bps != 0 {
+			outBps = bps
+		}
+		// Scale to the output sample size.
+		s := math.Ldexp(scale, int(outBps)-int(f.BitsPerSample))
+		min, max := sampleRange(outBps)
+		for _, subframe := range f.Subframes {
+			for i, sample := range subframe.Samples {
+				x := float64(sample) * s
+				if opts.Dither {
+					// TPDF noise of +/- 1 LSB of the output sample size.
+					x += rnd.Float64() - rnd.Float64()
+				}
+				subframe.Samples[i] = int32(clamp(math.Round(x), min, max))
+			}
+		}
+		f.BitsPerSample = outBps
+		return nil
+	}
+}
+
+// ReplayGainMode specifies whether to apply the track or album gain of
+// ReplayGain tags.
+type ReplayGainMode uint8
+
+// ReplayGain modes.
+const (
+	// ReplayGainTrack applies the REPLAYGAIN_TRACK_GAIN and
+	// REPLAYGAIN_TRACK_PEAK tags, normalizing each track individually.
+	ReplayGainTrack ReplayGainMode = iota
+	// ReplayGainAlbum applies the REPLAYGAIN_ALBUM_GAIN and
+	// REPLAYGAIN_ALBUM_PEAK tags, retaining the relative loudness of the tracks
+	// of an album. The track tags are used if the album tags are not present.
+	ReplayGainAlbum
+)
+
+// String returns the string representation of the ReplayGain mode.
+func (mode ReplayGainMode) String() string {
+	switch mode {
+	case ReplayGainTrack:
+		return "track"
+	case ReplayGainAlbum:
+		return "album"
+	}
+	return fmt.Sprintf("ReplayGainMode(%d)", uint8(mode))
+}
+
+// ReplayGain returns the gain options derived from the ReplayGain tags of the
+// VorbisComment metadata block of the stream, in the given mode, with the given
+// pre-amplification in decibels added to the gain. The peak tag, if present, is
+// used for clipping prevention.
+//
+// The metadata blocks of the stream must have been parsed; e.g. using Parse or
+// ParseFile.
+func (stream *Stream) ReplayGain(mode ReplayGainMode, preamp float64) (*GainOptions, error) {
+	var tags [][2]string
+	for _, block := range stream.Blocks {
+		if comment, ok := block.Body.(*meta.VorbisComment); ok {
+			tags = comment.Tags
+			break
+		}
+	}
+	gainTag, peakTag := "REPLAYGAIN_TRACK_GAIN", "REPLAYGAIN_TRACK_PEAK"
+	switch mode {
+	case ReplayGainTrack:
+	case ReplayGainAlbum:
+		if _, ok := findTag(tags, "REPLAYGAIN_ALBUM_GAIN"); ok {
+			gainTag, peakTag = "REPLAYGAIN_ALBUM_GAIN", "REPLAYGAIN_ALBUM_PEAK"
+		}
+	default:
+		return nil, fmt.Errorf("flac.Stream.ReplayGain: invalid ReplayGain mode %v", mode)
+	}
+	val, ok := findTag(tags, gainTag)
+	if !ok {
+		return nil, fmt.Errorf("flac.Stream.ReplayGain: %s tag not present", gainTag)
+	}
+	// The gain is specified in decibels, e.g. "-7.03 dB".
+	val = strings.TrimSpace(val)
+	if n := len(val) - len("dB"); n >= 0 && strings.EqualFold(val[n:], "dB") {
+		val = strings.TrimSpace(val[:n])
+	}
+	gain, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return nil, fmt.Errorf("flac.Stream.ReplayGain: invalid %s tag; %v", gainTag, err)
+	}
+	opts := &GainOptions{Gain: gain + preamp}
+	if val, ok := findTag(tags, peakTag); ok {
+		peak, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return nil, fmt.Errorf("flac.Stream.ReplayGain: invalid %s tag; %v", peakTag, err)
+		}
+		opts.Peak = peak
+	}
+	return opts, nil
+}
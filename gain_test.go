@@ -0,0 +1,121 @@
+package flac_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestReplayGain(t *testing.T) {
+	tags := [][2]string{
+		{"REPLAYGAIN_TRACK_GAIN", "-7.03 dB"},
+		{"replaygain_track_peak", "0.988831"},
+		{"REPLAYGAIN_ALBUM_GAIN", "+1.50 db"},
+		{"REPLAYGAIN_ALBUM_PEAK", " 0.5 "},
+	}
+	golden := []struct {
+		name   string
+		tags   [][2]string
+		mode   flac.ReplayGainMode
+		preamp float64
+		want   flac.GainOptions
+		err    bool
+	}{
+		{name: "track", tags: tags, mode: flac.ReplayGainTrack, want: flac.GainOptions{Gain: -7.03, Peak: 0.988831}},
+		{name: "album", tags: tags, mode: flac.ReplayGainAlbum, preamp: 3, want: flac.GainOptions{Gain: 4.5, Peak: 0.5}},
+		{name: "album fallback", tags: tags[:2], mode: flac.ReplayGainAlbum, want: flac.GainOptions{Gain: -7.03, Peak: 0.988831}},
+		{name: "no peak", tags: tags[:1], mode: flac.ReplayGainTrack, want: flac.GainOptions{Gain: -7.03}},
+		{name: "missing", tags: tags[2:], mode: flac.ReplayGainTrack, err: true},
+		{name: "invalid", tags: [][2]string{{"REPLAYGAIN_TRACK_GAIN", "loud"}}, mode: flac.ReplayGainTrack, err: true},
+	}
+	for _, g := range golden {
+		stream := &flac.Stream{
+			Info:   &meta.StreamInfo{},
+			Blocks: []*meta.Block{{Body: &meta.VorbisComment{Tags: g.tags}}},
+		}
+		got, err := stream.ReplayGain(g.mode, g.preamp)
+		if g.err {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", g.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error; %v", g.name, err)
+			continue
+		}
+		if math.Abs(got.Gain-g.want.Gain) > 1e-9 || got.Peak != g.want.Peak {
+			t.Errorf("%s: gain options mismatch; expected %+v, got %+v", g.name, g.want, *got)
+		}
+	}
+}
+
+func TestGainWithOptions(t *testing.T) {
+	newFrame := func(samples ...int32) *frame.Frame {
+		return &frame.Frame{
+			Header:    frame.Header{BitsPerSample: 16},
+			Subframes: []*frame.Subframe{{Samples: samples, NSamples: len(samples)}},
+		}
+	}
+	golden := []struct {
+		name string
+		opts flac.GainOptions
+		in   []int32
+		want []int32
+		bps  uint8
+	}{
+		{name: "attenuate", opts: flac.GainOptions{Gain: -6.0206}, in: []int32{1000, -1000, 32766}, want: []int32{500, -500, 16383}, bps: 16},
+		// Without a peak, samples exceeding full scale are clipped.
+		{name: "clip", opts: flac.GainOptions{Gain: 6.0206}, in: []int32{20000, -20000}, want: []int32{32767, -32768}, bps: 16},
+		// With a peak, the gain is limited to prevent clipping.
+		{name: "peak", opts: flac.GainOptions{Gain: 6.0206, Peak: 0.8}, in: []int32{16384, -16384}, want: []int32{20480, -20480}, bps: 16},
+		{name: "24-bit", opts: flac.GainOptions{BitsPerSample: 24}, in: []int32{1000, -1}, want: []int32{256000, -256}, bps: 24},
+		{name: "8-bit", opts: flac.GainOptions{BitsPerSample: 8}, in: []int32{1000, -32768}, want: []int32{4, -128}, bps: 8},
+	}
+	for _, g := range golden {
+		f := newFrame(g.in...)
+		if err := flac.GainWithOptions(&g.opts)(f); err != nil {
+			t.Errorf("%s: unexpected error; %v", g.name, err)
+			continue
+		}
+		if f.BitsPerSample != g.bps {
+			t.Errorf("%s: sample size mismatch; expected %d, got %d", g.name, g.bps, f.BitsPerSample)
+		}
+		for i, want := range g.want {
+			if got := f.Subframes[0].Samples[i]; got != want {
+				t.Errorf("%s: sample %d mismatch; expected %d, got %d", g.name, i, want, got)
+			}
+		}
+	}
+}
+
+func TestGainDither(t *testing.T) {
+	// A constant signal of half an LSB of the output sample size, which is lost
+	// without dither, and retained on average with dither.
+	const n = 100000
+	samples := make([]int32, n)
+	for i := range samples {
+		samples[i] = 128
+	}
+	f := &frame.Frame{
+		Header:    frame.Header{BitsPerSample: 16},
+		Subframes: []*frame.Subframe{{Samples: samples, NSamples: n}},
+	}
+	transform := flac.GainWithOptions(&flac.GainOptions{BitsPerSample: 8, Dither: true})
+	if err := transform(f); err != nil {
+		t.Fatal(err)
+	}
+	var sum float64
+	for _, sample := range f.Subframes[0].Samples {
+		if sample < -1 || sample > 2 {
+			t.Fatalf("dithered sample %d out of range", sample)
+		}
+		sum += float64(sample)
+	}
+	if mean := sum / n; math.Abs(mean-0.5) > 0.01 {
+		t.Errorf("mean mismatch of dithered samples; expected 0.5, got %.3f", mean)
+	}
+}
@@ -5,8 +5,10 @@ go 1.23.2
 toolchain go1.24.5
 
 require (
+	github.com/go-audio/audio v1.0.0
 	github.com/icza/bitio v1.1.0
 	github.com/mewkiz/pkg v0.0.0-20250417130911-3f050ff8c56d
+	golang.org/x/text v0.28.0
 )
 
 require github.com/mewpkg/term v0.0.0-20241026122259-37a80af23985 // indirect
@@ -0,0 +1,263 @@
+// Package goaudio provides conversion between FLAC streams and the audio
+// buffers of github.com/go-audio/audio, to enable the use of FLAC streams with
+// the transforms and WAV writers of the go-audio ecosystem.
+//
+// The audio samples of go-audio buffers are interleaved; i.e. the first sample
+// of each channel is followed by the second sample of each channel, etc.
+package goaudio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/go-audio/audio"
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+// Format returns the go-audio format of the given FLAC stream.
+func Format(stream *flac.Stream) *audio.Format {
+	return &audio.Format{
+		NumChannels: int(stream.Info.NChannels),
+		SampleRate:  int(stream.Info.SampleRate),
+	}
+}
+
+// --- [ Decoder ] -------------------------------------------------------------
+
+// A Decoder decodes the audio samples of a FLAC stream into go-audio buffers.
+type Decoder struct {
+	// Underlying FLAC stream.
+	stream *flac.Stream
+	// Current audio frame; nil if no frame has been decoded.
+	f *frame.Frame
+	// Position of the next sample (per channel) of the current audio frame.
+	pos int
+}
+
+// NewDecoder returns a new decoder for the audio samples of the given FLAC
+// stream.
+func NewDecoder(stream *flac.Stream) *Decoder {
+	return &Decoder{stream: stream}
+}
+
+// Format returns the go-audio format of the decoded audio samples.
+func (dec *Decoder) Format() *audio.Format {
+	return Format(dec.stream)
+}
+
+// PCMBuffer fills buf with interleaved audio samples decoded from the FLAC
+// stream, and returns the number of samples written to buf.Data. The format and
+// source bit depth of buf are set to those of the stream. Frames are decoded on
+// demand, and samples of partially consumed frames are retained for the next
+// call to PCMBuffer. It returns io.EOF to signal a graceful end of FLAC stream,
+// once all samples have been consumed.
+//
+// Only complete sample frames (one sample per channel) are written, so n is a
+// multiple of the number of channels.
+func (dec *Decoder) PCMBuffer(buf *audio.IntBuffer) (n int, err error) {
+	if buf == nil {
+		return 0, audio.ErrInvalidBuffer
+	}
+	buf.Format = dec.Format()
+	buf.SourceBitDepth = int(dec.stream.Info.BitsPerSample)
+	nchannels := int(dec.stream.Info.NChannels)
+	for n+nchannels <= len(buf.Data) {
+		if dec.f == nil || dec.pos >= dec.f.Subframes[0].NSamples {
+			f, err := dec.stream.ParseNext()
+			if err != nil {
+				if err == io.EOF && n > 0 {
+					return n, nil
+				}
+				return n, err
+			}
+			if len(f.Subframes) != nchannels {
+				return n, fmt.Errorf("goaudio.Decoder.PCMBuffer: channel count mismatch of frame %d; expected %d, got %d", f.Num, nchannels, len(f.Subframes))
+			}
+			dec.f, dec.pos = f, 0
+		}
+		// Interleave samples of the current frame.
+		for ; dec.pos < dec.f.Subframes[0].NSamples && n+nchannels <= len(buf.Data); dec.pos++ {
+			for _, subframe := range dec.f.Subframes {
+				buf.Data[n] = int(subframe.Samples[dec.pos])
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// FullPCMBuffer decodes the remaining audio samples of the FLAC stream into a
+// new buffer.
+func (dec *Decoder) FullPCMBuffer() (*audio.IntBuffer, error) {
+	nchannels := int(dec.stream.Info.NChannels)
+	buf := &audio.IntBuffer{
+		Format:         dec.Format(),
+		SourceBitDepth: int(dec.stream.Info.BitsPerSample),
+	}
+	if nsamples := dec.stream.Info.NSamples; nsamples != 0 {
+		buf.Data = make([]int, 0, int(nsamples)*nchannels)
+	}
+	chunk := &audio.IntBuffer{Data: make([]int, 4096*nchannels)}
+	for {
+		n, err := dec.PCMBuffer(chunk)
+		if err != nil {
+			if err == io.EOF {
+				return buf, nil
+			}
+			return buf, err
+		}
+		buf.Data = append(buf.Data, chunk.Data[:n]...)
+	}
+}
+
+// --- [ Encoder ] -------------------------------------------------------------
+
+// An Encoder encodes the interleaved audio samples of go-audio buffers into a
+// FLAC stream. Audio samples are buffered until a complete block is available,
+// which is encoded as a frame with a fixed block size.
+type Encoder struct {
+	// Underlying FLAC encoder.
+	enc *flac.Encoder
+	// Block size (in samples) of encoded frames.
+	blockSize int
+	// Pending audio samples, one slice per channel.
+	pending [][]int32
+}
+
+// defaultBlockSize specifies the block size of encoded frames if the maximum
+// block size of StreamInfo is unspecified.
+const defaultBlockSize = 4096
+
+// NewEncoder returns a new encoder which writes the audio samples of go-audio
+// buffers to the given FLAC encoder. The block size of encoded frames is the
+// maximum block size of the StreamInfo block of enc, or 4096 if unspecified.
+func NewEncoder(enc *flac.Encoder) *Encoder {
+	blockSize := int(enc.Info.BlockSizeMax)
+	if blockSize == 0 {
+		blockSize = defaultBlockSize
+	}
+	return &Encoder{
+		enc:       enc,
+		blockSize: blockSize,
+		pending:   make([][]int32, enc.Info.NChannels),
+	}
+}
+
+// Write encodes the interleaved audio samples of buf. The sample values of buf
+// must fit within the sample size of the StreamInfo block of the encoder. The
+// format of buf, if present, must match the StreamInfo block of the encoder.
+func (e *Encoder) Write(buf *audio.IntBuffer) error {
+	if buf == nil {
+		return audio.ErrInvalidBuffer
+	}
+	nchannels := len(e.pending)
+	if buf.Format != nil {
+		if buf.Format.NumChannels != nchannels {
+			return fmt.Errorf("goaudio.Encoder.Write: channel count mismatch; expected %d, got %d", nchannels, buf.Format.NumChannels)
+		}
+		if buf.Format.SampleRate != int(e.enc.Info.SampleRate) {
+			return fmt.Errorf("goaudio.Encoder.Write: sample rate mismatch; expected %d, got %d", e.enc.Info.SampleRate, buf.Format.SampleRate)
+		}
+	}
+	if len(buf.Data)%nchannels != 0 {
+		return fmt.Errorf("goaudio.Encoder.Write: number of samples (%d) not a multiple of the channel count (%d)", len(buf.Data), nchannels)
+	}
+	// Deinterleave samples.
+	for i := 0; i < len(buf.Data); i += nchannels {
+		for channel := range e.pending {
+			e.pending[channel] = append(e.pending[channel], int32(buf.Data[i+channel]))
+		}
+		if len(e.pending[0]) == e.blockSize {
+			if err := e.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close encodes the pending audio samples as a final frame, and closes the
+// underlying FLAC encoder.
+func (e *Encoder) Close() error {
+	if len(e.pending) > 0 && len(e.pending[0]) > 0 {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+	return e.enc.Close()
+}
+
+// flush encodes the pending audio samples as a frame.
+func (e *Encoder) flush() error {
+	info := e.enc.Info
+	nsamples := len(e.pending[0])
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(nsamples),
+			SampleRate:        info.SampleRate,
+			Channels:          frame.Channels(len(e.pending) - 1),
+			BitsPerSample:     info.BitsPerSample,
+		},
+		Subframes: make([]*frame.Subframe, len(e.pending)),
+	}
+	for channel, samples := range e.pending {
+		f.Subframes[channel] = &frame.Subframe{
+			SubHeader: frame.SubHeader{
+				Pred: frame.PredVerbatim,
+			},
+			Samples:  samples,
+			NSamples: nsamples,
+		}
+		e.pending[channel] = make([]int32, 0, e.blockSize)
+	}
+	return e.enc.WriteFrame(f)
+}
+
+// --- [ Float conversion ] ----------------------------------------------------
+
+// FloatBuffer returns a copy of buf with sample values normalized to the range
+// [-1, 1), based on the source bit depth of buf.
+func FloatBuffer(buf *audio.IntBuffer) (*audio.FloatBuffer, error) {
+	if buf.SourceBitDepth < 1 || buf.SourceBitDepth > 32 {
+		return nil, errors.New("goaudio.FloatBuffer: invalid source bit depth")
+	}
+	scale := 1 / float64(int64(1)<<(buf.SourceBitDepth-1))
+	fbuf := &audio.FloatBuffer{
+		Format: buf.Format,
+		Data:   make([]float64, len(buf.Data)),
+	}
+	for i, sample := range buf.Data {
+		fbuf.Data[i] = float64(sample) * scale
+	}
+	return fbuf, nil
+}
+
+// IntBuffer returns a copy of buf with sample values in the range [-1, 1)
+// scaled to the given bit depth. Sample values outside of the range are
+// clipped.
+func IntBuffer(buf *audio.FloatBuffer, bitDepth int) (*audio.IntBuffer, error) {
+	if bitDepth < 1 || bitDepth > 32 {
+		return nil, fmt.Errorf("goaudio.IntBuffer: invalid bit depth %d", bitDepth)
+	}
+	scale := float64(int64(1) << (bitDepth - 1))
+	max, min := scale-1, -scale
+	ibuf := &audio.IntBuffer{
+		Format:         buf.Format,
+		Data:           make([]int, len(buf.Data)),
+		SourceBitDepth: bitDepth,
+	}
+	for i, sample := range buf.Data {
+		x := math.Round(sample * scale)
+		if x > max {
+			x = max
+		} else if x < min {
+			x = min
+		}
+		ibuf.Data[i] = int(x)
+	}
+	return ibuf, nil
+}
@@ -0,0 +1,153 @@
+package goaudio_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-audio/audio"
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/goaudio"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestRoundTrip(t *testing.T) {
+	src, err := flac.ParseFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dec := goaudio.NewDecoder(src)
+	buf, err := dec.FullPCMBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nchannels := int(src.Info.NChannels)
+	if got, want := len(buf.Data), int(src.Info.NSamples)*nchannels; got != want {
+		t.Fatalf("sample count mismatch; expected %d, got %d", want, got)
+	}
+
+	// Encode in chunks of odd size, not aligned with the block size.
+	info := *src.Info
+	out := &bytes.Buffer{}
+	enc, err := flac.NewEncoder(out, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genc := goaudio.NewEncoder(enc)
+	const chunkSize = 1001
+	for i := 0; i < len(buf.Data); i += chunkSize * nchannels {
+		end := i + chunkSize*nchannels
+		if end > len(buf.Data) {
+			end = len(buf.Data)
+		}
+		chunk := &audio.IntBuffer{Format: buf.Format, Data: buf.Data[i:end]}
+		if err := genc.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := genc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the MD5 checksum of the re-encoded stream.
+	stream, err := flac.New(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5sum := md5.New()
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		f.Hash(md5sum)
+	}
+	var got [md5.Size]byte
+	copy(got[:], md5sum.Sum(nil))
+	if got != src.Info.MD5sum {
+		t.Errorf("MD5 checksum mismatch; expected %x, got %x", src.Info.MD5sum, got)
+	}
+}
+
+func TestEncoderDefaultBlockSize(t *testing.T) {
+	// StreamInfo with unknown block sizes, which are filled in by the encoder.
+	info := &meta.StreamInfo{SampleRate: 44100, NChannels: 1, BitsPerSample: 16}
+	path := filepath.Join(t.TempDir(), "default.flac")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := flac.NewEncoder(f, info)
+	if err != nil {
+		f.Close()
+		t.Fatal(err)
+	}
+	genc := goaudio.NewEncoder(enc)
+	const nsamples = 10000
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: 1, SampleRate: 44100},
+		Data:           make([]int, nsamples),
+		SourceBitDepth: 16,
+	}
+	for i := range buf.Data {
+		buf.Data[i] = i%200 - 100
+	}
+	if err := genc.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := genc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Frames hold 4096 samples, except for the last frame.
+	stream, err := flac.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if stream.Info.BlockSizeMax != 4096 {
+		t.Errorf("maximum block size mismatch; expected 4096, got %d", stream.Info.BlockSizeMax)
+	}
+	var blockSizes []uint16
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		blockSizes = append(blockSizes, f.BlockSize)
+	}
+	if want := []uint16{4096, 4096, nsamples - 2*4096}; !slices.Equal(blockSizes, want) {
+		t.Errorf("block sizes mismatch; expected %v, got %v", want, blockSizes)
+	}
+}
+
+func TestFloatBuffer(t *testing.T) {
+	ibuf := &audio.IntBuffer{Data: []int{-32768, -1, 0, 1, 32767}, SourceBitDepth: 16}
+	fbuf, err := goaudio.FloatBuffer(ibuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fbuf.Data[0] != -1 {
+		t.Errorf("minimum sample mismatch; expected -1, got %v", fbuf.Data[0])
+	}
+	got, err := goaudio.IntBuffer(fbuf, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range ibuf.Data {
+		if got.Data[i] != ibuf.Data[i] {
+			t.Errorf("sample %d mismatch; expected %d, got %d", i, ibuf.Data[i], got.Data[i])
+		}
+	}
+}
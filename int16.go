@@ -0,0 +1,51 @@
+package flac
+
+import (
+	"fmt"
+	"io"
+)
+
+// ReadInt16 fills dst with interleaved 16-bit audio samples decoded from the
+// stream, and returns the number of samples written to dst; e.g. to feed audio
+// devices expecting signed 16-bit samples in the dominant case of CD audio.
+// Frames are decoded on demand, and samples of partially consumed frames are
+// retained for the next call to ReadInt16. It returns io.EOF to signal a
+// graceful end of FLAC stream, once all samples have been consumed.
+//
+// Only complete sample frames (one sample per channel) are written, so n is a
+// multiple of the number of channels. The decoded samples are written to dst
+// without interleaved 32-bit intermediates; see frame.Frame.InterleaveInt16. It
+// is an error for the stream to hold samples of more than 16 bits-per-sample.
+//
+// Calls to ReadInt16 should not be interleaved with calls to Stream.Next,
+// Stream.ParseNext or Stream.ParseNextInto; Stream.Seek discards the retained
+// samples.
+func (stream *Stream) ReadInt16(dst []int16) (n int, err error) {
+	if stream.Info.BitsPerSample > 16 {
+		return 0, fmt.Errorf("flac.Stream.ReadInt16: unable to represent %d-bit samples as 16-bit samples", stream.Info.BitsPerSample)
+	}
+	nchannels := int(stream.Info.NChannels)
+	for n+nchannels <= len(dst) {
+		if stream.int16Frame == nil || stream.int16Pos >= len(stream.int16Frame.Subframes[0].Samples) {
+			f, err := stream.ParseNext()
+			if err != nil {
+				stream.int16Frame = nil
+				if err == io.EOF && n > 0 {
+					return n, nil
+				}
+				return n, err
+			}
+			if len(f.Subframes) != nchannels {
+				return n, fmt.Errorf("flac.Stream.ReadInt16: channel count mismatch of frame %d; expected %d, got %d", f.Num, nchannels, len(f.Subframes))
+			}
+			stream.int16Frame, stream.int16Pos = f, 0
+		}
+		nsamples, err := stream.int16Frame.InterleaveInt16(dst[n:], stream.int16Pos)
+		if err != nil {
+			return n, err
+		}
+		stream.int16Pos += nsamples
+		n += nsamples * nchannels
+	}
+	return n, nil
+}
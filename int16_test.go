@@ -0,0 +1,78 @@
+package flac_test
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestReadInt16(t *testing.T) {
+	paths := []string{
+		"testdata/love.flac",
+		"testdata/19875.flac",
+		"testdata/44127.flac",
+		"testdata/172960.flac",
+	}
+	for _, path := range paths {
+		// Interleaved 32-bit samples.
+		stream, err := flac.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []int16
+		for {
+			f, err := stream.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+			for i := range f.Subframes[0].Samples {
+				for _, subframe := range f.Subframes {
+					want = append(want, int16(subframe.Samples[i]))
+				}
+			}
+		}
+		stream.Close()
+
+		// Interleaved 16-bit samples, read using a buffer size not evenly
+		// divisible by the number of channels nor the block size.
+		stream, err = flac.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int16
+		buf := make([]int16, 1001)
+		for {
+			n, err := stream.ReadInt16(buf)
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatalf("%q: unable to read 16-bit samples; %v", path, err)
+			}
+			if n%int(stream.Info.NChannels) != 0 {
+				t.Fatalf("%q: partial sample frame; %d samples of %d channels", path, n, stream.Info.NChannels)
+			}
+			got = append(got, buf[:n]...)
+		}
+		stream.Close()
+		if !slices.Equal(got, want) {
+			t.Errorf("%q: 16-bit samples mismatch; expected %d samples, got %d samples", path, len(want), len(got))
+		}
+	}
+
+	// Samples of more than 16 bits-per-sample.
+	stream, err := flac.ParseFile("testdata/59996.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if _, err := stream.ReadInt16(make([]int16, 1024)); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected error for 24-bit samples, got %v", err)
+	}
+}
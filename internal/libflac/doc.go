@@ -0,0 +1,9 @@
+// Package libflac decodes FLAC files using the reference implementation
+// libFLAC, for differential testing of this package against libFLAC.
+//
+// The package requires cgo and the libFLAC development files (located using
+// pkg-config), and is only built with the flac_cgo_test build tag; cgo is thus
+// never a dependency of the flac package itself.
+//
+//	go test -tags flac_cgo_test -run LibFLAC .
+package libflac
@@ -0,0 +1,123 @@
+//go:build flac_cgo_test && cgo
+
+package libflac
+
+/*
+#cgo pkg-config: flac
+#include <stdlib.h>
+#include <FLAC/stream_decoder.h>
+
+// decoded holds the interleaved audio samples decoded by libFLAC.
+typedef struct {
+	FLAC__int32 *samples;
+	size_t n, cap;
+	unsigned channels;
+	// Status of the first error reported by libFLAC, plus one; 0 for none.
+	int status;
+	// Set if out of memory.
+	int nomem;
+} decoded;
+
+static FLAC__StreamDecoderWriteStatus write_cb(const FLAC__StreamDecoder *dec, const FLAC__Frame *frame, const FLAC__int32 *const buffer[], void *client) {
+	decoded *d = client;
+	unsigned nchannels = frame->header.channels;
+	unsigned blocksize = frame->header.blocksize;
+	size_t need = d->n + (size_t)blocksize * nchannels;
+	if (need > d->cap) {
+		size_t cap = 2 * d->cap;
+		if (cap < need) {
+			cap = need;
+		}
+		FLAC__int32 *samples = realloc(d->samples, cap * sizeof(FLAC__int32));
+		if (samples == NULL) {
+			d->nomem = 1;
+			return FLAC__STREAM_DECODER_WRITE_STATUS_ABORT;
+		}
+		d->samples = samples;
+		d->cap = cap;
+	}
+	d->channels = nchannels;
+	for (unsigned i = 0; i < blocksize; i++) {
+		for (unsigned c = 0; c < nchannels; c++) {
+			d->samples[d->n++] = buffer[c][i];
+		}
+	}
+	return FLAC__STREAM_DECODER_WRITE_STATUS_CONTINUE;
+}
+
+static void metadata_cb(const FLAC__StreamDecoder *dec, const FLAC__StreamMetadata *metadata, void *client) {
+}
+
+static void error_cb(const FLAC__StreamDecoder *dec, FLAC__StreamDecoderErrorStatus status, void *client) {
+	decoded *d = client;
+	if (d->status == 0) {
+		d->status = 1 + status;
+	}
+}
+
+// error_string returns the description of the given error status.
+static const char *error_string(int status) {
+	return FLAC__StreamDecoderErrorStatusString[status];
+}
+
+// decode_file decodes the FLAC file at the given path into d, verifying the
+// MD5 checksum of the audio samples. It returns 0 on success.
+static int decode_file(const char *path, decoded *d) {
+	FLAC__StreamDecoder *dec = FLAC__stream_decoder_new();
+	if (dec == NULL) {
+		d->nomem = 1;
+		return -1;
+	}
+	FLAC__stream_decoder_set_md5_checking(dec, 1);
+	if (FLAC__stream_decoder_init_file(dec, path, write_cb, metadata_cb, error_cb, d) != FLAC__STREAM_DECODER_INIT_STATUS_OK) {
+		FLAC__stream_decoder_delete(dec);
+		return -1;
+	}
+	FLAC__bool ok = FLAC__stream_decoder_process_until_end_of_stream(dec);
+	// finish reports MD5 checksum mismatches.
+	if (!FLAC__stream_decoder_finish(dec)) {
+		ok = 0;
+	}
+	FLAC__stream_decoder_delete(dec);
+	return ok && d->status == 0 && !d->nomem ? 0 : -1;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Decode decodes the FLAC file at the given path using libFLAC, and returns its
+// audio samples by channel. The MD5 checksum of the audio samples is verified
+// by libFLAC.
+func Decode(path string) ([][]int32, error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	var d C.decoded
+	defer C.free(unsafe.Pointer(d.samples))
+	if C.decode_file(cpath, &d) != 0 {
+		switch {
+		case d.nomem != 0:
+			return nil, fmt.Errorf("libflac.Decode: %q: out of memory", path)
+		case d.status != 0:
+			return nil, fmt.Errorf("libflac.Decode: %q: %s", path, C.GoString(C.error_string(d.status-1)))
+		default:
+			return nil, fmt.Errorf("libflac.Decode: %q: unable to decode file", path)
+		}
+	}
+	nchannels := int(d.channels)
+	if nchannels == 0 {
+		return nil, nil
+	}
+	interleaved := unsafe.Slice((*int32)(unsafe.Pointer(d.samples)), int(d.n))
+	samples := make([][]int32, nchannels)
+	for channel := range samples {
+		samples[channel] = make([]int32, len(interleaved)/nchannels)
+		for i := range samples[channel] {
+			samples[channel][i] = interleaved[i*nchannels+channel]
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,131 @@
+// Package pmath implements portable elementary functions, which return
+// bit-identical results on every platform and Go version.
+//
+// The functions of the math package may be implemented in architecture-specific
+// assembly, and may as such differ in the last bits of their results between
+// platforms. The functions of this package are implemented using only IEEE 754
+// basic arithmetic, which is correctly rounded, and explicit conversions which
+// prevent the fusion of multiply-add operations.
+//
+// The results are accurate to within a few units in the last place.
+package pmath
+
+import "math"
+
+// Cos returns the cosine of the radian argument x.
+func Cos(x float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return math.NaN()
+	}
+	// Reduce x to [0, π/4], using the symmetries of cos.
+	const twoPi = 2 * math.Pi
+	x = math.Abs(x)
+	x -= float64(twoPi * math.Floor(x/twoPi))
+	if x > math.Pi {
+		x = twoPi - x
+	}
+	sign := 1.0
+	if x > math.Pi/2 {
+		x = math.Pi - x
+		sign = -1
+	}
+	if x > math.Pi/4 {
+		// cos(x) = sin(π/2 - x)
+		x = math.Pi/2 - x
+		return sign * float64(x*horner(sinCoeffs, x*x))
+	}
+	return sign * horner(cosCoeffs, x*x)
+}
+
+// Exp returns e**x, the base-e exponential of x.
+func Exp(x float64) float64 {
+	switch {
+	case math.IsNaN(x) || math.IsInf(x, 1):
+		return x
+	case math.IsInf(x, -1):
+		return 0
+	case x > 709.8:
+		return math.Inf(1)
+	case x < -745.2:
+		return 0
+	}
+	// Reduce x to r in [-ln(2)/2, ln(2)/2], with x = n*ln(2) + r; ln(2) is
+	// split into a high part, of which the product with n is exact, and a low
+	// part.
+	const (
+		ln2Hi = 6.93147180369123816490e-01
+		ln2Lo = 1.90821492927058770002e-10
+	)
+	n := math.Round(x / math.Ln2)
+	r := x - float64(n*ln2Hi) - float64(n*ln2Lo)
+	return math.Ldexp(horner(expCoeffs, r), int(n))
+}
+
+// Log2 returns the binary logarithm of x.
+func Log2(x float64) float64 {
+	switch {
+	case math.IsNaN(x) || x < 0:
+		return math.NaN()
+	case x == 0:
+		return math.Inf(-1)
+	case math.IsInf(x, 1):
+		return x
+	}
+	// Reduce x to frac in [sqrt(2)/2, sqrt(2)), with x = frac * 2**exp.
+	frac, exp := math.Frexp(x)
+	if frac < math.Sqrt2/2 {
+		frac *= 2
+		exp--
+	}
+	// ln(frac) = 2*atanh(s), with s = (frac-1)/(frac+1).
+	s := (frac - 1) / (frac + 1)
+	ln := 2 * float64(s*horner(atanhCoeffs, s*s))
+	return float64(exp) + ln/math.Ln2
+}
+
+// horner evaluates the polynomial of the given coefficients, in order of
+// increasing degree, at x.
+func horner(coeffs []float64, x float64) float64 {
+	p := 0.0
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		p = float64(p*x) + coeffs[i]
+	}
+	return p
+}
+
+var (
+	// Taylor coefficients of cos(x), in powers of x**2.
+	cosCoeffs = taylor(10, func(k int) float64 { return sign(k) / factorial(2*k) })
+	// Taylor coefficients of sin(x)/x, in powers of x**2.
+	sinCoeffs = taylor(10, func(k int) float64 { return sign(k) / factorial(2*k+1) })
+	// Taylor coefficients of e**x.
+	expCoeffs = taylor(18, func(k int) float64 { return 1 / factorial(k) })
+	// Taylor coefficients of atanh(x)/x, in powers of x**2.
+	atanhCoeffs = taylor(14, func(k int) float64 { return 1 / float64(2*k+1) })
+)
+
+// taylor returns the first n coefficients of a series.
+func taylor(n int, coeff func(k int) float64) []float64 {
+	coeffs := make([]float64, n)
+	for k := range coeffs {
+		coeffs[k] = coeff(k)
+	}
+	return coeffs
+}
+
+// sign returns (-1)**k.
+func sign(k int) float64 {
+	if k%2 == 1 {
+		return -1
+	}
+	return 1
+}
+
+// factorial returns n!.
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
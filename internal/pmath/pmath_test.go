@@ -0,0 +1,51 @@
+package pmath_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mewkiz/flac/internal/pmath"
+)
+
+func TestFuncs(t *testing.T) {
+	golden := []struct {
+		name string
+		got  func(float64) float64
+		want func(float64) float64
+		// Range of arguments.
+		from, to float64
+	}{
+		{name: "Cos", got: pmath.Cos, want: math.Cos, from: -20, to: 20},
+		{name: "Exp", got: pmath.Exp, want: math.Exp, from: -700, to: 700},
+		{name: "Exp", got: pmath.Exp, want: math.Exp, from: -2, to: 2},
+		{name: "Log2", got: pmath.Log2, want: math.Log2, from: 1e-300, to: 1e-290},
+		{name: "Log2", got: pmath.Log2, want: math.Log2, from: 0.01, to: 1e6},
+	}
+	const n = 100000
+	for _, g := range golden {
+		for i := 0; i <= n; i++ {
+			x := g.from + (g.to-g.from)*float64(i)/n
+			got, want := g.got(x), g.want(x)
+			// Relative error, or absolute error for results close to zero.
+			if diff := math.Abs(got - want); diff > 1e-14*max(math.Abs(want), 1) {
+				t.Fatalf("%s(%v) mismatch; expected %v, got %v", g.name, x, want, got)
+			}
+		}
+	}
+	special := []struct {
+		name      string
+		got, want float64
+	}{
+		{name: "Cos(Inf)", got: pmath.Cos(math.Inf(1)), want: math.NaN()},
+		{name: "Exp(-Inf)", got: pmath.Exp(math.Inf(-1)), want: 0},
+		{name: "Exp(1000)", got: pmath.Exp(1000), want: math.Inf(1)},
+		{name: "Log2(0)", got: pmath.Log2(0), want: math.Inf(-1)},
+		{name: "Log2(-1)", got: pmath.Log2(-1), want: math.NaN()},
+		{name: "Log2(1024)", got: pmath.Log2(1024), want: 10},
+	}
+	for _, s := range special {
+		if s.got != s.want && !(math.IsNaN(s.got) && math.IsNaN(s.want)) {
+			t.Errorf("%s mismatch; expected %v, got %v", s.name, s.want, s.got)
+		}
+	}
+}
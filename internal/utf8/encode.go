@@ -54,7 +54,7 @@ func Encode(w io.Writer, x uint64) error {
 		// if c0 == 11111110
 		// total: 36 bits (0 + 6 + 6 + 6 + 6 + 6 + 6)
 		l = 6
-		bits = 0
+		bits = t7
 	}
 	// Store bits of c0.
 	if err := ioutilx.WriteByte(w, byte(bits)); err != nil {
@@ -0,0 +1,60 @@
+package flac
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// LowLatencyBlockSize returns the largest block size (in samples) of frames
+// holding at most the given duration of audio at the given sample rate. An
+// encoder buffering one block of audio before encoding it has an algorithmic
+// delay of one block; e.g. a block size of 240 samples at 48 kHz corresponds to
+// a delay of 5 ms.
+//
+// The block size is at least 16 samples, as required by the FLAC format; an
+// error is returned if the latency is shorter than 16 samples.
+func LowLatencyBlockSize(sampleRate uint32, latency time.Duration) (uint16, error) {
+	if sampleRate == 0 || sampleRate > frame.MaxSampleRate {
+		return 0, fmt.Errorf("flac.LowLatencyBlockSize: invalid sample rate %d", sampleRate)
+	}
+	blockSize := int64(latency) * int64(sampleRate) / int64(time.Second)
+	if blockSize < frame.MinBlockSize {
+		return 0, fmt.Errorf("flac.LowLatencyBlockSize: latency %v shorter than %d samples at %d Hz", latency, frame.MinBlockSize, sampleRate)
+	}
+	if blockSize > frame.MaxBlockSize {
+		blockSize = frame.MaxBlockSize
+	}
+	return uint16(blockSize), nil
+}
+
+// LowLatencyStreamInfo returns a StreamInfo metadata block of a fixed-blocksize
+// stream with the given audio properties, for real-time links with the given
+// maximum algorithmic delay; see LowLatencyBlockSize. The block size is stored
+// as both the minimum and maximum block size of the StreamInfo block, which is
+// used as block size by encoders such as the goaudio package.
+//
+// The total number of samples and the MD5 checksum are unknown, as is common
+// for live streams.
+func LowLatencyStreamInfo(sampleRate uint32, nchannels, bitsPerSample uint8, latency time.Duration) (*meta.StreamInfo, error) {
+	if nchannels < 1 || nchannels > frame.MaxChannels {
+		return nil, fmt.Errorf("flac.LowLatencyStreamInfo: invalid channel count %d", nchannels)
+	}
+	if bitsPerSample < frame.MinBitsPerSample || bitsPerSample > frame.MaxBitsPerSample {
+		return nil, fmt.Errorf("flac.LowLatencyStreamInfo: invalid sample size %d", bitsPerSample)
+	}
+	blockSize, err := LowLatencyBlockSize(sampleRate, latency)
+	if err != nil {
+		return nil, err
+	}
+	info := &meta.StreamInfo{
+		BlockSizeMin:  blockSize,
+		BlockSizeMax:  blockSize,
+		SampleRate:    sampleRate,
+		NChannels:     nchannels,
+		BitsPerSample: bitsPerSample,
+	}
+	return info, nil
+}
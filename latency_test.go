@@ -0,0 +1,212 @@
+package flac_test
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestLowLatencyBlockSize(t *testing.T) {
+	golden := []struct {
+		sampleRate uint32
+		latency    time.Duration
+		want       uint16
+		err        bool
+	}{
+		{sampleRate: 48000, latency: 5 * time.Millisecond, want: 240},
+		{sampleRate: 44100, latency: 10 * time.Millisecond, want: 441},
+		{sampleRate: 48000, latency: 4 * time.Millisecond, want: 192},
+		{sampleRate: 8000, latency: 2 * time.Millisecond, want: 16},
+		{sampleRate: 48000, latency: 10 * time.Second, want: 65535},
+		{sampleRate: 8000, latency: time.Millisecond, err: true},
+		{sampleRate: 0, latency: time.Millisecond, err: true},
+	}
+	for _, g := range golden {
+		got, err := flac.LowLatencyBlockSize(g.sampleRate, g.latency)
+		if g.err {
+			if err == nil {
+				t.Errorf("%d Hz, %v: expected error, got nil", g.sampleRate, g.latency)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d Hz, %v: unexpected error; %v", g.sampleRate, g.latency, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%d Hz, %v: block size mismatch; expected %d, got %d", g.sampleRate, g.latency, g.want, got)
+		}
+	}
+}
+
+// encodeSmallBlocks encodes nsamples of a stereo sine wave in frames of the
+// given block size to the FLAC file at path, and returns the encoded samples
+// per channel. The last frame holds the remaining samples.
+func encodeSmallBlocks(t *testing.T, path string, blockSize uint16, nsamples int) [][]int32 {
+	info, err := flac.LowLatencyStreamInfo(48000, 2, 16, time.Duration(blockSize)*time.Second/48000+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.BlockSizeMax != blockSize {
+		t.Fatalf("block size mismatch; expected %d, got %d", blockSize, info.BlockSizeMax)
+	}
+	fw, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := flac.NewEncoder(fw, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := make([][]int32, 2)
+	for offset := 0; offset < nsamples; offset += int(blockSize) {
+		n := int(blockSize)
+		if offset+n > nsamples {
+			n = nsamples - offset
+		}
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(n),
+				SampleRate:        48000,
+				Channels:          frame.ChannelsLR,
+				BitsPerSample:     16,
+			},
+		}
+		for channel := range samples {
+			subSamples := make([]int32, n)
+			for i := range subSamples {
+				subSamples[i] = int32(8000 * math.Sin(float64(offset+i)/20+float64(channel)))
+			}
+			samples[channel] = append(samples[channel], subSamples...)
+			f.Subframes = append(f.Subframes, &frame.Subframe{
+				SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+				Samples:   subSamples,
+				NSamples:  n,
+			})
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatalf("block size %d: unable to encode frame; %v", blockSize, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+func TestEncodeSmallBlockSize(t *testing.T) {
+	const nsamples = 1000
+	dir := t.TempDir()
+	for _, blockSize := range []uint16{16, 17, 64, 192, 240, 256} {
+		path := filepath.Join(dir, fmt.Sprintf("%d.flac", blockSize))
+		want := encodeSmallBlocks(t, path, blockSize, nsamples)
+
+		// Decode the stream, and verify the updated StreamInfo block.
+		var warnings []string
+		logger := flac.LoggerFunc(func(e flac.Event) {
+			if e.Kind == flac.EventWarning {
+				warnings = append(warnings, e.Err.Error())
+			}
+		})
+		r, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		stream, err := flac.NewWithOptions(r, &flac.DecodeOptions{Logger: logger})
+		if err != nil {
+			t.Fatalf("block size %d: unable to parse FLAC stream; %v", blockSize, err)
+		}
+		info := stream.Info
+		if info.BlockSizeMin != blockSize || info.BlockSizeMax != blockSize {
+			t.Errorf("block size %d: block size mismatch of StreamInfo; expected %d-%d, got %d-%d", blockSize, blockSize, blockSize, info.BlockSizeMin, info.BlockSizeMax)
+		}
+		if info.NSamples != nsamples {
+			t.Errorf("block size %d: sample count mismatch; expected %d, got %d", blockSize, nsamples, info.NSamples)
+		}
+		got := make([][]int32, 2)
+		for {
+			f, err := stream.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatalf("block size %d: unable to parse frame; %v", blockSize, err)
+			}
+			for channel, subframe := range f.Subframes {
+				got[channel] = append(got[channel], subframe.Samples...)
+			}
+		}
+		for channel := range want {
+			if !slices.Equal(got[channel], want[channel]) {
+				t.Errorf("block size %d: sample mismatch in channel %d", blockSize, channel)
+			}
+		}
+		if len(warnings) > 0 {
+			t.Errorf("block size %d: unexpected warnings; %q", blockSize, warnings)
+		}
+
+		// Seek to samples of the first, a middle, and the last frame.
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		seekStream, err := flac.NewSeek(r)
+		if err != nil {
+			t.Fatalf("block size %d: unable to parse FLAC stream; %v", blockSize, err)
+		}
+		for _, sampleNum := range []uint64{0, nsamples / 2, nsamples - 1} {
+			first, err := seekStream.Seek(sampleNum)
+			if err != nil {
+				t.Fatalf("block size %d: unable to seek to sample %d; %v", blockSize, sampleNum, err)
+			}
+			if want := sampleNum - sampleNum%uint64(blockSize); first != want {
+				t.Errorf("block size %d: first sample mismatch of frame containing sample %d; expected %d, got %d", blockSize, sampleNum, want, first)
+			}
+		}
+	}
+}
+
+func TestEncodeShortFrame(t *testing.T) {
+	// Only the last frame may hold fewer than 16 samples.
+	info, err := flac.LowLatencyStreamInfo(48000, 1, 16, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := flac.NewEncoder(io.Discard, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newFrame := func(n int) *frame.Frame {
+		return &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(n),
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     16,
+			},
+			Subframes: []*frame.Subframe{{
+				SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+				Samples:   make([]int32, n),
+				NSamples:  n,
+			}},
+		}
+	}
+	if err := enc.WriteFrame(newFrame(10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.WriteFrame(newFrame(240)); err == nil {
+		t.Error("expected error for frame following a frame of 10 samples, got nil")
+	}
+	if err := enc.WriteFrame(newFrame(0)); err == nil {
+		t.Error("expected error for frame of 0 samples, got nil")
+	}
+}
@@ -0,0 +1,71 @@
+//go:build flac_cgo_test && cgo
+
+package flac_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac/internal/libflac"
+)
+
+// TestLibFLAC decodes the FLAC files of the test corpora using both libFLAC and
+// this package, and verifies that the decoded audio samples are identical. The
+// corpora consist of the testdata directory, and of the directories listed in
+// the FLAC_CORPUS environment variable (e.g. the output of cmd/flacgen).
+//
+//	go run ./cmd/flacgen -o /tmp/corpus
+//	FLAC_CORPUS=/tmp/corpus go test -tags flac_cgo_test -run LibFLAC .
+func TestLibFLAC(t *testing.T) {
+	dirs := append([]string{"testdata"}, filepath.SplitList(os.Getenv("FLAC_CORPUS"))...)
+	var paths []string
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".flac") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			want, err := libflac.Decode(path)
+			if err != nil {
+				// Only files decoded by the reference implementation are compared.
+				t.Skipf("rejected by libFLAC; %v", err)
+			}
+			got := decodeChannels(t, path)
+			if want == nil {
+				// Stream without audio samples.
+				for channel := range got {
+					if len(got[channel]) != 0 {
+						t.Fatalf("channel %d: number of samples mismatch; expected 0, got %d", channel, len(got[channel]))
+					}
+				}
+				return
+			}
+			if len(got) != len(want) {
+				t.Fatalf("number of channels mismatch; expected %d, got %d", len(want), len(got))
+			}
+			for channel := range want {
+				if len(got[channel]) != len(want[channel]) {
+					t.Fatalf("channel %d: number of samples mismatch; expected %d, got %d", channel, len(want[channel]), len(got[channel]))
+				}
+				for i, sample := range want[channel] {
+					if got[channel][i] != sample {
+						t.Fatalf("channel %d: sample %d mismatch; expected %d, got %d", channel, i, sample, got[channel][i])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package flac
+
+import (
+	"fmt"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// A Logger receives diagnostic events from a FLAC decoder, to enable
+// applications to surface non-fatal conditions which are otherwise silently
+// ignored.
+//
+// The Log method is invoked synchronously by the decoder; implementations must
+// not retain the event after returning.
+type Logger interface {
+	// Log is invoked for each diagnostic event of the decoder.
+	Log(event Event)
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as loggers.
+type LoggerFunc func(event Event)
+
+// Log calls f(event).
+func (f LoggerFunc) Log(event Event) {
+	f(event)
+}
+
+// EventKind specifies the kind of a diagnostic event.
+type EventKind uint8
+
+// Diagnostic event kinds.
+const (
+	// EventBlock is logged when a metadata block has been parsed or skipped.
+	EventBlock EventKind = iota + 1
+	// EventFrame is logged when an audio frame has been parsed.
+	EventFrame
+	// EventResync is logged when Stream.Resync skips data to resynchronize
+	// with the next frame header.
+	EventResync
+	// EventWarning is logged for non-fatal violations of the FLAC format.
+	EventWarning
+	// EventParametersChanged is logged when the sample rate, sample size or
+	// number of channels of an audio frame differ from the preceding frame.
+	EventParametersChanged
+	// EventEndOfStream is logged when the end of the stream has been reached.
+	EventEndOfStream
+)
+
+// String returns the string representation of the event kind.
+func (kind EventKind) String() string {
+	switch kind {
+	case EventBlock:
+		return "block"
+	case EventFrame:
+		return "frame"
+	case EventResync:
+		return "resync"
+	case EventWarning:
+		return "warning"
+	case EventParametersChanged:
+		return "parameters changed"
+	case EventEndOfStream:
+		return "end of stream"
+	}
+	return fmt.Sprintf("EventKind(%d)", uint8(kind))
+}
+
+// An Event is a diagnostic event of a FLAC decoder.
+type Event struct {
+	// Kind of event.
+	Kind EventKind
+	// Byte offset of the metadata block or frame of the event, relative to the
+	// start of the stream; or -1 if unknown.
+	Offset int64
+	// Metadata block of EventBlock events; and of EventWarning events related
+	// to a metadata block.
+	Block *meta.Block
+	// Audio frame of EventFrame and EventParametersChanged events; and of
+	// EventWarning events related to an audio frame. Only the frame header is
+	// valid for warnings logged before the audio samples are parsed.
+	Frame *frame.Frame
+	// Frame header of the preceding frame of EventParametersChanged events.
+	Prev frame.Header
+	// Number of bytes skipped by EventResync events.
+	Skipped int64
+	// Description of EventWarning events; and the error ending the stream of
+	// EventEndOfStream events (e.g. a *TruncatedError), or nil if the stream
+	// ended gracefully.
+	Err error
+}
+
+// String returns a human-readable representation of the event.
+func (event Event) String() string {
+	switch event.Kind {
+	case EventBlock:
+		return fmt.Sprintf("offset %d: %v metadata block (%d bytes)", event.Offset, event.Block.Type, event.Block.Length)
+	case EventFrame:
+		return fmt.Sprintf("offset %d: frame %d (%d samples)", event.Offset, event.Frame.Num, event.Frame.BlockSize)
+	case EventResync:
+		return fmt.Sprintf("offset %d: resynchronized after skipping %d bytes", event.Offset, event.Skipped)
+	case EventWarning:
+		return fmt.Sprintf("offset %d: warning: %v", event.Offset, event.Err)
+	case EventParametersChanged:
+		prev, hdr := event.Prev, event.Frame.Header
+		return fmt.Sprintf("offset %d: parameters changed from %d Hz, %d bits-per-sample, %d channels to %d Hz, %d bits-per-sample, %d channels", event.Offset, prev.SampleRate, prev.BitsPerSample, prev.Channels.Count(), hdr.SampleRate, hdr.BitsPerSample, hdr.Channels.Count())
+	case EventEndOfStream:
+		if event.Err != nil {
+			return fmt.Sprintf("offset %d: end of stream: %v", event.Offset, event.Err)
+		}
+		return fmt.Sprintf("offset %d: end of stream", event.Offset)
+	}
+	return fmt.Sprintf("offset %d: %v", event.Offset, event.Kind)
+}
+
+// log logs the given event, if a logger or an event channel is present.
+func (stream *Stream) log(event Event) {
+	if stream.opts.Logger != nil {
+		stream.opts.Logger.Log(event)
+	}
+	if stream.events != nil {
+		stream.events.send(event)
+	}
+}
+
+// logging reports whether events are logged; i.e. whether a logger or an event
+// channel is present.
+func (stream *Stream) logging() bool {
+	return stream.opts.Logger != nil || stream.events != nil
+}
+
+// warnf logs a warning event related to the given frame, if a logger or an
+// event channel is present.
+func (stream *Stream) warnf(offset int64, f *frame.Frame, format string, args ...interface{}) {
+	stream.log(Event{Kind: EventWarning, Offset: offset, Frame: f, Err: fmt.Errorf(format, args...)})
+}
+
+// A DiscontinuityError reports an audio frame whose first sample differs from
+// the sample following the preceding frame; i.e. a duplicate or skipped range
+// of samples, as found in spliced files. Discontinuities are logged as the Err
+// of EventWarning events, and do not interrupt decoding.
+type DiscontinuityError struct {
+	// Expected first sample number of the frame; i.e. the sample number
+	// following the preceding frame, or 0 for the first frame.
+	Expected uint64
+	// First sample number of the frame.
+	Got uint64
+}
+
+// Error returns the error message of the discontinuity.
+func (e *DiscontinuityError) Error() string {
+	if e.Got > e.Expected {
+		return fmt.Sprintf("discontinuity; frame starts at sample %d, expected %d (%d samples skipped)", e.Got, e.Expected, e.Got-e.Expected)
+	}
+	return fmt.Sprintf("discontinuity; frame starts at sample %d, expected %d (%d samples duplicated)", e.Got, e.Expected, e.Expected-e.Got)
+}
+
+// checkFrameHeader logs warnings for properties of the frame header which are
+// inconsistent with StreamInfo or with the preceding frames.
+func (stream *Stream) checkFrameHeader(offset int64, f *frame.Frame) {
+	info := stream.Info
+	if first := stream.sampleNumber(f); first != stream.samplePos {
+		stream.log(Event{Kind: EventWarning, Offset: offset, Frame: f, Err: &DiscontinuityError{Expected: stream.samplePos, Got: first}})
+	}
+	if f.SampleRate != 0 && f.SampleRate != info.SampleRate {
+		stream.warnf(offset, f, "sample rate of frame header (%d) differs from StreamInfo (%d)", f.SampleRate, info.SampleRate)
+	}
+	if f.BitsPerSample != 0 && f.BitsPerSample != info.BitsPerSample {
+		stream.warnf(offset, f, "sample size of frame header (%d) differs from StreamInfo (%d)", f.BitsPerSample, info.BitsPerSample)
+	}
+	if nchannels := f.Channels.Count(); nchannels != int(info.NChannels) {
+		stream.warnf(offset, f, "channel count of frame header (%d) differs from StreamInfo (%d)", nchannels, info.NChannels)
+	}
+	if info.BlockSizeMax != 0 && f.BlockSize > info.BlockSizeMax {
+		stream.warnf(offset, f, "block size of frame header (%d) exceeds maximum block size of StreamInfo (%d)", f.BlockSize, info.BlockSizeMax)
+	}
+	if strategy := blockingStrategy(f.HasFixedBlockSize); stream.blocking != BlockingUnknown && strategy != stream.blocking {
+		stream.warnf(offset, f, "blocking strategy of frame header (%v) differs from preceding frames (%v)", strategy, stream.blocking)
+	}
+	if stream.shortBlockSize != 0 {
+		stream.warnf(offset, f, "block size of preceding frame (%d) below minimum block size of StreamInfo (%d); only the last frame may hold fewer samples", stream.shortBlockSize, info.BlockSizeMin)
+	}
+	stream.shortBlockSize = 0
+	if f.BlockSize < info.BlockSizeMin {
+		stream.shortBlockSize = f.BlockSize
+	}
+}
@@ -0,0 +1,71 @@
+package lossy
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// fft is a radix-2 fast Fourier transform of a fixed power-of-two size.
+type fft struct {
+	// Hann window of the transform size.
+	window []float64
+	// Twiddle factors; exp(-2πik/n) for k in [0, n/2).
+	twiddles []complex128
+	// Bit-reversed index of each sample.
+	rev []int
+	// Scratch buffer of the transform size.
+	buf []complex128
+	// Power spectrum buffer.
+	power []float64
+}
+
+// newFFT returns a new fast Fourier transform of size n, which must be a power
+// of two.
+func newFFT(n int) *fft {
+	t := &fft{
+		window:   make([]float64, n),
+		twiddles: make([]complex128, n/2),
+		rev:      make([]int, n),
+		buf:      make([]complex128, n),
+		power:    make([]float64, n/2),
+	}
+	for i := range t.window {
+		t.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	for k := range t.twiddles {
+		t.twiddles[k] = cmplx.Exp(complex(0, -2*math.Pi*float64(k)/float64(n)))
+	}
+	shift := bits.UintSize - bits.Len(uint(n-1))
+	for i := range t.rev {
+		t.rev[i] = int(bits.Reverse(uint(i)) >> shift)
+	}
+	return t
+}
+
+// powerSpectrum returns the power spectrum of the Hann-windowed samples, one
+// value per frequency bin in [0, n/2). The returned slice is reused by
+// subsequent calls.
+func (t *fft) powerSpectrum(samples []float64) []float64 {
+	n := len(t.buf)
+	for i, x := range samples {
+		t.buf[t.rev[i]] = complex(x*t.window[i], 0)
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := n / size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				w := t.twiddles[k*step] * t.buf[start+k+half]
+				u := t.buf[start+k]
+				t.buf[start+k] = u + w
+				t.buf[start+k+half] = u - w
+			}
+		}
+	}
+	for i := range t.power {
+		re, im := real(t.buf[i]), imag(t.buf[i])
+		t.power[i] = re*re + im*im
+	}
+	return t.power
+}
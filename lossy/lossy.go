@@ -0,0 +1,258 @@
+// Package lossy detects FLAC streams sourced from lossy codecs, such as MP3 or
+// AAC, by spectral analysis of the decoded audio; e.g. to flag transcodes in a
+// music library.
+//
+// Perceptual codecs discard high frequencies to save bits, which leaves a hard
+// cutoff in the spectrum of the decoded audio; typically at 16 to 17 kHz for
+// MP3 at 128 kbps, and at 19 to 20 kHz for higher bitrates. The cutoff is fixed
+// for the duration of the stream, independent of the content. The spectrum of a
+// lossless recording instead rolls off gradually, and only the anti-aliasing
+// filter of the recording chain cuts off close to the Nyquist frequency.
+//
+// A typical integration flags streams with a confidence above one half:
+//
+//	result, err := lossy.DetectFile("song.flac")
+//	if err != nil {
+//		return err
+//	}
+//	if result.Confidence > 0.5 {
+//		fmt.Printf("likely lossy source; %s\n", strings.Join(result.Reasons, "; "))
+//	}
+package lossy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/mewkiz/flac"
+)
+
+// Result is the result of lossy-source detection.
+type Result struct {
+	// Confidence in the range [0, 1] that the audio was sourced from a lossy
+	// codec.
+	Confidence float64
+	// Frequency in Hz of the detected hard cutoff; or 0 if no cutoff was
+	// detected.
+	Cutoff float64
+	// Attenuation in dB of the spectrum above the cutoff, relative to the
+	// spectrum below the cutoff; or 0 if no cutoff was detected.
+	Drop float64
+	// Fraction of analysed windows which individually exhibit the cutoff; a
+	// fixed lowpass of the encoder is present throughout the stream.
+	Consistency float64
+	// Number of analysed windows; silent windows are skipped.
+	Windows int
+	// Human-readable reasons for the confidence.
+	Reasons []string
+}
+
+// Analysis parameters.
+const (
+	// Number of samples per analysis window.
+	windowSize = 4096
+	// Width in Hz of the bands compared below and above a candidate cutoff.
+	bandWidth = 1000
+	// Minimum width in Hz of the band above a candidate cutoff, which is
+	// truncated at the top of the analysed spectrum.
+	minBandWidth = 300
+	// Width in Hz of the transition band of a cutoff, which is excluded from
+	// the compared bands.
+	guardWidth = 300
+	// Lowest candidate cutoff in Hz.
+	minCutoff = 8000
+	// Fraction of the Nyquist frequency analysed; the top of the spectrum is
+	// attenuated by the resampling filters of most recording chains.
+	topFraction = 0.98
+	// Minimum attenuation in dB of a hard cutoff.
+	minDrop = 20
+	// Attenuation in dB of a hard cutoff, at and above which the attenuation
+	// is fully indicative of a lossy source.
+	fullDrop = 45
+	// Mean square of the normalized samples of a window below which the window
+	// is considered silent (-80 dBFS).
+	silenceLevel = 1e-8
+)
+
+// Detect analyses the spectrum of the given FLAC stream in its entirety, and
+// reports whether the audio was likely sourced from a lossy codec. Channels are
+// downmixed by averaging prior to analysis.
+func Detect(stream *flac.Stream) (*Result, error) {
+	sampleRate := float64(stream.Info.SampleRate)
+	if sampleRate <= 0 {
+		return nil, errors.New("lossy.Detect: sample rate of stream not specified")
+	}
+	binHz := sampleRate / windowSize
+	a := &analyzer{
+		band:    int(math.Round(bandWidth / binHz)),
+		minBand: int(math.Round(minBandWidth / binHz)),
+		guard:   int(math.Round(guardWidth / binHz)),
+		first:   int(math.Ceil(minCutoff / binHz)),
+		top:     int(math.Floor(topFraction * windowSize / 2)),
+		fft:     newFFT(windowSize),
+		power:   make([]float64, windowSize/2),
+	}
+	if a.first < a.band {
+		a.first = a.band
+	}
+	if a.first+a.guard+a.minBand > a.top {
+		return nil, fmt.Errorf("lossy.Detect: sample rate %d Hz too low for analysis", stream.Info.SampleRate)
+	}
+
+	// Downmix the samples to mono, and analyse them in non-overlapping windows.
+	scale := 1 / float64(int64(1)<<(stream.Info.BitsPerSample-1))
+	window := make([]float64, 0, windowSize)
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(f.Subframes) == 0 {
+			continue
+		}
+		mul := scale / float64(len(f.Subframes))
+		for i := 0; i < f.Subframes[0].NSamples; i++ {
+			var sum int64
+			for _, subframe := range f.Subframes {
+				sum += int64(subframe.Samples[i])
+			}
+			window = append(window, float64(sum)*mul)
+			if len(window) == windowSize {
+				a.analyze(window)
+				window = window[:0]
+			}
+		}
+	}
+	return a.result(binHz), nil
+}
+
+// DetectFile analyses the spectrum of the given FLAC file. See Detect for
+// details.
+func DetectFile(path string) (*Result, error) {
+	stream, err := flac.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	return Detect(stream)
+}
+
+// analyzer accumulates the spectra of analysis windows.
+type analyzer struct {
+	// Width in frequency bins of the compared bands, the minimum width of the
+	// band above a candidate cutoff, and the transition band.
+	band, minBand, guard int
+	// Lowest candidate cutoff bin, and the top of the analysed spectrum.
+	first, top int
+	// Fast Fourier transform of windowSize samples.
+	fft *fft
+	// Summed power spectrum of the analysed windows.
+	power []float64
+	// Cutoff bin and attenuation in dB detected in each analysed window.
+	cutoffs []int
+	drops   []float64
+}
+
+// analyze accumulates the power spectrum of the given window of samples, and
+// detects the cutoff of the window.
+func (a *analyzer) analyze(window []float64) {
+	var ms float64
+	for _, x := range window {
+		ms += x * x
+	}
+	if ms/float64(len(window)) < silenceLevel {
+		return
+	}
+	spectrum := a.fft.powerSpectrum(window)
+	for i, p := range spectrum {
+		a.power[i] += p
+	}
+	bin, drop := a.findCutoff(spectrum)
+	a.cutoffs = append(a.cutoffs, bin)
+	a.drops = append(a.drops, drop)
+}
+
+// findCutoff returns the bin of the steepest cutoff of the given power
+// spectrum, and its attenuation in dB; the difference between the mean level of
+// the band below the cutoff and the band above the cutoff, separated by the
+// transition band.
+func (a *analyzer) findCutoff(power []float64) (bin int, drop float64) {
+	// Prefix sums of the level in dB of each bin.
+	sums := make([]float64, a.top+1)
+	for i := 0; i < a.top; i++ {
+		sums[i+1] = sums[i] + 10*math.Log10(power[i]+1e-20)
+	}
+	mean := func(start, end int) float64 {
+		return (sums[end] - sums[start]) / float64(end-start)
+	}
+	drop = math.Inf(-1)
+	for i := a.first; i+a.guard+a.minBand <= a.top; i++ {
+		end := i + a.guard + a.band
+		if end > a.top {
+			end = a.top
+		}
+		d := mean(i-a.band, i) - mean(i+a.guard, end)
+		if d > drop {
+			bin, drop = i, d
+		}
+	}
+	return bin, drop
+}
+
+// result returns the result of the analysed windows, with the given width in
+// Hz of a frequency bin.
+func (a *analyzer) result(binHz float64) *Result {
+	r := &Result{Windows: len(a.cutoffs)}
+	if r.Windows == 0 {
+		r.Reasons = append(r.Reasons, "no audio above the silence threshold")
+		return r
+	}
+	bin, drop := a.findCutoff(a.power)
+	if drop < minDrop {
+		r.Reasons = append(r.Reasons, "no hard cutoff detected")
+		return r
+	}
+	// The steepest descent is located at the start of the transition band.
+	r.Cutoff = (float64(bin) + float64(a.guard)/2) * binHz
+	r.Drop = drop
+	r.Reasons = append(r.Reasons, fmt.Sprintf("hard cutoff at %.1f kHz, attenuated by %.0f dB", r.Cutoff/1000, r.Drop))
+
+	// Fraction of windows exhibiting the same cutoff.
+	n := 0
+	for i, c := range a.cutoffs {
+		if a.drops[i] >= minDrop && abs(c-bin) <= a.band/2 {
+			n++
+		}
+	}
+	r.Consistency = float64(n) / float64(r.Windows)
+	r.Reasons = append(r.Reasons, fmt.Sprintf("cutoff present in %.0f%% of windows", 100*r.Consistency))
+
+	// A cutoff close to the Nyquist frequency is indistinguishable from the
+	// anti-aliasing filter of a lossless recording.
+	nyquist := binHz * windowSize / 2
+	freqScore := clamp((0.95-r.Cutoff/nyquist)/(0.95-0.88), 0, 1)
+	if freqScore < 1 {
+		r.Reasons = append(r.Reasons, "cutoff close to the Nyquist frequency; consistent with an anti-aliasing filter")
+	}
+	dropScore := clamp((r.Drop-minDrop)/(fullDrop-minDrop), 0, 1)
+	r.Confidence = dropScore * freqScore * (0.25 + 0.75*r.Consistency)
+	return r
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// clamp returns x clamped to the range [min, max].
+func clamp(x, min, max float64) float64 {
+	return math.Max(min, math.Min(max, x))
+}
@@ -0,0 +1,161 @@
+package lossy_test
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/lossy"
+	"github.com/mewkiz/flac/meta"
+)
+
+// noise returns n samples of white noise, lowpass filtered at the given cutoff
+// frequency using a Blackman-windowed sinc filter; or unfiltered if cutoff is 0.
+func noise(n int, cutoff, rate float64) []float64 {
+	rnd := rand.New(rand.NewSource(1))
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = rnd.NormFloat64()
+	}
+	if cutoff == 0 {
+		return x
+	}
+	const taps = 511
+	fc := cutoff / rate
+	h := make([]float64, taps)
+	for i := range h {
+		t := float64(i - taps/2)
+		sinc := 2 * fc
+		if t != 0 {
+			sinc = math.Sin(2*math.Pi*fc*t) / (math.Pi * t)
+		}
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/(taps-1)) + 0.08*math.Cos(4*math.Pi*float64(i)/(taps-1))
+		h[i] = sinc * w
+	}
+	y := make([]float64, n)
+	for i := range y {
+		var sum float64
+		for j, c := range h {
+			if k := i - j; k >= 0 {
+				sum += c * x[k]
+			}
+		}
+		y[i] = sum
+	}
+	return y
+}
+
+// encode encodes the given mono samples, scaled to 16 bits-per-sample with the
+// given peak level, to a FLAC file in the test directory, and returns its path.
+func encode(t *testing.T, samples []float64, level float64) string {
+	const (
+		rate      = 44100
+		blockSize = 4096
+	)
+	var peak float64
+	for _, x := range samples {
+		peak = math.Max(peak, math.Abs(x))
+	}
+	scale := 0.0
+	if peak > 0 {
+		scale = level * math.MaxInt16 / peak
+	}
+	path := filepath.Join(t.TempDir(), "test.flac")
+	fw, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+	info := &meta.StreamInfo{
+		BlockSizeMin:  blockSize,
+		BlockSizeMax:  blockSize,
+		SampleRate:    rate,
+		NChannels:     1,
+		BitsPerSample: 16,
+	}
+	enc, err := flac.NewEncoder(fw, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for offset := 0; offset < len(samples); offset += blockSize {
+		n := min(blockSize, len(samples)-offset)
+		subframe := &frame.Subframe{
+			SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+			NSamples:  n,
+			Samples:   make([]int32, n),
+		}
+		for i := range subframe.Samples {
+			subframe.Samples[i] = int32(math.Round(samples[offset+i] * scale))
+		}
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(n),
+				SampleRate:        rate,
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     16,
+			},
+			Subframes: []*frame.Subframe{subframe},
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDetect(t *testing.T) {
+	const n = 20 * 4096
+	golden := []struct {
+		name   string
+		cutoff float64
+		lossy  bool
+	}{
+		// Lowpass of MP3 at 128 kbps.
+		{name: "16kHz", cutoff: 16000, lossy: true},
+		// Lowpass of MP3 at high bitrates.
+		{name: "19kHz", cutoff: 19000, lossy: true},
+		// Anti-aliasing filter of a lossless recording.
+		{name: "21kHz", cutoff: 21000},
+		// Full-band audio.
+		{name: "full", cutoff: 0},
+	}
+	for _, g := range golden {
+		path := encode(t, noise(n, g.cutoff, 44100), 0.5)
+		result, err := lossy.DetectFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		if g.lossy {
+			if result.Confidence < 0.8 {
+				t.Errorf("%s: confidence too low; expected >= 0.8, got %.2f (%q)", g.name, result.Confidence, result.Reasons)
+			}
+			if math.Abs(result.Cutoff-g.cutoff) > 500 {
+				t.Errorf("%s: cutoff mismatch; expected %.0f Hz, got %.0f Hz", g.name, g.cutoff, result.Cutoff)
+			}
+			continue
+		}
+		if result.Confidence > 0.2 {
+			t.Errorf("%s: confidence too high; expected <= 0.2, got %.2f (%q)", g.name, result.Confidence, result.Reasons)
+		}
+	}
+}
+
+func TestDetectSilence(t *testing.T) {
+	path := encode(t, make([]float64, 8*4096), 0)
+	result, err := lossy.DetectFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Windows != 0 || result.Confidence != 0 {
+		t.Errorf("silence: expected no analysed windows, got %d windows with confidence %.2f", result.Windows, result.Confidence)
+	}
+}
@@ -0,0 +1,86 @@
+package flac_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestLowMemoryAllocs(t *testing.T) {
+	paths := []string{
+		"testdata/19875.flac",
+		"testdata/220014.flac",
+		"testdata/love.flac",
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Count frames and verify the MD5 checksum of the decoded audio
+			// samples, to ensure that reused buffers are fully overwritten.
+			stream, err := flac.NewWithOptions(bytes.NewReader(buf), &flac.DecodeOptions{LowMemory: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			md5sum := md5.New()
+			nframes := 0
+			for {
+				f, err := stream.ParseNext()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					t.Fatal(err)
+				}
+				f.Hash(md5sum)
+				nframes++
+			}
+			var got [md5.Size]byte
+			copy(got[:], md5sum.Sum(nil))
+			if got != stream.Info.MD5sum {
+				t.Errorf("MD5 checksum mismatch; expected %x, got %x", stream.Info.MD5sum, got)
+			}
+			if nframes < 3 {
+				t.Skipf("too few frames (%d)", nframes)
+			}
+
+			// Decode frames; the first call of AllocsPerRun is a warm-up run, so
+			// leave one frame to spare.
+			stream, err = flac.NewWithOptions(bytes.NewReader(buf), &flac.DecodeOptions{LowMemory: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decodeErr error
+			allocs := testing.AllocsPerRun(nframes-2, func() {
+				if _, err := stream.ParseNext(); err != nil && decodeErr == nil {
+					decodeErr = err
+				}
+			})
+			if decodeErr != nil {
+				t.Fatal(decodeErr)
+			}
+			if allocs != 0 {
+				t.Errorf("allocations per frame mismatch; expected 0, got %v", allocs)
+			}
+		})
+	}
+}
+
+func TestLowMemoryBudget(t *testing.T) {
+	f, err := os.Open("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	opts := &flac.DecodeOptions{LowMemory: true, MaxSampleMemory: 1024}
+	if _, err := flac.NewWithOptions(f, opts); !errors.Is(err, flac.ErrMemoryBudget) {
+		t.Fatalf("expected memory budget error, got %v", err)
+	}
+}
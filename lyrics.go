@@ -0,0 +1,246 @@
+package flac
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// A LyricLine is a line of synchronized lyrics.
+type LyricLine struct {
+	// Time offset of the line from the start of the stream.
+	Time time.Duration
+	// Text of the line; may hold word timestamps of enhanced LRC (e.g.
+	// <00:12.34>), which are retained as is.
+	Text string
+}
+
+// Lyrics holds synchronized lyrics, as stored in LRC format.
+//
+// ref: https://en.wikipedia.org/wiki/LRC_(file_format)
+type Lyrics struct {
+	// ID tags of the lyrics, as name-value pairs in order of appearance; e.g.
+	// {"ar", "Artist"} and {"ti", "Title"}. The offset tag is applied to the
+	// time offsets of the lines when parsed, and is thus not retained.
+	Tags [][2]string
+	// Lines of the lyrics in order of time offset.
+	Lines []LyricLine
+}
+
+// ParseLRC parses the given synchronized lyrics in LRC format. Lines with
+// multiple timestamps (e.g. [00:12.00][01:12.00]chorus) are repeated at each
+// time offset, and the lines are sorted by time offset. Lines without
+// timestamps or ID tags are ignored.
+func ParseLRC(r io.Reader) (*Lyrics, error) {
+	lyrics := new(Lyrics)
+	// Time offset in milliseconds of the offset tag; positive values shift the
+	// lines to an earlier time.
+	var offset int64
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		var times []time.Duration
+		for strings.HasPrefix(line, "[") {
+			end := strings.IndexByte(line, ']')
+			if end == -1 {
+				break
+			}
+			field := line[1:end]
+			if t, err := parseLRCTime(field); err == nil {
+				times = append(times, t)
+				line = line[end+1:]
+				continue
+			}
+			if len(times) > 0 {
+				// Text starting with brackets.
+				break
+			}
+			name, value, ok := strings.Cut(field, ":")
+			if !ok {
+				break
+			}
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+			if strings.EqualFold(name, "offset") {
+				x, err := strconv.ParseInt(strings.TrimPrefix(value, "+"), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("flac.ParseLRC: invalid offset %q on line %d", value, lineNum)
+				}
+				offset = x
+			} else {
+				lyrics.Tags = append(lyrics.Tags, [2]string{name, value})
+			}
+			line = ""
+		}
+		for _, t := range times {
+			lyrics.Lines = append(lyrics.Lines, LyricLine{Time: t, Text: strings.TrimSpace(line)})
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	for i := range lyrics.Lines {
+		lyrics.Lines[i].Time -= time.Duration(offset) * time.Millisecond
+	}
+	sort.SliceStable(lyrics.Lines, func(i, j int) bool {
+		return lyrics.Lines[i].Time < lyrics.Lines[j].Time
+	})
+	return lyrics, nil
+}
+
+// parseLRCTime parses the given LRC timestamp in mm:ss or mm:ss.xx format, with
+// an arbitrary number of fractional digits, and returns the corresponding time
+// offset.
+func parseLRCTime(s string) (time.Duration, error) {
+	min, sec, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("expected mm:ss.xx format")
+	}
+	sec, frac, _ := strings.Cut(sec, ".")
+	m, err := strconv.ParseUint(min, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	x, err := strconv.ParseUint(sec, 10, 8)
+	if err != nil {
+		return 0, err
+	}
+	if x >= 60 {
+		return 0, fmt.Errorf("seconds out of range")
+	}
+	t := time.Duration(m)*time.Minute + time.Duration(x)*time.Second
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		v, err := strconv.ParseUint(frac, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		for i := len(frac); i < 9; i++ {
+			v *= 10
+		}
+		t += time.Duration(v)
+	}
+	return t, nil
+}
+
+// WriteTo writes the synchronized lyrics to w in LRC format; the ID tags
+// followed by one line per time offset, with timestamps in mm:ss.xx format
+// rounded to the nearest hundredth of a second.
+func (lyrics *Lyrics) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, lyrics.String())
+	return int64(n), err
+}
+
+// String returns the synchronized lyrics in LRC format. See WriteTo.
+func (lyrics *Lyrics) String() string {
+	buf := new(strings.Builder)
+	for _, tag := range lyrics.Tags {
+		fmt.Fprintf(buf, "[%s:%s]\n", tag[0], tag[1])
+	}
+	for _, line := range lyrics.Lines {
+		cs := (line.Time + 5*time.Millisecond) / (10 * time.Millisecond)
+		fmt.Fprintf(buf, "[%02d:%02d.%02d]%s\n", cs/6000, cs/100%60, cs%100, line.Text)
+	}
+	return buf.String()
+}
+
+// ErrLyricsTime reports that the time offset of a line of synchronized lyrics
+// is outside of the duration of the stream.
+var ErrLyricsTime = errors.New("lyrics time offset out of range")
+
+// Validate reports whether the time offsets of the lines of the synchronized
+// lyrics are within the given duration of the stream; a 0 duration implies an
+// unknown duration, in which case only negative time offsets are rejected.
+// Errors returned by Validate wrap ErrLyricsTime.
+func (lyrics *Lyrics) Validate(duration time.Duration) error {
+	for i, line := range lyrics.Lines {
+		if line.Time < 0 || duration != 0 && line.Time > duration {
+			return fmt.Errorf("flac.Lyrics.Validate: %w; time offset %v of line %d (%q) outside of range [0, %v]", ErrLyricsTime, line.Time, i+1, line.Text, duration)
+		}
+	}
+	return nil
+}
+
+// streamDuration returns the duration of the stream described by the given
+// StreamInfo block, rounded down to the nearest nanosecond; or 0 if the sample
+// rate or the total number of samples is unknown.
+func streamDuration(info *meta.StreamInfo) time.Duration {
+	if info.SampleRate == 0 || info.NSamples == 0 {
+		return 0
+	}
+	secs := info.NSamples / uint64(info.SampleRate)
+	rem := info.NSamples % uint64(info.SampleRate)
+	return time.Duration(secs)*time.Second + time.Duration(rem*uint64(time.Second)/uint64(info.SampleRate))
+}
+
+// Lyrics returns the synchronized lyrics of the stream, stored in the LYRICS tag
+// of the VorbisComment block in LRC format (see
+// meta.VorbisComment.SyncedLyrics). The time offsets of the lines are validated
+// against the duration of the stream, as done by Lyrics.Validate. A nil value
+// is returned if the stream has no synchronized lyrics.
+//
+// See meta.VorbisComment.UnsyncedLyrics for unsynchronized lyrics.
+func (stream *Stream) Lyrics() (*Lyrics, error) {
+	for _, block := range stream.Blocks {
+		comment, ok := block.Body.(*meta.VorbisComment)
+		if !ok {
+			continue
+		}
+		text, ok := comment.SyncedLyrics()
+		if !ok {
+			continue
+		}
+		lyrics, err := ParseLRC(strings.NewReader(text))
+		if err != nil {
+			return nil, err
+		}
+		if err := lyrics.Validate(streamDuration(stream.Info)); err != nil {
+			return nil, err
+		}
+		return lyrics, nil
+	}
+	return nil, nil
+}
+
+// ExportLRC writes the synchronized lyrics of the stream to w in LRC format,
+// e.g. to a .lrc file alongside the FLAC file. See Stream.Lyrics.
+func (stream *Stream) ExportLRC(w io.Writer) error {
+	lyrics, err := stream.Lyrics()
+	if err != nil {
+		return err
+	}
+	if lyrics == nil {
+		return errors.New("flac.Stream.ExportLRC: no synchronized lyrics")
+	}
+	_, err = lyrics.WriteTo(w)
+	return err
+}
+
+// ImportLRC parses the synchronized lyrics in LRC format of r, e.g. of a .lrc
+// file, and stores them in the LYRICS tag of the given Vorbis comment (see
+// meta.VorbisComment.SetSyncedLyrics). The time offsets of the lines are
+// validated against the duration of the stream described by info, and the
+// Vorbis comment is left unchanged if invalid. The lyrics are stored as written
+// by Lyrics.WriteTo; i.e. with the offset tag applied.
+func ImportLRC(comment *meta.VorbisComment, r io.Reader, info *meta.StreamInfo) (*Lyrics, error) {
+	lyrics, err := ParseLRC(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lyrics.Lines) == 0 {
+		return nil, errors.New("flac.ImportLRC: no timestamped lines")
+	}
+	if err := lyrics.Validate(streamDuration(info)); err != nil {
+		return nil, err
+	}
+	comment.SetSyncedLyrics(lyrics.String())
+	return lyrics, nil
+}
@@ -0,0 +1,101 @@
+package flac_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestParseLRC(t *testing.T) {
+	const lrc = `[ar:Artist]
+[ti:Title]
+[offset:+500]
+
+[00:12.00][01:02.50]chorus
+[00:05.1]first line
+[00:30.123]<00:30.12>enhanced <00:31.00>words
+`
+	got, err := flac.ParseLRC(strings.NewReader(lrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &flac.Lyrics{
+		Tags: [][2]string{{"ar", "Artist"}, {"ti", "Title"}},
+		Lines: []flac.LyricLine{
+			{Time: 4600 * time.Millisecond, Text: "first line"},
+			{Time: 11500 * time.Millisecond, Text: "chorus"},
+			{Time: 29623 * time.Millisecond, Text: "<00:30.12>enhanced <00:31.00>words"},
+			{Time: 62 * time.Second, Text: "chorus"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lyrics mismatch; expected %+v, got %+v", want, got)
+	}
+	const wantLRC = `[ar:Artist]
+[ti:Title]
+[00:04.60]first line
+[00:11.50]chorus
+[00:29.62]<00:30.12>enhanced <00:31.00>words
+[01:02.00]chorus
+`
+	if s := got.String(); s != wantLRC {
+		t.Errorf("LRC mismatch; expected %q, got %q", wantLRC, s)
+	}
+
+	if err := got.Validate(time.Minute); !errors.Is(err, flac.ErrLyricsTime) {
+		t.Errorf("expected ErrLyricsTime for line past duration, got %v", err)
+	}
+	if err := got.Validate(0); err != nil {
+		t.Errorf("unexpected error for unknown duration; %v", err)
+	}
+}
+
+func TestImportLRC(t *testing.T) {
+	stream, err := flac.ParseFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var comment *meta.VorbisComment
+	for _, block := range stream.Blocks {
+		if body, ok := block.Body.(*meta.VorbisComment); ok {
+			comment = body
+		}
+	}
+	if comment == nil {
+		t.Fatal("VorbisComment block not found")
+	}
+
+	// Lines past the end of the stream are rejected.
+	if _, err := flac.ImportLRC(comment, strings.NewReader("[99:00.00]late\n"), stream.Info); !errors.Is(err, flac.ErrLyricsTime) {
+		t.Errorf("expected ErrLyricsTime for line past end of stream, got %v", err)
+	}
+	if _, ok := comment.SyncedLyrics(); ok {
+		t.Error("Vorbis comment modified by invalid lyrics")
+	}
+
+	const lrc = "[00:00.50]love\n"
+	if _, err := flac.ImportLRC(comment, strings.NewReader(lrc), stream.Info); err != nil {
+		t.Fatal(err)
+	}
+	lyrics, err := stream.Lyrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &flac.Lyrics{Lines: []flac.LyricLine{{Time: 500 * time.Millisecond, Text: "love"}}}
+	if !reflect.DeepEqual(lyrics, want) {
+		t.Errorf("lyrics mismatch; expected %+v, got %+v", want, lyrics)
+	}
+	buf := new(strings.Builder)
+	if err := stream.ExportLRC(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != lrc {
+		t.Errorf("exported LRC mismatch; expected %q, got %q", lrc, buf.String())
+	}
+}
@@ -0,0 +1,131 @@
+package flac
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// An MD5Check reports the result of FixMD5.
+type MD5Check struct {
+	// MD5 checksum of the unencoded audio samples stored in the StreamInfo
+	// block; zero if unset.
+	Stored [md5.Size]uint8
+	// MD5 checksum of the decoded audio samples.
+	Computed [md5.Size]uint8
+	// Specifies whether the StreamInfo block was patched with the computed
+	// checksum.
+	Fixed bool
+}
+
+// Mismatch reports whether the stored MD5 checksum differs from the computed
+// checksum, including the case of an unset stored checksum.
+func (c *MD5Check) Mismatch() bool {
+	return c.Stored != c.Computed
+}
+
+// FixMD5 decodes the FLAC file at path, recomputes the MD5 checksum of the
+// decoded audio samples, and patches the StreamInfo block of the file in place
+// if the stored checksum differs; e.g. if the checksum was left unset by the
+// encoder. If dryRun is set, the mismatch is reported without modifying the
+// file.
+func FixMD5(path string, dryRun bool) (*MD5Check, error) {
+	flag := os.O_RDWR
+	if dryRun {
+		flag = os.O_RDONLY
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	check, err := fixMD5(f, dryRun)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// fixMD5 recomputes the MD5 checksum of the decoded audio samples of the given
+// FLAC file, and patches its StreamInfo block if the stored checksum differs
+// and dryRun is not set. See FixMD5 for details.
+func fixMD5(f *os.File, dryRun bool) (*MD5Check, error) {
+	stream, err := New(f)
+	if err != nil {
+		return nil, err
+	}
+	check := &MD5Check{Stored: stream.Info.MD5sum}
+	h := md5.New()
+	for {
+		frame, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		frame.Hash(h)
+	}
+	copy(check.Computed[:], h.Sum(nil))
+	if !check.Mismatch() || dryRun {
+		return check, nil
+	}
+	offset, err := md5Offset(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteAt(check.Computed[:], offset); err != nil {
+		return nil, err
+	}
+	check.Fixed = true
+	return check, nil
+}
+
+// md5Offset returns the byte offset of the MD5 checksum of the StreamInfo block
+// within the given FLAC file.
+func md5Offset(r io.ReaderAt) (int64, error) {
+	// Skip prepended ID3v2 data.
+	var hdr [10]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return 0, err
+	}
+	offset := id3v2Size(hdr[:])
+	// 4 bytes: FLAC signature; 4 bytes: metadata block header.
+	var buf [8]byte
+	if _, err := r.ReadAt(buf[:], offset); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(buf[:4], flacSignature) {
+		return 0, fmt.Errorf("flac.FixMD5: invalid FLAC signature; expected %q, got %q", flacSignature, buf[:4])
+	}
+	// The first metadata block must be a StreamInfo block.
+	if typ := meta.Type(buf[4] & 0x7F); typ != meta.TypeStreamInfo {
+		return 0, fmt.Errorf("flac.FixMD5: invalid first metadata block type; expected %v, got %v", meta.TypeStreamInfo, typ)
+	}
+	// The StreamInfo block holds 34 bytes, and ends with the MD5 checksum.
+	const streamInfoSize = 34
+	if size := binary.BigEndian.Uint32(buf[4:]) & 0xFFFFFF; size != streamInfoSize {
+		return 0, fmt.Errorf("flac.FixMD5: invalid StreamInfo block size; expected %d, got %d", streamInfoSize, size)
+	}
+	return offset + 8 + streamInfoSize - md5.Size, nil
+}
+
+// id3v2Size returns the size in bytes of the ID3v2 data prepended to a FLAC
+// file, including its 10-byte header, as specified by the given header; or 0 if
+// not present.
+func id3v2Size(hdr []byte) int64 {
+	if len(hdr) < 10 || !bytes.Equal(hdr[:3], id3Signature) {
+		return 0
+	}
+	// The size is encoded as a synchsafe integer, and excludes the 10-byte
+	// header.
+	return 10 + (int64(hdr[6])<<21 | int64(hdr[7])<<14 | int64(hdr[8])<<7 | int64(hdr[9]))
+}
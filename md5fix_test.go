@@ -0,0 +1,77 @@
+package flac_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestFixMD5(t *testing.T) {
+	orig, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.New(bytes.NewReader(orig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := stream.Info.MD5sum
+	// ID3v2 header with a synchsafe size of 200 bytes.
+	id3 := append([]byte("ID3\x04\x00\x00\x00\x00\x01\x48"), make([]byte, 200)...)
+	golden := []struct {
+		name   string
+		prefix []byte
+	}{
+		{name: "plain"},
+		{name: "id3v2", prefix: id3},
+	}
+	dir := t.TempDir()
+	for _, g := range golden {
+		// Zero the MD5 checksum of StreamInfo; 4 bytes signature, 4 bytes
+		// metadata block header and 18 bytes preceding the checksum.
+		data := append(append([]byte(nil), g.prefix...), orig...)
+		damaged := append([]byte(nil), data...)
+		md5Offset := len(g.prefix) + 4 + 4 + 18
+		copy(damaged[md5Offset:md5Offset+16], make([]byte, 16))
+		path := filepath.Join(dir, g.name+".flac")
+		if err := os.WriteFile(path, damaged, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Dry run.
+		check, err := flac.FixMD5(path, true)
+		if err != nil {
+			t.Fatalf("%s: unable to check MD5 checksum; %v", g.name, err)
+		}
+		if !check.Mismatch() || check.Fixed || check.Computed != want {
+			t.Errorf("%s: dry run mismatch; got %+v", g.name, check)
+		}
+		if got, _ := os.ReadFile(path); !bytes.Equal(got, damaged) {
+			t.Errorf("%s: file modified by dry run", g.name)
+		}
+
+		// Fix.
+		check, err = flac.FixMD5(path, false)
+		if err != nil {
+			t.Fatalf("%s: unable to fix MD5 checksum; %v", g.name, err)
+		}
+		if !check.Fixed {
+			t.Errorf("%s: expected MD5 checksum to be fixed", g.name)
+		}
+		if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+			t.Errorf("%s: content mismatch of fixed file", g.name)
+		}
+
+		// Fixed file.
+		check, err = flac.FixMD5(path, false)
+		if err != nil {
+			t.Fatalf("%s: unable to check MD5 checksum; %v", g.name, err)
+		}
+		if check.Mismatch() || check.Fixed {
+			t.Errorf("%s: unexpected mismatch of fixed file; got %+v", g.name, check)
+		}
+	}
+}
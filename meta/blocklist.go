@@ -0,0 +1,133 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A BlockList is a list of metadata blocks, in stream order; e.g. the metadata
+// blocks of a FLAC stream following the StreamInfo block.
+type BlockList []*Block
+
+// First returns the first metadata block of the given type; or nil if not
+// present.
+func (l BlockList) First(t Type) *Block {
+	for _, block := range l {
+		if block.Type == t {
+			return block
+		}
+	}
+	return nil
+}
+
+// OfType returns the metadata blocks of the given type, in stream order.
+func (l BlockList) OfType(t Type) BlockList {
+	var blocks BlockList
+	for _, block := range l {
+		if block.Type == t {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// VorbisComment returns the body of the VorbisComment metadata block; or nil if
+// not present.
+func (l BlockList) VorbisComment() *VorbisComment {
+	for _, block := range l {
+		if body, ok := block.Body.(*VorbisComment); ok {
+			return body
+		}
+	}
+	return nil
+}
+
+// SeekTable returns the body of the SeekTable metadata block; or nil if not
+// present.
+func (l BlockList) SeekTable() *SeekTable {
+	for _, block := range l {
+		if body, ok := block.Body.(*SeekTable); ok {
+			return body
+		}
+	}
+	return nil
+}
+
+// Pictures returns the bodies of the Picture metadata blocks, in stream order.
+func (l BlockList) Pictures() []*Picture {
+	var pics []*Picture
+	for _, block := range l {
+		if pic, ok := block.Body.(*Picture); ok {
+			pics = append(pics, pic)
+		}
+	}
+	return pics
+}
+
+// FrontCover returns the front cover among the Picture metadata blocks, with
+// the fallbacks of FrontCover; or nil if not present.
+func (l BlockList) FrontCover() *Picture {
+	return FrontCover(l)
+}
+
+// Size returns the total size in bytes of the metadata blocks when serialized,
+// including the 4-byte header of each block; as specified by the length of the
+// block headers.
+func (l BlockList) Size() int64 {
+	var size int64
+	for _, block := range l {
+		size += 4 + block.Length
+	}
+	return size
+}
+
+// ErrInvalidBlockList reports that a list of metadata blocks violates the
+// constraints of the FLAC format.
+var ErrInvalidBlockList = errors.New("invalid metadata block list")
+
+// maxBlockLength is the maximum length in bytes of a metadata block body, as
+// stored in the 24-bit length field of the block header.
+const maxBlockLength = 1<<24 - 1
+
+// Validate reports whether the metadata blocks satisfy the constraints of the
+// FLAC format on the order and number of metadata blocks; i.e. a StreamInfo
+// block may only be the first block, at most one SeekTable and VorbisComment
+// block may exist, block lengths fit in 24 bits, and only the last block is
+// marked as last. The Picture metadata blocks are validated as done by
+// ValidatePictures, reporting ErrInvalidPicture.
+func (l BlockList) Validate() error {
+	if err := l.validate(); err != nil {
+		return fmt.Errorf("meta.BlockList.Validate: %w", err)
+	}
+	return nil
+}
+
+// validate validates the metadata blocks. See Validate for details.
+func (l BlockList) validate() error {
+	var nseekTables, ncomments int
+	for i, block := range l {
+		switch block.Type {
+		case TypeStreamInfo:
+			if i != 0 {
+				return fmt.Errorf("%w; StreamInfo block at index %d; expected first block", ErrInvalidBlockList, i)
+			}
+		case TypeSeekTable:
+			nseekTables++
+			if nseekTables > 1 {
+				return fmt.Errorf("%w; more than one SeekTable block", ErrInvalidBlockList)
+			}
+		case TypeVorbisComment:
+			ncomments++
+			if ncomments > 1 {
+				return fmt.Errorf("%w; more than one VorbisComment block", ErrInvalidBlockList)
+			}
+		}
+		if block.Length > maxBlockLength {
+			return fmt.Errorf("%w; length (%d) of %v block at index %d exceeds 24 bits", ErrInvalidBlockList, block.Length, block.Type, i)
+		}
+		if block.IsLast && i != len(l)-1 {
+			return fmt.Errorf("%w; %v block at index %d marked as last block", ErrInvalidBlockList, block.Type, i)
+		}
+	}
+	return validatePictures(l)
+}
@@ -0,0 +1,91 @@
+package meta
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultTagAliases maps from common aliases of Vorbis comment field names to
+// the canonical field names, as used by VorbisComment.Canonicalize if no
+// aliases are specified. Keys are upper case, with spaces, underscores and
+// hyphens preserved.
+var DefaultTagAliases = map[string]string{
+	"ALBUM ARTIST":    "ALBUMARTIST",
+	"ALBUM_ARTIST":    "ALBUMARTIST",
+	"ALBUM-ARTIST":    "ALBUMARTIST",
+	"TRACK":           "TRACKNUMBER",
+	"TRACKNUM":        "TRACKNUMBER",
+	"TRACK NUMBER":    "TRACKNUMBER",
+	"TOTALTRACKS":     "TRACKTOTAL",
+	"TRACKS":          "TRACKTOTAL",
+	"DISC":            "DISCNUMBER",
+	"DISCNUM":         "DISCNUMBER",
+	"DISC NUMBER":     "DISCNUMBER",
+	"TOTALDISCS":      "DISCTOTAL",
+	"DISCS":           "DISCTOTAL",
+	"YEAR":            "DATE",
+	"UNSYNCEDLYRICS":  "LYRICS",
+	"UNSYNCED LYRICS": "LYRICS",
+	"ORIGINALYEAR":    "ORIGINALDATE",
+}
+
+// CanonicalizeOptions specifies the options of VorbisComment.Canonicalize.
+type CanonicalizeOptions struct {
+	// Aliases maps from upper case field names to canonical field names; nil
+	// specifies DefaultTagAliases. Use an empty map to keep aliases.
+	Aliases map[string]string
+}
+
+// Canonicalize canonicalizes the tags of the Vorbis comment in place, and
+// returns the number of tags changed. Field names are converted to upper case
+// and mapped through the aliases of the options, and values are normalized as
+// done by NormalizeTagValue. A nil opts specifies the default options.
+//
+// Field names of Vorbis comments are case-insensitive, but tag editors disagree
+// on the names of common fields; canonicalization eases the comparison and
+// deduplication of tags, e.g. by library managers.
+func (comment *VorbisComment) Canonicalize(opts *CanonicalizeOptions) int {
+	if opts == nil {
+		opts = &CanonicalizeOptions{}
+	}
+	aliases := opts.Aliases
+	if aliases == nil {
+		aliases = DefaultTagAliases
+	}
+	changed := 0
+	for i, tag := range comment.Tags {
+		name := CanonicalTagName(tag[0], aliases)
+		value := NormalizeTagValue(tag[1])
+		if name != tag[0] || value != tag[1] {
+			comment.Tags[i] = [2]string{name, value}
+			changed++
+		}
+	}
+	return changed
+}
+
+// CanonicalTagName returns the canonical field name of the given field name;
+// i.e. the field name in upper case, mapped through the given aliases.
+func CanonicalTagName(name string, aliases map[string]string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if canonical, ok := aliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// NormalizeTagValue returns the given tag value in Unicode Normalization Form C
+// (NFC), with control characters other than tab and line feed removed, and
+// leading and trailing white space trimmed. Carriage returns are removed, so
+// that line breaks of multi-line values (e.g. lyrics) are line feeds.
+func NormalizeTagValue(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' {
+			return -1
+		}
+		return r
+	}, value)
+	return norm.NFC.String(strings.TrimSpace(value))
+}
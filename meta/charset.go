@@ -0,0 +1,73 @@
+package meta
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// A TagRepair records a string of a VorbisComment metadata block transcoded to
+// UTF-8 by VorbisComment.RepairEncoding.
+type TagRepair struct {
+	// Index of the repaired tag within VorbisComment.Tags; or -1 if the vendor
+	// string was repaired.
+	Index int
+	// Name of the repaired tag; empty for the vendor string.
+	Name string
+	// Original value, which is not valid UTF-8, and the transcoded value.
+	Old, New string
+}
+
+// RepairEncoding transcodes the vendor string and tag values which are not
+// valid UTF-8 from Windows-1252 (CP1252), the superset of ISO-8859-1 (Latin-1)
+// used by legacy Windows software, to UTF-8; and returns the repairs made.
+// Strings which are valid UTF-8 are left unchanged, as the byte sequences of
+// non-ASCII Windows-1252 text are rarely valid UTF-8.
+//
+// Vorbis comments are specified to be UTF-8 encoded, but tag editors of legacy
+// systems stored text in the code page of the system.
+func (comment *VorbisComment) RepairEncoding() []TagRepair {
+	var repairs []TagRepair
+	if !utf8.ValidString(comment.Vendor) {
+		repaired := decodeWindows1252(comment.Vendor)
+		repairs = append(repairs, TagRepair{Index: -1, Old: comment.Vendor, New: repaired})
+		comment.Vendor = repaired
+	}
+	for i, tag := range comment.Tags {
+		if utf8.ValidString(tag[1]) {
+			continue
+		}
+		repaired := decodeWindows1252(tag[1])
+		repairs = append(repairs, TagRepair{Index: i, Name: tag[0], Old: tag[1], New: repaired})
+		comment.Tags[i][1] = repaired
+	}
+	return repairs
+}
+
+// windows1252 maps from the bytes 0x80 through 0x9F of Windows-1252 to Unicode
+// code points; the remaining bytes map to the code points of the same value, as
+// in ISO-8859-1. The 5 bytes undefined by Windows-1252 map to the C1 control
+// codes of the same value, as done by the WHATWG Encoding Standard.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// decodeWindows1252 decodes the given Windows-1252 encoded string to UTF-8.
+func decodeWindows1252(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/2)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c < 0xA0:
+			b.WriteRune(windows1252[c-0x80])
+		default:
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package meta
+
+import (
+	"strings"
+)
+
+// Vorbis comment field names of embedded lyrics. Synchronized lyrics are stored
+// in the LYRICS field in LRC format, and unsynchronized lyrics are stored as
+// plain text in the UNSYNCEDLYRICS field; plain text of the LYRICS field, as
+// written by some tag editors, is taken as unsynchronized lyrics.
+const (
+	TagLyrics         = "LYRICS"
+	TagUnsyncedLyrics = "UNSYNCEDLYRICS"
+)
+
+// UnsyncedLyrics returns the unsynchronized lyrics of the Vorbis comment; i.e.
+// the UNSYNCEDLYRICS tag, or the LYRICS tag if it does not hold synchronized
+// lyrics. An empty string is returned if the Vorbis comment has no lyrics.
+func (comment *VorbisComment) UnsyncedLyrics() string {
+	if text, ok := comment.Get(TagUnsyncedLyrics); ok {
+		return text
+	}
+	if text, ok := comment.Get(TagLyrics); ok && !isLRC(text) {
+		return text
+	}
+	return ""
+}
+
+// SetUnsyncedLyrics replaces the unsynchronized lyrics of the Vorbis comment
+// with the given text, stored in the UNSYNCEDLYRICS tag; the tag is removed if
+// text is empty. A LYRICS tag of unsynchronized lyrics is removed, as it would
+// otherwise be superseded.
+func (comment *VorbisComment) SetUnsyncedLyrics(text string) {
+	if lyrics, ok := comment.Get(TagLyrics); ok && !isLRC(lyrics) {
+		comment.Delete(TagLyrics)
+	}
+	if text == "" {
+		comment.Delete(TagUnsyncedLyrics)
+		return
+	}
+	comment.Set(TagUnsyncedLyrics, text)
+}
+
+// SyncedLyrics returns the synchronized lyrics of the Vorbis comment in LRC
+// format; i.e. the LYRICS tag if it holds at least one line starting with a
+// timestamp. The boolean return value reports whether the Vorbis comment has
+// synchronized lyrics.
+func (comment *VorbisComment) SyncedLyrics() (string, bool) {
+	if text, ok := comment.Get(TagLyrics); ok && isLRC(text) {
+		return text, true
+	}
+	return "", false
+}
+
+// SetSyncedLyrics replaces the LYRICS tag of the Vorbis comment with the given
+// synchronized lyrics in LRC format; the tag is removed if lrc is empty.
+// Unsynchronized lyrics of the LYRICS tag are moved to the UNSYNCEDLYRICS tag,
+// unless present.
+func (comment *VorbisComment) SetSyncedLyrics(lrc string) {
+	if text, ok := comment.Get(TagLyrics); ok && !isLRC(text) {
+		if _, ok := comment.Get(TagUnsyncedLyrics); !ok {
+			comment.Set(TagUnsyncedLyrics, text)
+		}
+	}
+	if lrc == "" {
+		comment.Delete(TagLyrics)
+		return
+	}
+	comment.Set(TagLyrics, lrc)
+}
+
+// isLRC reports whether the given text holds synchronized lyrics in LRC format;
+// i.e. whether a line starts with a timestamp in [mm:ss] or [mm:ss.xx] format.
+func isLRC(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) < len("[0:0]") || line[0] != '[' {
+			continue
+		}
+		end := strings.IndexByte(line, ']')
+		if end == -1 {
+			continue
+		}
+		min, sec, ok := strings.Cut(line[1:end], ":")
+		if ok && isDigits(min) && isDigits(strings.Replace(sec, ".", "", 1)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits.
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -33,7 +33,7 @@ import (
 	"io"
 	"io/ioutil"
 
-	"github.com/mewkiz/flac/internal/bits"
+	"github.com/mewkiz/flac/bits"
 )
 
 // A Block contains the header and body of a metadata block.
@@ -2,9 +2,11 @@ package meta_test
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mewkiz/flac"
@@ -252,6 +254,269 @@ func TestParsePicture(t *testing.T) {
 	}
 }
 
+func TestFrontCover(t *testing.T) {
+	picture := func(typ meta.PictureType, desc string) *meta.Block {
+		return &meta.Block{Header: meta.Header{Type: meta.TypePicture}, Body: &meta.Picture{Type: typ, MIME: meta.MIMEJPEG, Desc: desc}}
+	}
+	golden := []struct {
+		blocks []*meta.Block
+		want   string
+	}{
+		{blocks: nil, want: ""},
+		{blocks: []*meta.Block{picture(meta.PictureFileIcon, "icon"), picture(meta.PictureArtist, "artist")}, want: ""},
+		{blocks: []*meta.Block{picture(meta.PictureBackCover, "back"), picture(meta.PictureFrontCover, "front"), picture(meta.PictureFrontCover, "front 2")}, want: "front"},
+		{blocks: []*meta.Block{picture(meta.PictureBackCover, "back"), picture(meta.PictureOther, "other")}, want: "other"},
+		{blocks: []*meta.Block{picture(meta.PictureArtist, "artist"), picture(meta.PictureBackCover, "back")}, want: "back"},
+	}
+	for i, g := range golden {
+		got := meta.FrontCover(g.blocks)
+		switch {
+		case got == nil && g.want != "":
+			t.Errorf("i=%d: expected picture %q, got nil", i, g.want)
+		case got != nil && got.Desc != g.want:
+			t.Errorf("i=%d: picture mismatch; expected %q, got %q", i, g.want, got.Desc)
+		}
+	}
+}
+
+func TestValidatePictures(t *testing.T) {
+	icon := &meta.Picture{Type: meta.PictureFileIcon, MIME: meta.MIMEPNG, Width: 32, Height: 32}
+	picture := func(pic *meta.Picture) *meta.Block {
+		return &meta.Block{Header: meta.Header{Type: meta.TypePicture}, Body: pic}
+	}
+	golden := []struct {
+		blocks []*meta.Block
+		valid  bool
+	}{
+		{blocks: []*meta.Block{picture(icon), picture(&meta.Picture{Type: meta.PictureOtherFileIcon}), picture(&meta.Picture{Type: meta.PictureFrontCover}), picture(&meta.Picture{Type: meta.PictureFrontCover})}, valid: true},
+		{blocks: []*meta.Block{picture(icon), picture(icon)}, valid: false},
+		{blocks: []*meta.Block{picture(&meta.Picture{Type: meta.PictureOtherFileIcon}), picture(&meta.Picture{Type: meta.PictureOtherFileIcon})}, valid: false},
+		{blocks: []*meta.Block{picture(&meta.Picture{Type: meta.PictureFileIcon, MIME: meta.MIMEJPEG, Width: 32, Height: 32})}, valid: false},
+		{blocks: []*meta.Block{picture(&meta.Picture{Type: meta.PictureFileIcon, MIME: meta.MIMEPNG, Width: 64, Height: 64})}, valid: false},
+		{blocks: []*meta.Block{picture(&meta.Picture{Type: 21})}, valid: false},
+	}
+	for i, g := range golden {
+		err := meta.ValidatePictures(g.blocks)
+		if g.valid && err != nil {
+			t.Errorf("i=%d: unexpected error; %v", i, err)
+		}
+		if !g.valid && !errors.Is(err, meta.ErrInvalidPicture) {
+			t.Errorf("i=%d: expected ErrInvalidPicture, got %v", i, err)
+		}
+	}
+}
+
+func TestBlockList(t *testing.T) {
+	stream, err := flac.ParseFile("testdata/silence.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	blocks := stream.Blocks
+	if err := blocks.Validate(); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+	if got := blocks.First(meta.TypePicture); got == nil || got.Body != blocks.FrontCover() {
+		t.Errorf("front cover mismatch; expected first Picture block")
+	}
+	if got, want := len(blocks.Pictures()), len(blocks.OfType(meta.TypePicture)); got != want || got == 0 {
+		t.Errorf("number of pictures mismatch; expected %d, got %d", want, got)
+	}
+	if (blocks.VorbisComment() == nil) != (blocks.First(meta.TypeVorbisComment) == nil) {
+		t.Errorf("VorbisComment block mismatch")
+	}
+	// The FLAC signature, the StreamInfo block and the remaining blocks precede
+	// the audio frames.
+	if got, want := 4+4+34+blocks.Size(), stream.DataStart(); got != want {
+		t.Errorf("size mismatch; expected %d, got %d", want, got)
+	}
+
+	comment := &meta.Block{Header: meta.Header{Type: meta.TypeVorbisComment}, Body: &meta.VorbisComment{}}
+	last := &meta.Block{Header: meta.Header{Type: meta.TypePadding, IsLast: true}}
+	invalid := []meta.BlockList{
+		{comment, comment},
+		{last, comment},
+		{comment, {Header: meta.Header{Type: meta.TypeStreamInfo}}},
+		{{Header: meta.Header{Type: meta.TypePadding, Length: 1 << 24}}},
+	}
+	for i, blocks := range invalid {
+		if err := blocks.Validate(); !errors.Is(err, meta.ErrInvalidBlockList) {
+			t.Errorf("i=%d: expected ErrInvalidBlockList, got %v", i, err)
+		}
+	}
+}
+
+func TestScanVorbisComment(t *testing.T) {
+	// VorbisComment block with a large tag among small tags.
+	tags := [][2]string{
+		{"TITLE", "title"},
+		{"LYRICS", strings.Repeat("la ", 100000)},
+		{"ARTIST", "artist"},
+		{"ALBUM", "album"},
+	}
+	body := &bytes.Buffer{}
+	appendString := func(s string) {
+		binary.Write(body, binary.LittleEndian, uint32(len(s)))
+		body.WriteString(s)
+	}
+	appendString("vendor")
+	binary.Write(body, binary.LittleEndian, uint32(len(tags)))
+	for _, tag := range tags {
+		appendString(tag[0] + "=" + tag[1])
+	}
+	block := func() *meta.Block {
+		hdr := []byte{byte(meta.TypeVorbisComment) | 0x80, byte(body.Len() >> 16), byte(body.Len() >> 8), byte(body.Len())}
+		block, err := meta.New(bytes.NewReader(append(hdr, body.Bytes()...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return block
+	}
+
+	golden := []struct {
+		limits *meta.CommentLimits
+		stop   string
+		want   [][2]string
+		err    error
+	}{
+		{limits: nil, want: tags},
+		{limits: &meta.CommentLimits{MaxTagSize: 1000, SkipOversized: true}, want: [][2]string{tags[0], tags[2], tags[3]}},
+		{limits: &meta.CommentLimits{MaxTagSize: 1000}, want: tags[:1], err: meta.ErrDeclaredBlockTooBig},
+		{limits: &meta.CommentLimits{MaxTags: 3}, err: meta.ErrDeclaredBlockTooBig},
+		{limits: nil, stop: "ARTIST", want: tags[:3]},
+	}
+	for i, g := range golden {
+		var got [][2]string
+		vendor, err := block().ScanVorbisComment(g.limits, func(name, value string) error {
+			got = append(got, [2]string{name, value})
+			if name == g.stop {
+				return meta.ErrStopScan
+			}
+			return nil
+		})
+		if !errors.Is(err, g.err) {
+			t.Errorf("i=%d: error mismatch; expected %v, got %v", i, g.err, err)
+			continue
+		}
+		if err == nil && vendor != "vendor" {
+			t.Errorf("i=%d: vendor mismatch; expected %q, got %q", i, "vendor", vendor)
+		}
+		if !reflect.DeepEqual(got, g.want) {
+			t.Errorf("i=%d: tags mismatch; expected %d tags, got %d", i, len(g.want), len(got))
+		}
+	}
+}
+
+func TestRepairEncoding(t *testing.T) {
+	comment := &meta.VorbisComment{
+		Vendor: "reference libFLAC 1.2.1 20070917",
+		Tags: [][2]string{
+			{"ARTIST", "Bj\xf6rk"},
+			{"TITLE", "J\xf3ga"},
+			{"ALBUM", "Homog\u00e9nic"},
+			{"COMMENT", "\x93quoted\x94 \x80 5"},
+		},
+	}
+	repairs := comment.RepairEncoding()
+	want := [][2]string{
+		{"ARTIST", "Björk"},
+		{"TITLE", "Jóga"},
+		{"ALBUM", "Homogénic"},
+		{"COMMENT", "“quoted” € 5"},
+	}
+	if !reflect.DeepEqual(comment.Tags, want) {
+		t.Errorf("tags mismatch; expected %q, got %q", want, comment.Tags)
+	}
+	var indices []int
+	for _, repair := range repairs {
+		indices = append(indices, repair.Index)
+	}
+	if !reflect.DeepEqual(indices, []int{0, 1, 3}) {
+		t.Errorf("repaired tags mismatch; expected [0 1 3], got %v", indices)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	comment := &meta.VorbisComment{
+		Tags: [][2]string{
+			{"Album Artist", "Bj\u00f6rk"},
+			{"title", "Jo\u0301ga\x00 "},
+			{"ARTIST", "Björk"},
+			{"lyrics", "line 1\r\nline 2"},
+			{"Year", "1997"},
+		},
+	}
+	if got, want := comment.Canonicalize(nil), 4; got != want {
+		t.Errorf("number of changed tags mismatch; expected %d, got %d", want, got)
+	}
+	want := [][2]string{
+		{"ALBUMARTIST", "Björk"},
+		{"TITLE", "Jóga"},
+		{"ARTIST", "Björk"},
+		{"LYRICS", "line 1\nline 2"},
+		{"DATE", "1997"},
+	}
+	if !reflect.DeepEqual(comment.Tags, want) {
+		t.Errorf("tags mismatch; expected %q, got %q", want, comment.Tags)
+	}
+
+	// Custom aliases.
+	aliases := map[string]string{"ALBUMARTIST": "ALBUM ARTIST"}
+	if got, want := meta.CanonicalTagName("AlbumArtist", aliases), "ALBUM ARTIST"; got != want {
+		t.Errorf("tag name mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestMusicBrainz(t *testing.T) {
+	comment := &meta.VorbisComment{
+		Tags: [][2]string{
+			{"TITLE", "Jóga"},
+			{"musicbrainz_trackid", "5a8b06c6-cd48-4b6c-a3b1-42dba5d3b4c9"},
+			{"MUSICBRAINZ_ARTISTID", "87c5dedd-371d-4a53-9f7f-80522fb7f3cb"},
+			{"MUSICBRAINZ_ARTISTID", "0383dadf-2a4e-4d10-a46a-e9e041da8eb3"},
+			{"ARTIST", "Björk"},
+		},
+	}
+	mb := comment.MusicBrainz()
+	if got, want := mb.TrackID, "5a8b06c6-cd48-4b6c-a3b1-42dba5d3b4c9"; got != want {
+		t.Errorf("track ID mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := len(mb.ArtistIDs), 2; got != want {
+		t.Errorf("number of artist IDs mismatch; expected %d, got %d", want, got)
+	}
+	if err := mb.Validate(); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+
+	// Replace the artist IDs and the track ID, and add a release group ID.
+	mb.ArtistIDs = mb.ArtistIDs[:1]
+	mb.TrackID = ""
+	mb.ReleaseGroupID = "2a6d1ed5-f4f5-3b8e-8a8c-7e0b4f4a3b8e"
+	if err := comment.SetMusicBrainz(mb); err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{
+		{"TITLE", "Jóga"},
+		{"MUSICBRAINZ_ARTISTID", "87c5dedd-371d-4a53-9f7f-80522fb7f3cb"},
+		{"ARTIST", "Björk"},
+		{"MUSICBRAINZ_RELEASEGROUPID", "2a6d1ed5-f4f5-3b8e-8a8c-7e0b4f4a3b8e"},
+	}
+	if !reflect.DeepEqual(comment.Tags, want) {
+		t.Errorf("tags mismatch; expected %q, got %q", want, comment.Tags)
+	}
+
+	// Invalid identifiers are rejected.
+	for _, id := range []string{"2A6D1ED5-F4F5-3B8E-8A8C-7E0B4F4A3B8E", "2a6d1ed5f4f53b8e8a8c7e0b4f4a3b8e", "2a6d1ed5-f4f5-3b8e-8a8c-7e0b4f4a3b8", "not an id"} {
+		mb.AlbumID = id
+		if err := comment.SetMusicBrainz(mb); !errors.Is(err, meta.ErrInvalidMBID) {
+			t.Errorf("%q: expected ErrInvalidMBID, got %v", id, err)
+		}
+	}
+	if !reflect.DeepEqual(comment.Tags, want) {
+		t.Errorf("tags modified by invalid MusicBrainz tags; expected %q, got %q", want, comment.Tags)
+	}
+}
+
 // TODO: better error verification than string-based comparisons.
 func TestMissingValue(t *testing.T) {
 	_, err := flac.ParseFile("testdata/missing-value.flac")
@@ -309,3 +574,89 @@ func TestVorbisCommentTooManyTagsOOM(t *testing.T) {
 		}
 	}
 }
+
+func TestNewStreamInfo(t *testing.T) {
+	golden := []struct {
+		sampleRate                 uint32
+		nchannels, bps             uint8
+		blockSizeMin, blockSizeMax uint16
+		err                        bool
+	}{
+		{sampleRate: 44100, nchannels: 2, bps: 16, blockSizeMin: 4096, blockSizeMax: 4096},
+		{sampleRate: 1, nchannels: 8, bps: 4, blockSizeMin: 16, blockSizeMax: 65535},
+		{sampleRate: 655350, nchannels: 1, bps: 32, blockSizeMin: 1152, blockSizeMax: 4608},
+		{sampleRate: 0, nchannels: 2, bps: 16, blockSizeMin: 4096, blockSizeMax: 4096, err: true},
+		{sampleRate: 655351, nchannels: 2, bps: 16, blockSizeMin: 4096, blockSizeMax: 4096, err: true},
+		{sampleRate: 44100, nchannels: 0, bps: 16, blockSizeMin: 4096, blockSizeMax: 4096, err: true},
+		{sampleRate: 44100, nchannels: 9, bps: 16, blockSizeMin: 4096, blockSizeMax: 4096, err: true},
+		{sampleRate: 44100, nchannels: 2, bps: 3, blockSizeMin: 4096, blockSizeMax: 4096, err: true},
+		{sampleRate: 44100, nchannels: 2, bps: 33, blockSizeMin: 4096, blockSizeMax: 4096, err: true},
+		{sampleRate: 44100, nchannels: 2, bps: 16, blockSizeMin: 15, blockSizeMax: 4096, err: true},
+		{sampleRate: 44100, nchannels: 2, bps: 16, blockSizeMin: 4096, blockSizeMax: 1152, err: true},
+	}
+	for _, g := range golden {
+		si, err := meta.NewStreamInfo(g.sampleRate, g.nchannels, g.bps, g.blockSizeMin, g.blockSizeMax)
+		if g.err {
+			if !errors.Is(err, meta.ErrInvalidStreamInfo) {
+				t.Errorf("%+v: expected ErrInvalidStreamInfo, got %v", g, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: unexpected error; %v", g, err)
+			continue
+		}
+		want := &meta.StreamInfo{BlockSizeMin: g.blockSizeMin, BlockSizeMax: g.blockSizeMax, SampleRate: g.sampleRate, NChannels: g.nchannels, BitsPerSample: g.bps}
+		if !reflect.DeepEqual(si, want) {
+			t.Errorf("StreamInfo mismatch; expected %+v, got %+v", want, si)
+		}
+	}
+}
+
+func TestStreamInfoValidate(t *testing.T) {
+	for _, g := range golden {
+		if err := g.info.Validate(); err != nil {
+			t.Errorf("%q: unexpected error; %v", g.path, err)
+		}
+	}
+	valid := meta.StreamInfo{BlockSizeMin: 4096, BlockSizeMax: 4096, SampleRate: 44100, NChannels: 2, BitsPerSample: 16}
+	invalid := []func(si *meta.StreamInfo){
+		func(si *meta.StreamInfo) { si.FrameSizeMin, si.FrameSizeMax = 200, 100 },
+		func(si *meta.StreamInfo) { si.FrameSizeMax = 1 << 24 },
+		func(si *meta.StreamInfo) { si.NSamples = 1 << 36 },
+	}
+	for i, modify := range invalid {
+		si := valid
+		modify(&si)
+		if err := si.Validate(); !errors.Is(err, meta.ErrInvalidStreamInfo) {
+			t.Errorf("%d: expected ErrInvalidStreamInfo, got %v", i, err)
+		}
+	}
+}
+
+func TestLyrics(t *testing.T) {
+	const lrc = "[ti:Song]\n[00:01.00]first\n[00:02.50]second\n"
+	comment := &meta.VorbisComment{Tags: [][2]string{{"LYRICS", "first\nsecond"}}}
+	if got, want := comment.UnsyncedLyrics(), "first\nsecond"; got != want {
+		t.Errorf("unsynchronized lyrics mismatch; expected %q, got %q", want, got)
+	}
+	if _, ok := comment.SyncedLyrics(); ok {
+		t.Error("synchronized lyrics found in plain text")
+	}
+
+	// Unsynchronized lyrics of the LYRICS tag are moved to UNSYNCEDLYRICS.
+	comment.SetSyncedLyrics(lrc)
+	want := [][2]string{{"LYRICS", lrc}, {"UNSYNCEDLYRICS", "first\nsecond"}}
+	if !reflect.DeepEqual(comment.Tags, want) {
+		t.Errorf("tags mismatch; expected %q, got %q", want, comment.Tags)
+	}
+	if got, ok := comment.SyncedLyrics(); !ok || got != lrc {
+		t.Errorf("synchronized lyrics mismatch; expected %q, got %q", lrc, got)
+	}
+
+	comment.SetUnsyncedLyrics("")
+	comment.SetSyncedLyrics("")
+	if len(comment.Tags) != 0 {
+		t.Errorf("tags not removed; got %q", comment.Tags)
+	}
+}
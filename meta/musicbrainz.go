@@ -0,0 +1,172 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Vorbis comment field names of the MusicBrainz tags, as written by MusicBrainz
+// Picard.
+//
+// ref: https://picard-docs.musicbrainz.org/en/appendices/tag_mapping.html
+const (
+	TagMusicBrainzTrackID        = "MUSICBRAINZ_TRACKID"
+	TagMusicBrainzReleaseTrackID = "MUSICBRAINZ_RELEASETRACKID"
+	TagMusicBrainzAlbumID        = "MUSICBRAINZ_ALBUMID"
+	TagMusicBrainzReleaseGroupID = "MUSICBRAINZ_RELEASEGROUPID"
+	TagMusicBrainzArtistID       = "MUSICBRAINZ_ARTISTID"
+	TagMusicBrainzAlbumArtistID  = "MUSICBRAINZ_ALBUMARTISTID"
+	TagMusicBrainzWorkID         = "MUSICBRAINZ_WORKID"
+	TagMusicBrainzDiscID         = "MUSICBRAINZ_DISCID"
+	TagAcoustID                  = "ACOUSTID_ID"
+	TagReleaseType               = "RELEASETYPE"
+	TagReleaseStatus             = "RELEASESTATUS"
+	TagReleaseCountry            = "RELEASECOUNTRY"
+)
+
+// MusicBrainzTags holds the MusicBrainz tags of a Vorbis comment. Identifiers
+// are MusicBrainz identifiers (MBIDs), i.e. UUIDs in canonical form; except for
+// the disc ID. Fields of multiple values are stored as one tag per value.
+type MusicBrainzTags struct {
+	// MBID of the recording (MUSICBRAINZ_TRACKID).
+	TrackID string
+	// MBID of the track of the release (MUSICBRAINZ_RELEASETRACKID).
+	ReleaseTrackID string
+	// MBID of the release (MUSICBRAINZ_ALBUMID).
+	AlbumID string
+	// MBID of the release group (MUSICBRAINZ_RELEASEGROUPID).
+	ReleaseGroupID string
+	// MBIDs of the artists of the recording (MUSICBRAINZ_ARTISTID).
+	ArtistIDs []string
+	// MBIDs of the artists of the release (MUSICBRAINZ_ALBUMARTISTID).
+	AlbumArtistIDs []string
+	// MBIDs of the works of the recording (MUSICBRAINZ_WORKID).
+	WorkIDs []string
+	// Disc ID of the CD table of contents (MUSICBRAINZ_DISCID); not an MBID.
+	DiscID string
+	// AcoustID of the recording (ACOUSTID_ID).
+	AcoustID string
+	// Primary and secondary types of the release group (RELEASETYPE); e.g.
+	// "album" and "live".
+	ReleaseTypes []string
+	// Status of the release (RELEASESTATUS); e.g. "official".
+	ReleaseStatus string
+	// Country of the release (RELEASECOUNTRY); e.g. "GB".
+	ReleaseCountry string
+}
+
+// MusicBrainz returns the MusicBrainz tags of the Vorbis comment; fields of
+// absent tags are left empty.
+func (comment *VorbisComment) MusicBrainz() *MusicBrainzTags {
+	get := func(name string) string {
+		value, _ := comment.Get(name)
+		return value
+	}
+	return &MusicBrainzTags{
+		TrackID:        get(TagMusicBrainzTrackID),
+		ReleaseTrackID: get(TagMusicBrainzReleaseTrackID),
+		AlbumID:        get(TagMusicBrainzAlbumID),
+		ReleaseGroupID: get(TagMusicBrainzReleaseGroupID),
+		ArtistIDs:      comment.GetAll(TagMusicBrainzArtistID),
+		AlbumArtistIDs: comment.GetAll(TagMusicBrainzAlbumArtistID),
+		WorkIDs:        comment.GetAll(TagMusicBrainzWorkID),
+		DiscID:         get(TagMusicBrainzDiscID),
+		AcoustID:       get(TagAcoustID),
+		ReleaseTypes:   comment.GetAll(TagReleaseType),
+		ReleaseStatus:  get(TagReleaseStatus),
+		ReleaseCountry: get(TagReleaseCountry),
+	}
+}
+
+// SetMusicBrainz replaces the MusicBrainz tags of the Vorbis comment with the
+// given tags, as done by VorbisComment.Set; tags of empty fields are removed.
+// The identifiers are validated as done by MusicBrainzTags.Validate, and the
+// Vorbis comment is left unchanged if invalid.
+func (comment *VorbisComment) SetMusicBrainz(mb *MusicBrainzTags) error {
+	if err := mb.validate(); err != nil {
+		return fmt.Errorf("meta.VorbisComment.SetMusicBrainz: %w", err)
+	}
+	set := func(name, value string) {
+		if value == "" {
+			comment.Delete(name)
+			return
+		}
+		comment.Set(name, value)
+	}
+	set(TagMusicBrainzTrackID, mb.TrackID)
+	set(TagMusicBrainzReleaseTrackID, mb.ReleaseTrackID)
+	set(TagMusicBrainzAlbumID, mb.AlbumID)
+	set(TagMusicBrainzReleaseGroupID, mb.ReleaseGroupID)
+	comment.Set(TagMusicBrainzArtistID, mb.ArtistIDs...)
+	comment.Set(TagMusicBrainzAlbumArtistID, mb.AlbumArtistIDs...)
+	comment.Set(TagMusicBrainzWorkID, mb.WorkIDs...)
+	set(TagMusicBrainzDiscID, mb.DiscID)
+	set(TagAcoustID, mb.AcoustID)
+	comment.Set(TagReleaseType, mb.ReleaseTypes...)
+	set(TagReleaseStatus, mb.ReleaseStatus)
+	set(TagReleaseCountry, mb.ReleaseCountry)
+	return nil
+}
+
+// ErrInvalidMBID reports that a MusicBrainz identifier is not a UUID in
+// canonical form.
+var ErrInvalidMBID = errors.New("invalid MusicBrainz identifier")
+
+// Validate reports whether the non-empty identifiers of the MusicBrainz tags
+// are UUIDs in canonical form; i.e. 32 lower case hexadecimal digits separated
+// into groups of 8-4-4-4-12 digits by hyphens. The disc ID is not validated.
+func (mb *MusicBrainzTags) Validate() error {
+	if err := mb.validate(); err != nil {
+		return fmt.Errorf("meta.MusicBrainzTags.Validate: %w", err)
+	}
+	return nil
+}
+
+// validate validates the identifiers of the MusicBrainz tags. See Validate for
+// details.
+func (mb *MusicBrainzTags) validate() error {
+	ids := []struct {
+		name   string
+		values []string
+	}{
+		{TagMusicBrainzTrackID, []string{mb.TrackID}},
+		{TagMusicBrainzReleaseTrackID, []string{mb.ReleaseTrackID}},
+		{TagMusicBrainzAlbumID, []string{mb.AlbumID}},
+		{TagMusicBrainzReleaseGroupID, []string{mb.ReleaseGroupID}},
+		{TagMusicBrainzArtistID, mb.ArtistIDs},
+		{TagMusicBrainzAlbumArtistID, mb.AlbumArtistIDs},
+		{TagMusicBrainzWorkID, mb.WorkIDs},
+		{TagAcoustID, []string{mb.AcoustID}},
+	}
+	for _, id := range ids {
+		for _, value := range id.values {
+			if value != "" && !ValidMBID(value) {
+				return fmt.Errorf("%w; %s=%q", ErrInvalidMBID, id.name, value)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidMBID reports whether s is a MusicBrainz identifier; i.e. a UUID in
+// canonical form, of 32 lower case hexadecimal digits separated into groups of
+// 8-4-4-4-12 digits by hyphens.
+func ValidMBID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
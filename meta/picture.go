@@ -2,6 +2,7 @@ package meta
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -12,34 +13,12 @@ const maxPictureDataSize = 128 << 20 // 128 MB
 //
 // ref: https://www.xiph.org/flac/format.html#metadata_block_picture
 type Picture struct {
-	// Picture type according to the ID3v2 APIC frame:
-	//
-	//     0: Other
-	//     1: 32x32 pixels 'file icon' (PNG only)
-	//     2: Other file icon
-	//     3: Cover (front)
-	//     4: Cover (back)
-	//     5: Leaflet page
-	//     6: Media (e.g. label side of CD)
-	//     7: Lead artist/lead performer/soloist
-	//     8: Artist/performer
-	//     9: Conductor
-	//    10: Band/Orchestra
-	//    11: Composer
-	//    12: Lyricist/text writer
-	//    13: Recording Location
-	//    14: During recording
-	//    15: During performance
-	//    16: Movie/video screen capture
-	//    17: A bright coloured fish
-	//    18: Illustration
-	//    19: Band/artist logotype
-	//    20: Publisher/Studio logotype
+	// Picture type according to the ID3v2 APIC frame; see PictureType.
 	//
 	// ref: http://id3.org/id3v2.4.0-frames
-	Type uint32
-	// MIME type string. The MIME type "-->" specifies that the picture data is
-	// to be interpreted as an URL instead of image data.
+	Type PictureType
+	// MIME type string. The MIME type "-->" (MIMEURL) specifies that the
+	// picture data is to be interpreted as an URL instead of image data.
 	MIME string
 	// Description of the picture.
 	Desc string
@@ -53,6 +32,176 @@ type Picture struct {
 	Data []byte
 }
 
+// PictureType specifies the type of an embedded picture, according to the
+// ID3v2 APIC frame.
+type PictureType uint32
+
+// Picture types.
+const (
+	PictureOther             PictureType = 0  // Other
+	PictureFileIcon          PictureType = 1  // 32x32 pixels 'file icon' (PNG only)
+	PictureOtherFileIcon     PictureType = 2  // Other file icon
+	PictureFrontCover        PictureType = 3  // Cover (front)
+	PictureBackCover         PictureType = 4  // Cover (back)
+	PictureLeaflet           PictureType = 5  // Leaflet page
+	PictureMedia             PictureType = 6  // Media (e.g. label side of CD)
+	PictureLeadArtist        PictureType = 7  // Lead artist/lead performer/soloist
+	PictureArtist            PictureType = 8  // Artist/performer
+	PictureConductor         PictureType = 9  // Conductor
+	PictureBand              PictureType = 10 // Band/Orchestra
+	PictureComposer          PictureType = 11 // Composer
+	PictureLyricist          PictureType = 12 // Lyricist/text writer
+	PictureRecordingLocation PictureType = 13 // Recording Location
+	PictureDuringRecording   PictureType = 14 // During recording
+	PictureDuringPerformance PictureType = 15 // During performance
+	PictureScreenCapture     PictureType = 16 // Movie/video screen capture
+	PictureFish              PictureType = 17 // A bright coloured fish
+	PictureIllustration      PictureType = 18 // Illustration
+	PictureBandLogotype      PictureType = 19 // Band/artist logotype
+	PicturePublisherLogotype PictureType = 20 // Publisher/Studio logotype
+)
+
+// pictureTypeNames maps from picture type to name.
+var pictureTypeNames = [...]string{
+	PictureOther:             "other",
+	PictureFileIcon:          "file icon",
+	PictureOtherFileIcon:     "other file icon",
+	PictureFrontCover:        "front cover",
+	PictureBackCover:         "back cover",
+	PictureLeaflet:           "leaflet page",
+	PictureMedia:             "media",
+	PictureLeadArtist:        "lead artist",
+	PictureArtist:            "artist",
+	PictureConductor:         "conductor",
+	PictureBand:              "band",
+	PictureComposer:          "composer",
+	PictureLyricist:          "lyricist",
+	PictureRecordingLocation: "recording location",
+	PictureDuringRecording:   "during recording",
+	PictureDuringPerformance: "during performance",
+	PictureScreenCapture:     "screen capture",
+	PictureFish:              "bright coloured fish",
+	PictureIllustration:      "illustration",
+	PictureBandLogotype:      "band logotype",
+	PicturePublisherLogotype: "publisher logotype",
+}
+
+func (t PictureType) String() string {
+	if int(t) < len(pictureTypeNames) {
+		return pictureTypeNames[t]
+	}
+	return "<unknown picture type>"
+}
+
+// MIME types of embedded pictures.
+const (
+	// MIMEURL specifies that the picture data is an URL of the picture.
+	MIMEURL = "-->"
+	// MIMEPNG and MIMEJPEG are the MIME types of PNG and JPEG images, the most
+	// common formats of embedded pictures.
+	MIMEPNG  = "image/png"
+	MIMEJPEG = "image/jpeg"
+)
+
+// IsURL reports whether the picture data is an URL of the picture, rather than
+// image data.
+func (pic *Picture) IsURL() bool {
+	return pic.MIME == MIMEURL
+}
+
+// frontCoverFallbacks specifies the picture types selected by FrontCover, in
+// order of preference.
+var frontCoverFallbacks = []PictureType{
+	PictureFrontCover,
+	PictureOther,
+	PictureMedia,
+	PictureLeaflet,
+	PictureIllustration,
+	PictureBackCover,
+}
+
+// FrontCover returns the front cover among the Picture metadata blocks of
+// blocks; falling back to a picture of type "other", media, leaflet page,
+// illustration or back cover, in order of preference, if no picture has the
+// front cover type. It returns nil if no such picture exists.
+func FrontCover(blocks []*Block) *Picture {
+	return SelectPicture(blocks, frontCoverFallbacks...)
+}
+
+// SelectPicture returns the first picture of the first of the given picture
+// types found among the Picture metadata blocks of blocks; or nil if none of the
+// picture types are found.
+func SelectPicture(blocks []*Block, types ...PictureType) *Picture {
+	for _, t := range types {
+		for _, block := range blocks {
+			if pic, ok := block.Body.(*Picture); ok && pic.Type == t {
+				return pic
+			}
+		}
+	}
+	return nil
+}
+
+// ErrInvalidPicture reports that a Picture metadata block violates the
+// constraints of the FLAC format.
+var ErrInvalidPicture = errors.New("invalid Picture")
+
+// Validate reports whether the picture type is known, and whether a file icon
+// (type 1) is a 32x32 pixels PNG image, as required by the FLAC format.
+func (pic *Picture) Validate() error {
+	if err := pic.validate(); err != nil {
+		return fmt.Errorf("meta.Picture.Validate: %w", err)
+	}
+	return nil
+}
+
+// validate validates the fields of the Picture metadata block. See Validate for
+// details.
+func (pic *Picture) validate() error {
+	switch {
+	case int(pic.Type) >= len(pictureTypeNames):
+		return fmt.Errorf("%w; unknown picture type %d", ErrInvalidPicture, pic.Type)
+	case pic.Type == PictureFileIcon && pic.MIME != MIMEPNG:
+		return fmt.Errorf("%w; file icon of MIME type %q; expected %q", ErrInvalidPicture, pic.MIME, MIMEPNG)
+	case pic.Type == PictureFileIcon && (pic.Width != 32 || pic.Height != 32):
+		return fmt.Errorf("%w; file icon of %dx%d pixels; expected 32x32 pixels", ErrInvalidPicture, pic.Width, pic.Height)
+	}
+	return nil
+}
+
+// ValidatePictures validates the Picture metadata blocks of blocks, as done by
+// Picture.Validate, and reports whether at most one picture each of the file
+// icon types (1 and 2) exists, as required by the FLAC format.
+func ValidatePictures(blocks []*Block) error {
+	if err := validatePictures(blocks); err != nil {
+		return fmt.Errorf("meta.ValidatePictures: %w", err)
+	}
+	return nil
+}
+
+// validatePictures validates the Picture metadata blocks of blocks. See
+// ValidatePictures for details.
+func validatePictures(blocks []*Block) error {
+	var nicons [PictureOtherFileIcon + 1]int
+	for _, block := range blocks {
+		pic, ok := block.Body.(*Picture)
+		if !ok {
+			continue
+		}
+		if err := pic.validate(); err != nil {
+			return err
+		}
+		if pic.Type != PictureFileIcon && pic.Type != PictureOtherFileIcon {
+			continue
+		}
+		nicons[pic.Type]++
+		if nicons[pic.Type] > 1 {
+			return fmt.Errorf("%w; more than one picture of type %d (%v)", ErrInvalidPicture, pic.Type, pic.Type)
+		}
+	}
+	return nil
+}
+
 // parsePicture reads and parses the body of a Picture metadata block.
 func (block *Block) parsePicture() error {
 	// 32 bits: Type.
@@ -6,7 +6,26 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/mewkiz/flac/internal/bits"
+	"github.com/mewkiz/flac/bits"
+)
+
+// Limits of the stream properties of the FLAC format.
+//
+// ref: https://www.xiph.org/flac/format.html
+const (
+	// MinBlockSize is the minimum block size (in samples) of a FLAC stream. The
+	// last frame of a stream may hold fewer samples.
+	MinBlockSize = 16
+	// MaxBlockSize is the maximum block size (in samples) of a FLAC stream.
+	MaxBlockSize = 65535
+	// MaxChannels is the maximum number of channels of a FLAC stream.
+	MaxChannels = 8
+	// MinBitsPerSample is the minimum sample size in bits-per-sample.
+	MinBitsPerSample = 4
+	// MaxBitsPerSample is the maximum sample size in bits-per-sample.
+	MaxBitsPerSample = 32
+	// MaxSampleRate is the maximum sample rate in Hz.
+	MaxSampleRate = 655350
 )
 
 // StreamInfo contains the basic properties of a FLAC audio stream, such as its
@@ -39,6 +58,70 @@ type StreamInfo struct {
 	MD5sum [md5.Size]uint8
 }
 
+// NewStreamInfo returns a new StreamInfo metadata block with the given audio
+// properties and range of block sizes, after validating the fields against the
+// ranges permitted by the FLAC format; see StreamInfo.Validate. The frame sizes,
+// total number of samples and MD5 checksum are unknown, and are updated by the
+// encoder once all audio frames have been encoded.
+func NewStreamInfo(sampleRate uint32, nchannels, bitsPerSample uint8, blockSizeMin, blockSizeMax uint16) (*StreamInfo, error) {
+	si := &StreamInfo{
+		BlockSizeMin:  blockSizeMin,
+		BlockSizeMax:  blockSizeMax,
+		SampleRate:    sampleRate,
+		NChannels:     nchannels,
+		BitsPerSample: bitsPerSample,
+	}
+	if err := si.validate(); err != nil {
+		return nil, fmt.Errorf("meta.NewStreamInfo: %w", err)
+	}
+	return si, nil
+}
+
+// ErrInvalidStreamInfo reports that a field of a StreamInfo metadata block is
+// outside of the range permitted by the FLAC format.
+var ErrInvalidStreamInfo = errors.New("invalid StreamInfo")
+
+// Validate reports whether the fields of the StreamInfo metadata block are
+// within the ranges permitted by the FLAC format; i.e. block sizes of at least
+// 16 samples, a sample rate between 1 and 655350 Hz, between 1 and 8 channels,
+// and a sample size between 4 and 32 bits-per-sample. Unknown frame sizes and
+// total number of samples (0 values) are permitted. Errors returned by Validate
+// wrap ErrInvalidStreamInfo.
+func (si *StreamInfo) Validate() error {
+	if err := si.validate(); err != nil {
+		return fmt.Errorf("meta.StreamInfo.Validate: %w", err)
+	}
+	return nil
+}
+
+// validate validates the fields of the StreamInfo metadata block. See Validate
+// for details.
+func (si *StreamInfo) validate() error {
+	switch {
+	case si.BlockSizeMin < MinBlockSize:
+		return fmt.Errorf("%w; minimum block size (%d) below %d samples", ErrInvalidStreamInfo, si.BlockSizeMin, MinBlockSize)
+	case si.BlockSizeMax < MinBlockSize:
+		return fmt.Errorf("%w; maximum block size (%d) below %d samples", ErrInvalidStreamInfo, si.BlockSizeMax, MinBlockSize)
+	case si.BlockSizeMin > si.BlockSizeMax:
+		return fmt.Errorf("%w; minimum block size (%d) exceeds maximum block size (%d)", ErrInvalidStreamInfo, si.BlockSizeMin, si.BlockSizeMax)
+	case si.FrameSizeMin >= 1<<24:
+		return fmt.Errorf("%w; minimum frame size (%d) exceeds 24 bits", ErrInvalidStreamInfo, si.FrameSizeMin)
+	case si.FrameSizeMax >= 1<<24:
+		return fmt.Errorf("%w; maximum frame size (%d) exceeds 24 bits", ErrInvalidStreamInfo, si.FrameSizeMax)
+	case si.FrameSizeMin != 0 && si.FrameSizeMax != 0 && si.FrameSizeMin > si.FrameSizeMax:
+		return fmt.Errorf("%w; minimum frame size (%d) exceeds maximum frame size (%d)", ErrInvalidStreamInfo, si.FrameSizeMin, si.FrameSizeMax)
+	case si.SampleRate < 1 || si.SampleRate > MaxSampleRate:
+		return fmt.Errorf("%w; sample rate (%d) outside of range [1, %d] Hz", ErrInvalidStreamInfo, si.SampleRate, MaxSampleRate)
+	case si.NChannels < 1 || si.NChannels > MaxChannels:
+		return fmt.Errorf("%w; number of channels (%d) outside of range [1, %d]", ErrInvalidStreamInfo, si.NChannels, MaxChannels)
+	case si.BitsPerSample < MinBitsPerSample || si.BitsPerSample > MaxBitsPerSample:
+		return fmt.Errorf("%w; sample size (%d) outside of range [%d, %d] bits-per-sample", ErrInvalidStreamInfo, si.BitsPerSample, MinBitsPerSample, MaxBitsPerSample)
+	case si.NSamples >= 1<<36:
+		return fmt.Errorf("%w; total number of samples (%d) exceeds 36 bits", ErrInvalidStreamInfo, si.NSamples)
+	}
+	return nil
+}
+
 // parseStreamInfo reads and parses the body of a StreamInfo metadata block.
 func (block *Block) parseStreamInfo() error {
 	// 16 bits: BlockSizeMin.
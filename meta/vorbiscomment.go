@@ -2,7 +2,9 @@ package meta
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -18,57 +20,195 @@ type VorbisComment struct {
 	Tags [][2]string
 }
 
+// Get returns the value of the first tag with the given field name, compared
+// case-insensitively. The boolean return value reports whether the tag exists.
+func (comment *VorbisComment) Get(name string) (string, bool) {
+	for _, tag := range comment.Tags {
+		if strings.EqualFold(tag[0], name) {
+			return tag[1], true
+		}
+	}
+	return "", false
+}
+
+// GetAll returns the values of the tags with the given field name, compared
+// case-insensitively, in stream order.
+func (comment *VorbisComment) GetAll(name string) []string {
+	var values []string
+	for _, tag := range comment.Tags {
+		if strings.EqualFold(tag[0], name) {
+			values = append(values, tag[1])
+		}
+	}
+	return values
+}
+
+// Set replaces the tags with the given field name, compared case-insensitively,
+// by a tag of each of the given values. The first tag of the field is replaced
+// in place, and the remaining values follow it; the tags are appended if the
+// field does not exist. Set removes the tags if no values are given.
+func (comment *VorbisComment) Set(name string, values ...string) {
+	var tags [][2]string
+	done := false
+	for _, tag := range comment.Tags {
+		if !strings.EqualFold(tag[0], name) {
+			tags = append(tags, tag)
+			continue
+		}
+		if !done {
+			for _, value := range values {
+				tags = append(tags, [2]string{name, value})
+			}
+			done = true
+		}
+	}
+	if !done {
+		for _, value := range values {
+			tags = append(tags, [2]string{name, value})
+		}
+	}
+	comment.Tags = tags
+}
+
+// Delete removes the tags with the given field name, compared
+// case-insensitively.
+func (comment *VorbisComment) Delete(name string) {
+	comment.Set(name)
+}
+
+// CommentLimits specifies the limits of Block.ScanVorbisComment.
+type CommentLimits struct {
+	// MaxTags specifies the maximum number of tags; a 0 value implies 50000, the
+	// limit of Block.Parse.
+	MaxTags int
+	// MaxTagSize specifies the maximum size in bytes of the vendor string and of
+	// each tag (NAME=VALUE); a 0 value implies no limit other than the length of
+	// the block.
+	MaxTagSize int
+	// SkipOversized specifies whether tags exceeding MaxTagSize are skipped
+	// without being read into memory; e.g. embedded lyrics or pictures.
+	// Otherwise, oversized tags are reported as ErrDeclaredBlockTooBig.
+	SkipOversized bool
+}
+
+// ErrStopScan may be returned by the callback of Block.ScanVorbisComment to
+// stop the scan; the remaining tags are skipped, and ScanVorbisComment returns
+// a nil error.
+var ErrStopScan = errors.New("meta: stop scan")
+
+// ScanVorbisComment reads and parses the body of a VorbisComment metadata block
+// iteratively, calling fn with the name and value of each tag, in stream order;
+// rather than storing all tags in memory, as done by Block.Parse. The vendor
+// string is returned once the block has been consumed. The block body is left
+// unset.
+//
+// The scan stops at the first error returned by fn, which is returned by
+// ScanVorbisComment; unless the error is ErrStopScan. A nil limits specifies
+// the default limits.
+func (block *Block) ScanVorbisComment(limits *CommentLimits, fn func(name, value string) error) (vendor string, err error) {
+	if block.Type != TypeVorbisComment {
+		return "", fmt.Errorf("meta.Block.ScanVorbisComment: invalid block type %v; expected %v", block.Type, TypeVorbisComment)
+	}
+	if limits == nil {
+		limits = &CommentLimits{}
+	}
+	vendor, err = scanVorbisComment(block.lr, limits, "meta.Block.ScanVorbisComment", fn)
+	if errors.Is(err, ErrStopScan) {
+		return vendor, block.Skip()
+	}
+	return vendor, err
+}
+
 // parseVorbisComment reads and parses the body of a VorbisComment metadata
 // block.
 func (block *Block) parseVorbisComment() (err error) {
+	comment := new(VorbisComment)
+	block.Body = comment
+	comment.Vendor, err = scanVorbisComment(block.lr, &CommentLimits{}, "meta.Block.parseVorbisComment", func(name, value string) error {
+		comment.Tags = append(comment.Tags, [2]string{name, value})
+		return nil
+	})
+	return err
+}
+
+// scanVorbisComment reads and parses the body of a VorbisComment metadata block
+// from r, calling fn for each tag and returning the vendor string; as done by
+// Block.ScanVorbisComment. Errors are prefixed with the given function name.
+func scanVorbisComment(r io.Reader, limits *CommentLimits, funcName string, fn func(name, value string) error) (vendor string, err error) {
 	// 32 bits: vendor length.
 	var x uint32
-	if err = binary.Read(block.lr, binary.LittleEndian, &x); err != nil {
-		return unexpected(err)
+	if err = binary.Read(r, binary.LittleEndian, &x); err != nil {
+		return "", unexpected(err)
 	}
 
 	// (vendor length) bits: Vendor.
-	vendor, err := readString(block.lr, int(x))
+	if limits.MaxTagSize > 0 && int64(x) > int64(limits.MaxTagSize) {
+		return "", fmt.Errorf("%s: %w, vendor string size=%d", funcName, ErrDeclaredBlockTooBig, x)
+	}
+	if err := checkRemaining(r, x); err != nil {
+		return "", err
+	}
+	vendor, err = readString(r, int(x))
 	if err != nil {
-		return unexpected(err)
+		return "", unexpected(err)
 	}
-	comment := new(VorbisComment)
-	block.Body = comment
-	comment.Vendor = vendor
 
 	// Parse tags.
 	// 32 bits: number of tags.
-	if err = binary.Read(block.lr, binary.LittleEndian, &x); err != nil {
-		return unexpected(err)
+	if err = binary.Read(r, binary.LittleEndian, &x); err != nil {
+		return vendor, unexpected(err)
 	}
-	if x > maxTags {
-		return fmt.Errorf("meta.Block.parseVorbisComment: %w, number of tags=%d", ErrDeclaredBlockTooBig, x)
+	ntags := limits.MaxTags
+	if ntags == 0 {
+		ntags = maxTags
 	}
-	if x < 1 {
-		return nil
+	if int64(x) > int64(ntags) {
+		return vendor, fmt.Errorf("%s: %w, number of tags=%d", funcName, ErrDeclaredBlockTooBig, x)
 	}
-	comment.Tags = make([][2]string, x)
-	for i := range comment.Tags {
+	for n := x; n > 0; n-- {
 		// 32 bits: vector length
-		if err = binary.Read(block.lr, binary.LittleEndian, &x); err != nil {
-			return unexpected(err)
+		if err = binary.Read(r, binary.LittleEndian, &x); err != nil {
+			return vendor, unexpected(err)
+		}
+		if err := checkRemaining(r, x); err != nil {
+			return vendor, err
+		}
+		if limits.MaxTagSize > 0 && int64(x) > int64(limits.MaxTagSize) {
+			if !limits.SkipOversized {
+				return vendor, fmt.Errorf("%s: %w, tag size=%d", funcName, ErrDeclaredBlockTooBig, x)
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(x)); err != nil {
+				return vendor, unexpected(err)
+			}
+			continue
 		}
 
 		// (vector length): vector.
-		vector, err := readString(block.lr, int(x))
+		vector, err := readString(r, int(x))
 		if err != nil {
-			return unexpected(err)
+			return vendor, unexpected(err)
 		}
 
 		// Parse tag, which has the following format:
 		//    NAME=VALUE
 		pos := strings.Index(vector, "=")
 		if pos == -1 {
-			return fmt.Errorf("meta.Block.parseVorbisComment: unable to locate '=' in vector %q", vector)
+			return vendor, fmt.Errorf("%s: unable to locate '=' in vector %q", funcName, vector)
+		}
+		if err := fn(vector[:pos], vector[pos+1:]); err != nil {
+			return vendor, err
 		}
-		comment.Tags[i][0] = vector[:pos]
-		comment.Tags[i][1] = vector[pos+1:]
 	}
 
+	return vendor, nil
+}
+
+// checkRemaining returns io.ErrUnexpectedEOF if r is limited to fewer than n
+// remaining bytes, so that the length fields of malformed blocks are rejected
+// before allocating memory.
+func checkRemaining(r io.Reader, n uint32) error {
+	if lr, ok := r.(*io.LimitedReader); ok && int64(n) > lr.N {
+		return io.ErrUnexpectedEOF
+	}
 	return nil
 }
@@ -0,0 +1,359 @@
+package flac
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/icza/bitio"
+	"github.com/mewkiz/flac/meta"
+)
+
+// A MetadataRewrite specifies how the metadata blocks of a FLAC file were
+// rewritten.
+type MetadataRewrite uint8
+
+// Metadata rewrites.
+const (
+	// The metadata blocks were rewritten in place, within the space of the
+	// original metadata blocks; the padding of the file absorbed the change in
+	// size, and the audio frames were left untouched.
+	RewriteInPlace MetadataRewrite = iota + 1
+	// The file was rewritten, as the metadata blocks did not fit the space of
+	// the original metadata blocks, or as requested by RewriteOptions.Atomic;
+	// the rewritten file replaced the original file atomically.
+	RewriteFile
+)
+
+// String returns a human-readable representation of the metadata rewrite.
+func (r MetadataRewrite) String() string {
+	switch r {
+	case RewriteInPlace:
+		return "in-place rewrite"
+	case RewriteFile:
+		return "file rewrite"
+	}
+	return fmt.Sprintf("MetadataRewrite(%d)", uint8(r))
+}
+
+// A MetadataPlan describes the rewrite of the metadata blocks of a FLAC file,
+// as planned by PlanMetadataEdit and performed by EditMetadata.
+type MetadataPlan struct {
+	// Rewrite of the file.
+	Rewrite MetadataRewrite
+	// Size in bytes of the FLAC signature and metadata blocks of the original
+	// and the rewritten file.
+	OldHeaderSize, NewHeaderSize int64
+	// Total size in bytes of the bodies of the padding blocks of the original
+	// and the rewritten file.
+	OldPadding, NewPadding int64
+	// Number of bytes following the metadata blocks (i.e. the audio frames and
+	// any trailing data) which are moved to a different byte offset; 0 if the
+	// size of the metadata blocks is unchanged.
+	BytesMoved int64
+	// Number of bytes written by the rewrite; i.e. the size of the metadata
+	// blocks for in-place rewrites, and the size of the file for file
+	// rewrites.
+	BytesWritten int64
+	// Changes of the metadata blocks, excluding padding blocks; in order of the
+	// original metadata blocks (removed and modified blocks) followed by the
+	// rewritten metadata blocks (added blocks).
+	Changes []BlockChange
+}
+
+// PaddingConsumed returns the number of bytes of padding consumed by the
+// rewrite; negative if the padding grows.
+func (plan *MetadataPlan) PaddingConsumed() int64 {
+	return plan.OldPadding - plan.NewPadding
+}
+
+// A BlockChange describes the change of a metadata block.
+type BlockChange struct {
+	// Kind of change.
+	Kind BlockChangeKind
+	// Type of the metadata block.
+	Type meta.Type
+	// Encoded size in bytes of the metadata block, including its header, in the
+	// original and the rewritten file; 0 if not present.
+	OldSize, NewSize int64
+}
+
+// BlockChangeKind specifies the kind of change of a metadata block.
+type BlockChangeKind uint8
+
+// Kinds of metadata block changes.
+const (
+	BlockAdded BlockChangeKind = iota + 1
+	BlockRemoved
+	BlockModified
+)
+
+// String returns a human-readable representation of the kind of change.
+func (kind BlockChangeKind) String() string {
+	switch kind {
+	case BlockAdded:
+		return "added"
+	case BlockRemoved:
+		return "removed"
+	case BlockModified:
+		return "modified"
+	}
+	return fmt.Sprintf("BlockChangeKind(%d)", uint8(kind))
+}
+
+// PlanMetadataEdit reports how EditMetadata would rewrite the FLAC file at path
+// to hold the given metadata blocks, using the specified rewrite options,
+// without modifying the file; e.g. for batch tools to warn before expensive
+// file rewrites. A nil opts specifies the default options.
+func PlanMetadataEdit(path string, blocks []*meta.Block, opts *RewriteOptions) (*MetadataPlan, error) {
+	if opts == nil {
+		opts = &RewriteOptions{}
+	}
+	f, stream, hdrStart, err := openMetadata(path, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	edit, err := planEdit(f, stream, hdrStart, blocks, opts)
+	if err != nil {
+		return nil, err
+	}
+	return edit.plan, nil
+}
+
+// EditMetadata rewrites the metadata blocks of the FLAC file at path to hold
+// the given metadata blocks, following the StreamInfo block of the file, using
+// the specified rewrite options, and reports how the file was rewritten. A nil
+// opts specifies the default options. Padding blocks of the given metadata
+// blocks may be resized or merged.
+//
+// If the metadata blocks fit the space of the original metadata blocks,
+// including their padding, the metadata blocks are rewritten in place, with
+// the padding merged into a single padding block following the last metadata
+// block; as such, the audio frames are never touched. Otherwise, the file is
+// rewritten to a temporary file, retaining the padding blocks, which replaces
+// the original file atomically; a crash leaves either the original file or
+// the rewritten file (see RewriteOptions). Prepended ID3v2 data and trailing
+// data are retained.
+func EditMetadata(path string, blocks []*meta.Block, opts *RewriteOptions) (*MetadataPlan, error) {
+	if opts == nil {
+		opts = &RewriteOptions{}
+	}
+	f, stream, hdrStart, err := openMetadata(path, os.O_RDWR)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return editMetadata(path, f, stream, hdrStart, blocks, opts)
+}
+
+// openMetadata opens the FLAC file at path using the given flag, and parses its
+// metadata blocks. The byte offset of the FLAC signature follows any prepended
+// ID3v2 data.
+func openMetadata(path string, flag int) (f *os.File, stream *Stream, hdrStart int64, err error) {
+	f, err = os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	var id3 [10]byte
+	if _, err := f.ReadAt(id3[:], 0); err != nil {
+		f.Close()
+		return nil, nil, 0, err
+	}
+	stream, err = Parse(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, 0, err
+	}
+	return f, stream, id3v2Size(id3[:]), nil
+}
+
+// metadataEdit is a planned rewrite of the metadata blocks of a file.
+type metadataEdit struct {
+	plan *MetadataPlan
+	// Encoded FLAC signature and metadata blocks of the rewritten file.
+	hdr []byte
+	// Byte offsets of the FLAC signature and of the first frame header of the
+	// original file, and size of the original file.
+	hdrStart, hdrEnd, size int64
+}
+
+// planEdit plans the rewrite of the metadata blocks of the given file, with the
+// parsed stream, to hold the given metadata blocks.
+func planEdit(f *os.File, stream *Stream, hdrStart int64, blocks []*meta.Block, opts *RewriteOptions) (*metadataEdit, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	edit := &metadataEdit{
+		plan:     &MetadataPlan{},
+		hdrStart: hdrStart,
+		hdrEnd:   stream.DataStart(),
+		size:     fi.Size(),
+	}
+	// Lay out the metadata blocks within the space of the original metadata
+	// blocks, merging the padding blocks.
+	var unpadded []*meta.Block
+	for _, block := range blocks {
+		if block.Type != meta.TypePadding {
+			unpadded = append(unpadded, block)
+		}
+	}
+	hdr, err := MarshalMetadata(stream.Info, unpadded...)
+	if err != nil {
+		return nil, err
+	}
+	// The spare space is either left empty, or filled by a padding block of at
+	// least its 4-byte metadata block header.
+	fits := false
+	if spare := edit.hdrEnd - hdrStart - int64(len(hdr)); spare == 0 || spare >= 4 && spare-4 <= maxBlockLength {
+		fits = true
+		if spare > 0 {
+			unpadded = append(unpadded, &meta.Block{Header: meta.Header{Type: meta.TypePadding, Length: spare - 4}})
+			if hdr, err = MarshalMetadata(stream.Info, unpadded...); err != nil {
+				return nil, err
+			}
+		}
+		blocks = unpadded
+	} else if hdr, err = MarshalMetadata(stream.Info, blocks...); err != nil {
+		return nil, err
+	}
+	edit.hdr = hdr
+
+	plan := edit.plan
+	plan.OldHeaderSize = edit.hdrEnd - hdrStart
+	plan.NewHeaderSize = int64(len(hdr))
+	plan.OldPadding = paddingSize(stream.Blocks)
+	plan.NewPadding = paddingSize(blocks)
+	if plan.NewHeaderSize != plan.OldHeaderSize {
+		plan.BytesMoved = edit.size - edit.hdrEnd
+	}
+	if fits && !opts.Atomic {
+		plan.Rewrite = RewriteInPlace
+		plan.BytesWritten = plan.NewHeaderSize
+	} else {
+		plan.Rewrite = RewriteFile
+		plan.BytesWritten = edit.size - plan.OldHeaderSize + plan.NewHeaderSize
+	}
+	if plan.Changes, err = blockChanges(stream.Blocks, blocks); err != nil {
+		return nil, err
+	}
+	return edit, nil
+}
+
+// editMetadata rewrites the metadata blocks of the given file at path, with the
+// parsed stream, to hold the given metadata blocks. The file is closed by the
+// caller.
+func editMetadata(path string, f *os.File, stream *Stream, hdrStart int64, blocks []*meta.Block, opts *RewriteOptions) (*MetadataPlan, error) {
+	edit, err := planEdit(f, stream, hdrStart, blocks, opts)
+	if err != nil {
+		return nil, err
+	}
+	if edit.plan.Rewrite == RewriteInPlace {
+		if _, err := f.WriteAt(edit.hdr, edit.hdrStart); err != nil {
+			return nil, err
+		}
+		// Commit the rewritten metadata blocks to stable storage.
+		if err := f.Sync(); err != nil {
+			return nil, err
+		}
+		return edit.plan, nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	parts := []io.Reader{
+		io.NewSectionReader(f, 0, edit.hdrStart),
+		bytes.NewReader(edit.hdr),
+		io.NewSectionReader(f, edit.hdrEnd, edit.size-edit.hdrEnd),
+	}
+	if err := rewriteFile(path, fi, parts, opts); err != nil {
+		return nil, err
+	}
+	return edit.plan, nil
+}
+
+// paddingSize returns the total size in bytes of the bodies of the padding
+// blocks of blocks.
+func paddingSize(blocks []*meta.Block) int64 {
+	var n int64
+	for _, block := range blocks {
+		if block.Type == meta.TypePadding {
+			n += block.Length
+		}
+	}
+	return n
+}
+
+// blockChanges returns the changes from the original metadata blocks to the
+// rewritten metadata blocks, excluding padding blocks. Blocks of identical
+// encoding are unchanged; the remaining original and rewritten blocks of the
+// same type are paired in order as modified blocks, and the unpaired blocks are
+// removed or added.
+func blockChanges(from, to []*meta.Block) ([]BlockChange, error) {
+	oldData, err := encodeBlocks(from)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := encodeBlocks(to)
+	if err != nil {
+		return nil, err
+	}
+	// Match unchanged blocks.
+	oldMatched := make([]bool, len(from))
+	newMatched := make([]bool, len(to))
+	for i := range from {
+		for j := range to {
+			if !newMatched[j] && from[i].Type == to[j].Type && bytes.Equal(oldData[i], newData[j]) {
+				oldMatched[i], newMatched[j] = true, true
+				break
+			}
+		}
+	}
+	var changes []BlockChange
+	for i, block := range from {
+		if oldMatched[i] || block.Type == meta.TypePadding {
+			continue
+		}
+		change := BlockChange{Kind: BlockRemoved, Type: block.Type, OldSize: int64(len(oldData[i]))}
+		for j := range to {
+			if !newMatched[j] && to[j].Type == block.Type {
+				newMatched[j] = true
+				change.Kind = BlockModified
+				change.NewSize = int64(len(newData[j]))
+				break
+			}
+		}
+		changes = append(changes, change)
+	}
+	for j, block := range to {
+		if newMatched[j] || block.Type == meta.TypePadding {
+			continue
+		}
+		changes = append(changes, BlockChange{Kind: BlockAdded, Type: block.Type, NewSize: int64(len(newData[j]))})
+	}
+	return changes, nil
+}
+
+// encodeBlocks returns the encoding of each of the given metadata blocks,
+// including its header.
+func encodeBlocks(blocks []*meta.Block) ([][]byte, error) {
+	data := make([][]byte, len(blocks))
+	for i, block := range blocks {
+		if block.Type != meta.TypePadding && block.Length != 0 && block.Body == nil {
+			return nil, fmt.Errorf("flac.PlanMetadataEdit: unable to encode metadata block of type %v without body", block.Type)
+		}
+		buf := &bytes.Buffer{}
+		bw := bitio.NewWriter(buf)
+		if err := encodeBlock(bw, block, false); err != nil {
+			return nil, err
+		}
+		if _, err := bw.Align(); err != nil {
+			return nil, err
+		}
+		data[i] = buf.Bytes()
+	}
+	return data, nil
+}
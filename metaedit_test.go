@@ -0,0 +1,137 @@
+package flac_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestPlanMetadataEdit(t *testing.T) {
+	const path = "testdata/love.flac"
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Close()
+	// Metadata blocks: seek table (18 bytes), VorbisComment (40 bytes), padding
+	// (8192 bytes).
+	table, comment, padding := src.Blocks[0], src.Blocks[1], src.Blocks[2]
+	tagged := &meta.Block{
+		Header: comment.Header,
+		Body:   &meta.VorbisComment{Vendor: comment.Body.(*meta.VorbisComment).Vendor, Tags: [][2]string{{"TITLE", "love"}}},
+	}
+	app := &meta.Block{
+		Header: meta.Header{Type: meta.TypeApplication, Length: 4 + 10000},
+		Body:   &meta.Application{ID: 0x74657374, Data: make([]byte, 10000)},
+	}
+	// Size in bytes of the audio frames.
+	audioSize := int64(len(orig)) - src.DataStart()
+
+	golden := []struct {
+		name   string
+		blocks []*meta.Block
+		want   flac.MetadataPlan
+	}{
+		{
+			name:   "add tag",
+			blocks: []*meta.Block{table, tagged, padding},
+			want: flac.MetadataPlan{
+				Rewrite:       flac.RewriteInPlace,
+				OldHeaderSize: src.DataStart(),
+				NewHeaderSize: src.DataStart(),
+				OldPadding:    8192,
+				NewPadding:    8192 - 4 - len64("TITLE=love"),
+				BytesWritten:  src.DataStart(),
+				Changes:       []flac.BlockChange{{Kind: flac.BlockModified, Type: meta.TypeVorbisComment, OldSize: 4 + 40, NewSize: 4 + 40 + 4 + len64("TITLE=love")}},
+			},
+		},
+		{
+			name:   "remove seek table",
+			blocks: []*meta.Block{comment, padding},
+			want: flac.MetadataPlan{
+				Rewrite:       flac.RewriteInPlace,
+				OldHeaderSize: src.DataStart(),
+				NewHeaderSize: src.DataStart(),
+				OldPadding:    8192,
+				NewPadding:    8192 + 4 + 18,
+				BytesWritten:  src.DataStart(),
+				Changes:       []flac.BlockChange{{Kind: flac.BlockRemoved, Type: meta.TypeSeekTable, OldSize: 4 + 18}},
+			},
+		},
+		{
+			name:   "add application",
+			blocks: []*meta.Block{table, comment, app, padding},
+			want: flac.MetadataPlan{
+				Rewrite:       flac.RewriteFile,
+				OldHeaderSize: src.DataStart(),
+				NewHeaderSize: src.DataStart() + 4 + 4 + 10000,
+				OldPadding:    8192,
+				NewPadding:    8192,
+				BytesMoved:    audioSize,
+				BytesWritten:  src.DataStart() + 4 + 4 + 10000 + audioSize,
+				Changes:       []flac.BlockChange{{Kind: flac.BlockAdded, Type: meta.TypeApplication, NewSize: 4 + 4 + 10000}},
+			},
+		},
+	}
+	for _, g := range golden {
+		dst := filepath.Join(t.TempDir(), "edit.flac")
+		if err := os.WriteFile(dst, orig, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		plan, err := flac.PlanMetadataEdit(dst, g.blocks, nil)
+		if err != nil {
+			t.Errorf("%s: unable to plan metadata edit; %v", g.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(*plan, g.want) {
+			t.Errorf("%s: plan mismatch; expected %+v, got %+v", g.name, g.want, *plan)
+		}
+		// Planning leaves the file untouched.
+		data, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, orig) {
+			t.Errorf("%s: file modified by planning", g.name)
+		}
+
+		// The edit is performed as planned.
+		got, err := flac.EditMetadata(dst, g.blocks, nil)
+		if err != nil {
+			t.Errorf("%s: unable to edit metadata; %v", g.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, plan) {
+			t.Errorf("%s: edit mismatch; expected %+v, got %+v", g.name, *plan, *got)
+		}
+		stream, err := flac.ParseFile(dst)
+		if err != nil {
+			t.Fatalf("%s: unable to parse edited file; %v", g.name, err)
+		}
+		stream.Close()
+		if stream.DataStart() != plan.NewHeaderSize {
+			t.Errorf("%s: header size mismatch; expected %d, got %d", g.name, plan.NewHeaderSize, stream.DataStart())
+		}
+		data, err = os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data[stream.DataStart():], orig[src.DataStart():]) {
+			t.Errorf("%s: audio frames mismatch", g.name)
+		}
+	}
+}
+
+// len64 returns the length of s as an int64.
+func len64(s string) int64 {
+	return int64(len(s))
+}
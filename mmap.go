@@ -0,0 +1,130 @@
+package flac
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// A MappedFile is a read-only file mapped into memory, which is accessed using
+// io.ReaderAt without system calls, and without reading the file up front; the
+// operating system pages in the parts of the file which are accessed. As such,
+// the metadata blocks of huge files may be accessed, and the audio frames
+// located by seeking, without reading the file in its entirety.
+//
+// On platforms without memory-mapped files, and if the file may not be mapped,
+// a MappedFile reads the file using its ReadAt method instead.
+type MappedFile struct {
+	// Contents of the memory-mapped file; nil if not mapped.
+	data []byte
+	// Underlying file; nil if mapped.
+	f *os.File
+	// Size in bytes of the file.
+	size int64
+}
+
+// MapFile maps the file at path into memory for reading; falling back to reads
+// of the file on platforms without memory-mapped files. The Close method of the
+// mapped file must be called when finished using it.
+func MapFile(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	size := fi.Size()
+	m := &MappedFile{f: f, size: size}
+	if size == 0 || int64(int(size)) != size {
+		return m, nil
+	}
+	data, err := mmap(f, int(size))
+	if err != nil {
+		// Fall back to reads of the file.
+		return m, nil
+	}
+	// The mapping remains valid once the file is closed.
+	f.Close()
+	m.data, m.f = data, nil
+	return m, nil
+}
+
+// OpenMmap returns a Stream with seeking enabled of the FLAC file at path,
+// mapped into memory using MapFile. It reads and parses the FLAC signature and
+// all metadata blocks. The memory-mapped file is read directly, without read
+// buffers; see NewSeekReaderAt.
+//
+// Note: The Close method of the stream must be called when finished using it,
+// to unmap the file.
+func OpenMmap(path string) (*Stream, error) {
+	m, err := MapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Retain the metadata blocks of the stream header, which are skipped by
+	// NewSeek.
+	stream, err := newSeek(io.NewSectionReader(m, 0, m.Size()), 0, &DecodeOptions{}, true)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	stream.closer = m
+	return stream, nil
+}
+
+// Mapped reports whether the file is mapped into memory.
+func (m *MappedFile) Mapped() bool {
+	return m.data != nil
+}
+
+// Size returns the size in bytes of the file.
+func (m *MappedFile) Size() int64 {
+	return m.size
+}
+
+// Bytes returns the contents of the memory-mapped file, without copying; or nil
+// if the file is not mapped. The contents must not be modified, and must not be
+// accessed once the file is closed.
+func (m *MappedFile) Bytes() []byte {
+	return m.data
+}
+
+// ReadAt reads len(p) bytes of the file into p, starting at byte offset off, as
+// specified by io.ReaderAt.
+func (m *MappedFile) ReadAt(p []byte, off int64) (n int, err error) {
+	if m.data == nil {
+		if m.f == nil {
+			return 0, os.ErrClosed
+		}
+		return m.f.ReadAt(p, off)
+	}
+	if off < 0 {
+		return 0, errors.New("flac.MappedFile.ReadAt: negative offset")
+	}
+	if off >= m.size {
+		return 0, io.EOF
+	}
+	n = copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close unmaps the file, or closes the file if not mapped.
+func (m *MappedFile) Close() error {
+	if m.data != nil {
+		data := m.data
+		m.data = nil
+		return munmap(data)
+	}
+	if m.f != nil {
+		f := m.f
+		m.f = nil
+		return f.Close()
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package flac
+
+import (
+	"errors"
+	"os"
+)
+
+// mmap reports that memory-mapped files are not supported on this platform.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errors.New("flac.MapFile: memory-mapped files not supported")
+}
+
+// munmap is never called on platforms without memory-mapped files.
+func munmap(data []byte) error {
+	return nil
+}
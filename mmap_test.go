@@ -0,0 +1,79 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"testing/iotest"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestMapFile(t *testing.T) {
+	const path = "testdata/love.flac"
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := flac.MapFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Size() != int64(len(want)) {
+		t.Errorf("size mismatch; expected %d, got %d", len(want), m.Size())
+	}
+	if m.Mapped() && !bytes.Equal(m.Bytes(), want) {
+		t.Error("contents of memory-mapped file mismatch")
+	}
+	if err := iotest.TestReader(io.NewSectionReader(m, 0, m.Size()), want); err != nil {
+		t.Error(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ReadAt(make([]byte, 1), 0); err == nil {
+		t.Error("expected error for read of closed file")
+	}
+}
+
+func TestOpenMmap(t *testing.T) {
+	const path = "testdata/172960.flac"
+	want, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.Close()
+	stream, err := flac.OpenMmap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.DataStart() != want.DataStart() || len(stream.Blocks) != len(want.Blocks) {
+		t.Errorf("metadata mismatch; expected data start %d and %d blocks, got %d and %d blocks", want.DataStart(), len(want.Blocks), stream.DataStart(), len(stream.Blocks))
+	}
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantFrame, err := want.ParseNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for channel, subframe := range frame.Subframes {
+			if !slices.Equal(subframe.Samples, wantFrame.Subframes[channel].Samples) {
+				t.Fatalf("samples of channel %d of frame %d mismatch", channel, frame.Num)
+			}
+		}
+	}
+	if _, err := stream.Seek(20000); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package flac
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the first size bytes of f into memory for reading.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap unmaps the memory-mapped data.
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
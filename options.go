@@ -0,0 +1,156 @@
+package flac
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// An Option configures the Stream created by NewStream and Open.
+type Option func(cfg *streamConfig)
+
+// streamConfig specifies the configuration of a Stream created by NewStream and
+// Open.
+type streamConfig struct {
+	// Enable seeking; see WithSeeking.
+	seek bool
+	// Parse all metadata blocks; see WithAllMetadata.
+	allMetadata bool
+	// Decoder options.
+	opts DecodeOptions
+}
+
+// newStreamConfig returns the stream configuration of the given options.
+func newStreamConfig(opts []Option) *streamConfig {
+	cfg := new(streamConfig)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithSeeking enables seeking, as done by NewSeek; the reader of the stream
+// must implement io.ReadSeeker. Only the decoder options of strict validation,
+// watchdog limits, MD5 verification and retained intermediates are supported
+// by seekable streams.
+func WithSeeking() Option {
+	return func(cfg *streamConfig) {
+		cfg.seek = true
+	}
+}
+
+// WithAllMetadata parses all metadata blocks into Stream.Blocks, as done by
+// Parse; otherwise, the bodies of all metadata blocks but StreamInfo are
+// skipped.
+func WithAllMetadata() Option {
+	return func(cfg *streamConfig) {
+		cfg.allMetadata = true
+	}
+}
+
+// WithMD5 enables the verification of the MD5 checksum of the decoded audio
+// samples against StreamInfo; see DecodeOptions.VerifyMD5.
+func WithMD5() Option {
+	return func(cfg *streamConfig) {
+		cfg.opts.VerifyMD5 = true
+	}
+}
+
+// Limits specifies the limits of the decoder watchdog, which rejects
+// pathological inputs such as decompression bombs. The zero value specifies no
+// limits.
+type Limits struct {
+	// Maximum ratio of decoded samples to consumed bytes; see
+	// DecodeOptions.MaxSampleRatio.
+	MaxSampleRatio float64
+	// Maximum time spent decoding a frame; see DecodeOptions.MaxFrameTime.
+	MaxFrameTime time.Duration
+}
+
+// WithLimits enables the given limits of the decoder watchdog.
+func WithLimits(limits Limits) Option {
+	return func(cfg *streamConfig) {
+		cfg.opts.MaxSampleRatio = limits.MaxSampleRatio
+		cfg.opts.MaxFrameTime = limits.MaxFrameTime
+	}
+}
+
+// WithStrictness enables strict validation, targeting the given revision of
+// the specification of the FLAC format; see DecodeOptions.Strict and
+// DecodeOptions.Spec.
+func WithStrictness(spec frame.Spec) Option {
+	return func(cfg *streamConfig) {
+		cfg.opts.Strict = true
+		cfg.opts.Spec = spec
+	}
+}
+
+// WithDecodeOptions specifies the decoder options of the stream, replacing the
+// decoder options set by preceding options. A nil opts specifies the default
+// options.
+func WithDecodeOptions(opts *DecodeOptions) Option {
+	return func(cfg *streamConfig) {
+		if opts == nil {
+			cfg.opts = DecodeOptions{}
+			return
+		}
+		cfg.opts = *opts
+	}
+}
+
+// NewStream creates a new Stream for accessing the audio samples of r, using
+// the specified options. By default, it reads and parses the FLAC signature and
+// the StreamInfo metadata block, but skips all other metadata blocks, as done
+// by New; see WithAllMetadata and WithSeeking.
+//
+// Call Stream.Next to parse the frame header of the next audio frame, and call
+// Stream.ParseNext to parse the entire next frame including audio samples.
+func NewStream(r io.Reader, opts ...Option) (*Stream, error) {
+	return newStream(r, newStreamConfig(opts), "flac.NewStream")
+}
+
+// newStream creates a new Stream for accessing the audio samples of r, using
+// the given stream configuration. The given function name is used for error
+// messages.
+func newStream(r io.Reader, cfg *streamConfig, funcName string) (*Stream, error) {
+	if !cfg.seek {
+		return newWithOptions(r, &cfg.opts, !cfg.allMetadata)
+	}
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return nil, fmt.Errorf("%s: seeking requires an io.ReadSeeker, got %T", funcName, r)
+	}
+	if err := checkSeekOptions(&cfg.opts); err != nil {
+		return nil, fmt.Errorf("%s: %w", funcName, err)
+	}
+	return newSeek(rs, 0, &cfg.opts, cfg.allMetadata)
+}
+
+// checkSeekOptions reports whether the given decoder options are supported by
+// seekable streams.
+func checkSeekOptions(opts *DecodeOptions) error {
+	switch {
+	case opts.LowMemory || opts.MaxSampleMemory != 0:
+		return errors.New("low-memory mode not supported with seeking")
+	case opts.Logger != nil || opts.EventBuffer != 0:
+		return errors.New("event logging not supported with seeking")
+	case opts.Digest:
+		return errors.New("digests not supported with seeking")
+	case opts.OnTotals != nil || len(opts.SampleHashes) > 0:
+		return errors.New("stream totals and sample hashes not supported with seeking")
+	case opts.RepairTags:
+		return errors.New("tag repair not supported with seeking")
+	case opts.TrackFrameSizes || opts.OnFrameSize != nil || opts.BitrateWindow != 0:
+		return errors.New("frame size tracking not supported with seeking")
+	case opts.ReadBufferSize != 0:
+		return errors.New("read buffer size not supported with seeking; see NewSeekSize")
+	case opts.Pace != 0:
+		return errors.New("pace not supported with seeking")
+	case !(opts.MaxSampleRatio >= 0) || opts.MaxFrameTime < 0:
+		return errors.New("invalid watchdog limits")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package flac_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestNewStreamOptions(t *testing.T) {
+	const path = "testdata/love.flac"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Metadata blocks are skipped by default.
+	stream, err := flac.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.Blocks) != 0 {
+		t.Errorf("number of metadata blocks mismatch; expected 0, got %d", len(stream.Blocks))
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("unable to close stream; %v", err)
+	}
+
+	// Seekable stream with all metadata blocks.
+	stream, err = flac.NewStream(bytes.NewReader(data), flac.WithSeeking(), flac.WithAllMetadata(), flac.WithStrictness(frame.SpecRFC9639))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.Blocks) != 3 {
+		t.Errorf("number of metadata blocks mismatch; expected 3, got %d", len(stream.Blocks))
+	}
+	if _, err := stream.Seek(10000); err != nil {
+		t.Errorf("unable to seek; %v", err)
+	}
+
+	// Seeking requires an io.ReadSeeker, and supports a subset of the decoder
+	// options.
+	if _, err := flac.NewStream(iotest.HalfReader(bytes.NewReader(data)), flac.WithSeeking()); err == nil {
+		t.Error("expected error for seeking of io.Reader")
+	}
+	if _, err := flac.NewStream(bytes.NewReader(data), flac.WithSeeking(), flac.WithDecodeOptions(&flac.DecodeOptions{Digest: true})); err == nil {
+		t.Error("expected error for digests of seekable stream")
+	}
+}
+
+func TestNewStreamMD5(t *testing.T) {
+	data, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The MD5 checksum is stored in the last 16 bytes of the StreamInfo body,
+	// which starts at offset 8.
+	damaged := bytes.Clone(data)
+	damaged[8+18] ^= 0xFF
+	golden := []struct {
+		name string
+		data []byte
+		opts []flac.Option
+		want error
+	}{
+		{name: "valid", data: data, opts: []flac.Option{flac.WithMD5()}, want: io.EOF},
+		{name: "damaged", data: damaged, opts: []flac.Option{flac.WithMD5()}, want: flac.ErrChecksumMismatch},
+		{name: "damaged without verification", data: damaged, want: io.EOF},
+		{name: "damaged seekable", data: damaged, opts: []flac.Option{flac.WithSeeking(), flac.WithMD5()}, want: flac.ErrChecksumMismatch},
+	}
+	for _, g := range golden {
+		stream, err := flac.NewStream(bytes.NewReader(g.data), g.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			if _, err = stream.ParseNext(); err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, g.want) {
+			t.Errorf("%s: error mismatch; expected %v, got %v", g.name, g.want, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package flac
+
+import (
+	"time"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// pacer rate-limits the decoding of audio frames to a multiple of real time.
+// See DecodeOptions.Pace.
+type pacer struct {
+	// Number of samples per second of wall time; the sample rate times the
+	// pace.
+	rate float64
+	// Wall time of the first sample of the stream, shifted forward by the time
+	// the decoder fell behind.
+	start time.Time
+	// Number of samples decoded since start.
+	nsamples uint64
+}
+
+// newPacer returns a pacer decoding audio samples of the given sample rate at
+// the given multiple of real time; or nil if pacing is disabled or the sample
+// rate is unknown.
+func newPacer(sampleRate uint32, pace float64) *pacer {
+	if pace == 0 || sampleRate == 0 {
+		return nil
+	}
+	return &pacer{rate: float64(sampleRate) * pace}
+}
+
+// wait blocks until the given decoded frame is due, and accounts for its
+// samples. The first frame is due immediately, and each subsequent frame once
+// the samples of the preceding frames have been played at the pace of the
+// pacer.
+func (p *pacer) wait(f *frame.Frame) {
+	now := time.Now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	due := p.start.Add(time.Duration(float64(p.nsamples) / p.rate * float64(time.Second)))
+	if d := due.Sub(now); d > 0 {
+		time.Sleep(d)
+	} else {
+		// Time lost by the caller or the decoder is not made up for by a burst
+		// of frames.
+		p.start = p.start.Add(-d)
+	}
+	p.nsamples += uint64(f.BlockSize)
+}
@@ -0,0 +1,54 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestPace(t *testing.T) {
+	data, err := os.ReadFile("testdata/172960.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pace = 10
+	stream, err := flac.NewWithOptions(bytes.NewReader(data), &flac.DecodeOptions{Pace: pace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Samples per second of wall time.
+	rate := pace * float64(stream.Info.SampleRate)
+	start := time.Now()
+	var nsamples uint64
+	for {
+		f, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Frames are not returned before the preceding samples have been played
+		// at the given pace.
+		due := time.Duration(float64(nsamples) / rate * float64(time.Second))
+		if elapsed := time.Since(start); elapsed < due {
+			t.Errorf("frame at sample %d returned early; expected after %v, got %v", nsamples, due, elapsed)
+		}
+		nsamples += uint64(f.BlockSize)
+	}
+	if nsamples != stream.Info.NSamples {
+		t.Errorf("number of samples mismatch; expected %d, got %d", stream.Info.NSamples, nsamples)
+	}
+
+	// Invalid paces.
+	for _, pace := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if _, err := flac.NewWithOptions(bytes.NewReader(data), &flac.DecodeOptions{Pace: pace}); err == nil {
+			t.Errorf("pace %v: expected error for invalid pace", pace)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Package preview generates short preview clips of FLAC streams, as FLAC or
+// WAVE files; e.g. for streaming services generating previews server-side.
+//
+// The audio samples of the clip are decoded using flac.Stream.DecodeRange,
+// which seeks directly to the start of the clip in streams created with
+// flac.NewSeek:
+//
+//	f, err := os.Open("song.flac")
+//	...
+//	stream, err := flac.NewSeek(f)
+//	...
+//	// 30 seconds from 25% into the song.
+//	err = preview.Write(w, stream, nil)
+package preview
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+	"github.com/mewkiz/flac/wav"
+)
+
+// Format is the file format of a preview clip.
+type Format uint8
+
+// File formats.
+const (
+	// FLAC stream.
+	FormatFLAC Format = iota
+	// WAVE file of PCM audio samples.
+	FormatWAV
+)
+
+// String returns a string representation of the file format.
+func (format Format) String() string {
+	switch format {
+	case FormatFLAC:
+		return "FLAC"
+	case FormatWAV:
+		return "WAV"
+	}
+	return fmt.Sprintf("<unknown format %d>", uint8(format))
+}
+
+// Options specifies the options of a preview clip.
+type Options struct {
+	// Start of the clip, as a fraction of the duration of the stream in the
+	// range [0, 1). The start is moved towards the beginning of the stream, if
+	// the clip would otherwise extend past its end.
+	Start float64
+	// Duration of the clip; streams shorter than the duration are previewed
+	// in their entirety. A value of 0 specifies 30 seconds.
+	Duration time.Duration
+	// Duration of the linear fade-in at the start of the clip and fade-out at
+	// the end of the clip; or 0 for no fades.
+	Fade time.Duration
+	// File format of the clip.
+	Format Format
+	// KeepMetadata specifies whether to copy the VorbisComment and Picture
+	// metadata blocks of the stream to FLAC clips. The metadata blocks of the
+	// stream must have been parsed (e.g. using flac.Parse).
+	KeepMetadata bool
+}
+
+// DefaultOptions returns the default options of a preview clip; a 30-second
+// FLAC clip from 25% into the stream.
+func DefaultOptions() *Options {
+	return &Options{
+		Start:    0.25,
+		Duration: defaultDuration,
+	}
+}
+
+// defaultDuration specifies the default duration of a preview clip.
+const defaultDuration = 30 * time.Second
+
+// blockSize specifies the block size of the frames of FLAC clips.
+const blockSize = 4096
+
+// Write writes a preview clip of the given FLAC stream to w, using the given
+// options; a nil value specifies the default options. The total number of
+// samples of the StreamInfo block of the stream must be specified.
+//
+// Audio samples are decoded from the current position of streams not created
+// with flac.NewSeek, as such the clip must start at or after the current
+// position. If w implements io.WriteSeeker, the StreamInfo block of FLAC clips
+// is updated with the MD5 checksum of the audio samples; see
+// flac.Encoder.Close.
+func Write(w io.Writer, stream *flac.Stream, opts *Options) error {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	info := stream.Info
+	if info.NSamples == 0 {
+		return errors.New("preview.Write: total number of samples of stream not specified")
+	}
+	if opts.Start < 0 || opts.Start >= 1 {
+		return fmt.Errorf("preview.Write: invalid start %v; expected >= 0 and < 1", opts.Start)
+	}
+	if opts.Duration < 0 || opts.Fade < 0 {
+		return fmt.Errorf("preview.Write: invalid duration %v or fade %v", opts.Duration, opts.Fade)
+	}
+	duration := opts.Duration
+	if duration == 0 {
+		duration = defaultDuration
+	}
+	// Determine the sample range of the clip.
+	nsamples := min(max(durationSamples(duration, info.SampleRate), 1), info.NSamples)
+	start := min(uint64(opts.Start*float64(info.NSamples)), info.NSamples-nsamples)
+	channels, err := stream.DecodeRange(start, nsamples, nil)
+	if err != nil {
+		return err
+	}
+	fade(channels, durationSamples(opts.Fade, info.SampleRate))
+
+	clipInfo := &meta.StreamInfo{
+		BlockSizeMin:  blockSize,
+		BlockSizeMax:  blockSize,
+		SampleRate:    info.SampleRate,
+		NChannels:     info.NChannels,
+		BitsPerSample: info.BitsPerSample,
+		NSamples:      nsamples,
+	}
+	switch opts.Format {
+	case FormatFLAC:
+		var blocks []*meta.Block
+		if opts.KeepMetadata {
+			for _, block := range stream.Blocks {
+				switch block.Type {
+				case meta.TypeVorbisComment, meta.TypePicture:
+					blocks = append(blocks, block)
+				}
+			}
+		}
+		enc, err := flac.NewEncoder(w, clipInfo, blocks...)
+		if err != nil {
+			return err
+		}
+		if err := writeFrames(enc.WriteFrame, clipInfo, channels); err != nil {
+			enc.Close()
+			return err
+		}
+		return enc.Close()
+	case FormatWAV:
+		ww, err := wav.NewWriter(w, clipInfo)
+		if err != nil {
+			return err
+		}
+		if err := writeFrames(ww.WriteFrame, clipInfo, channels); err != nil {
+			return err
+		}
+		return ww.Close()
+	}
+	return fmt.Errorf("preview.Write: unsupported format %v", opts.Format)
+}
+
+// WriteFile writes a preview clip of the FLAC file at src to dst. See Write
+// for details.
+func WriteFile(dst, src string, opts *Options) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	var blocks []*meta.Block
+	if opts != nil && opts.KeepMetadata {
+		// Parse the metadata blocks, which are skipped by flac.NewSeek.
+		parsed, err := flac.Parse(r)
+		if err != nil {
+			return err
+		}
+		blocks = parsed.Blocks
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	stream, err := flac.NewSeek(r)
+	if err != nil {
+		return err
+	}
+	stream.Blocks = blocks
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	// Hide the Close method of w from the encoder, to close w on error.
+	if err := Write(struct{ io.WriteSeeker }{w}, stream, opts); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// durationSamples returns the number of samples (per channel) of the given
+// duration at the given sample rate.
+func durationSamples(d time.Duration, sampleRate uint32) uint64 {
+	return uint64(d.Seconds()*float64(sampleRate) + 0.5)
+}
+
+// fade applies a linear fade-in and fade-out of n samples (per channel) to the
+// given audio samples, in place. The fades are shortened to half the length of
+// the samples.
+func fade(channels [][]int32, n uint64) {
+	if len(channels) == 0 {
+		return
+	}
+	nsamples := len(channels[0])
+	n = min(n, uint64(nsamples/2))
+	for i := 0; i < int(n); i++ {
+		gain := (float64(i) + 0.5) / float64(n)
+		for _, samples := range channels {
+			samples[i] = int32(float64(samples[i]) * gain)
+			samples[nsamples-1-i] = int32(float64(samples[nsamples-1-i]) * gain)
+		}
+	}
+}
+
+// writeFrames writes the given audio samples as frames of blockSize samples
+// (per channel) using the given write function.
+func writeFrames(write func(f *frame.Frame) error, info *meta.StreamInfo, channels [][]int32) error {
+	nsamples := len(channels[0])
+	for num, start := 0, 0; start < nsamples; num, start = num+1, start+blockSize {
+		end := min(start+blockSize, nsamples)
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(end - start),
+				SampleRate:        info.SampleRate,
+				Channels:          frame.Channels(info.NChannels - 1),
+				BitsPerSample:     info.BitsPerSample,
+				Num:               uint64(num),
+			},
+			Subframes: make([]*frame.Subframe, len(channels)),
+		}
+		for channel, samples := range channels {
+			f.Subframes[channel] = &frame.Subframe{
+				SubHeader: frame.SubHeader{
+					Pred: frame.PredVerbatim,
+				},
+				Samples:  samples[start:end],
+				NSamples: end - start,
+			}
+		}
+		if err := write(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package preview_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/preview"
+)
+
+// decodeRange returns the given range of audio samples of the FLAC file at
+// path.
+func decodeRange(t *testing.T, path string, first, n uint64) [][]int32 {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stream, err := flac.NewSeek(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := stream.DecodeRange(first, n, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+// decodeAll returns the audio samples of the given FLAC stream.
+func decodeAll(t *testing.T, stream *flac.Stream) [][]int32 {
+	t.Helper()
+	samples := make([][]int32, stream.Info.NChannels)
+	for channels, err := range stream.Channels() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range samples {
+			samples[i] = append(samples[i], channels[i]...)
+		}
+	}
+	return samples
+}
+
+func TestWriteFLAC(t *testing.T) {
+	const path = "../testdata/59996.flac" // 8192 samples at 44.1 kHz
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stream, err := flac.NewSeek(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	opts := &preview.Options{Start: 0.25, Duration: 50 * time.Millisecond}
+	if err := preview.Write(buf, stream, opts); err != nil {
+		t.Fatal(err)
+	}
+	clip, err := flac.New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2205 samples from sample 2048.
+	if clip.Info.NSamples != 2205 {
+		t.Fatalf("number of samples mismatch; expected 2205, got %d", clip.Info.NSamples)
+	}
+	got := decodeAll(t, clip)
+	want := decodeRange(t, path, 2048, 2205)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("audio samples of clip mismatch")
+	}
+
+	// The clip is moved to the start of streams shorter than the duration.
+	if _, err := stream.Seek(0); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := preview.Write(buf, stream, nil); err != nil {
+		t.Fatal(err)
+	}
+	clip, err = flac.New(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeAll(t, clip); !reflect.DeepEqual(got, decodeRange(t, path, 0, 8192)) {
+		t.Fatalf("audio samples of full-length clip mismatch")
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	const path = "../testdata/59996.flac" // stereo, 24 bits-per-sample
+	dst := t.TempDir() + "/clip.wav"
+	opts := &preview.Options{Start: 0.5, Duration: 10 * time.Millisecond, Fade: 2 * time.Millisecond, Format: preview.FormatWAV}
+	if err := preview.WriteFile(dst, path, opts); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 441 samples from sample 4096, with 88 samples of fade-in and fade-out.
+	const nsamples = 441
+	if want := 44 + nsamples*2*3; len(data) != want {
+		t.Fatalf("size of WAVE file mismatch; expected %d, got %d", want, len(data))
+	}
+	want := decodeRange(t, path, 4096, nsamples)
+	sample := func(i, channel int) int32 {
+		b := data[44+(i*2+channel)*3:]
+		return int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+	}
+	for _, i := range []int{100, 200, 340} {
+		if got := sample(i, 1); got != want[1][i] {
+			t.Fatalf("sample %d mismatch; expected %d, got %d", i, want[1][i], got)
+		}
+	}
+	if got, orig := sample(0, 0), want[0][0]; abs(got) > abs(orig)/50+1 {
+		t.Fatalf("first sample not faded in; original %d, got %d", orig, got)
+	}
+	if riffSize := binary.LittleEndian.Uint32(data[4:8]); int(riffSize) != len(data)-8 {
+		t.Fatalf("RIFF size mismatch; expected %d, got %d", len(data)-8, riffSize)
+	}
+}
+
+// abs returns the absolute value of x.
+func abs(x int32) int32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
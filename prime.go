@@ -0,0 +1,90 @@
+package flac
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/mewkiz/flac/bufseekio"
+	"github.com/mewkiz/flac/frame"
+)
+
+// maxPrimeSize specifies the maximum size in bytes of the read buffer of
+// Stream.Prime.
+const maxPrimeSize = 64 << 20
+
+// Prime reads ahead up to n audio frames of the stream into the read buffer,
+// without decoding them; so that the following calls to ParseNext do not block
+// on reads of high-latency readers, such as files on network storage. Prime is
+// typically called after Seek, to hide the latency of the first frames played.
+// It returns the number of complete frames buffered, which is less than n near
+// the end of the stream, or if n frames exceed 64 MiB.
+//
+// Frames are located by their frame headers, as done by Repair. The read buffer
+// of streams created by NewSeek is grown as needed to hold the frames, and
+// retains its size. Streams of in-memory readers need no priming; Prime only
+// counts their frames. Streams without seeking support cannot be primed.
+func (stream *Stream) Prime(n int) (int, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	size := n * max(typicalFrameSize(stream.Info), 1)
+	for {
+		size = min(size, maxPrimeSize)
+		data, err := stream.peek(size)
+		atEOF := err == io.EOF
+		if err != nil && !atEOF && err != bufseekio.ErrBufferFull {
+			return 0, err
+		}
+		nframes := countFrames(data, n, atEOF)
+		if nframes >= n || atEOF || size >= maxPrimeSize {
+			return nframes, nil
+		}
+		size *= 2
+	}
+}
+
+// peek returns up to size bytes of the stream following the current offset,
+// without advancing the stream; or fewer bytes with io.EOF at the end of the
+// stream.
+func (stream *Stream) peek(size int) ([]byte, error) {
+	switch r := stream.r.(type) {
+	case *bufseekio.ReadSeeker:
+		if r.Size() < size {
+			r.Resize(size)
+		}
+		return r.Peek(size)
+	case io.ReaderAt:
+		// In-memory readers; see isInMemory.
+		buf := make([]byte, size)
+		n, err := r.ReadAt(buf, stream.Offset())
+		if n == size {
+			err = nil
+		}
+		return buf[:n], err
+	}
+	return nil, errors.New("flac.Stream.Prime: priming requires a seekable stream; see NewSeek")
+}
+
+// countFrames returns the number of complete frames of data, up to limit frames,
+// where data starts with a frame header. The last frame of data is complete if
+// atEOF is set.
+func countFrames(data []byte, limit int, atEOF bool) int {
+	nframes, start := 0, 0
+	for nframes < limit {
+		f, err := frame.New(bytes.NewReader(data[start:]))
+		if err != nil {
+			break
+		}
+		next := findNextFrame(data, start+2, &f.Header, int64(f.Num))
+		if next >= len(data) {
+			if atEOF {
+				nframes++
+			}
+			break
+		}
+		nframes++
+		start = next
+	}
+	return nframes
+}
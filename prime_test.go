@@ -0,0 +1,100 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestPrime(t *testing.T) {
+	const path = "testdata/love.flac"
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rs := &readCounter{ReadSeeker: f}
+	stream, err := flac.NewSeek(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 4
+	if _, err := stream.Seek(stream.Info.NSamples / 3); err != nil {
+		t.Fatal(err)
+	}
+	got, err := stream.Prime(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != n {
+		t.Fatalf("number of primed frames mismatch; expected %d, got %d", n, got)
+	}
+	// Primed frames are parsed without reading from the underlying reader.
+	reads := rs.n
+	for i := 0; i < n; i++ {
+		if _, err := stream.ParseNext(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rs.n != reads {
+		t.Errorf("%d reads while parsing primed frames", rs.n-reads)
+	}
+
+	// Prime the remaining frames of the stream.
+	pos := stream.SamplePosition()
+	remaining, err := stream.Prime(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 0
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		want++
+	}
+	if remaining != want {
+		t.Errorf("number of primed frames at sample %d mismatch; expected %d, got %d", pos, want, remaining)
+	}
+
+	// Streams of in-memory readers are only counted.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err = flac.NewSeek(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := stream.Prime(n); err != nil || got != n {
+		t.Errorf("number of primed frames of in-memory stream mismatch; expected %d, got %d (%v)", n, got, err)
+	}
+
+	// Streams without seeking support cannot be primed.
+	stream, err = flac.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Prime(n); err == nil {
+		t.Error("expected error when priming stream without seeking support")
+	}
+}
+
+// readCounter counts the reads of the underlying io.ReadSeeker.
+type readCounter struct {
+	io.ReadSeeker
+	// Number of reads.
+	n int
+}
+
+// Read reads from the underlying io.ReadSeeker, and counts the read.
+func (r *readCounter) Read(p []byte) (int, error) {
+	r.n++
+	return r.ReadSeeker.Read(p)
+}
@@ -0,0 +1,313 @@
+package flac
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/internal/hashutil/crc16"
+	"github.com/mewkiz/flac/meta"
+)
+
+// A Decoder is a push-based FLAC decoder, which is fed the compressed FLAC
+// stream in chunks of arbitrary size as they arrive, rather than pulling data
+// from an io.Reader. The decoder never blocks waiting for input, which makes it
+// suitable for event-loop environments, such as WebAssembly in the browser,
+// where data arrives asynchronously (e.g. from fetch streams).
+//
+// Incoming data is buffered until a complete metadata block or frame is
+// available, at which point it is parsed and the frame is delivered to the
+// frame handler of the decoder. Incomplete metadata blocks and frames are not
+// parsed; as such, the cost of decoding is independent of the chunk size.
+type Decoder struct {
+	// The StreamInfo metadata block describing the basic properties of the FLAC
+	// audio stream; nil until parsed.
+	Info *meta.StreamInfo
+	// Zero or more metadata blocks.
+	Blocks []*meta.Block
+
+	// Frame handler, invoked for each decoded audio frame.
+	handle func(f *frame.Frame) error
+	// Buffered input not yet parsed.
+	buf []byte
+	// Specifies whether all metadata blocks have been parsed.
+	hasMeta bool
+	// Size in bytes of the buffered input required to complete the metadata
+	// blocks, as known so far.
+	metaNeed int
+	// Running CRC-16 checksum of the pending frame, and the number of bytes of
+	// the pending frame covered by the checksum.
+	crc  uint16
+	ncrc int
+	// Sticky error; once set, the decoder refuses further input.
+	err error
+}
+
+// NewDecoder returns a new push-based decoder, which invokes handle for each
+// audio frame decoded from the data written to the decoder. Errors returned by
+// handle are propagated to the caller of Write.
+//
+// Call Decoder.Write to feed the decoder with data, and call Decoder.Close once
+// the end of the FLAC stream has been reached.
+func NewDecoder(handle func(f *frame.Frame) error) *Decoder {
+	return &Decoder{handle: handle}
+}
+
+// Write feeds the decoder with the next chunk of the FLAC stream. The metadata
+// blocks and audio frames completed by p are parsed before Write returns, and
+// decoded frames are delivered to the frame handler of the decoder. Write
+// implements io.Writer, and consumes p in its entirety unless an error occurs.
+//
+// The decoder retains no reference to p after Write returns.
+func (dec *Decoder) Write(p []byte) (n int, err error) {
+	if dec.err != nil {
+		return 0, dec.err
+	}
+	dec.buf = append(dec.buf, p...)
+	if err := dec.decode(false); err != nil {
+		dec.err = err
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close signals the end of the FLAC stream, and decodes any remaining buffered
+// data. It returns an error if the stream ends with an incomplete metadata
+// block or frame.
+func (dec *Decoder) Close() error {
+	if dec.err != nil {
+		return dec.err
+	}
+	if err := dec.decode(true); err != nil {
+		dec.err = err
+		return err
+	}
+	dec.err = errors.New("flac.Decoder.Write: decoder closed")
+	return nil
+}
+
+// decode parses as many metadata blocks and frames as are available in the
+// buffered input. If final is set, no more input is to be expected, and short
+// data is reported as an error.
+func (dec *Decoder) decode(final bool) error {
+	r := &chunkReader{buf: dec.buf}
+	defer func() {
+		// Retain unparsed input.
+		if r.start > 0 {
+			n := copy(dec.buf, dec.buf[r.start:])
+			dec.buf = dec.buf[:n]
+		}
+	}()
+	if !dec.hasMeta {
+		if !final {
+			// Wait for the metadata blocks to be complete.
+			if len(dec.buf) < dec.metaNeed {
+				return nil
+			}
+			n, ok := metadataSize(dec.buf)
+			if !ok {
+				dec.metaNeed = n
+				return nil
+			}
+		}
+		if err := dec.parseMeta(r); err != nil {
+			if r.short && !final {
+				// Wait for more data.
+				r.pos = r.start
+				return nil
+			}
+			return err
+		}
+		r.start = r.pos
+		dec.hasMeta = true
+	}
+	for r.start < len(r.buf) {
+		if !final && !dec.frameEnd(r.buf[r.start:]) {
+			// Wait for the frame to be complete.
+			return nil
+		}
+		r.short = false
+		f, err := frame.Parse(r)
+		if err != nil {
+			if r.short && !final {
+				// The candidate end of the frame is not its end; continue the
+				// search.
+				r.pos = r.start
+				continue
+			}
+			if r.short {
+				return fmt.Errorf("flac.Decoder.Close: incomplete frame at end of stream; %v", err)
+			}
+			return err
+		}
+		r.start = r.pos
+		dec.crc, dec.ncrc = 0, 0
+		if err := dec.handle(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frameEnd reports whether the given buffered input of the pending frame holds
+// a candidate end of the frame, continuing the search of the preceding call.
+// As the CRC-16 checksum of a frame, including its CRC-16 footer, is zero, the
+// frame may only end where the running checksum of its bytes is zero. Each byte
+// of the pending frame is therefore checksummed once, rather than reparsing
+// the frame as data arrives.
+func (dec *Decoder) frameEnd(data []byte) bool {
+	for dec.ncrc < len(data) {
+		dec.crc = crc16.Update(dec.crc, crc16.IBMTable, data[dec.ncrc:dec.ncrc+1])
+		dec.ncrc++
+		if dec.crc == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataSize returns the size in bytes of the FLAC signature, the prepended
+// ID3v2 data and the metadata blocks at the start of buf, as located by the
+// lengths of their headers, and reports whether buf holds them in their
+// entirety. If not, the returned size is the number of bytes to be buffered
+// before the size is known more precisely. Invalid FLAC signatures are
+// reported as complete, to be rejected by parseMeta.
+func metadataSize(buf []byte) (int, bool) {
+	n := 4
+	if len(buf) < n {
+		return n, false
+	}
+	if bytes.Equal(buf[:3], id3Signature) {
+		if len(buf) < 10 {
+			return 10, false
+		}
+		// The size is encoded as a synchsafe integer.
+		size := int(buf[6])<<21 | int(buf[7])<<14 | int(buf[8])<<7 | int(buf[9])
+		n = 10 + size + 4
+		if len(buf) < n {
+			return n, false
+		}
+	}
+	if !bytes.Equal(buf[n-4:n], flacSignature) {
+		return n, true
+	}
+	for {
+		if len(buf) < n+4 {
+			return n + 4, false
+		}
+		isLast := buf[n]&0x80 != 0
+		length := int(buf[n+1])<<16 | int(buf[n+2])<<8 | int(buf[n+3])
+		n += 4 + length
+		if len(buf) < n {
+			return n, false
+		}
+		if isLast {
+			return n, true
+		}
+	}
+}
+
+// parseMeta parses the FLAC signature and all metadata blocks of the stream.
+func (dec *Decoder) parseMeta(r *chunkReader) error {
+	// Verify FLAC signature.
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return err
+	}
+
+	// Skip prepended ID3v2 data.
+	if bytes.Equal(buf[:3], id3Signature) {
+		var hdr [6]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return err
+		}
+		// The size is encoded as a synchsafe integer.
+		size := int(hdr[2])<<21 | int(hdr[3])<<14 | int(hdr[4])<<7 | int(hdr[5])
+		if _, err := io.CopyN(io.Discard, r, int64(size)); err != nil {
+			return err
+		}
+
+		// Second attempt at verifying signature.
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+	}
+
+	if !bytes.Equal(buf[:], flacSignature) {
+		return fmt.Errorf("flac.Decoder.parseMeta: invalid FLAC signature; expected %q, got %q", flacSignature, buf)
+	}
+
+	// Parse StreamInfo metadata block.
+	block, err := meta.Parse(r)
+	if err != nil {
+		return err
+	}
+	si, ok := block.Body.(*meta.StreamInfo)
+	if !ok {
+		return fmt.Errorf("flac.Decoder.parseMeta: incorrect type of first metadata block; expected *meta.StreamInfo, got %T", block.Body)
+	}
+
+	// Parse the remaining metadata blocks.
+	var blocks []*meta.Block
+	for !block.IsLast {
+		block, err = meta.Parse(r)
+		if err != nil {
+			if err != meta.ErrReservedType {
+				return err
+			}
+			if err = block.Skip(); err != nil {
+				return err
+			}
+		}
+		// The bodies of padding and reserved metadata blocks are consumed using
+		// io.Copy, which doesn't report short data.
+		if r.short {
+			return io.ErrUnexpectedEOF
+		}
+		blocks = append(blocks, block)
+	}
+	dec.Info = si
+	dec.Blocks = blocks
+	return nil
+}
+
+// chunkReader is an io.Reader over buffered input, which records whether a
+// read was attempted past the end of the buffered input.
+type chunkReader struct {
+	buf []byte
+	// Read position.
+	pos int
+	// Start of unparsed input.
+	start int
+	// Specifies whether a read was attempted past the end of buf.
+	short bool
+}
+
+// Read implements io.Reader.
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n = copy(p, r.buf[r.pos:])
+	r.pos += n
+	if n < len(p) {
+		r.short = true
+		if n == 0 {
+			return 0, io.EOF
+		}
+	}
+	return n, nil
+}
+
+// ReadByte implements io.ByteReader.
+func (r *chunkReader) ReadByte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		r.short = true
+		return 0, io.EOF
+	}
+	c := r.buf[r.pos]
+	r.pos++
+	return c, nil
+}
@@ -0,0 +1,108 @@
+package flac_test
+
+import (
+	"crypto/md5"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestDecoderWrite(t *testing.T) {
+	paths := []string{
+		"testdata/19875.flac",
+		"testdata/59996.flac",
+		"testdata/love.flac",
+		// Prepended ID3v2 data.
+		"id3v2+testdata/love.flac",
+	}
+	for _, path := range paths {
+		name, id3 := strings.CutPrefix(path, "id3v2+")
+		buf, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id3 {
+			// ID3v2 header and 10 bytes of tag data.
+			hdr := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 10}
+			buf = append(append(hdr, make([]byte, 10)...), buf...)
+		}
+		for _, chunkSize := range []int{1, 1000, 4096, len(buf)} {
+			md5sum := md5.New()
+			dec := flac.NewDecoder(func(f *frame.Frame) error {
+				f.Hash(md5sum)
+				return nil
+			})
+			for p := buf; len(p) > 0; {
+				n := chunkSize
+				if n > len(p) {
+					n = len(p)
+				}
+				if _, err := dec.Write(p[:n]); err != nil {
+					t.Fatalf("%s (chunk size %d): %v", path, chunkSize, err)
+				}
+				p = p[n:]
+			}
+			if err := dec.Close(); err != nil {
+				t.Fatalf("%s (chunk size %d): %v", path, chunkSize, err)
+			}
+			if dec.Info == nil {
+				t.Fatalf("%s (chunk size %d): StreamInfo not parsed", path, chunkSize)
+			}
+			var got [md5.Size]byte
+			copy(got[:], md5sum.Sum(nil))
+			if got != dec.Info.MD5sum {
+				t.Errorf("%s (chunk size %d): MD5 checksum mismatch; expected %x, got %x", path, chunkSize, dec.Info.MD5sum, got)
+			}
+		}
+	}
+}
+
+func TestDecoderWriteFrames(t *testing.T) {
+	buf, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := frameOffsets(t, buf)
+	// Frames are delivered once complete, and not before.
+	var nframes int
+	dec := flac.NewDecoder(func(f *frame.Frame) error {
+		nframes++
+		return nil
+	})
+	pos := 0
+	for i, end := range append(offsets[1:], int64(len(buf))) {
+		if _, err := dec.Write(buf[pos : end-1]); err != nil {
+			t.Fatal(err)
+		}
+		if nframes != i {
+			t.Fatalf("number of frames mismatch before end of frame %d; expected %d, got %d", i, i, nframes)
+		}
+		if _, err := dec.Write(buf[end-1 : end]); err != nil {
+			t.Fatal(err)
+		}
+		if nframes != i+1 {
+			t.Fatalf("number of frames mismatch at end of frame %d; expected %d, got %d", i, i+1, nframes)
+		}
+		pos = int(end)
+	}
+	if err := dec.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	buf, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := flac.NewDecoder(func(f *frame.Frame) error { return nil })
+	if _, err := dec.Write(buf[:len(buf)-10]); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Close(); err == nil {
+		t.Fatal("expected error for truncated stream")
+	}
+}
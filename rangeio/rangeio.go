@@ -0,0 +1,256 @@
+// Package rangeio implements random access to remote FLAC objects through
+// ranged reads; e.g. the ranged GET requests of cloud object storage such as
+// S3 or GCS.
+//
+// A ReaderAt implements io.ReaderAt on top of a ReadRangeFunc, reading the
+// object in chunks which are cached for subsequent reads. Reads of adjacent
+// uncached chunks are coalesced into a single ranged read, and concurrent reads
+// of the same chunk share a single ranged read. The chunks holding the stream
+// header (the FLAC signature and metadata blocks) remain cached once read by
+// NewStream, so that opening the same object again requires no ranged reads
+// of the stream header.
+//
+//	r := rangeio.NewReaderAt(func(off, n int64) ([]byte, error) {
+//		return getObjectRange(bucket, key, off, n)
+//	}, objectSize, nil)
+//	stream, err := r.NewStream()
+//	_, err = stream.Seek(sampleNum)
+package rangeio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/mewkiz/flac"
+)
+
+// A ReadRangeFunc reads n bytes of a remote object, starting at byte offset
+// off; e.g. using a ranged GET request with the header "Range: bytes=off-end",
+// where end is off+n-1. Fewer than n bytes may only be returned at the end of
+// the object.
+type ReadRangeFunc func(off, n int64) ([]byte, error)
+
+// Options specifies the chunking and caching of a ReaderAt. The zero value
+// specifies the default options.
+type Options struct {
+	// Size in bytes of chunks; 256 KiB by default. Larger chunks reduce the
+	// number of ranged reads, at the cost of reading more data than needed for
+	// random access.
+	ChunkSize int
+	// Maximum number of cached chunks, excluding the chunks holding the stream
+	// header; 16 by default.
+	CacheSize int
+}
+
+const (
+	// defaultChunkSize specifies the default size in bytes of chunks.
+	defaultChunkSize = 256 * 1024
+	// defaultCacheSize specifies the default number of cached chunks.
+	defaultCacheSize = 16
+)
+
+// A ReaderAt reads a remote object through ranged reads, caching the chunks
+// read. A ReaderAt is safe for concurrent use by multiple goroutines.
+type ReaderAt struct {
+	// Ranged read of the remote object.
+	readRange ReadRangeFunc
+	// Size in bytes of the remote object.
+	size int64
+	// Size in bytes of chunks.
+	chunkSize int64
+	// Maximum number of cached chunks, excluding pinned chunks.
+	cacheSize int
+
+	// mu protects the fields below.
+	mu sync.Mutex
+	// Cached and pending chunks, by chunk index.
+	chunks map[int64]*chunk
+	// Chunks starting before pinEnd hold the stream header, and are never
+	// evicted.
+	pinEnd int64
+	// Use counter, recording the order of chunk accesses.
+	tick uint64
+}
+
+// chunk is a chunk of the remote object.
+type chunk struct {
+	// Contents of the chunk, and error of the ranged read; valid once done is
+	// closed.
+	data []byte
+	err  error
+	// Closed when the ranged read of the chunk has completed.
+	done chan struct{}
+	// Tick of the last access to the chunk.
+	used uint64
+}
+
+// NewReaderAt returns a new ReaderAt which reads the remote object of the given
+// size in bytes using readRange, with the given options; a nil value specifies
+// the default options.
+func NewReaderAt(readRange ReadRangeFunc, size int64, opts *Options) *ReaderAt {
+	if opts == nil {
+		opts = &Options{}
+	}
+	r := &ReaderAt{
+		readRange: readRange,
+		size:      size,
+		chunkSize: defaultChunkSize,
+		cacheSize: defaultCacheSize,
+		chunks:    make(map[int64]*chunk),
+	}
+	if opts.ChunkSize > 0 {
+		r.chunkSize = int64(opts.ChunkSize)
+	}
+	if opts.CacheSize > 0 {
+		r.cacheSize = opts.CacheSize
+	}
+	return r
+}
+
+// Size returns the size in bytes of the remote object.
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+// NewStream returns a Stream of the remote object with seeking enabled (see
+// flac.NewSeekReaderAt), and retains the chunks holding its stream header in
+// the cache.
+func (r *ReaderAt) NewStream() (*flac.Stream, error) {
+	stream, err := flac.NewSeekReaderAt(r, r.size)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.pinEnd = max(r.pinEnd, stream.DataStart())
+	r.mu.Unlock()
+	return stream, nil
+}
+
+// ReadAt reads len(p) bytes of the remote object into p, starting at byte
+// offset off, as specified by io.ReaderAt.
+func (r *ReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("rangeio.ReaderAt.ReadAt: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	end := min(off+int64(len(p)), r.size)
+	if end == off {
+		return 0, nil
+	}
+	firstIndex, lastIndex := off/r.chunkSize, (end-1)/r.chunkSize
+	chunks, runs := r.acquire(firstIndex, lastIndex)
+	for _, run := range runs {
+		r.load(firstIndex+int64(run.start), chunks[run.start:run.end])
+	}
+	for i, c := range chunks {
+		<-c.done
+		if c.err != nil {
+			return n, c.err
+		}
+		start := (firstIndex + int64(i)) * r.chunkSize
+		lo := max(off, start) - start
+		hi := min(end, start+int64(len(c.data))) - start
+		if lo >= hi {
+			return n, fmt.Errorf("rangeio.ReaderAt.ReadAt: short ranged read of chunk at offset %d", start)
+		}
+		n += copy(p[n:], c.data[lo:hi])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// run is a run of consecutive chunks, by index into a slice of chunks.
+type run struct {
+	start, end int
+}
+
+// acquire returns the chunks of the given range of chunk indices, and the runs
+// of chunks to be read by the caller. Chunks which are pending for other
+// callers are shared, coalescing concurrent reads of the same chunk.
+func (r *ReaderAt) acquire(firstIndex, lastIndex int64) ([]*chunk, []run) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	chunks := make([]*chunk, 0, lastIndex-firstIndex+1)
+	var runs []run
+	for index := firstIndex; index <= lastIndex; index++ {
+		r.tick++
+		c, ok := r.chunks[index]
+		if !ok {
+			c = &chunk{done: make(chan struct{})}
+			r.chunks[index] = c
+			i := len(chunks)
+			if len(runs) > 0 && runs[len(runs)-1].end == i {
+				runs[len(runs)-1].end++
+			} else {
+				runs = append(runs, run{start: i, end: i + 1})
+			}
+		}
+		c.used = r.tick
+		chunks = append(chunks, c)
+	}
+	r.evict()
+	return chunks, runs
+}
+
+// load reads the given consecutive chunks, starting at the given chunk index,
+// using a single ranged read.
+func (r *ReaderAt) load(index int64, chunks []*chunk) {
+	off := index * r.chunkSize
+	n := min(int64(len(chunks))*r.chunkSize, r.size-off)
+	data, err := r.readRange(off, n)
+	if err == nil && int64(len(data)) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		err = fmt.Errorf("rangeio.ReaderAt.ReadAt: unable to read %d bytes at offset %d; %w", n, off, err)
+	}
+	r.mu.Lock()
+	for i, c := range chunks {
+		if err != nil {
+			c.err = err
+			// Remove failed chunks from the cache, for subsequent reads to retry.
+			if r.chunks[index+int64(i)] == c {
+				delete(r.chunks, index+int64(i))
+			}
+		} else {
+			start := int64(i) * r.chunkSize
+			c.data = data[start:min(start+r.chunkSize, n)]
+		}
+		close(c.done)
+	}
+	r.mu.Unlock()
+}
+
+// evict evicts the least recently used chunks exceeding the cache size,
+// excluding the chunks holding the stream header. Pending chunks remain
+// available to the callers waiting for them.
+//
+// The caller must hold r.mu.
+func (r *ReaderAt) evict() {
+	for {
+		var (
+			lru       int64 = -1
+			lruTick   uint64
+			nunpinned int
+		)
+		for index, c := range r.chunks {
+			if index*r.chunkSize < r.pinEnd {
+				continue
+			}
+			nunpinned++
+			if lru == -1 || c.used < lruTick {
+				lru, lruTick = index, c.used
+			}
+		}
+		if nunpinned <= r.cacheSize {
+			return
+		}
+		delete(r.chunks, lru)
+	}
+}
@@ -0,0 +1,191 @@
+package rangeio_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/rangeio"
+)
+
+// object is a remote object, which records its ranged reads.
+type object struct {
+	data []byte
+	// Error returned by ranged reads; nil for none.
+	err error
+
+	mu sync.Mutex
+	// Byte offsets of ranged reads.
+	reads []int64
+}
+
+// readRange reads n bytes of the object at offset off.
+func (o *object) readRange(off, n int64) ([]byte, error) {
+	o.mu.Lock()
+	o.reads = append(o.reads, off)
+	err := o.err
+	o.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(o.data[off:min(off+n, int64(len(o.data)))]), nil
+}
+
+// nreads returns the number of ranged reads.
+func (o *object) nreads() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.reads)
+}
+
+func TestReaderAt(t *testing.T) {
+	data, err := os.ReadFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &object{data: data}
+	r := rangeio.NewReaderAt(o.readRange, int64(len(data)), &rangeio.Options{ChunkSize: 1000, CacheSize: 4})
+	if err := iotest.TestReader(io.NewSectionReader(r, 0, r.Size()), data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reads spanning several uncached chunks are coalesced.
+	o.reads = nil
+	r = rangeio.NewReaderAt(o.readRange, int64(len(data)), &rangeio.Options{ChunkSize: 1000, CacheSize: 4})
+	buf := make([]byte, 2500)
+	if _, err := r.ReadAt(buf, 10500); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data[10500:13000]) {
+		t.Error("contents of read spanning several chunks mismatch")
+	}
+	if want := []int64{10000}; !slices.Equal(o.reads, want) {
+		t.Errorf("ranged reads mismatch; expected %v, got %v", want, o.reads)
+	}
+	// Reads of cached chunks require no ranged reads, and only uncached chunks
+	// are read.
+	if _, err := r.ReadAt(buf, 11000); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{10000, 13000}; !slices.Equal(o.reads, want) {
+		t.Errorf("ranged reads mismatch; expected %v, got %v", want, o.reads)
+	}
+
+	// Reads at the end of the object.
+	n, err := r.ReadAt(buf, int64(len(data))-100)
+	if n != 100 || err != io.EOF {
+		t.Errorf("read at end of object mismatch; expected 100 bytes and io.EOF, got %d bytes and %v", n, err)
+	}
+	if _, err := r.ReadAt(buf, int64(len(data))); err != io.EOF {
+		t.Errorf("read past end of object mismatch; expected io.EOF, got %v", err)
+	}
+}
+
+func TestReaderAtError(t *testing.T) {
+	errRange := errors.New("ranged read failed")
+	o := &object{data: make([]byte, 5000), err: errRange}
+	r := rangeio.NewReaderAt(o.readRange, 5000, &rangeio.Options{ChunkSize: 1000})
+	buf := make([]byte, 10)
+	if _, err := r.ReadAt(buf, 1500); !errors.Is(err, errRange) {
+		t.Fatalf("error mismatch; expected %v, got %v", errRange, err)
+	}
+	// Failed chunks are read again.
+	o.err = nil
+	if _, err := r.ReadAt(buf, 1500); err != nil {
+		t.Fatal(err)
+	}
+	if n := o.nreads(); n != 2 {
+		t.Errorf("number of ranged reads mismatch; expected 2, got %d", n)
+	}
+}
+
+func TestReaderAtConcurrent(t *testing.T) {
+	o := &object{data: make([]byte, 5000)}
+	release := make(chan struct{})
+	readRange := func(off, n int64) ([]byte, error) {
+		<-release
+		return o.readRange(off, n)
+	}
+	r := rangeio.NewReaderAt(readRange, 5000, &rangeio.Options{ChunkSize: 1000})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 100)
+			if _, err := r.ReadAt(buf, 2000+int64(i)*100); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	close(release)
+	wg.Wait()
+	// Concurrent reads of the same chunk share a ranged read; the chunk is read
+	// again only if evicted, which requires more chunks than the cache holds.
+	if n := o.nreads(); n != 1 {
+		t.Errorf("number of ranged reads mismatch; expected 1, got %d", n)
+	}
+}
+
+func TestNewStream(t *testing.T) {
+	const path = "../testdata/172960.flac"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.Close()
+	o := &object{data: data}
+	// Small chunks and cache, for the stream header to span several chunks and
+	// the audio frames to be evicted.
+	r := rangeio.NewReaderAt(o.readRange, int64(len(data)), &rangeio.Options{ChunkSize: 4096, CacheSize: 2})
+	stream, err := r.NewStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantFrame, err := want.ParseNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for channel, subframe := range frame.Subframes {
+			if !slices.Equal(subframe.Samples, wantFrame.Subframes[channel].Samples) {
+				t.Fatalf("samples of channel %d of frame %d mismatch", channel, frame.Num)
+			}
+		}
+	}
+	if _, err := stream.Seek(20000); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening the object again reads no chunks of the stream header.
+	o.mu.Lock()
+	o.reads = nil
+	o.mu.Unlock()
+	stream, err = r.NewStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataStart := stream.DataStart()
+	for _, off := range o.reads {
+		if off < dataStart {
+			t.Errorf("ranged read of stream header at offset %d", off)
+		}
+	}
+}
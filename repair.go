@@ -0,0 +1,277 @@
+package flac
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/internal/hashutil/crc16"
+)
+
+// RepairOptions specifies the options of Repair. The zero value specifies the
+// default options.
+type RepairOptions struct {
+	// FixBitFlips enables single-bit-flip correction of frames failing their
+	// CRC checksums. If unset, damaged frames are only reported.
+	FixBitFlips bool
+	// MaxSearchSize specifies the maximum size in bytes of damaged frames
+	// searched for bit flips; larger frames are reported as irrecoverable. A 0
+	// value implies a limit of 1 MiB.
+	MaxSearchSize int
+}
+
+// defaultMaxSearchSize specifies the maximum size in bytes of damaged frames
+// searched for bit flips, if unspecified by RepairOptions.
+const defaultMaxSearchSize = 1 << 20
+
+// A RepairReport reports the damaged frames of a FLAC stream, as located by
+// Repair.
+type RepairReport struct {
+	// Number of frames of the stream, including damaged frames.
+	Frames int
+	// Frames corrected by single-bit-flip correction.
+	Corrected []FrameRepair
+	// Damaged frames which were not corrected.
+	Irrecoverable []FrameRepair
+}
+
+// A FrameRepair describes a damaged frame of a FLAC stream.
+type FrameRepair struct {
+	// Index of the frame within the stream, starting at 0.
+	Index int
+	// Byte offset of the frame, relative to the start of the stream.
+	Offset int64
+	// Size of the frame in bytes, up to the next frame.
+	Size int
+	// Bit offset of the corrected bit flip, relative to the start of the frame
+	// (bit 0 is the most significant bit of the first byte); or -1 if the frame
+	// was not corrected.
+	Bit int64
+	// Parse error of the damaged frame.
+	Err error
+}
+
+// Repair reads the FLAC stream of r in its entirety, locates frames failing
+// their CRC checksums, optionally corrects single-bit flips within them, and
+// writes the repaired stream to w. The metadata blocks must be intact. Damaged
+// frames which are not corrected are written as is.
+//
+// A bit flip is corrected only if exactly one bit position yields a frame
+// passing both the CRC-8 checksum of the frame header and the CRC-16 checksum
+// of the frame. As the CRC-16 checksum is linear, candidate bit positions are
+// located without decoding the frame once per bit. The CRC-16 polynomial has a
+// period of 32767 bits, so bit flips within frames larger than 4 KiB may be
+// ambiguous, in which case the frame is reported as irrecoverable.
+func Repair(w io.Writer, r io.Reader, opts *RepairOptions) (*RepairReport, error) {
+	if opts == nil {
+		opts = &RepairOptions{}
+	}
+	maxSearchSize := opts.MaxSearchSize
+	if maxSearchSize == 0 {
+		maxSearchSize = defaultMaxSearchSize
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := New(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	hint := &frame.Header{SampleRate: stream.Info.SampleRate, BitsPerSample: stream.Info.BitsPerSample}
+	report := &RepairReport{}
+	offset := int(stream.DataStart())
+	// Frame number, or sample number, expected of the next frame; or -1 if
+	// unknown.
+	next := int64(0)
+	for offset < len(data) {
+		if isID3v1(data[offset:]) {
+			// Trailing ID3v1 tag.
+			break
+		}
+		index := report.Frames
+		report.Frames++
+		f, n, err := parseFrameAt(data[offset:], hint)
+		if err == nil {
+			next = nextFrameNum(f)
+			offset += n
+			continue
+		}
+
+		// Locate the end of the damaged frame at the next valid frame header.
+		var hdr *frame.Header
+		if g, err := frame.New(bytes.NewReader(data[offset:])); err == nil {
+			hdr = &g.Header
+			next = int64(g.Num)
+		}
+		end := findNextFrame(data, offset+1, hdr, next)
+		repair := FrameRepair{Index: index, Offset: int64(offset), Size: end - offset, Bit: -1, Err: err}
+		if opts.FixBitFlips && repair.Size <= maxSearchSize {
+			if bit, ok := fixBitFlip(data[offset:end], hint); ok {
+				repair.Bit = bit
+				report.Corrected = append(report.Corrected, repair)
+				if g, _, err := parseFrameAt(data[offset:end], hint); err == nil {
+					next = nextFrameNum(g)
+				}
+				offset = end
+				continue
+			}
+		}
+		report.Irrecoverable = append(report.Irrecoverable, repair)
+		if hdr != nil && hdr.HasFixedBlockSize {
+			next++
+		} else {
+			next = -1
+		}
+		offset = end
+	}
+	if _, err := w.Write(data); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// RepairFile repairs the FLAC file at src, and writes the repaired FLAC file to
+// dst. See Repair for details.
+func RepairFile(dst, src string, opts *RepairOptions) (*RepairReport, error) {
+	r, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	buf := &bytes.Buffer{}
+	report, err := Repair(buf, r, opts)
+	if err != nil {
+		return report, err
+	}
+	if err := os.WriteFile(dst, buf.Bytes(), 0644); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// parseFrameAt parses the frame at the start of data, and returns the frame and
+// its size in bytes.
+func parseFrameAt(data []byte, hint *frame.Header) (*frame.Frame, int, error) {
+	r := bytes.NewReader(data)
+	f, err := frame.New(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if f.SampleRate == 0 {
+		f.SampleRate = hint.SampleRate
+	}
+	if f.BitsPerSample == 0 {
+		f.BitsPerSample = hint.BitsPerSample
+	}
+	if err := f.Parse(); err != nil {
+		return nil, 0, err
+	}
+	return f, len(data) - r.Len(), nil
+}
+
+// nextFrameNum returns the frame number (or sample number of variable-blocksize
+// streams) of the frame following f.
+func nextFrameNum(f *frame.Frame) int64 {
+	if f.HasFixedBlockSize {
+		return int64(f.Num) + 1
+	}
+	return int64(f.Num) + int64(f.BlockSize)
+}
+
+// findNextFrame returns the offset of the first valid frame header in data at
+// or after start, which succeeds a damaged frame with the given header (nil if
+// unknown) and frame number (or sample number; -1 if unknown). It returns
+// len(data) if no such frame header is found.
+func findNextFrame(data []byte, start int, hdr *frame.Header, num int64) int {
+	for i := start; ; i++ {
+		var g frame.Header
+		i, g = frame.FindHeader(data, i)
+		if i == -1 {
+			break
+		}
+		switch {
+		case num < 0:
+			// Any valid frame header.
+		case g.HasFixedBlockSize:
+			if int64(g.Num) != num+1 {
+				continue
+			}
+		default:
+			// The sample number of the next frame follows the samples of the
+			// damaged frame.
+			if int64(g.Num) <= num || hdr != nil && int64(g.Num) != num+int64(hdr.BlockSize) {
+				continue
+			}
+		}
+		return i
+	}
+	if isID3v1(data[len(data)-min(len(data), id3v1Size):]) {
+		return len(data) - id3v1Size
+	}
+	return len(data)
+}
+
+// fixBitFlip corrects a single bit flip within the given damaged frame in
+// place, and returns the bit offset of the corrected bit. The boolean return
+// value reports whether exactly one bit position yields a valid frame.
+func fixBitFlip(data []byte, hint *frame.Header) (int64, bool) {
+	if len(data) < 3 {
+		return -1, false
+	}
+	// The CRC-16 checksum (polynomial x^16 + x^15 + x^2 + x^0, initialized with
+	// 0) is linear; flipping bit i of the frame changes the checksum by the
+	// checksum of a message with only bit i set. The checksum of bit i is
+	// x^(16+k) mod P, where k is the number of bits following bit i.
+	n := len(data) - 2
+	stored := uint16(data[n])<<8 | uint16(data[n+1])
+	syndrome := crc16.ChecksumIBM(data[:n]) ^ stored
+	var candidates []int64
+	// Bit flips within the CRC-16 checksum itself.
+	for k := 0; k < 16; k++ {
+		if syndrome == 1<<k {
+			candidates = append(candidates, int64(8*len(data)-1-k))
+		}
+	}
+	v := uint16(crc16.IBM) // x^16 mod P
+	for i := int64(8*n - 1); i >= 0; i-- {
+		if v == syndrome {
+			candidates = append(candidates, i)
+		}
+		// Multiply by x.
+		if v&0x8000 != 0 {
+			v = v<<1 ^ crc16.IBM
+		} else {
+			v <<= 1
+		}
+	}
+	// Validate candidates by decoding the corrected frame.
+	var fixed []int64
+	for _, bit := range candidates {
+		flipBit(data, bit)
+		if _, err := frame.ParseBytes(data, hint); err == nil {
+			fixed = append(fixed, bit)
+		}
+		flipBit(data, bit)
+	}
+	if len(fixed) != 1 {
+		return -1, false
+	}
+	flipBit(data, fixed[0])
+	return fixed[0], true
+}
+
+// flipBit flips the given bit of data, where bit 0 is the most significant bit
+// of the first byte.
+func flipBit(data []byte, bit int64) {
+	data[bit/8] ^= 0x80 >> uint(bit%8)
+}
+
+// id3v1Size specifies the size in bytes of an ID3v1 tag.
+const id3v1Size = 128
+
+// isID3v1 reports whether data holds an ID3v1 tag.
+func isID3v1(data []byte) bool {
+	return len(data) == id3v1Size && string(data[:3]) == "TAG"
+}
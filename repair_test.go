@@ -0,0 +1,121 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+// frameOffsets returns the byte offsets of the frames of the given FLAC stream.
+func frameOffsets(t *testing.T, data []byte) []int64 {
+	stream, err := flac.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int64
+	for {
+		offset := stream.Offset()
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+func TestRepair(t *testing.T) {
+	golden := []struct {
+		path string
+		// Frame index and bit offsets within the frame of bit flips.
+		index int
+		bits  []int64
+		// Expected corrected bit; or -1 if irrecoverable.
+		want int64
+	}{
+		{path: "testdata/love.flac", index: 3, bits: []int64{8 * 1000}, want: 8 * 1000},
+		{path: "testdata/love.flac", index: 5, bits: []int64{3}, want: 3},             // sync code
+		{path: "testdata/love.flac", index: 5, bits: []int64{8*4 + 1}, want: 8*4 + 1}, // frame header
+		{path: "testdata/59996.flac", index: 0, bits: []int64{8*100 + 2}, want: -1},   // ambiguous; frame exceeds 4 KiB
+		{path: "testdata/172960.flac", index: 2, bits: []int64{8*200 + 5}, want: -1},  // ambiguous; frame exceeds 4 KiB
+		{path: "testdata/love.flac", index: 7, bits: []int64{8 * 1000, 8 * 2000}, want: -1},
+	}
+	for _, g := range golden {
+		orig, err := os.ReadFile(g.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets := frameOffsets(t, orig)
+		damaged := append([]byte(nil), orig...)
+		for _, bit := range g.bits {
+			pos := offsets[g.index]*8 + bit
+			damaged[pos/8] ^= 0x80 >> uint(pos%8)
+		}
+
+		// Report damaged frames without correction.
+		buf := &bytes.Buffer{}
+		report, err := flac.Repair(buf, bytes.NewReader(damaged), nil)
+		if err != nil {
+			t.Fatalf("%q: unable to repair FLAC stream; %v", g.path, err)
+		}
+		if report.Frames != len(offsets) {
+			t.Errorf("%q: frame count mismatch; expected %d, got %d", g.path, len(offsets), report.Frames)
+		}
+		if len(report.Corrected) != 0 || len(report.Irrecoverable) != 1 {
+			t.Fatalf("%q: expected 1 damaged frame, got %d corrected and %d irrecoverable", g.path, len(report.Corrected), len(report.Irrecoverable))
+		}
+		if got := report.Irrecoverable[0]; got.Index != g.index || got.Offset != offsets[g.index] {
+			t.Errorf("%q: damaged frame mismatch; expected frame %d at offset %d, got frame %d at offset %d", g.path, g.index, offsets[g.index], got.Index, got.Offset)
+		}
+		if !bytes.Equal(buf.Bytes(), damaged) {
+			t.Errorf("%q: damaged stream modified without bit flip correction", g.path)
+		}
+
+		// Correct bit flips.
+		buf.Reset()
+		report, err = flac.Repair(buf, bytes.NewReader(damaged), &flac.RepairOptions{FixBitFlips: true})
+		if err != nil {
+			t.Fatalf("%q: unable to repair FLAC stream; %v", g.path, err)
+		}
+		if g.want == -1 {
+			if len(report.Corrected) != 0 || len(report.Irrecoverable) != 1 {
+				t.Errorf("%q: expected 1 irrecoverable frame, got %d corrected and %d irrecoverable", g.path, len(report.Corrected), len(report.Irrecoverable))
+			}
+			continue
+		}
+		if len(report.Corrected) != 1 || len(report.Irrecoverable) != 0 {
+			t.Fatalf("%q: expected 1 corrected frame, got %d corrected and %d irrecoverable", g.path, len(report.Corrected), len(report.Irrecoverable))
+		}
+		if got := report.Corrected[0]; got.Index != g.index || got.Bit != g.want {
+			t.Errorf("%q: corrected frame mismatch; expected bit %d of frame %d, got bit %d of frame %d", g.path, g.want, g.index, got.Bit, got.Index)
+		}
+		if !bytes.Equal(buf.Bytes(), orig) {
+			t.Errorf("%q: content mismatch of repaired stream", g.path)
+		}
+	}
+}
+
+func TestRepairIntact(t *testing.T) {
+	for _, path := range []string{"testdata/59996.flac", "testdata/172960.flac", "testdata/love.flac"} {
+		orig, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		report, err := flac.Repair(buf, bytes.NewReader(orig), &flac.RepairOptions{FixBitFlips: true})
+		if err != nil {
+			t.Fatalf("%q: unable to repair FLAC stream; %v", path, err)
+		}
+		if len(report.Corrected) != 0 || len(report.Irrecoverable) != 0 {
+			t.Errorf("%q: expected no damaged frames, got %d corrected and %d irrecoverable", path, len(report.Corrected), len(report.Irrecoverable))
+		}
+		if !bytes.Equal(buf.Bytes(), orig) {
+			t.Errorf("%q: content mismatch of intact stream", path)
+		}
+	}
+}
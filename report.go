@@ -0,0 +1,145 @@
+package flac
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// A StreamReport is a structured summary of a FLAC stream, intended for media
+// indexers. It is JSON-serializable.
+type StreamReport struct {
+	// Container format; "flac" for native FLAC streams.
+	Container string `json:"container"`
+	// Specifies whether ID3v2 data is prepended to the FLAC stream.
+	ID3v2 bool `json:"id3v2,omitempty"`
+	// Size of the stream in bytes.
+	Size int64 `json:"size"`
+	// Size of the audio frames in bytes.
+	AudioSize int64 `json:"audio_size"`
+	// Duration of the stream; or 0 if the total number of samples is unknown.
+	Duration time.Duration `json:"duration"`
+	// Sample rate in Hz.
+	SampleRate uint32 `json:"sample_rate"`
+	// Number of channels.
+	Channels uint8 `json:"channels"`
+	// Sample size in bits-per-sample.
+	BitsPerSample uint8 `json:"bits_per_sample"`
+	// Total number of samples per channel; or 0 if unknown.
+	NSamples uint64 `json:"samples"`
+	// Average bitrate of the audio frames in bits per second; or 0 if the
+	// duration is unknown.
+	Bitrate int64 `json:"bitrate"`
+	// MD5 checksum of the unencoded audio samples, in hexadecimal; empty if
+	// unset.
+	MD5 string `json:"md5,omitempty"`
+	// Vendor string of the VorbisComment metadata block; empty if not present.
+	Vendor string `json:"vendor,omitempty"`
+	// Tags of the VorbisComment metadata block, as (name, value) pairs in order
+	// of appearance.
+	Tags [][2]string `json:"tags,omitempty"`
+	// Number of embedded pictures.
+	Pictures int `json:"pictures"`
+	// Total size of the image data of embedded pictures in bytes.
+	PicturesSize int64 `json:"pictures_size"`
+	// Specifies whether the stream contains a SeekTable metadata block.
+	HasSeekTable bool `json:"has_seek_table"`
+	// Number of seek points of the SeekTable metadata block, excluding
+	// placeholder points.
+	SeekPoints int `json:"seek_points,omitempty"`
+	// Number of metadata blocks, including StreamInfo.
+	MetadataBlocks int `json:"metadata_blocks"`
+}
+
+// Report returns a structured summary of the FLAC stream of r. The metadata
+// blocks are parsed, while the audio frames are not decoded. If r implements
+// io.Seeker, the size of the stream is determined by seeking to the end of r;
+// otherwise, the audio frames are read in their entirety to determine the size
+// of the stream.
+func Report(r io.Reader) (*StreamReport, error) {
+	// Start offset of the stream in r, if r implements io.Seeker.
+	var start int64
+	rs, seekable := r.(io.Seeker)
+	if seekable {
+		var err error
+		if start, err = rs.Seek(0, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	br := bufio.NewReader(r)
+	sig, _ := br.Peek(len(id3Signature))
+	stream, err := Parse(br)
+	if err != nil {
+		return nil, err
+	}
+	// Determine the size of the stream.
+	var size int64
+	if seekable {
+		end, err := rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		size = end - start
+	} else {
+		// Skip audio frames.
+		if _, err := io.Copy(io.Discard, stream.r); err != nil {
+			return nil, err
+		}
+		size = stream.Offset()
+	}
+
+	info := stream.Info
+	report := &StreamReport{
+		Container:      "flac",
+		ID3v2:          bytes.Equal(sig, id3Signature),
+		Size:           size,
+		AudioSize:      size - stream.DataStart(),
+		SampleRate:     info.SampleRate,
+		Channels:       info.NChannels,
+		BitsPerSample:  info.BitsPerSample,
+		NSamples:       info.NSamples,
+		MetadataBlocks: 1 + len(stream.Blocks),
+	}
+	if info.SampleRate != 0 && info.NSamples != 0 {
+		report.Duration = time.Duration(float64(info.NSamples) / float64(info.SampleRate) * float64(time.Second))
+		report.Bitrate = int64(float64(report.AudioSize*8) * float64(info.SampleRate) / float64(info.NSamples))
+	}
+	var zero [16]uint8
+	if info.MD5sum != zero {
+		report.MD5 = hex.EncodeToString(info.MD5sum[:])
+	}
+	for _, block := range stream.Blocks {
+		switch body := block.Body.(type) {
+		case *meta.VorbisComment:
+			report.Vendor = body.Vendor
+			report.Tags = append(report.Tags, body.Tags...)
+		case *meta.Picture:
+			report.Pictures++
+			report.PicturesSize += int64(len(body.Data))
+		case *meta.SeekTable:
+			report.HasSeekTable = true
+			for _, point := range body.Points {
+				if point.SampleNum != meta.PlaceholderPoint {
+					report.SeekPoints++
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// ReportFile returns a structured summary of the FLAC file at path. See Report
+// for details.
+func ReportFile(path string) (*StreamReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Report(f)
+}
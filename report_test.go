@@ -0,0 +1,71 @@
+package flac_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestReport(t *testing.T) {
+	golden := []struct {
+		path  string
+		id3v2 bool
+	}{
+		{path: "testdata/172960.flac"},
+		{path: "testdata/id3.flac", id3v2: true},
+		{path: "testdata/love.flac"},
+	}
+	for _, g := range golden {
+		t.Run(g.path, func(t *testing.T) {
+			report, err := flac.ReportFile(g.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fi, err := os.Stat(g.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream, err := flac.ParseFile(g.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+			if report.Size != fi.Size() {
+				t.Errorf("size mismatch; expected %d, got %d", fi.Size(), report.Size)
+			}
+			if report.ID3v2 != g.id3v2 {
+				t.Errorf("ID3v2 mismatch; expected %v, got %v", g.id3v2, report.ID3v2)
+			}
+			if report.NSamples != stream.Info.NSamples || report.SampleRate != stream.Info.SampleRate {
+				t.Errorf("stream properties mismatch; got %+v", report)
+			}
+			if report.Bitrate <= 0 || report.Duration <= 0 {
+				t.Errorf("invalid bitrate (%d) or duration (%v)", report.Bitrate, report.Duration)
+			}
+			if report.MetadataBlocks != 1+len(stream.Blocks) {
+				t.Errorf("metadata block count mismatch; expected %d, got %d", 1+len(stream.Blocks), report.MetadataBlocks)
+			}
+			if _, err := json.Marshal(report); err != nil {
+				t.Errorf("unable to marshal report; %v", err)
+			}
+
+			// Streams which are not seekable are read in their entirety.
+			data, err := os.ReadFile(g.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := flac.Report(struct{ io.Reader }{bytes.NewReader(data)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, report) {
+				t.Errorf("report mismatch of non-seekable stream; expected %+v, got %+v", report, got)
+			}
+		})
+	}
+}
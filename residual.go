@@ -0,0 +1,234 @@
+package flac
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// ResidualFormat specifies the file format of exported residual signals.
+type ResidualFormat uint8
+
+// Residual formats.
+const (
+	// ResidualCSV specifies comma-separated values, with one record per
+	// residual; the frame number, channel, sample number and residual:
+	//
+	//	frame,channel,sample,residual
+	//	0,0,2,-7
+	//
+	// Warm-up samples, and the samples of constant and verbatim subframes, have
+	// no residuals and are omitted.
+	ResidualCSV ResidualFormat = iota + 1
+	// ResidualWAV specifies a WAVE file of 32-bit signed PCM, with one channel
+	// per subframe and the sample rate of the stream. Warm-up samples, and the
+	// samples of constant and verbatim subframes, are stored as 0.
+	ResidualWAV
+)
+
+// String returns the string representation of the residual format.
+func (format ResidualFormat) String() string {
+	switch format {
+	case ResidualCSV:
+		return "csv"
+	case ResidualWAV:
+		return "wav"
+	}
+	return fmt.Sprintf("ResidualFormat(%d)", uint8(format))
+}
+
+// ExportResiduals parses the FLAC stream of r in its entirety, and writes the
+// residual signals (signal errors of the prediction) of each fixed and FIR
+// subframe to w, in the given format; e.g. for codec research on predictor
+// performance. The residuals are those stored in the stream; i.e. of the audio
+// samples with wasted bits-per-sample shifted out, and of the side channel for
+// inter-channel decorrelated frames.
+//
+// The ResidualWAV format requires the total number of samples of the stream to
+// be specified by StreamInfo.
+func ExportResiduals(w io.Writer, r io.Reader, format ResidualFormat) error {
+	stream, err := NewWithOptions(r, &DecodeOptions{KeepIntermediates: true})
+	if err != nil {
+		return err
+	}
+	var ew residualWriter
+	switch format {
+	case ResidualCSV:
+		ew = newResidualCSVWriter(w)
+	case ResidualWAV:
+		ew, err = newResidualWAVWriter(w, stream.Info.SampleRate, stream.Info.NChannels, stream.Info.NSamples)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("flac.ExportResiduals: invalid residual format %v", format)
+	}
+	for num := uint64(0); ; num++ {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if err := ew.writeFrame(num, stream.sampleNumber(f), f); err != nil {
+			return err
+		}
+	}
+	return ew.flush()
+}
+
+// residualWriter writes the residual signals of audio frames in a given format.
+type residualWriter interface {
+	// writeFrame writes the residuals of the given frame, with the given frame
+	// number and first sample number.
+	writeFrame(num, sampleNum uint64, f *frame.Frame) error
+	// flush flushes any buffered data to the underlying writer.
+	flush() error
+}
+
+// --- [ CSV ] -----------------------------------------------------------------
+
+// residualCSVWriter writes residual signals as comma-separated values.
+type residualCSVWriter struct {
+	// Underlying CSV writer.
+	w *csv.Writer
+	// Specifies whether the header record has been written.
+	hasHeader bool
+}
+
+// newResidualCSVWriter returns a new residual writer of comma-separated values.
+func newResidualCSVWriter(w io.Writer) *residualCSVWriter {
+	return &residualCSVWriter{w: csv.NewWriter(w)}
+}
+
+// writeFrame writes one record per residual of the given frame.
+func (rw *residualCSVWriter) writeFrame(num, sampleNum uint64, f *frame.Frame) error {
+	if !rw.hasHeader {
+		if err := rw.w.Write([]string{"frame", "channel", "sample", "residual"}); err != nil {
+			return err
+		}
+		rw.hasHeader = true
+	}
+	record := make([]string, 4)
+	record[0] = strconv.FormatUint(num, 10)
+	for channel, subframe := range f.Subframes {
+		record[1] = strconv.Itoa(channel)
+		for i, residual := range subframe.Residuals {
+			record[2] = strconv.FormatUint(sampleNum+uint64(subframe.Order+i), 10)
+			record[3] = strconv.FormatInt(int64(residual), 10)
+			if err := rw.w.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush flushes the buffered records to the underlying writer.
+func (rw *residualCSVWriter) flush() error {
+	rw.w.Flush()
+	return rw.w.Error()
+}
+
+// --- [ WAV ] -----------------------------------------------------------------
+
+// residualWAVWriter writes residual signals as a WAVE file of 32-bit signed
+// PCM.
+type residualWAVWriter struct {
+	// Buffered underlying writer.
+	w *bufio.Writer
+	// Number of channels.
+	nchannels int
+	// Number of samples (per channel) remaining, as specified by the WAVE
+	// header.
+	remaining uint64
+}
+
+// newResidualWAVWriter returns a new residual writer of a WAVE file with the
+// given sample rate, number of channels and total number of samples (per
+// channel), and writes the WAVE header.
+func newResidualWAVWriter(w io.Writer, sampleRate uint32, nchannels uint8, nsamples uint64) (*residualWAVWriter, error) {
+	if nsamples == 0 {
+		return nil, errors.New("flac.ExportResiduals: total number of samples of stream not specified; required by WAVE format")
+	}
+	const bytesPerSample = 4
+	dataSize := nsamples * uint64(nchannels) * bytesPerSample
+	if dataSize > 0xFFFFFFFF-36 {
+		return nil, fmt.Errorf("flac.ExportResiduals: size of residual data (%d bytes) exceeds the limit of the WAVE format", dataSize)
+	}
+	bw := bufio.NewWriter(w)
+	hdr := struct {
+		RiffID        [4]byte
+		RiffSize      uint32
+		WaveID        [4]byte
+		FmtID         [4]byte
+		FmtSize       uint32
+		FormatTag     uint16
+		NChannels     uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		DataID        [4]byte
+		DataSize      uint32
+	}{
+		RiffID:        [4]byte{'R', 'I', 'F', 'F'},
+		RiffSize:      uint32(36 + dataSize),
+		WaveID:        [4]byte{'W', 'A', 'V', 'E'},
+		FmtID:         [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		FormatTag:     1, // PCM
+		NChannels:     uint16(nchannels),
+		SampleRate:    sampleRate,
+		ByteRate:      sampleRate * uint32(nchannels) * bytesPerSample,
+		BlockAlign:    uint16(nchannels) * bytesPerSample,
+		BitsPerSample: 8 * bytesPerSample,
+		DataID:        [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(dataSize),
+	}
+	if err := binary.Write(bw, binary.LittleEndian, &hdr); err != nil {
+		return nil, err
+	}
+	return &residualWAVWriter{w: bw, nchannels: int(nchannels), remaining: nsamples}, nil
+}
+
+// writeFrame writes the interleaved residuals of the given frame.
+func (rw *residualWAVWriter) writeFrame(num, sampleNum uint64, f *frame.Frame) error {
+	if len(f.Subframes) != rw.nchannels {
+		return fmt.Errorf("flac.ExportResiduals: channel count mismatch of frame %d; expected %d, got %d", num, rw.nchannels, len(f.Subframes))
+	}
+	nsamples := uint64(f.BlockSize)
+	if nsamples > rw.remaining {
+		return fmt.Errorf("flac.ExportResiduals: number of samples exceeds the total number of samples of StreamInfo at frame %d", num)
+	}
+	rw.remaining -= nsamples
+	var buf [4]byte
+	for i := 0; i < int(f.BlockSize); i++ {
+		for _, subframe := range f.Subframes {
+			var residual int32
+			if j := i - subframe.Order; j >= 0 && j < len(subframe.Residuals) {
+				residual = subframe.Residuals[j]
+			}
+			binary.LittleEndian.PutUint32(buf[:], uint32(residual))
+			if _, err := rw.w.Write(buf[:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush flushes the buffered samples to the underlying writer.
+func (rw *residualWAVWriter) flush() error {
+	if rw.remaining != 0 {
+		return fmt.Errorf("flac.ExportResiduals: stream ended %d samples short of the total number of samples of StreamInfo", rw.remaining)
+	}
+	return rw.w.Flush()
+}
@@ -0,0 +1,128 @@
+package flac_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestExportResiduals(t *testing.T) {
+	const path = "testdata/love.flac"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode the residuals of each frame, keyed by channel and sample number.
+	stream, err := flac.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := stream.Info
+	nchannels := int(info.NChannels)
+	want := make([]int32, int(info.NSamples)*nchannels)
+	nresiduals := 0
+	for sampleNum := 0; ; {
+		f, err := stream.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		f.KeepResiduals = true
+		if err := f.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		for channel, subframe := range f.Subframes {
+			for i, residual := range subframe.Residuals {
+				want[(sampleNum+subframe.Order+i)*nchannels+channel] = residual
+			}
+			nresiduals += len(subframe.Residuals)
+		}
+		sampleNum += int(f.BlockSize)
+	}
+
+	// Verify CSV export.
+	out := new(bytes.Buffer)
+	if err := flac.ExportResiduals(out, bytes.NewReader(data), flac.ResidualCSV); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1+nresiduals {
+		t.Fatalf("%q: CSV record count mismatch; expected %d, got %d", path, 1+nresiduals, len(records))
+	}
+	for _, record := range records[1:] {
+		channel, _ := strconv.Atoi(record[1])
+		sampleNum, _ := strconv.Atoi(record[2])
+		residual, _ := strconv.Atoi(record[3])
+		if w := want[sampleNum*nchannels+channel]; int32(residual) != w {
+			t.Fatalf("%q: CSV residual mismatch of channel %d at sample %d; expected %d, got %d", path, channel, sampleNum, w, residual)
+		}
+	}
+
+	// Verify WAV export.
+	out.Reset()
+	if err := flac.ExportResiduals(out, bytes.NewReader(data), flac.ResidualWAV); err != nil {
+		t.Fatal(err)
+	}
+	wav := out.Bytes()
+	if size := 44 + 4*len(want); len(wav) != size {
+		t.Fatalf("%q: WAV size mismatch; expected %d, got %d", path, size, len(wav))
+	}
+	if string(wav[:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+		t.Fatalf("%q: invalid WAV header", path)
+	}
+	for i, w := range want {
+		if got := int32(binary.LittleEndian.Uint32(wav[44+4*i:])); got != w {
+			t.Fatalf("%q: WAV residual mismatch at index %d; expected %d, got %d", path, i, w, got)
+		}
+	}
+}
+
+func TestKeepIntermediates(t *testing.T) {
+	const path = "testdata/love.flac"
+	for _, opts := range []*flac.DecodeOptions{
+		{},
+		{KeepIntermediates: true},
+		{KeepIntermediates: true, LowMemory: true},
+	} {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := flac.NewWithOptions(f, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nresiduals := 0
+		for {
+			frame, err := stream.ParseNext()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+			for _, subframe := range frame.Subframes {
+				if !opts.KeepIntermediates && subframe.Residuals != nil {
+					t.Fatalf("%q: unexpected residuals with options %+v", path, opts)
+				}
+				nresiduals += len(subframe.Residuals)
+			}
+		}
+		f.Close()
+		if opts.KeepIntermediates && nresiduals == 0 {
+			t.Errorf("%q: missing residuals with options %+v", path, opts)
+		}
+	}
+}
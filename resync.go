@@ -0,0 +1,243 @@
+package flac
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac/bufseekio"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// ResetReader replaces the underlying reader of the stream with r, retaining
+// the parsed metadata blocks, seek table and decoder state; e.g. to resume
+// decoding from the body of a reconnected HTTP request with range support
+// after a network failure. r must hold the same FLAC stream as the original
+// reader, at the same byte offsets.
+//
+// The stream is repositioned at the frame following the frames decoded so far
+// (see Stream.SamplePosition). The preceding frames are skipped without being
+// decoded, so that sample hashes, stream totals, MD5 verification, frame sizes
+// and events cover each frame once, as if the stream had been decoded without
+// interruption. Following ResetReader, the stream has seeking enabled, and
+// reads of r are buffered as done by NewSeek; call Seek, SeekTime or Resync to
+// resynchronize at another sample number or byte offset, which is not supported
+// for streams computing sample hashes, stream totals or frame sizes, or paced
+// streams.
+//
+// The original reader is not closed, and r is not closed by Stream.Close.
+// ResetReader is not supported for streams computing digests (see
+// DecodeOptions.Digest).
+func (stream *Stream) ResetReader(r io.ReadSeeker) error {
+	if stream.dr != nil {
+		return errors.New("flac.Stream.ResetReader: reader replacement not supported for streams computing digests")
+	}
+	if stream.closer == nil {
+		// Stream.Close closes the original reader, if any, rather than r.
+		if closer, ok := stream.r.(io.Closer); ok {
+			stream.closer = closer
+		} else {
+			stream.closer = nopCloser{}
+		}
+	}
+	if isInMemory(r) {
+		stream.r = r
+	} else {
+		br := bufseekio.NewReadSeeker(r)
+		br.Resize(readaheadSize(stream.Info))
+		stream.r = br
+	}
+	stream.br, stream.cr = nil, nil
+	stream.int16Frame = nil
+	if stream.seekTable == nil {
+		// Retain the seek table of the metadata blocks, if parsed.
+		for _, block := range stream.Blocks {
+			if table, ok := block.Body.(*meta.SeekTable); ok {
+				stream.seekTable = table
+				break
+			}
+		}
+	}
+	sampleNum := stream.samplePos
+	if sampleNum == 0 || stream.Info.NSamples != 0 && sampleNum >= stream.Info.NSamples {
+		// Position at the start or the end of the audio frames, which need not
+		// be located by Seek.
+		offset := stream.dataStart
+		whence := io.SeekStart
+		if sampleNum != 0 {
+			offset, whence = 0, io.SeekEnd
+		}
+		if _, err := stream.r.(io.Seeker).Seek(offset, whence); err != nil {
+			return err
+		}
+		stream.resetSeek(sampleNum)
+		return nil
+	}
+	if err := stream.locateFrame(sampleNum); err != nil {
+		return fmt.Errorf("flac.Stream.ResetReader: unable to locate frame of sample %d; %w", sampleNum, err)
+	}
+	return nil
+}
+
+// locateFrame positions the stream at the start of the frame containing the
+// given sample number. Unlike Stream.Seek, the frames preceding the frame are
+// skipped without being decoded by the stream, so that the state of the
+// decoding options (e.g. sample hashes, MD5 verification and events) is
+// retained; e.g. to resume decoding after Stream.ResetReader.
+func (stream *Stream) locateFrame(sampleNum uint64) error {
+	rs := stream.r.(io.ReadSeeker)
+	var point meta.SeekPoint
+	if stream.seekTable != nil {
+		// Placeholder points and empty seek tables locate the first frame.
+		if p, err := stream.searchFromStart(sampleNum); err == nil && p.SampleNum <= sampleNum {
+			point = p
+		}
+	}
+	if _, err := rs.Seek(stream.dataStart+int64(point.Offset), io.SeekStart); err != nil {
+		return err
+	}
+	for {
+		offset, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		f, err := stream.skipFrame()
+		if err != nil {
+			if err == io.EOF && stream.Info.NSamples == 0 {
+				// The frames decoded so far end the stream of unknown length.
+				stream.resetSeek(sampleNum)
+				return nil
+			}
+			return err
+		}
+		if first := stream.sampleNumber(f); first+uint64(f.BlockSize) > sampleNum {
+			_, err := rs.Seek(offset, io.SeekStart)
+			stream.resetSeek(first)
+			return err
+		}
+	}
+}
+
+// checkReposition returns an error if the stream may not be repositioned by
+// Stream.Seek or Stream.Resync, as its decoding options accumulate state over
+// the frames decoded from the start of the stream; which is only the case for
+// streams with seeking enabled by Stream.ResetReader (see checkSeekOptions).
+func (stream *Stream) checkReposition() error {
+	switch {
+	case stream.hasher != nil || stream.totals != nil:
+		return errors.New("repositioning not supported for streams computing stream totals or sample hashes")
+	case stream.frameSizes != nil:
+		return errors.New("repositioning not supported for streams tracking frame sizes")
+	case stream.pacer != nil:
+		return errors.New("repositioning not supported for paced streams")
+	}
+	return nil
+}
+
+// nopCloser is an io.Closer which does nothing.
+type nopCloser struct{}
+
+// Close does nothing.
+func (nopCloser) Close() error {
+	return nil
+}
+
+// minResyncWindow specifies the minimum size in bytes of the data searched for
+// frame headers at once by Stream.Resync.
+const minResyncWindow = 1 << 20
+
+// Resync repositions the stream at the first audio frame starting at or
+// following the given byte offset, relative to the start of the underlying
+// io.Reader (see Stream.Offset); e.g. the offset at which a reconnected HTTP
+// request resumes. It returns the sample number of the first sample of the
+// frame, or io.EOF if no frame follows the offset.
+//
+// The resynchronization is reported to DecodeOptions.Logger and Stream.Events
+// as an EventResync event, holding the byte offset of the frame and the number
+// of bytes skipped following the given offset.
+//
+// Frames are located by their frame headers, and are verified by decoding the
+// frame and validating its CRC-16 checksum against the frame data; frames whose
+// channels or sample rate differ from StreamInfo are skipped. The stream must
+// have seeking enabled; see NewSeek and Stream.ResetReader.
+func (stream *Stream) Resync(offset int64) (uint64, error) {
+	rs, ok := stream.r.(io.ReadSeeker)
+	if !ok {
+		return 0, ErrNoSeeker
+	}
+	if err := stream.checkReposition(); err != nil {
+		return 0, fmt.Errorf("flac.Stream.Resync: %w", err)
+	}
+	// The MD5 checksum only covers streams decoded from the start.
+	stream.md5sum = nil
+	start := max(offset, stream.dataStart)
+	pos := start
+	window := max(minResyncWindow, 2*int(stream.Info.FrameSizeMax))
+	buf := make([]byte, window)
+	hint := &frame.Header{SampleRate: stream.Info.SampleRate, BitsPerSample: stream.Info.BitsPerSample}
+	for {
+		if _, err := rs.Seek(pos, io.SeekStart); err != nil {
+			return 0, err
+		}
+		n, err := io.ReadFull(rs, buf)
+		atEOF := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !atEOF {
+			return 0, err
+		}
+		data := buf[:n]
+		// Frames starting in the second half of the window may be truncated by
+		// the end of the window; they are searched in the next window.
+		end := len(data)
+		if !atEOF {
+			end = len(data) / 2
+		}
+		for i := 0; i < end; i++ {
+			i, _ = frame.FindHeader(data, i)
+			if i == -1 || i >= end {
+				break
+			}
+			f, ok := verifyFrame(data[i:], hint, stream.Info)
+			if !ok {
+				continue
+			}
+			frameStart := pos + int64(i)
+			if _, err := rs.Seek(frameStart, io.SeekStart); err != nil {
+				return 0, err
+			}
+			stream.log(Event{Kind: EventResync, Offset: frameStart, Skipped: frameStart - start})
+			sampleNum := stream.sampleNumber(f)
+			stream.resetSeek(sampleNum)
+			return sampleNum, nil
+		}
+		if atEOF {
+			return 0, io.EOF
+		}
+		pos += int64(end)
+	}
+}
+
+// verifyFrame parses the audio frame at the start of data, filling in the
+// sample rate and sample size of hint if unspecified by the frame header, and
+// reports whether the frame is valid; i.e. whether its CRC-16 checksum matches,
+// and whether its channels and sample rate match the given StreamInfo.
+func verifyFrame(data []byte, hint *frame.Header, info *meta.StreamInfo) (*frame.Frame, bool) {
+	f, err := frame.New(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	if f.Channels.Count() != int(info.NChannels) || f.SampleRate != 0 && f.SampleRate != info.SampleRate {
+		return nil, false
+	}
+	if f.SampleRate == 0 {
+		f.SampleRate = hint.SampleRate
+	}
+	if f.BitsPerSample == 0 {
+		f.BitsPerSample = hint.BitsPerSample
+	}
+	if err := f.Parse(); err != nil {
+		return nil, false
+	}
+	return f, true
+}
@@ -0,0 +1,178 @@
+package flac_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestResetReader(t *testing.T) {
+	data, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Byte offset and first sample number of each frame.
+	type framePos struct {
+		offset    int64
+		sampleNum uint64
+	}
+	var frames []framePos
+	ref, err := flac.NewSeek(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		offset := ref.Offset()
+		f, err := ref.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, framePos{offset: offset, sampleNum: f.SampleNumber()})
+	}
+	if len(frames) < 4 {
+		t.Fatalf("number of frames mismatch; expected >= 4, got %d", len(frames))
+	}
+
+	// Replace the reader of a non-seekable stream following a failure; e.g.
+	// of a truncated HTTP response.
+	var resyncs []flac.Event
+	opts := &flac.DecodeOptions{
+		Logger: flac.LoggerFunc(func(event flac.Event) {
+			if event.Kind == flac.EventResync {
+				resyncs = append(resyncs, event)
+			}
+		}),
+	}
+	stream, err := flac.NewWithOptions(bytes.NewReader(data[:frames[3].offset+10]), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := stream.ParseNext(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := stream.ParseNext(); err == nil {
+		t.Fatal("expected error of truncated stream")
+	}
+	if err := stream.ResetReader(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stream.ParseNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.SampleNumber(), frames[3].sampleNum; got != want {
+		t.Errorf("sample number mismatch after reader replacement; expected %d, got %d", want, got)
+	}
+
+	// Resynchronize at byte offsets.
+	golden := []struct {
+		offset int64
+		want   uint64
+	}{
+		{offset: 0, want: 0},
+		{offset: frames[1].offset, want: frames[1].sampleNum},
+		{offset: frames[1].offset + 1, want: frames[2].sampleNum},
+		{offset: frames[2].offset - 1, want: frames[2].sampleNum},
+	}
+	for _, g := range golden {
+		resyncs = nil
+		got, err := stream.Resync(g.offset)
+		if err != nil {
+			t.Errorf("offset %d: unable to resynchronize; %v", g.offset, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("offset %d: sample number mismatch; expected %d, got %d", g.offset, g.want, got)
+		}
+		// The resynchronization is logged with the skipped bytes.
+		frameStart := frames[0].offset
+		for _, pos := range frames {
+			if pos.sampleNum == g.want {
+				frameStart = pos.offset
+			}
+		}
+		want := flac.Event{Kind: flac.EventResync, Offset: frameStart, Skipped: frameStart - max(g.offset, frames[0].offset)}
+		if len(resyncs) != 1 || resyncs[0] != want {
+			t.Errorf("offset %d: resync events mismatch; expected [%v], got %v", g.offset, want, resyncs)
+		}
+		f, err := stream.ParseNext()
+		if err != nil {
+			t.Errorf("offset %d: unable to decode frame; %v", g.offset, err)
+			continue
+		}
+		if f.SampleNumber() != g.want {
+			t.Errorf("offset %d: sample number of decoded frame mismatch; expected %d, got %d", g.offset, g.want, f.SampleNumber())
+		}
+	}
+	if _, err := stream.Resync(int64(len(data))); err != io.EOF {
+		t.Errorf("expected io.EOF for offset at end of stream, got %v", err)
+	}
+}
+
+func TestResetReaderHashes(t *testing.T) {
+	data, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Straight decode.
+	want := sha256.New()
+	ref, err := flac.NewWithOptions(bytes.NewReader(data), &flac.DecodeOptions{SampleHashes: []hash.Hash{want}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int64
+	for {
+		offsets = append(offsets, ref.Offset())
+		if _, err := ref.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+	}
+	if len(offsets) < 7 {
+		t.Fatalf("number of frames mismatch; expected >= 6 frames, got %d", len(offsets)-1)
+	}
+
+	// Decode interrupted at frame 5, and resumed following a reader reset.
+	got := sha256.New()
+	opts := &flac.DecodeOptions{SampleHashes: []hash.Hash{got}, VerifyMD5: true}
+	stream, err := flac.NewWithOptions(bytes.NewReader(data[:offsets[5]+10]), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			break
+		}
+	}
+	if err := stream.ResetReader(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatalf("unable to decode resumed stream; %v", err)
+		}
+	}
+	if !bytes.Equal(got.Sum(nil), want.Sum(nil)) {
+		t.Errorf("SHA-256 mismatch after reader reset; expected %x, got %x", want.Sum(nil), got.Sum(nil))
+	}
+
+	// Streams computing sample hashes may not be repositioned.
+	if _, err := stream.Seek(0); err == nil {
+		t.Error("expected error of seek in stream computing sample hashes")
+	}
+}
@@ -0,0 +1,112 @@
+package flac
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RewriteOptions specifies how the metadata of a FLAC file is rewritten; see
+// ReplaceFrontCoverWithOptions. The zero value specifies the default options.
+//
+// Files are rewritten safely: the rewritten file is written to a temporary file
+// in the directory of the original file, using the permissions of the original
+// file, committed to stable storage (fsync), and renamed to replace the
+// original file; the rename is committed to stable storage by syncing the
+// directory. As such, a crash or an aborted rewrite leaves either the original
+// file or the rewritten file; never a partially written file.
+type RewriteOptions struct {
+	// Atomic specifies whether to always rewrite the file, rather than
+	// rewriting the metadata blocks in place if they fit the space of the
+	// original metadata blocks. In-place rewrites are faster, but a crash
+	// during the write may leave the metadata blocks partially written.
+	Atomic bool
+	// PreserveOwner specifies whether to copy the owner and group of the
+	// original file to the rewritten file; on Unix only. Changing the owner
+	// typically requires superuser privileges; the rewrite fails if denied.
+	PreserveOwner bool
+	// PreserveTimes specifies whether to copy the modification time of the
+	// original file to the rewritten file; e.g. for file synchronization tools
+	// which detect changes by modification time.
+	PreserveTimes bool
+	// Progress, if non-nil, is invoked during file rewrites with the number of
+	// bytes written so far and the total number of bytes of the rewritten file.
+	// A non-nil error aborts the rewrite, leaving the original file untouched;
+	// the error is returned to the caller.
+	Progress func(written, total int64) error
+}
+
+// rewriteFile writes the concatenation of the given parts to a temporary file
+// in the directory of path, using the permissions (and optionally the owner and
+// modification time) of fi, and renames the temporary file to path once
+// committed to stable storage, along with the directory of path. The parts must implement Size, as implemented
+// by *io.SectionReader and *bytes.Reader.
+func rewriteFile(path string, fi os.FileInfo, parts []io.Reader, opts *RewriteOptions) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	pw := &progressWriter{w: f, progress: opts.Progress}
+	for _, part := range parts {
+		pw.total += part.(interface{ Size() int64 }).Size()
+	}
+	for _, part := range parts {
+		if _, err := io.Copy(pw, part); err != nil {
+			return fail(err)
+		}
+	}
+	if err := f.Chmod(fi.Mode().Perm()); err != nil {
+		return fail(err)
+	}
+	if opts.PreserveOwner {
+		if err := chown(f, fi); err != nil {
+			return fail(err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if opts.PreserveTimes {
+		if err := os.Chtimes(tmpPath, time.Time{}, fi.ModTime()); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// Commit the rename to stable storage.
+	return syncDir(filepath.Dir(path))
+}
+
+// progressWriter reports the progress of writes to the underlying io.Writer.
+type progressWriter struct {
+	// Underlying io.Writer.
+	w io.Writer
+	// Progress hook; or nil if not specified.
+	progress func(written, total int64) error
+	// Number of bytes written, and total number of bytes to write.
+	written, total int64
+}
+
+// Write writes len(p) bytes from p, and reports the progress of the writes.
+func (pw *progressWriter) Write(p []byte) (n int, err error) {
+	n, err = pw.w.Write(p)
+	pw.written += int64(n)
+	if err != nil || pw.progress == nil {
+		return n, err
+	}
+	return n, pw.progress(pw.written, pw.total)
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package flac
+
+import "os"
+
+// chown changes the owner and group of f to those of fi; a no-op on platforms
+// without Unix file ownership.
+func chown(f *os.File, fi os.FileInfo) error {
+	return nil
+}
+
+// syncDir commits the directory entries of dir to stable storage; a no-op on
+// platforms without support for syncing directories.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package flac
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown changes the owner and group of f to those of fi.
+func chown(f *os.File, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return f.Chown(int(st.Uid), int(st.Gid))
+}
+
+// syncDir commits the directory entries of dir to stable storage; e.g. a file
+// renamed within dir.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
@@ -0,0 +1,166 @@
+package flac
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// Vorbis comment keys of the files written by a RotatingEncoder.
+const (
+	// TagPart specifies the 1-based index of the file within the recording.
+	TagPart = "PART"
+	// TagSampleOffset specifies the sample number of the first sample of the
+	// file within the recording.
+	TagSampleOffset = "SAMPLE_OFFSET"
+)
+
+// RotateOptions specifies the options of a RotatingEncoder.
+type RotateOptions struct {
+	// Create returns the output stream of the file with the given 1-based index;
+	// e.g.
+	//
+	//	func(part int) (io.Writer, error) {
+	//		return os.Create(fmt.Sprintf("recording-%03d.flac", part))
+	//	}
+	//
+	// The StreamInfo block of a file is updated once the file is finalized if
+	// the output stream implements io.WriteSeeker, and the output stream is
+	// closed if it implements io.Closer; see Encoder.Close.
+	Create func(part int) (io.Writer, error)
+	// MaxDuration specifies the maximum duration of audio of each file; or 0 for
+	// no limit. Files are split at frame boundaries, so a file exceeds the
+	// maximum duration only if it holds a single frame of longer duration.
+	MaxDuration time.Duration
+	// MaxSize specifies the size in bytes of each file at which the file is
+	// finalized; or 0 for no limit. The file is finalized once its size reaches
+	// MaxSize, and may as such exceed MaxSize by up to one frame; or by up to
+	// EncodeOptions.Workers frames when encoding concurrently.
+	MaxSize int64
+	// Encoder options of each file; a nil value specifies the default options.
+	Encode *EncodeOptions
+}
+
+// A RotatingEncoder encodes a recording to a sequence of FLAC files, starting a
+// new file once the current file reaches the maximum duration or size of the
+// rotation options; e.g. to split long recordings into manageable files.
+//
+// Each file holds the metadata blocks of the encoder, with the Vorbis comments
+// TagPart and TagSampleOffset added to locate the file within the recording.
+type RotatingEncoder struct {
+	// Stream properties of the recording.
+	info meta.StreamInfo
+	// Metadata blocks of each file, excluding the StreamInfo block.
+	blocks []*meta.Block
+	// Rotation options.
+	opts RotateOptions
+	// Encoder of the current file; or nil if no file is open.
+	enc *Encoder
+	// Number of files created.
+	part int
+	// Sample number of the first sample of the current file.
+	offset uint64
+}
+
+// NewRotatingEncoder returns a new encoder which encodes a recording with the
+// given stream properties to a sequence of FLAC files, using the given rotation
+// options. The StreamInfo block and metadata blocks are stored in each file;
+// the total number of samples and MD5 checksum are computed per file. Files are
+// created on demand, as frames are written.
+func NewRotatingEncoder(info *meta.StreamInfo, opts *RotateOptions, blocks ...*meta.Block) (*RotatingEncoder, error) {
+	if opts == nil || opts.Create == nil {
+		return nil, errors.New("flac.NewRotatingEncoder: Create option not specified")
+	}
+	if opts.MaxDuration < 0 || opts.MaxSize < 0 {
+		return nil, errors.New("flac.NewRotatingEncoder: invalid negative maximum duration or size")
+	}
+	// NOTE: the errors of StreamInfo validation are not wrapped, as done by
+	// NewEncoderWithOptions.
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+	r := &RotatingEncoder{
+		info:   *info,
+		blocks: blocks,
+		opts:   *opts,
+	}
+	r.info.NSamples = 0
+	r.info.MD5sum = [16]uint8{}
+	return r, nil
+}
+
+// WriteFrame encodes the given audio frame to the current file, finalizing the
+// current file and starting the next if the current file is full. The Num field
+// of the frame header is calculated relative to the start of the file.
+func (r *RotatingEncoder) WriteFrame(f *frame.Frame) error {
+	if len(f.Subframes) == 0 {
+		return errors.New("flac.RotatingEncoder.WriteFrame: frame without subframes")
+	}
+	if r.enc != nil && r.full(uint64(f.Subframes[0].NSamples)) {
+		if err := r.finalize(); err != nil {
+			return err
+		}
+	}
+	if r.enc == nil {
+		if err := r.next(); err != nil {
+			return err
+		}
+	}
+	return r.enc.WriteFrame(f)
+}
+
+// Close finalizes the current file.
+func (r *RotatingEncoder) Close() error {
+	if r.enc == nil {
+		return nil
+	}
+	return r.finalize()
+}
+
+// full reports whether the current file is full, given the number of samples
+// (per channel) of the next frame.
+func (r *RotatingEncoder) full(nsamples uint64) bool {
+	if r.opts.MaxSize > 0 && r.enc.ow.n >= r.opts.MaxSize {
+		return true
+	}
+	if r.opts.MaxDuration > 0 {
+		maxSamples := uint64(r.opts.MaxDuration.Seconds()*float64(r.info.SampleRate) + 0.5)
+		return r.enc.nsamples+nsamples > maxSamples
+	}
+	return false
+}
+
+// next starts the next file.
+func (r *RotatingEncoder) next() error {
+	r.part++
+	w, err := r.opts.Create(r.part)
+	if err != nil {
+		return err
+	}
+	tags := [][2]string{
+		{TagPart, strconv.Itoa(r.part)},
+		{TagSampleOffset, strconv.FormatUint(r.offset, 10)},
+	}
+	info := r.info
+	enc, err := NewEncoderWithOptions(w, &info, r.opts.Encode, withTags(r.blocks, tags)...)
+	if err != nil {
+		if c, ok := w.(io.Closer); ok {
+			c.Close()
+		}
+		return err
+	}
+	r.enc = enc
+	return nil
+}
+
+// finalize finalizes the current file.
+func (r *RotatingEncoder) finalize() error {
+	enc := r.enc
+	r.enc = nil
+	r.offset += enc.nsamples
+	return enc.Close()
+}
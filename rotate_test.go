@@ -0,0 +1,126 @@
+package flac_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestRotatingEncoder(t *testing.T) {
+	const (
+		blockSize = 1024
+		nframes   = 10
+	)
+	info := &meta.StreamInfo{
+		BlockSizeMin:  blockSize,
+		BlockSizeMax:  blockSize,
+		SampleRate:    44100,
+		NChannels:     2,
+		BitsPerSample: 16,
+	}
+	comment := &meta.Block{
+		Header: meta.Header{Type: meta.TypeVorbisComment, Length: int64(4 + 4 + 4 + len("TITLE=take"))},
+		Body:   &meta.VorbisComment{Tags: [][2]string{{"TITLE", "take"}}},
+	}
+	golden := []struct {
+		name string
+		opts flac.RotateOptions
+		// Number of frames of each file; or nil if not known in advance.
+		want []int
+	}{
+		{
+			name: "duration",
+			opts: flac.RotateOptions{MaxDuration: 3 * blockSize * time.Second / 44100},
+			want: []int{3, 3, 3, 1},
+		},
+		{
+			name: "size",
+			opts: flac.RotateOptions{MaxSize: 1024},
+		},
+		{
+			name: "unlimited",
+			want: []int{10},
+		},
+	}
+	for _, g := range golden {
+		t.Run(g.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := g.opts
+			opts.Encode = &flac.EncodeOptions{}
+			opts.Create = func(part int) (io.Writer, error) {
+				return os.Create(filepath.Join(dir, fmt.Sprintf("part-%d.flac", part)))
+			}
+			enc, err := flac.NewRotatingEncoder(info, &opts, comment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for num := 0; num < nframes; num++ {
+				if err := enc.WriteFrame(makeTestFrame(info, num)); err != nil {
+					t.Fatalf("unable to write frame %d; %v", num, err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var offset uint64
+			for i := 0; offset < nframes*blockSize; i++ {
+				path := filepath.Join(dir, fmt.Sprintf("part-%d.flac", i+1))
+				stream, err := flac.ParseFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				stream.Close()
+				if g.want != nil {
+					if got, want := stream.Info.NSamples, uint64(g.want[i]*blockSize); got != want {
+						t.Errorf("part %d: number of samples mismatch; expected %d, got %d", i+1, want, got)
+					}
+				}
+				if fi, err := os.Stat(path); err != nil {
+					t.Fatal(err)
+				} else if opts.MaxSize > 0 && offset+stream.Info.NSamples < nframes*blockSize && fi.Size() < opts.MaxSize {
+					t.Errorf("part %d: file of %d bytes finalized before reaching %d bytes", i+1, fi.Size(), opts.MaxSize)
+				}
+				var tags [][2]string
+				for _, block := range stream.Blocks {
+					if c, ok := block.Body.(*meta.VorbisComment); ok {
+						tags = c.Tags
+					}
+				}
+				want := [][2]string{
+					{"TITLE", "take"},
+					{flac.TagPart, strconv.Itoa(i + 1)},
+					{flac.TagSampleOffset, strconv.FormatUint(offset, 10)},
+				}
+				if fmt.Sprint(tags) != fmt.Sprint(want) {
+					t.Errorf("part %d: tags mismatch; expected %v, got %v", i+1, want, tags)
+				}
+				check, err := flac.FixMD5(path, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if check.Mismatch() {
+					t.Errorf("part %d: MD5 checksum mismatch", i+1)
+				}
+				offset += stream.Info.NSamples
+				if stream.Info.NSamples == 0 {
+					t.Fatalf("part %d: empty file", i+1)
+				}
+				if offset == nframes*blockSize {
+					if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("part-%d.flac", i+2))); err == nil {
+						t.Errorf("unexpected part %d", i+2)
+					}
+					if g.want != nil && i+1 != len(g.want) {
+						t.Errorf("number of parts mismatch; expected %d, got %d", len(g.want), i+1)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package flac
+
+import (
+	"crypto/md5"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// sampleHasher writes the decoded audio samples of frames to a set of hashes,
+// in the canonical byte representation of the MD5 checksum of StreamInfo; i.e.
+// interleaved little-endian two's complement integers of the smallest number of
+// bytes holding the bits-per-sample. See DecodeOptions.SampleHashes.
+type sampleHasher struct {
+	// Hashes of the decoded audio samples.
+	hashes []hash.Hash
+	// Buffer of the canonical audio samples of a frame, reused across frames.
+	buf []byte
+}
+
+// write writes the decoded audio samples of the given frame to each hash.
+func (h *sampleHasher) write(f *frame.Frame) error {
+	bps := int(f.BitsPerSample)
+	format := frame.PCMFormat{BytesPerSample: (bps + 7) / 8, BitsPerSample: bps}
+	buf, err := f.AppendPCM(h.buf[:0], format)
+	if err != nil {
+		return err
+	}
+	h.buf = buf
+	for _, hash := range h.hashes {
+		hash.Write(buf)
+	}
+	return nil
+}
+
+// hashSamples writes the decoded audio samples of the given frame to the
+// sample hashes of the stream, if any.
+func (stream *Stream) hashSamples(f *frame.Frame) error {
+	if stream.hasher == nil {
+		return nil
+	}
+	return stream.hasher.write(f)
+}
+
+// verifyMD5 verifies the MD5 checksum of the decoded audio samples against the
+// MD5 checksum of StreamInfo once the end of the stream is reached, as signaled
+// by the given error of parsing the next frame; see DecodeOptions.VerifyMD5. It
+// returns err, or an error wrapping ErrChecksumMismatch on mismatch.
+func (stream *Stream) verifyMD5(err error) error {
+	if stream.md5sum == nil || err != io.EOF {
+		return err
+	}
+	var sum [md5.Size]uint8
+	copy(sum[:], stream.md5sum.Sum(nil))
+	stream.md5sum = nil
+	if sum != stream.Info.MD5sum {
+		return fmt.Errorf("flac.Stream.ParseNext: %w; expected %x, got %x", ErrChecksumMismatch, stream.Info.MD5sum, sum)
+	}
+	return err
+}
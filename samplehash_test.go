@@ -0,0 +1,55 @@
+package flac_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestSampleHashes(t *testing.T) {
+	// 16-bit stereo, 24-bit mono, 8-bit mono and 24-bit stereo.
+	paths := []string{
+		"testdata/172960.flac",
+		"testdata/243749.flac",
+		"testdata/44127.flac",
+		"testdata/59996.flac",
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, lowMemory := range []bool{false, true} {
+			md5sum, sha256sum := md5.New(), sha256.New()
+			opts := &flac.DecodeOptions{LowMemory: lowMemory, SampleHashes: []hash.Hash{md5sum, sha256sum}}
+			stream, err := flac.NewWithOptions(bytes.NewReader(data), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// SHA-256 checksum of the audio samples as hashed by Frame.Hash.
+			want := sha256.New()
+			for {
+				f, err := stream.ParseNext()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.Hash(want)
+			}
+			if got := md5sum.Sum(nil); !bytes.Equal(got, stream.Info.MD5sum[:]) {
+				t.Errorf("%s: MD5 checksum mismatch; expected %x, got %x", path, stream.Info.MD5sum, got)
+			}
+			if got, want := sha256sum.Sum(nil), want.Sum(nil); !bytes.Equal(got, want) {
+				t.Errorf("%s: SHA-256 checksum mismatch; expected %x, got %x", path, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package flac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// ScanOptions specifies the options of ScanDir. The zero value specifies the
+// default options.
+type ScanOptions struct {
+	// Workers specifies the number of files parsed concurrently; a 0 value
+	// implies runtime.GOMAXPROCS(0).
+	Workers int
+	// FollowSymlinks specifies whether to follow symbolic links to files and
+	// directories; otherwise, symbolic links are skipped. Directories reached
+	// through multiple paths (e.g. symbolic link cycles) are scanned once.
+	FollowSymlinks bool
+	// Match reports whether to scan the file at the given path; nil specifies
+	// files with the extension ".flac", compared case-insensitively.
+	Match func(path string) bool
+}
+
+// A ScanResult holds the metadata of a FLAC file scanned by ScanDir.
+type ScanResult struct {
+	// Path of the file, as found by walking the root directory.
+	Path string
+	// File information of the file; nil if the file could not be accessed.
+	File fs.FileInfo
+	// StreamInfo metadata block of the file.
+	Info *meta.StreamInfo
+	// Metadata blocks of the file, excluding the StreamInfo block.
+	Blocks meta.BlockList
+	// Error of accessing or parsing the file; if non-nil, the metadata of the
+	// file is unset.
+	Err error
+}
+
+// ScanDir walks the directory tree rooted at root, parses the metadata blocks of
+// each FLAC file using concurrent workers, and calls fn with the result of each
+// file; e.g. to index a music library. The audio frames are not parsed.
+//
+// fn is called from the goroutine of the caller, one result at the time, in the
+// order in which the files have been parsed. Errors of accessing directories
+// and of parsing files are reported to fn as results with a non-nil Err, and the
+// scan continues. ScanDir returns the errors of all such results joined, with
+// each error prefixed by its path; or nil if all files were parsed.
+//
+// If fn returns an error, the scan is stopped and the error is returned. If ctx
+// is canceled, the scan is stopped and the error of the context is returned.
+func ScanDir(ctx context.Context, root string, opts *ScanOptions, fn func(result *ScanResult) error) error {
+	if opts == nil {
+		opts = &ScanOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	match := opts.Match
+	if match == nil {
+		match = hasFLACExt
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Walk the directory tree, sending the files to scan and the errors of
+	// accessing directories to the workers.
+	jobs := make(chan *ScanResult)
+	go func() {
+		defer close(jobs)
+		w := &scanWalker{ctx: ctx, jobs: jobs, match: match, followSymlinks: opts.FollowSymlinks, visited: make(map[string]bool)}
+		w.walk(root)
+	}()
+
+	// Parse the metadata blocks of files concurrently.
+	results := make(chan *ScanResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range jobs {
+				if result.Err == nil {
+					scanFile(result)
+				}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var errs []error
+	for result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Path, result.Err))
+		}
+		if err := fn(result); err != nil {
+			cancel()
+			for range results {
+			}
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// hasFLACExt reports whether the given path has the extension ".flac", compared
+// case-insensitively.
+func hasFLACExt(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".flac")
+}
+
+// scanFile parses the metadata blocks of the file of the given result, and
+// stores the metadata in the result.
+func scanFile(result *ScanResult) {
+	stream, err := ParseFile(result.Path)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	result.Info = stream.Info
+	result.Blocks = stream.Blocks
+	if err := stream.Close(); err != nil {
+		result.Err = err
+		result.Info, result.Blocks = nil, nil
+	}
+}
+
+// scanWalker walks a directory tree for ScanDir.
+type scanWalker struct {
+	ctx context.Context
+	// Files to scan, and errors of accessing directories.
+	jobs chan<- *ScanResult
+	// Reports whether to scan a file.
+	match func(path string) bool
+	// Specifies whether to follow symbolic links.
+	followSymlinks bool
+	// Real paths of the directories walked, if following symbolic links.
+	visited map[string]bool
+}
+
+// walk walks the directory tree rooted at root. It returns early if the context
+// is canceled.
+func (w *scanWalker) walk(root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if !w.send(&ScanResult{Path: path, Err: err}) {
+				return filepath.SkipAll
+			}
+			return nil
+		}
+		switch {
+		case d.IsDir():
+			if w.followSymlinks && !w.visit(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			if !w.followSymlinks {
+				return nil
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				if !w.send(&ScanResult{Path: path, Err: err}) {
+					return filepath.SkipAll
+				}
+				return nil
+			}
+			if fi.IsDir() {
+				// WalkDir does not follow a symbolic link of its root, unless
+				// the path ends with a separator.
+				w.walk(path + string(filepath.Separator))
+				if w.ctx.Err() != nil {
+					return filepath.SkipAll
+				}
+				return nil
+			}
+			if fi.Mode().IsRegular() && w.match(path) && !w.send(&ScanResult{Path: path, File: fi}) {
+				return filepath.SkipAll
+			}
+			return nil
+		case !d.Type().IsRegular() || !w.match(path):
+			return nil
+		}
+		fi, err := d.Info()
+		if !w.send(&ScanResult{Path: path, File: fi, Err: err}) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+// visit marks the directory at the given path as visited, and reports whether
+// it was unvisited.
+func (w *scanWalker) visit(path string) bool {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		// Walk the directory; errors of reading it are reported by WalkDir.
+		return true
+	}
+	if w.visited[realPath] {
+		return false
+	}
+	w.visited[realPath] = true
+	return true
+}
+
+// send sends the given job to the workers, and reports whether the job was sent
+// before the context was canceled.
+func (w *scanWalker) send(job *ScanResult) bool {
+	select {
+	case w.jobs <- job:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,110 @@
+package flac_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestScanDir(t *testing.T) {
+	data, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Directory tree of FLAC files, other files, a damaged FLAC file and
+	// symbolic links; including a symbolic link cycle.
+	root := t.TempDir()
+	files := map[string][]byte{
+		"a.flac":          data,
+		"b/c.FLAC":        data,
+		"b/d/e.flac":      data,
+		"b/d/notes.txt":   []byte("notes"),
+		"damaged.flac":    data[:100],
+		"other/f.flac":    data,
+		"other/cover.jpg": []byte("jpeg"),
+	}
+	for name, buf := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tree := filepath.Join(root, "tree")
+	if err := os.Mkdir(tree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range map[string]string{"link.flac": "../a.flac", "other": "../other", "loop": ".."} {
+		if err := os.Symlink(target, filepath.Join(tree, name)); err != nil {
+			t.Skipf("symbolic links not supported; %v", err)
+		}
+	}
+
+	golden := []struct {
+		followSymlinks bool
+		want           []string
+	}{
+		{followSymlinks: false, want: []string{"a.flac", "b/c.FLAC", "b/d/e.flac", "other/f.flac"}},
+		// Directories reached through multiple paths are walked once.
+		{followSymlinks: true, want: []string{"a.flac", "b/c.FLAC", "b/d/e.flac", "other/f.flac", "tree/link.flac"}},
+	}
+	for _, g := range golden {
+		var got []string
+		opts := &flac.ScanOptions{Workers: 3, FollowSymlinks: g.followSymlinks}
+		err := flac.ScanDir(context.Background(), root, opts, func(result *flac.ScanResult) error {
+			rel, err := filepath.Rel(root, filepath.Clean(result.Path))
+			if err != nil {
+				return err
+			}
+			if rel == "damaged.flac" {
+				if result.Err == nil {
+					t.Errorf("follow %v: expected error of damaged file", g.followSymlinks)
+				}
+				return nil
+			}
+			if result.Err != nil {
+				t.Errorf("follow %v: %s: unexpected error; %v", g.followSymlinks, rel, result.Err)
+				return nil
+			}
+			if result.Info.NSamples == 0 || result.File == nil || result.File.Size() != int64(len(data)) {
+				t.Errorf("follow %v: %s: metadata mismatch", g.followSymlinks, rel)
+			}
+			got = append(got, filepath.ToSlash(rel))
+			return nil
+		})
+		if err == nil || !strings.Contains(err.Error(), "damaged.flac") {
+			t.Errorf("follow %v: expected error of damaged file, got %v", g.followSymlinks, err)
+		}
+		sort.Strings(got)
+		if !slices.Equal(got, g.want) {
+			t.Errorf("follow %v: scanned files mismatch; expected %q, got %q", g.followSymlinks, g.want, got)
+		}
+	}
+
+	// Errors of the callback stop the scan.
+	errStop := errors.New("stop")
+	n := 0
+	err = flac.ScanDir(context.Background(), root, nil, func(result *flac.ScanResult) error {
+		n++
+		return errStop
+	})
+	if err != errStop || n != 1 {
+		t.Errorf("expected scan stopped after 1 result, got %d results (%v)", n, err)
+	}
+
+	// Canceled contexts stop the scan.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := flac.ScanDir(ctx, root, nil, func(result *flac.ScanResult) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package flac
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// SetSeekCacheSize sets the number of regions of the stream for which
+// Stream.Seek caches a seek point per frame; 0 by default, which disables the
+// cache.
+//
+// The seek table (the SeekTable metadata block, or the seek table generated as
+// specified by SetSeekTableSize) provides a coarse index of the stream, and the
+// cache a fine index of the regions visited. A region spans the frames between
+// two consecutive seek points of the seek table. Seeking into a region records
+// the seek points of the frames parsed to locate the target sample, and seeking
+// into the recorded part of the region again locates the frame without parsing
+// any frames; e.g. when repeatedly seeking around the current position of an
+// audiobook or DJ set. The least recently used region is evicted once more than
+// n regions are cached, bounding the memory usage to n regions of the seek
+// table.
+//
+// Without a seek table, the entire stream forms a single region. Refining the
+// seek table (see SetSeekRefinement) splits regions, and is therefore best left
+// disabled when using the cache.
+func (stream *Stream) SetSeekCacheSize(n int) {
+	if n <= 0 {
+		stream.seekCache = nil
+		return
+	}
+	stream.seekCache = &seekCache{size: n}
+}
+
+// seekCache caches the seek points of the frames parsed by Stream.Seek, per
+// region of the stream between two consecutive seek points of the seek table.
+type seekCache struct {
+	// Maximum number of cached regions.
+	size int
+	// Cached regions, ordered from least to most recently used.
+	regions []*seekRegion
+}
+
+// seekRegion holds the seek points of consecutive frames of a region of the
+// stream.
+type seekRegion struct {
+	// Sample number of the seek point of the seek table starting the region.
+	start uint64
+	// Seek points of consecutive frames, starting at the seek point of the seek
+	// table; sorted by sample number.
+	points []meta.SeekPoint
+}
+
+// region returns the cached region starting at the seek point of the given
+// sample number, and marks it as most recently used. A new region is created if
+// not cached, evicting the least recently used region if the cache is full.
+func (cache *seekCache) region(start uint64) *seekRegion {
+	for i, region := range cache.regions {
+		if region.start == start {
+			cache.regions = append(slices.Delete(cache.regions, i, i+1), region)
+			return region
+		}
+	}
+	if len(cache.regions) >= cache.size {
+		cache.regions = slices.Delete(cache.regions, 0, 1)
+	}
+	region := &seekRegion{start: start}
+	cache.regions = append(cache.regions, region)
+	return region
+}
+
+// find returns the seek point of the frame containing the given sample number.
+// The boolean return value reports whether the frame has been recorded.
+func (region *seekRegion) find(sampleNum uint64) (meta.SeekPoint, bool) {
+	points := region.points
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].SampleNum > sampleNum
+	})
+	if i == 0 {
+		return meta.SeekPoint{}, false
+	}
+	point := points[i-1]
+	return point, sampleNum < point.SampleNum+uint64(point.NSamples)
+}
+
+// add records the seek point of the frame following the last recorded frame of
+// the region; seek points of frames recorded already are ignored.
+func (region *seekRegion) add(point meta.SeekPoint) {
+	if n := len(region.points); n > 0 && point.SampleNum <= region.points[n-1].SampleNum {
+		return
+	}
+	region.points = append(region.points, point)
+}
@@ -0,0 +1,92 @@
+package flac
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// A BrokenSeekPoint describes a seek point of a seek table which does not
+// reference the start of its target frame.
+type BrokenSeekPoint struct {
+	// Index of the seek point within the seek table, starting at 0.
+	Index int
+	// Broken seek point.
+	Point meta.SeekPoint
+	// Problem of the seek point.
+	Err error
+}
+
+// CheckSeekTable validates each seek point of the seek table of the stream, by
+// seeking to the byte offset of the seek point and parsing the frame header at
+// the target offset. A seek point is broken if no valid frame header is located
+// at its offset, or if the sample number or block size of the frame do not
+// match the seek point. Placeholder points are skipped. The broken seek points
+// are returned in seek table order; e.g. to locate seek tables invalidated by
+// file edits which moved the audio frames, or by truncation of the stream.
+//
+// The stream must be created using NewSeek, and is restored to its current
+// position once the seek table has been validated. The seek table is that of
+// the SeekTable metadata block of the stream; ErrNoSeektable is returned if the
+// stream has no seek table.
+func (stream *Stream) CheckSeekTable() ([]BrokenSeekPoint, error) {
+	rs, ok := stream.r.(io.ReadSeeker)
+	if !ok {
+		return nil, ErrNoSeeker
+	}
+	if stream.seekTable == nil || len(stream.seekTable.Points) == 0 {
+		return nil, ErrNoSeektable
+	}
+	pos, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	var broken []BrokenSeekPoint
+	for i, point := range stream.seekTable.Points {
+		if point.SampleNum == meta.PlaceholderPoint {
+			continue
+		}
+		if err := stream.checkSeekPoint(rs, size, point); err != nil {
+			broken = append(broken, BrokenSeekPoint{Index: i, Point: point, Err: err})
+		}
+	}
+	if _, err := rs.Seek(pos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return broken, nil
+}
+
+// checkSeekPoint validates the given seek point of a stream of the given size
+// in bytes, by parsing the frame header at its byte offset.
+func (stream *Stream) checkSeekPoint(rs io.ReadSeeker, size int64, point meta.SeekPoint) error {
+	if stream.Info.NSamples != 0 && point.SampleNum >= stream.Info.NSamples {
+		return fmt.Errorf("sample number %d beyond the end of the stream (%d samples)", point.SampleNum, stream.Info.NSamples)
+	}
+	offset := stream.dataStart + int64(point.Offset)
+	if offset >= size {
+		return fmt.Errorf("byte offset %d beyond the end of the stream (%d bytes)", offset, size)
+	}
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	f, err := frame.New(rs)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("no valid frame header at offset %d; %v", offset, err)
+	}
+	if first := stream.sampleNumber(f); first != point.SampleNum {
+		return fmt.Errorf("sample number mismatch of frame at offset %d; expected %d, got %d", offset, point.SampleNum, first)
+	}
+	if f.BlockSize != point.NSamples {
+		return fmt.Errorf("block size mismatch of frame at offset %d; expected %d, got %d", offset, point.NSamples, f.BlockSize)
+	}
+	return nil
+}
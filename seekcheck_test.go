@@ -0,0 +1,95 @@
+package flac_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestCheckSeekTable(t *testing.T) {
+	golden := []struct {
+		path string
+		// Replacement seek point, at the start of the seek table.
+		sampleNum, offset uint64
+		want              []int
+	}{
+		{path: "testdata/love.flac"},
+		// Byte offset not at the start of a frame.
+		{path: "testdata/love.flac", offset: 100, want: []int{0}},
+		// Sample number mismatch.
+		{path: "testdata/love.flac", sampleNum: 4096, want: []int{0}},
+		// Truncated stream, with seek points beyond the end of the stream.
+		{path: "testdata/id3.flac", want: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}},
+	}
+	for _, g := range golden {
+		buf, err := os.ReadFile(g.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.sampleNum != 0 || g.offset != 0 {
+			// Locate the first seek point {SampleNum: 0, Offset: 0, NSamples: 4096}.
+			point := binary.BigEndian.AppendUint16(make([]byte, 16), 4096)
+			i := bytes.Index(buf, point)
+			if i == -1 {
+				t.Fatalf("%q: unable to locate seek point", g.path)
+			}
+			binary.BigEndian.PutUint64(buf[i:], g.sampleNum)
+			binary.BigEndian.PutUint64(buf[i+8:], g.offset)
+		}
+		stream, err := flac.NewSeek(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		broken, err := stream.CheckSeekTable()
+		if err != nil {
+			t.Errorf("%q: unable to check seek table; %v", g.path, err)
+			continue
+		}
+		var got []int
+		for _, b := range broken {
+			got = append(got, b.Index)
+		}
+		if !slices.Equal(got, g.want) {
+			t.Errorf("%q: broken seek points mismatch; expected %v, got %v", g.path, g.want, broken)
+		}
+		// The stream position is restored.
+		f, err := stream.ParseNext()
+		if err != nil {
+			t.Errorf("%q: unable to parse frame after check; %v", g.path, err)
+			continue
+		}
+		if f.Num != 0 {
+			t.Errorf("%q: frame number mismatch after check; expected 0, got %d", g.path, f.Num)
+		}
+	}
+}
+
+func TestCheckSeekTableMissing(t *testing.T) {
+	// Seekable stream without seek table.
+	const path = "testdata/172960.flac"
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.NewSeek(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.CheckSeekTable(); !errors.Is(err, flac.ErrNoSeektable) {
+		t.Errorf("%q: expected ErrNoSeektable, got %v", path, err)
+	}
+
+	// Non-seekable stream.
+	stream, err = flac.New(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.CheckSeekTable(); !errors.Is(err, flac.ErrNoSeeker) {
+		t.Errorf("%q: expected ErrNoSeeker for non-seekable stream, got %v", path, err)
+	}
+}
@@ -0,0 +1,138 @@
+package flac
+
+import (
+	"cmp"
+	"io"
+	"slices"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// SetSeekTableSize sets the number of seek points of the seek table generated
+// by the first call to Stream.Seek, for streams without a SeekTable metadata
+// block; 100 by default.
+//
+// A positive n bounds both the memory usage of the seek table and the time
+// spent generating it: seek points are located at n evenly spaced byte offsets
+// of the audio frames, parsing n frames rather than the entire stream. Seeking
+// then parses the frames following the preceding seek point, i.e. on average
+// 1/(2n) of the stream. Streams holding no more frames than n get a seek point
+// per frame.
+//
+// A negative n specifies a seek point per frame, parsing the entire stream on
+// the first seek. An n of 0 disables the seek table; seeking then parses the
+// frames from the start of the stream, unless refined by SetSeekRefinement.
+//
+// SetSeekTableSize has no effect once the seek table has been generated.
+func (stream *Stream) SetSeekTableSize(n int) {
+	stream.seekTableSize = n
+}
+
+// SetSeekRefinement specifies whether to refine the seek table on demand, by
+// adding a seek point of the frame located by each call to Stream.Seek; unless
+// the frame has a seek point already. Subsequent seeks into the same region of
+// the stream then parse fewer frames, and the seek table grows by at most one
+// seek point per seek.
+//
+// The seek points of a SeekTable metadata block are copied before the seek
+// table is refined, leaving the metadata block unchanged.
+func (stream *Stream) SetSeekRefinement(refine bool) {
+	stream.refineSeekTable = refine
+}
+
+// sparseSeekPoints returns seekTableSize seek points of the FLAC stream, of the
+// first valid frame following each of seekTableSize evenly spaced byte offsets
+// of the audio frames of the given size in bytes. Fewer seek points are returned
+// if no valid frame starts between two offsets.
+func (stream *Stream) sparseSeekPoints(rs io.ReadSeeker, dataSize int64) ([]meta.SeekPoint, error) {
+	n := int64(stream.seekTableSize)
+	var points []meta.SeekPoint
+	for i := int64(0); i < n; i++ {
+		start := stream.dataStart + i*dataSize/n
+		end := stream.dataStart + (i+1)*dataSize/n
+		point, ok, err := stream.findSeekPoint(rs, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || (len(points) > 0 && point.SampleNum <= points[len(points)-1].SampleNum) {
+			continue
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// findSeekPoint returns the seek point of the first valid frame starting within
+// the byte offsets [start, end) of the stream. The boolean return value reports
+// whether a frame was found.
+//
+// Frame headers are located using frame.SyncScanner, and frames are verified
+// against their CRC-16 checksum and the channel count of StreamInfo.
+func (stream *Stream) findSeekPoint(rs io.ReadSeeker, start, end int64) (meta.SeekPoint, bool, error) {
+	for start < end {
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return meta.SeekPoint{}, false, err
+		}
+		// Limit the scan to headers starting before end.
+		s := frame.NewSyncScanner(io.LimitReader(rs, end-start+frame.MaxHeaderSize-1))
+		off, _, err := s.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return meta.SeekPoint{}, false, err
+		}
+		offset := start + off
+		if offset >= end {
+			break
+		}
+		// The scanner reads ahead; parse the frame from its start.
+		if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+			return meta.SeekPoint{}, false, err
+		}
+		if f, err := frame.New(rs); err == nil {
+			if f.SampleRate == 0 {
+				f.SampleRate = stream.Info.SampleRate
+			}
+			if f.BitsPerSample == 0 {
+				f.BitsPerSample = stream.Info.BitsPerSample
+			}
+			if f.Channels.Count() == int(stream.Info.NChannels) && f.Parse() == nil {
+				point := meta.SeekPoint{
+					SampleNum: stream.sampleNumber(f),
+					Offset:    uint64(offset - stream.dataStart),
+					NSamples:  f.BlockSize,
+				}
+				return point, true, nil
+			}
+		}
+		// False positive, or damaged frame.
+		start = offset + 1
+	}
+	return meta.SeekPoint{}, false, nil
+}
+
+// addSeekPoint adds the given seek point to the seek table, ordered by sample
+// number; unless a seek point of the same sample number exists.
+func (stream *Stream) addSeekPoint(point meta.SeekPoint) {
+	var points []meta.SeekPoint
+	if stream.seekTable != nil {
+		points = stream.seekTable.Points
+	} else {
+		// Seek points must cover the start of the stream, as Stream.Seek scans
+		// forward from the seek point preceding the target sample number.
+		points = []meta.SeekPoint{{SampleNum: 0, Offset: 0}}
+	}
+	// Placeholder points sort last, as their sample number is the largest
+	// possible.
+	i, found := slices.BinarySearchFunc(points, point.SampleNum, func(p meta.SeekPoint, sampleNum uint64) int {
+		return cmp.Compare(p.SampleNum, sampleNum)
+	})
+	if found {
+		return
+	}
+	// Copy the seek points on insertion, as they may be shared with a SeekTable
+	// metadata block.
+	stream.seekTable = &meta.SeekTable{Points: slices.Insert(slices.Clip(points), i, point)}
+}
@@ -0,0 +1,159 @@
+package flac_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestSetSeekTableSize(t *testing.T) {
+	const (
+		blockSize = 192
+		nsamples  = 500*blockSize + 100
+	)
+	path := filepath.Join(t.TempDir(), "seek.flac")
+	samples := encodeSmallBlocks(t, path, blockSize, nsamples)
+	targets := []uint64{nsamples - 1, 0, 12345, 50000, 50001, 77777, blockSize, nsamples - 100}
+	for _, size := range []int{-1, 0, 1, 10, 100, 1000} {
+		for _, refine := range []bool{false, true} {
+			name := fmt.Sprintf("size %d, refine %v", size, refine)
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			stream, err := flac.NewSeek(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream.SetSeekTableSize(size)
+			stream.SetSeekRefinement(refine)
+			for _, target := range targets {
+				first, err := stream.Seek(target)
+				if err != nil {
+					t.Fatalf("%s: unable to seek to sample %d; %v", name, target, err)
+				}
+				if want := target - target%blockSize; first != want {
+					t.Errorf("%s: first sample of frame containing sample %d mismatch; expected %d, got %d", name, target, want, first)
+					continue
+				}
+				frame, err := stream.ParseNext()
+				if err != nil {
+					t.Fatal(err)
+				}
+				for channel, subframe := range frame.Subframes {
+					want := samples[channel][first : first+uint64(subframe.NSamples)]
+					if !slices.Equal(subframe.Samples, want) {
+						t.Errorf("%s: samples of channel %d of frame at sample %d mismatch", name, channel, first)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSetSeekRefinement(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seek.flac")
+	encodeSmallBlocks(t, path, 192, 1000*192)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rs := &readCounter{ReadSeeker: f}
+	stream, err := flac.NewSeekSize(rs, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a seek table, the first seek parses the frames from the start of
+	// the stream, and subsequent seeks start at the refined seek point.
+	stream.SetSeekTableSize(0)
+	stream.SetSeekRefinement(true)
+	const target = 150000
+	reads := rs.n
+	if _, err := stream.Seek(target); err != nil {
+		t.Fatal(err)
+	}
+	first := rs.n - reads
+	for _, sampleNum := range []uint64{0, target, target + 100} {
+		if _, err := stream.Seek(sampleNum); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reads = rs.n
+	if _, err := stream.Seek(target + 10); err != nil {
+		t.Fatal(err)
+	}
+	if refined := rs.n - reads; refined >= first || refined > 2 {
+		t.Errorf("number of reads of refined seek mismatch; expected at most 2 (%d before refinement), got %d", first, refined)
+	}
+}
+
+func TestSetSeekCacheSize(t *testing.T) {
+	const (
+		blockSize = 192
+		nsamples  = 1000 * blockSize
+	)
+	path := filepath.Join(t.TempDir(), "seek.flac")
+	samples := encodeSmallBlocks(t, path, blockSize, nsamples)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rs := &readCounter{ReadSeeker: f}
+	stream, err := flac.NewSeekSize(rs, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Regions of about 100 frames, of which one is cached.
+	stream.SetSeekTableSize(10)
+	stream.SetSeekCacheSize(1)
+	seek := func(sampleNum uint64) int {
+		reads := rs.n
+		first, err := stream.Seek(sampleNum)
+		if err != nil {
+			t.Fatalf("unable to seek to sample %d; %v", sampleNum, err)
+		}
+		n := rs.n - reads
+		if want := sampleNum - sampleNum%blockSize; first != want {
+			t.Fatalf("first sample of frame containing sample %d mismatch; expected %d, got %d", sampleNum, want, first)
+		}
+		frame, err := stream.ParseNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for channel, subframe := range frame.Subframes {
+			if !slices.Equal(subframe.Samples, samples[channel][first:first+blockSize]) {
+				t.Errorf("samples of channel %d of frame at sample %d mismatch", channel, first)
+			}
+		}
+		return n
+	}
+	const target = 550*blockSize + 10
+	if n := seek(target); n == 0 {
+		t.Fatalf("number of reads of uncached seek mismatch; expected > 0, got %d", n)
+	}
+	// Seeks into the recorded frames of the region read nothing.
+	for _, sampleNum := range []uint64{target, 530 * blockSize, 540*blockSize + 1, target - 1} {
+		if n := seek(sampleNum); n != 0 {
+			t.Errorf("number of reads of cached seek to sample %d mismatch; expected 0, got %d", sampleNum, n)
+		}
+	}
+	// Seeks beyond the recorded frames of the region extend the region.
+	if n := seek(590 * blockSize); n == 0 {
+		t.Errorf("number of reads of seek beyond recorded frames mismatch; expected > 0, got %d", n)
+	}
+	if n := seek(580 * blockSize); n != 0 {
+		t.Errorf("number of reads of cached seek to sample %d mismatch; expected 0, got %d", 580*blockSize, n)
+	}
+	// Seeking into another region evicts the cached region.
+	seek(150 * blockSize)
+	if n := seek(target); n == 0 {
+		t.Errorf("number of reads of seek into evicted region mismatch; expected > 0, got %d", n)
+	}
+}
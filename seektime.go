@@ -0,0 +1,81 @@
+package flac
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	mathbits "math/bits"
+	"time"
+)
+
+// SeekTime seeks to the frame containing the sample at the given time offset
+// from the start of the stream. The time offset is converted to a sample number
+// using the sample rate of StreamInfo, rounded to the nearest sample. The
+// return value specifies the time offset of the first sample of the frame
+// containing the sample, rounded to the nearest nanosecond.
+//
+// The stream must be created using NewSeek to enable seeking.
+func (stream *Stream) SeekTime(d time.Duration) (time.Duration, error) {
+	sampleRate := uint64(stream.Info.SampleRate)
+	if sampleRate == 0 {
+		return 0, errors.New("flac.Stream.SeekTime: unknown sample rate")
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("flac.Stream.SeekTime: negative time offset %v", d)
+	}
+	// sampleNum = round(d * sampleRate / 1s), using 128-bit intermediates.
+	sampleNum, ok := mulDivRound(uint64(d), sampleRate, uint64(time.Second))
+	if !ok {
+		return 0, fmt.Errorf("flac.Stream.SeekTime: time offset %v out of range", d)
+	}
+	first, err := stream.Seek(sampleNum)
+	if err != nil {
+		return 0, err
+	}
+	ns, ok := mulDivRound(first, uint64(time.Second), sampleRate)
+	if !ok || ns > math.MaxInt64 {
+		return 0, fmt.Errorf("flac.Stream.SeekTime: sample number %d out of range of time.Duration", first)
+	}
+	return time.Duration(ns), nil
+}
+
+// SeekFraction seeks to the frame containing the sample at the given fraction
+// of the total number of samples of the stream, between 0 (the first sample)
+// and 1 (the last sample); e.g. the position of a player's seek bar. The
+// fraction is converted to a sample number using the total number of samples
+// of StreamInfo, rounded down to the nearest sample. The return value specifies
+// the fraction of the first sample of the frame containing the sample.
+//
+// The stream must be created using NewSeek to enable seeking.
+func (stream *Stream) SeekFraction(p float64) (float64, error) {
+	nsamples := stream.Info.NSamples
+	if nsamples == 0 {
+		return 0, errors.New("flac.Stream.SeekFraction: unknown total number of samples")
+	}
+	if !(p >= 0 && p <= 1) {
+		return 0, fmt.Errorf("flac.Stream.SeekFraction: fraction %v outside of range [0, 1]", p)
+	}
+	// The fraction 1 specifies the last sample.
+	sampleNum := min(uint64(p*float64(nsamples)), nsamples-1)
+	first, err := stream.Seek(sampleNum)
+	if err != nil {
+		return 0, err
+	}
+	return float64(first) / float64(nsamples), nil
+}
+
+// mulDivRound returns x*y/z rounded to the nearest integer, using a 128-bit
+// intermediate product. The boolean return value reports whether the result
+// fits in 64 bits.
+func mulDivRound(x, y, z uint64) (uint64, bool) {
+	hi, lo := mathbits.Mul64(x, y)
+	// Add z/2 to round to nearest.
+	var carry uint64
+	lo, carry = mathbits.Add64(lo, z/2, 0)
+	hi += carry
+	if hi >= z {
+		return 0, false
+	}
+	q, _ := mathbits.Div64(hi, lo, z)
+	return q, true
+}
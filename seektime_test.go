@@ -0,0 +1,98 @@
+package flac_test
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestSeekTime(t *testing.T) {
+	f, err := os.Open("testdata/172960.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stream, err := flac.NewSeek(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 96 kHz stream of 43683 samples, in frames of 4096 samples.
+	golden := []struct {
+		d    time.Duration
+		want time.Duration
+		err  bool
+	}{
+		{d: 0, want: 0},
+		{d: 100 * time.Millisecond, want: 85333333}, // sample 9600 of frame at sample 8192
+		{d: 85328125, want: 85333333},               // sample 8191.5, rounded to 8192
+		{d: 85328124, want: 42666667},               // sample 8191.49999, rounded to 8191
+		{d: 455020833, want: 426666667},             // last sample
+		{d: time.Second, err: true},
+		{d: -time.Millisecond, err: true},
+	}
+	for _, g := range golden {
+		got, err := stream.SeekTime(g.d)
+		if g.err {
+			if err == nil {
+				t.Errorf("d=%v: expected error", g.d)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("d=%v: unable to seek; %v", g.d, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("d=%v: time offset mismatch; expected %v, got %v", g.d, g.want, got)
+		}
+	}
+}
+
+func TestSeekFraction(t *testing.T) {
+	f, err := os.Open("testdata/172960.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stream, err := flac.NewSeek(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const nsamples = 43683
+	golden := []struct {
+		p    float64
+		want uint64
+		err  bool
+	}{
+		{p: 0, want: 0},
+		{p: 0.5, want: 20480},
+		{p: 1, want: 40960}, // last sample
+		{p: -0.1, err: true},
+		{p: 1.1, err: true},
+		{p: math.NaN(), err: true},
+	}
+	for _, g := range golden {
+		got, err := stream.SeekFraction(g.p)
+		if g.err {
+			if err == nil {
+				t.Errorf("p=%v: expected error", g.p)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("p=%v: unable to seek; %v", g.p, err)
+			continue
+		}
+		if want := float64(g.want) / nsamples; got != want {
+			t.Errorf("p=%v: fraction mismatch; expected %v, got %v", g.p, want, got)
+		}
+		if pos := stream.SamplePosition(); pos != g.want {
+			t.Errorf("p=%v: sample position mismatch; expected %d, got %d", g.p, g.want, pos)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package flac
+
+import (
+	"github.com/mewkiz/flac/frame"
+)
+
+// SkipFrames skips the next n audio frames of the stream, without decoding
+// their audio samples (see frame.Frame.Skip); e.g. for fast-forward previews or
+// sparse analysis sampling of non-seekable streams. The frame headers and CRC
+// checksums of the skipped frames are validated as done by Stream.ParseNext.
+//
+// SkipFrames returns the number of frames skipped, which is less than n only
+// if an error occurred; it returns io.EOF if the stream ends before n frames
+// have been skipped. As for Stream.Next, the totals of the stream are not
+// reported once frames have been skipped (see DecodeOptions.OnTotals).
+func (stream *Stream) SkipFrames(n int) (int, error) {
+	// Stream totals are only computed from frames decoded by the stream.
+	stream.totals = nil
+	stream.md5sum = nil
+	stream.int16Frame = nil
+	var f frame.Frame
+	for i := 0; i < n; i++ {
+		offset := stream.frameOffset()
+		if err := stream.checkEnd(); err != nil {
+			return i, err
+		}
+		if err := frame.NewInto(stream.r, &f); err != nil {
+			return i, stream.truncated(err, stream.samplePos)
+		}
+		if stream.opts.Strict {
+			if err := stream.checkStrict(&f); err != nil {
+				return i, err
+			}
+		}
+		stream.advance(offset, &f)
+		if err := f.Skip(); err != nil {
+			return i, stream.truncated(err, stream.sampleNumber(&f))
+		}
+	}
+	return n, nil
+}
+
+// skipFrame reads the header of the next audio frame, and skips its subframes
+// (see frame.Frame.Skip); e.g. to locate the frame containing a given sample.
+// Unlike Stream.SkipFrames, the sample position of the stream and the state of
+// the decoding options (e.g. sample hashes, frame sizes and events) are left
+// unchanged, as the frames are not decoded by the stream.
+func (stream *Stream) skipFrame() (*frame.Frame, error) {
+	f, err := frame.New(stream.r)
+	if err != nil {
+		return nil, err
+	}
+	if f.BitsPerSample == 0 {
+		f.BitsPerSample = stream.Info.BitsPerSample
+	}
+	// The block size of fixed-blocksize frames locates their first sample.
+	if f.HasFixedBlockSize && f.BlockSize > stream.fixedBlockSize {
+		stream.fixedBlockSize = f.BlockSize
+	}
+	if err := f.Skip(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// scanFrame skips the next audio frame of the stream, as done by skipFrame, and
+// advances the sample position of the stream past the frame; e.g. to scan the
+// frames of the stream when seeking. As the frame is not decoded by the stream,
+// it is not accounted for by the decoder watchdog. scanFrame returns io.EOF at
+// the end of the stream.
+func (stream *Stream) scanFrame() (*frame.Frame, error) {
+	if err := stream.checkEnd(); err != nil {
+		return nil, err
+	}
+	f, err := stream.skipFrame()
+	if err != nil {
+		return nil, stream.truncated(err, stream.samplePos)
+	}
+	stream.samplePos = stream.sampleNumber(f) + uint64(f.BlockSize)
+	return f, nil
+}
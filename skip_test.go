@@ -0,0 +1,81 @@
+package flac_test
+
+import (
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestSkipFrames(t *testing.T) {
+	const path = "testdata/172960.flac"
+	want, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.Close()
+	var nframes int
+	for {
+		if _, err := want.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		nframes++
+	}
+
+	for _, skip := range []int{0, 1, 3, nframes - 1} {
+		ref, err := flac.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ref.Close()
+		var wantFrame []int32
+		for i := 0; i <= skip; i++ {
+			f, err := ref.ParseNext()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantFrame = f.Subframes[0].Samples
+		}
+		stream, err := flac.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.Close()
+		n, err := stream.SkipFrames(skip)
+		if err != nil {
+			t.Fatalf("skip %d: unable to skip frames; %v", skip, err)
+		}
+		if n != skip {
+			t.Errorf("skip %d: number of skipped frames mismatch; expected %d, got %d", skip, skip, n)
+		}
+		// The first frame following the skipped frames is decoded.
+		f, err := stream.ParseNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(f.Subframes[0].Samples, wantFrame) {
+			t.Errorf("skip %d: samples of frame %d mismatch", skip, skip)
+		}
+		if got, want := stream.SamplePosition(), ref.SamplePosition(); got != want {
+			t.Errorf("skip %d: sample position mismatch; expected %d, got %d", skip, want, got)
+		}
+	}
+
+	// Skipping past the end of the stream.
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	n, err := stream.SkipFrames(nframes + 10)
+	if err != io.EOF {
+		t.Errorf("error mismatch; expected io.EOF, got %v", err)
+	}
+	if n != nframes {
+		t.Errorf("number of skipped frames mismatch; expected %d, got %d", nframes, n)
+	}
+}
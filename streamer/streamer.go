@@ -0,0 +1,179 @@
+// Package streamer provides an audio streamer backed by a FLAC stream, for
+// consumption by the audio players of games and media applications.
+//
+// The Streamer type implements the Streamer, StreamSeeker and StreamSeekCloser
+// interfaces of github.com/gopxl/beep (and its predecessor
+// github.com/faiface/beep) without depending on them, as the interfaces are
+// satisfied structurally:
+//
+//	s, err := streamer.New(stream)
+//	format := beep.Format{
+//		SampleRate:  beep.SampleRate(s.SampleRate()),
+//		NumChannels: s.NumChannels(),
+//		Precision:   s.Precision(),
+//	}
+//	speaker.Play(s)
+//
+// The Transition type streams two FLAC streams in sequence, with a gapless
+// transition or a crossfade between them.
+package streamer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+// A Streamer streams the audio samples of a FLAC stream as stereo float64
+// samples in the range [-1, 1]. Mono streams are duplicated to both channels,
+// and only the first two channels of streams with more than two channels are
+// streamed.
+type Streamer struct {
+	// Underlying FLAC stream.
+	stream *flac.Stream
+	// Current audio frame; nil if no frame has been decoded.
+	f *frame.Frame
+	// Position of the next sample (per channel) of the current audio frame.
+	pos int
+	// Sample number of the next sample to stream.
+	samplePos int
+	// Scale factor to normalize samples to the range [-1, 1].
+	scale float64
+	// Specifies whether the streamer has been seeked to the end of the stream.
+	eof bool
+	// Sticky decoding error.
+	err error
+}
+
+// New returns a new streamer for the audio samples of the given FLAC stream.
+// The stream must be created using flac.NewSeek to enable seeking.
+func New(stream *flac.Stream) (*Streamer, error) {
+	bps := stream.Info.BitsPerSample
+	if bps < 1 || bps > 32 {
+		return nil, fmt.Errorf("streamer.New: invalid sample size %d", bps)
+	}
+	s := &Streamer{
+		stream: stream,
+		scale:  1 / float64(int64(1)<<(bps-1)),
+	}
+	return s, nil
+}
+
+// SampleRate returns the sample rate of the stream in Hz.
+func (s *Streamer) SampleRate() int {
+	return int(s.stream.Info.SampleRate)
+}
+
+// NumChannels returns the number of channels streamed, which is always 2.
+func (s *Streamer) NumChannels() int {
+	return 2
+}
+
+// Precision returns the number of bytes used to encode a single sample of the
+// stream.
+func (s *Streamer) Precision() int {
+	return (int(s.stream.Info.BitsPerSample) + 7) / 8
+}
+
+// Stream fills samples with stereo audio samples, and returns the number of
+// samples streamed. It returns ok=false once the stream is drained or a
+// decoding error occurs, in which case Err reports the error.
+func (s *Streamer) Stream(samples [][2]float64) (n int, ok bool) {
+	if s.err != nil || s.eof {
+		return 0, false
+	}
+	for n < len(samples) {
+		if s.f == nil || s.pos >= s.f.Subframes[0].NSamples {
+			if err := s.next(); err != nil {
+				if err != io.EOF {
+					s.err = err
+				}
+				return n, n > 0
+			}
+		}
+		left := s.f.Subframes[0].Samples
+		right := left
+		if len(s.f.Subframes) > 1 {
+			right = s.f.Subframes[1].Samples
+		}
+		for ; s.pos < s.f.Subframes[0].NSamples && n < len(samples); s.pos++ {
+			samples[n][0] = float64(left[s.pos]) * s.scale
+			samples[n][1] = float64(right[s.pos]) * s.scale
+			n++
+			s.samplePos++
+		}
+	}
+	return n, true
+}
+
+// next decodes the next audio frame.
+func (s *Streamer) next() error {
+	f, err := s.stream.ParseNext()
+	if err != nil {
+		return err
+	}
+	if len(f.Subframes) == 0 {
+		return errors.New("streamer.Streamer.Stream: frame without subframes")
+	}
+	s.f, s.pos = f, 0
+	return nil
+}
+
+// Err returns the error which caused Stream to stop streaming; or nil if the
+// stream was drained without error.
+func (s *Streamer) Err() error {
+	return s.err
+}
+
+// Len returns the total number of samples (per channel) of the stream; or 0 if
+// unknown.
+func (s *Streamer) Len() int {
+	return int(s.stream.Info.NSamples)
+}
+
+// Position returns the sample number of the next sample to stream.
+func (s *Streamer) Position() int {
+	return s.samplePos
+}
+
+// Seek seeks to the given sample number, in the range [0, Len()]; or to any
+// sample number of the stream if Len is unknown. The stream must be created
+// using flac.NewSeek to enable seeking.
+func (s *Streamer) Seek(p int) error {
+	// The range of sample numbers is validated by the underlying stream if the
+	// total number of samples is unknown.
+	known := s.Len() > 0
+	if p < 0 || (known && p > s.Len()) {
+		return fmt.Errorf("streamer.Streamer.Seek: sample number %d out of range [0, %d]", p, s.Len())
+	}
+	s.err = nil
+	if known && p == s.Len() {
+		// Seek to the end of the stream.
+		s.eof = true
+		s.samplePos = p
+		return nil
+	}
+	s.eof = false
+	start, err := s.stream.Seek(uint64(p))
+	if err != nil {
+		return fmt.Errorf("streamer.Streamer.Seek: %w", err)
+	}
+	// Decode the frame containing the sample, and skip the samples preceding it.
+	if err := s.next(); err != nil {
+		return fmt.Errorf("streamer.Streamer.Seek: %w", err)
+	}
+	s.pos = p - int(start)
+	if s.pos < 0 || s.pos >= s.f.Subframes[0].NSamples {
+		return fmt.Errorf("streamer.Streamer.Seek: sample number %d not contained within frame starting at sample %d", p, start)
+	}
+	s.samplePos = p
+	return nil
+}
+
+// Close closes the underlying FLAC stream.
+func (s *Streamer) Close() error {
+	return s.stream.Close()
+}
@@ -0,0 +1,139 @@
+package streamer_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/streamer"
+)
+
+// StreamSeekCloser mirrors the beep.StreamSeekCloser interface.
+type StreamSeekCloser interface {
+	Stream(samples [][2]float64) (n int, ok bool)
+	Err() error
+	Len() int
+	Position() int
+	Seek(p int) error
+	Close() error
+}
+
+var _ StreamSeekCloser = (*streamer.Streamer)(nil)
+
+func TestStreamer(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/172960.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.NewSeek(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := streamer.New(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Stream all samples in chunks.
+	var all [][2]float64
+	chunk := make([][2]float64, 1000)
+	for {
+		n, ok := s.Stream(chunk)
+		all = append(all, chunk[:n]...)
+		if !ok {
+			break
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != s.Len() {
+		t.Fatalf("sample count mismatch; expected %d, got %d", s.Len(), len(all))
+	}
+	if s.Position() != s.Len() {
+		t.Fatalf("position mismatch; expected %d, got %d", s.Len(), s.Position())
+	}
+
+	// Seek and compare against the samples streamed from the start.
+	for _, p := range []int{0, 100, 4095, 4096, 9000, 40960, s.Len() - 10} {
+		if err := s.Seek(p); err != nil {
+			t.Fatalf("seek to %d: %v", p, err)
+		}
+		if s.Position() != p {
+			t.Errorf("position mismatch after seek; expected %d, got %d", p, s.Position())
+		}
+		n, ok := s.Stream(chunk[:10])
+		if !ok || n != 10 {
+			t.Fatalf("seek to %d: unable to stream samples (n=%d, ok=%v)", p, n, ok)
+		}
+		for i := 0; i < n; i++ {
+			if chunk[i] != all[p+i] {
+				t.Errorf("seek to %d: sample %d mismatch; expected %v, got %v", p, p+i, all[p+i], chunk[i])
+				break
+			}
+		}
+	}
+
+	// Seek to the end.
+	if err := s.Seek(s.Len()); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := s.Stream(chunk); n != 0 || ok {
+		t.Errorf("expected drained streamer; got n=%d, ok=%v", n, ok)
+	}
+	if err := s.Seek(s.Len() + 1); err == nil {
+		t.Error("expected error for seek past end of stream")
+	}
+}
+
+func TestStreamerUnknownLen(t *testing.T) {
+	buf, err := os.ReadFile("../testdata/172960.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := decodeAll(t, "../testdata/172960.flac")
+	// Clear the total number of samples of StreamInfo; the lower 36 bits of the
+	// 8 bytes at offset 18, following the signature, the block header and the
+	// block and frame sizes.
+	buf[21] &^= 0x0F
+	for i := 22; i < 26; i++ {
+		buf[i] = 0
+	}
+	stream, err := flac.NewSeek(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := streamer.New(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 0 {
+		t.Fatalf("length mismatch; expected 0, got %d", s.Len())
+	}
+
+	chunk := make([][2]float64, 10)
+	for _, p := range []int{0, 100, 4096, 40960, len(want) - 10} {
+		if err := s.Seek(p); err != nil {
+			t.Fatalf("seek to %d: %v", p, err)
+		}
+		if s.Position() != p {
+			t.Errorf("position mismatch after seek; expected %d, got %d", p, s.Position())
+		}
+		n, ok := s.Stream(chunk)
+		if !ok || n != len(chunk) {
+			t.Fatalf("seek to %d: unable to stream samples (n=%d, ok=%v)", p, n, ok)
+		}
+		for i := 0; i < n; i++ {
+			if chunk[i] != want[p+i] {
+				t.Errorf("seek to %d: sample %d mismatch; expected %v, got %v", p, p+i, want[p+i], chunk[i])
+				break
+			}
+		}
+	}
+	if err := s.Seek(-1); err == nil {
+		t.Error("expected error for seek to negative sample number")
+	}
+}
@@ -0,0 +1,329 @@
+package streamer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/dsp/resample"
+)
+
+// TransitionOptions specifies the transition between two consecutive tracks of
+// a Transition.
+type TransitionOptions struct {
+	// Duration of the crossfade between the end of the first track and the
+	// start of the second track; or 0 for a gapless transition, in which the
+	// first sample of the second track immediately follows the last sample of
+	// the first track. The crossfade is shortened to the length of the shortest
+	// track.
+	Crossfade time.Duration
+	// Resample enables resampling of the second track to the sample rate of the
+	// first track. Otherwise, tracks with differing sample rates are rejected.
+	Resample bool
+	// Quality preset of the resampler.
+	Quality resample.Quality
+}
+
+// A Transition streams the audio samples of two FLAC streams in sequence, as a
+// single stream of stereo float64 samples in the range [-1, 1] at the sample
+// rate of the first stream; e.g. for gapless playback or crossfading between the
+// tracks of a playlist. The samples of the second stream are mixed with the
+// last samples of the first stream using equal-power fade curves.
+//
+// The Transition type implements the Streamer interface of github.com/gopxl/beep.
+type Transition struct {
+	// Sources of the first and second track.
+	first, second source
+	// Number of samples (per channel) of the crossfade.
+	fade int
+	// Buffered samples of the first track, holding back the last fade samples
+	// until the end of the first track is reached.
+	pending [][2]float64
+	// Scratch buffer of the samples of the second track during the crossfade.
+	buf [][2]float64
+	// Specifies whether the end of the first track has been reached.
+	firstDone bool
+	// Number of samples (per channel) of the crossfade, once the end of the
+	// first track has been reached; shorter than fade if the first track is
+	// shorter than the crossfade duration.
+	fadeLen int
+	// Number of samples (per channel) streamed.
+	samplePos int
+	// Total number of samples (per channel) to stream; or 0 if unknown.
+	length int
+	// Underlying streamers, closed by Close.
+	streamers []*Streamer
+	// Sticky decoding error.
+	err error
+}
+
+// NewTransition returns a new transition from the first to the second FLAC
+// stream, using the given options; a nil value specifies a gapless transition.
+func NewTransition(first, second *flac.Stream, opts *TransitionOptions) (*Transition, error) {
+	if opts == nil {
+		opts = &TransitionOptions{}
+	}
+	if opts.Crossfade < 0 {
+		return nil, fmt.Errorf("streamer.NewTransition: invalid crossfade duration %v", opts.Crossfade)
+	}
+	a, err := New(first)
+	if err != nil {
+		return nil, err
+	}
+	b, err := New(second)
+	if err != nil {
+		return nil, err
+	}
+	srcRate, dstRate := b.SampleRate(), a.SampleRate()
+	if dstRate <= 0 {
+		return nil, fmt.Errorf("streamer.NewTransition: invalid sample rate %d of first stream", dstRate)
+	}
+	t := &Transition{
+		first:     a,
+		second:    b,
+		fade:      int(opts.Crossfade.Seconds()*float64(dstRate) + 0.5),
+		streamers: []*Streamer{a, b},
+	}
+	// Number of samples of the second track, at the sample rate of the first
+	// track.
+	n := b.Len()
+	if srcRate != dstRate {
+		if !opts.Resample {
+			return nil, fmt.Errorf("streamer.NewTransition: sample rate mismatch; first stream %d Hz, second stream %d Hz", dstRate, srcRate)
+		}
+		r, err := resample.New(srcRate, dstRate, 2, opts.Quality)
+		if err != nil {
+			return nil, err
+		}
+		t.second = &resampledSource{src: b, r: r}
+		n = int((int64(n)*int64(dstRate) + int64(srcRate) - 1) / int64(srcRate))
+	}
+	if n > 0 && t.fade > n {
+		t.fade = n
+	}
+	if a.Len() > 0 && n > 0 {
+		t.length = a.Len() + n - min(t.fade, a.Len(), n)
+	}
+	return t, nil
+}
+
+// SampleRate returns the sample rate of the transition in Hz; i.e. the sample
+// rate of the first stream.
+func (t *Transition) SampleRate() int {
+	return t.streamers[0].SampleRate()
+}
+
+// NumChannels returns the number of channels streamed, which is always 2.
+func (t *Transition) NumChannels() int {
+	return 2
+}
+
+// Stream fills samples with stereo audio samples, and returns the number of
+// samples streamed. It returns ok=false once both streams are drained or a
+// decoding error occurs, in which case Err reports the error.
+func (t *Transition) Stream(samples [][2]float64) (n int, ok bool) {
+	if t.err != nil {
+		return 0, false
+	}
+	if !t.firstDone {
+		n = t.streamFirst(samples)
+		if t.err != nil {
+			return n, n > 0
+		}
+		if !t.firstDone {
+			t.samplePos += n
+			return n, true
+		}
+	}
+	// Second track, starting with the crossfade of the held back samples of
+	// the first track.
+	for n < len(samples) {
+		if len(t.pending) > 0 {
+			m := t.crossfade(samples[n:])
+			if t.err != nil {
+				break
+			}
+			n += m
+			continue
+		}
+		m, ok := t.second.Stream(samples[n:])
+		n += m
+		if !ok {
+			t.err = t.second.Err()
+			break
+		}
+	}
+	t.samplePos += n
+	return n, n > 0
+}
+
+// streamFirst streams the samples of the first track, holding back the last
+// fade samples for the crossfade.
+func (t *Transition) streamFirst(samples [][2]float64) (n int) {
+	for n < len(samples) {
+		// Available samples, excluding the held back samples.
+		if avail := len(t.pending) - t.fade; avail > 0 {
+			m := copy(samples[n:], t.pending[:avail])
+			t.pending = append(t.pending[:0], t.pending[m:]...)
+			n += m
+			continue
+		}
+		// Read more samples of the first track.
+		start := len(t.pending)
+		want := max(len(samples)-n, 1024)
+		t.pending = append(t.pending, make([][2]float64, want)...)
+		m, ok := t.first.Stream(t.pending[start:])
+		t.pending = t.pending[:start+m]
+		if !ok {
+			if err := t.first.Err(); err != nil {
+				t.err = err
+				return n
+			}
+			t.firstDone = true
+			t.fadeLen = len(t.pending)
+			return n
+		}
+	}
+	return n
+}
+
+// crossfade mixes the held back samples of the first track with the samples of
+// the second track, and returns the number of samples streamed.
+func (t *Transition) crossfade(samples [][2]float64) int {
+	m := min(len(samples), len(t.pending))
+	if cap(t.buf) < m {
+		t.buf = make([][2]float64, m)
+	}
+	buf := t.buf[:m]
+	k, ok := t.second.Stream(buf)
+	if !ok && k < m {
+		if err := t.second.Err(); err != nil {
+			t.err = err
+			return 0
+		}
+		// The second track, of unknown length, is shorter than the crossfade;
+		// the remaining held back samples are streamed unattenuated.
+		clear(buf[k:])
+	}
+	for i := range buf {
+		// Position within the crossfade in the range (0, 1).
+		pos := (float64(t.fadeLen-len(t.pending)+i) + 0.5) / float64(t.fadeLen)
+		gainOut := math.Cos(pos * math.Pi / 2)
+		gainIn := math.Sin(pos * math.Pi / 2)
+		if i >= k {
+			gainOut = 1
+		}
+		for c := range samples[i] {
+			samples[i][c] = t.pending[i][c]*gainOut + buf[i][c]*gainIn
+		}
+	}
+	t.pending = t.pending[m:]
+	if len(t.pending) == 0 {
+		t.pending = nil
+	}
+	return m
+}
+
+// Err returns the error which caused Stream to stop streaming; or nil if both
+// streams were drained without error.
+func (t *Transition) Err() error {
+	return t.err
+}
+
+// Len returns the total number of samples (per channel) of the transition; or
+// 0 if the number of samples of either stream is unknown.
+func (t *Transition) Len() int {
+	return t.length
+}
+
+// Position returns the number of samples (per channel) streamed.
+func (t *Transition) Position() int {
+	return t.samplePos
+}
+
+// Close closes the underlying FLAC streams.
+func (t *Transition) Close() error {
+	var err error
+	for _, s := range t.streamers {
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// source is a source of stereo audio samples.
+type source interface {
+	// Stream fills samples with stereo audio samples, and returns the number of
+	// samples streamed; ok=false once the source is drained.
+	Stream(samples [][2]float64) (n int, ok bool)
+	// Err returns the error which caused Stream to stop streaming.
+	Err() error
+}
+
+// resampledSource is a source of stereo audio samples resampled from an
+// underlying source.
+type resampledSource struct {
+	// Underlying source.
+	src source
+	// Stereo resampler.
+	r *resample.Resampler
+	// Buffered resampled samples, per channel.
+	out [][]float64
+	// Scratch buffer of the samples of the underlying source.
+	buf [][2]float64
+	// Specifies whether the underlying source has been drained and the
+	// resampler flushed.
+	done bool
+	// Sticky resampling error.
+	err error
+}
+
+// Stream fills samples with resampled stereo audio samples, and returns the
+// number of samples streamed.
+func (rs *resampledSource) Stream(samples [][2]float64) (n int, ok bool) {
+	for n < len(samples) {
+		if len(rs.out) > 0 && len(rs.out[0]) > 0 {
+			m := min(len(samples)-n, len(rs.out[0]))
+			for i := 0; i < m; i++ {
+				samples[n+i] = [2]float64{rs.out[0][i], rs.out[1][i]}
+			}
+			rs.out[0], rs.out[1] = rs.out[0][m:], rs.out[1][m:]
+			n += m
+			continue
+		}
+		if rs.done || rs.err != nil {
+			return n, n > 0
+		}
+		if rs.buf == nil {
+			rs.buf = make([][2]float64, 4096)
+		}
+		m, ok := rs.src.Stream(rs.buf)
+		in := [][]float64{make([]float64, m), make([]float64, m)}
+		for i, sample := range rs.buf[:m] {
+			in[0][i], in[1][i] = sample[0], sample[1]
+		}
+		out, err := rs.r.Process(in)
+		if err != nil {
+			rs.err = err
+			return n, n > 0
+		}
+		if !ok {
+			flushed := rs.r.Flush()
+			out[0] = append(out[0], flushed[0]...)
+			out[1] = append(out[1], flushed[1]...)
+			rs.done = true
+		}
+		rs.out = out
+	}
+	return n, true
+}
+
+// Err returns the error of the underlying source or the resampler.
+func (rs *resampledSource) Err() error {
+	if rs.err != nil {
+		return rs.err
+	}
+	return rs.src.Err()
+}
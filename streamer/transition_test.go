@@ -0,0 +1,144 @@
+package streamer_test
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/streamer"
+)
+
+// Streamer mirrors the beep.Streamer interface.
+type Streamer interface {
+	Stream(samples [][2]float64) (n int, ok bool)
+	Err() error
+}
+
+var _ Streamer = (*streamer.Transition)(nil)
+
+// open opens the given FLAC file from memory.
+func open(t *testing.T, path string) *flac.Stream {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.New(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+// streamAll streams all samples of s in chunks of the given size.
+func streamAll(t *testing.T, s Streamer, chunkSize int) [][2]float64 {
+	var all [][2]float64
+	chunk := make([][2]float64, chunkSize)
+	for {
+		n, ok := s.Stream(chunk)
+		all = append(all, chunk[:n]...)
+		if !ok {
+			break
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return all
+}
+
+// decodeAll returns all samples of the given FLAC file.
+func decodeAll(t *testing.T, path string) [][2]float64 {
+	s, err := streamer.New(open(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	return streamAll(t, s, 4096)
+}
+
+func TestTransitionGapless(t *testing.T) {
+	const (
+		first  = "../testdata/59996.flac"
+		second = "../testdata/220014.flac"
+	)
+	want := append(decodeAll(t, first), decodeAll(t, second)...)
+	for _, chunkSize := range []int{1, 1000, 8192} {
+		tr, err := streamer.NewTransition(open(t, first), open(t, second), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := streamAll(t, tr, chunkSize)
+		tr.Close()
+		if len(got) != len(want) || len(got) != tr.Len() {
+			t.Fatalf("chunk size %d: sample count mismatch; expected %d (Len %d), got %d", chunkSize, len(want), tr.Len(), len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("chunk size %d: sample %d mismatch; expected %v, got %v", chunkSize, i, want[i], got[i])
+			}
+		}
+	}
+}
+
+func TestTransitionCrossfade(t *testing.T) {
+	const path = "../testdata/59996.flac"
+	a := decodeAll(t, path)
+	opts := &streamer.TransitionOptions{Crossfade: 50 * time.Millisecond}
+	tr, err := streamer.NewTransition(open(t, path), open(t, path), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	got := streamAll(t, tr, 1000)
+	const fade = 2205 // 50 ms at 44.1 kHz
+	if want := 2*len(a) - fade; len(got) != want || len(got) != tr.Len() {
+		t.Fatalf("sample count mismatch; expected %d (Len %d), got %d", want, tr.Len(), len(got))
+	}
+	start := len(a) - fade
+	for i := range got {
+		var want [2]float64
+		switch {
+		case i < start:
+			want = a[i]
+		case i < len(a):
+			pos := (float64(i-start) + 0.5) / fade
+			gainOut, gainIn := math.Cos(pos*math.Pi/2), math.Sin(pos*math.Pi/2)
+			for c := range want {
+				want[c] = a[i][c]*gainOut + a[i-start][c]*gainIn
+			}
+		default:
+			want = a[i-start]
+		}
+		for c := range want {
+			if math.Abs(got[i][c]-want[c]) > 1e-12 {
+				t.Fatalf("sample %d mismatch; expected %v, got %v", i, want, got[i])
+			}
+		}
+	}
+}
+
+func TestTransitionResample(t *testing.T) {
+	const (
+		first  = "../testdata/59996.flac" // 44.1 kHz
+		second = "../testdata/19875.flac" // 48 kHz
+	)
+	if _, err := streamer.NewTransition(open(t, first), open(t, second), nil); err == nil {
+		t.Fatal("expected sample rate mismatch error, got nil")
+	}
+	opts := &streamer.TransitionOptions{Crossfade: 10 * time.Millisecond, Resample: true}
+	tr, err := streamer.NewTransition(open(t, first), open(t, second), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	if tr.SampleRate() != 44100 {
+		t.Errorf("sample rate mismatch; expected 44100, got %d", tr.SampleRate())
+	}
+	got := streamAll(t, tr, 1000)
+	if len(got) != tr.Len() {
+		t.Errorf("sample count mismatch; expected %d, got %d", tr.Len(), len(got))
+	}
+}
@@ -0,0 +1,321 @@
+// Package streamutil implements utilities for decoding FLAC streams from
+// network sources.
+//
+// A Reader reads a remote source through an OpenFunc, and transparently
+// recovers from transient failures by reopening the source at the offset of
+// the next byte to read, with exponential backoff between attempts; as such,
+// only persistent failures are reported to the decoder. HTTPOpener opens
+// remote sources using GET requests, resuming with Range requests when
+// supported by the server.
+//
+//	req, err := http.NewRequest("GET", url, nil)
+//	r := streamutil.NewReader(streamutil.HTTPOpener(nil, req), nil)
+//	defer r.Close()
+//	stream, err := flac.New(r)
+package streamutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An OpenFunc opens a remote source for reading, starting at byte offset off;
+// e.g. using a GET request with the header "Range: bytes=off-".
+type OpenFunc func(off int64) (io.ReadCloser, error)
+
+// Options specifies the retry behaviour of a Reader. The zero value specifies
+// the default options.
+type Options struct {
+	// Maximum number of consecutive retries of a failed read, before the
+	// failure is reported; 5 by default. A negative value disables retries.
+	MaxRetries int
+	// Delay before the first retry; 100 ms by default. The delay is doubled on
+	// each consecutive retry, up to MaxBackoff.
+	MinBackoff time.Duration
+	// Maximum delay between retries; 5 s by default.
+	MaxBackoff time.Duration
+	// Size in bytes of the remote source, if known; 0 if unknown. A source
+	// ending before Size is treated as a transient failure, and seeking
+	// relative to the end of the source requires Size.
+	Size int64
+	// Retryable, if non-nil, reports whether the given error is transient, and
+	// the failed read should be retried; IsTransient by default.
+	Retryable func(err error) bool
+	// OnRetry, if non-nil, is called before each retry with the number of the
+	// retry, starting at 1, and the error of the failed read; e.g. for logging.
+	OnRetry func(retry int, err error)
+}
+
+const (
+	// defaultMaxRetries specifies the default maximum number of consecutive
+	// retries.
+	defaultMaxRetries = 5
+	// defaultMinBackoff specifies the default delay before the first retry.
+	defaultMinBackoff = 100 * time.Millisecond
+	// defaultMaxBackoff specifies the default maximum delay between retries.
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// A Reader reads a remote source, retrying failed reads by reopening the
+// source at the offset of the next byte to read. A Reader is not safe for
+// concurrent use by multiple goroutines.
+type Reader struct {
+	// Opens the remote source at a given offset.
+	open OpenFunc
+	// Retry options.
+	opts Options
+	// Body of the remote source, positioned at off; nil if not opened.
+	body io.ReadCloser
+	// Byte offset of the next byte to read.
+	off int64
+	// Sticky error of a persistent failure.
+	err error
+}
+
+// NewReader returns a new Reader which reads the remote source opened by open,
+// with the given options; a nil value specifies the default options. The
+// remote source is opened on the first read.
+func NewReader(open OpenFunc, opts *Options) *Reader {
+	if opts == nil {
+		opts = &Options{}
+	}
+	r := &Reader{open: open, opts: *opts}
+	switch {
+	case r.opts.MaxRetries == 0:
+		r.opts.MaxRetries = defaultMaxRetries
+	case r.opts.MaxRetries < 0:
+		r.opts.MaxRetries = 0
+	}
+	if r.opts.MinBackoff <= 0 {
+		r.opts.MinBackoff = defaultMinBackoff
+	}
+	if r.opts.MaxBackoff <= 0 {
+		r.opts.MaxBackoff = defaultMaxBackoff
+	}
+	if r.opts.Retryable == nil {
+		r.opts.Retryable = IsTransient
+	}
+	return r
+}
+
+// Offset returns the byte offset of the next byte to read.
+func (r *Reader) Offset() int64 {
+	return r.off
+}
+
+// Read reads up to len(p) bytes into p. Transient failures are retried by
+// reopening the remote source at the current offset; the error of a
+// persistent failure, or of a failure exceeding the maximum number of retries,
+// is returned by subsequent reads until the Reader is seeked.
+func (r *Reader) Read(p []byte) (n int, err error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for retry := 0; ; retry++ {
+		if err != nil {
+			if !r.opts.Retryable(err) {
+				r.err = fmt.Errorf("streamutil.Reader.Read: unable to read at offset %d; %w", r.off, err)
+				return 0, r.err
+			}
+			if retry > r.opts.MaxRetries {
+				r.err = fmt.Errorf("streamutil.Reader.Read: unable to read at offset %d after %d retries; %w", r.off, r.opts.MaxRetries, err)
+				return 0, r.err
+			}
+			if r.opts.OnRetry != nil {
+				r.opts.OnRetry(retry, err)
+			}
+			time.Sleep(r.backoff(retry))
+		}
+		if r.body == nil {
+			if r.body, err = r.open(r.off); err != nil {
+				r.body = nil
+				continue
+			}
+		}
+		n, err = r.body.Read(p)
+		r.off += int64(n)
+		if err == io.EOF && (r.opts.Size == 0 || r.off >= r.opts.Size) {
+			return n, io.EOF
+		}
+		if err != nil {
+			r.body.Close()
+			r.body = nil
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		// Errors following a successful read are retried by the next read.
+		if n > 0 || err == nil {
+			return n, nil
+		}
+	}
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (r *Reader) backoff(retry int) time.Duration {
+	d := r.opts.MinBackoff
+	for i := 1; i < retry && d < r.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.opts.MaxBackoff)
+}
+
+// Seek sets the offset of the next Read, as specified by io.Seeker, and clears
+// the error of a previous persistent failure. The remote source is reopened at
+// the new offset on the next read. Seeking relative to the end of the source
+// requires Options.Size.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		if r.opts.Size == 0 {
+			return 0, errors.New("streamutil.Reader.Seek: seek relative to end of source of unknown size")
+		}
+		offset += r.opts.Size
+	default:
+		return 0, errors.New("streamutil.Reader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("streamutil.Reader.Seek: negative position")
+	}
+	if offset != r.off && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.off = offset
+	r.err = nil
+	return offset, nil
+}
+
+// Close closes the remote source.
+func (r *Reader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+// IsTransient reports whether err is a transient failure. All errors are
+// transient, except for the cancellation of a context, mismatched Content-Range
+// responses (see RangeError), and HTTP client errors (status codes 4xx) other
+// than 408 Request Timeout and 429 Too Many Requests.
+func IsTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rerr *RangeError
+	if errors.As(err, &rerr) {
+		return false
+	}
+	var serr *StatusError
+	if errors.As(err, &serr) {
+		switch serr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return serr.StatusCode < 400 || serr.StatusCode >= 500
+	}
+	return true
+}
+
+// A StatusError reports an unexpected status code of an HTTP response.
+type StatusError struct {
+	// HTTP status code of the response.
+	StatusCode int
+}
+
+// Error returns an error string describing the status code.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// A RangeError reports that the Content-Range of an HTTP response does not
+// match the offset of the Range request; e.g. as the server ignores the
+// requested range. RangeErrors are persistent failures.
+type RangeError struct {
+	// Content-Range header value of the response.
+	ContentRange string
+	// Requested byte offset.
+	Offset int64
+}
+
+// Error returns an error string describing the mismatch.
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("streamutil.HTTPOpener: Content-Range %q mismatch for offset %d", e.ContentRange, e.Offset)
+}
+
+// HTTPOpener returns an OpenFunc which opens the remote source of the given
+// GET request using client; a nil client specifies http.DefaultClient. The
+// request is cloned for each attempt, and may specify headers such as
+// authorization and a context to cancel the requests.
+//
+// Sources are opened at non-zero offsets using Range requests. If the server
+// does not support Range requests, the source is read from the start, and the
+// data preceding the offset is discarded.
+func HTTPOpener(client *http.Client, req *http.Request) OpenFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(off int64) (io.ReadCloser, error) {
+		req := req.Clone(req.Context())
+		if off > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", off))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusPartialContent && off > 0:
+			if start, ok := rangeStart(resp.Header.Get("Content-Range")); !ok || start != off {
+				resp.Body.Close()
+				return nil, &RangeError{ContentRange: resp.Header.Get("Content-Range"), Offset: off}
+			}
+		case resp.StatusCode == http.StatusOK:
+			// Range requests not supported.
+			if _, err := io.CopyN(io.Discard, resp.Body, off); err != nil {
+				resp.Body.Close()
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+		case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && off > 0:
+			// Offset at the end of the source.
+			resp.Body.Close()
+			return http.NoBody, nil
+		default:
+			resp.Body.Close()
+			return nil, &StatusError{StatusCode: resp.StatusCode}
+		}
+		return resp.Body, nil
+	}
+}
+
+// rangeStart returns the first byte offset of the given Content-Range header
+// value; e.g. 100 for "bytes 100-199/200".
+func rangeStart(contentRange string) (int64, bool) {
+	s, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	s, _, ok = strings.Cut(s, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(s, 10, 64)
+	return start, err == nil
+}
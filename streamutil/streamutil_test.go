@@ -0,0 +1,204 @@
+package streamutil_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/streamutil"
+)
+
+// errFlaky is the transient error of a flaky source.
+var errFlaky = errors.New("connection reset")
+
+// flaky is a remote source whose bodies fail after n bytes.
+type flaky struct {
+	data []byte
+	n    int
+	// Byte offsets of opened bodies.
+	opens []int64
+}
+
+// open opens the source at offset off.
+func (f *flaky) open(off int64) (io.ReadCloser, error) {
+	f.opens = append(f.opens, off)
+	end := min(off+int64(f.n), int64(len(f.data)))
+	r := io.MultiReader(bytes.NewReader(f.data[off:end]), iotest.ErrReader(errFlaky))
+	if end == int64(len(f.data)) {
+		r = bytes.NewReader(f.data[off:])
+	}
+	return io.NopCloser(r), nil
+}
+
+// fastRetries specifies options which retry without delay.
+var fastRetries = &streamutil.Options{MinBackoff: time.Nanosecond}
+
+func TestReader(t *testing.T) {
+	data, err := os.ReadFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &flaky{data: data, n: 1000}
+	opts := *fastRetries
+	opts.Size = int64(len(data))
+	r := streamutil.NewReader(src.open, &opts)
+	if err := iotest.TestReader(r, data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoding recovers from transient failures.
+	var retries int
+	opts.Size = 0
+	opts.OnRetry = func(retry int, err error) {
+		if retry != 1 || !errors.Is(err, errFlaky) {
+			t.Errorf("retry mismatch; expected retry 1 of %v, got retry %d of %v", errFlaky, retry, err)
+		}
+		retries++
+	}
+	src = &flaky{data: data, n: 4096}
+	r = streamutil.NewReader(src.open, &opts)
+	defer r.Close()
+	stream, err := flac.NewStream(r, flac.WithMD5())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err != io.EOF {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	if want := (len(data) - 1) / 4096; retries != want {
+		t.Errorf("number of retries mismatch; expected %d, got %d", want, retries)
+	}
+	for i, off := range src.opens {
+		if want := int64(i) * 4096; off != want {
+			t.Errorf("offset mismatch of reopened source %d; expected %d, got %d", i, want, off)
+		}
+	}
+}
+
+func TestReaderPersistent(t *testing.T) {
+	// Failures exceeding the maximum number of retries.
+	var nopens int
+	opts := *fastRetries
+	opts.MaxRetries = 3
+	r := streamutil.NewReader(func(off int64) (io.ReadCloser, error) {
+		nopens++
+		return nil, errFlaky
+	}, &opts)
+	if _, err := r.Read(make([]byte, 10)); !errors.Is(err, errFlaky) {
+		t.Errorf("error mismatch; expected %v, got %v", errFlaky, err)
+	}
+	if nopens != 1+3 {
+		t.Errorf("number of attempts mismatch; expected %d, got %d", 1+3, nopens)
+	}
+	// The error is sticky.
+	if _, err := r.Read(make([]byte, 10)); !errors.Is(err, errFlaky) || nopens != 1+3 {
+		t.Errorf("error mismatch of subsequent read; expected %v, got %v", errFlaky, err)
+	}
+
+	// Persistent failures are not retried.
+	nopens = 0
+	r = streamutil.NewReader(func(off int64) (io.ReadCloser, error) {
+		nopens++
+		return nil, &streamutil.StatusError{StatusCode: http.StatusNotFound}
+	}, fastRetries)
+	var serr *streamutil.StatusError
+	if _, err := r.Read(make([]byte, 10)); !errors.As(err, &serr) || serr.StatusCode != http.StatusNotFound {
+		t.Errorf("error mismatch; expected status %d, got %v", http.StatusNotFound, err)
+	}
+	if nopens != 1 {
+		t.Errorf("number of attempts mismatch; expected 1, got %d", nopens)
+	}
+}
+
+func TestHTTPOpener(t *testing.T) {
+	data, err := os.ReadFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ranges := range []bool{true, false} {
+		// Responses are cut short after 5000 bytes; only the first response if
+		// Range requests are not supported.
+		var nreqs, ranged int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nreqs++
+			start, n := 0, len(data)
+			if ranges || nreqs == 1 {
+				n = 5000
+			}
+			if ranges && r.Header.Get("Range") != "" {
+				ranged++
+				s := r.Header.Get("Range")[len("bytes="):]
+				start, _ = strconv.Atoi(s[:len(s)-1])
+				w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(data)-1)+"/"+strconv.Itoa(len(data)))
+				w.Header().Set("Content-Length", strconv.Itoa(len(data)-start))
+				w.WriteHeader(http.StatusPartialContent)
+			} else {
+				w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			}
+			w.Write(data[start:min(start+n, len(data))])
+		}))
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := streamutil.NewReader(streamutil.HTTPOpener(srv.Client(), req), fastRetries)
+		got, err := io.ReadAll(r)
+		r.Close()
+		srv.Close()
+		if err != nil {
+			t.Errorf("ranges %v: unable to read source; %v", ranges, err)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("ranges %v: data mismatch", ranges)
+		}
+		if want := (len(data) - 1) / 5000; ranges && ranged != want {
+			t.Errorf("number of Range requests mismatch; expected %d, got %d", want, ranged)
+		}
+		if !ranges && nreqs != 2 {
+			t.Errorf("number of requests mismatch; expected 2, got %d", nreqs)
+		}
+	}
+}
+
+func TestHTTPOpenerRangeMismatch(t *testing.T) {
+	// Responses to Range requests start at the beginning of the source.
+	var nreqs int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nreqs++
+		w.Header().Set("Content-Range", "bytes 0-99/100")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(make([]byte, 100))
+	}))
+	defer srv.Close()
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := streamutil.NewReader(streamutil.HTTPOpener(srv.Client(), req), fastRetries)
+	defer r.Close()
+	if _, err := r.Seek(50, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var rerr *streamutil.RangeError
+	if _, err := r.Read(make([]byte, 10)); !errors.As(err, &rerr) || rerr.Offset != 50 {
+		t.Errorf("error mismatch; expected Content-Range mismatch for offset 50, got %v", err)
+	}
+	// Mismatched responses are not retried.
+	if nreqs != 1 {
+		t.Errorf("number of requests mismatch; expected 1, got %d", nreqs)
+	}
+}
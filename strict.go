@@ -0,0 +1,32 @@
+package flac
+
+import (
+	"github.com/mewkiz/flac/frame"
+)
+
+// checkStrict validates the header of the given frame against the rules of the
+// FLAC format which depend on the surrounding stream, as enforced in strict
+// mode; see DecodeOptions.Strict. The rules which are local to the frame header
+// are validated by the frame parser, except for the rules of the targeted
+// revision of the specification; see DecodeOptions.Spec.
+func (stream *Stream) checkStrict(f *frame.Frame) error {
+	if err := f.CheckSpec(stream.opts.Spec); err != nil {
+		return err
+	}
+	if stream.shortFrame != 0 {
+		// Only the last frame may hold fewer than 16 samples.
+		return &frame.HeaderError{Rule: frame.RuleBlockSize, Value: uint64(stream.shortFrame)}
+	}
+	if f.HasFixedBlockSize && f.Num >= 1<<31 {
+		return &frame.HeaderError{Rule: frame.RuleFrameNumber, Value: f.Num}
+	}
+	if f.BlockSize < frame.MinBlockSize {
+		// The frame is the last frame if the total number of samples is known
+		// and reached; otherwise, any frame following it is rejected.
+		if end := stream.sampleNumber(f) + uint64(f.BlockSize); stream.Info.NSamples != 0 && end < stream.Info.NSamples {
+			return &frame.HeaderError{Rule: frame.RuleBlockSize, Value: uint64(f.BlockSize)}
+		}
+		stream.shortFrame = f.BlockSize
+	}
+	return nil
+}
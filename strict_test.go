@@ -0,0 +1,178 @@
+package flac_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestStrictStreamInfo(t *testing.T) {
+	const path = "testdata/love.flac"
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Minimum block size of StreamInfo exceeding the maximum block size; the
+	// StreamInfo body starts at offset 8, after the FLAC signature and the
+	// metadata block header.
+	buf[8], buf[9] = 0xFF, 0xFF
+
+	// Accepted by default.
+	stream, err := flac.NewWithOptions(bytes.NewReader(buf), nil)
+	if err != nil {
+		t.Fatalf("%q: unexpected error in default mode; %v", path, err)
+	}
+	if _, err := stream.ParseNext(); err != nil && err != io.EOF {
+		t.Errorf("%q: unable to decode frame in default mode; %v", path, err)
+	}
+
+	// Rejected in strict mode.
+	_, err = flac.NewWithOptions(bytes.NewReader(buf), &flac.DecodeOptions{Strict: true})
+	if !errors.Is(err, meta.ErrInvalidStreamInfo) {
+		t.Errorf("%q: expected ErrInvalidStreamInfo in strict mode, got %v", path, err)
+	}
+}
+
+func TestEncoderStreamInfo(t *testing.T) {
+	info := &meta.StreamInfo{BlockSizeMin: 4096, BlockSizeMax: 4096, SampleRate: 44100, NChannels: 0, BitsPerSample: 16}
+	if _, err := flac.NewEncoder(io.Discard, info); !errors.Is(err, meta.ErrInvalidStreamInfo) {
+		t.Errorf("expected ErrInvalidStreamInfo for StreamInfo without channels, got %v", err)
+	}
+}
+
+func TestStrictFrameHeader(t *testing.T) {
+	// newFrame returns a verbatim mono frame of the given block size and frame
+	// number.
+	newFrame := func(blockSize uint16, num uint64) []byte {
+		subframe := &frame.Subframe{
+			SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+			Samples:   make([]int32, blockSize),
+			NSamples:  int(blockSize),
+		}
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         blockSize,
+				SampleRate:        44100,
+				Channels:          frame.ChannelsMono,
+				BitsPerSample:     16,
+				Num:               num,
+			},
+			Subframes: []*frame.Subframe{subframe},
+		}
+		data, err := flac.MarshalFrame(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	golden := []struct {
+		name     string
+		nsamples uint64
+		frames   [][]byte
+		rule     frame.HeaderRule
+	}{
+		// Frame of a single sample preceding the last frame; rejected once the
+		// following frame is parsed if the total number of samples is unknown.
+		{name: "short frame", nsamples: 17, frames: [][]byte{newFrame(1, 0), newFrame(16, 1)}, rule: frame.RuleBlockSize},
+		{name: "short frame of unknown length stream", frames: [][]byte{newFrame(1, 0), newFrame(16, 1)}, rule: frame.RuleBlockSize},
+		{name: "frame number exceeding 31 bits", frames: [][]byte{newFrame(16, 1<<31)}, rule: frame.RuleFrameNumber},
+	}
+	for _, g := range golden {
+		info := &meta.StreamInfo{
+			BlockSizeMin:  16,
+			BlockSizeMax:  16,
+			SampleRate:    44100,
+			NChannels:     1,
+			BitsPerSample: 16,
+			NSamples:      g.nsamples,
+		}
+		buf, err := flac.MarshalMetadata(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range g.frames {
+			buf = append(buf, data...)
+		}
+		for _, strict := range []bool{false, true} {
+			stream, err := flac.NewWithOptions(bytes.NewReader(buf), &flac.DecodeOptions{Strict: strict})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for {
+				if _, err = stream.ParseNext(); err != nil {
+					break
+				}
+			}
+			var e *frame.HeaderError
+			switch {
+			case !strict && err != io.EOF:
+				t.Errorf("%s: unexpected error in default mode; %v", g.name, err)
+			case strict && !errors.As(err, &e):
+				t.Errorf("%s: expected *frame.HeaderError in strict mode, got %v", g.name, err)
+			case strict && e.Rule != g.rule:
+				t.Errorf("%s: rule mismatch; expected %d, got %d", g.name, g.rule, e.Rule)
+			}
+		}
+	}
+}
+
+func TestStrictSpec(t *testing.T) {
+	info := &meta.StreamInfo{
+		BlockSizeMin:  16,
+		BlockSizeMax:  16,
+		SampleRate:    44100,
+		NChannels:     1,
+		BitsPerSample: 32,
+		NSamples:      16,
+	}
+	buf, err := flac.MarshalMetadata(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Verbatim frame of 32 bits-per-sample, stored in the frame header using
+	// the bit pattern (111) of RFC 9639.
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         16,
+			SampleRate:        44100,
+			Channels:          frame.ChannelsMono,
+			BitsPerSample:     32,
+		},
+		Subframes: []*frame.Subframe{{
+			SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+			Samples:   make([]int32, 16),
+			NSamples:  16,
+		}},
+	}
+	data, err := flac.MarshalFrame(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf = append(buf, data...)
+	for _, spec := range []frame.Spec{frame.SpecRFC9639, frame.SpecXiph} {
+		stream, err := flac.NewWithOptions(bytes.NewReader(buf), &flac.DecodeOptions{Strict: true, Spec: spec})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = stream.ParseNext()
+		var e *frame.HeaderError
+		switch spec {
+		case frame.SpecRFC9639:
+			if err != nil {
+				t.Errorf("%v: unable to decode frame of 32 bits-per-sample; %v", spec, err)
+			}
+		case frame.SpecXiph:
+			if !errors.As(err, &e) || e.Rule != frame.RuleReservedBitsPerSample {
+				t.Errorf("%v: expected reserved sample size error, got %v", spec, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package flac
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// CheckSubset reports whether the stream conforms to the streamable subset of
+// the FLAC format. It validates the StreamInfo metadata block and parses the
+// remaining audio frames of the stream, validating each frame in turn. Errors
+// caused by subset violations wrap frame.ErrNotSubset.
+//
+// Note: CheckSubset consumes the audio frames of the stream.
+func (stream *Stream) CheckSubset() error {
+	if err := checkInfoSubset(stream.Info); err != nil {
+		return fmt.Errorf("flac.Stream.CheckSubset: %w", err)
+	}
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := f.CheckSubset(stream.Info.SampleRate); err != nil {
+			return fmt.Errorf("flac.Stream.CheckSubset: frame at sample %d; %w", f.SampleNumber(), err)
+		}
+	}
+}
+
+// checkInfoSubset reports whether the stream properties described by the given
+// StreamInfo metadata block conform to the streamable subset of the FLAC
+// format. Errors returned by checkInfoSubset wrap frame.ErrNotSubset, and are
+// prefixed by the caller.
+func checkInfoSubset(info *meta.StreamInfo) error {
+	if info.SampleRate == 0 || info.SampleRate > frame.MaxSampleRate {
+		return fmt.Errorf("%w; invalid sample rate (%d)", frame.ErrNotSubset, info.SampleRate)
+	}
+	if info.NChannels < 1 || info.NChannels > frame.MaxChannels {
+		return fmt.Errorf("%w; invalid number of channels (%d)", frame.ErrNotSubset, info.NChannels)
+	}
+	if info.BitsPerSample > frame.SubsetMaxBitsPerSample {
+		return fmt.Errorf("%w; sample size (%d) exceeds %d bits-per-sample", frame.ErrNotSubset, info.BitsPerSample, frame.SubsetMaxBitsPerSample)
+	}
+	if max := frame.SubsetMaxBlockSizeFor(info.SampleRate); int(info.BlockSizeMax) > max {
+		return fmt.Errorf("%w; maximum block size (%d) exceeds %d samples at %d Hz", frame.ErrNotSubset, info.BlockSizeMax, max, info.SampleRate)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package flac_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestEncodeSubset(t *testing.T) {
+	const path = "testdata/love.flac"
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	out := new(bytes.Buffer)
+	opts := &flac.EncodeOptions{Subset: true}
+	enc, err := flac.NewEncoderWithOptions(out, stream.Info, opts, stream.Blocks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		// Leave the sample rate and sample size unspecified, for the encoder to
+		// fill in.
+		f.SampleRate = 0
+		f.BitsPerSample = 0
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := flac.New(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.CheckSubset(); err != nil {
+		t.Fatalf("%q: unexpected subset violation of encoded stream; %v", path, err)
+	}
+}
+
+func TestEncodeSubsetViolation(t *testing.T) {
+	// Block size exceeds 4608 samples at 44.1 kHz.
+	info := &meta.StreamInfo{
+		BlockSizeMin:  8192,
+		BlockSizeMax:  8192,
+		SampleRate:    44100,
+		NChannels:     1,
+		BitsPerSample: 16,
+	}
+	opts := &flac.EncodeOptions{Subset: true}
+	if _, err := flac.NewEncoderWithOptions(io.Discard, info, opts); !errors.Is(err, frame.ErrNotSubset) {
+		t.Fatalf("expected subset violation of StreamInfo, got %v", err)
+	}
+
+	info.BlockSizeMin = 4096
+	info.BlockSizeMax = 4096
+	enc, err := flac.NewEncoderWithOptions(io.Discard, info, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subframe := &frame.Subframe{
+		SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+		Samples:   make([]int32, 8192),
+		NSamples:  8192,
+	}
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         8192,
+			Channels:          frame.ChannelsMono,
+		},
+		Subframes: []*frame.Subframe{subframe},
+	}
+	if err := enc.WriteFrame(f); !errors.Is(err, frame.ErrNotSubset) {
+		t.Fatalf("expected subset violation of frame, got %v", err)
+	}
+}
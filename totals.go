@@ -0,0 +1,115 @@
+package flac
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/pkg/errutil"
+)
+
+// StreamTotals holds the totals of a stream computed by decoding its audio
+// frames, as reported to DecodeOptions.OnTotals; e.g. for streams encoded to
+// non-seekable outputs, whose StreamInfo block leaves them unset.
+type StreamTotals struct {
+	// Total number of samples (per channel) of the stream.
+	NSamples uint64 `json:"nsamples"`
+	// MD5 checksum of the decoded audio samples.
+	MD5sum [md5.Size]uint8 `json:"md5"`
+}
+
+// ReadTotals reads the stream totals stored in the given sidecar file.
+func ReadTotals(path string) (*StreamTotals, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	totals := &StreamTotals{}
+	if err := json.Unmarshal(buf, totals); err != nil {
+		return nil, fmt.Errorf("flac.ReadTotals: invalid stream totals %q; %w", path, err)
+	}
+	return totals, nil
+}
+
+// WriteFile writes the stream totals to the given sidecar file. The file is
+// replaced atomically, as done by Checkpoint.WriteFile.
+func (totals *StreamTotals) WriteFile(path string) error {
+	buf, err := json.Marshal(totals)
+	if err != nil {
+		return errutil.Err(err)
+	}
+	return writeFileAtomic(path, buf)
+}
+
+// maxNSamples is the largest total number of samples storable in the 36-bit
+// field of the StreamInfo block.
+const maxNSamples = 1<<36 - 1
+
+// PatchTotals patches the total number of samples and the MD5 checksum of the
+// StreamInfo block of the FLAC file at path in place, with the given stream
+// totals; e.g. as read from a sidecar file written when the file was decoded,
+// avoiding a second decode of the file.
+func PatchTotals(path string, totals *StreamTotals) error {
+	if totals.NSamples > maxNSamples {
+		return fmt.Errorf("flac.PatchTotals: total number of samples (%d) exceeds maximum (%d)", totals.NSamples, uint64(maxNSamples))
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	offset, err := md5Offset(f)
+	if err != nil {
+		return err
+	}
+	// The 36-bit total number of samples ends the 8 bytes preceding the MD5
+	// checksum, following the sample rate, channel count and sample size.
+	var buf [8 + md5.Size]byte
+	if _, err := f.ReadAt(buf[:8], offset-8); err != nil {
+		return err
+	}
+	x := binary.BigEndian.Uint64(buf[:8])
+	x = x&^maxNSamples | totals.NSamples
+	binary.BigEndian.PutUint64(buf[:8], x)
+	copy(buf[8:], totals.MD5sum[:])
+	if _, err := f.WriteAt(buf[:], offset-8); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// totalsState tracks the totals of the audio frames decoded so far.
+type totalsState struct {
+	// Total number of samples (per channel).
+	nsamples uint64
+	// MD5 running hash of the decoded audio samples; updated as one of the
+	// sample hashes of the stream.
+	md5sum hash.Hash
+}
+
+// addTotals adds the given decoded frame to the stream totals, if tracked.
+func (stream *Stream) addTotals(f *frame.Frame) {
+	if stream.totals == nil {
+		return
+	}
+	// The MD5 running hash is updated by the sample hashes of the stream.
+	stream.totals.nsamples += uint64(f.BlockSize)
+}
+
+// reportTotals reports the stream totals to DecodeOptions.OnTotals once the
+// end of the stream is reached, as signaled by the given error of parsing the
+// next frame.
+func (stream *Stream) reportTotals(err error) {
+	if stream.totals == nil || err != io.EOF {
+		return
+	}
+	totals := &StreamTotals{NSamples: stream.totals.nsamples}
+	copy(totals.MD5sum[:], stream.totals.md5sum.Sum(nil))
+	stream.totals = nil
+	stream.opts.OnTotals(totals)
+}
@@ -0,0 +1,97 @@
+package flac_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestOnTotals(t *testing.T) {
+	const path = "testdata/love.flac"
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clear the totals of a copy of the file, as left by encoders writing to
+	// non-seekable outputs.
+	dir := t.TempDir()
+	unset := filepath.Join(dir, "unset.flac")
+	if err := os.WriteFile(unset, want, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := flac.PatchTotals(unset, &flac.StreamTotals{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode the copy, storing the computed totals in a sidecar file.
+	sidecar := unset + ".totals"
+	for _, lowMemory := range []bool{false, true} {
+		data, err := os.ReadFile(unset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got *flac.StreamTotals
+		opts := &flac.DecodeOptions{
+			LowMemory: lowMemory,
+			OnTotals:  func(totals *flac.StreamTotals) { got = totals },
+		}
+		stream, err := flac.NewWithOptions(bytes.NewReader(data), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stream.Info.NSamples != 0 {
+			t.Fatalf("total number of samples not cleared; got %d", stream.Info.NSamples)
+		}
+		for {
+			if _, err := stream.ParseNext(); err != nil {
+				if err == io.EOF {
+					break
+				}
+				t.Fatal(err)
+			}
+		}
+		if got == nil {
+			t.Fatalf("low-memory %v: stream totals not reported", lowMemory)
+		}
+		if err := got.WriteFile(sidecar); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Patch the copy using the sidecar file.
+	totals, err := flac.ReadTotals(sidecar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := flac.PatchTotals(unset, totals); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(unset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("patched file mismatch; expected totals of %q", path)
+	}
+
+	// Totals of streams whose StreamInfo block specifies them are not reported.
+	opts := &flac.DecodeOptions{
+		OnTotals: func(totals *flac.StreamTotals) { t.Errorf("unexpected stream totals of %q", path) },
+	}
+	stream, err := flac.NewWithOptions(bytes.NewReader(want), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+	}
+}
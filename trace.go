@@ -0,0 +1,266 @@
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/icza/bitio"
+	iobits "github.com/mewkiz/flac/bits"
+	"github.com/mewkiz/flac/frame"
+)
+
+// Trace parses the FLAC stream of r in its entirety, and writes a textual trace
+// of each audio frame to w, similar to the output of `flac --analyze`; for
+// debugging interoperability issues with other encoders. The trace of a frame
+// lists the fields of the frame header, including its CRC-8 and CRC-16
+// checksums, followed by the prediction method, order, coefficients, warm-up
+// samples and Rice partitions of each subframe, and the size in bits of each
+// subframe and Rice partition:
+//
+//	frame=1	offset=8318	bits=16864	blocksize=4096	sample_rate=44100	channels=2	channel_assignment=LEFT_SIDE	crc8=0x24	crc16=0xAF80
+//		subframe=0	wasted_bits=0	type=FIXED	order=2	residual_type=RICE	partition_order=5	bits=16769
+//			warmup[0]=126
+//			warmup[1]=126
+//			parameter[0]=0	bits=130
+//			...
+//
+// The size of a subframe and its Rice partitions is derived from its decoded
+// residuals, as the Rice coding of residuals is deterministic.
+func Trace(w io.Writer, r io.Reader) error {
+	return TraceWithOptions(w, r, nil)
+}
+
+// TraceOptions specifies the options of a bitstream trace. The zero value
+// specifies the trace of Trace.
+type TraceOptions struct {
+	// Residuals specifies whether to include the residuals of each fixed and
+	// FIR subframe in the trace, one per line following the Rice partitions of
+	// the subframe:
+	//
+	//	residual[0]=-2
+	//
+	// To export the residual signals for use by other tools, see
+	// ExportResiduals.
+	Residuals bool
+}
+
+// TraceWithOptions parses the FLAC stream of r in its entirety, and writes a
+// textual trace of each audio frame to w, using the given trace options; a nil
+// value specifies the default options. See Trace for details.
+func TraceWithOptions(w io.Writer, r io.Reader, opts *TraceOptions) error {
+	if opts == nil {
+		opts = &TraceOptions{}
+	}
+	tr := &traceReader{r: r}
+	stream, err := NewWithOptions(tr, &DecodeOptions{KeepIntermediates: true})
+	if err != nil {
+		return err
+	}
+	tr.discard(stream.Offset())
+	t := &tracer{w: w, opts: opts}
+	for num := 0; ; num++ {
+		start := stream.Offset()
+		f, err := stream.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				return t.err
+			}
+			return err
+		}
+		end := stream.Offset()
+		t.traceFrame(num, start, tr.bytes(start, end), f)
+		tr.discard(end)
+		if t.err != nil {
+			return t.err
+		}
+	}
+}
+
+// TraceFile writes a textual trace of each audio frame of the FLAC file at path
+// to w. See Trace for details.
+func TraceFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Trace(w, f)
+}
+
+// traceReader is an io.Reader which retains the bytes read from the underlying
+// reader, to provide access to the raw bytes of parsed audio frames.
+type traceReader struct {
+	// Underlying reader.
+	r io.Reader
+	// Retained bytes, starting at offset base of the underlying reader.
+	buf  []byte
+	base int64
+}
+
+// Read reads from the underlying reader, and retains the bytes read.
+func (tr *traceReader) Read(p []byte) (n int, err error) {
+	n, err = tr.r.Read(p)
+	tr.buf = append(tr.buf, p[:n]...)
+	return n, err
+}
+
+// bytes returns the retained bytes between the start and end offsets.
+func (tr *traceReader) bytes(start, end int64) []byte {
+	return tr.buf[start-tr.base : end-tr.base]
+}
+
+// discard discards the retained bytes preceding the given offset.
+func (tr *traceReader) discard(offset int64) {
+	n := copy(tr.buf, tr.buf[offset-tr.base:])
+	tr.buf = tr.buf[:n]
+	tr.base = offset
+}
+
+// tracer writes a textual trace, recording the first write error.
+type tracer struct {
+	// Underlying writer.
+	w io.Writer
+	// Trace options.
+	opts *TraceOptions
+	// First write error.
+	err error
+}
+
+// printf writes a formatted line to the trace, indented by the given number of
+// tabs.
+func (t *tracer) printf(indent int, format string, args ...interface{}) {
+	if t.err != nil {
+		return
+	}
+	for ; indent > 0; indent-- {
+		if _, t.err = io.WriteString(t.w, "\t"); t.err != nil {
+			return
+		}
+	}
+	_, t.err = fmt.Fprintf(t.w, format+"\n", args...)
+}
+
+// traceFrame writes the trace of the given audio frame, with the given frame
+// index, byte offset and raw bytes.
+func (t *tracer) traceFrame(num int, offset int64, raw []byte, f *frame.Frame) {
+	// Determine the size of the frame header, whose last byte holds its CRC-8
+	// checksum.
+	hr := bytes.NewReader(raw)
+	if _, err := frame.New(hr); err != nil {
+		t.err = fmt.Errorf("flac.Trace: unable to parse header of frame %d; %v", num, err)
+		return
+	}
+	hdrSize := len(raw) - hr.Len()
+	crc8 := raw[hdrSize-1]
+	crc16 := binary.BigEndian.Uint16(raw[len(raw)-2:])
+	t.printf(0, "frame=%d\toffset=%d\tbits=%d\tblocksize=%d\tsample_rate=%d\tchannels=%d\tchannel_assignment=%s\tcrc8=0x%02X\tcrc16=0x%04X", num, offset, 8*len(raw), f.BlockSize, f.SampleRate, f.Channels.Count(), channelAssignmentName(f.Channels), crc8, crc16)
+
+	// Restore the decorrelated and wasted-bits shifted samples of a copy of the
+	// frame, as stored in the stream.
+	g := &frame.Frame{
+		Header:    f.Header,
+		Subframes: make([]*frame.Subframe, len(f.Subframes)),
+	}
+	for i, subframe := range f.Subframes {
+		sub := *subframe
+		sub.Samples = append([]int32(nil), subframe.Samples...)
+		g.Subframes[i] = &sub
+	}
+	g.Decorrelate()
+	nbits := 0
+	for channel, subframe := range g.Subframes {
+		bps := subframeBitsPerSample(g.Header, channel)
+		n, err := encodedSize(func(bw *bitio.Writer) error {
+			return encodeSubframe(bw, g.Header, subframe, bps)
+		})
+		if err != nil {
+			t.err = fmt.Errorf("flac.Trace: unable to size subframe %d of frame %d; %v", channel, num, err)
+			return
+		}
+		nbits += n
+		for i := range subframe.Samples {
+			subframe.Samples[i] >>= subframe.Wasted
+		}
+		t.traceSubframe(channel, subframe, n)
+	}
+	// Zero-padding to byte alignment, preceding the CRC-16 checksum.
+	padding := 8*(len(raw)-hdrSize-2) - nbits
+	if padding < 0 || padding >= 8 {
+		t.printf(1, "warning: size of subframes (%d bits) inconsistent with frame size", nbits)
+	}
+}
+
+// traceSubframe writes the trace of the given subframe, with wasted bits
+// shifted out of its samples, and with the given size in bits.
+func (t *tracer) traceSubframe(channel int, subframe *frame.Subframe, nbits int) {
+	switch subframe.Pred {
+	case frame.PredConstant:
+		t.printf(1, "subframe=%d\twasted_bits=%d\ttype=CONSTANT\tvalue=%d\tbits=%d", channel, subframe.Wasted, subframe.Samples[0], nbits)
+		return
+	case frame.PredVerbatim:
+		t.printf(1, "subframe=%d\twasted_bits=%d\ttype=VERBATIM\tbits=%d", channel, subframe.Wasted, nbits)
+		return
+	}
+	residualType := "RICE"
+	paramSize := uint(4)
+	if subframe.ResidualCodingMethod == frame.ResidualCodingMethodRice2 {
+		residualType = "RICE2"
+		paramSize = 5
+	}
+	partOrder := subframe.RiceSubframe.PartOrder
+	if subframe.Pred == frame.PredFIR {
+		t.printf(1, "subframe=%d\twasted_bits=%d\ttype=LPC\torder=%d\tqlp_coeff_precision=%d\tquantization_level=%d\tresidual_type=%s\tpartition_order=%d\tbits=%d", channel, subframe.Wasted, subframe.Order, subframe.CoeffPrec, subframe.CoeffShift, residualType, partOrder, nbits)
+		for i, coeff := range subframe.Coeffs {
+			t.printf(2, "qlp_coeff[%d]=%d", i, coeff)
+		}
+	} else {
+		t.printf(1, "subframe=%d\twasted_bits=%d\ttype=FIXED\torder=%d\tresidual_type=%s\tpartition_order=%d\tbits=%d", channel, subframe.Wasted, subframe.Order, residualType, partOrder, nbits)
+	}
+	for i := 0; i < subframe.Order; i++ {
+		t.printf(2, "warmup[%d]=%d", i, subframe.Samples[i])
+	}
+	residuals := subframe.Residuals
+	nparts := 1 << partOrder
+	for i, partition := range subframe.RiceSubframe.Partitions {
+		// Determine the number of residuals of the partition.
+		n := subframe.NSamples / nparts
+		if i == 0 {
+			n -= subframe.Order
+		}
+		part := residuals[:n]
+		residuals = residuals[n:]
+		if partition.Param == 1<<paramSize-1 {
+			nbits := int(paramSize) + 5 + n*int(partition.EscapedBitsPerSample)
+			t.printf(2, "parameter[%d]=ESCAPE, raw_bits=%d\tbits=%d", i, partition.EscapedBitsPerSample, nbits)
+			continue
+		}
+		nbits := int(paramSize)
+		k := partition.Param
+		for _, residual := range part {
+			nbits += 1 + int(k) + int(iobits.EncodeZigZag(residual)>>k)
+		}
+		t.printf(2, "parameter[%d]=%d\tbits=%d", i, k, nbits)
+	}
+	if t.opts.Residuals {
+		for i, residual := range subframe.Residuals {
+			t.printf(2, "residual[%d]=%d", i, residual)
+		}
+	}
+}
+
+// channelAssignmentName returns the name of the given channel assignment, as
+// used by `flac --analyze`.
+func channelAssignmentName(channels frame.Channels) string {
+	switch channels {
+	case frame.ChannelsLeftSide:
+		return "LEFT_SIDE"
+	case frame.ChannelsSideRight:
+		return "RIGHT_SIDE"
+	case frame.ChannelsMidSide:
+		return "MID_SIDE"
+	}
+	return "INDEPENDENT"
+}
@@ -0,0 +1,66 @@
+package flac_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestTrace(t *testing.T) {
+	paths := []string{
+		"testdata/172960.flac",
+		"testdata/19875.flac",
+		"testdata/love.flac",
+	}
+	for _, path := range paths {
+		stream, err := flac.ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nframes := 0
+		for {
+			if _, err := stream.ParseNext(); err != nil {
+				break
+			}
+			nframes++
+		}
+		stream.Close()
+
+		out := new(bytes.Buffer)
+		if err := flac.TraceFile(out, path); err != nil {
+			t.Errorf("%q: unable to trace stream; %v", path, err)
+			continue
+		}
+		ntraced := 0
+		s := bufio.NewScanner(out)
+		for s.Scan() {
+			line := s.Text()
+			if strings.HasPrefix(line, "frame=") {
+				ntraced++
+			}
+			if strings.Contains(line, "warning") {
+				t.Errorf("%q: unexpected warning in trace; %s", path, line)
+			}
+		}
+		if ntraced != nframes {
+			t.Errorf("%q: number of traced frames mismatch; expected %d, got %d", path, nframes, ntraced)
+		}
+	}
+
+	// Compare against the first frames of a known trace.
+	out := new(bytes.Buffer)
+	if err := flac.TraceFile(out, "testdata/love.flac"); err != nil {
+		t.Fatal(err)
+	}
+	const want = "frame=0\toffset=8304\tbits=112\tblocksize=4096\tsample_rate=44100\tchannels=2\tchannel_assignment=INDEPENDENT\tcrc8=0xC2\tcrc16=0xF078\n" +
+		"\tsubframe=0\twasted_bits=1\ttype=CONSTANT\tvalue=63\tbits=24\n" +
+		"\tsubframe=1\twasted_bits=1\ttype=CONSTANT\tvalue=63\tbits=24\n" +
+		"frame=1\toffset=8318\tbits=16864\tblocksize=4096\tsample_rate=44100\tchannels=2\tchannel_assignment=LEFT_SIDE\tcrc8=0x24\tcrc16=0xAF80\n" +
+		"\tsubframe=0\twasted_bits=0\ttype=FIXED\torder=2\tresidual_type=RICE\tpartition_order=5\tbits=16769\n"
+	if got := out.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("trace mismatch; expected prefix %q, got %q", want, got[:len(want)])
+	}
+}
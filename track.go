@@ -0,0 +1,215 @@
+package flac
+
+import (
+	"slices"
+	"time"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// A Track is a FLAC file, providing access to its audio samples, tags,
+// pictures, chapters and lyrics, and editing of its metadata; so that common
+// tasks of applications require no knowledge of metadata blocks and audio
+// frames.
+//
+// Edits of the metadata (e.g. Track.SetTag) are applied to the metadata blocks
+// of the track in memory, and are written to the file by Track.Save.
+type Track struct {
+	// Path of the FLAC file.
+	path string
+	// Underlying stream of the FLAC file; its metadata blocks hold the pending
+	// edits.
+	stream *Stream
+	// Specifies whether the metadata blocks hold pending edits.
+	modified bool
+}
+
+// OpenTrack opens the FLAC file at path as a track, parsing all metadata
+// blocks. The file is memory-mapped where supported, and seeking is enabled;
+// see OpenMmap.
+//
+// Note: The Close method of the track must be called when finished using it.
+func OpenTrack(path string) (*Track, error) {
+	stream, err := OpenMmap(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Track{path: path, stream: stream}, nil
+}
+
+// Close closes the FLAC file of the track. Pending edits of the metadata are
+// discarded.
+func (t *Track) Close() error {
+	return t.stream.Close()
+}
+
+// Path returns the path of the FLAC file of the track.
+func (t *Track) Path() string {
+	return t.path
+}
+
+// Stream returns the underlying stream of the track, for decoding of the audio
+// samples; e.g. using Stream.Channels, Stream.ReadInt16 and Stream.SeekTime.
+// The stream is replaced when the track is saved.
+func (t *Track) Stream() *Stream {
+	return t.stream
+}
+
+// Info returns the StreamInfo metadata block of the track; i.e. its sample
+// rate, number of channels, sample size and total number of samples.
+func (t *Track) Info() *meta.StreamInfo {
+	return t.stream.Info
+}
+
+// Duration returns the duration of the track; or 0 if the total number of
+// samples is unknown.
+func (t *Track) Duration() time.Duration {
+	return streamDuration(t.stream.Info)
+}
+
+// comment returns the first VorbisComment metadata block of the track; or nil
+// if not present.
+func (t *Track) comment() *meta.VorbisComment {
+	for _, block := range t.stream.Blocks {
+		if comment, ok := block.Body.(*meta.VorbisComment); ok {
+			return comment
+		}
+	}
+	return nil
+}
+
+// Tag returns the value of the first tag with the given field name, compared
+// case-insensitively; e.g. "TITLE" or "ARTIST". An empty string is returned if
+// the tag does not exist.
+func (t *Track) Tag(name string) string {
+	comment := t.comment()
+	if comment == nil {
+		return ""
+	}
+	value, _ := comment.Get(name)
+	return value
+}
+
+// Tags returns the tags of the track, as name-value pairs in stream order.
+func (t *Track) Tags() [][2]string {
+	comment := t.comment()
+	if comment == nil {
+		return nil
+	}
+	return slices.Clone(comment.Tags)
+}
+
+// SetTag replaces the tags with the given field name by a tag of each of the
+// given values, as done by meta.VorbisComment.Set; the tags are removed if no
+// values are given. A VorbisComment metadata block is added if the track has
+// none.
+func (t *Track) SetTag(name string, values ...string) {
+	comment := t.comment()
+	if comment == nil {
+		if len(values) == 0 {
+			return
+		}
+		comment = &meta.VorbisComment{Vendor: defaultVendor()}
+		block := &meta.Block{
+			Header: meta.Header{Type: meta.TypeVorbisComment, Length: int64(4 + len(comment.Vendor) + 4)},
+			Body:   comment,
+		}
+		t.stream.Blocks = insertBlock(t.stream.Blocks, block)
+	}
+	comment.Set(name, values...)
+	t.modified = true
+}
+
+// Pictures returns the pictures of the track, in stream order.
+func (t *Track) Pictures() []*meta.Picture {
+	var pics []*meta.Picture
+	for _, block := range t.stream.Blocks {
+		if pic, ok := block.Body.(*meta.Picture); ok {
+			pics = append(pics, pic)
+		}
+	}
+	return pics
+}
+
+// FrontCover returns the front cover of the track, as located by
+// meta.FrontCover; or nil if the track has no such picture.
+func (t *Track) FrontCover() *meta.Picture {
+	return meta.FrontCover(t.stream.Blocks)
+}
+
+// SetFrontCover replaces the front cover of the track with pic, or adds pic if
+// the track has no front cover, as done by ReplaceFrontCover.
+func (t *Track) SetFrontCover(pic *meta.Picture) error {
+	if err := pic.Validate(); err != nil {
+		return err
+	}
+	blocks, err := withFrontCover(t.stream.Blocks, pic)
+	if err != nil {
+		return err
+	}
+	t.stream.Blocks = blocks
+	t.modified = true
+	return nil
+}
+
+// Chapters returns the chapters of the track; see Stream.Chapters.
+func (t *Track) Chapters() ([]Chapter, error) {
+	return t.stream.Chapters()
+}
+
+// Lyrics returns the synchronized lyrics of the track; see Stream.Lyrics.
+func (t *Track) Lyrics() (*Lyrics, error) {
+	return t.stream.Lyrics()
+}
+
+// Modified reports whether the metadata of the track holds edits which have not
+// been saved.
+func (t *Track) Modified() bool {
+	return t.modified
+}
+
+// Save writes the edited metadata of the track to its FLAC file, as done by
+// EditMetadata, and reports how the file was rewritten. The underlying stream
+// of the track is reopened, and is positioned at the first audio frame. Save
+// does nothing and returns 0 if the track holds no pending edits.
+func (t *Track) Save() (MetadataRewrite, error) {
+	return t.SaveWithOptions(nil)
+}
+
+// SaveWithOptions writes the edited metadata of the track to its FLAC file, as
+// done by Save, using the specified rewrite options. A nil opts specifies the
+// default options.
+func (t *Track) SaveWithOptions(opts *RewriteOptions) (MetadataRewrite, error) {
+	if !t.modified {
+		return 0, nil
+	}
+	blocks := t.stream.Blocks
+	// The file is released before being rewritten, as it may be memory-mapped.
+	if err := t.stream.Close(); err != nil {
+		return 0, err
+	}
+	plan, editErr := EditMetadata(t.path, blocks, opts)
+	stream, err := OpenMmap(t.path)
+	if err != nil {
+		return 0, err
+	}
+	if editErr != nil {
+		// Retain the pending edits.
+		stream.Blocks = blocks
+		t.stream = stream
+		return 0, editErr
+	}
+	t.stream = stream
+	t.modified = false
+	return plan.Rewrite, nil
+}
+
+// insertBlock returns the given metadata blocks with block inserted following
+// the last non-padding block.
+func insertBlock(blocks []*meta.Block, block *meta.Block) []*meta.Block {
+	i := len(blocks)
+	for i > 0 && blocks[i-1].Type == meta.TypePadding {
+		i--
+	}
+	return slices.Insert(slices.Clip(blocks), i, block)
+}
@@ -0,0 +1,86 @@
+package flac_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestTrack(t *testing.T) {
+	orig, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "track.flac")
+	if err := os.WriteFile(path, orig, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	track, err := flac.OpenTrack(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		track.Close()
+	}()
+	info := track.Info()
+	if want := info.NSamples * 1e9 / uint64(info.SampleRate); uint64(track.Duration()) != want {
+		t.Errorf("duration mismatch; expected %dns, got %v", want, track.Duration())
+	}
+	if pics := track.Pictures(); len(pics) != 0 {
+		t.Errorf("number of pictures mismatch; expected 0, got %d", len(pics))
+	}
+
+	// Edit tags and front cover.
+	track.SetTag("TITLE", "love")
+	track.SetTag("GENRE", "pop", "rock")
+	pic := &meta.Picture{Type: meta.PictureFrontCover, MIME: meta.MIMEPNG, Data: bytes.Repeat([]byte{0xAB}, 100)}
+	if err := track.SetFrontCover(pic); err != nil {
+		t.Fatal(err)
+	}
+	if !track.Modified() {
+		t.Error("edits not reported as modified")
+	}
+	rewrite, err := track.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewrite != flac.RewriteInPlace {
+		t.Errorf("rewrite mismatch; expected %v, got %v", flac.RewriteInPlace, rewrite)
+	}
+	if track.Modified() {
+		t.Error("saved edits reported as modified")
+	}
+	// The stream is reopened following the rewrite.
+	if _, err := track.Stream().ParseNext(); err != nil {
+		t.Errorf("unable to decode audio frame of saved track; %v", err)
+	}
+	track.Close()
+
+	track, err = flac.OpenTrack(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := track.Tag("title"); got != "love" {
+		t.Errorf("title mismatch; expected %q, got %q", "love", got)
+	}
+	var genres []string
+	for _, tag := range track.Tags() {
+		if tag[0] == "GENRE" {
+			genres = append(genres, tag[1])
+		}
+	}
+	if len(genres) != 2 || genres[0] != "pop" || genres[1] != "rock" {
+		t.Errorf("genres mismatch; expected [pop rock], got %q", genres)
+	}
+	if cover := track.FrontCover(); cover == nil || !bytes.Equal(cover.Data, pic.Data) {
+		t.Error("front cover not found")
+	}
+	// Saving a track without edits leaves the file untouched.
+	if rewrite, err := track.Save(); err != nil || rewrite != 0 {
+		t.Errorf("unexpected rewrite of unmodified track; %v, %v", rewrite, err)
+	}
+}
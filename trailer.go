@@ -0,0 +1,275 @@
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A TrailerKind specifies the kind of a tag trailing the audio frames of a FLAC
+// stream.
+type TrailerKind uint8
+
+// Trailer kinds.
+const (
+	// TrailerID3v1 is an ID3v1 tag of 128 bytes, which ends the stream.
+	TrailerID3v1 TrailerKind = iota + 1
+	// TrailerAPE is an APEv2 tag, including its header and footer.
+	TrailerAPE
+)
+
+// String returns the string representation of the trailer kind.
+func (kind TrailerKind) String() string {
+	switch kind {
+	case TrailerID3v1:
+		return "ID3v1"
+	case TrailerAPE:
+		return "APEv2"
+	}
+	return fmt.Sprintf("TrailerKind(%d)", uint8(kind))
+}
+
+// A Trailer is a tag trailing the audio frames of a FLAC stream, as appended by
+// taggers of other formats (e.g. MP3); see Stream.Trailers.
+type Trailer struct {
+	// Kind of tag.
+	Kind TrailerKind
+	// Byte offset of the tag, relative to the start of the stream.
+	Offset int64
+	// Raw contents of the tag.
+	Data []byte
+}
+
+// A TruncatedError reports that a FLAC stream ended prematurely; i.e. within an
+// audio frame or a trailing tag, or before the total number of samples of the
+// StreamInfo block. It wraps io.ErrUnexpectedEOF.
+type TruncatedError struct {
+	// Number of samples per channel decoded up to the end of the stream.
+	Decoded uint64
+	// Total number of samples per channel of the StreamInfo block; or 0 if
+	// unknown.
+	NSamples uint64
+	// Underlying error.
+	Err error
+}
+
+// Error returns the error message of the truncated stream.
+func (e *TruncatedError) Error() string {
+	if e.NSamples == 0 {
+		return fmt.Sprintf("flac: stream truncated after %d samples; %v", e.Decoded, e.Err)
+	}
+	return fmt.Sprintf("flac: stream truncated after %d of %d samples; %v", e.Decoded, e.NSamples, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TruncatedError) Unwrap() error {
+	return e.Err
+}
+
+// A TrailingDataError reports unrecognized data following the last sample of a
+// FLAC stream, such as padding or remnants of a previous file. The audio
+// samples of the stream are intact.
+type TrailingDataError struct {
+	// Byte offset of the trailing data, relative to the start of the stream.
+	Offset int64
+}
+
+// Error returns the error message of the trailing data.
+func (e *TrailingDataError) Error() string {
+	return fmt.Sprintf("flac: unrecognized data at offset %d following the last audio frame", e.Offset)
+}
+
+// Trailers returns the tags trailing the audio frames of the stream, in order
+// of appearance. Trailing tags are located once the stream is decoded to its
+// end, and are not reported as errors; i.e. Stream.ParseNext returns io.EOF
+// following the tags.
+//
+// At the end of the audio frames, the decoder distinguishes the following
+// cases:
+//
+//   - clean end of stream, with or without trailing ID3v1 and APEv2 tags:
+//     io.EOF.
+//   - premature end of stream, within an audio frame or a trailing tag, or
+//     before the total number of samples of StreamInfo: *TruncatedError.
+//   - unrecognized data following the last sample, if the total number of
+//     samples is known or following trailing tags: *TrailingDataError. Otherwise,
+//     unrecognized data is parsed as a frame header, as the stream may be
+//     corrupt.
+func (stream *Stream) Trailers() []Trailer {
+	return stream.trailers
+}
+
+// Sizes and signatures of trailing tags.
+const (
+	// apeHeaderSize specifies the size in bytes of the header and footer of an
+	// APEv2 tag.
+	apeHeaderSize = 32
+	// apeIsHeader is the flag of an APEv2 header, as opposed to a footer.
+	apeIsHeader = 1 << 29
+	// maxAPETagSize specifies the maximum size in bytes of APEv2 tags.
+	maxAPETagSize = 16 << 20
+)
+
+// apeSignature is the preamble of APEv2 headers and footers.
+var apeSignature = []byte("APETAGEX")
+
+// checkEnd checks whether the audio frames of the stream end at the current
+// offset, where the next frame header is expected; and parses the trailing tags
+// if so. It returns nil if a frame header may follow, and returns the error
+// reported by Stream.Trailers otherwise.
+func (stream *Stream) checkEnd() error {
+	c, err := stream.peekByte()
+	switch {
+	case err == io.EOF:
+		return stream.endOfStream()
+	case err != nil || c == 0xFF:
+		// Sync code of a frame header; read errors are reported by the frame
+		// parser.
+		return nil
+	}
+	ntrailers := len(stream.trailers)
+	for {
+		offset := stream.Offset()
+		trailer, err := stream.parseTrailer()
+		switch {
+		case err == io.EOF:
+			return stream.endOfStream()
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return &TruncatedError{Decoded: stream.samplePos, NSamples: stream.Info.NSamples, Err: err}
+		case err != nil:
+			return err
+		case trailer != nil:
+			stream.trailers = append(stream.trailers, *trailer)
+			continue
+		}
+		if len(stream.trailers) > ntrailers || stream.Info.NSamples != 0 && stream.samplePos >= stream.Info.NSamples {
+			return &TrailingDataError{Offset: offset}
+		}
+		return nil
+	}
+}
+
+// endOfStream returns the error of the stream ending at the current sample
+// position; io.EOF, or a *TruncatedError if samples are missing.
+func (stream *Stream) endOfStream() error {
+	if nsamples := stream.Info.NSamples; nsamples != 0 && stream.samplePos < nsamples {
+		err := &TruncatedError{Decoded: stream.samplePos, NSamples: nsamples, Err: io.ErrUnexpectedEOF}
+		stream.logEnd(err)
+		return err
+	}
+	stream.logEnd(nil)
+	return io.EOF
+}
+
+// logEnd logs an end of stream event, with the given error ending the stream;
+// or nil if the stream ended gracefully.
+func (stream *Stream) logEnd(err error) {
+	if !stream.logging() {
+		return
+	}
+	stream.log(Event{Kind: EventEndOfStream, Offset: stream.Offset(), Err: err})
+}
+
+// truncated returns a *TruncatedError if err reports that the stream ended
+// within the audio frame starting at the given sample number, and returns err
+// otherwise. The sample position of the stream is reset to the start of the
+// truncated frame.
+func (stream *Stream) truncated(err error, sample uint64) error {
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	// The truncated frame has not been decoded; e.g. Stream.ResetReader resumes
+	// decoding at its first sample.
+	stream.samplePos = sample
+	terr := &TruncatedError{Decoded: sample, NSamples: stream.Info.NSamples, Err: err}
+	stream.logEnd(terr)
+	return terr
+}
+
+// parseTrailer parses the trailing tag at the current offset of the stream. It
+// returns a nil trailer without advancing the stream if no tag is recognized,
+// and io.EOF at the end of the stream.
+func (stream *Stream) parseTrailer() (*Trailer, error) {
+	offset := stream.Offset()
+	buf, err := stream.peekNext(apeHeaderSize)
+	if len(buf) == 0 {
+		return nil, err
+	}
+	var kind TrailerKind
+	var size int
+	switch {
+	case bytes.HasPrefix(buf, id3v1Signature):
+		// ID3v1 tags end the stream.
+		buf, err := stream.peekNext(id3v1Size + 1)
+		if len(buf) != id3v1Size || err != io.EOF {
+			return nil, nil
+		}
+		kind, size = TrailerID3v1, id3v1Size
+	case len(buf) == apeHeaderSize && bytes.HasPrefix(buf, apeSignature):
+		// The tag size of APEv2 headers excludes the header.
+		tagSize := binary.LittleEndian.Uint32(buf[12:])
+		flags := binary.LittleEndian.Uint32(buf[20:])
+		if flags&apeIsHeader == 0 || tagSize < apeHeaderSize || tagSize > maxAPETagSize {
+			return nil, nil
+		}
+		kind, size = TrailerAPE, apeHeaderSize+int(tagSize)
+	default:
+		return nil, nil
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(stream.rawReader(), data); err != nil {
+		return nil, unexpected(err)
+	}
+	return &Trailer{Kind: kind, Offset: offset, Data: data}, nil
+}
+
+// id3v1Signature is the preamble of ID3v1 tags.
+var id3v1Signature = []byte("TAG")
+
+// peekByte returns the next byte of the stream, without advancing the stream.
+func (stream *Stream) peekByte() (byte, error) {
+	if r, ok := stream.r.(io.ByteScanner); ok && stream.br == nil {
+		// In-memory readers; see isInMemory.
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		return c, r.UnreadByte()
+	}
+	buf, err := stream.peekNext(1)
+	if len(buf) == 0 {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// peekNext returns up to size bytes of the stream following the current offset,
+// without advancing the stream; or fewer bytes with an error, io.EOF at the end
+// of the stream. Unlike Stream.peek, streams without seeking support are peeked
+// within the bounds of their read buffer.
+func (stream *Stream) peekNext(size int) ([]byte, error) {
+	if stream.br != nil {
+		return stream.br.Peek(size)
+	}
+	return stream.peek(size)
+}
+
+// rawReader returns the reader of the stream, bypassing the computation of
+// digests; see DecodeOptions.Digest.
+func (stream *Stream) rawReader() io.Reader {
+	if stream.br != nil {
+		return stream.br
+	}
+	return stream.r
+}
+
+// unexpected returns io.ErrUnexpectedEOF if err is io.EOF, and returns err
+// otherwise.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package flac_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+// apeTag returns an APEv2 tag of the given text items (key, value pairs),
+// including its footer, and its header if hasHeader is set.
+func apeTag(hasHeader bool, items ...[2]string) []byte {
+	var body []byte
+	for _, item := range items {
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(item[1])))
+		body = binary.LittleEndian.AppendUint32(body, 0)
+		body = append(body, item[0]+"\x00"+item[1]...)
+	}
+	header := func(flags uint32) []byte {
+		buf := []byte("APETAGEX")
+		buf = binary.LittleEndian.AppendUint32(buf, 2000)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(body)+32))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(items)))
+		buf = binary.LittleEndian.AppendUint32(buf, flags)
+		return append(buf, make([]byte, 8)...)
+	}
+	if !hasHeader {
+		return append(body, header(0)...)
+	}
+	tag := header(1<<31 | 1<<29)
+	tag = append(tag, body...)
+	return append(tag, header(1<<31)...)
+}
+
+func TestTrailers(t *testing.T) {
+	orig, err := os.ReadFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := frameOffsets(t, orig)
+	last := offsets[len(offsets)-1]
+	id3v1 := append([]byte("TAG"), make([]byte, 125)...)
+	ape := apeTag(true, [2]string{"Artist", "Love"})
+	cat := func(bufs ...[]byte) []byte {
+		return slices.Concat(append([][]byte{orig}, bufs...)...)
+	}
+
+	golden := []struct {
+		name string
+		data []byte
+		// Expected trailing tags.
+		want []flac.TrailerKind
+		// Expected error at the end of the audio frames; io.EOF, a
+		// *flac.TruncatedError or a *flac.TrailingDataError.
+		wantErr error
+	}{
+		{name: "clean", data: orig, wantErr: io.EOF},
+		{name: "id3v1", data: cat(id3v1), want: []flac.TrailerKind{flac.TrailerID3v1}, wantErr: io.EOF},
+		{name: "ape", data: cat(ape), want: []flac.TrailerKind{flac.TrailerAPE}, wantErr: io.EOF},
+		{name: "ape and id3v1", data: cat(ape, id3v1), want: []flac.TrailerKind{flac.TrailerAPE, flac.TrailerID3v1}, wantErr: io.EOF},
+		{name: "padding", data: cat(make([]byte, 1000)), wantErr: &flac.TrailingDataError{Offset: int64(len(orig))}},
+		{name: "garbage after id3v1", data: cat(id3v1, []byte("junk")), wantErr: &flac.TrailingDataError{Offset: int64(len(orig))}},
+		{name: "garbage after ape", data: cat(ape, []byte("junk")), want: []flac.TrailerKind{flac.TrailerAPE}, wantErr: &flac.TrailingDataError{Offset: int64(len(orig) + len(ape))}},
+		{name: "truncated frame", data: orig[:len(orig)-100], wantErr: &flac.TruncatedError{}},
+		{name: "truncated at frame boundary", data: orig[:last], wantErr: &flac.TruncatedError{}},
+		{name: "truncated ape", data: cat(ape[:40]), wantErr: &flac.TruncatedError{}},
+	}
+	for _, g := range golden {
+		for _, seek := range []bool{false, true} {
+			var stream *flac.Stream
+			var err error
+			if seek {
+				stream, err = flac.NewSeek(bytes.NewReader(g.data))
+			} else {
+				stream, err = flac.New(bytes.NewReader(g.data))
+			}
+			if err != nil {
+				t.Fatalf("%s (seek=%v): unable to create stream; %v", g.name, seek, err)
+			}
+			for {
+				_, err = stream.ParseNext()
+				if err != nil {
+					break
+				}
+			}
+			var truncated *flac.TruncatedError
+			var trailing *flac.TrailingDataError
+			switch want := g.wantErr.(type) {
+			case *flac.TruncatedError:
+				if !errors.As(err, &truncated) || !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Errorf("%s (seek=%v): expected truncated stream, got %v", g.name, seek, err)
+				}
+			case *flac.TrailingDataError:
+				if !errors.As(err, &trailing) || *trailing != *want {
+					t.Errorf("%s (seek=%v): expected %v, got %v", g.name, seek, want, err)
+				}
+			default:
+				if err != want {
+					t.Errorf("%s (seek=%v): expected %v, got %v", g.name, seek, want, err)
+				}
+			}
+			var got []flac.TrailerKind
+			for _, trailer := range stream.Trailers() {
+				got = append(got, trailer.Kind)
+			}
+			if !slices.Equal(got, g.want) {
+				t.Errorf("%s (seek=%v): trailers mismatch; expected %v, got %v", g.name, seek, g.want, got)
+			}
+		}
+	}
+
+	// Contents of trailing tags.
+	stream, err := flac.New(bytes.NewReader(cat(ape, id3v1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = stream.ParseNext()
+	}
+	trailers := stream.Trailers()
+	if len(trailers) != 2 || !bytes.Equal(trailers[0].Data, ape) || trailers[1].Offset != int64(len(orig)+len(ape)) || !bytes.Equal(trailers[1].Data, id3v1) {
+		t.Errorf("trailer contents mismatch; got %+v", trailers)
+	}
+}
@@ -0,0 +1,206 @@
+package flac
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// A Transform is a sample transform applied to each decoded audio frame of a
+// transcode pipeline, before the frame is re-encoded. The audio samples of the
+// subframes may be modified in place, or replaced.
+//
+// Transforms which change the number of samples of a frame (e.g. resampling)
+// must update the samples of each subframe accordingly, and transforms which
+// change the sample rate or sample size must update the frame header; the block
+// size and subframe sample counts are updated by the pipeline.
+type Transform func(f *frame.Frame) error
+
+// TranscodeOptions specifies the options of a transcode pipeline.
+type TranscodeOptions struct {
+	// Sample transforms applied to each decoded audio frame, in order.
+	Transforms []Transform
+	// Progress, if non-nil, is invoked after each encoded frame with the number
+	// of samples (per channel) transcoded so far and the total number of
+	// samples of the source stream; or 0 if unknown.
+	Progress func(nsamples, total uint64)
+	// Verify enables verification of the decoded audio samples of the source
+	// stream against the MD5 checksum stored in its StreamInfo block, and
+	// verification of the encoded frames, which are decoded by dst as they are
+	// written to the output stream, and compared against the transformed audio
+	// samples using MD5 checksums. The encoded frames may only be verified if
+	// no frames have been written to dst before Transcode.
+	Verify bool
+}
+
+// ErrChecksumMismatch reports that the MD5 checksum of audio samples does not
+// match the expected checksum.
+var ErrChecksumMismatch = errors.New("MD5 checksum mismatch")
+
+// Transcode decodes the audio frames of src, applies the sample transforms of
+// opts, and re-encodes the frames using dst. A nil opts specifies the default
+// options. Transcode is a building block for batch conversion tools, which
+// re-encode files with different encoder settings or sample formats.
+//
+// The subframes of each frame are re-encoded from their audio samples using the
+// prediction analysis of dst; the prediction methods of src are not retained.
+// The StreamInfo block of dst must be consistent with the transformed frames.
+//
+// Transcode does not close dst; the caller is responsible for closing dst, to
+// flush the updated StreamInfo block.
+func Transcode(dst *Encoder, src *Stream, opts *TranscodeOptions) error {
+	if opts == nil {
+		opts = &TranscodeOptions{}
+	}
+	// MD5 checksums of the decoded audio samples of src and the transformed
+	// audio samples respectively.
+	srcSum, wantSum := md5.New(), md5.New()
+	if opts.Verify {
+		if dst.nsamples != 0 {
+			return errors.New("flac.Transcode: unable to verify encoded frames; frames written to encoder before transcoding")
+		}
+		dst.verifyFrames(true)
+		defer dst.verifyFrames(false)
+	}
+	var nsamples uint64
+	for {
+		f, err := src.ParseNext()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		// Store sample rate and sample size in the frame header, as the
+		// properties of src and dst may differ.
+		if f.SampleRate == 0 {
+			f.SampleRate = src.Info.SampleRate
+		}
+		if f.BitsPerSample == 0 {
+			f.BitsPerSample = src.Info.BitsPerSample
+		}
+		if opts.Verify {
+			f.Hash(srcSum)
+		}
+
+		// Apply sample transforms.
+		for _, transform := range opts.Transforms {
+			if err := transform(f); err != nil {
+				return fmt.Errorf("flac.Transcode: unable to transform frame %d; %v", f.Num, err)
+			}
+		}
+
+		// Re-encode subframes from their audio samples.
+		for _, subframe := range f.Subframes {
+			subframe.SubHeader = frame.SubHeader{Pred: frame.PredVerbatim}
+			subframe.NSamples = len(subframe.Samples)
+		}
+		if len(f.Subframes) > 0 {
+			f.BlockSize = uint16(len(f.Subframes[0].Samples))
+		}
+		if err := dst.WriteFrame(f); err != nil {
+			return err
+		}
+		if opts.Verify {
+			f.Hash(wantSum)
+		}
+		nsamples += uint64(len(f.Subframes[0].Samples))
+		if opts.Progress != nil {
+			opts.Progress(nsamples, src.Info.NSamples)
+		}
+	}
+
+	if opts.Verify {
+		var zero [md5.Size]uint8
+		want := src.Info.MD5sum[:]
+		got := srcSum.Sum(nil)
+		if src.Info.MD5sum != zero && !bytes.Equal(got, want) {
+			return fmt.Errorf("flac.Transcode: %w for decoded audio samples of source stream; expected %032x, got %032x", ErrChecksumMismatch, want, got)
+		}
+		// Wait for the pending frames to be written, if dst uses worker
+		// goroutines.
+		if err := dst.Flush(); err != nil {
+			return err
+		}
+		want, got = wantSum.Sum(nil), dst.verifySum.Sum(nil)
+		if !bytes.Equal(got, want) {
+			return fmt.Errorf("flac.Transcode: %w for encoded audio samples; expected %032x, got %032x", ErrChecksumMismatch, want, got)
+		}
+	}
+	return nil
+}
+
+// verifyFrames enables or disables the verification of the frames written by
+// the encoder to the output stream, which are decoded to update the MD5 running
+// hash of verifySum; see TranscodeOptions.Verify.
+func (enc *Encoder) verifyFrames(enable bool) {
+	if !enable {
+		enc.verifySum, enc.ow.capture = nil, nil
+		return
+	}
+	enc.verifySum, enc.ow.capture = md5.New(), &bytes.Buffer{}
+}
+
+// verifyFrame decodes the frame last written by the encoder to the output
+// stream, and adds its audio samples to the MD5 running hash of verifySum.
+func (enc *Encoder) verifyFrame() error {
+	hint := &frame.Header{SampleRate: enc.Info.SampleRate, BitsPerSample: enc.Info.BitsPerSample}
+	f, err := frame.ParseBytes(enc.ow.capture.Bytes(), hint)
+	enc.ow.capture.Reset()
+	if err != nil {
+		return fmt.Errorf("flac.Transcode: unable to decode encoded frame at offset %d; %v", enc.lastFrameOffset, err)
+	}
+	f.Hash(enc.verifySum)
+	return nil
+}
+
+// Dither returns a sample transform which reduces the sample size of the audio
+// samples to the given bits-per-sample, using triangular probability density
+// function (TPDF) dither. Frames with a sample size of at most bps are left
+// unchanged. The pseudo-random sequence of the dither noise is deterministic.
+func Dither(bps uint8) Transform {
+	rnd := rand.New(rand.NewSource(1))
+	return func(f *frame.Frame) error {
+		if f.BitsPerSample <= bps {
+			return nil
+		}
+		shift := uint(f.BitsPerSample - bps)
+		step := float64(int64(1) << shift)
+		min, max := sampleRange(bps)
+		for _, subframe := range f.Subframes {
+			for i, sample := range subframe.Samples {
+				// TPDF noise of +/- 1 LSB of the target sample size.
+				noise := (rnd.Float64() - rnd.Float64()) * step
+				x := math.Round((float64(sample) + noise) / step)
+				subframe.Samples[i] = int32(clamp(x, min, max))
+			}
+		}
+		f.BitsPerSample = bps
+		return nil
+	}
+}
+
+// sampleRange returns the minimum and maximum sample value of the given sample
+// size.
+func sampleRange(bps uint8) (min, max float64) {
+	max = float64(int64(1)<<(bps-1) - 1)
+	min = -max - 1
+	return min, max
+}
+
+// clamp returns x clamped to the range [min, max].
+func clamp(x, min, max float64) float64 {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
@@ -0,0 +1,150 @@
+package flac_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestTranscode(t *testing.T) {
+	golden := []struct {
+		name       string
+		transforms []flac.Transform
+		bps        uint8
+		workers    int
+	}{
+		{name: "identity"},
+		{name: "gain", transforms: []flac.Transform{flac.Gain(-6)}},
+		{name: "gain-workers", transforms: []flac.Transform{flac.Gain(-6)}, workers: 4},
+		{name: "dither", transforms: []flac.Transform{flac.Dither(8)}, bps: 8},
+	}
+	for _, g := range golden {
+		t.Run(g.name, func(t *testing.T) {
+			src, err := flac.ParseFile("testdata/love.flac")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer src.Close()
+			info := *src.Info
+			if g.bps != 0 {
+				info.BitsPerSample = g.bps
+			}
+			buf := &bytes.Buffer{}
+			dst, err := flac.NewEncoderWithOptions(buf, &info, &flac.EncodeOptions{Workers: g.workers})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var progress uint64
+			opts := &flac.TranscodeOptions{
+				Transforms: g.transforms,
+				Progress: func(nsamples, total uint64) {
+					progress = nsamples
+				},
+				Verify: true,
+			}
+			if err := flac.Transcode(dst, src, opts); err != nil {
+				t.Fatal(err)
+			}
+			if err := dst.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if progress != src.Info.NSamples {
+				t.Errorf("progress mismatch; expected %d, got %d", src.Info.NSamples, progress)
+			}
+
+			// Decode the transcoded stream.
+			stream, err := flac.New(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			md5sum := md5.New()
+			for {
+				f, err := stream.ParseNext()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					t.Fatal(err)
+				}
+				if g.bps != 0 && f.BitsPerSample != g.bps {
+					t.Fatalf("sample size mismatch; expected %d, got %d", g.bps, f.BitsPerSample)
+				}
+				f.Hash(md5sum)
+			}
+			var got [md5.Size]byte
+			copy(got[:], md5sum.Sum(nil))
+			if identity := len(g.transforms) == 0; identity != (got == src.Info.MD5sum) {
+				t.Errorf("MD5 checksum mismatch; identity transcode %v, got %x, source %x", identity, got, src.Info.MD5sum)
+			}
+		})
+	}
+}
+
+func TestTranscodeVerifyStreamInfo(t *testing.T) {
+	src, err := flac.ParseFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	path := filepath.Join(t.TempDir(), "out.flac")
+	fw, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := *src.Info
+	info.NSamples, info.MD5sum = 0, [md5.Size]byte{}
+	dst, err := flac.NewEncoder(fw, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := flac.Transcode(dst, src, &flac.TranscodeOptions{Verify: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify that StreamInfo of the output file has been updated on close.
+	stream, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if stream.Info.NSamples != src.Info.NSamples {
+		t.Errorf("number of samples mismatch; expected %d, got %d", src.Info.NSamples, stream.Info.NSamples)
+	}
+	if stream.Info.MD5sum != src.Info.MD5sum {
+		t.Errorf("MD5 checksum mismatch; expected %x, got %x", src.Info.MD5sum, stream.Info.MD5sum)
+	}
+}
+
+func TestTranscodeVerifyWritten(t *testing.T) {
+	src, err := flac.ParseFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	info := *src.Info
+	dst, err := flac.NewEncoder(&bytes.Buffer{}, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	f, err := src.ParseNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.WriteFrame(f); err != nil {
+		t.Fatal(err)
+	}
+	// The encoded frames may not be verified, as a frame has been written to
+	// dst before transcoding.
+	if err := flac.Transcode(dst, src, &flac.TranscodeOptions{Verify: true}); err == nil {
+		t.Fatal("expected error for verification of partially written stream")
+	}
+}
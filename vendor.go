@@ -0,0 +1,91 @@
+package flac
+
+import (
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// modulePath specifies the module path of this package.
+const modulePath = "github.com/mewkiz/flac"
+
+// vendorName specifies the name of this package, as recorded in the vendor
+// string written by the encoder, and as guessed by AnalyzeEncoder.
+const vendorName = "farcloser/flac"
+
+// version specifies the version of this package; e.g. "1.0.14". It may be set
+// at link time, using
+//
+//	go build -ldflags "-X github.com/mewkiz/flac.version=1.0.14"
+//
+// If empty, the version recorded in the build information of the running
+// binary is used, if known.
+var version string
+
+// ownVendor matches the vendor string of this package; e.g. "farcloser/flac
+// 1.0.14".
+var ownVendor = regexp.MustCompile(`^farcloser/flac(?: ([0-9][0-9a-z.+\-]*))?$`)
+
+// moduleVersion returns the version of this module, as set at link time or
+// recorded in the build information of the running binary; or an empty string
+// if unknown.
+func moduleVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	mod := &info.Main
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			mod = dep
+			break
+		}
+	}
+	if mod.Path != modulePath || mod.Version == "(devel)" {
+		return ""
+	}
+	return strings.TrimPrefix(mod.Version, "v")
+}
+
+// defaultVendor returns the vendor string of this package; e.g. "farcloser/flac
+// 1.0.14", or "farcloser/flac" if the version is unknown.
+func defaultVendor() string {
+	if v := moduleVersion(); v != "" {
+		return vendorName + " " + v
+	}
+	return vendorName
+}
+
+// Vendor returns the vendor string of the VorbisComment metadata block of the
+// stream, which identifies the encoder (and commonly its version) that produced
+// the stream; e.g. "reference libFLAC 1.3.2 20170101", or "farcloser/flac
+// 1.0.14". An empty string is returned if the stream has no VorbisComment block.
+//
+// Vendor strings are set by encoders, but are easily rewritten by tag editors;
+// see AnalyzeEncoder for a guess of the encoder based on the characteristics of
+// the audio frames.
+func (stream *Stream) Vendor() string {
+	if comment := meta.BlockList(stream.Blocks).VorbisComment(); comment != nil {
+		return comment.Vendor
+	}
+	return ""
+}
+
+// encoderVendor returns the vendor string written by the encoder for the given
+// metadata blocks and options; or an empty string if the given metadata blocks
+// are retained.
+func encoderVendor(blocks []*meta.Block, opts *EncodeOptions) string {
+	if opts.Vendor != "" {
+		return opts.Vendor
+	}
+	if len(blocks) == 0 {
+		// VorbisComment block created by the encoder.
+		return defaultVendor()
+	}
+	return ""
+}
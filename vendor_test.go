@@ -0,0 +1,88 @@
+package flac_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestVendor(t *testing.T) {
+	stream, err := flac.ParseFile("testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if got, want := stream.Vendor(), "reference libFLAC 1.3.1 20141125"; got != want {
+		t.Errorf("vendor mismatch; expected %q, got %q", want, got)
+	}
+
+	info := &meta.StreamInfo{
+		BlockSizeMin:  1024,
+		BlockSizeMax:  1024,
+		SampleRate:    44100,
+		NChannels:     2,
+		BitsPerSample: 16,
+	}
+	comment := &meta.Block{
+		Header: meta.Header{Type: meta.TypeVorbisComment, Length: 4 + 4},
+		Body:   &meta.VorbisComment{},
+	}
+	libflac := &meta.Block{
+		Header: meta.Header{Type: meta.TypeVorbisComment, Length: 4 + 32 + 4},
+		Body:   &meta.VorbisComment{Vendor: "reference libFLAC 1.3.1 20141125"},
+	}
+	golden := []struct {
+		opts   flac.EncodeOptions
+		blocks []*meta.Block
+		want   string
+	}{
+		// No metadata blocks; VorbisComment block added by the encoder.
+		{want: "farcloser/flac"},
+		// Metadata blocks retained.
+		{blocks: []*meta.Block{comment}, want: ""},
+		{blocks: []*meta.Block{libflac}, want: "reference libFLAC 1.3.1 20141125"},
+		// Vendor string of options.
+		{opts: flac.EncodeOptions{Vendor: "qa pipeline"}, blocks: []*meta.Block{comment}, want: "qa pipeline"},
+		{opts: flac.EncodeOptions{Vendor: "qa pipeline"}, want: "qa pipeline"},
+	}
+	for _, g := range golden {
+		out := new(bytes.Buffer)
+		enc, err := flac.NewEncoderWithOptions(out, info, &g.opts, g.blocks...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for num := 0; num < 4; num++ {
+			if err := enc.WriteFrame(makeTestFrame(info, num)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data := out.Bytes()
+		stream, err := flac.Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := stream.Vendor(); got != g.want {
+			t.Errorf("vendor mismatch; expected %q, got %q", g.want, got)
+		}
+		if g.want != "farcloser/flac" {
+			continue
+		}
+		// The encoder is identified by its vendor string.
+		a, err := flac.AnalyzeEncoder(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Guess.Name != "farcloser/flac" || a.Guess.Version != "" {
+			t.Errorf("guess mismatch; expected farcloser/flac, got %v", a.Guess)
+		}
+	}
+	// The vendor string of the given VorbisComment block is not modified.
+	if vendor := comment.Body.(*meta.VorbisComment).Vendor; vendor != "" {
+		t.Errorf("vendor string of metadata block modified; got %q", vendor)
+	}
+}
@@ -0,0 +1,125 @@
+package flac
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+// A WatchdogLimit identifies a limit of the decoder watchdog.
+type WatchdogLimit uint8
+
+// Limits of the decoder watchdog.
+const (
+	// LimitSampleRatio limits the ratio of decoded samples to consumed bytes;
+	// see DecodeOptions.MaxSampleRatio.
+	LimitSampleRatio WatchdogLimit = iota + 1
+	// LimitFrameTime limits the time spent decoding a frame; see
+	// DecodeOptions.MaxFrameTime.
+	LimitFrameTime
+)
+
+// A WatchdogError reports that decoding a stream exceeded a limit of the
+// decoder watchdog, as is the case for pathological inputs such as
+// decompression bombs.
+type WatchdogError struct {
+	// Exceeded limit.
+	Limit WatchdogLimit
+	// Sample number of the first sample of the frame exceeding the limit.
+	SampleNum uint64
+	// Ratio of the samples decoded to the bytes consumed by the audio frames
+	// so far, for LimitSampleRatio.
+	Ratio float64
+	// Time spent decoding the frame, for LimitFrameTime.
+	Time time.Duration
+}
+
+// Error returns the error message of the exceeded watchdog limit.
+func (e *WatchdogError) Error() string {
+	var msg string
+	switch e.Limit {
+	case LimitSampleRatio:
+		msg = fmt.Sprintf("ratio of decoded samples to consumed bytes (%.1f) exceeds limit", e.Ratio)
+	case LimitFrameTime:
+		msg = fmt.Sprintf("frame decode time (%v) exceeds limit", e.Time)
+	default:
+		msg = fmt.Sprintf("limit %d exceeded", e.Limit)
+	}
+	return fmt.Sprintf("flac: watchdog; %s at sample %d", msg, e.SampleNum)
+}
+
+// minWatchdogSamples specifies the number of decoded samples (across all
+// channels) below which the ratio of decoded samples to consumed bytes is not
+// limited; i.e. about 12 seconds of stereo audio at 44.1 kHz. As such, short
+// streams of silence, which consist of a few bytes per frame, are not rejected.
+const minWatchdogSamples = 1 << 20
+
+// watchdog enforces the watchdog limits of the decoder options.
+type watchdog struct {
+	// Maximum ratio of decoded samples to consumed bytes; 0 if unlimited.
+	maxRatio float64
+	// Maximum time spent decoding a frame; 0 if unlimited.
+	maxTime time.Duration
+	// Number of samples decoded since the watchdog was last reset, across all
+	// channels.
+	nsamples uint64
+	// Byte offset of the audio frames at which the watchdog was last reset,
+	// relative to the first frame; e.g. the frame sought by Stream.Seek.
+	offset int64
+}
+
+// newWatchdog returns a watchdog enforcing the limits of the given decoder
+// options; or nil if unlimited.
+func newWatchdog(opts *DecodeOptions) *watchdog {
+	if opts.MaxSampleRatio == 0 && opts.MaxFrameTime == 0 {
+		return nil
+	}
+	return &watchdog{maxRatio: opts.MaxSampleRatio, maxTime: opts.MaxFrameTime}
+}
+
+// reset resets the count of decoded samples and consumed bytes of the watchdog,
+// once the stream has been repositioned at the given byte offset, relative to
+// the first frame.
+func (w *watchdog) reset(offset int64) {
+	w.nsamples = 0
+	w.offset = offset
+}
+
+// check checks the given decoded frame, whose decoding started at the given
+// time, against the limits of the watchdog. The audio frames of the stream have
+// consumed size bytes so far, relative to the first frame, including the given
+// frame.
+func (w *watchdog) check(f *frame.Frame, sampleNum uint64, start time.Time, size int64) error {
+	if w.maxTime != 0 {
+		if d := time.Since(start); d > w.maxTime {
+			return &WatchdogError{Limit: LimitFrameTime, SampleNum: sampleNum, Time: d}
+		}
+	}
+	w.nsamples += uint64(f.BlockSize) * uint64(len(f.Subframes))
+	size -= w.offset
+	if w.maxRatio != 0 && w.nsamples > minWatchdogSamples && size > 0 {
+		if ratio := float64(w.nsamples) / float64(size); ratio > w.maxRatio {
+			return &WatchdogError{Limit: LimitSampleRatio, SampleNum: sampleNum, Ratio: ratio}
+		}
+	}
+	return nil
+}
+
+// watchStart returns the start time of decoding the next frame if the decode
+// time is limited by the watchdog, and the zero time otherwise.
+func (stream *Stream) watchStart() time.Time {
+	if stream.watchdog == nil || stream.watchdog.maxTime == 0 {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// watch checks the given decoded frame, whose decoding started at the given
+// time, against the limits of the watchdog; if any.
+func (stream *Stream) watch(f *frame.Frame, start time.Time) error {
+	if stream.watchdog == nil {
+		return nil
+	}
+	return stream.watchdog.check(f, stream.sampleNumber(f), start, stream.Offset()-stream.dataStart)
+}
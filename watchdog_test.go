@@ -0,0 +1,165 @@
+package flac_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// encodeSilence returns a stream of the given number of frames of digital
+// silence, holding 4096 samples of 2 channels each.
+func encodeSilence(t *testing.T, nframes int) []byte {
+	t.Helper()
+	const blockSize = 4096
+	info := &meta.StreamInfo{
+		BlockSizeMin:  blockSize,
+		BlockSizeMax:  blockSize,
+		SampleRate:    44100,
+		NChannels:     2,
+		BitsPerSample: 16,
+	}
+	buf := &bytes.Buffer{}
+	enc, err := flac.NewEncoder(buf, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < nframes; i++ {
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         blockSize,
+				SampleRate:        info.SampleRate,
+				Channels:          frame.ChannelsLR,
+				BitsPerSample:     info.BitsPerSample,
+				Num:               uint64(i),
+			},
+		}
+		for channel := 0; channel < 2; channel++ {
+			f.Subframes = append(f.Subframes, &frame.Subframe{
+				SubHeader: frame.SubHeader{Pred: frame.PredConstant},
+				Samples:   make([]int32, blockSize),
+				NSamples:  blockSize,
+			})
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decodeWatched decodes the frames of the given stream, using the given decoder
+// options.
+func decodeWatched(data []byte, opts *flac.DecodeOptions) error {
+	stream, err := flac.NewWithOptions(bytes.NewReader(data), opts)
+	if err != nil {
+		return err
+	}
+	for {
+		if _, err := stream.ParseNext(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	// Silence of about 1.3 million samples, in about 15 bytes per frame.
+	silence := encodeSilence(t, 160)
+	music, err := os.ReadFile("testdata/172960.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decodeWatched(silence, &flac.DecodeOptions{MaxSampleRatio: 1000}); err != nil {
+		t.Errorf("silence below limit: unable to decode; %v", err)
+	}
+	if err := decodeWatched(music, &flac.DecodeOptions{MaxSampleRatio: 2}); err != nil {
+		t.Errorf("music: unable to decode; %v", err)
+	}
+	err = decodeWatched(silence, &flac.DecodeOptions{MaxSampleRatio: 100})
+	var e *flac.WatchdogError
+	if !errors.As(err, &e) || e.Limit != flac.LimitSampleRatio {
+		t.Fatalf("silence above limit: error mismatch; expected *flac.WatchdogError of LimitSampleRatio, got %v", err)
+	}
+	if e.Ratio <= 100 || e.SampleNum == 0 {
+		t.Errorf("silence above limit: unexpected watchdog error %+v", e)
+	}
+
+	for _, lowMem := range []bool{false, true} {
+		err := decodeWatched(music, &flac.DecodeOptions{MaxFrameTime: time.Nanosecond, LowMemory: lowMem})
+		if !errors.As(err, &e) || e.Limit != flac.LimitFrameTime {
+			t.Errorf("low memory %v: error mismatch; expected *flac.WatchdogError of LimitFrameTime, got %v", lowMem, err)
+		}
+	}
+	if err := decodeWatched(music, &flac.DecodeOptions{MaxFrameTime: time.Minute}); err != nil {
+		t.Errorf("music: unable to decode; %v", err)
+	}
+
+	// Invalid limits.
+	for _, opts := range []*flac.DecodeOptions{{MaxSampleRatio: -1}, {MaxFrameTime: -time.Second}} {
+		if _, err := flac.NewWithOptions(bytes.NewReader(music), opts); err == nil {
+			t.Errorf("options %+v: expected error for invalid limit", opts)
+		}
+	}
+}
+
+func TestWatchdogSeek(t *testing.T) {
+	// Verbatim audio of 2^20 samples of 2 channels; about 0.5 samples per byte.
+	info := &meta.StreamInfo{
+		BlockSizeMin:  4096,
+		BlockSizeMax:  4096,
+		SampleRate:    44100,
+		NChannels:     2,
+		BitsPerSample: 16,
+	}
+	buf := &bytes.Buffer{}
+	enc, err := flac.NewEncoder(buf, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.AnalysisEnabled = false
+	for num := 0; num < 1<<20/4096; num++ {
+		if err := enc.WriteFrame(makeTestFrame(info, num)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.NewStream(bytes.NewReader(buf.Bytes()), flac.WithSeeking(), flac.WithLimits(flac.Limits{MaxSampleRatio: 5}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	decodeAll := func() {
+		t.Helper()
+		for {
+			if _, err := stream.ParseNext(); err != nil {
+				if err == io.EOF {
+					return
+				}
+				t.Fatal(err)
+			}
+		}
+	}
+	decodeAll()
+	// Samples decoded before seeking are not accounted for by the watchdog.
+	for _, sampleNum := range []uint64{0, 1 << 19, 4096} {
+		if _, err := stream.Seek(sampleNum); err != nil {
+			t.Fatalf("unable to seek to sample %d; %v", sampleNum, err)
+		}
+		decodeAll()
+	}
+}
@@ -0,0 +1,196 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Bext is the broadcast audio extension chunk of a Broadcast Wave (BWF) file.
+//
+// ref: https://tech.ebu.ch/docs/tech/tech3285.pdf
+type Bext struct {
+	// Description of the sound sequence; at most 256 bytes.
+	Description string
+	// Name of the originator; at most 32 bytes.
+	Originator string
+	// Reference of the originator; at most 32 bytes.
+	OriginatorReference string
+	// Date of creation in the format "yyyy-mm-dd"; at most 10 bytes.
+	OriginationDate string
+	// Time of creation in the format "hh-mm-ss"; at most 8 bytes.
+	OriginationTime string
+	// Sample number of the first sample, counted from midnight.
+	TimeReference uint64
+	// Version of the chunk.
+	Version uint16
+	// SMPTE unique material identifier (UMID) of version 1 or later; a basic
+	// UMID occupies the first 32 bytes.
+	UMID [64]byte
+	// Loudness values of version 2 or later, in hundredths of LUFS, LU or dBTP.
+	LoudnessValue, LoudnessRange, MaxTruePeakLevel, MaxMomentaryLoudness, MaxShortTermLoudness int16
+	// Coding history; one line per coding process, terminated by CR LF.
+	CodingHistory string
+}
+
+// bextSize specifies the size in bytes of the fixed-size fields of the bext
+// chunk, preceding the coding history.
+const bextSize = 602
+
+// ParseBext parses the body of a bext chunk.
+func ParseBext(body []byte) (*Bext, error) {
+	if len(body) < bextSize {
+		return nil, fmt.Errorf("wav.ParseBext: bext chunk of %d bytes too short; expected at least %d bytes", len(body), bextSize)
+	}
+	le := binary.LittleEndian
+	b := &Bext{
+		Description:          bextString(body[0:256]),
+		Originator:           bextString(body[256:288]),
+		OriginatorReference:  bextString(body[288:320]),
+		OriginationDate:      bextString(body[320:330]),
+		OriginationTime:      bextString(body[330:338]),
+		TimeReference:        le.Uint64(body[338:346]),
+		Version:              le.Uint16(body[346:348]),
+		LoudnessValue:        int16(le.Uint16(body[412:414])),
+		LoudnessRange:        int16(le.Uint16(body[414:416])),
+		MaxTruePeakLevel:     int16(le.Uint16(body[416:418])),
+		MaxMomentaryLoudness: int16(le.Uint16(body[418:420])),
+		MaxShortTermLoudness: int16(le.Uint16(body[420:422])),
+		CodingHistory:        bextString(body[bextSize:]),
+	}
+	copy(b.UMID[:], body[348:412])
+	return b, nil
+}
+
+// bextString returns the string of a NUL-padded field of the bext chunk.
+func bextString(field []byte) string {
+	if i := bytes.IndexByte(field, 0); i != -1 {
+		field = field[:i]
+	}
+	return string(field)
+}
+
+// Bytes returns the body of the bext chunk.
+func (b *Bext) Bytes() ([]byte, error) {
+	body := make([]byte, bextSize, bextSize+len(b.CodingHistory))
+	fields := []struct {
+		name  string
+		value string
+		field []byte
+	}{
+		{name: "description", value: b.Description, field: body[0:256]},
+		{name: "originator", value: b.Originator, field: body[256:288]},
+		{name: "originator reference", value: b.OriginatorReference, field: body[288:320]},
+		{name: "origination date", value: b.OriginationDate, field: body[320:330]},
+		{name: "origination time", value: b.OriginationTime, field: body[330:338]},
+	}
+	for _, f := range fields {
+		if len(f.value) > len(f.field) {
+			return nil, fmt.Errorf("wav.Bext.Bytes: %s of %d bytes exceeds the %d bytes of the bext field", f.name, len(f.value), len(f.field))
+		}
+		copy(f.field, f.value)
+	}
+	le := binary.LittleEndian
+	le.PutUint64(body[338:346], b.TimeReference)
+	le.PutUint16(body[346:348], b.Version)
+	copy(body[348:412], b.UMID[:])
+	le.PutUint16(body[412:414], uint16(b.LoudnessValue))
+	le.PutUint16(body[414:416], uint16(b.LoudnessRange))
+	le.PutUint16(body[416:418], uint16(b.MaxTruePeakLevel))
+	le.PutUint16(body[418:420], uint16(b.MaxMomentaryLoudness))
+	le.PutUint16(body[420:422], uint16(b.MaxShortTermLoudness))
+	return append(body, b.CodingHistory...), nil
+}
+
+// Vorbis comment keys of the fields of the bext chunk, as used by FFmpeg.
+const (
+	KeyDescription         = "DESCRIPTION"
+	KeyOriginator          = "ORIGINATOR"
+	KeyOriginatorReference = "ORIGINATOR_REFERENCE"
+	KeyOriginationDate     = "ORIGINATION_DATE"
+	KeyOriginationTime     = "ORIGINATION_TIME"
+	KeyTimeReference       = "TIME_REFERENCE"
+	KeyUMID                = "UMID"
+	KeyCodingHistory       = "CODING_HISTORY"
+)
+
+// Comments returns the fields of the bext chunk as Vorbis comments; empty
+// fields and a zero time reference are omitted. The time reference is stored
+// in decimal, and the UMID in hexadecimal. The version and loudness fields are
+// not mapped.
+func (b *Bext) Comments() [][2]string {
+	var tags [][2]string
+	add := func(key, value string) {
+		if value != "" {
+			tags = append(tags, [2]string{key, value})
+		}
+	}
+	add(KeyDescription, b.Description)
+	add(KeyOriginator, b.Originator)
+	add(KeyOriginatorReference, b.OriginatorReference)
+	add(KeyOriginationDate, b.OriginationDate)
+	add(KeyOriginationTime, b.OriginationTime)
+	if b.TimeReference != 0 {
+		add(KeyTimeReference, strconv.FormatUint(b.TimeReference, 10))
+	}
+	if b.UMID != [64]byte{} {
+		umid := b.UMID[:]
+		// Omit the zero-valued extended part of a basic UMID.
+		if [32]byte(umid[32:]) == [32]byte{} {
+			umid = umid[:32]
+		}
+		add(KeyUMID, strings.ToUpper(hex.EncodeToString(umid)))
+	}
+	add(KeyCodingHistory, b.CodingHistory)
+	return tags
+}
+
+// BextFromComments returns the bext chunk of the given Vorbis comments; the
+// inverse of Bext.Comments. The version is 1 if a UMID is present, and 0
+// otherwise. It returns false if the comments hold none of the fields specific
+// to the bext chunk; a description alone is not mapped.
+func BextFromComments(tags [][2]string) (*Bext, bool, error) {
+	b := &Bext{}
+	found := false
+	for _, tag := range tags {
+		key, value := strings.ToUpper(tag[0]), tag[1]
+		switch key {
+		case KeyDescription:
+			b.Description = value
+			continue
+		case KeyOriginator:
+			b.Originator = value
+		case KeyOriginatorReference:
+			b.OriginatorReference = value
+		case KeyOriginationDate:
+			b.OriginationDate = value
+		case KeyOriginationTime:
+			b.OriginationTime = value
+		case KeyTimeReference:
+			x, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("wav.BextFromComments: invalid time reference %q; %w", value, err)
+			}
+			b.TimeReference = x
+		case KeyUMID:
+			umid, err := hex.DecodeString(value)
+			if err != nil || (len(umid) != 32 && len(umid) != 64) {
+				return nil, false, fmt.Errorf("wav.BextFromComments: invalid UMID %q; expected 32 or 64 bytes in hexadecimal", value)
+			}
+			copy(b.UMID[:], umid)
+			b.Version = 1
+		case KeyCodingHistory:
+			b.CodingHistory = value
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return b, true, nil
+}
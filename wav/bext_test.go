@@ -0,0 +1,136 @@
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+	"github.com/mewkiz/flac/wav"
+)
+
+// testBext returns the bext chunk of the tests.
+func testBext() *wav.Bext {
+	b := &wav.Bext{
+		Description:         "Interview, take 1",
+		Originator:          "Field recorder",
+		OriginatorReference: "FR0001",
+		OriginationDate:     "2024-03-01",
+		OriginationTime:     "14-30-00",
+		TimeReference:       2116800000,
+		Version:             1,
+		CodingHistory:       "A=PCM,F=48000,W=24,M=stereo,T=original\r\n",
+	}
+	for i := 0; i < 32; i++ {
+		b.UMID[i] = byte(i + 1)
+	}
+	return b
+}
+
+func TestBextComments(t *testing.T) {
+	want := testBext()
+	body, err := want.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := wav.ParseBext(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bext mismatch; expected %#v, got %#v", want, got)
+	}
+	tags := want.Comments()
+	if len(tags) != 8 {
+		t.Fatalf("number of Vorbis comments mismatch; expected 8, got %d: %q", len(tags), tags)
+	}
+	got, ok, err := wav.BextFromComments(tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Fatalf("bext of Vorbis comments mismatch; expected %#v, got %#v", want, got)
+	}
+	// A description alone is not mapped.
+	if _, ok, _ := wav.BextFromComments([][2]string{{"description", "foo"}}); ok {
+		t.Fatalf("unexpected bext of description")
+	}
+	if _, _, err := wav.BextFromComments([][2]string{{"UMID", "xyz"}}); err == nil {
+		t.Fatalf("expected error for invalid UMID")
+	}
+	if _, err := (&wav.Bext{OriginationDate: "2024-03-01T00"}).Bytes(); err == nil {
+		t.Fatalf("expected error for oversized origination date")
+	}
+}
+
+func TestBextImportExport(t *testing.T) {
+	bext := testBext()
+	body, err := bext.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Append reserved bytes to the chunk, which are preserved only as foreign
+	// metadata.
+	body = append(body, 0, 0)
+	src := makeWAV(2, 16, 5000, [][]byte{chunk(binary.LittleEndian, "bext", body)}, nil)
+	dir := t.TempDir()
+	wavPath := filepath.Join(dir, "in.wav")
+	flacPath := filepath.Join(dir, "out.flac")
+	if err := os.WriteFile(wavPath, src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &wav.ImportOptions{
+		KeepForeignMetadata: true,
+		BextComments:        true,
+		Blocks: []*meta.Block{
+			{Body: &meta.VorbisComment{Vendor: "test", Tags: [][2]string{{"TITLE", "Interview"}}}},
+		},
+	}
+	if err := wav.ImportFile(flacPath, wavPath, opts); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := flac.ParseFile(flacPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var tags [][2]string
+	for _, block := range stream.Blocks {
+		if comment, ok := block.Body.(*meta.VorbisComment); ok {
+			tags = comment.Tags
+		}
+	}
+	want := append([][2]string{{"TITLE", "Interview"}}, bext.Comments()...)
+	if !reflect.DeepEqual(tags, want) {
+		t.Fatalf("Vorbis comments mismatch; expected %q, got %q", want, tags)
+	}
+
+	// The bext chunk of the foreign metadata is restored exactly.
+	buf := &bytes.Buffer{}
+	if err := wav.Export(buf, stream, &wav.ExportOptions{KeepForeignMetadata: true, BextComments: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), src) {
+		t.Fatalf("restored WAVE file differs from the original")
+	}
+
+	// The bext chunk is recreated from the Vorbis comments.
+	stream, err = flac.ParseFile(flacPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	buf.Reset()
+	if err := wav.Export(buf, stream, &wav.ExportOptions{BextComments: true}); err != nil {
+		t.Fatal(err)
+	}
+	body, _ = bext.Bytes()
+	want2 := makeWAV(2, 16, 5000, [][]byte{chunk(binary.LittleEndian, "bext", body)}, nil)
+	if !bytes.Equal(buf.Bytes(), want2) {
+		t.Fatalf("WAVE file with recreated bext chunk mismatch")
+	}
+}
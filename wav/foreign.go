@@ -0,0 +1,353 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac/meta"
+)
+
+// Registered application IDs of the APPLICATION metadata blocks holding foreign
+// metadata.
+//
+// ref: https://www.xiph.org/flac/id.html
+const (
+	// AppRIFF identifies the chunks of a RIFF/WAVE file ("riff").
+	AppRIFF uint32 = 0x72696666
+	// AppAIFF identifies the chunks of an AIFF or AIFF-C file ("aiff").
+	AppAIFF uint32 = 0x61696666
+)
+
+// A Container is the container format of foreign metadata.
+type Container uint8
+
+// Container formats.
+const (
+	// RIFF/WAVE, with little-endian chunk sizes.
+	ContainerRIFF Container = iota + 1
+	// AIFF or AIFF-C, with big-endian chunk sizes.
+	ContainerAIFF
+)
+
+// String returns a string representation of the container format.
+func (c Container) String() string {
+	switch c {
+	case ContainerRIFF:
+		return "RIFF"
+	case ContainerAIFF:
+		return "AIFF"
+	}
+	return fmt.Sprintf("<unknown container %d>", uint8(c))
+}
+
+// appID returns the application ID of the APPLICATION metadata blocks of the
+// container format.
+func (c Container) appID() uint32 {
+	if c == ContainerAIFF {
+		return AppAIFF
+	}
+	return AppRIFF
+}
+
+// byteOrder returns the byte order of the chunk sizes of the container format.
+func (c Container) byteOrder() binary.ByteOrder {
+	if c == ContainerAIFF {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// audioID returns the chunk ID of the audio chunk of the container format.
+func (c Container) audioID() string {
+	if c == ContainerAIFF {
+		return "SSND"
+	}
+	return "data"
+}
+
+// audioHeaderSize returns the size in bytes of the audio chunk preceding the
+// audio payload; the chunk header, and for AIFF the offset and block size
+// fields of the SSND chunk.
+func (c Container) audioHeaderSize() int {
+	if c == ContainerAIFF {
+		return 16
+	}
+	return 8
+}
+
+// ForeignMetadata holds the chunks of a RIFF/WAVE or AIFF file other than the
+// audio payload, for bit-exact restoration of the file from the decoded audio
+// samples of a FLAC stream.
+//
+// Foreign metadata is stored in APPLICATION metadata blocks, one per chunk,
+// following the convention of `flac --keep-foreign-metadata`; as such the
+// foreign metadata of FLAC streams encoded by libFLAC is restored by Export,
+// and vice versa.
+type ForeignMetadata struct {
+	// Container format.
+	Container Container
+	// Raw bytes of the chunks of the file in file order, including chunk
+	// headers and pad bytes. The first chunk is the 12-byte file header; i.e.
+	// the "RIFF" or "FORM" chunk header followed by the form type. The audio
+	// chunk ("data" or "SSND") holds the bytes preceding the audio payload; i.e.
+	// the chunk header, and for AIFF the offset and block size fields.
+	Chunks [][]byte
+}
+
+// ErrNoForeignMetadata reports that a FLAC stream holds no foreign metadata.
+var ErrNoForeignMetadata = errors.New("wav: no foreign metadata")
+
+// maxChunkSize specifies the maximum size in bytes of a chunk stored as foreign
+// metadata, as limited by the 24-bit length of metadata blocks and the 32-bit
+// application ID.
+const maxChunkSize = 1<<24 - 1 - 4
+
+// ReadForeignMetadata reads the chunks of the RIFF/WAVE or AIFF file of r, and
+// returns its foreign metadata. The audio payload is skipped. On return, the
+// read position of r is at the start of the audio payload.
+func ReadForeignMetadata(r io.ReadSeeker) (*ForeignMetadata, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("wav.ReadForeignMetadata: unable to read file header; %w", err)
+	}
+	fm := &ForeignMetadata{Chunks: [][]byte{hdr[:]}}
+	switch {
+	case string(hdr[0:4]) == "RIFF" && string(hdr[8:12]) == "WAVE":
+		fm.Container = ContainerRIFF
+	case string(hdr[0:4]) == "FORM" && (string(hdr[8:12]) == "AIFF" || string(hdr[8:12]) == "AIFC"):
+		fm.Container = ContainerAIFF
+	default:
+		return nil, fmt.Errorf("wav.ReadForeignMetadata: unsupported file header %q", hdr[:])
+	}
+	order := fm.Container.byteOrder()
+	// Offset of the end of the form chunk.
+	end := 8 + int64(order.Uint32(hdr[4:8]))
+	pos := int64(len(hdr))
+	for pos < end {
+		var chunkHdr [8]byte
+		n, err := io.ReadFull(r, chunkHdr[:])
+		if err != nil {
+			if n == 0 && err == io.EOF {
+				// Tolerate form chunks whose size exceeds the file.
+				break
+			}
+			return nil, fmt.Errorf("wav.ReadForeignMetadata: unable to read chunk header at offset %d; %w", pos, err)
+		}
+		id := string(chunkHdr[0:4])
+		size := int64(order.Uint32(chunkHdr[4:8]))
+		padded := size + size&1
+		if id == fm.Container.audioID() {
+			chunk := append([]byte(nil), chunkHdr[:]...)
+			if fm.Container == ContainerAIFF {
+				var ssnd [8]byte
+				if _, err := io.ReadFull(r, ssnd[:]); err != nil {
+					return nil, fmt.Errorf("wav.ReadForeignMetadata: unable to read SSND chunk; %w", err)
+				}
+				if offset := binary.BigEndian.Uint32(ssnd[0:4]); offset != 0 {
+					return nil, fmt.Errorf("wav.ReadForeignMetadata: unsupported offset %d of SSND chunk", offset)
+				}
+				chunk = append(chunk, ssnd[:]...)
+			}
+			fm.Chunks = append(fm.Chunks, chunk)
+			if _, err := r.Seek(8+padded-int64(len(chunk)), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+			pos += 8 + padded
+			continue
+		}
+		if 8+padded > maxChunkSize {
+			return nil, fmt.Errorf("wav.ReadForeignMetadata: size of chunk %q (%d bytes) exceeds the limit of APPLICATION metadata blocks", id, size)
+		}
+		chunk := make([]byte, 8+padded)
+		copy(chunk, chunkHdr[:])
+		if _, err := io.ReadFull(r, chunk[8:]); err != nil {
+			return nil, fmt.Errorf("wav.ReadForeignMetadata: unable to read chunk %q; %w", id, err)
+		}
+		fm.Chunks = append(fm.Chunks, chunk)
+		pos += 8 + padded
+	}
+	if err := fm.validate(); err != nil {
+		return nil, fmt.Errorf("wav.ReadForeignMetadata: %w", err)
+	}
+	if _, err := r.Seek(fm.AudioOffset(), io.SeekStart); err != nil {
+		return nil, err
+	}
+	return fm, nil
+}
+
+// ParseForeignMetadata returns the foreign metadata stored in the given
+// APPLICATION metadata blocks, or ErrNoForeignMetadata if not present.
+func ParseForeignMetadata(blocks []*meta.Block) (*ForeignMetadata, error) {
+	var fm *ForeignMetadata
+	for _, block := range blocks {
+		app, ok := block.Body.(*meta.Application)
+		if !ok {
+			continue
+		}
+		var c Container
+		switch app.ID {
+		case AppRIFF:
+			c = ContainerRIFF
+		case AppAIFF:
+			c = ContainerAIFF
+		default:
+			continue
+		}
+		if fm == nil {
+			fm = &ForeignMetadata{Container: c}
+		} else if fm.Container != c {
+			return nil, fmt.Errorf("wav.ParseForeignMetadata: foreign metadata of both %v and %v container formats", fm.Container, c)
+		}
+		fm.Chunks = append(fm.Chunks, app.Data)
+	}
+	if fm == nil {
+		return nil, ErrNoForeignMetadata
+	}
+	if err := fm.validate(); err != nil {
+		return nil, fmt.Errorf("wav.ParseForeignMetadata: %w", err)
+	}
+	return fm, nil
+}
+
+// Blocks returns the APPLICATION metadata blocks holding the foreign metadata,
+// one per chunk.
+func (fm *ForeignMetadata) Blocks() []*meta.Block {
+	blocks := make([]*meta.Block, len(fm.Chunks))
+	for i, chunk := range fm.Chunks {
+		blocks[i] = &meta.Block{
+			Header: meta.Header{
+				Type:   meta.TypeApplication,
+				Length: int64(4 + len(chunk)),
+			},
+			Body: &meta.Application{
+				ID:   fm.Container.appID(),
+				Data: chunk,
+			},
+		}
+	}
+	return blocks
+}
+
+// Chunk returns the body of the first chunk with the given ID, excluding the
+// chunk header and pad byte; or false if not present.
+func (fm *ForeignMetadata) Chunk(id string) ([]byte, bool) {
+	order := fm.Container.byteOrder()
+	for _, chunk := range fm.Chunks[1:] {
+		if string(chunk[0:4]) != id || id == fm.Container.audioID() {
+			continue
+		}
+		size := order.Uint32(chunk[4:8])
+		return chunk[8 : 8+size], true
+	}
+	return nil, false
+}
+
+// AudioOffset returns the byte offset of the audio payload within the file.
+func (fm *ForeignMetadata) AudioOffset() int64 {
+	var offset int64
+	for i, chunk := range fm.Chunks {
+		offset += int64(len(chunk))
+		if i > 0 && fm.isAudio(chunk) {
+			break
+		}
+	}
+	return offset
+}
+
+// AudioSize returns the size in bytes of the audio payload, excluding the pad
+// byte.
+func (fm *ForeignMetadata) AudioSize() int64 {
+	for _, chunk := range fm.Chunks[1:] {
+		if fm.isAudio(chunk) {
+			size := int64(fm.Container.byteOrder().Uint32(chunk[4:8]))
+			return size - int64(fm.Container.audioHeaderSize()-8)
+		}
+	}
+	return 0
+}
+
+// Write writes the chunks of the foreign metadata to w, restoring the original
+// file. The audio payload of AudioSize bytes is read from audio, and written
+// in place of the audio chunk.
+func (fm *ForeignMetadata) Write(w io.Writer, audio io.Reader) error {
+	for i, chunk := range fm.Chunks {
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		if i == 0 || !fm.isAudio(chunk) {
+			continue
+		}
+		size := fm.AudioSize()
+		n, err := io.CopyN(w, audio, size)
+		if err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("wav.ForeignMetadata.Write: audio payload ended after %d bytes; expected %d bytes", n, size)
+			}
+			return err
+		}
+		if size&1 != 0 {
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isAudio reports whether the given chunk is the audio chunk.
+func (fm *ForeignMetadata) isAudio(chunk []byte) bool {
+	return string(chunk[0:4]) == fm.Container.audioID()
+}
+
+// validate validates the chunks of the foreign metadata.
+func (fm *ForeignMetadata) validate() error {
+	if len(fm.Chunks) == 0 || len(fm.Chunks[0]) != 12 {
+		return errors.New("missing file header")
+	}
+	hdr := fm.Chunks[0]
+	form, formType := string(hdr[0:4]), string(hdr[8:12])
+	switch fm.Container {
+	case ContainerRIFF:
+		if form != "RIFF" || formType != "WAVE" {
+			return fmt.Errorf("invalid RIFF/WAVE file header %q", hdr)
+		}
+	case ContainerAIFF:
+		if form != "FORM" || (formType != "AIFF" && formType != "AIFC") {
+			return fmt.Errorf("invalid AIFF file header %q", hdr)
+		}
+	default:
+		return fmt.Errorf("invalid container format %v", fm.Container)
+	}
+	order := fm.Container.byteOrder()
+	naudio := 0
+	for _, chunk := range fm.Chunks[1:] {
+		if len(chunk) < 8 {
+			return fmt.Errorf("chunk of %d bytes too short for chunk header", len(chunk))
+		}
+		id := string(chunk[0:4])
+		size := int(order.Uint32(chunk[4:8]))
+		if fm.isAudio(chunk) {
+			if len(chunk) != fm.Container.audioHeaderSize() || size < fm.Container.audioHeaderSize()-8 {
+				return fmt.Errorf("invalid audio chunk %q", id)
+			}
+			naudio++
+			continue
+		}
+		if len(chunk) != 8+size+size&1 {
+			return fmt.Errorf("size of chunk %q (%d bytes) inconsistent with chunk header (%d bytes)", id, len(chunk)-8, size)
+		}
+	}
+	if naudio != 1 {
+		return fmt.Errorf("expected one %q chunk, got %d", fm.Container.audioID(), naudio)
+	}
+	formatID := "fmt "
+	if fm.Container == ContainerAIFF {
+		formatID = "COMM"
+	}
+	if _, ok := fm.Chunk(formatID); !ok {
+		return fmt.Errorf("missing %q chunk", formatID)
+	}
+	return nil
+}
@@ -0,0 +1,500 @@
+// Package wav implements conversion between WAVE files of PCM audio samples and
+// FLAC streams; i.e. the import of WAVE files for archiving in FLAC, and the
+// export of FLAC streams to WAVE files.
+//
+// The chunks of a WAVE file other than the audio samples (e.g. the broadcast
+// extension chunk of Broadcast Wave files) may be preserved as foreign
+// metadata, and are restored bit-exact on export. The fields of the broadcast
+// extension chunk may furthermore be mapped to and from Vorbis comments:
+//
+//	// Archive the WAVE file, keeping its chunks, and tag the FLAC file with
+//	// the fields of its bext chunk.
+//	opts := &wav.ImportOptions{KeepForeignMetadata: true, BextComments: true}
+//	err := wav.ImportFile("take1.flac", "take1.wav", opts)
+//	...
+//	// Restore the original WAVE file.
+//	err = wav.ExportFile("take1.wav", "take1.flac", &wav.ExportOptions{KeepForeignMetadata: true})
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// ImportOptions specifies the options of a WAVE import. The zero value
+// specifies the default options.
+type ImportOptions struct {
+	// KeepForeignMetadata specifies whether to store the chunks of the WAVE file
+	// as foreign metadata in APPLICATION metadata blocks, for bit-exact
+	// restoration of the WAVE file on export.
+	KeepForeignMetadata bool
+	// BextComments specifies whether to map the fields of the broadcast
+	// extension (bext) chunk of Broadcast Wave files to Vorbis comments; see
+	// Bext.Comments.
+	BextComments bool
+	// Options of the FLAC encoder; nil specifies the default options.
+	Encode *flac.EncodeOptions
+	// Additional metadata blocks of the FLAC stream, stored before the foreign
+	// metadata. The Vorbis comments of the bext chunk are appended to the
+	// VorbisComment metadata block, if present.
+	Blocks []*meta.Block
+}
+
+// importBlockSize specifies the block size of imported frames.
+const importBlockSize = 4096
+
+// Import reads the WAVE file of r and encodes its audio samples as a FLAC
+// stream, writing to w, using the given import options; a nil value specifies
+// the default options. If w implements io.WriteSeeker, the StreamInfo block is
+// updated once the audio samples have been encoded, and if w implements
+// io.Closer, it is closed; see flac.Encoder.Close.
+func Import(w io.Writer, r io.ReadSeeker, opts *ImportOptions) error {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+	fm, err := ReadForeignMetadata(r)
+	if err != nil {
+		return err
+	}
+	if fm.Container != ContainerRIFF {
+		return fmt.Errorf("wav.Import: unsupported container format %v", fm.Container)
+	}
+	format, err := fm.format()
+	if err != nil {
+		return err
+	}
+	info, err := meta.NewStreamInfo(format.sampleRate, uint8(format.nchannels), uint8(format.bitsPerSample), importBlockSize, importBlockSize)
+	if err != nil {
+		return fmt.Errorf("wav.Import: %w", err)
+	}
+	frameSize := int64(format.blockAlign)
+	if fm.AudioSize()%frameSize != 0 {
+		return fmt.Errorf("wav.Import: size of audio data (%d bytes) not a multiple of the block alignment (%d bytes)", fm.AudioSize(), frameSize)
+	}
+	info.NSamples = uint64(fm.AudioSize() / frameSize)
+	blocks := opts.Blocks
+	if body, ok := fm.Chunk("bext"); ok && opts.BextComments {
+		bext, err := ParseBext(body)
+		if err != nil {
+			return err
+		}
+		blocks = withComments(blocks, bext.Comments())
+	}
+	if opts.KeepForeignMetadata {
+		blocks = append(slices.Clip(blocks), fm.Blocks()...)
+	}
+	enc, err := flac.NewEncoderWithOptions(w, info, opts.Encode, blocks...)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(io.LimitReader(r, int64(info.NSamples)*frameSize))
+	buf := make([]byte, importBlockSize*frameSize)
+	for remaining := info.NSamples; remaining > 0; {
+		nsamples := min(remaining, importBlockSize)
+		remaining -= nsamples
+		data := buf[:nsamples*uint64(frameSize)]
+		if _, err := io.ReadFull(br, data); err != nil {
+			enc.Close()
+			return fmt.Errorf("wav.Import: unable to read audio samples; %w", err)
+		}
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(nsamples),
+				SampleRate:        info.SampleRate,
+				Channels:          frame.Channels(info.NChannels - 1),
+				BitsPerSample:     info.BitsPerSample,
+			},
+			Subframes: make([]*frame.Subframe, info.NChannels),
+		}
+		for channel := range f.Subframes {
+			f.Subframes[channel] = &frame.Subframe{
+				SubHeader: frame.SubHeader{
+					Pred: frame.PredVerbatim,
+				},
+				Samples:  make([]int32, nsamples),
+				NSamples: int(nsamples),
+			}
+		}
+		format.unpack(f.Subframes, data)
+		if err := enc.WriteFrame(f); err != nil {
+			enc.Close()
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// ImportFile reads the WAVE file at src and encodes its audio samples as a
+// FLAC file at dst. See Import for details.
+func ImportFile(dst, src string, opts *ImportOptions) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	// Hide the Close method of w from the encoder, to close w on error.
+	if err := Import(struct{ io.WriteSeeker }{w}, r, opts); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// ExportOptions specifies the options of a WAVE export. The zero value
+// specifies the default options.
+type ExportOptions struct {
+	// KeepForeignMetadata specifies whether to restore the chunks of the
+	// original WAVE file from the foreign metadata of the FLAC stream, if
+	// present. Otherwise, a canonical WAVE file is written.
+	KeepForeignMetadata bool
+	// BextComments specifies whether to write a broadcast extension (bext)
+	// chunk from the Vorbis comments of the FLAC stream, if present; see
+	// BextFromComments. The bext chunk of restored foreign metadata takes
+	// precedence, to preserve the original chunk exactly.
+	BextComments bool
+}
+
+// Export decodes the audio samples of the given FLAC stream, and writes them
+// as a WAVE file to w, using the given export options; a nil value specifies
+// the default options. The metadata blocks of the stream must have been parsed
+// (e.g. using flac.Parse) to restore foreign metadata.
+//
+// The total number of samples of the StreamInfo block must be specified, as
+// required by the WAVE format.
+func Export(w io.Writer, stream *flac.Stream, opts *ExportOptions) error {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	info := stream.Info
+	if info.NSamples == 0 {
+		return errors.New("wav.Export: total number of samples of stream not specified; required by WAVE format")
+	}
+	var fm *ForeignMetadata
+	if opts.KeepForeignMetadata {
+		var err error
+		fm, err = ParseForeignMetadata(stream.Blocks)
+		switch {
+		case err == ErrNoForeignMetadata:
+			fm = nil
+		case err != nil:
+			return err
+		case fm.Container != ContainerRIFF:
+			return fmt.Errorf("wav.Export: unsupported container format %v of foreign metadata", fm.Container)
+		}
+	}
+	if fm == nil {
+		var chunks [][]byte
+		if opts.BextComments {
+			bext, ok, err := bextFromStream(stream)
+			if err != nil {
+				return err
+			}
+			if ok {
+				body, err := bext.Bytes()
+				if err != nil {
+					return err
+				}
+				chunks = append(chunks, riffChunk("bext", body))
+			}
+		}
+		var err error
+		if fm, err = canonical(info, chunks...); err != nil {
+			return err
+		}
+	}
+	format, err := fm.format()
+	if err != nil {
+		return err
+	}
+	if format.nchannels != int(info.NChannels) || format.sampleRate != info.SampleRate || format.bitsPerSample != int(info.BitsPerSample) {
+		return fmt.Errorf("wav.Export: audio format of foreign metadata (%d Hz, %d channels, %d bits-per-sample) inconsistent with StreamInfo (%d Hz, %d channels, %d bits-per-sample)", format.sampleRate, format.nchannels, format.bitsPerSample, info.SampleRate, info.NChannels, info.BitsPerSample)
+	}
+	if want := int64(info.NSamples) * int64(format.blockAlign); fm.AudioSize() != want {
+		return fmt.Errorf("wav.Export: size of audio payload of foreign metadata (%d bytes) inconsistent with the total number of samples of StreamInfo (%d bytes)", fm.AudioSize(), want)
+	}
+	bw := bufio.NewWriter(w)
+	pr := &pcmReader{stream: stream, format: format}
+	if err := fm.Write(bw, pr); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ExportFile decodes the audio samples of the FLAC file at src, and writes them
+// as a WAVE file at dst. See Export for details.
+func ExportFile(dst, src string, opts *ExportOptions) error {
+	stream, err := flac.ParseFile(src)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := Export(w, stream, opts); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// pcmReader is an io.Reader of the interleaved PCM audio samples of a FLAC
+// stream, in the sample format of a WAVE file.
+type pcmReader struct {
+	// Underlying FLAC stream.
+	stream *flac.Stream
+	// Sample format.
+	format *format
+	// Pending bytes of the current audio frame.
+	buf []byte
+}
+
+// Read reads the interleaved PCM audio samples of the FLAC stream.
+func (pr *pcmReader) Read(p []byte) (n int, err error) {
+	for len(pr.buf) == 0 {
+		f, err := pr.stream.ParseNext()
+		if err != nil {
+			return 0, err
+		}
+		if len(f.Subframes) != pr.format.nchannels {
+			return 0, fmt.Errorf("wav.Export: channel count mismatch of frame %d; expected %d, got %d", f.Num, pr.format.nchannels, len(f.Subframes))
+		}
+		if pr.buf, err = f.AppendPCM(pr.buf[:0], pr.format.pcm()); err != nil {
+			return 0, err
+		}
+	}
+	n = copy(p, pr.buf)
+	pr.buf = pr.buf[n:]
+	return n, nil
+}
+
+// --- [ fmt chunk ] -----------------------------------------------------------
+
+// Format tags of the fmt chunk.
+const (
+	formatPCM        = 0x0001
+	formatExtensible = 0xFFFE
+)
+
+// subFormatPCM is the GUID of the PCM sub-format of WAVE_FORMAT_EXTENSIBLE,
+// excluding the leading format tag.
+var subFormatPCM = [14]byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71}
+
+// format is the sample format of a WAVE file.
+type format struct {
+	// Number of channels.
+	nchannels int
+	// Sample rate in Hz.
+	sampleRate uint32
+	// Size in bytes of one sample of each channel.
+	blockAlign int
+	// Number of valid bits per sample, and the size in bits of the container of
+	// each sample. Samples are left-justified within their container.
+	bitsPerSample, containerBits int
+}
+
+// format parses the fmt chunk of the foreign metadata.
+func (fm *ForeignMetadata) format() (*format, error) {
+	body, ok := fm.Chunk("fmt ")
+	if !ok || len(body) < 16 {
+		return nil, errors.New("wav: missing or truncated fmt chunk")
+	}
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	f := &format{
+		nchannels:     int(binary.LittleEndian.Uint16(body[2:4])),
+		sampleRate:    binary.LittleEndian.Uint32(body[4:8]),
+		blockAlign:    int(binary.LittleEndian.Uint16(body[12:14])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(body[14:16])),
+	}
+	switch tag {
+	case formatPCM:
+	case formatExtensible:
+		if len(body) < 40 || binary.LittleEndian.Uint16(body[24:26]) != formatPCM || [14]byte(body[26:40]) != subFormatPCM {
+			return nil, errors.New("wav: unsupported sub-format of WAVE_FORMAT_EXTENSIBLE; only PCM is supported")
+		}
+		if validBits := int(binary.LittleEndian.Uint16(body[18:20])); validBits != 0 {
+			f.bitsPerSample = validBits
+		}
+	default:
+		return nil, fmt.Errorf("wav: unsupported format tag 0x%04X; only PCM is supported", tag)
+	}
+	if f.nchannels < 1 || f.nchannels > 8 {
+		return nil, fmt.Errorf("wav: unsupported number of channels %d", f.nchannels)
+	}
+	if f.blockAlign%f.nchannels != 0 {
+		return nil, fmt.Errorf("wav: block alignment %d not a multiple of the number of channels %d", f.blockAlign, f.nchannels)
+	}
+	f.containerBits = 8 * f.blockAlign / f.nchannels
+	if f.containerBits < 8 || f.containerBits > 32 || f.bitsPerSample < 4 || f.bitsPerSample > f.containerBits {
+		return nil, fmt.Errorf("wav: unsupported sample format; %d bits-per-sample in %d-bit container", f.bitsPerSample, f.containerBits)
+	}
+	return f, nil
+}
+
+// unpack decodes the interleaved samples of data into the given subframes.
+func (f *format) unpack(subframes []*frame.Subframe, data []byte) {
+	nbytes := f.containerBits / 8
+	shift := uint(f.containerBits - f.bitsPerSample)
+	for i := range subframes[0].Samples {
+		for _, subframe := range subframes {
+			var x uint32
+			for j := nbytes - 1; j >= 0; j-- {
+				x = x<<8 | uint32(data[j])
+			}
+			data = data[nbytes:]
+			var sample int32
+			if nbytes == 1 {
+				// 8-bit samples are unsigned.
+				sample = int32(x) - 128
+			} else {
+				// Sign-extend the container.
+				sample = int32(x<<(32-f.containerBits)) >> (32 - f.containerBits)
+			}
+			subframe.Samples[i] = sample >> shift
+		}
+	}
+}
+
+// pcm returns the PCM sample format of the data chunk, with samples
+// left-justified within their container.
+func (f *format) pcm() frame.PCMFormat {
+	nbytes := f.containerBits / 8
+	return frame.PCMFormat{BytesPerSample: nbytes, Unsigned: nbytes == 1}
+}
+
+// defaultChannelMasks specifies the speaker positions of the channels of FLAC
+// streams, indexed by the number of channels.
+//
+// ref: https://www.xiph.org/flac/format.html#frame_header
+var defaultChannelMasks = [...]uint32{
+	1: 0x004, // front center
+	2: 0x003, // front left, front right
+	3: 0x007, // front left, front right, front center
+	4: 0x033, // front left, front right, back left, back right
+	5: 0x037, // front left, front right, front center, back left, back right
+	6: 0x03F, // 5.1: as above, with LFE
+	7: 0x70F, // 6.1: front left, front right, front center, LFE, back center, side left, side right
+	8: 0x63F, // 7.1: 5.1 with side left, side right
+}
+
+// canonical returns the foreign metadata of a canonical WAVE file of the audio
+// samples of a FLAC stream with the given StreamInfo block, and the given
+// chunks preceding the data chunk. The
+// WAVE_FORMAT_EXTENSIBLE format is used for streams of more than two channels,
+// or with a sample size not a multiple of 8 bits.
+func canonical(info *meta.StreamInfo, chunks ...[]byte) (*ForeignMetadata, error) {
+	nchannels := int(info.NChannels)
+	if nchannels < 1 || nchannels >= len(defaultChannelMasks) {
+		return nil, fmt.Errorf("wav: unsupported number of channels %d", nchannels)
+	}
+	containerBits := (int(info.BitsPerSample) + 7) &^ 7
+	blockAlign := nchannels * containerBits / 8
+	dataSize := int64(info.NSamples) * int64(blockAlign)
+	extensible := nchannels > 2 || int(info.BitsPerSample) != containerBits
+	var body []byte
+	le := binary.LittleEndian
+	tag := uint16(formatPCM)
+	if extensible {
+		tag = formatExtensible
+	}
+	body = le.AppendUint16(body, tag)
+	body = le.AppendUint16(body, uint16(nchannels))
+	body = le.AppendUint32(body, info.SampleRate)
+	body = le.AppendUint32(body, info.SampleRate*uint32(blockAlign))
+	body = le.AppendUint16(body, uint16(blockAlign))
+	body = le.AppendUint16(body, uint16(containerBits))
+	if extensible {
+		body = le.AppendUint16(body, 22)
+		body = le.AppendUint16(body, uint16(info.BitsPerSample))
+		body = le.AppendUint32(body, defaultChannelMasks[nchannels])
+		body = le.AppendUint16(body, formatPCM)
+		body = append(body, subFormatPCM[:]...)
+	}
+	chunks = append([][]byte{riffChunk("fmt ", body)}, chunks...)
+	riffSize := 4 + 8 + dataSize + dataSize&1
+	for _, chunk := range chunks {
+		riffSize += int64(len(chunk))
+	}
+	if riffSize > 0xFFFFFFFF {
+		return nil, fmt.Errorf("wav: size of audio data (%d bytes) exceeds the limit of the WAVE format", dataSize)
+	}
+	hdr := append([]byte("RIFF"), le.AppendUint32(nil, uint32(riffSize))...)
+	hdr = append(hdr, "WAVE"...)
+	data := append([]byte("data"), le.AppendUint32(nil, uint32(dataSize))...)
+	fm := &ForeignMetadata{Container: ContainerRIFF}
+	fm.Chunks = append(fm.Chunks, hdr)
+	fm.Chunks = append(fm.Chunks, chunks...)
+	fm.Chunks = append(fm.Chunks, data)
+	return fm, nil
+}
+
+// riffChunk returns the RIFF chunk of the given ID and body, including the pad
+// byte of odd-sized chunks.
+func riffChunk(id string, body []byte) []byte {
+	chunk := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	chunk = append(chunk, body...)
+	if len(body)%2 != 0 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// withComments returns the given metadata blocks with the given Vorbis comments
+// appended to a copy of the VorbisComment metadata block; or to a new
+// VorbisComment metadata block, if not present.
+func withComments(blocks []*meta.Block, tags [][2]string) []*meta.Block {
+	if len(tags) == 0 {
+		return blocks
+	}
+	comment := &meta.VorbisComment{Tags: tags}
+	i := slices.IndexFunc(blocks, func(block *meta.Block) bool {
+		_, ok := block.Body.(*meta.VorbisComment)
+		return ok
+	})
+	if i != -1 {
+		orig := blocks[i].Body.(*meta.VorbisComment)
+		comment.Vendor = orig.Vendor
+		comment.Tags = append(slices.Clip(orig.Tags), tags...)
+	}
+	// Length of the metadata block body.
+	length := 4 + len(comment.Vendor) + 4
+	for _, tag := range comment.Tags {
+		length += 4 + len(tag[0]) + 1 + len(tag[1])
+	}
+	block := &meta.Block{
+		Header: meta.Header{Type: meta.TypeVorbisComment, Length: int64(length)},
+		Body:   comment,
+	}
+	if i == -1 {
+		return append(slices.Clip(blocks), block)
+	}
+	blocks = slices.Clone(blocks)
+	blocks[i] = block
+	return blocks
+}
+
+// bextFromStream returns the bext chunk of the Vorbis comments of the given
+// FLAC stream; or false if not present.
+func bextFromStream(stream *flac.Stream) (*Bext, bool, error) {
+	for _, block := range stream.Blocks {
+		if comment, ok := block.Body.(*meta.VorbisComment); ok {
+			return BextFromComments(comment.Tags)
+		}
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,183 @@
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/wav"
+)
+
+// chunk returns a RIFF chunk with the given ID and body, including the pad
+// byte of odd-sized chunks.
+func chunk(order binary.AppendByteOrder, id string, body []byte) []byte {
+	buf := append([]byte(id), order.AppendUint32(nil, uint32(len(body)))...)
+	buf = append(buf, body...)
+	if len(body)%2 != 0 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+// makeWAV returns a WAVE file of PCM audio samples with the given format, and
+// the given chunks preceding and following the data chunk.
+func makeWAV(nchannels, bitsPerSample, nsamples int, before, after [][]byte) []byte {
+	le := binary.LittleEndian
+	nbytes := (bitsPerSample + 7) / 8
+	var fmtBody []byte
+	fmtBody = le.AppendUint16(fmtBody, 1)
+	fmtBody = le.AppendUint16(fmtBody, uint16(nchannels))
+	fmtBody = le.AppendUint32(fmtBody, 44100)
+	fmtBody = le.AppendUint32(fmtBody, uint32(44100*nchannels*nbytes))
+	fmtBody = le.AppendUint16(fmtBody, uint16(nchannels*nbytes))
+	fmtBody = le.AppendUint16(fmtBody, uint16(bitsPerSample))
+	data := make([]byte, nsamples*nchannels*nbytes)
+	for i := range data {
+		data[i] = byte(i*7 + i/3)
+	}
+	body := []byte("WAVE")
+	body = append(body, chunk(le, "fmt ", fmtBody)...)
+	for _, c := range before {
+		body = append(body, c...)
+	}
+	body = append(body, chunk(le, "data", data)...)
+	for _, c := range after {
+		body = append(body, c...)
+	}
+	return chunk(le, "RIFF", body)
+}
+
+// roundTrip imports the given WAVE file to FLAC and exports it back to WAVE.
+func roundTrip(t *testing.T, src []byte, keep bool) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	wavPath := filepath.Join(dir, "in.wav")
+	flacPath := filepath.Join(dir, "out.flac")
+	outPath := filepath.Join(dir, "out.wav")
+	if err := os.WriteFile(wavPath, src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := wav.ImportFile(flacPath, wavPath, &wav.ImportOptions{KeepForeignMetadata: keep}); err != nil {
+		t.Fatalf("unable to import WAVE file; %v", err)
+	}
+	if err := wav.ExportFile(outPath, flacPath, &wav.ExportOptions{KeepForeignMetadata: keep}); err != nil {
+		t.Fatalf("unable to export WAVE file; %v", err)
+	}
+	got, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestForeignMetadataRoundTrip(t *testing.T) {
+	le := binary.LittleEndian
+	bext := make([]byte, 602)
+	copy(bext, "Originator description")
+	before := [][]byte{chunk(le, "bext", bext), chunk(le, "junk", []byte{1, 2, 3})}
+	after := [][]byte{chunk(le, "LIST", []byte("INFOICMT\x05\x00\x00\x00take\x00\x00"))}
+	golden := []struct {
+		name                               string
+		nchannels, bitsPerSample, nsamples int
+	}{
+		{name: "stereo 16-bit", nchannels: 2, bitsPerSample: 16, nsamples: 10000},
+		{name: "stereo 24-bit", nchannels: 2, bitsPerSample: 24, nsamples: 5000},
+		{name: "mono 8-bit odd", nchannels: 1, bitsPerSample: 8, nsamples: 4097},
+		{name: "mono 12-bit", nchannels: 1, bitsPerSample: 12, nsamples: 100},
+	}
+	for _, g := range golden {
+		t.Run(g.name, func(t *testing.T) {
+			src := makeWAV(g.nchannels, g.bitsPerSample, g.nsamples, before, after)
+			if g.bitsPerSample == 12 {
+				// Clear the padding bits of the left-justified samples.
+				data := src[len(src)-len(after[0])-2*g.nsamples:]
+				for i := 0; i < 2*g.nsamples; i += 2 {
+					data[i] &^= 0x0F
+				}
+			}
+			got := roundTrip(t, src, true)
+			if !bytes.Equal(got, src) {
+				t.Fatalf("restored WAVE file differs from the original; expected %d bytes, got %d bytes", len(src), len(got))
+			}
+		})
+	}
+}
+
+func TestExportCanonical(t *testing.T) {
+	le := binary.LittleEndian
+	src := makeWAV(2, 16, 3000, nil, nil)
+	got := roundTrip(t, src, false)
+	if !bytes.Equal(got, src) {
+		t.Fatalf("canonical WAVE file differs from the original")
+	}
+	// Chunks other than fmt and data are dropped without foreign metadata.
+	src = makeWAV(2, 16, 3000, [][]byte{chunk(le, "bext", make([]byte, 602))}, nil)
+	got = roundTrip(t, src, false)
+	if want := makeWAV(2, 16, 3000, nil, nil); !bytes.Equal(got, want) {
+		t.Fatalf("canonical WAVE file mismatch")
+	}
+}
+
+func TestParseForeignMetadata(t *testing.T) {
+	stream, err := flac.ParseFile("../testdata/love.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if _, err := wav.ParseForeignMetadata(stream.Blocks); !errors.Is(err, wav.ErrNoForeignMetadata) {
+		t.Fatalf("error mismatch; expected %v, got %v", wav.ErrNoForeignMetadata, err)
+	}
+}
+
+func TestForeignMetadataAIFF(t *testing.T) {
+	be := binary.BigEndian
+	comm := make([]byte, 18)
+	be.PutUint16(comm[0:2], 1)
+	be.PutUint32(comm[2:6], 4)
+	be.PutUint16(comm[6:8], 16)
+	copy(comm[8:], []byte{0x40, 0x0E, 0xAC, 0x44}) // 44100 Hz, 80-bit extended
+	audio := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	body := []byte("AIFF")
+	body = append(body, chunk(be, "COMM", comm)...)
+	body = append(body, chunk(be, "NAME", []byte("take"))...)
+	body = append(body, chunk(be, "SSND", append(make([]byte, 8), audio...))...)
+	body = append(body, chunk(be, "ANNO", []byte("odd"))...)
+	src := chunk(be, "FORM", body)
+
+	r := bytes.NewReader(src)
+	fm, err := wav.ReadForeignMetadata(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Container != wav.ContainerAIFF {
+		t.Fatalf("container mismatch; expected %v, got %v", wav.ContainerAIFF, fm.Container)
+	}
+	if got, want := fm.AudioSize(), int64(len(audio)); got != want {
+		t.Fatalf("audio size mismatch; expected %d, got %d", want, got)
+	}
+	offset, _ := r.Seek(0, io.SeekCurrent)
+	if offset != fm.AudioOffset() || !bytes.Equal(src[offset:offset+8], audio) {
+		t.Fatalf("read position mismatch; expected start of audio payload, got offset %d", offset)
+	}
+	// Round-trip through APPLICATION metadata blocks.
+	fm, err = wav.ParseForeignMetadata(fm.Blocks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := fm.Write(buf, bytes.NewReader(audio)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), src) {
+		t.Fatalf("restored AIFF file differs from the original")
+	}
+	// Short audio payload.
+	if err := fm.Write(&bytes.Buffer{}, bytes.NewReader(audio[:4])); err == nil {
+		t.Fatalf("expected error for short audio payload")
+	}
+}
@@ -0,0 +1,91 @@
+package wav
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// A Writer writes the audio samples of FLAC frames as a canonical WAVE file;
+// e.g. to export audio samples which are not decoded from a FLAC stream in its
+// entirety.
+type Writer struct {
+	// Buffered underlying writer.
+	w *bufio.Writer
+	// Sample format.
+	format *format
+	// Number of samples (per channel) remaining, as specified by the WAVE
+	// header.
+	remaining uint64
+	// Specifies whether the data chunk is followed by a pad byte.
+	pad bool
+	// Scratch buffer of packed samples.
+	buf []byte
+}
+
+// NewWriter returns a new writer of a canonical WAVE file of the audio samples
+// of a FLAC stream with the given StreamInfo block, and writes the WAVE
+// header. The total number of samples of info must be specified, as required
+// by the WAVE format.
+func NewWriter(w io.Writer, info *meta.StreamInfo) (*Writer, error) {
+	if info.NSamples == 0 {
+		return nil, errors.New("wav.NewWriter: total number of samples not specified; required by WAVE format")
+	}
+	fm, err := canonical(info)
+	if err != nil {
+		return nil, err
+	}
+	format, err := fm.format()
+	if err != nil {
+		return nil, err
+	}
+	bw := bufio.NewWriter(w)
+	for _, chunk := range fm.Chunks {
+		if _, err := bw.Write(chunk); err != nil {
+			return nil, err
+		}
+	}
+	ww := &Writer{
+		w:         bw,
+		format:    format,
+		remaining: info.NSamples,
+		pad:       fm.AudioSize()%2 != 0,
+	}
+	return ww, nil
+}
+
+// WriteFrame writes the interleaved audio samples of the given frame.
+func (ww *Writer) WriteFrame(f *frame.Frame) error {
+	if len(f.Subframes) != ww.format.nchannels {
+		return fmt.Errorf("wav.Writer.WriteFrame: channel count mismatch of frame %d; expected %d, got %d", f.Num, ww.format.nchannels, len(f.Subframes))
+	}
+	nsamples := uint64(f.Subframes[0].NSamples)
+	if nsamples > ww.remaining {
+		return fmt.Errorf("wav.Writer.WriteFrame: number of samples exceeds the total number of samples of StreamInfo at frame %d", f.Num)
+	}
+	ww.remaining -= nsamples
+	var err error
+	if ww.buf, err = f.AppendPCM(ww.buf[:0], ww.format.pcm()); err != nil {
+		return err
+	}
+	_, err = ww.w.Write(ww.buf)
+	return err
+}
+
+// Close flushes the buffered samples to the underlying writer. It does not
+// close the underlying writer.
+func (ww *Writer) Close() error {
+	if ww.remaining != 0 {
+		return fmt.Errorf("wav.Writer.Close: audio samples ended %d samples short of the total number of samples of StreamInfo", ww.remaining)
+	}
+	if ww.pad {
+		if err := ww.w.WriteByte(0); err != nil {
+			return err
+		}
+	}
+	return ww.w.Flush()
+}